@@ -17,6 +17,7 @@ limitations under the License.
 package v1alpha2
 
 import (
+	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	cfg "sigs.k8s.io/controller-runtime/pkg/config/v1alpha1"
 )
@@ -38,11 +39,59 @@ type Configuration struct {
 	// batch/v1.Jobs that don't set the annotation kueue.x-k8s.io/queue-name.
 	// If set to true, then those jobs will be suspended and never started unless
 	// they are assigned a queue and eventually admitted. This also applies to
-	// jobs created before starting the kueue controller.
+	// jobs created before starting the kueue controller. It doesn't apply to
+	// Deployments and StatefulSets, which are only managed with a queue name.
 	// Defaults to false; therefore, those jobs are not managed and if they are created
 	// unsuspended, they will start immediately.
 	ManageJobsWithoutQueueName bool `json:"manageJobsWithoutQueueName"`
 
+	// StrictCreationOrder controls whether ClusterQueues order their pending
+	// workloads only by creation timestamp, regardless of the workload priority
+	// and of the LocalQueue the workloads were submitted to. Workloads with the
+	// same creation timestamp are ordered by namespace and name.
+	// Defaults to false; therefore, workloads are ordered by priority first.
+	StrictCreationOrder bool `json:"strictCreationOrder,omitempty"`
+
+	// FlavorAssignmentPolicy is the policy used to choose a flavor for each
+	// resource of a Workload, for the ClusterQueues that don't set
+	// .spec.flavorAssignmentPolicy. The possible values are:
+	//
+	// - FirstFit: the first flavor in which the Workload fits, in the order
+	//   of the flavors of the resource.
+	// - BestFit: the flavor that requires borrowing the least quota and, for
+	//   the same borrowing, leaves the least unused min quota.
+	// - LowestCost: the flavor with the lowest cost, declared in the
+	//   kueue.x-k8s.io/cost-per-cpu-hour annotation of the ResourceFlavors.
+	//
+	// Defaults to FirstFit.
+	FlavorAssignmentPolicy FlavorAssignmentPolicy `json:"flavorAssignmentPolicy,omitempty"`
+
+	// FlavorFungibility is the default, for the ClusterQueues that don't set
+	// .spec.flavorFungibility or some of its fields, of whether a Workload
+	// tries the next flavor before borrowing or preempting in the flavor
+	// being evaluated.
+	// If not set, Workloads borrow in the first flavor that fits and try the
+	// next flavor before preempting.
+	FlavorFungibility *FlavorFungibility `json:"flavorFungibility,omitempty"`
+
+	// RequeueEvictedFirst controls whether the workloads that were evicted
+	// after being admitted, for example when they are preempted or to restore
+	// the balance of a cohort, are placed in front of the other pending
+	// workloads with the same priority when they are requeued.
+	// Defaults to false; therefore, evicted workloads are ordered like any
+	// other pending workload.
+	RequeueEvictedFirst bool `json:"requeueEvictedFirst,omitempty"`
+
+	// PriorityInheritance controls whether the pending workloads are ordered
+	// in their ClusterQueues with the highest priority among their own, the
+	// ones of the other pending workloads of their admission group, and the
+	// one of the pending workload they depend on, named in the
+	// kueue.x-k8s.io/depends-on annotation. This way, a low priority member
+	// doesn't hold back a high priority admission group.
+	// Defaults to false; therefore, workloads are ordered by their own
+	// priority.
+	PriorityInheritance bool `json:"priorityInheritance,omitempty"`
+
 	// InternalCertManagement is configuration for internalCertManagement
 	InternalCertManagement *InternalCertManagement `json:"internalCertManagement,omitempty"`
 
@@ -51,6 +100,485 @@ type Configuration struct {
 	// This is achieved by blocking the start of new jobs until the previously
 	// started job has all pods running (ready).
 	WaitForPodsReady *WaitForPodsReady `json:"waitForPodsReady,omitempty"`
+
+	// JobSuspendReconciliation is configuration for how Kueue handles Jobs
+	// that are running while their Workloads are not admitted, for example,
+	// because the Job was manually unsuspended.
+	JobSuspendReconciliation *JobSuspendReconciliation `json:"jobSuspendReconciliation,omitempty"`
+
+	// PrioritySources is the list of sources, in order of precedence, from
+	// which Kueue takes the PriorityClass of the Workloads created for Jobs.
+	// The PriorityClass of the first source that provides one is used. If
+	// none does, the global default PriorityClass is used. The possible
+	// values are:
+	//
+	// - PriorityClassLabel: the PriorityClass named in the
+	//   kueue.x-k8s.io/priority-class label of the Job.
+	// - PodPriorityClass: the priorityClassName of the Job's pod template.
+	// - LocalQueue: the priorityClassName of the Job's LocalQueue.
+	//
+	// Defaults to [PriorityClassLabel, PodPriorityClass, LocalQueue].
+	PrioritySources []PrioritySource `json:"prioritySources,omitempty"`
+
+	// CohortRebalancing is configuration for a loop that evicts admitted
+	// Workloads from the ClusterQueue that borrows the most in a cohort, when
+	// other ClusterQueues in the cohort have pending Workloads while their min
+	// quota is lent. The evicted Workloads are queued again.
+	// If not set, Workloads are never evicted.
+	CohortRebalancing *CohortRebalancing `json:"cohortRebalancing,omitempty"`
+
+	// Preemption is configuration for the preemption of admitted Workloads,
+	// which is enabled through the preemption policies of the ClusterQueues.
+	Preemption *Preemption `json:"preemption,omitempty"`
+
+	// Archival is configuration for sending a record of each finished
+	// Workload to an external endpoint before the Workload can be deleted.
+	// If not set, finished Workloads are not archived.
+	Archival *Archival `json:"archival,omitempty"`
+
+	// FairSharing is configuration for sharing the resources of a cohort
+	// fairly among its ClusterQueues.
+	FairSharing *FairSharing `json:"fairSharing,omitempty"`
+
+	// RequeueBackoff is configuration for delaying, with an exponential
+	// backoff, the next admission attempt of Workloads that repeatedly fail
+	// to be admitted.
+	// If not set, Workloads are requeued immediately.
+	RequeueBackoff *RequeueBackoff `json:"requeueBackoff,omitempty"`
+
+	// WorkloadAging is configuration for increasing the effective priority
+	// of the pending Workloads, to order them in their ClusterQueues, the
+	// longer they wait to be admitted, so that Workloads with low priority
+	// are eventually admitted.
+	// If not set, Workloads are ordered by their priority.
+	WorkloadAging *WorkloadAging `json:"workloadAging,omitempty"`
+
+	// MaxWorkloadEvictions is the number of times a Workload can be evicted
+	// after being admitted before Kueue deactivates it, by setting its
+	// .spec.active to false. Inactive Workloads aren't considered for
+	// admission until they are activated again.
+	// If not set, Workloads are never deactivated.
+	MaxWorkloadEvictions *int32 `json:"maxWorkloadEvictions,omitempty"`
+
+	// Quarantine is configuration for setting aside the Workloads that fail
+	// to be admitted too many consecutive times, so that they are only
+	// retried at a slow, fixed interval.
+	// If not set, Workloads are never quarantined.
+	Quarantine *Quarantine `json:"quarantine,omitempty"`
+
+	// MetricsCardinality is configuration for bounding the number of series
+	// of the metrics that Kueue reports, for installations with many
+	// ClusterQueues or ResourceFlavors.
+	// If not set, the metrics are reported with all their labels.
+	MetricsCardinality *MetricsCardinality `json:"metricsCardinality,omitempty"`
+
+	// ResourceFlavorDeletionPolicy is what happens when a ResourceFlavor is
+	// deleted while it's in use. The possible values are:
+	//
+	// - Block: the ResourceFlavor is only removed once no ClusterQueue lists
+	//   it. ClusterQueues keep assigning it to new Workloads until then.
+	// - Evict: the ResourceFlavor isn't assigned to new Workloads and the
+	//   admitted Workloads that use it are evicted, to be admitted again with
+	//   other flavors. It's removed once no admitted Workload uses it.
+	// - KeepRunning: the ResourceFlavor isn't assigned to new Workloads, and
+	//   the admitted Workloads that use it keep running. It's removed once
+	//   they finish.
+	//
+	// With Evict and KeepRunning, the ClusterQueues that list the
+	// ResourceFlavor report it in their Degraded condition.
+	// Defaults to Block.
+	ResourceFlavorDeletionPolicy ResourceFlavorDeletionPolicy `json:"resourceFlavorDeletionPolicy,omitempty"`
+
+	// CapacitySnapshots is configuration for periodically sending the queue
+	// depth, usage and borrowing of each ClusterQueue to an external
+	// endpoint, to build capacity planning reports regardless of the
+	// retention of the metrics.
+	// If not set, no snapshots are taken.
+	CapacitySnapshots *CapacitySnapshots `json:"capacitySnapshots,omitempty"`
+
+	// Resources is configuration for rewriting the resource requests of the
+	// Workloads before they are accounted against the quotas, so that
+	// requests in different formats can share the same quota.
+	// If not set, the requests are accounted as they are.
+	Resources *Resources `json:"resources,omitempty"`
+
+	// Integrations is configuration for the job integrations, besides the
+	// batch/v1 Jobs, that Kueue manages.
+	// If not set, only batch/v1 Jobs are managed.
+	Integrations *Integrations `json:"integrations,omitempty"`
+
+	// ExternalAdmission is configuration for asking an external endpoint,
+	// such as a budget or compliance system, to approve the admission of each
+	// Workload before it's applied.
+	// If not set, Workloads are admitted without external approval.
+	ExternalAdmission *ExternalAdmission `json:"externalAdmission,omitempty"`
+
+	// ProvisioningRequests is configuration for the controller of the
+	// AdmissionChecks with the controllerName
+	// kueue.x-k8s.io/provisioning-request. For each Workload with reserved
+	// quota, the controller creates a ProvisioningRequest, so that the
+	// cluster autoscaler provisions the nodes for its pods before it's
+	// admitted. The ProvisioningRequest CRD must be installed in the cluster.
+	// If not set, the controller isn't started and these AdmissionChecks are
+	// never active.
+	ProvisioningRequests *ProvisioningRequests `json:"provisioningRequests,omitempty"`
+}
+
+type Integrations struct {
+	// Frameworks are the names of the additional job frameworks to manage.
+	// The supported frameworks are:
+	// - "kubeflow.org/tfjob"
+	// - "kubeflow.org/pytorchjob"
+	// - "kubeflow.org/xgboostjob"
+	// - "kubeflow.org/paddlejob"
+	// - "apps/deployment"
+	// - "apps/statefulset"
+	// - "pod", for the Pods with the queue name label and no controller,
+	//   which are grouped by the kueue.x-k8s.io/pod-group-name label
+	// The CRDs of the frameworks must be installed in the cluster.
+	// The batch Jobs owned by the jobs of these frameworks are left to the
+	// frameworks, as their quota is accounted through the workloads of the
+	// owners.
+	Frameworks []string `json:"frameworks,omitempty"`
+}
+
+type PrioritySource string
+
+const (
+	PrioritySourcePriorityClassLabel PrioritySource = "PriorityClassLabel"
+	PrioritySourcePodPriorityClass   PrioritySource = "PodPriorityClass"
+	PrioritySourceLocalQueue         PrioritySource = "LocalQueue"
+)
+
+type Preemption struct {
+	// VictimSelection is the heuristic used to order the candidate Workloads
+	// when preemption needs to free quota. Workloads from other ClusterQueues
+	// in the cohort are always preempted before Workloads from the
+	// ClusterQueue of the pending Workload. The possible values are:
+	//
+	// - LowestPriorityFirst: preempt Workloads with lower priority first and,
+	//   for the same priority, the most recently admitted first.
+	// - MostRecentlyAdmittedFirst: preempt the most recently admitted
+	//   Workloads first and, for the same admission time, the ones with lower
+	//   priority first.
+	// - FewestVictims: preempt first the Workloads that free the largest part
+	//   of the quota that the pending Workload needs, so that fewer Workloads
+	//   are preempted.
+	//
+	// Defaults to LowestPriorityFirst.
+	VictimSelection VictimSelectionStrategy `json:"victimSelection,omitempty"`
+
+	// DryRun indicates if the scheduler only reports the Workloads that it
+	// would preempt, in an event of the pending Workload and in the logs,
+	// without evicting them. It allows to validate the preemption policies
+	// of the ClusterQueues before enabling them.
+	DryRun bool `json:"dryRun,omitempty"`
+}
+
+type FlavorFungibility struct {
+	// WhenCanBorrow determines whether a Workload tries the next flavor
+	// before borrowing in the current flavor. The possible values are:
+	//
+	// - Borrow: assign the current flavor, borrowing quota from the cohort.
+	// - TryNextFlavor: try the next flavor, and only borrow if no flavor
+	//   fits without borrowing.
+	//
+	// Defaults to Borrow.
+	WhenCanBorrow FlavorFungibilityPolicy `json:"whenCanBorrow,omitempty"`
+
+	// WhenCanPreempt determines whether a Workload tries the next flavor
+	// before preempting in the current flavor. The possible values are:
+	//
+	// - Preempt: assign the current flavor, preempting Workloads if needed.
+	// - TryNextFlavor: try the next flavor, and only preempt if no flavor
+	//   fits.
+	//
+	// Defaults to TryNextFlavor.
+	WhenCanPreempt FlavorFungibilityPolicy `json:"whenCanPreempt,omitempty"`
+}
+
+type FlavorFungibilityPolicy string
+
+const (
+	FlavorFungibilityBorrow        FlavorFungibilityPolicy = "Borrow"
+	FlavorFungibilityPreempt       FlavorFungibilityPolicy = "Preempt"
+	FlavorFungibilityTryNextFlavor FlavorFungibilityPolicy = "TryNextFlavor"
+)
+
+type FlavorAssignmentPolicy string
+
+const (
+	FlavorAssignmentFirstFit   FlavorAssignmentPolicy = "FirstFit"
+	FlavorAssignmentBestFit    FlavorAssignmentPolicy = "BestFit"
+	FlavorAssignmentLowestCost FlavorAssignmentPolicy = "LowestCost"
+)
+
+type VictimSelectionStrategy string
+
+const (
+	VictimSelectionLowestPriorityFirst       VictimSelectionStrategy = "LowestPriorityFirst"
+	VictimSelectionMostRecentlyAdmittedFirst VictimSelectionStrategy = "MostRecentlyAdmittedFirst"
+	VictimSelectionFewestVictims             VictimSelectionStrategy = "FewestVictims"
+)
+
+type Resources struct {
+	// PodOverhead is added to the requests of each pod of the Workloads,
+	// before the transformations are applied.
+	PodOverhead corev1.ResourceList `json:"podOverhead,omitempty"`
+
+	// Transformations rewrite the requests of the pod sets of the Workloads.
+	// Each input resource can only be transformed once.
+	Transformations []ResourceTransformation `json:"transformations,omitempty"`
+}
+
+type ResourceTransformation struct {
+	// Input is the name of the requested resource that is transformed.
+	Input corev1.ResourceName `json:"input"`
+
+	// Strategy is whether the input resource is kept in the requests. The
+	// possible values are:
+	//
+	// - Retain: the input resource is kept, and the outputs are added.
+	// - Replace: the input resource is removed, and the outputs are added.
+	//
+	// Defaults to Retain.
+	Strategy ResourceTransformationStrategy `json:"strategy,omitempty"`
+
+	// Outputs are the quantities of resources added to the requests for each
+	// unit of the input resource. The quantities can be fractional, such as
+	// 0.25 of nvidia.com/gpu for each unit of nvidia.com/mig-1g.5gb. The total
+	// of each pod set is rounded up.
+	Outputs corev1.ResourceList `json:"outputs,omitempty"`
+}
+
+type ResourceTransformationStrategy string
+
+const (
+	ResourceTransformationRetain  ResourceTransformationStrategy = "Retain"
+	ResourceTransformationReplace ResourceTransformationStrategy = "Replace"
+)
+
+type ResourceFlavorDeletionPolicy string
+
+const (
+	ResourceFlavorDeletionBlock       ResourceFlavorDeletionPolicy = "Block"
+	ResourceFlavorDeletionEvict       ResourceFlavorDeletionPolicy = "Evict"
+	ResourceFlavorDeletionKeepRunning ResourceFlavorDeletionPolicy = "KeepRunning"
+)
+
+type JobSuspendPolicy string
+
+const (
+	// JobSuspendPolicySuspend suspends the running Jobs that are not admitted.
+	JobSuspendPolicySuspend JobSuspendPolicy = "Suspend"
+	// JobSuspendPolicyReport only records a warning event for the running Jobs
+	// that are not admitted.
+	JobSuspendPolicyReport JobSuspendPolicy = "Report"
+)
+
+type JobSuspendReconciliation struct {
+	// Policy is the action taken when a Job is running while its Workload is
+	// not admitted. The possible values are:
+	//
+	// - Suspend: the Job is suspended again.
+	// - Report: a warning event is recorded for the Job, which keeps running.
+	//
+	// The Jobs of evicted Workloads are suspended with either policy.
+	// Defaults to Suspend.
+	Policy JobSuspendPolicy `json:"policy,omitempty"`
+
+	// Period is the interval of a loop that periodically checks the suspend
+	// state of all the Jobs managed by Kueue, as a safety net for missed events.
+	// If not set, the Jobs are only checked when they change.
+	Period *metav1.Duration `json:"period,omitempty"`
+}
+
+type CohortRebalancing struct {
+	// Period is the interval between checks of the balance of the cohorts.
+	// Defaults to 1m.
+	Period *metav1.Duration `json:"period,omitempty"`
+
+	// ImbalanceThreshold is the percentage of the min quota of a ClusterQueue
+	// with pending Workloads that has to be used by other ClusterQueues for
+	// the cohort to be considered imbalanced.
+	// Defaults to 20.
+	ImbalanceThreshold *int32 `json:"imbalanceThreshold,omitempty"`
+
+	// SustainedPeriod is how long a cohort has to stay imbalanced before
+	// Workloads are evicted.
+	// Defaults to 5m.
+	SustainedPeriod *metav1.Duration `json:"sustainedPeriod,omitempty"`
+
+	// MaxEvictionsPerCohort is the maximum number of Workloads evicted from a
+	// cohort at a time. After the evictions, the cohort has to stay imbalanced
+	// for the sustained period again before more Workloads are evicted.
+	// Defaults to 1.
+	MaxEvictionsPerCohort *int32 `json:"maxEvictionsPerCohort,omitempty"`
+}
+
+type FairSharing struct {
+	// Enable when true, indicates that the scheduler considers the pending
+	// Workloads from the ClusterQueues with the lowest dominant resource
+	// share first. The dominant resource share of a ClusterQueue is the
+	// highest fraction, among all the resources, of the quota of the cohort
+	// that the ClusterQueue uses. Workloads that fit within the min quota of
+	// their ClusterQueues are still considered before the ones that borrow.
+	// Defaults to false.
+	Enable bool `json:"enable,omitempty"`
+
+	// UsageHalfLifeTime, when set, makes the scheduler compute the dominant
+	// resource share of the ClusterQueues from their historical usage instead
+	// of their current usage. The historical usage decays exponentially, losing
+	// half of its value after each UsageHalfLifeTime, so the ClusterQueues that
+	// recently used a lot of resources yield to the ones that were idle.
+	// Defaults to unset, which uses the current usage.
+	UsageHalfLifeTime *metav1.Duration `json:"usageHalfLifeTime,omitempty"`
+}
+
+type RequeueBackoff struct {
+	// BaseDelay is the delay before the next admission attempt after the
+	// first failed one. The delay doubles with each failed attempt, and a
+	// jitter of up to 10% is added to it.
+	// Defaults to 1s.
+	BaseDelay *metav1.Duration `json:"baseDelay,omitempty"`
+
+	// MaxDelay is the maximum delay before the next admission attempt.
+	// Defaults to 5m.
+	MaxDelay *metav1.Duration `json:"maxDelay,omitempty"`
+}
+
+type Quarantine struct {
+	// Threshold is the number of consecutive failed admission attempts after
+	// which a Workload is quarantined. Quarantined Workloads have the
+	// Quarantined condition and are skipped by the admission cycles until
+	// their next retry. The quarantine ends when the Workload is admitted.
+	// Defaults to 10.
+	Threshold *int32 `json:"threshold,omitempty"`
+
+	// RetryInterval is the delay between the admission attempts of a
+	// quarantined Workload. A jitter of up to 10% is added to it.
+	// Defaults to 30m.
+	RetryInterval *metav1.Duration `json:"retryInterval,omitempty"`
+}
+
+type MetricsCardinality struct {
+	// MaxClusterQueues is the maximum number of ClusterQueues reported with
+	// their name in the cluster_queue label. The metrics of the ClusterQueues
+	// reported after the limit is reached are aggregated under the "other"
+	// value, until the ClusterQueues are deleted.
+	// If not set, all the ClusterQueues are reported with their name.
+	MaxClusterQueues *int32 `json:"maxClusterQueues,omitempty"`
+
+	// DisableFlavorLabel when true, indicates that the metrics per
+	// ResourceFlavor report the sum for all the flavors, with an empty
+	// flavor label.
+	// Defaults to false.
+	DisableFlavorLabel bool `json:"disableFlavorLabel,omitempty"`
+
+	// DisablePriorityClassLabel when true, indicates that the metrics per
+	// PriorityClass, such as pending_workloads_by_priority_class, are not
+	// reported.
+	// Defaults to false.
+	DisablePriorityClassLabel bool `json:"disablePriorityClassLabel,omitempty"`
+}
+
+type WorkloadAging struct {
+	// Rate is the increase of the effective priority of a pending Workload
+	// for each minute that it waits to be admitted, since it was created or
+	// last evicted. The effective priority only affects the order of the
+	// Workloads in their ClusterQueues, not preemption.
+	// Defaults to 1.
+	Rate *int32 `json:"rate,omitempty"`
+
+	// Cap is the maximum increase of the effective priority of a Workload.
+	// Defaults to 100.
+	Cap *int32 `json:"cap,omitempty"`
+}
+
+type Archival struct {
+	// URL is the endpoint that receives the records of the finished Workloads,
+	// encoded as JSON, in HTTP POST requests. It can be a webhook or a gateway
+	// that stores the records in an object store. A response with a status
+	// code other than 2xx is considered a failure and the request is retried.
+	URL string `json:"url"`
+
+	// Timeout is the timeout of each request to the URL.
+	// Defaults to 10s.
+	Timeout *metav1.Duration `json:"timeout,omitempty"`
+}
+
+type ExternalAdmission struct {
+	// URL is the endpoint that reviews the candidate admissions. Each review
+	// is sent, encoded as JSON, in an HTTP POST request, and the endpoint
+	// responds whether the admission is allowed and, if not, why. A Workload
+	// whose admission is denied is requeued as inadmissible.
+	URL string `json:"url"`
+
+	// Timeout is the timeout of each request to the URL. The scheduler waits
+	// for the response before admitting other Workloads.
+	// Defaults to 10s.
+	Timeout *metav1.Duration `json:"timeout,omitempty"`
+
+	// FailurePolicy is what happens when the endpoint can't be reached or
+	// responds with an error. The possible values are:
+	//
+	// - Fail: the admission is denied.
+	// - Ignore: the admission is allowed.
+	//
+	// Defaults to Fail.
+	FailurePolicy ExternalAdmissionFailurePolicy `json:"failurePolicy,omitempty"`
+}
+
+type ExternalAdmissionFailurePolicy string
+
+const (
+	ExternalAdmissionFail   ExternalAdmissionFailurePolicy = "Fail"
+	ExternalAdmissionIgnore ExternalAdmissionFailurePolicy = "Ignore"
+)
+
+type ProvisioningRequests struct {
+	// ProvisioningClassName is the class of the ProvisioningRequests, which
+	// tells the cluster autoscaler how to provision the capacity.
+	// Defaults to check-capacity.autoscaling.x-k8s.io.
+	ProvisioningClassName string `json:"provisioningClassName,omitempty"`
+
+	// Parameters are passed to the cluster autoscaler in the
+	// ProvisioningRequests. The supported parameters depend on the
+	// provisioning class.
+	Parameters map[string]string `json:"parameters,omitempty"`
+
+	// MaxRetries is the number of times a ProvisioningRequest that failed is
+	// created again for the same quota reservation. Once the retries are
+	// exhausted, the admission check is set to Retry, so that the Workload is
+	// evicted, releasing its quota, and requeued.
+	// Defaults to 3.
+	MaxRetries *int32 `json:"maxRetries,omitempty"`
+
+	// RetryBackoff is the time to wait before creating a ProvisioningRequest
+	// again after it failed. It doubles after each failure, up to 1h.
+	// Defaults to 1m.
+	RetryBackoff *metav1.Duration `json:"retryBackoff,omitempty"`
+}
+
+type CapacitySnapshots struct {
+	// URL is the endpoint that receives the snapshots, encoded as JSON, in
+	// HTTP POST requests. A snapshot that fails to be sent is dropped.
+	URL string `json:"url"`
+
+	// Interval is the time between snapshots. The snapshots are taken at the
+	// multiples of the interval since midnight in the TimeZone, so that the
+	// snapshots of different days can be compared by time of day.
+	// Defaults to 15m.
+	Interval *metav1.Duration `json:"interval,omitempty"`
+
+	// TimeZone is the IANA name of the time zone in which the snapshots are
+	// aligned and their day of the week and hour are reported.
+	// Defaults to UTC.
+	TimeZone string `json:"timeZone,omitempty"`
+
+	// Timeout is the timeout of each request to the URL.
+	// Defaults to 10s.
+	Timeout *metav1.Duration `json:"timeout,omitempty"`
 }
 
 type WaitForPodsReady struct {
@@ -60,6 +588,12 @@ type WaitForPodsReady struct {
 	// admitted and do not block admission of other workloads. The PodsReady
 	// condition is only added if this setting is enabled. It defaults to false.
 	Enable bool `json:"enable,omitempty"`
+
+	// Timeout is how long an admitted workload can wait for its pods to be
+	// ready. After the timeout, the workload is evicted with the reason
+	// PodsReadyTimeout and queued again. If empty, the workloads wait
+	// indefinitely.
+	Timeout *metav1.Duration `json:"timeout,omitempty"`
 }
 
 type InternalCertManagement struct {
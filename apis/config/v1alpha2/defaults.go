@@ -17,6 +17,9 @@ limitations under the License.
 package v1alpha2
 
 import (
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/utils/pointer"
 )
@@ -29,6 +32,31 @@ const (
 	DefaultHealthProbeBindAddress = ":8081"
 	DefaultMetricsBindAddress     = ":8080"
 	DefaultLeaderElectionID       = "c1f6bfd2.kueue.x-k8s.io"
+
+	DefaultCohortRebalancingPeriod          = time.Minute
+	DefaultCohortRebalancingThreshold       = 20
+	DefaultCohortRebalancingSustainedPeriod = 5 * time.Minute
+	DefaultCohortRebalancingMaxEvictions    = 1
+
+	DefaultArchivalTimeout = 10 * time.Second
+
+	DefaultExternalAdmissionTimeout = 10 * time.Second
+
+	DefaultProvisioningClassName    = "check-capacity.autoscaling.x-k8s.io"
+	DefaultProvisioningMaxRetries   = 3
+	DefaultProvisioningRetryBackoff = time.Minute
+
+	DefaultCapacitySnapshotsInterval = 15 * time.Minute
+	DefaultCapacitySnapshotsTimeout  = 10 * time.Second
+
+	DefaultRequeueBackoffBaseDelay = time.Second
+	DefaultRequeueBackoffMaxDelay  = 5 * time.Minute
+
+	DefaultQuarantineThreshold     = 10
+	DefaultQuarantineRetryInterval = 30 * time.Minute
+
+	DefaultWorkloadAgingRate = 1
+	DefaultWorkloadAgingCap  = 100
 )
 
 func addDefaultingFuncs(scheme *runtime.Scheme) error {
@@ -70,4 +98,100 @@ func SetDefaults_Configuration(cfg *Configuration) {
 			cfg.InternalCertManagement.WebhookSecretName = pointer.String(DefaultWebhookSecretName)
 		}
 	}
+	if cfg.FlavorFungibility != nil {
+		if len(cfg.FlavorFungibility.WhenCanBorrow) == 0 {
+			cfg.FlavorFungibility.WhenCanBorrow = FlavorFungibilityBorrow
+		}
+		if len(cfg.FlavorFungibility.WhenCanPreempt) == 0 {
+			cfg.FlavorFungibility.WhenCanPreempt = FlavorFungibilityTryNextFlavor
+		}
+	}
+	if cfg.JobSuspendReconciliation != nil && len(cfg.JobSuspendReconciliation.Policy) == 0 {
+		cfg.JobSuspendReconciliation.Policy = JobSuspendPolicySuspend
+	}
+	if cfg.PrioritySources == nil {
+		cfg.PrioritySources = []PrioritySource{
+			PrioritySourcePriorityClassLabel,
+			PrioritySourcePodPriorityClass,
+			PrioritySourceLocalQueue,
+		}
+	}
+	if cfg.CohortRebalancing != nil {
+		if cfg.CohortRebalancing.Period == nil {
+			cfg.CohortRebalancing.Period = &metav1.Duration{Duration: DefaultCohortRebalancingPeriod}
+		}
+		if cfg.CohortRebalancing.ImbalanceThreshold == nil {
+			cfg.CohortRebalancing.ImbalanceThreshold = pointer.Int32(DefaultCohortRebalancingThreshold)
+		}
+		if cfg.CohortRebalancing.SustainedPeriod == nil {
+			cfg.CohortRebalancing.SustainedPeriod = &metav1.Duration{Duration: DefaultCohortRebalancingSustainedPeriod}
+		}
+		if cfg.CohortRebalancing.MaxEvictionsPerCohort == nil {
+			cfg.CohortRebalancing.MaxEvictionsPerCohort = pointer.Int32(DefaultCohortRebalancingMaxEvictions)
+		}
+	}
+	if cfg.Preemption != nil && len(cfg.Preemption.VictimSelection) == 0 {
+		cfg.Preemption.VictimSelection = VictimSelectionLowestPriorityFirst
+	}
+	if cfg.Archival != nil && cfg.Archival.Timeout == nil {
+		cfg.Archival.Timeout = &metav1.Duration{Duration: DefaultArchivalTimeout}
+	}
+	if cfg.ExternalAdmission != nil {
+		if cfg.ExternalAdmission.Timeout == nil {
+			cfg.ExternalAdmission.Timeout = &metav1.Duration{Duration: DefaultExternalAdmissionTimeout}
+		}
+		if len(cfg.ExternalAdmission.FailurePolicy) == 0 {
+			cfg.ExternalAdmission.FailurePolicy = ExternalAdmissionFail
+		}
+	}
+	if cfg.ProvisioningRequests != nil {
+		if len(cfg.ProvisioningRequests.ProvisioningClassName) == 0 {
+			cfg.ProvisioningRequests.ProvisioningClassName = DefaultProvisioningClassName
+		}
+		if cfg.ProvisioningRequests.MaxRetries == nil {
+			cfg.ProvisioningRequests.MaxRetries = pointer.Int32(DefaultProvisioningMaxRetries)
+		}
+		if cfg.ProvisioningRequests.RetryBackoff == nil {
+			cfg.ProvisioningRequests.RetryBackoff = &metav1.Duration{Duration: DefaultProvisioningRetryBackoff}
+		}
+	}
+	if cfg.CapacitySnapshots != nil {
+		if cfg.CapacitySnapshots.Interval == nil {
+			cfg.CapacitySnapshots.Interval = &metav1.Duration{Duration: DefaultCapacitySnapshotsInterval}
+		}
+		if cfg.CapacitySnapshots.Timeout == nil {
+			cfg.CapacitySnapshots.Timeout = &metav1.Duration{Duration: DefaultCapacitySnapshotsTimeout}
+		}
+	}
+	if cfg.RequeueBackoff != nil {
+		if cfg.RequeueBackoff.BaseDelay == nil {
+			cfg.RequeueBackoff.BaseDelay = &metav1.Duration{Duration: DefaultRequeueBackoffBaseDelay}
+		}
+		if cfg.RequeueBackoff.MaxDelay == nil {
+			cfg.RequeueBackoff.MaxDelay = &metav1.Duration{Duration: DefaultRequeueBackoffMaxDelay}
+		}
+	}
+	if cfg.Quarantine != nil {
+		if cfg.Quarantine.Threshold == nil {
+			cfg.Quarantine.Threshold = pointer.Int32(DefaultQuarantineThreshold)
+		}
+		if cfg.Quarantine.RetryInterval == nil {
+			cfg.Quarantine.RetryInterval = &metav1.Duration{Duration: DefaultQuarantineRetryInterval}
+		}
+	}
+	if cfg.WorkloadAging != nil {
+		if cfg.WorkloadAging.Rate == nil {
+			cfg.WorkloadAging.Rate = pointer.Int32(DefaultWorkloadAgingRate)
+		}
+		if cfg.WorkloadAging.Cap == nil {
+			cfg.WorkloadAging.Cap = pointer.Int32(DefaultWorkloadAgingCap)
+		}
+	}
+	if cfg.Resources != nil {
+		for i := range cfg.Resources.Transformations {
+			if t := &cfg.Resources.Transformations[i]; len(t.Strategy) == 0 {
+				t.Strategy = ResourceTransformationRetain
+			}
+		}
+	}
 }
@@ -18,8 +18,10 @@ package v1alpha2
 
 import (
 	"testing"
+	"time"
 
 	"github.com/google/go-cmp/cmp"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	componentconfigv1alpha1 "k8s.io/component-base/config/v1alpha1"
 	"k8s.io/utils/pointer"
 	ctrlconfigv1alpha1 "sigs.k8s.io/controller-runtime/pkg/config/v1alpha1"
@@ -46,6 +48,12 @@ func TestSetDefaults_Configuration(t *testing.T) {
 		},
 	}
 
+	defaultPrioritySources := []PrioritySource{
+		PrioritySourcePriorityClassLabel,
+		PrioritySourcePodPriorityClass,
+		PrioritySourceLocalQueue,
+	}
+
 	testCases := map[string]struct {
 		original *Configuration
 		want     *Configuration
@@ -62,6 +70,7 @@ func TestSetDefaults_Configuration(t *testing.T) {
 				InternalCertManagement: &InternalCertManagement{
 					Enable: pointer.Bool(false),
 				},
+				PrioritySources: defaultPrioritySources,
 			},
 		},
 		"defaulting ControllerManagerConfigurationSpec": {
@@ -95,6 +104,7 @@ func TestSetDefaults_Configuration(t *testing.T) {
 				InternalCertManagement: &InternalCertManagement{
 					Enable: pointer.Bool(false),
 				},
+				PrioritySources: defaultPrioritySources,
 			},
 		},
 		"should not default ControllerManagerConfigurationSpec": {
@@ -138,6 +148,7 @@ func TestSetDefaults_Configuration(t *testing.T) {
 				InternalCertManagement: &InternalCertManagement{
 					Enable: pointer.Bool(false),
 				},
+				PrioritySources: defaultPrioritySources,
 			},
 		},
 		"should not set LeaderElectionID": {
@@ -170,6 +181,7 @@ func TestSetDefaults_Configuration(t *testing.T) {
 				InternalCertManagement: &InternalCertManagement{
 					Enable: pointer.Bool(false),
 				},
+				PrioritySources: defaultPrioritySources,
 			},
 		},
 		"defaulting InternalCertManagement": {
@@ -184,6 +196,7 @@ func TestSetDefaults_Configuration(t *testing.T) {
 					WebhookServiceName: pointer.String(DefaultWebhookServiceName),
 					WebhookSecretName:  pointer.String(DefaultWebhookSecretName),
 				},
+				PrioritySources: defaultPrioritySources,
 			},
 		},
 		"should not default InternalCertManagement": {
@@ -199,6 +212,324 @@ func TestSetDefaults_Configuration(t *testing.T) {
 				InternalCertManagement: &InternalCertManagement{
 					Enable: pointer.Bool(false),
 				},
+				PrioritySources: defaultPrioritySources,
+			},
+		},
+		"defaulting JobSuspendReconciliation": {
+			original: &Configuration{
+				Namespace: pointer.String(overwriteNamespace),
+				InternalCertManagement: &InternalCertManagement{
+					Enable: pointer.Bool(false),
+				},
+				JobSuspendReconciliation: &JobSuspendReconciliation{},
+			},
+			want: &Configuration{
+				Namespace:                          pointer.String(overwriteNamespace),
+				ControllerManagerConfigurationSpec: defaultCtrlManagerConfigurationSpec,
+				InternalCertManagement: &InternalCertManagement{
+					Enable: pointer.Bool(false),
+				},
+				JobSuspendReconciliation: &JobSuspendReconciliation{
+					Policy: JobSuspendPolicySuspend,
+				},
+				PrioritySources: defaultPrioritySources,
+			},
+		},
+		"should not default JobSuspendReconciliation": {
+			original: &Configuration{
+				Namespace: pointer.String(overwriteNamespace),
+				InternalCertManagement: &InternalCertManagement{
+					Enable: pointer.Bool(false),
+				},
+				JobSuspendReconciliation: &JobSuspendReconciliation{
+					Policy: JobSuspendPolicyReport,
+				},
+			},
+			want: &Configuration{
+				Namespace:                          pointer.String(overwriteNamespace),
+				ControllerManagerConfigurationSpec: defaultCtrlManagerConfigurationSpec,
+				InternalCertManagement: &InternalCertManagement{
+					Enable: pointer.Bool(false),
+				},
+				JobSuspendReconciliation: &JobSuspendReconciliation{
+					Policy: JobSuspendPolicyReport,
+				},
+				PrioritySources: defaultPrioritySources,
+			},
+		},
+		"should not default PrioritySources": {
+			original: &Configuration{
+				Namespace: pointer.String(overwriteNamespace),
+				InternalCertManagement: &InternalCertManagement{
+					Enable: pointer.Bool(false),
+				},
+				PrioritySources: []PrioritySource{PrioritySourceLocalQueue},
+			},
+			want: &Configuration{
+				Namespace:                          pointer.String(overwriteNamespace),
+				ControllerManagerConfigurationSpec: defaultCtrlManagerConfigurationSpec,
+				InternalCertManagement: &InternalCertManagement{
+					Enable: pointer.Bool(false),
+				},
+				PrioritySources: []PrioritySource{PrioritySourceLocalQueue},
+			},
+		},
+		"defaulting CohortRebalancing": {
+			original: &Configuration{
+				Namespace: pointer.String(overwriteNamespace),
+				InternalCertManagement: &InternalCertManagement{
+					Enable: pointer.Bool(false),
+				},
+				CohortRebalancing: &CohortRebalancing{
+					ImbalanceThreshold: pointer.Int32(50),
+				},
+			},
+			want: &Configuration{
+				Namespace:                          pointer.String(overwriteNamespace),
+				ControllerManagerConfigurationSpec: defaultCtrlManagerConfigurationSpec,
+				InternalCertManagement: &InternalCertManagement{
+					Enable: pointer.Bool(false),
+				},
+				PrioritySources: defaultPrioritySources,
+				CohortRebalancing: &CohortRebalancing{
+					Period:                &metav1.Duration{Duration: DefaultCohortRebalancingPeriod},
+					ImbalanceThreshold:    pointer.Int32(50),
+					SustainedPeriod:       &metav1.Duration{Duration: DefaultCohortRebalancingSustainedPeriod},
+					MaxEvictionsPerCohort: pointer.Int32(DefaultCohortRebalancingMaxEvictions),
+				},
+			},
+		},
+		"defaulting FlavorFungibility": {
+			original: &Configuration{
+				Namespace: pointer.String(overwriteNamespace),
+				InternalCertManagement: &InternalCertManagement{
+					Enable: pointer.Bool(false),
+				},
+				FlavorFungibility: &FlavorFungibility{
+					WhenCanPreempt: FlavorFungibilityPreempt,
+				},
+			},
+			want: &Configuration{
+				Namespace:                          pointer.String(overwriteNamespace),
+				ControllerManagerConfigurationSpec: defaultCtrlManagerConfigurationSpec,
+				InternalCertManagement: &InternalCertManagement{
+					Enable: pointer.Bool(false),
+				},
+				PrioritySources: defaultPrioritySources,
+				FlavorFungibility: &FlavorFungibility{
+					WhenCanBorrow:  FlavorFungibilityBorrow,
+					WhenCanPreempt: FlavorFungibilityPreempt,
+				},
+			},
+		},
+		"defaulting Preemption": {
+			original: &Configuration{
+				Namespace: pointer.String(overwriteNamespace),
+				InternalCertManagement: &InternalCertManagement{
+					Enable: pointer.Bool(false),
+				},
+				Preemption: &Preemption{},
+			},
+			want: &Configuration{
+				Namespace:                          pointer.String(overwriteNamespace),
+				ControllerManagerConfigurationSpec: defaultCtrlManagerConfigurationSpec,
+				InternalCertManagement: &InternalCertManagement{
+					Enable: pointer.Bool(false),
+				},
+				PrioritySources: defaultPrioritySources,
+				Preemption: &Preemption{
+					VictimSelection: VictimSelectionLowestPriorityFirst,
+				},
+			},
+		},
+		"defaulting Archival": {
+			original: &Configuration{
+				Namespace: pointer.String(overwriteNamespace),
+				InternalCertManagement: &InternalCertManagement{
+					Enable: pointer.Bool(false),
+				},
+				Archival: &Archival{
+					URL: "https://archive.example.com/workloads",
+				},
+			},
+			want: &Configuration{
+				Namespace:                          pointer.String(overwriteNamespace),
+				ControllerManagerConfigurationSpec: defaultCtrlManagerConfigurationSpec,
+				InternalCertManagement: &InternalCertManagement{
+					Enable: pointer.Bool(false),
+				},
+				PrioritySources: defaultPrioritySources,
+				Archival: &Archival{
+					URL:     "https://archive.example.com/workloads",
+					Timeout: &metav1.Duration{Duration: DefaultArchivalTimeout},
+				},
+			},
+		},
+		"defaulting CapacitySnapshots": {
+			original: &Configuration{
+				Namespace: pointer.String(overwriteNamespace),
+				InternalCertManagement: &InternalCertManagement{
+					Enable: pointer.Bool(false),
+				},
+				CapacitySnapshots: &CapacitySnapshots{
+					URL: "https://planning.example.com/snapshots",
+				},
+			},
+			want: &Configuration{
+				Namespace:                          pointer.String(overwriteNamespace),
+				ControllerManagerConfigurationSpec: defaultCtrlManagerConfigurationSpec,
+				InternalCertManagement: &InternalCertManagement{
+					Enable: pointer.Bool(false),
+				},
+				PrioritySources: defaultPrioritySources,
+				CapacitySnapshots: &CapacitySnapshots{
+					URL:      "https://planning.example.com/snapshots",
+					Interval: &metav1.Duration{Duration: DefaultCapacitySnapshotsInterval},
+					Timeout:  &metav1.Duration{Duration: DefaultCapacitySnapshotsTimeout},
+				},
+			},
+		},
+		"defaulting ExternalAdmission": {
+			original: &Configuration{
+				Namespace: pointer.String(overwriteNamespace),
+				InternalCertManagement: &InternalCertManagement{
+					Enable: pointer.Bool(false),
+				},
+				ExternalAdmission: &ExternalAdmission{
+					URL: "https://budget.example.com/review",
+				},
+			},
+			want: &Configuration{
+				Namespace:                          pointer.String(overwriteNamespace),
+				ControllerManagerConfigurationSpec: defaultCtrlManagerConfigurationSpec,
+				InternalCertManagement: &InternalCertManagement{
+					Enable: pointer.Bool(false),
+				},
+				PrioritySources: defaultPrioritySources,
+				ExternalAdmission: &ExternalAdmission{
+					URL:           "https://budget.example.com/review",
+					Timeout:       &metav1.Duration{Duration: DefaultExternalAdmissionTimeout},
+					FailurePolicy: ExternalAdmissionFail,
+				},
+			},
+		},
+		"defaulting ProvisioningRequests": {
+			original: &Configuration{
+				Namespace: pointer.String(overwriteNamespace),
+				InternalCertManagement: &InternalCertManagement{
+					Enable: pointer.Bool(false),
+				},
+				ProvisioningRequests: &ProvisioningRequests{},
+			},
+			want: &Configuration{
+				Namespace:                          pointer.String(overwriteNamespace),
+				ControllerManagerConfigurationSpec: defaultCtrlManagerConfigurationSpec,
+				InternalCertManagement: &InternalCertManagement{
+					Enable: pointer.Bool(false),
+				},
+				PrioritySources: defaultPrioritySources,
+				ProvisioningRequests: &ProvisioningRequests{
+					ProvisioningClassName: DefaultProvisioningClassName,
+					MaxRetries:            pointer.Int32(DefaultProvisioningMaxRetries),
+					RetryBackoff:          &metav1.Duration{Duration: DefaultProvisioningRetryBackoff},
+				},
+			},
+		},
+		"defaulting RequeueBackoff": {
+			original: &Configuration{
+				Namespace: pointer.String(overwriteNamespace),
+				InternalCertManagement: &InternalCertManagement{
+					Enable: pointer.Bool(false),
+				},
+				RequeueBackoff: &RequeueBackoff{
+					MaxDelay: &metav1.Duration{Duration: time.Minute},
+				},
+			},
+			want: &Configuration{
+				Namespace:                          pointer.String(overwriteNamespace),
+				ControllerManagerConfigurationSpec: defaultCtrlManagerConfigurationSpec,
+				InternalCertManagement: &InternalCertManagement{
+					Enable: pointer.Bool(false),
+				},
+				PrioritySources: defaultPrioritySources,
+				RequeueBackoff: &RequeueBackoff{
+					BaseDelay: &metav1.Duration{Duration: DefaultRequeueBackoffBaseDelay},
+					MaxDelay:  &metav1.Duration{Duration: time.Minute},
+				},
+			},
+		},
+		"defaulting WorkloadAging": {
+			original: &Configuration{
+				Namespace: pointer.String(overwriteNamespace),
+				InternalCertManagement: &InternalCertManagement{
+					Enable: pointer.Bool(false),
+				},
+				WorkloadAging: &WorkloadAging{
+					Rate: pointer.Int32(10),
+				},
+			},
+			want: &Configuration{
+				Namespace:                          pointer.String(overwriteNamespace),
+				ControllerManagerConfigurationSpec: defaultCtrlManagerConfigurationSpec,
+				InternalCertManagement: &InternalCertManagement{
+					Enable: pointer.Bool(false),
+				},
+				PrioritySources: defaultPrioritySources,
+				WorkloadAging: &WorkloadAging{
+					Rate: pointer.Int32(10),
+					Cap:  pointer.Int32(DefaultWorkloadAgingCap),
+				},
+			},
+		},
+		"defaulting Quarantine": {
+			original: &Configuration{
+				Namespace: pointer.String(overwriteNamespace),
+				InternalCertManagement: &InternalCertManagement{
+					Enable: pointer.Bool(false),
+				},
+				Quarantine: &Quarantine{
+					Threshold: pointer.Int32(3),
+				},
+			},
+			want: &Configuration{
+				Namespace:                          pointer.String(overwriteNamespace),
+				ControllerManagerConfigurationSpec: defaultCtrlManagerConfigurationSpec,
+				InternalCertManagement: &InternalCertManagement{
+					Enable: pointer.Bool(false),
+				},
+				PrioritySources: defaultPrioritySources,
+				Quarantine: &Quarantine{
+					Threshold:     pointer.Int32(3),
+					RetryInterval: &metav1.Duration{Duration: DefaultQuarantineRetryInterval},
+				},
+			},
+		},
+		"defaulting Resources": {
+			original: &Configuration{
+				Namespace: pointer.String(overwriteNamespace),
+				InternalCertManagement: &InternalCertManagement{
+					Enable: pointer.Bool(false),
+				},
+				Resources: &Resources{
+					Transformations: []ResourceTransformation{
+						{Input: "example.com/a"},
+						{Input: "example.com/b", Strategy: ResourceTransformationReplace},
+					},
+				},
+			},
+			want: &Configuration{
+				Namespace:                          pointer.String(overwriteNamespace),
+				ControllerManagerConfigurationSpec: defaultCtrlManagerConfigurationSpec,
+				InternalCertManagement: &InternalCertManagement{
+					Enable: pointer.Bool(false),
+				},
+				PrioritySources: defaultPrioritySources,
+				Resources: &Resources{
+					Transformations: []ResourceTransformation{
+						{Input: "example.com/a", Strategy: ResourceTransformationRetain},
+						{Input: "example.com/b", Strategy: ResourceTransformationReplace},
+					},
+				},
 			},
 		},
 	}
@@ -22,9 +22,91 @@ limitations under the License.
 package v1alpha2
 
 import (
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 )
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *Archival) DeepCopyInto(out *Archival) {
+	*out = *in
+	if in.Timeout != nil {
+		in, out := &in.Timeout, &out.Timeout
+		*out = new(v1.Duration)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new Archival.
+func (in *Archival) DeepCopy() *Archival {
+	if in == nil {
+		return nil
+	}
+	out := new(Archival)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *CapacitySnapshots) DeepCopyInto(out *CapacitySnapshots) {
+	*out = *in
+	if in.Interval != nil {
+		in, out := &in.Interval, &out.Interval
+		*out = new(v1.Duration)
+		**out = **in
+	}
+	if in.Timeout != nil {
+		in, out := &in.Timeout, &out.Timeout
+		*out = new(v1.Duration)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new CapacitySnapshots.
+func (in *CapacitySnapshots) DeepCopy() *CapacitySnapshots {
+	if in == nil {
+		return nil
+	}
+	out := new(CapacitySnapshots)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *CohortRebalancing) DeepCopyInto(out *CohortRebalancing) {
+	*out = *in
+	if in.Period != nil {
+		in, out := &in.Period, &out.Period
+		*out = new(v1.Duration)
+		**out = **in
+	}
+	if in.ImbalanceThreshold != nil {
+		in, out := &in.ImbalanceThreshold, &out.ImbalanceThreshold
+		*out = new(int32)
+		**out = **in
+	}
+	if in.SustainedPeriod != nil {
+		in, out := &in.SustainedPeriod, &out.SustainedPeriod
+		*out = new(v1.Duration)
+		**out = **in
+	}
+	if in.MaxEvictionsPerCohort != nil {
+		in, out := &in.MaxEvictionsPerCohort, &out.MaxEvictionsPerCohort
+		*out = new(int32)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new CohortRebalancing.
+func (in *CohortRebalancing) DeepCopy() *CohortRebalancing {
+	if in == nil {
+		return nil
+	}
+	out := new(CohortRebalancing)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *Configuration) DeepCopyInto(out *Configuration) {
 	*out = *in
@@ -35,6 +117,11 @@ func (in *Configuration) DeepCopyInto(out *Configuration) {
 		**out = **in
 	}
 	in.ControllerManagerConfigurationSpec.DeepCopyInto(&out.ControllerManagerConfigurationSpec)
+	if in.FlavorFungibility != nil {
+		in, out := &in.FlavorFungibility, &out.FlavorFungibility
+		*out = new(FlavorFungibility)
+		**out = **in
+	}
 	if in.InternalCertManagement != nil {
 		in, out := &in.InternalCertManagement, &out.InternalCertManagement
 		*out = new(InternalCertManagement)
@@ -43,8 +130,88 @@ func (in *Configuration) DeepCopyInto(out *Configuration) {
 	if in.WaitForPodsReady != nil {
 		in, out := &in.WaitForPodsReady, &out.WaitForPodsReady
 		*out = new(WaitForPodsReady)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.JobSuspendReconciliation != nil {
+		in, out := &in.JobSuspendReconciliation, &out.JobSuspendReconciliation
+		*out = new(JobSuspendReconciliation)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.PrioritySources != nil {
+		in, out := &in.PrioritySources, &out.PrioritySources
+		*out = make([]PrioritySource, len(*in))
+		copy(*out, *in)
+	}
+	if in.CohortRebalancing != nil {
+		in, out := &in.CohortRebalancing, &out.CohortRebalancing
+		*out = new(CohortRebalancing)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Preemption != nil {
+		in, out := &in.Preemption, &out.Preemption
+		*out = new(Preemption)
 		**out = **in
 	}
+	if in.Archival != nil {
+		in, out := &in.Archival, &out.Archival
+		*out = new(Archival)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.FairSharing != nil {
+		in, out := &in.FairSharing, &out.FairSharing
+		*out = new(FairSharing)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.RequeueBackoff != nil {
+		in, out := &in.RequeueBackoff, &out.RequeueBackoff
+		*out = new(RequeueBackoff)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.WorkloadAging != nil {
+		in, out := &in.WorkloadAging, &out.WorkloadAging
+		*out = new(WorkloadAging)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.MaxWorkloadEvictions != nil {
+		in, out := &in.MaxWorkloadEvictions, &out.MaxWorkloadEvictions
+		*out = new(int32)
+		**out = **in
+	}
+	if in.Quarantine != nil {
+		in, out := &in.Quarantine, &out.Quarantine
+		*out = new(Quarantine)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.MetricsCardinality != nil {
+		in, out := &in.MetricsCardinality, &out.MetricsCardinality
+		*out = new(MetricsCardinality)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.CapacitySnapshots != nil {
+		in, out := &in.CapacitySnapshots, &out.CapacitySnapshots
+		*out = new(CapacitySnapshots)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Resources != nil {
+		in, out := &in.Resources, &out.Resources
+		*out = new(Resources)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Integrations != nil {
+		in, out := &in.Integrations, &out.Integrations
+		*out = new(Integrations)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.ExternalAdmission != nil {
+		in, out := &in.ExternalAdmission, &out.ExternalAdmission
+		*out = new(ExternalAdmission)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.ProvisioningRequests != nil {
+		in, out := &in.ProvisioningRequests, &out.ProvisioningRequests
+		*out = new(ProvisioningRequests)
+		(*in).DeepCopyInto(*out)
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new Configuration.
@@ -65,6 +232,81 @@ func (in *Configuration) DeepCopyObject() runtime.Object {
 	return nil
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ExternalAdmission) DeepCopyInto(out *ExternalAdmission) {
+	*out = *in
+	if in.Timeout != nil {
+		in, out := &in.Timeout, &out.Timeout
+		*out = new(v1.Duration)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ExternalAdmission.
+func (in *ExternalAdmission) DeepCopy() *ExternalAdmission {
+	if in == nil {
+		return nil
+	}
+	out := new(ExternalAdmission)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *FairSharing) DeepCopyInto(out *FairSharing) {
+	*out = *in
+	if in.UsageHalfLifeTime != nil {
+		in, out := &in.UsageHalfLifeTime, &out.UsageHalfLifeTime
+		*out = new(v1.Duration)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new FairSharing.
+func (in *FairSharing) DeepCopy() *FairSharing {
+	if in == nil {
+		return nil
+	}
+	out := new(FairSharing)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *FlavorFungibility) DeepCopyInto(out *FlavorFungibility) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new FlavorFungibility.
+func (in *FlavorFungibility) DeepCopy() *FlavorFungibility {
+	if in == nil {
+		return nil
+	}
+	out := new(FlavorFungibility)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *Integrations) DeepCopyInto(out *Integrations) {
+	*out = *in
+	if in.Frameworks != nil {
+		in, out := &in.Frameworks, &out.Frameworks
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new Integrations.
+func (in *Integrations) DeepCopy() *Integrations {
+	if in == nil {
+		return nil
+	}
+	out := new(Integrations)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *InternalCertManagement) DeepCopyInto(out *InternalCertManagement) {
 	*out = *in
@@ -95,9 +337,202 @@ func (in *InternalCertManagement) DeepCopy() *InternalCertManagement {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *JobSuspendReconciliation) DeepCopyInto(out *JobSuspendReconciliation) {
+	*out = *in
+	if in.Period != nil {
+		in, out := &in.Period, &out.Period
+		*out = new(v1.Duration)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new JobSuspendReconciliation.
+func (in *JobSuspendReconciliation) DeepCopy() *JobSuspendReconciliation {
+	if in == nil {
+		return nil
+	}
+	out := new(JobSuspendReconciliation)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *MetricsCardinality) DeepCopyInto(out *MetricsCardinality) {
+	*out = *in
+	if in.MaxClusterQueues != nil {
+		in, out := &in.MaxClusterQueues, &out.MaxClusterQueues
+		*out = new(int32)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new MetricsCardinality.
+func (in *MetricsCardinality) DeepCopy() *MetricsCardinality {
+	if in == nil {
+		return nil
+	}
+	out := new(MetricsCardinality)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *Preemption) DeepCopyInto(out *Preemption) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new Preemption.
+func (in *Preemption) DeepCopy() *Preemption {
+	if in == nil {
+		return nil
+	}
+	out := new(Preemption)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ProvisioningRequests) DeepCopyInto(out *ProvisioningRequests) {
+	*out = *in
+	if in.Parameters != nil {
+		in, out := &in.Parameters, &out.Parameters
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	if in.MaxRetries != nil {
+		in, out := &in.MaxRetries, &out.MaxRetries
+		*out = new(int32)
+		**out = **in
+	}
+	if in.RetryBackoff != nil {
+		in, out := &in.RetryBackoff, &out.RetryBackoff
+		*out = new(v1.Duration)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ProvisioningRequests.
+func (in *ProvisioningRequests) DeepCopy() *ProvisioningRequests {
+	if in == nil {
+		return nil
+	}
+	out := new(ProvisioningRequests)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *Quarantine) DeepCopyInto(out *Quarantine) {
+	*out = *in
+	if in.Threshold != nil {
+		in, out := &in.Threshold, &out.Threshold
+		*out = new(int32)
+		**out = **in
+	}
+	if in.RetryInterval != nil {
+		in, out := &in.RetryInterval, &out.RetryInterval
+		*out = new(v1.Duration)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new Quarantine.
+func (in *Quarantine) DeepCopy() *Quarantine {
+	if in == nil {
+		return nil
+	}
+	out := new(Quarantine)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *RequeueBackoff) DeepCopyInto(out *RequeueBackoff) {
+	*out = *in
+	if in.BaseDelay != nil {
+		in, out := &in.BaseDelay, &out.BaseDelay
+		*out = new(v1.Duration)
+		**out = **in
+	}
+	if in.MaxDelay != nil {
+		in, out := &in.MaxDelay, &out.MaxDelay
+		*out = new(v1.Duration)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RequeueBackoff.
+func (in *RequeueBackoff) DeepCopy() *RequeueBackoff {
+	if in == nil {
+		return nil
+	}
+	out := new(RequeueBackoff)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ResourceTransformation) DeepCopyInto(out *ResourceTransformation) {
+	*out = *in
+	if in.Outputs != nil {
+		in, out := &in.Outputs, &out.Outputs
+		*out = make(corev1.ResourceList, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val.DeepCopy()
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ResourceTransformation.
+func (in *ResourceTransformation) DeepCopy() *ResourceTransformation {
+	if in == nil {
+		return nil
+	}
+	out := new(ResourceTransformation)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *Resources) DeepCopyInto(out *Resources) {
+	*out = *in
+	if in.PodOverhead != nil {
+		in, out := &in.PodOverhead, &out.PodOverhead
+		*out = make(corev1.ResourceList, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val.DeepCopy()
+		}
+	}
+	if in.Transformations != nil {
+		in, out := &in.Transformations, &out.Transformations
+		*out = make([]ResourceTransformation, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new Resources.
+func (in *Resources) DeepCopy() *Resources {
+	if in == nil {
+		return nil
+	}
+	out := new(Resources)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *WaitForPodsReady) DeepCopyInto(out *WaitForPodsReady) {
 	*out = *in
+	if in.Timeout != nil {
+		in, out := &in.Timeout, &out.Timeout
+		*out = new(v1.Duration)
+		**out = **in
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new WaitForPodsReady.
@@ -109,3 +544,28 @@ func (in *WaitForPodsReady) DeepCopy() *WaitForPodsReady {
 	in.DeepCopyInto(out)
 	return out
 }
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *WorkloadAging) DeepCopyInto(out *WorkloadAging) {
+	*out = *in
+	if in.Rate != nil {
+		in, out := &in.Rate, &out.Rate
+		*out = new(int32)
+		**out = **in
+	}
+	if in.Cap != nil {
+		in, out := &in.Cap, &out.Cap
+		*out = new(int32)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new WorkloadAging.
+func (in *WorkloadAging) DeepCopy() *WorkloadAging {
+	if in == nil {
+		return nil
+	}
+	out := new(WorkloadAging)
+	in.DeepCopyInto(out)
+	return out
+}
@@ -0,0 +1,99 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha2
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// AdmissionCheckSpec defines the desired state of AdmissionCheck
+type AdmissionCheckSpec struct {
+	// controllerName is the name of the controller that performs the check,
+	// such as kueue.x-k8s.io/provisioning-request. The controller reports the
+	// state of the check in the status of the Workloads and the readiness of
+	// the check in the Active condition of the AdmissionCheck.
+	// controllerName cannot be changed.
+	// +kubebuilder:validation:MaxLength=256
+	// +kubebuilder:validation:MinLength=1
+	// +kubebuilder:validation:XValidation:rule="self == oldSelf",message="controllerName is immutable"
+	ControllerName string `json:"controllerName"`
+
+	// parameters references an object with the configuration of the check,
+	// which is interpreted by its controller.
+	// +optional
+	Parameters *AdmissionCheckParametersReference `json:"parameters,omitempty"`
+}
+
+type AdmissionCheckParametersReference struct {
+	// apiGroup is the group of the object.
+	// +kubebuilder:validation:MaxLength=253
+	APIGroup string `json:"apiGroup"`
+
+	// kind is the kind of the object.
+	// +kubebuilder:validation:MaxLength=63
+	Kind string `json:"kind"`
+
+	// name is the name of the object.
+	// +kubebuilder:validation:MaxLength=63
+	Name string `json:"name"`
+}
+
+// AdmissionCheckStatus defines the observed state of AdmissionCheck
+type AdmissionCheckStatus struct {
+	// conditions hold the latest available observations of the
+	// AdmissionCheck current state. The controller of the check sets the
+	// Active condition once it's ready to perform the check.
+	// +optional
+	// +listType=map
+	// +listMapKey=type
+	Conditions []metav1.Condition `json:"conditions,omitempty"`
+}
+
+const (
+	// AdmissionCheckActive means that the controller of the AdmissionCheck
+	// is ready to perform the check. The ClusterQueues that use an inactive
+	// AdmissionCheck don't admit workloads.
+	AdmissionCheckActive = "Active"
+)
+
+//+kubebuilder:object:root=true
+//+kubebuilder:resource:scope=Cluster
+//+kubebuilder:subresource:status
+
+// AdmissionCheck is the Schema for the admissionchecks API. An
+// AdmissionCheck is a condition, other than the quota, that the workloads
+// of the ClusterQueues that list it must meet before they are admitted.
+type AdmissionCheck struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   AdmissionCheckSpec   `json:"spec,omitempty"`
+	Status AdmissionCheckStatus `json:"status,omitempty"`
+}
+
+//+kubebuilder:object:root=true
+
+// AdmissionCheckList contains a list of AdmissionCheck
+type AdmissionCheckList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []AdmissionCheck `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&AdmissionCheck{}, &AdmissionCheckList{})
+}
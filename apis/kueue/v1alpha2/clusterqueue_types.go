@@ -53,6 +53,40 @@ type ClusterQueueSpec struct {
 	// +kubebuilder:validation:MaxItems=16
 	Resources []Resource `json:"resources,omitempty"`
 
+	// resourceGroups describe groups of resources that must be assigned the
+	// same flavor, like the cpu, memory and gpus of a node type. Each flavor
+	// of a group defines the quota of the resources covered by the group.
+	// When a workload is admitted by this ClusterQueue, all the resources of
+	// a group that a pod set requests get assigned the same flavor. Example:
+	//
+	// - coveredResources: ["cpu", "memory"]
+	//   flavors:
+	//   - name: spot
+	//     resources:
+	//     - name: cpu
+	//       quota:
+	//         min: 18
+	//     - name: memory
+	//       quota:
+	//         min: 72Gi
+	//
+	// A resource can't be covered by more than one group or be listed in
+	// resources, and the flavors of a group can't be used by other groups or
+	// resources.
+	//
+	// resourceGroups can be up to 16 elements.
+	//
+	// +listType=atomic
+	// +kubebuilder:validation:MaxItems=16
+	// +optional
+	ResourceGroups []ResourceGroup `json:"resourceGroups,omitempty"`
+
+	// className is the name of the ClusterQueueClass of this ClusterQueue.
+	// The fields set in the class replace the same fields of this spec, and
+	// they are updated when the class changes.
+	// +optional
+	ClassName string `json:"className,omitempty"`
+
 	// cohort that this ClusterQueue belongs to. CQs that belong to the
 	// same cohort can borrow unused resources from each other.
 	//
@@ -121,6 +155,9 @@ type ClusterQueueSpec struct {
 	//
 	// The name style is similar to label keys. These are just names to link CQs
 	// together, and they are meaningless otherwise.
+	//
+	// +kubebuilder:validation:MaxLength=253
+	// +kubebuilder:validation:Pattern="^[a-z0-9]([-a-z0-9]*[a-z0-9])?([.][a-z0-9]([-a-z0-9]*[a-z0-9])?)*$"
 	Cohort string `json:"cohort,omitempty"`
 
 	// QueueingStrategy indicates the queueing strategy of the workloads
@@ -136,14 +173,288 @@ type ClusterQueueSpec struct {
 	//
 	// +kubebuilder:default=BestEffortFIFO
 	// +kubebuilder:validation:Enum=StrictFIFO;BestEffortFIFO
+	// +kubebuilder:validation:XValidation:rule="self == oldSelf",message="queueingStrategy is immutable"
 	QueueingStrategy QueueingStrategy `json:"queueingStrategy,omitempty"`
 
+	// backfill allows, when the queueingStrategy is StrictFIFO, admitting
+	// workloads behind a head that doesn't fit in the available quota, as long
+	// as they can't delay the estimated start of the head.
+	// The estimated start of the head is based on the expectedDuration of the
+	// workloads admitted by this ClusterQueue, and only workloads with an
+	// expectedDuration that finish before then are backfilled.
+	// Defaults to false.
+	Backfill bool `json:"backfill,omitempty"`
+
+	// headBlockingTimeout is, when the queueingStrategy is StrictFIFO, how
+	// long a workload that can't be admitted can stay at the head of the
+	// ClusterQueue, blocking the newer workloads. Once the timeout expires,
+	// the workload is moved to the inadmissible workloads, with a condition
+	// explaining why, and the next workload becomes the head. The workload is
+	// queued again when the quota available to the ClusterQueue changes.
+	// If not set, the head blocks the ClusterQueue until it's admitted.
+	// +optional
+	HeadBlockingTimeout *metav1.Duration `json:"headBlockingTimeout,omitempty"`
+
+	// orderingPolicy indicates how the pending workloads are ordered within
+	// the queueing strategy of this ClusterQueue. This field is immutable.
+	// Current Supported Policies:
+	//
+	// - Priority (default): workloads are ordered by priority, and then by
+	// creation time.
+	// - EarliestDeadlineFirst: workloads with a deadline are ordered first, by
+	// the latest time at which they can start to meet their deadline, that is,
+	// the deadline minus the expected duration. Workloads without a deadline
+	// are ordered after them, as with Priority.
+	//
+	// +kubebuilder:validation:Enum=Priority;EarliestDeadlineFirst
+	// +kubebuilder:validation:XValidation:rule="self == oldSelf",message="orderingPolicy is immutable"
+	OrderingPolicy OrderingPolicy `json:"orderingPolicy,omitempty"`
+
+	// localQueueFairness indicates how the pending workloads of the
+	// LocalQueues pointing to this ClusterQueue are interleaved, so that a
+	// LocalQueue with many pending workloads doesn't starve the others.
+	// Current Supported Policies:
+	//
+	// - None (default): the pending workloads are ordered regardless of their
+	// LocalQueue.
+	// - RoundRobin: the LocalQueues take turns, starting with the LocalQueue
+	// that had a workload popped least recently.
+	// - Usage: the workloads of the LocalQueues with fewer admitted workloads
+	// go first.
+	//
+	// The orderingPolicy orders the workloads of each LocalQueue, and the
+	// workloads of the LocalQueues in the same turn.
+	//
+	// +kubebuilder:validation:Enum=None;RoundRobin;Usage
+	LocalQueueFairness LocalQueueFairness `json:"localQueueFairness,omitempty"`
+
+	// flavorAssignmentPolicy indicates how a flavor is chosen for a resource
+	// when the workload fits in more than one of its flavors.
+	// Current Supported Policies:
+	//
+	// - FirstFit: the first flavor in which the workload fits, in the order
+	// of the flavors of the resource.
+	// - BestFit: the flavor that requires borrowing the least quota and, for
+	// the same borrowing, leaves the least unused min quota, to reduce the
+	// fragmentation of the quota.
+	// - LowestCost: the flavor with the lowest cost, declared in the
+	// kueue.x-k8s.io/cost-per-cpu-hour annotation of the ResourceFlavors.
+	// Flavors without a cost are preferred last.
+	//
+	// Defaults to the policy set in the Kueue configuration, which is
+	// FirstFit unless configured otherwise.
+	//
+	// +kubebuilder:validation:Enum=FirstFit;BestFit;LowestCost
+	FlavorAssignmentPolicy FlavorAssignmentPolicy `json:"flavorAssignmentPolicy,omitempty"`
+
+	// flavorFungibility indicates whether a workload should try the next
+	// flavor of a resource, in the order of the flavors, before borrowing or
+	// preempting in the flavor being evaluated.
+	// The fields that are not set default to the flavor fungibility set in
+	// the Kueue configuration, which is to borrow and to try the next flavor
+	// before preempting unless configured otherwise.
+	FlavorFungibility *FlavorFungibility `json:"flavorFungibility,omitempty"`
+
 	// namespaceSelector defines which namespaces are allowed to submit workloads to
 	// this clusterQueue. Beyond this basic support for policy, an policy agent like
 	// Gatekeeper should be used to enforce more advanced policies.
 	// Defaults to null which is a nothing selector (no namespaces eligible).
 	// If set to an empty selector `{}`, then all namespaces are eligible.
 	NamespaceSelector *metav1.LabelSelector `json:"namespaceSelector,omitempty"`
+
+	// preemption describes policies to preempt Workloads from this ClusterQueue
+	// or the ClusterQueue's cohort.
+	//
+	// Preemption can happen in two scenarios:
+	//
+	// - When a Workload fits within the min quota of the ClusterQueue, but the
+	//   quota is currently borrowed by other ClusterQueues in the cohort.
+	//   Preempting Workloads in other ClusterQueues allows this ClusterQueue to
+	//   reclaim its min quota.
+	// - When a Workload doesn't fit within the min quota of the ClusterQueue
+	//   and there are admitted Workloads in the ClusterQueue with lower priority.
+	//
+	// The preemption algorithm tries to find a minimal set of Workloads to
+	// preempt to accommodate the pending Workload, preempting Workloads with
+	// lower priority first.
+	Preemption *ClusterQueuePreemption `json:"preemption,omitempty"`
+
+	// fairSharing defines the properties of the ClusterQueue when competing
+	// with other ClusterQueues in the cohort for unused quota, if fair sharing
+	// is enabled in the Kueue configuration.
+	FairSharing *FairSharing `json:"fairSharing,omitempty"`
+
+	// priorityBands split the min quota of the ClusterQueue among ranges of
+	// workload priorities, in proportion to the weights of the bands. Example:
+	//
+	// - name: high
+	//   minPriority: 1000
+	//   weight: 7
+	// - name: low
+	//   minPriority: 0
+	//   weight: 3
+	//
+	// guarantees 70% of the quota to the workloads with priority 1000 or
+	// higher, and 30% to the rest. A band can use the quota that the other
+	// bands don't use, but a pending workload that fits in the guaranteed
+	// quota of its band can preempt the workloads of the bands that use more
+	// than their guaranteed quota, regardless of their priority.
+	// The pending workloads of the band with the lowest usage, relative to its
+	// guaranteed quota, go first.
+	//
+	// A workload belongs to the band with the highest minPriority that is not
+	// above the priority of the workload, or to the band with the lowest
+	// minPriority if there is none.
+	//
+	// priorityBands can be up to 8 elements.
+	// +listType=map
+	// +listMapKey=name
+	// +kubebuilder:validation:MaxItems=8
+	// +optional
+	PriorityBands []PriorityBand `json:"priorityBands,omitempty"`
+
+	// reservedTiers reserve a part of the min quota of the ClusterQueue for the
+	// workloads with a high priority, so that urgent workloads always find
+	// quota available. Example:
+	//
+	// - name: urgent
+	//   minPriority: 1000
+	//   percent: 20
+	//
+	// reserves 20% of the min quota of each flavor for the workloads with
+	// priority 1000 or higher. The workloads with a lower priority can't use
+	// the reserved quota while the workloads of the tier don't use it, even
+	// if it's borrowed from the cohort. The workloads of a tier can use the
+	// quota reserved for the tiers with a lower minPriority.
+	// The percents of all the tiers can't add up to more than 100.
+	//
+	// reservedTiers can be up to 8 elements.
+	// +listType=map
+	// +listMapKey=name
+	// +kubebuilder:validation:MaxItems=8
+	// +optional
+	ReservedTiers []ReservedTier `json:"reservedTiers,omitempty"`
+
+	// quotaWindows override the quota of some flavors of the ClusterQueue
+	// during recurring windows of time. For example:
+	//
+	// - name: off-hours
+	//   start: "20:00"
+	//   end: "06:00"
+	//   timeZone: Europe/Madrid
+	//   quotas:
+	//   - resource: cpu
+	//     flavor: default
+	//     quota:
+	//       min: 200
+	//
+	// raises the min quota of cpu in the default flavor to 200 every night.
+	// Outside of the active windows, the quotas of resources and
+	// resourceGroups apply. When more than one active window overrides the
+	// quota of the same resource and flavor, the one listed last wins.
+	// Only flavors already listed in resources or resourceGroups can be
+	// overridden. When a window starts or ends, the pending workloads are
+	// evaluated again.
+	//
+	// quotaWindows can be up to 16 elements.
+	// +listType=map
+	// +listMapKey=name
+	// +kubebuilder:validation:MaxItems=16
+	// +optional
+	QuotaWindows []QuotaWindow `json:"quotaWindows,omitempty"`
+
+	// budgets limit the accumulated usage of resources over a period of time,
+	// measured from the admission of the workloads until they finish. For
+	// example:
+	//
+	// - resource: nvidia.com/gpu
+	//   period: Month
+	//   limit: 10000
+	//
+	// allows the workloads of the ClusterQueue to use 10000 GPU-hours each
+	// month. Once the usage in the period reaches the limit, the ClusterQueue
+	// stops admitting workloads that request the resource, in the flavor of
+	// the budget if it sets one, until the next period starts. The admitted
+	// workloads keep running.
+	//
+	// budgets can be up to 16 elements.
+	// +listType=atomic
+	// +kubebuilder:validation:MaxItems=16
+	// +optional
+	Budgets []Budget `json:"budgets,omitempty"`
+
+	// flavorFallbacks restrict the workloads that are repeatedly evicted
+	// while admitted in a flavor to another flavor. For example:
+	//
+	// - flavor: spot
+	//   fallbackFlavor: on-demand
+	//   maxEvictions: 2
+	//   window: 1h
+	//
+	// admits a workload only in the on-demand flavor once it was evicted from
+	// the spot flavor more than 2 times within an hour. The evictions are
+	// recorded in the status of the Workload, so the fallback lasts until the
+	// Workload is deleted. The fallbackFlavor must be listed for the same
+	// resources as the flavor.
+	//
+	// flavorFallbacks can be up to 16 elements.
+	// +listType=map
+	// +listMapKey=flavor
+	// +kubebuilder:validation:MaxItems=16
+	// +optional
+	FlavorFallbacks []FlavorFallback `json:"flavorFallbacks,omitempty"`
+
+	// revocableBorrowing indicates if the workloads that this ClusterQueue
+	// admits borrowing quota from the cohort are revocable. When other
+	// ClusterQueues in the cohort need their min quota back, revocable
+	// workloads are preempted first, regardless of their priority and of the
+	// reclaimWithinCohort policy of the reclaiming ClusterQueue.
+	// Defaults to false.
+	// +optional
+	RevocableBorrowing bool `json:"revocableBorrowing,omitempty"`
+
+	// borrowWithinCohort restricts the workloads of this ClusterQueue that
+	// can borrow quota from the cohort.
+	// +optional
+	BorrowWithinCohort *BorrowWithinCohort `json:"borrowWithinCohort,omitempty"`
+
+	// admissionChecks are the names of the AdmissionChecks that the workloads
+	// of this ClusterQueue must pass, after their quota is reserved, before
+	// they are admitted and their jobs can start. The ClusterQueue is
+	// inactive while any of the AdmissionChecks doesn't exist or isn't
+	// active.
+	//
+	// admissionChecks can be up to 8 elements.
+	// +listType=set
+	// +kubebuilder:validation:MaxItems=8
+	// +optional
+	AdmissionChecks []string `json:"admissionChecks,omitempty"`
+
+	// maxWorkloadsPendingChecks is the maximum number of workloads of this
+	// ClusterQueue whose quota is reserved while they wait for their
+	// admissionChecks. Once it's reached, the pending workloads stay queued
+	// until the admission checks of a workload pass or the workload is
+	// evicted, so that the external systems behind the admission checks, like
+	// provisioners, aren't overwhelmed. If not set, there is no limit.
+	// +kubebuilder:validation:Minimum=1
+	// +optional
+	MaxWorkloadsPendingChecks *int32 `json:"maxWorkloadsPendingChecks,omitempty"`
+
+	// deletionPolicy indicates what happens to the admitted workloads when
+	// the ClusterQueue is deleted. The ClusterQueue stops admitting new
+	// workloads as soon as it's marked for deletion, and it's only removed
+	// once it has no admitted workloads.
+	// Current Supported Policies:
+	//
+	// - Block: the ClusterQueue waits for the admitted workloads to finish.
+	// - Drain: the ClusterQueue evicts the admitted workloads.
+	//
+	// The progress of the deletion is reported in the Active condition and in
+	// the admittedWorkloads of the status.
+	//
+	// +kubebuilder:default=Block
+	// +kubebuilder:validation:Enum=Block;Drain
+	DeletionPolicy DeletionPolicy `json:"deletionPolicy,omitempty"`
 }
 
 type QueueingStrategy string
@@ -160,8 +471,347 @@ const (
 	BestEffortFIFO QueueingStrategy = "BestEffortFIFO"
 )
 
+type OrderingPolicy string
+
+const (
+	// PriorityOrdering means that workloads are ordered by priority, and then
+	// by creation time.
+	PriorityOrdering OrderingPolicy = "Priority"
+
+	// EarliestDeadlineFirst means that workloads with a deadline are ordered
+	// first, by the latest time at which they can start to meet their deadline.
+	EarliestDeadlineFirst OrderingPolicy = "EarliestDeadlineFirst"
+)
+
+type LocalQueueFairness string
+
+const (
+	// LocalQueueFairnessNone means that the workloads are ordered regardless
+	// of their LocalQueue.
+	LocalQueueFairnessNone LocalQueueFairness = "None"
+
+	// RoundRobin means that the LocalQueues take turns, starting with the
+	// LocalQueue that had a workload popped least recently.
+	RoundRobin LocalQueueFairness = "RoundRobin"
+
+	// LocalQueueUsage means that the workloads of the LocalQueues with fewer
+	// admitted workloads go first.
+	LocalQueueUsage LocalQueueFairness = "Usage"
+)
+
+type FlavorAssignmentPolicy string
+
+const (
+	// FirstFit means that the first flavor in which the workload fits is
+	// assigned.
+	FirstFit FlavorAssignmentPolicy = "FirstFit"
+
+	// BestFit means that the flavor in which the workload fits that requires
+	// borrowing the least quota, and then leaves the least unused min quota,
+	// is assigned.
+	BestFit FlavorAssignmentPolicy = "BestFit"
+
+	// LowestCost means that the flavor in which the workload fits with the
+	// lowest cost, declared by the ResourceFlavors, is assigned.
+	LowestCost FlavorAssignmentPolicy = "LowestCost"
+)
+
+// FlavorFungibility determines whether a workload should try the next flavor
+// before borrowing or preempting in the flavor being evaluated.
+type FlavorFungibility struct {
+	// whenCanBorrow determines whether a workload should try the next flavor
+	// before borrowing in the current flavor. Possible values are:
+	//
+	// - `Borrow`: assign the current flavor, borrowing quota from the cohort.
+	// - `TryNextFlavor`: try the next flavor, and only borrow in the first
+	//   flavor that fits if no flavor fits without borrowing.
+	//
+	// +kubebuilder:validation:Enum=Borrow;TryNextFlavor
+	WhenCanBorrow FlavorFungibilityPolicy `json:"whenCanBorrow,omitempty"`
+
+	// whenCanPreempt determines whether a workload should try the next flavor
+	// before preempting in the current flavor. Possible values are:
+	//
+	// - `Preempt`: assign the current flavor, preempting workloads or
+	//   waiting for quota to be reclaimed if needed.
+	// - `TryNextFlavor`: try the next flavor, and only preempt in the flavor
+	//   where preemption is most likely to succeed if no flavor fits.
+	//
+	// +kubebuilder:validation:Enum=Preempt;TryNextFlavor
+	WhenCanPreempt FlavorFungibilityPolicy `json:"whenCanPreempt,omitempty"`
+
+	// whenNoFlavorFits determines what happens to a podSet that doesn't fit
+	// in any single flavor. Possible values are:
+	//
+	// - `Wait`: keep the workload pending until a flavor has enough quota
+	//   for all the pods of the podSet.
+	// - `SplitPodSet`: split the pods of the podSet between two flavors, for
+	//   example 6 pods in a spot flavor and 4 in an on-demand flavor, if
+	//   that makes the workload fit.
+	//
+	// +kubebuilder:validation:Enum=Wait;SplitPodSet
+	WhenNoFlavorFits FlavorFungibilityPolicy `json:"whenNoFlavorFits,omitempty"`
+}
+
+type FlavorFungibilityPolicy string
+
+const (
+	Borrow        FlavorFungibilityPolicy = "Borrow"
+	Preempt       FlavorFungibilityPolicy = "Preempt"
+	TryNextFlavor FlavorFungibilityPolicy = "TryNextFlavor"
+	Wait          FlavorFungibilityPolicy = "Wait"
+	SplitPodSet   FlavorFungibilityPolicy = "SplitPodSet"
+)
+
+type DeletionPolicy string
+
+const (
+	// BlockDeletion means that the deletion of the ClusterQueue waits for its
+	// admitted workloads to finish.
+	BlockDeletion DeletionPolicy = "Block"
+
+	// DrainDeletion means that the admitted workloads are evicted when the
+	// ClusterQueue is deleted.
+	DrainDeletion DeletionPolicy = "Drain"
+)
+
+type PreemptionPolicy string
+
+const (
+	PreemptionPolicyNever         PreemptionPolicy = "Never"
+	PreemptionPolicyAny           PreemptionPolicy = "Any"
+	PreemptionPolicyLowerPriority PreemptionPolicy = "LowerPriority"
+)
+
+// ClusterQueuePreemption contains policies to preempt Workloads from this
+// ClusterQueue or the ClusterQueue's cohort.
+type ClusterQueuePreemption struct {
+	// reclaimWithinCohort determines whether a pending Workload can preempt
+	// Workloads from other ClusterQueues in the cohort that are using more than
+	// their min quota. Possible values are:
+	//
+	// - `Never` (default): do not preempt workloads in the cohort.
+	// - `LowerPriority`: if the pending workload fits within the min
+	//   quota of its ClusterQueue, only preempt workloads in the cohort that have
+	//   lower priority than the pending Workload.
+	// - `Any`: if the pending workload fits within the min quota of its
+	//   ClusterQueue, preempt any workload in the cohort, irrespective of
+	//   priority.
+	//
+	// +kubebuilder:default=Never
+	// +kubebuilder:validation:Enum=Never;LowerPriority;Any
+	ReclaimWithinCohort PreemptionPolicy `json:"reclaimWithinCohort,omitempty"`
+
+	// withinClusterQueue determines whether a pending workload that doesn't fit
+	// within the min quota for its ClusterQueue, can preempt active Workloads in
+	// the ClusterQueue. Possible values are:
+	//
+	// - `Never` (default): do not preempt workloads in the ClusterQueue.
+	// - `LowerPriority`: only preempt workloads in the ClusterQueue that have
+	//   lower priority than the pending Workload.
+	//
+	// +kubebuilder:default=Never
+	// +kubebuilder:validation:Enum=Never;LowerPriority
+	WithinClusterQueue PreemptionPolicy `json:"withinClusterQueue,omitempty"`
+
+	// maxVictims is the maximum number of Workloads that a single pending
+	// Workload in this ClusterQueue can preempt. If the preemption requires
+	// more victims, they are only reported, in an event of the pending
+	// Workload, and the pending Workload stays pending.
+	// If not set, the number of victims is not limited.
+	//
+	// +optional
+	// +kubebuilder:validation:Minimum=0
+	MaxVictims *int32 `json:"maxVictims,omitempty"`
+}
+
+// BorrowWithinCohort contains the restrictions on the workloads that can
+// borrow quota from the cohort.
+type BorrowWithinCohort struct {
+	// minPriority is the lowest priority of the workloads that can borrow
+	// quota from the cohort. Workloads with a lower priority are only
+	// admitted within the min quota of the ClusterQueue, and can only
+	// preempt other workloads to fit within it.
+	// When not set, workloads of any priority can borrow.
+	// +optional
+	MinPriority *int32 `json:"minPriority,omitempty"`
+}
+
+// FairSharing contains the properties of the ClusterQueue when participating
+// in fair sharing.
+type FairSharing struct {
+	// weight gives a comparative advantage to this ClusterQueue when competing
+	// for unused resources in the cohort against other ClusterQueues.
+	// The dominant resource share of the ClusterQueue is divided by its weight,
+	// so a ClusterQueue with weight 2 can use twice as much of the cohort
+	// resources as a ClusterQueue with weight 1 before its workloads are
+	// considered after theirs.
+	// A weight of 0 means that the ClusterQueue only gets unused quota after
+	// every other ClusterQueue in the cohort.
+	// Defaults to 1.
+	// +kubebuilder:default=1
+	Weight *resource.Quantity `json:"weight,omitempty"`
+}
+
+// PriorityBand is a range of workload priorities that is guaranteed a share
+// of the quota of the ClusterQueue.
+type PriorityBand struct {
+	// name of the band.
+	Name string `json:"name"`
+
+	// minPriority is the lowest priority of the workloads in the band. The
+	// band covers the priorities up to the minPriority of the next band.
+	MinPriority int32 `json:"minPriority"`
+
+	// weight of the band. The band is guaranteed its weight divided by the
+	// sum of the weights of the bands, of the min quota of each flavor.
+	// Defaults to 1.
+	// +kubebuilder:default=1
+	// +kubebuilder:validation:Minimum=1
+	Weight int32 `json:"weight,omitempty"`
+}
+
+// ReservedTier is a part of the quota of the ClusterQueue that is reserved
+// for the workloads with a minimum priority.
+type ReservedTier struct {
+	// name of the tier.
+	Name string `json:"name"`
+
+	// minPriority is the lowest priority of the workloads that can use the
+	// quota reserved for the tier.
+	MinPriority int32 `json:"minPriority"`
+
+	// percent of the min quota of each flavor that is reserved for the tier.
+	// +kubebuilder:validation:Minimum=1
+	// +kubebuilder:validation:Maximum=100
+	Percent int32 `json:"percent"`
+}
+
+// Budget is a limit on the accumulated usage of a resource over a period of
+// time.
+type Budget struct {
+	// resource is the name of the resource.
+	Resource corev1.ResourceName `json:"resource"`
+
+	// flavor limits the budget to the usage of the resource in the flavor.
+	// Empty means the usage in all the flavors.
+	// +optional
+	Flavor ResourceFlavorReference `json:"flavor,omitempty"`
+
+	// period is the period of time over which the usage is accumulated.
+	// Periods start at midnight UTC, and weeks start on Monday.
+	// +kubebuilder:validation:Enum=Day;Week;Month
+	Period BudgetPeriod `json:"period"`
+
+	// limit is the usage allowed in a period, in resource-hours: the
+	// quantity of the resource multiplied by the hours it's used. CPU is
+	// measured in cores and any other resource in its base unit.
+	Limit resource.Quantity `json:"limit"`
+}
+
+// FlavorFallback is a flavor that the workloads evicted too often from
+// another flavor are restricted to.
+type FlavorFallback struct {
+	// flavor is the name of the flavor the workloads are evicted from.
+	Flavor ResourceFlavorReference `json:"flavor"`
+
+	// fallbackFlavor is the name of the flavor the workloads are admitted in
+	// once they exceed maxEvictions.
+	FallbackFlavor ResourceFlavorReference `json:"fallbackFlavor"`
+
+	// maxEvictions is the number of evictions from the flavor that a workload
+	// tolerates within the window.
+	// +kubebuilder:validation:Minimum=1
+	// +kubebuilder:validation:Maximum=15
+	MaxEvictions int32 `json:"maxEvictions"`
+
+	// window is the period of time in which the evictions are counted.
+	Window metav1.Duration `json:"window"`
+}
+
+type BudgetPeriod string
+
+const (
+	BudgetPeriodDay   BudgetPeriod = "Day"
+	BudgetPeriodWeek  BudgetPeriod = "Week"
+	BudgetPeriodMonth BudgetPeriod = "Month"
+)
+
+// BudgetStatus is the usage of a budget in its current period.
+type BudgetStatus struct {
+	// resource is the name of the resource of the budget.
+	Resource corev1.ResourceName `json:"resource"`
+
+	// flavor is the flavor of the budget, empty for all the flavors.
+	// +optional
+	Flavor ResourceFlavorReference `json:"flavor,omitempty"`
+
+	// period is the period of the budget.
+	Period BudgetPeriod `json:"period"`
+
+	// periodStart is the start of the current period.
+	PeriodStart metav1.Time `json:"periodStart"`
+
+	// used is the usage in the current period, in resource-hours, including
+	// the usage of the workloads that are still running.
+	Used resource.Quantity `json:"used"`
+
+	// remaining is the usage left in the current period, in resource-hours.
+	Remaining resource.Quantity `json:"remaining"`
+}
+
+// QuotaWindow is a recurring window of time during which the ClusterQueue
+// has different quotas.
+type QuotaWindow struct {
+	// name of the window.
+	Name string `json:"name"`
+
+	// days of the week in which the window starts. Empty means every day.
+	// +kubebuilder:validation:MaxItems=7
+	// +optional
+	Days []Weekday `json:"days,omitempty"`
+
+	// start is the time of the day, in the HH:MM format, at which the window
+	// starts.
+	// +kubebuilder:validation:Pattern=`^([01][0-9]|2[0-3]):[0-5][0-9]$`
+	Start string `json:"start"`
+
+	// end is the time of the day, in the HH:MM format, at which the window
+	// ends. If it's not after start, the window ends the next day.
+	// +kubebuilder:validation:Pattern=`^([01][0-9]|2[0-3]):[0-5][0-9]$`
+	End string `json:"end"`
+
+	// timeZone is the IANA name of the time zone of start and end.
+	// Defaults to UTC.
+	// +optional
+	TimeZone string `json:"timeZone,omitempty"`
+
+	// quotas are the quotas that apply during the window.
+	// +kubebuilder:validation:MinItems=1
+	// +kubebuilder:validation:MaxItems=16
+	Quotas []WindowQuota `json:"quotas"`
+}
+
+// +kubebuilder:validation:Enum=Monday;Tuesday;Wednesday;Thursday;Friday;Saturday;Sunday
+type Weekday string
+
+// WindowQuota is the quota of a resource in a flavor during a QuotaWindow.
+type WindowQuota struct {
+	// resource is the name of the resource.
+	Resource corev1.ResourceName `json:"resource"`
+
+	// flavor is the name of the flavor.
+	Flavor ResourceFlavorReference `json:"flavor"`
+
+	// quota replaces the quota of the resource in the flavor.
+	Quota Quota `json:"quota"`
+}
+
 type Resource struct {
 	// name of the resource. For example, cpu, memory or nvidia.com/gpu.
+	//
+	// +kubebuilder:validation:MaxLength=317
+	// +kubebuilder:validation:Pattern="^([a-z0-9]([-a-z0-9]*[a-z0-9])?([.][a-z0-9]([-a-z0-9]*[a-z0-9])?)*/)?([A-Za-z0-9][-A-Za-z0-9_.]*)?[A-Za-z0-9]$"
 	Name corev1.ResourceName `json:"name"`
 
 	// flavors is the list of different flavors of this resource and their limits.
@@ -201,6 +851,70 @@ type Resource struct {
 	Flavors []Flavor `json:"flavors"`
 }
 
+type ResourceGroup struct {
+	// coveredResources is the list of resources covered by the flavors in
+	// this group. For example, cpu, memory or nvidia.com/gpu.
+	//
+	// coveredResources can be up to 16 elements.
+	//
+	// +listType=set
+	// +kubebuilder:validation:MaxItems=16
+	// +kubebuilder:validation:MinItems=1
+	CoveredResources []corev1.ResourceName `json:"coveredResources"`
+
+	// flavors is the list of flavors that provide the resources of this
+	// group, evaluated in order as the flavors of a resource. Each flavor must
+	// define the quota of all the coveredResources.
+	//
+	// flavors can be up to 16 elements.
+	//
+	// +listType=map
+	// +listMapKey=name
+	// +kubebuilder:validation:MaxItems=16
+	// +kubebuilder:validation:MinItems=1
+	Flavors []FlavorQuotas `json:"flavors"`
+}
+
+type FlavorQuotas struct {
+	// name is a reference to the resourceFlavor that defines this flavor.
+	Name ResourceFlavorReference `json:"name"`
+
+	// resources is the list of quotas of the covered resources in this
+	// flavor.
+	//
+	// +listType=map
+	// +listMapKey=name
+	// +kubebuilder:validation:MaxItems=16
+	// +kubebuilder:validation:MinItems=1
+	Resources []ResourceQuota `json:"resources"`
+
+	// hold, when true, stops assigning this flavor to new workloads, as the
+	// hold of the flavor of a resource.
+	// +optional
+	Hold bool `json:"hold,omitempty"`
+
+	// admissionChecks are the names of the AdmissionChecks that the workloads
+	// assigned this flavor must pass, as the admissionChecks of the flavor of
+	// a resource.
+	//
+	// admissionChecks can be up to 8 elements.
+	// +listType=set
+	// +kubebuilder:validation:MaxItems=8
+	// +optional
+	AdmissionChecks []string `json:"admissionChecks,omitempty"`
+}
+
+type ResourceQuota struct {
+	// name of the resource.
+	//
+	// +kubebuilder:validation:MaxLength=317
+	// +kubebuilder:validation:Pattern="^([a-z0-9]([-a-z0-9]*[a-z0-9])?([.][a-z0-9]([-a-z0-9]*[a-z0-9])?)*/)?([A-Za-z0-9][-A-Za-z0-9_.]*)?[A-Za-z0-9]$"
+	Name corev1.ResourceName `json:"name"`
+
+	// quota is the limit of resource usage at a point in time.
+	Quota Quota `json:"quota"`
+}
+
 type Flavor struct {
 	// name is a reference to the resourceFlavor that defines this flavor.
 	// +kubebuilder:default=default
@@ -208,6 +922,23 @@ type Flavor struct {
 
 	// quota is the limit of resource usage at a point in time.
 	Quota Quota `json:"quota"`
+
+	// hold, when true, stops assigning this flavor to new workloads, for
+	// example while its nodes are drained for maintenance. The workloads
+	// already admitted with the flavor keep running and using its quota.
+	// +optional
+	Hold bool `json:"hold,omitempty"`
+
+	// admissionChecks are the names of the AdmissionChecks that the workloads
+	// assigned this flavor must pass, besides the admissionChecks of the
+	// ClusterQueue. For example, a check that provisions capacity only for
+	// an autoscaled flavor.
+	//
+	// admissionChecks can be up to 8 elements.
+	// +listType=set
+	// +kubebuilder:validation:MaxItems=8
+	// +optional
+	AdmissionChecks []string `json:"admissionChecks,omitempty"`
 }
 
 // ResourceFlavorReference is the name of the ResourceFlavor.
@@ -247,6 +978,24 @@ type ClusterQueueStatus struct {
 	// +optional
 	AdmittedWorkloads int32 `json:"admittedWorkloads"`
 
+	// quotaSharing shows how the min quota of this clusterQueue is shared with
+	// the other members of its cohort. When several clusterQueues have unused
+	// quota, the quantity borrowed by a member is attributed to them in
+	// proportion to their unused quota.
+	// +optional
+	QuotaSharing *QuotaSharing `json:"quotaSharing,omitempty"`
+
+	// effectivePolicies are the scheduling policies that this clusterQueue
+	// uses, after applying the defaults of the API and of the Kueue
+	// configuration to its spec.
+	// +optional
+	EffectivePolicies *ClusterQueuePolicies `json:"effectivePolicies,omitempty"`
+
+	// budgets report the usage of the budgets of the clusterQueue in their
+	// current period.
+	// +optional
+	Budgets []BudgetStatus `json:"budgets,omitempty"`
+
 	// conditions hold the latest available observations of the ClusterQueue
 	// current state.
 	// +optional
@@ -261,6 +1010,14 @@ const (
 	// ClusterQueueActive indicates that the ClusterQueue can admit new workloads and its quota
 	// can be borrowed by other ClusterQueues in the same cohort.
 	ClusterQueueActive string = "Active"
+
+	// ClusterQueueDegraded indicates that some flavors of the ClusterQueue
+	// are being deleted, so they aren't assigned to new workloads.
+	ClusterQueueDegraded string = "Degraded"
+
+	// ClusterQueueInvalidCohort indicates that the tree of cohorts of the
+	// ClusterQueue has a cycle or an orphan parent.
+	ClusterQueueInvalidCohort string = "InvalidCohort"
 )
 
 type Usage struct {
@@ -272,6 +1029,55 @@ type Usage struct {
 	Borrowed *resource.Quantity `json:"borrowing,omitempty"`
 }
 
+// ClusterQueuePolicies are the effective scheduling policies of a
+// ClusterQueue.
+type ClusterQueuePolicies struct {
+	// queueingStrategy is the queueing strategy of the workloads.
+	QueueingStrategy QueueingStrategy `json:"queueingStrategy"`
+
+	// orderingPolicy is how the pending workloads are ordered within the
+	// queueing strategy.
+	OrderingPolicy OrderingPolicy `json:"orderingPolicy"`
+
+	// localQueueFairness is how the pending workloads of the LocalQueues are
+	// interleaved.
+	LocalQueueFairness LocalQueueFairness `json:"localQueueFairness"`
+
+	// flavorAssignmentPolicy is how a flavor is chosen for a resource.
+	FlavorAssignmentPolicy FlavorAssignmentPolicy `json:"flavorAssignmentPolicy"`
+
+	// flavorFungibility is whether a workload tries the next flavor before
+	// borrowing or preempting.
+	FlavorFungibility FlavorFungibility `json:"flavorFungibility"`
+
+	// preemption are the policies to preempt workloads.
+	Preemption ClusterQueuePreemption `json:"preemption"`
+}
+
+type QuotaSharing struct {
+	// lentTo is the unused quota of this clusterQueue currently lent to each
+	// member of the cohort.
+	// +listType=map
+	// +listMapKey=clusterQueue
+	// +optional
+	LentTo []SharedQuota `json:"lentTo,omitempty"`
+
+	// borrowedFrom is the quota that this clusterQueue is currently borrowing
+	// from each member of the cohort.
+	// +listType=map
+	// +listMapKey=clusterQueue
+	// +optional
+	BorrowedFrom []SharedQuota `json:"borrowedFrom,omitempty"`
+}
+
+type SharedQuota struct {
+	// clusterQueue is the name of the cohort member.
+	ClusterQueue string `json:"clusterQueue"`
+
+	// resources are the shared quantities, by resource and flavor.
+	Resources map[corev1.ResourceName]map[string]resource.Quantity `json:"resources"`
+}
+
 //+kubebuilder:object:root=true
 //+kubebuilder:resource:scope=Cluster,shortName={cq}
 //+kubebuilder:subresource:status
@@ -0,0 +1,96 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha2
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// ClusterQueueClassSpec defines the settings shared by the ClusterQueues of
+// a class. Each field that is set replaces the same field in the spec of the
+// ClusterQueues that reference the class; the fields that are not set are
+// left to each ClusterQueue.
+type ClusterQueueClassSpec struct {
+	// resources are the quotas, by resource and flavor, of the ClusterQueues
+	// of the class. Together with resourceGroups, they replace the
+	// resources and resourceGroups of the ClusterQueues, if any of them is
+	// set.
+	// +listType=map
+	// +listMapKey=name
+	// +kubebuilder:validation:MaxItems=16
+	// +optional
+	Resources []Resource `json:"resources,omitempty"`
+
+	// resourceGroups are the groups of resources that share flavors in the
+	// ClusterQueues of the class.
+	// +listType=atomic
+	// +kubebuilder:validation:MaxItems=16
+	// +optional
+	ResourceGroups []ResourceGroup `json:"resourceGroups,omitempty"`
+
+	// cohort is the cohort of the ClusterQueues of the class.
+	// +optional
+	Cohort string `json:"cohort,omitempty"`
+
+	// namespaceSelector selects the namespaces allowed to submit workloads to
+	// the ClusterQueues of the class.
+	// +optional
+	NamespaceSelector *metav1.LabelSelector `json:"namespaceSelector,omitempty"`
+
+	// preemption describes the policies to preempt Workloads from the
+	// ClusterQueues of the class or their cohort.
+	// +optional
+	Preemption *ClusterQueuePreemption `json:"preemption,omitempty"`
+}
+
+// ClusterQueueClassStatus defines the observed state of a ClusterQueueClass
+type ClusterQueueClassStatus struct {
+	// clusterQueues are the names of the ClusterQueues that reference the
+	// class.
+	// +listType=set
+	// +optional
+	ClusterQueues []string `json:"clusterQueues,omitempty"`
+}
+
+//+kubebuilder:object:root=true
+//+kubebuilder:resource:scope=Cluster
+//+kubebuilder:subresource:status
+
+// ClusterQueueClass is the Schema for the clusterqueueclasses API. It's a
+// template of the settings shared by many ClusterQueues, which reference it
+// in their .spec.className field. The changes to the class are propagated to
+// its ClusterQueues.
+type ClusterQueueClass struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   ClusterQueueClassSpec   `json:"spec,omitempty"`
+	Status ClusterQueueClassStatus `json:"status,omitempty"`
+}
+
+//+kubebuilder:object:root=true
+
+// ClusterQueueClassList contains a list of ClusterQueueClass
+type ClusterQueueClassList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []ClusterQueueClass `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&ClusterQueueClass{}, &ClusterQueueClassList{})
+}
@@ -0,0 +1,113 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha2
+
+import (
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// CohortSpec defines the desired state of a cohort
+type CohortSpec struct {
+	// parent is the name of the parent cohort. Cohorts form a tree in which
+	// the ClusterQueues can borrow the unused quota of any other ClusterQueue
+	// or cohort under the same root cohort.
+	// A parent that would create a cycle is ignored.
+	// +optional
+	Parent string `json:"parent,omitempty"`
+
+	// resources are the quotas, by resource and flavor, that the cohort adds
+	// to its tree and the limits of the usage of the cohort, including its
+	// descendant cohorts.
+	// +listType=map
+	// +listMapKey=name
+	// +kubebuilder:validation:MaxItems=16
+	// +optional
+	Resources []CohortResource `json:"resources,omitempty"`
+}
+
+type CohortResource struct {
+	// name of the resource. For example, cpu, memory or nvidia.com/gpu.
+	Name corev1.ResourceName `json:"name"`
+
+	// flavors are the quotas of the resource, by flavor.
+	// +listType=map
+	// +listMapKey=name
+	// +kubebuilder:validation:MaxItems=16
+	// +kubebuilder:validation:MinItems=1
+	Flavors []CohortFlavor `json:"flavors"`
+}
+
+type CohortFlavor struct {
+	// name is a reference to the resourceFlavor of the quota.
+	Name ResourceFlavorReference `json:"name"`
+
+	// quota of the flavor. min is added to the quota that the ClusterQueues
+	// in the tree can borrow, and max, if not null, limits the usage of the
+	// ClusterQueues in the cohort and its descendant cohorts.
+	Quota Quota `json:"quota"`
+}
+
+// CohortStatus defines the observed state of a cohort
+type CohortStatus struct {
+	// clusterQueues are the names of the ClusterQueues that belong to the
+	// cohort or to its descendant cohorts.
+	// +listType=set
+	// +optional
+	ClusterQueues []string `json:"clusterQueues,omitempty"`
+
+	// quota is the sum of the min quotas (by resource and flavor) of the
+	// active ClusterQueues in the cohort and of the cohort itself, including
+	// its descendant cohorts.
+	// +optional
+	Quota map[corev1.ResourceName]map[string]resource.Quantity `json:"quota,omitempty"`
+
+	// usage is the sum of the resources (by resource and flavor) used by the
+	// workloads admitted by the ClusterQueues in the cohort, including its
+	// descendant cohorts.
+	// +optional
+	Usage map[corev1.ResourceName]map[string]resource.Quantity `json:"usage,omitempty"`
+}
+
+//+kubebuilder:object:root=true
+//+kubebuilder:resource:scope=Cluster
+//+kubebuilder:subresource:status
+
+// Cohort is the Schema for the cohorts API. A Cohort object is optional; it
+// configures the cohort with the same name, as referenced by the .spec.cohort
+// field of the ClusterQueues, and reports its status.
+type Cohort struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   CohortSpec   `json:"spec,omitempty"`
+	Status CohortStatus `json:"status,omitempty"`
+}
+
+//+kubebuilder:object:root=true
+
+// CohortList contains a list of Cohort
+type CohortList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []Cohort `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&Cohort{}, &CohortList{})
+}
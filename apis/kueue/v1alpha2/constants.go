@@ -16,8 +16,15 @@ limitations under the License.
 
 package v1alpha2
 
+import corev1 "k8s.io/api/core/v1"
+
 const (
 	ResourceInUseFinalizerName = "kueue.k8s.io/resource-in-use"
 
 	DefaultPodSetName = "main"
+
+	// ResourceWorkloads is the resource that counts the workloads admitted
+	// by a ClusterQueue. Like "pods", which counts the pods of the admitted
+	// workloads, it's only requested when the ClusterQueue defines its quota.
+	ResourceWorkloads corev1.ResourceName = "kueue.x-k8s.io/workloads"
 )
@@ -17,6 +17,7 @@ limitations under the License.
 package v1alpha2
 
 import (
+	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
 
@@ -24,11 +25,63 @@ import (
 type LocalQueueSpec struct {
 	// clusterQueue is a reference to a clusterQueue that backs this localQueue.
 	ClusterQueue ClusterQueueReference `json:"clusterQueue,omitempty"`
+
+	// limits are optional hard caps on the total amount of resources, summed
+	// across all flavors, that the admitted workloads from this localQueue can
+	// use at a time. Workloads that would exceed the limits are not admitted,
+	// even if the clusterQueue has available quota.
+	// +optional
+	Limits corev1.ResourceList `json:"limits,omitempty"`
+
+	// limitsPercent are optional hard caps, like limits, given as a
+	// percentage of the quota of the clusterQueue for the resource, summed
+	// across all flavors. They keep a single localQueue from using all the
+	// quota of a clusterQueue shared with other namespaces, and follow the
+	// changes of the quota. When a resource has both a limit and a
+	// percentage, the lowest applies.
+	// +optional
+	LimitsPercent map[corev1.ResourceName]int32 `json:"limitsPercent,omitempty"`
+
+	// tolerations are added to the podSets of the workloads created in this
+	// localQueue, unless the podSets already have equivalent tolerations.
+	// They are applied when the workload is created, so changing them doesn't
+	// affect existing workloads.
+	// +optional
+	Tolerations []corev1.Toleration `json:"tolerations,omitempty"`
+
+	// priorityClassName is the name of the PriorityClass used for the
+	// workloads of the Jobs in this localQueue that don't get a
+	// PriorityClass from a source with higher precedence, as configured in
+	// the kueue manager.
+	// +optional
+	PriorityClassName string `json:"priorityClassName,omitempty"`
+
+	// priorityClassRouting routes the workloads of this localQueue to other
+	// clusterQueues based on their priorityClassName, for example, to send
+	// high priority workloads to a clusterQueue with on-demand capacity and
+	// low priority workloads to a clusterQueue with spot capacity. Workloads
+	// with a priorityClassName without a route are queued in clusterQueue.
+	// priorityClassRouting cannot be changed.
+	// +listType=map
+	// +listMapKey=priorityClassName
+	// +kubebuilder:validation:MaxItems=16
+	// +optional
+	PriorityClassRouting []PriorityClassRoute `json:"priorityClassRouting,omitempty"`
 }
 
 // ClusterQueueReference is the name of the ClusterQueue.
 type ClusterQueueReference string
 
+type PriorityClassRoute struct {
+	// priorityClassName is the name of the PriorityClass of the routed
+	// workloads.
+	PriorityClassName string `json:"priorityClassName"`
+
+	// clusterQueue is a reference to the clusterQueue in which the routed
+	// workloads are queued.
+	ClusterQueue ClusterQueueReference `json:"clusterQueue"`
+}
+
 // LocalQueueStatus defines the observed state of LocalQueue
 type LocalQueueStatus struct {
 	// PendingWorkloads is the number of Workloads in the LocalQueue not yet admitted to a ClusterQueue
@@ -45,7 +45,34 @@ type ResourceFlavor struct {
 	//
 	// +listType=atomic
 	// +kubebuilder:validation:MaxItems=8
+	// +kubebuilder:validation:XValidation:rule="self.all(t, t.effect in ['NoSchedule', 'PreferNoSchedule', 'NoExecute'])",message="the effect of the taints must be NoSchedule, PreferNoSchedule or NoExecute"
 	Taints []corev1.Taint `json:"taints,omitempty"`
+
+	// sharedResources are the resources that the nodes of this flavor share
+	// between several pods, such as GPUs with time-slicing or MPS, where the
+	// device plugin advertises each device as several replicas. The quotas of
+	// these resources in this flavor count devices, and each pod consumes
+	// the share of a device that it requests.
+	//
+	// sharedResources can be up to 8 elements.
+	//
+	// +listType=map
+	// +listMapKey=name
+	// +kubebuilder:validation:MaxItems=8
+	SharedResources []SharedResource `json:"sharedResources,omitempty"`
+}
+
+type SharedResource struct {
+	// name of the resource, as requested by the pods. For example,
+	// nvidia.com/gpu.
+	Name corev1.ResourceName `json:"name"`
+
+	// replicas is the number of pods that share one unit of the resource.
+	// With 4 replicas, the requests of 4 pods for one unit of the resource
+	// consume one unit of the quota.
+	//
+	// +kubebuilder:validation:Minimum=1
+	Replicas int32 `json:"replicas"`
 }
 
 //+kubebuilder:object:root=true
@@ -18,6 +18,7 @@ package v1alpha2
 
 import (
 	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
 
@@ -38,26 +39,92 @@ type WorkloadSpec struct {
 	// queueName cannot be changed once set.
 	QueueName string `json:"queueName,omitempty"`
 
-	// admission holds the parameters of the admission of the workload by a ClusterQueue.
-	// admission cannot be changed once set.
+	// admission is the deprecated location of the admission of the workload
+	// by a ClusterQueue. Kueue moves it to status.admission, and it can't be
+	// set in new workloads.
+	//
+	// Deprecated: use status.admission.
+	// +optional
 	Admission *Admission `json:"admission,omitempty"`
 
 	// If specified, indicates the workload's priority.
 	// "system-node-critical" and "system-cluster-critical" are two special
 	// keywords which indicate the highest priorities with the former being
 	// the highest priority. Any other name must be defined by creating a
-	// PriorityClass object with that name. If not specified, the workload
-	// priority will be default or zero if there is no default.
+	// PriorityClass object with that name, or a WorkloadPriorityClass when
+	// priorityClassSource is kueue.x-k8s.io/workloadpriorityclass. If not
+	// specified, the workload priority will be default or zero if there is no
+	// default.
 	PriorityClassName string `json:"priorityClassName,omitempty"`
 
+	// priorityClassSource is the kind of the priorityClassName:
+	//
+	// - kueue.x-k8s.io/workloadpriorityclass: a WorkloadPriorityClass, which
+	//   only sets the priority of the Workload in Kueue, while the pods keep
+	//   their own priority.
+	// - scheduling.k8s.io/priorityclass: a PriorityClass, which also sets the
+	//   priority of the pods.
+	// - empty: the Workload has no priorityClassName.
+	//
+	// +optional
+	// +kubebuilder:default=""
+	// +kubebuilder:validation:Enum=kueue.x-k8s.io/workloadpriorityclass;scheduling.k8s.io/priorityclass;""
+	PriorityClassSource string `json:"priorityClassSource,omitempty"`
+
 	// Priority determines the order of access to the resources managed by the
 	// ClusterQueue where the workload is queued.
 	// The priority value is populated from PriorityClassName.
 	// The higher the value, the higher the priority.
 	// If priorityClassName is specified, priority must not be null.
 	Priority *int32 `json:"priority,omitempty"`
+
+	// deadline is the time by which the workload should complete.
+	// ClusterQueues with the EarliestDeadlineFirst orderingPolicy use it to
+	// order their pending workloads.
+	// +optional
+	Deadline *metav1.Time `json:"deadline,omitempty"`
+
+	// expectedDuration is how long the workload is expected to run once
+	// admitted. Along with the deadline, it determines the latest time at which
+	// the workload should start.
+	// +optional
+	ExpectedDuration *metav1.Duration `json:"expectedDuration,omitempty"`
+
+	// active determines whether the workload is considered for admission.
+	// Inactive workloads are removed from their queues until they are
+	// activated again, and admitted ones are evicted.
+	// Kueue deactivates a workload when it is evicted more times than the
+	// maxWorkloadEvictions of the Kueue configuration.
+	// Defaults to true.
+	// +kubebuilder:default=true
+	// +optional
+	Active *bool `json:"active,omitempty"`
+
+	// managedBy is the name of the controller that admits the workload.
+	// Kueue only queues and admits the workloads managed by
+	// kueue.x-k8s.io/manager, the default. The workloads managed by other
+	// controllers, such as a multi-cluster dispatcher, are skipped by the
+	// scheduler, while their admission, set by the other controller, is
+	// still accounted in the usage of its ClusterQueue.
+	// managedBy cannot be changed.
+	// +kubebuilder:validation:MaxLength=256
+	// +optional
+	ManagedBy string `json:"managedBy,omitempty"`
 }
 
+// WorkloadManagedByKueue is the managedBy of the workloads that Kueue admits.
+const WorkloadManagedByKueue = "kueue.x-k8s.io/manager"
+
+const (
+	// WorkloadPriorityClassSource is the priorityClassSource of the workloads
+	// whose priorityClassName is a WorkloadPriorityClass.
+	WorkloadPriorityClassSource = "kueue.x-k8s.io/workloadpriorityclass"
+
+	// PodPriorityClassSource is the priorityClassSource of the workloads
+	// whose priorityClassName is a PriorityClass.
+	PodPriorityClassSource = "scheduling.k8s.io/priorityclass"
+)
+
 type Admission struct {
 	// clusterQueue is the name of the ClusterQueue that admitted this workload.
 	ClusterQueue ClusterQueueReference `json:"clusterQueue"`
@@ -66,6 +133,21 @@ type Admission struct {
 	// +listType=map
 	// +listMapKey=name
 	PodSetFlavors []PodSetFlavors `json:"podSetFlavors"`
+
+	// revocable indicates that the workload was admitted borrowing quota
+	// from the cohort by a ClusterQueue with revocableBorrowing. Revocable
+	// workloads are the first ones preempted when other ClusterQueues in the
+	// cohort reclaim their min quota.
+	// +optional
+	Revocable bool `json:"revocable,omitempty"`
+
+	// admissionChecks are the names of the AdmissionChecks of the
+	// ClusterQueue when the quota was reserved. The workload is only
+	// admitted once all of them are Ready in the status.
+	// +listType=set
+	// +kubebuilder:validation:MaxItems=8
+	// +optional
+	AdmissionChecks []string `json:"admissionChecks,omitempty"`
 }
 
 type PodSetFlavors struct {
@@ -74,7 +156,37 @@ type PodSetFlavors struct {
 	Name string `json:"name"`
 
 	// Flavors are the flavors assigned to the workload for each resource.
+	// The resources in different resource groups can be assigned different
+	// flavors, for example CPU from one flavor and GPUs from another. The
+	// pods of the podSet run on nodes that match the labels of all of them.
 	Flavors map[corev1.ResourceName]string `json:"flavors,omitempty"`
+
+	// flavorsReason is a short explanation of why the flavors were chosen,
+	// including the preceding flavors that were skipped and why.
+	// +optional
+	FlavorsReason string `json:"flavorsReason,omitempty"`
+
+	// count is the number of pods admitted for the podSet, when the workload
+	// is partially admitted with fewer pods than the podSet count. When the
+	// podSet is split, it's the number of pods admitted with the flavors
+	// above.
+	// +optional
+	Count *int32 `json:"count,omitempty"`
+
+	// splits are the other parts of the podSet, admitted with other flavors,
+	// when no single flavor had enough quota for all its pods.
+	// +optional
+	Splits []PodSetSplit `json:"splits,omitempty"`
+}
+
+type PodSetSplit struct {
+	// flavors are the flavors assigned to this part of the podSet for each
+	// resource.
+	Flavors map[corev1.ResourceName]string `json:"flavors,omitempty"`
+
+	// count is the number of pods of the podSet admitted with these flavors.
+	// +kubebuilder:validation:Minimum=1
+	Count int32 `json:"count"`
 }
 
 type PodSet struct {
@@ -90,25 +202,211 @@ type PodSet struct {
 	// count is the number of pods for the spec.
 	// +kubebuilder:validation:Minimum=1
 	Count int32 `json:"count"`
+
+	// minCount is the minimum number of pods for the spec, if the workload
+	// supports partial admission. When there isn't enough quota to admit the
+	// full count, the workload can be admitted with a count between minCount
+	// and count, recorded in the admission.
+	// Only one podSet in the workload can set minCount.
+	// +optional
+	// +kubebuilder:validation:Minimum=1
+	MinCount *int32 `json:"minCount,omitempty"`
 }
 
 // WorkloadStatus defines the observed state of Workload
 type WorkloadStatus struct {
+	// admission holds the parameters of the admission of the workload by a
+	// ClusterQueue. admission can be set back to null, but its fields cannot
+	// be changed once set, except for the clusterQueue when the workload is
+	// moved to another queue.
+	//
+	// +optional
+	Admission *Admission `json:"admission,omitempty"`
+
 	// conditions hold the latest available observations of the Workload
 	// current state.
 	//
 	// The type of the condition could be:
 	//
-	// - Admitted: the Workload was admitted through a ClusterQueue.
+	// - QuotaReserved: the ClusterQueue reserved quota for the Workload.
+	// - Admitted: the Workload was admitted through a ClusterQueue, after
+	//   its quota was reserved and all its admission checks are Ready.
+	// - PodsReady: at least the count of pods of each podSet are ready or
+	//   succeeded, while the Workload is admitted.
+	// - Evicted: the Workload was evicted after being admitted, and it's
+	//   pending to be admitted again.
 	// - Finished: the associated workload finished running (failed or succeeded).
+	// - Quarantined: the Workload failed to be admitted too many consecutive
+	//   times.
+	// - InvalidWorkload: the job of the Workload changed after it was
+	//   admitted.
+	//
+	// While the Workload is pending, the QuotaReserved and Admitted conditions
+	// are False with the same reason, which tells why the Workload isn't
+	// admitted yet.
 	//
 	// +optional
 	// +listType=map
 	// +listMapKey=type
 	Conditions []metav1.Condition `json:"conditions,omitempty"`
+
+	// resourceUsage is a snapshot of the time and resources the Workload used,
+	// recorded once the workload finishes.
+	//
+	// +optional
+	ResourceUsage *WorkloadResourceUsage `json:"resourceUsage,omitempty"`
+
+	// prioritySource is the source from which the priorityClassName of the
+	// Workload was taken, when it was created for a Job. The possible values
+	// are WorkloadPriorityClassLabel, PriorityClassLabel, PodPriorityClass,
+	// LocalQueue and Default.
+	//
+	// +optional
+	PrioritySource string `json:"prioritySource,omitempty"`
+
+	// requeueState holds the state of the requeueing backoff of the Workload,
+	// when it repeatedly fails to be admitted and the requeue backoff is
+	// enabled in the Kueue configuration.
+	//
+	// +optional
+	RequeueState *RequeueState `json:"requeueState,omitempty"`
+
+	// evictions is the number of times the Workload was evicted after being
+	// admitted. It restarts from zero when the Workload is deactivated.
+	//
+	// +optional
+	Evictions int32 `json:"evictions,omitempty"`
+
+	// schedulingStats holds the number of times the scheduler evaluated the
+	// Workload without admitting it, and the time it spent doing so.
+	//
+	// +optional
+	SchedulingStats *SchedulingStats `json:"schedulingStats,omitempty"`
+
+	// reclaimablePods keeps track of the number of pods of each podSet that
+	// finished and no longer need their quota. The quota of these pods is
+	// released while the Workload keeps running.
+	//
+	// +optional
+	// +listType=map
+	// +listMapKey=name
+	ReclaimablePods []ReclaimablePod `json:"reclaimablePods,omitempty"`
+
+	// flavorEvictions holds the times at which the Workload was evicted while
+	// admitted in each flavor, up to the 16 most recent ones per flavor. They
+	// are checked against the flavorFallbacks of the ClusterQueue.
+	//
+	// +optional
+	// +listType=map
+	// +listMapKey=flavor
+	FlavorEvictions []FlavorEvictions `json:"flavorEvictions,omitempty"`
+
+	// admissionChecks are the states of the admission checks of the
+	// admission, reported by their controllers.
+	//
+	// +optional
+	// +listType=map
+	// +listMapKey=name
+	// +kubebuilder:validation:MaxItems=8
+	AdmissionChecks []AdmissionCheckState `json:"admissionChecks,omitempty"`
 }
 
+type AdmissionCheckState struct {
+	// name is the name of the AdmissionCheck.
+	Name string `json:"name"`
+
+	// state of the check. The possible values are:
+	//
+	// - Pending: the check is still being performed.
+	// - Ready: the check passed; the workload can be admitted.
+	// - Retry: the check can't pass now; the workload is evicted, releasing
+	//   its quota, and requeued.
+	// - Rejected: the check will never pass; the workload is evicted and
+	//   deactivated.
+	//
+	// +kubebuilder:validation:Enum=Pending;Ready;Retry;Rejected
+	State CheckState `json:"state"`
+
+	// lastTransitionTime is the last time the state changed.
+	LastTransitionTime metav1.Time `json:"lastTransitionTime"`
+
+	// message is a human readable message about the state of the check.
+	// +kubebuilder:validation:MaxLength=32768
+	// +optional
+	Message string `json:"message,omitempty"`
+}
+
+type CheckState string
+
 const (
+	CheckStatePending  CheckState = "Pending"
+	CheckStateReady    CheckState = "Ready"
+	CheckStateRetry    CheckState = "Retry"
+	CheckStateRejected CheckState = "Rejected"
+)
+
+type FlavorEvictions struct {
+	// flavor is the name of the flavor the Workload was evicted from.
+	Flavor string `json:"flavor"`
+
+	// times are the times of the evictions, oldest first.
+	//
+	// +kubebuilder:validation:MaxItems=16
+	Times []metav1.Time `json:"times"`
+}
+
+type ReclaimablePod struct {
+	// name is the name of the podSet.
+	Name string `json:"name"`
+
+	// count is the number of pods of the podSet whose quota can be released.
+	//
+	// +kubebuilder:validation:Minimum=0
+	Count int32 `json:"count"`
+}
+
+type SchedulingStats struct {
+	// attempts is the number of times the scheduler evaluated the Workload
+	// for admission without admitting it.
+	Attempts int32 `json:"attempts"`
+
+	// evaluationTime is the total time the scheduler spent evaluating the
+	// Workload in these attempts.
+	EvaluationTime metav1.Duration `json:"evaluationTime"`
+}
+
+type RequeueState struct {
+	// count is the number of consecutive times the Workload was requeued
+	// after failing to be admitted.
+	Count int32 `json:"count"`
+
+	// requeueAt is the time until which the Workload is held back before it
+	// is considered for admission again.
+	// +optional
+	RequeueAt *metav1.Time `json:"requeueAt,omitempty"`
+}
+
+type WorkloadResourceUsage struct {
+	// queuedTime is the time between the creation of the Workload and its
+	// admission.
+	QueuedTime metav1.Duration `json:"queuedTime"`
+
+	// runTime is the time between the admission of the Workload and its
+	// finish.
+	RunTime metav1.Duration `json:"runTime"`
+
+	// admittedResources are the total resources (by flavor) admitted for all
+	// the podSets of the Workload.
+	// +optional
+	AdmittedResources map[corev1.ResourceName]map[string]resource.Quantity `json:"admittedResources,omitempty"`
+}
+
+const (
+	// WorkloadQuotaReserved means that a ClusterQueue reserved quota for the
+	// Workload. Once all the admission checks of the admission are Ready,
+	// the Workload is admitted.
+	WorkloadQuotaReserved = "QuotaReserved"
+
 	// WorkloadAdmitted means that the Workload was admitted by a ClusterQueue.
 	WorkloadAdmitted = "Admitted"
 
@@ -119,12 +417,84 @@ const (
 	// WorkloadPodsReady means that at least `.spec.podSets[*].count` Pods are
 	// ready or have succeeded.
 	WorkloadPodsReady = "PodsReady"
+
+	// WorkloadEvicted means that the Workload was evicted after being
+	// admitted and it's pending to be admitted again.
+	WorkloadEvicted = "Evicted"
+
+	// WorkloadQuarantined means that the Workload failed to be admitted too
+	// many consecutive times and it's only retried at a slow interval.
+	WorkloadQuarantined = "Quarantined"
+
+	// WorkloadInvalid means that the Workload no longer matches the pod
+	// template of the job that owns it, because the job was modified after
+	// the Workload was admitted.
+	WorkloadInvalid = "InvalidWorkload"
+)
+
+// Reasons of the Workload conditions.
+const (
+	// WorkloadReasonPending means that the Workload is waiting for quota
+	// in its ClusterQueue. It's the reason of the QuotaReserved and
+	// Admitted conditions while the Workload is pending.
+	WorkloadReasonPending = "Pending"
+
+	// WorkloadReasonInadmissible means that the Workload can't be admitted
+	// because its LocalQueue or ClusterQueue doesn't exist or is inactive.
+	WorkloadReasonInadmissible = "Inadmissible"
+
+	// WorkloadReasonInactive means that the Workload isn't admitted because
+	// it's inactive.
+	WorkloadReasonInactive = "Inactive"
+
+	// WorkloadReasonWaitingForPodsReady means that the scheduler is waiting
+	// for the pods of the admitted Workloads to be ready before admitting
+	// more Workloads.
+	WorkloadReasonWaitingForPodsReady = "Waiting"
+
+	// WorkloadReasonQuotaReserved is the reason of the QuotaReserved
+	// condition when a ClusterQueue reserved quota for the Workload.
+	WorkloadReasonQuotaReserved = "QuotaReserved"
+
+	// WorkloadReasonAdmissionChecksPending is the reason of the Admitted
+	// condition while the Workload has quota reserved and waits for its
+	// admission checks.
+	WorkloadReasonAdmissionChecksPending = "AdmissionChecksPending"
+
+	// WorkloadReasonAdmitted is the reason of the Admitted condition when
+	// the Workload is admitted.
+	WorkloadReasonAdmitted = "AdmissionByKueue"
+
+	// WorkloadReasonPodsReady is the reason of the PodsReady condition.
+	WorkloadReasonPodsReady = "PodsReady"
+
+	// WorkloadReasonJobFinished is the reason of the Finished condition
+	// when the job of the Workload finished.
+	WorkloadReasonJobFinished = "JobFinished"
+)
+
+// Reasons of the Evicted condition. The same reason is set in the
+// QuotaReserved and Admitted conditions of the evicted Workload.
+const (
+	// WorkloadEvictedByPreemption means that the Workload was preempted to
+	// admit a Workload with higher priority or to reclaim the quota that its
+	// ClusterQueue borrowed.
+	WorkloadEvictedByPreemption = "Preempted"
+
+	// WorkloadEvictedByPodsReadyTimeout means that the pods of the Workload
+	// weren't ready within the timeout of waitForPodsReady after it was
+	// admitted.
+	WorkloadEvictedByPodsReadyTimeout = "PodsReadyTimeout"
+
+	// WorkloadEvictedByDeactivation means that the Workload was deactivated
+	// while it was admitted.
+	WorkloadEvictedByDeactivation = "Deactivated"
 )
 
 // +kubebuilder:object:root=true
 // +kubebuilder:subresource:status
 // +kubebuilder:printcolumn:name="Queue",JSONPath=".spec.queueName",type=string,description="Name of the queue this workload was submitted to"
-// +kubebuilder:printcolumn:name="Admitted by",JSONPath=".spec.admission.clusterQueue",type=string,description="Name of the ClusterQueue that admitted this workload"
+// +kubebuilder:printcolumn:name="Admitted by",JSONPath=".status.admission.clusterQueue",type=string,description="Name of the ClusterQueue that admitted this workload"
 // +kubebuilder:printcolumn:name="Age",JSONPath=".metadata.creationTimestamp",type=date,description="Time this workload was created"
 // +kubebuilder:resource:shortName={wl}
 
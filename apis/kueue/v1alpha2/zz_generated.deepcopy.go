@@ -23,6 +23,7 @@ package v1alpha2
 
 import (
 	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
 	"k8s.io/apimachinery/pkg/apis/meta/v1"
 	runtime "k8s.io/apimachinery/pkg/runtime"
 )
@@ -37,6 +38,11 @@ func (in *Admission) DeepCopyInto(out *Admission) {
 			(*in)[i].DeepCopyInto(&(*out)[i])
 		}
 	}
+	if in.AdmissionChecks != nil {
+		in, out := &in.AdmissionChecks, &out.AdmissionChecks
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new Admission.
@@ -50,7 +56,7 @@ func (in *Admission) DeepCopy() *Admission {
 }
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *ClusterQueue) DeepCopyInto(out *ClusterQueue) {
+func (in *AdmissionCheck) DeepCopyInto(out *AdmissionCheck) {
 	*out = *in
 	out.TypeMeta = in.TypeMeta
 	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
@@ -58,18 +64,18 @@ func (in *ClusterQueue) DeepCopyInto(out *ClusterQueue) {
 	in.Status.DeepCopyInto(&out.Status)
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ClusterQueue.
-func (in *ClusterQueue) DeepCopy() *ClusterQueue {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new AdmissionCheck.
+func (in *AdmissionCheck) DeepCopy() *AdmissionCheck {
 	if in == nil {
 		return nil
 	}
-	out := new(ClusterQueue)
+	out := new(AdmissionCheck)
 	in.DeepCopyInto(out)
 	return out
 }
 
 // DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
-func (in *ClusterQueue) DeepCopyObject() runtime.Object {
+func (in *AdmissionCheck) DeepCopyObject() runtime.Object {
 	if c := in.DeepCopy(); c != nil {
 		return c
 	}
@@ -77,31 +83,31 @@ func (in *ClusterQueue) DeepCopyObject() runtime.Object {
 }
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *ClusterQueueList) DeepCopyInto(out *ClusterQueueList) {
+func (in *AdmissionCheckList) DeepCopyInto(out *AdmissionCheckList) {
 	*out = *in
 	out.TypeMeta = in.TypeMeta
 	in.ListMeta.DeepCopyInto(&out.ListMeta)
 	if in.Items != nil {
 		in, out := &in.Items, &out.Items
-		*out = make([]ClusterQueue, len(*in))
+		*out = make([]AdmissionCheck, len(*in))
 		for i := range *in {
 			(*in)[i].DeepCopyInto(&(*out)[i])
 		}
 	}
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ClusterQueueList.
-func (in *ClusterQueueList) DeepCopy() *ClusterQueueList {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new AdmissionCheckList.
+func (in *AdmissionCheckList) DeepCopy() *AdmissionCheckList {
 	if in == nil {
 		return nil
 	}
-	out := new(ClusterQueueList)
+	out := new(AdmissionCheckList)
 	in.DeepCopyInto(out)
 	return out
 }
 
 // DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
-func (in *ClusterQueueList) DeepCopyObject() runtime.Object {
+func (in *AdmissionCheckList) DeepCopyObject() runtime.Object {
 	if c := in.DeepCopy(); c != nil {
 		return c
 	}
@@ -109,52 +115,59 @@ func (in *ClusterQueueList) DeepCopyObject() runtime.Object {
 }
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *ClusterQueueSpec) DeepCopyInto(out *ClusterQueueSpec) {
+func (in *AdmissionCheckParametersReference) DeepCopyInto(out *AdmissionCheckParametersReference) {
 	*out = *in
-	if in.Resources != nil {
-		in, out := &in.Resources, &out.Resources
-		*out = make([]Resource, len(*in))
-		for i := range *in {
-			(*in)[i].DeepCopyInto(&(*out)[i])
-		}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new AdmissionCheckParametersReference.
+func (in *AdmissionCheckParametersReference) DeepCopy() *AdmissionCheckParametersReference {
+	if in == nil {
+		return nil
 	}
-	if in.NamespaceSelector != nil {
-		in, out := &in.NamespaceSelector, &out.NamespaceSelector
-		*out = new(v1.LabelSelector)
-		(*in).DeepCopyInto(*out)
+	out := new(AdmissionCheckParametersReference)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *AdmissionCheckSpec) DeepCopyInto(out *AdmissionCheckSpec) {
+	*out = *in
+	if in.Parameters != nil {
+		in, out := &in.Parameters, &out.Parameters
+		*out = new(AdmissionCheckParametersReference)
+		**out = **in
 	}
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ClusterQueueSpec.
-func (in *ClusterQueueSpec) DeepCopy() *ClusterQueueSpec {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new AdmissionCheckSpec.
+func (in *AdmissionCheckSpec) DeepCopy() *AdmissionCheckSpec {
 	if in == nil {
 		return nil
 	}
-	out := new(ClusterQueueSpec)
+	out := new(AdmissionCheckSpec)
 	in.DeepCopyInto(out)
 	return out
 }
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *ClusterQueueStatus) DeepCopyInto(out *ClusterQueueStatus) {
+func (in *AdmissionCheckState) DeepCopyInto(out *AdmissionCheckState) {
 	*out = *in
-	if in.UsedResources != nil {
-		in, out := &in.UsedResources, &out.UsedResources
-		*out = make(UsedResources, len(*in))
-		for key, val := range *in {
-			var outVal map[string]Usage
-			if val == nil {
-				(*out)[key] = nil
-			} else {
-				in, out := &val, &outVal
-				*out = make(map[string]Usage, len(*in))
-				for key, val := range *in {
-					(*out)[key] = *val.DeepCopy()
-				}
-			}
-			(*out)[key] = outVal
-		}
+	in.LastTransitionTime.DeepCopyInto(&out.LastTransitionTime)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new AdmissionCheckState.
+func (in *AdmissionCheckState) DeepCopy() *AdmissionCheckState {
+	if in == nil {
+		return nil
 	}
+	out := new(AdmissionCheckState)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *AdmissionCheckStatus) DeepCopyInto(out *AdmissionCheckStatus) {
+	*out = *in
 	if in.Conditions != nil {
 		in, out := &in.Conditions, &out.Conditions
 		*out = make([]v1.Condition, len(*in))
@@ -164,53 +177,91 @@ func (in *ClusterQueueStatus) DeepCopyInto(out *ClusterQueueStatus) {
 	}
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ClusterQueueStatus.
-func (in *ClusterQueueStatus) DeepCopy() *ClusterQueueStatus {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new AdmissionCheckStatus.
+func (in *AdmissionCheckStatus) DeepCopy() *AdmissionCheckStatus {
 	if in == nil {
 		return nil
 	}
-	out := new(ClusterQueueStatus)
+	out := new(AdmissionCheckStatus)
 	in.DeepCopyInto(out)
 	return out
 }
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *Flavor) DeepCopyInto(out *Flavor) {
+func (in *Budget) DeepCopyInto(out *Budget) {
 	*out = *in
-	in.Quota.DeepCopyInto(&out.Quota)
+	out.Limit = in.Limit.DeepCopy()
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new Flavor.
-func (in *Flavor) DeepCopy() *Flavor {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new Budget.
+func (in *Budget) DeepCopy() *Budget {
 	if in == nil {
 		return nil
 	}
-	out := new(Flavor)
+	out := new(Budget)
 	in.DeepCopyInto(out)
 	return out
 }
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *LocalQueue) DeepCopyInto(out *LocalQueue) {
+func (in *BudgetStatus) DeepCopyInto(out *BudgetStatus) {
+	*out = *in
+	in.PeriodStart.DeepCopyInto(&out.PeriodStart)
+	out.Used = in.Used.DeepCopy()
+	out.Remaining = in.Remaining.DeepCopy()
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new BudgetStatus.
+func (in *BudgetStatus) DeepCopy() *BudgetStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(BudgetStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *BorrowWithinCohort) DeepCopyInto(out *BorrowWithinCohort) {
+	*out = *in
+	if in.MinPriority != nil {
+		in, out := &in.MinPriority, &out.MinPriority
+		*out = new(int32)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new BorrowWithinCohort.
+func (in *BorrowWithinCohort) DeepCopy() *BorrowWithinCohort {
+	if in == nil {
+		return nil
+	}
+	out := new(BorrowWithinCohort)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ClusterQueue) DeepCopyInto(out *ClusterQueue) {
 	*out = *in
 	out.TypeMeta = in.TypeMeta
 	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
-	out.Spec = in.Spec
-	out.Status = in.Status
+	in.Spec.DeepCopyInto(&out.Spec)
+	in.Status.DeepCopyInto(&out.Status)
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new LocalQueue.
-func (in *LocalQueue) DeepCopy() *LocalQueue {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ClusterQueue.
+func (in *ClusterQueue) DeepCopy() *ClusterQueue {
 	if in == nil {
 		return nil
 	}
-	out := new(LocalQueue)
+	out := new(ClusterQueue)
 	in.DeepCopyInto(out)
 	return out
 }
 
 // DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
-func (in *LocalQueue) DeepCopyObject() runtime.Object {
+func (in *ClusterQueue) DeepCopyObject() runtime.Object {
 	if c := in.DeepCopy(); c != nil {
 		return c
 	}
@@ -218,122 +269,984 @@ func (in *LocalQueue) DeepCopyObject() runtime.Object {
 }
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *LocalQueueList) DeepCopyInto(out *LocalQueueList) {
+func (in *ClusterQueueClass) DeepCopyInto(out *ClusterQueueClass) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	in.Spec.DeepCopyInto(&out.Spec)
+	in.Status.DeepCopyInto(&out.Status)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ClusterQueueClass.
+func (in *ClusterQueueClass) DeepCopy() *ClusterQueueClass {
+	if in == nil {
+		return nil
+	}
+	out := new(ClusterQueueClass)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *ClusterQueueClass) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ClusterQueueClassList) DeepCopyInto(out *ClusterQueueClassList) {
 	*out = *in
 	out.TypeMeta = in.TypeMeta
 	in.ListMeta.DeepCopyInto(&out.ListMeta)
 	if in.Items != nil {
 		in, out := &in.Items, &out.Items
-		*out = make([]LocalQueue, len(*in))
+		*out = make([]ClusterQueueClass, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ClusterQueueClassList.
+func (in *ClusterQueueClassList) DeepCopy() *ClusterQueueClassList {
+	if in == nil {
+		return nil
+	}
+	out := new(ClusterQueueClassList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *ClusterQueueClassList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ClusterQueueClassSpec) DeepCopyInto(out *ClusterQueueClassSpec) {
+	*out = *in
+	if in.Resources != nil {
+		in, out := &in.Resources, &out.Resources
+		*out = make([]Resource, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.ResourceGroups != nil {
+		in, out := &in.ResourceGroups, &out.ResourceGroups
+		*out = make([]ResourceGroup, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.NamespaceSelector != nil {
+		in, out := &in.NamespaceSelector, &out.NamespaceSelector
+		*out = new(v1.LabelSelector)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Preemption != nil {
+		in, out := &in.Preemption, &out.Preemption
+		*out = new(ClusterQueuePreemption)
+		(*in).DeepCopyInto(*out)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ClusterQueueClassSpec.
+func (in *ClusterQueueClassSpec) DeepCopy() *ClusterQueueClassSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(ClusterQueueClassSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ClusterQueueClassStatus) DeepCopyInto(out *ClusterQueueClassStatus) {
+	*out = *in
+	if in.ClusterQueues != nil {
+		in, out := &in.ClusterQueues, &out.ClusterQueues
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ClusterQueueClassStatus.
+func (in *ClusterQueueClassStatus) DeepCopy() *ClusterQueueClassStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(ClusterQueueClassStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ClusterQueueList) DeepCopyInto(out *ClusterQueueList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]ClusterQueue, len(*in))
 		for i := range *in {
 			(*in)[i].DeepCopyInto(&(*out)[i])
 		}
 	}
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new LocalQueueList.
-func (in *LocalQueueList) DeepCopy() *LocalQueueList {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ClusterQueueList.
+func (in *ClusterQueueList) DeepCopy() *ClusterQueueList {
+	if in == nil {
+		return nil
+	}
+	out := new(ClusterQueueList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *ClusterQueueList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ClusterQueuePolicies) DeepCopyInto(out *ClusterQueuePolicies) {
+	*out = *in
+	out.FlavorFungibility = in.FlavorFungibility
+	in.Preemption.DeepCopyInto(&out.Preemption)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ClusterQueuePolicies.
+func (in *ClusterQueuePolicies) DeepCopy() *ClusterQueuePolicies {
+	if in == nil {
+		return nil
+	}
+	out := new(ClusterQueuePolicies)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ClusterQueuePreemption) DeepCopyInto(out *ClusterQueuePreemption) {
+	*out = *in
+	if in.MaxVictims != nil {
+		in, out := &in.MaxVictims, &out.MaxVictims
+		*out = new(int32)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ClusterQueuePreemption.
+func (in *ClusterQueuePreemption) DeepCopy() *ClusterQueuePreemption {
+	if in == nil {
+		return nil
+	}
+	out := new(ClusterQueuePreemption)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ClusterQueueSpec) DeepCopyInto(out *ClusterQueueSpec) {
+	*out = *in
+	if in.Resources != nil {
+		in, out := &in.Resources, &out.Resources
+		*out = make([]Resource, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.ResourceGroups != nil {
+		in, out := &in.ResourceGroups, &out.ResourceGroups
+		*out = make([]ResourceGroup, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.HeadBlockingTimeout != nil {
+		in, out := &in.HeadBlockingTimeout, &out.HeadBlockingTimeout
+		*out = new(v1.Duration)
+		**out = **in
+	}
+	if in.FlavorFungibility != nil {
+		in, out := &in.FlavorFungibility, &out.FlavorFungibility
+		*out = new(FlavorFungibility)
+		**out = **in
+	}
+	if in.NamespaceSelector != nil {
+		in, out := &in.NamespaceSelector, &out.NamespaceSelector
+		*out = new(v1.LabelSelector)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Preemption != nil {
+		in, out := &in.Preemption, &out.Preemption
+		*out = new(ClusterQueuePreemption)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.FairSharing != nil {
+		in, out := &in.FairSharing, &out.FairSharing
+		*out = new(FairSharing)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.PriorityBands != nil {
+		in, out := &in.PriorityBands, &out.PriorityBands
+		*out = make([]PriorityBand, len(*in))
+		copy(*out, *in)
+	}
+	if in.ReservedTiers != nil {
+		in, out := &in.ReservedTiers, &out.ReservedTiers
+		*out = make([]ReservedTier, len(*in))
+		copy(*out, *in)
+	}
+	if in.QuotaWindows != nil {
+		in, out := &in.QuotaWindows, &out.QuotaWindows
+		*out = make([]QuotaWindow, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.Budgets != nil {
+		in, out := &in.Budgets, &out.Budgets
+		*out = make([]Budget, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.FlavorFallbacks != nil {
+		in, out := &in.FlavorFallbacks, &out.FlavorFallbacks
+		*out = make([]FlavorFallback, len(*in))
+		copy(*out, *in)
+	}
+	if in.BorrowWithinCohort != nil {
+		in, out := &in.BorrowWithinCohort, &out.BorrowWithinCohort
+		*out = new(BorrowWithinCohort)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.AdmissionChecks != nil {
+		in, out := &in.AdmissionChecks, &out.AdmissionChecks
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.MaxWorkloadsPendingChecks != nil {
+		in, out := &in.MaxWorkloadsPendingChecks, &out.MaxWorkloadsPendingChecks
+		*out = new(int32)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ClusterQueueSpec.
+func (in *ClusterQueueSpec) DeepCopy() *ClusterQueueSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(ClusterQueueSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ClusterQueueStatus) DeepCopyInto(out *ClusterQueueStatus) {
+	*out = *in
+	if in.UsedResources != nil {
+		in, out := &in.UsedResources, &out.UsedResources
+		*out = make(UsedResources, len(*in))
+		for key, val := range *in {
+			var outVal map[string]Usage
+			if val == nil {
+				(*out)[key] = nil
+			} else {
+				in, out := &val, &outVal
+				*out = make(map[string]Usage, len(*in))
+				for key, val := range *in {
+					(*out)[key] = *val.DeepCopy()
+				}
+			}
+			(*out)[key] = outVal
+		}
+	}
+	if in.QuotaSharing != nil {
+		in, out := &in.QuotaSharing, &out.QuotaSharing
+		*out = new(QuotaSharing)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.EffectivePolicies != nil {
+		in, out := &in.EffectivePolicies, &out.EffectivePolicies
+		*out = new(ClusterQueuePolicies)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Budgets != nil {
+		in, out := &in.Budgets, &out.Budgets
+		*out = make([]BudgetStatus, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.Conditions != nil {
+		in, out := &in.Conditions, &out.Conditions
+		*out = make([]v1.Condition, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ClusterQueueStatus.
+func (in *ClusterQueueStatus) DeepCopy() *ClusterQueueStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(ClusterQueueStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *Cohort) DeepCopyInto(out *Cohort) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	in.Spec.DeepCopyInto(&out.Spec)
+	in.Status.DeepCopyInto(&out.Status)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new Cohort.
+func (in *Cohort) DeepCopy() *Cohort {
+	if in == nil {
+		return nil
+	}
+	out := new(Cohort)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *Cohort) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *CohortFlavor) DeepCopyInto(out *CohortFlavor) {
+	*out = *in
+	in.Quota.DeepCopyInto(&out.Quota)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new CohortFlavor.
+func (in *CohortFlavor) DeepCopy() *CohortFlavor {
+	if in == nil {
+		return nil
+	}
+	out := new(CohortFlavor)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *CohortList) DeepCopyInto(out *CohortList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]Cohort, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new CohortList.
+func (in *CohortList) DeepCopy() *CohortList {
+	if in == nil {
+		return nil
+	}
+	out := new(CohortList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *CohortList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *CohortResource) DeepCopyInto(out *CohortResource) {
+	*out = *in
+	if in.Flavors != nil {
+		in, out := &in.Flavors, &out.Flavors
+		*out = make([]CohortFlavor, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new CohortResource.
+func (in *CohortResource) DeepCopy() *CohortResource {
+	if in == nil {
+		return nil
+	}
+	out := new(CohortResource)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *CohortSpec) DeepCopyInto(out *CohortSpec) {
+	*out = *in
+	if in.Resources != nil {
+		in, out := &in.Resources, &out.Resources
+		*out = make([]CohortResource, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new CohortSpec.
+func (in *CohortSpec) DeepCopy() *CohortSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(CohortSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *CohortStatus) DeepCopyInto(out *CohortStatus) {
+	*out = *in
+	if in.ClusterQueues != nil {
+		in, out := &in.ClusterQueues, &out.ClusterQueues
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.Quota != nil {
+		in, out := &in.Quota, &out.Quota
+		*out = make(map[corev1.ResourceName]map[string]resource.Quantity, len(*in))
+		for key, val := range *in {
+			var outVal map[string]resource.Quantity
+			if val == nil {
+				(*out)[key] = nil
+			} else {
+				in, out := &val, &outVal
+				*out = make(map[string]resource.Quantity, len(*in))
+				for key, val := range *in {
+					(*out)[key] = val.DeepCopy()
+				}
+			}
+			(*out)[key] = outVal
+		}
+	}
+	if in.Usage != nil {
+		in, out := &in.Usage, &out.Usage
+		*out = make(map[corev1.ResourceName]map[string]resource.Quantity, len(*in))
+		for key, val := range *in {
+			var outVal map[string]resource.Quantity
+			if val == nil {
+				(*out)[key] = nil
+			} else {
+				in, out := &val, &outVal
+				*out = make(map[string]resource.Quantity, len(*in))
+				for key, val := range *in {
+					(*out)[key] = val.DeepCopy()
+				}
+			}
+			(*out)[key] = outVal
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new CohortStatus.
+func (in *CohortStatus) DeepCopy() *CohortStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(CohortStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *FairSharing) DeepCopyInto(out *FairSharing) {
+	*out = *in
+	if in.Weight != nil {
+		in, out := &in.Weight, &out.Weight
+		x := (*in).DeepCopy()
+		*out = &x
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new FairSharing.
+func (in *FairSharing) DeepCopy() *FairSharing {
+	if in == nil {
+		return nil
+	}
+	out := new(FairSharing)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *Flavor) DeepCopyInto(out *Flavor) {
+	*out = *in
+	in.Quota.DeepCopyInto(&out.Quota)
+	if in.AdmissionChecks != nil {
+		in, out := &in.AdmissionChecks, &out.AdmissionChecks
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new Flavor.
+func (in *Flavor) DeepCopy() *Flavor {
+	if in == nil {
+		return nil
+	}
+	out := new(Flavor)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *FlavorEvictions) DeepCopyInto(out *FlavorEvictions) {
+	*out = *in
+	if in.Times != nil {
+		in, out := &in.Times, &out.Times
+		*out = make([]v1.Time, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new FlavorEvictions.
+func (in *FlavorEvictions) DeepCopy() *FlavorEvictions {
+	if in == nil {
+		return nil
+	}
+	out := new(FlavorEvictions)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *FlavorFallback) DeepCopyInto(out *FlavorFallback) {
+	*out = *in
+	out.Window = in.Window
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new FlavorFallback.
+func (in *FlavorFallback) DeepCopy() *FlavorFallback {
+	if in == nil {
+		return nil
+	}
+	out := new(FlavorFallback)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *FlavorFungibility) DeepCopyInto(out *FlavorFungibility) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new FlavorFungibility.
+func (in *FlavorFungibility) DeepCopy() *FlavorFungibility {
+	if in == nil {
+		return nil
+	}
+	out := new(FlavorFungibility)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *FlavorQuotas) DeepCopyInto(out *FlavorQuotas) {
+	*out = *in
+	if in.Resources != nil {
+		in, out := &in.Resources, &out.Resources
+		*out = make([]ResourceQuota, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.AdmissionChecks != nil {
+		in, out := &in.AdmissionChecks, &out.AdmissionChecks
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new FlavorQuotas.
+func (in *FlavorQuotas) DeepCopy() *FlavorQuotas {
+	if in == nil {
+		return nil
+	}
+	out := new(FlavorQuotas)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *LocalQueue) DeepCopyInto(out *LocalQueue) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	in.Spec.DeepCopyInto(&out.Spec)
+	out.Status = in.Status
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new LocalQueue.
+func (in *LocalQueue) DeepCopy() *LocalQueue {
+	if in == nil {
+		return nil
+	}
+	out := new(LocalQueue)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *LocalQueue) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *LocalQueueList) DeepCopyInto(out *LocalQueueList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]LocalQueue, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new LocalQueueList.
+func (in *LocalQueueList) DeepCopy() *LocalQueueList {
+	if in == nil {
+		return nil
+	}
+	out := new(LocalQueueList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *LocalQueueList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *LocalQueueSpec) DeepCopyInto(out *LocalQueueSpec) {
+	*out = *in
+	if in.Limits != nil {
+		in, out := &in.Limits, &out.Limits
+		*out = make(corev1.ResourceList, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val.DeepCopy()
+		}
+	}
+	if in.LimitsPercent != nil {
+		in, out := &in.LimitsPercent, &out.LimitsPercent
+		*out = make(map[corev1.ResourceName]int32, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	if in.Tolerations != nil {
+		in, out := &in.Tolerations, &out.Tolerations
+		*out = make([]corev1.Toleration, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.PriorityClassRouting != nil {
+		in, out := &in.PriorityClassRouting, &out.PriorityClassRouting
+		*out = make([]PriorityClassRoute, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new LocalQueueSpec.
+func (in *LocalQueueSpec) DeepCopy() *LocalQueueSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(LocalQueueSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *LocalQueueStatus) DeepCopyInto(out *LocalQueueStatus) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new LocalQueueStatus.
+func (in *LocalQueueStatus) DeepCopy() *LocalQueueStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(LocalQueueStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PodSet) DeepCopyInto(out *PodSet) {
+	*out = *in
+	in.Spec.DeepCopyInto(&out.Spec)
+	if in.MinCount != nil {
+		in, out := &in.MinCount, &out.MinCount
+		*out = new(int32)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PodSet.
+func (in *PodSet) DeepCopy() *PodSet {
+	if in == nil {
+		return nil
+	}
+	out := new(PodSet)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PodSetFlavors) DeepCopyInto(out *PodSetFlavors) {
+	*out = *in
+	if in.Flavors != nil {
+		in, out := &in.Flavors, &out.Flavors
+		*out = make(map[corev1.ResourceName]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	if in.Count != nil {
+		in, out := &in.Count, &out.Count
+		*out = new(int32)
+		**out = **in
+	}
+	if in.Splits != nil {
+		in, out := &in.Splits, &out.Splits
+		*out = make([]PodSetSplit, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PodSetFlavors.
+func (in *PodSetFlavors) DeepCopy() *PodSetFlavors {
+	if in == nil {
+		return nil
+	}
+	out := new(PodSetFlavors)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PodSetSplit) DeepCopyInto(out *PodSetSplit) {
+	*out = *in
+	if in.Flavors != nil {
+		in, out := &in.Flavors, &out.Flavors
+		*out = make(map[corev1.ResourceName]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PodSetSplit.
+func (in *PodSetSplit) DeepCopy() *PodSetSplit {
+	if in == nil {
+		return nil
+	}
+	out := new(PodSetSplit)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PriorityBand) DeepCopyInto(out *PriorityBand) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PriorityBand.
+func (in *PriorityBand) DeepCopy() *PriorityBand {
+	if in == nil {
+		return nil
+	}
+	out := new(PriorityBand)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PriorityClassRoute) DeepCopyInto(out *PriorityClassRoute) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PriorityClassRoute.
+func (in *PriorityClassRoute) DeepCopy() *PriorityClassRoute {
 	if in == nil {
 		return nil
 	}
-	out := new(LocalQueueList)
+	out := new(PriorityClassRoute)
 	in.DeepCopyInto(out)
 	return out
 }
 
-// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
-func (in *LocalQueueList) DeepCopyObject() runtime.Object {
-	if c := in.DeepCopy(); c != nil {
-		return c
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *Quota) DeepCopyInto(out *Quota) {
+	*out = *in
+	out.Min = in.Min.DeepCopy()
+	if in.Max != nil {
+		in, out := &in.Max, &out.Max
+		x := (*in).DeepCopy()
+		*out = &x
 	}
-	return nil
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new Quota.
+func (in *Quota) DeepCopy() *Quota {
+	if in == nil {
+		return nil
+	}
+	out := new(Quota)
+	in.DeepCopyInto(out)
+	return out
 }
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *LocalQueueSpec) DeepCopyInto(out *LocalQueueSpec) {
+func (in *QuotaSharing) DeepCopyInto(out *QuotaSharing) {
 	*out = *in
+	if in.LentTo != nil {
+		in, out := &in.LentTo, &out.LentTo
+		*out = make([]SharedQuota, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.BorrowedFrom != nil {
+		in, out := &in.BorrowedFrom, &out.BorrowedFrom
+		*out = make([]SharedQuota, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new LocalQueueSpec.
-func (in *LocalQueueSpec) DeepCopy() *LocalQueueSpec {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new QuotaSharing.
+func (in *QuotaSharing) DeepCopy() *QuotaSharing {
 	if in == nil {
 		return nil
 	}
-	out := new(LocalQueueSpec)
+	out := new(QuotaSharing)
 	in.DeepCopyInto(out)
 	return out
 }
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *LocalQueueStatus) DeepCopyInto(out *LocalQueueStatus) {
+func (in *QuotaWindow) DeepCopyInto(out *QuotaWindow) {
 	*out = *in
+	if in.Days != nil {
+		in, out := &in.Days, &out.Days
+		*out = make([]Weekday, len(*in))
+		copy(*out, *in)
+	}
+	if in.Quotas != nil {
+		in, out := &in.Quotas, &out.Quotas
+		*out = make([]WindowQuota, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new LocalQueueStatus.
-func (in *LocalQueueStatus) DeepCopy() *LocalQueueStatus {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new QuotaWindow.
+func (in *QuotaWindow) DeepCopy() *QuotaWindow {
 	if in == nil {
 		return nil
 	}
-	out := new(LocalQueueStatus)
+	out := new(QuotaWindow)
 	in.DeepCopyInto(out)
 	return out
 }
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *PodSet) DeepCopyInto(out *PodSet) {
+func (in *ReclaimablePod) DeepCopyInto(out *ReclaimablePod) {
 	*out = *in
-	in.Spec.DeepCopyInto(&out.Spec)
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PodSet.
-func (in *PodSet) DeepCopy() *PodSet {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ReclaimablePod.
+func (in *ReclaimablePod) DeepCopy() *ReclaimablePod {
 	if in == nil {
 		return nil
 	}
-	out := new(PodSet)
+	out := new(ReclaimablePod)
 	in.DeepCopyInto(out)
 	return out
 }
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *PodSetFlavors) DeepCopyInto(out *PodSetFlavors) {
+func (in *RequeueState) DeepCopyInto(out *RequeueState) {
 	*out = *in
-	if in.Flavors != nil {
-		in, out := &in.Flavors, &out.Flavors
-		*out = make(map[corev1.ResourceName]string, len(*in))
-		for key, val := range *in {
-			(*out)[key] = val
-		}
+	if in.RequeueAt != nil {
+		in, out := &in.RequeueAt, &out.RequeueAt
+		*out = (*in).DeepCopy()
 	}
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PodSetFlavors.
-func (in *PodSetFlavors) DeepCopy() *PodSetFlavors {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RequeueState.
+func (in *RequeueState) DeepCopy() *RequeueState {
 	if in == nil {
 		return nil
 	}
-	out := new(PodSetFlavors)
+	out := new(RequeueState)
 	in.DeepCopyInto(out)
 	return out
 }
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *Quota) DeepCopyInto(out *Quota) {
+func (in *ReservedTier) DeepCopyInto(out *ReservedTier) {
 	*out = *in
-	out.Min = in.Min.DeepCopy()
-	if in.Max != nil {
-		in, out := &in.Max, &out.Max
-		x := (*in).DeepCopy()
-		*out = &x
-	}
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new Quota.
-func (in *Quota) DeepCopy() *Quota {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ReservedTier.
+func (in *ReservedTier) DeepCopy() *ReservedTier {
 	if in == nil {
 		return nil
 	}
-	out := new(Quota)
+	out := new(ReservedTier)
 	in.DeepCopyInto(out)
 	return out
 }
@@ -379,6 +1292,11 @@ func (in *ResourceFlavor) DeepCopyInto(out *ResourceFlavor) {
 			(*in)[i].DeepCopyInto(&(*out)[i])
 		}
 	}
+	if in.SharedResources != nil {
+		in, out := &in.SharedResources, &out.SharedResources
+		*out = make([]SharedResource, len(*in))
+		copy(*out, *in)
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ResourceFlavor.
@@ -431,6 +1349,112 @@ func (in *ResourceFlavorList) DeepCopyObject() runtime.Object {
 	return nil
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ResourceGroup) DeepCopyInto(out *ResourceGroup) {
+	*out = *in
+	if in.CoveredResources != nil {
+		in, out := &in.CoveredResources, &out.CoveredResources
+		*out = make([]corev1.ResourceName, len(*in))
+		copy(*out, *in)
+	}
+	if in.Flavors != nil {
+		in, out := &in.Flavors, &out.Flavors
+		*out = make([]FlavorQuotas, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ResourceGroup.
+func (in *ResourceGroup) DeepCopy() *ResourceGroup {
+	if in == nil {
+		return nil
+	}
+	out := new(ResourceGroup)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ResourceQuota) DeepCopyInto(out *ResourceQuota) {
+	*out = *in
+	in.Quota.DeepCopyInto(&out.Quota)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ResourceQuota.
+func (in *ResourceQuota) DeepCopy() *ResourceQuota {
+	if in == nil {
+		return nil
+	}
+	out := new(ResourceQuota)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *SchedulingStats) DeepCopyInto(out *SchedulingStats) {
+	*out = *in
+	out.EvaluationTime = in.EvaluationTime
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SchedulingStats.
+func (in *SchedulingStats) DeepCopy() *SchedulingStats {
+	if in == nil {
+		return nil
+	}
+	out := new(SchedulingStats)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *SharedQuota) DeepCopyInto(out *SharedQuota) {
+	*out = *in
+	if in.Resources != nil {
+		in, out := &in.Resources, &out.Resources
+		*out = make(map[corev1.ResourceName]map[string]resource.Quantity, len(*in))
+		for key, val := range *in {
+			var outVal map[string]resource.Quantity
+			if val == nil {
+				(*out)[key] = nil
+			} else {
+				in, out := &val, &outVal
+				*out = make(map[string]resource.Quantity, len(*in))
+				for key, val := range *in {
+					(*out)[key] = val.DeepCopy()
+				}
+			}
+			(*out)[key] = outVal
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SharedQuota.
+func (in *SharedQuota) DeepCopy() *SharedQuota {
+	if in == nil {
+		return nil
+	}
+	out := new(SharedQuota)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *SharedResource) DeepCopyInto(out *SharedResource) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SharedResource.
+func (in *SharedResource) DeepCopy() *SharedResource {
+	if in == nil {
+		return nil
+	}
+	out := new(SharedResource)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *Usage) DeepCopyInto(out *Usage) {
 	*out = *in
@@ -487,6 +1511,22 @@ func (in UsedResources) DeepCopy() UsedResources {
 	return *out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *WindowQuota) DeepCopyInto(out *WindowQuota) {
+	*out = *in
+	in.Quota.DeepCopyInto(&out.Quota)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new WindowQuota.
+func (in *WindowQuota) DeepCopy() *WindowQuota {
+	if in == nil {
+		return nil
+	}
+	out := new(WindowQuota)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *Workload) DeepCopyInto(out *Workload) {
 	*out = *in
@@ -546,6 +1586,97 @@ func (in *WorkloadList) DeepCopyObject() runtime.Object {
 	return nil
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *WorkloadPriorityClass) DeepCopyInto(out *WorkloadPriorityClass) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new WorkloadPriorityClass.
+func (in *WorkloadPriorityClass) DeepCopy() *WorkloadPriorityClass {
+	if in == nil {
+		return nil
+	}
+	out := new(WorkloadPriorityClass)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *WorkloadPriorityClass) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *WorkloadPriorityClassList) DeepCopyInto(out *WorkloadPriorityClassList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]WorkloadPriorityClass, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new WorkloadPriorityClassList.
+func (in *WorkloadPriorityClassList) DeepCopy() *WorkloadPriorityClassList {
+	if in == nil {
+		return nil
+	}
+	out := new(WorkloadPriorityClassList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *WorkloadPriorityClassList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *WorkloadResourceUsage) DeepCopyInto(out *WorkloadResourceUsage) {
+	*out = *in
+	out.QueuedTime = in.QueuedTime
+	out.RunTime = in.RunTime
+	if in.AdmittedResources != nil {
+		in, out := &in.AdmittedResources, &out.AdmittedResources
+		*out = make(map[corev1.ResourceName]map[string]resource.Quantity, len(*in))
+		for key, val := range *in {
+			var outVal map[string]resource.Quantity
+			if val == nil {
+				(*out)[key] = nil
+			} else {
+				in, out := &val, &outVal
+				*out = make(map[string]resource.Quantity, len(*in))
+				for key, val := range *in {
+					(*out)[key] = val.DeepCopy()
+				}
+			}
+			(*out)[key] = outVal
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new WorkloadResourceUsage.
+func (in *WorkloadResourceUsage) DeepCopy() *WorkloadResourceUsage {
+	if in == nil {
+		return nil
+	}
+	out := new(WorkloadResourceUsage)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *WorkloadSpec) DeepCopyInto(out *WorkloadSpec) {
 	*out = *in
@@ -566,6 +1697,20 @@ func (in *WorkloadSpec) DeepCopyInto(out *WorkloadSpec) {
 		*out = new(int32)
 		**out = **in
 	}
+	if in.Deadline != nil {
+		in, out := &in.Deadline, &out.Deadline
+		*out = (*in).DeepCopy()
+	}
+	if in.ExpectedDuration != nil {
+		in, out := &in.ExpectedDuration, &out.ExpectedDuration
+		*out = new(v1.Duration)
+		**out = **in
+	}
+	if in.Active != nil {
+		in, out := &in.Active, &out.Active
+		*out = new(bool)
+		**out = **in
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new WorkloadSpec.
@@ -581,6 +1726,11 @@ func (in *WorkloadSpec) DeepCopy() *WorkloadSpec {
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *WorkloadStatus) DeepCopyInto(out *WorkloadStatus) {
 	*out = *in
+	if in.Admission != nil {
+		in, out := &in.Admission, &out.Admission
+		*out = new(Admission)
+		(*in).DeepCopyInto(*out)
+	}
 	if in.Conditions != nil {
 		in, out := &in.Conditions, &out.Conditions
 		*out = make([]v1.Condition, len(*in))
@@ -588,6 +1738,40 @@ func (in *WorkloadStatus) DeepCopyInto(out *WorkloadStatus) {
 			(*in)[i].DeepCopyInto(&(*out)[i])
 		}
 	}
+	if in.ResourceUsage != nil {
+		in, out := &in.ResourceUsage, &out.ResourceUsage
+		*out = new(WorkloadResourceUsage)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.RequeueState != nil {
+		in, out := &in.RequeueState, &out.RequeueState
+		*out = new(RequeueState)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.SchedulingStats != nil {
+		in, out := &in.SchedulingStats, &out.SchedulingStats
+		*out = new(SchedulingStats)
+		**out = **in
+	}
+	if in.ReclaimablePods != nil {
+		in, out := &in.ReclaimablePods, &out.ReclaimablePods
+		*out = make([]ReclaimablePod, len(*in))
+		copy(*out, *in)
+	}
+	if in.FlavorEvictions != nil {
+		in, out := &in.FlavorEvictions, &out.FlavorEvictions
+		*out = make([]FlavorEvictions, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.AdmissionChecks != nil {
+		in, out := &in.AdmissionChecks, &out.AdmissionChecks
+		*out = make([]AdmissionCheckState, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new WorkloadStatus.
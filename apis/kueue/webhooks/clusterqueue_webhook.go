@@ -19,6 +19,7 @@ package webhooks
 import (
 	"context"
 	"fmt"
+	"time"
 
 	"k8s.io/apimachinery/pkg/api/resource"
 	apivalidation "k8s.io/apimachinery/pkg/api/validation"
@@ -33,6 +34,7 @@ import (
 	"sigs.k8s.io/controller-runtime/pkg/webhook"
 
 	kueue "sigs.k8s.io/kueue/apis/kueue/v1alpha2"
+	utilapi "sigs.k8s.io/kueue/pkg/util/api"
 )
 
 const (
@@ -97,11 +99,36 @@ func ValidateClusterQueue(cq *kueue.ClusterQueue) field.ErrorList {
 	path := field.NewPath("spec")
 
 	var allErrs field.ErrorList
+	if len(cq.Spec.ClassName) != 0 {
+		allErrs = append(allErrs, validateNameReference(cq.Spec.ClassName, path.Child("className"))...)
+	}
 	if len(cq.Spec.Cohort) != 0 {
 		allErrs = append(allErrs, validateNameReference(cq.Spec.Cohort, path.Child("cohort"))...)
 	}
+	if cq.Spec.Backfill && cq.Spec.QueueingStrategy != kueue.StrictFIFO {
+		allErrs = append(allErrs, field.Invalid(path.Child("backfill"), cq.Spec.Backfill, "only supported with the StrictFIFO queueingStrategy"))
+	}
+	if cq.Spec.HeadBlockingTimeout != nil {
+		if cq.Spec.QueueingStrategy != kueue.StrictFIFO {
+			allErrs = append(allErrs, field.Invalid(path.Child("headBlockingTimeout"), cq.Spec.HeadBlockingTimeout, "only supported with the StrictFIFO queueingStrategy"))
+		} else if cq.Spec.HeadBlockingTimeout.Duration <= 0 {
+			allErrs = append(allErrs, field.Invalid(path.Child("headBlockingTimeout"), cq.Spec.HeadBlockingTimeout, "must be greater than 0"))
+		}
+	}
 	allErrs = append(allErrs, validateResources(cq.Spec.Resources, path.Child("resources"))...)
+	allErrs = append(allErrs, validateResourceGroups(cq.Spec.ResourceGroups, cq.Spec.Resources, path.Child("resourceGroups"))...)
 	allErrs = append(allErrs, validateNamespaceSelector(cq.Spec.NamespaceSelector, path.Child("namespaceSelector"))...)
+	if cq.Spec.Preemption != nil {
+		allErrs = append(allErrs, validatePreemption(cq.Spec.Preemption, path.Child("preemption"))...)
+	}
+	if cq.Spec.FairSharing != nil && cq.Spec.FairSharing.Weight != nil {
+		allErrs = append(allErrs, validateResourceQuantity(*cq.Spec.FairSharing.Weight, path.Child("fairSharing", "weight"))...)
+	}
+	allErrs = append(allErrs, validatePriorityBands(cq.Spec.PriorityBands, path.Child("priorityBands"))...)
+	allErrs = append(allErrs, validateReservedTiers(cq.Spec.ReservedTiers, path.Child("reservedTiers"))...)
+	allErrs = append(allErrs, validateQuotaWindows(cq, path.Child("quotaWindows"))...)
+	allErrs = append(allErrs, validateBudgets(cq.Spec.Budgets, path.Child("budgets"))...)
+	allErrs = append(allErrs, validateFlavorFallbacks(cq, path.Child("flavorFallbacks"))...)
 
 	return allErrs
 }
@@ -109,11 +136,13 @@ func ValidateClusterQueue(cq *kueue.ClusterQueue) field.ErrorList {
 // Since Kubernetes 1.25, we can use CEL validation rules to implement
 // a few common immutability patterns directly in the manifest for a CRD.
 // ref: https://kubernetes.io/blog/2022/09/29/enforce-immutability-using-cel/
-// We need to validate the spec.queueingStrategy immutable manually before Kubernetes 1.25.
+// The CRD declares such rules for spec.queueingStrategy and spec.orderingPolicy,
+// but we still need to validate them manually before Kubernetes 1.25.
 func ValidateClusterQueueUpdate(newObj, oldObj *kueue.ClusterQueue) field.ErrorList {
 	var allErrs field.ErrorList
 	allErrs = append(allErrs, ValidateClusterQueue(newObj)...)
 	allErrs = append(allErrs, apivalidation.ValidateImmutableField(newObj.Spec.QueueingStrategy, oldObj.Spec.QueueingStrategy, field.NewPath("spec", "queueingStrategy"))...)
+	allErrs = append(allErrs, apivalidation.ValidateImmutableField(newObj.Spec.OrderingPolicy, oldObj.Spec.OrderingPolicy, field.NewPath("spec", "orderingPolicy"))...)
 	return allErrs
 }
 
@@ -129,7 +158,7 @@ func validateResources(resources []kueue.Resource, path *field.Path) field.Error
 		for j, flavor := range resource.Flavors {
 			path := path.Child("flavors").Index(j)
 			allErrs = append(allErrs, validateNameReference(string(flavor.Name), path.Child("name"))...)
-			allErrs = append(allErrs, validateFlavorQuota(flavor, path.Child("quota"))...)
+			allErrs = append(allErrs, validateFlavorQuota(flavor.Name, flavor.Quota, path.Child("quota"))...)
 			flavorsPerRes[i].Insert(string(flavor.Name))
 		}
 		for j := 0; j < i; j++ {
@@ -143,14 +172,224 @@ func validateResources(resources []kueue.Resource, path *field.Path) field.Error
 	return allErrs
 }
 
-func validateFlavorQuota(flavor kueue.Flavor, path *field.Path) field.ErrorList {
+// validateResourceGroups validates the resource groups, which can't share
+// resources or flavors with each other or with the resources.
+func validateResourceGroups(groups []kueue.ResourceGroup, resources []kueue.Resource, path *field.Path) field.ErrorList {
 	var allErrs field.ErrorList
-	allErrs = append(allErrs, validateResourceQuantity(flavor.Quota.Min, path.Child("min"))...)
+	seenResources := sets.NewString()
+	seenFlavors := sets.NewString()
+	for _, r := range resources {
+		seenResources.Insert(string(r.Name))
+		for _, f := range r.Flavors {
+			seenFlavors.Insert(string(f.Name))
+		}
+	}
+	for i, group := range groups {
+		path := path.Index(i)
+		covered := sets.NewString()
+		for j, rName := range group.CoveredResources {
+			path := path.Child("coveredResources").Index(j)
+			allErrs = append(allErrs, validateResourceName(rName, path)...)
+			if seenResources.Has(string(rName)) {
+				allErrs = append(allErrs, field.Duplicate(path, rName))
+			}
+			seenResources.Insert(string(rName))
+			covered.Insert(string(rName))
+		}
+		for j, flavor := range group.Flavors {
+			path := path.Child("flavors").Index(j)
+			allErrs = append(allErrs, validateNameReference(string(flavor.Name), path.Child("name"))...)
+			if seenFlavors.Has(string(flavor.Name)) {
+				allErrs = append(allErrs, field.Invalid(path.Child("name"), flavor.Name, "is already used by another resource group or resource"))
+			}
+			seenFlavors.Insert(string(flavor.Name))
+			quotas := sets.NewString()
+			for k, rq := range flavor.Resources {
+				path := path.Child("resources").Index(k)
+				if !covered.Has(string(rq.Name)) {
+					allErrs = append(allErrs, field.NotSupported(path.Child("name"), rq.Name, covered.List()))
+				}
+				quotas.Insert(string(rq.Name))
+				allErrs = append(allErrs, validateFlavorQuota(flavor.Name, rq.Quota, path.Child("quota"))...)
+			}
+			if missing := covered.Difference(quotas); missing.Len() > 0 {
+				allErrs = append(allErrs, field.Required(path.Child("resources"), fmt.Sprintf("must define the quota of the covered resources %v", missing.List())))
+			}
+		}
+	}
+	return allErrs
+}
 
-	if flavor.Quota.Max != nil {
-		allErrs = append(allErrs, validateResourceQuantity(*flavor.Quota.Max, path.Child("max"))...)
-		if flavor.Quota.Min.Cmp(*flavor.Quota.Max) > 0 {
-			allErrs = append(allErrs, field.Invalid(path.Child("min"), flavor.Quota.Min.String(), fmt.Sprintf("must be less than or equal to %s max", flavor.Name)))
+func validatePriorityBands(bands []kueue.PriorityBand, path *field.Path) field.ErrorList {
+	var allErrs field.ErrorList
+	names := sets.NewString()
+	minPriorities := sets.NewInt32()
+	for i, band := range bands {
+		path := path.Index(i)
+		allErrs = append(allErrs, validateNameReference(band.Name, path.Child("name"))...)
+		if names.Has(band.Name) {
+			allErrs = append(allErrs, field.Duplicate(path.Child("name"), band.Name))
+		}
+		names.Insert(band.Name)
+		if minPriorities.Has(band.MinPriority) {
+			allErrs = append(allErrs, field.Duplicate(path.Child("minPriority"), band.MinPriority))
+		}
+		minPriorities.Insert(band.MinPriority)
+		if band.Weight < 0 {
+			allErrs = append(allErrs, field.Invalid(path.Child("weight"), band.Weight, "must be greater than 0"))
+		}
+	}
+	return allErrs
+}
+
+// validateReservedTiers validates that the reserved tiers have unique names
+// and minPriorities, and that they don't reserve more than the whole quota.
+func validateReservedTiers(tiers []kueue.ReservedTier, path *field.Path) field.ErrorList {
+	var allErrs field.ErrorList
+	names := sets.NewString()
+	minPriorities := sets.NewInt32()
+	var total int32
+	for i, tier := range tiers {
+		path := path.Index(i)
+		allErrs = append(allErrs, validateNameReference(tier.Name, path.Child("name"))...)
+		if names.Has(tier.Name) {
+			allErrs = append(allErrs, field.Duplicate(path.Child("name"), tier.Name))
+		}
+		names.Insert(tier.Name)
+		if minPriorities.Has(tier.MinPriority) {
+			allErrs = append(allErrs, field.Duplicate(path.Child("minPriority"), tier.MinPriority))
+		}
+		minPriorities.Insert(tier.MinPriority)
+		if tier.Percent < 1 || tier.Percent > 100 {
+			allErrs = append(allErrs, field.Invalid(path.Child("percent"), tier.Percent, "must be between 1 and 100"))
+			continue
+		}
+		total += tier.Percent
+	}
+	if total > 100 {
+		allErrs = append(allErrs, field.Invalid(path, total, "the percents of the tiers must add up to 100 or less"))
+	}
+	return allErrs
+}
+
+// validateQuotaWindows validates the quota windows, which can only override
+// the quota of the flavors that the ClusterQueue defines for each resource.
+func validateQuotaWindows(cq *kueue.ClusterQueue, path *field.Path) field.ErrorList {
+	var allErrs field.ErrorList
+	flavors := make(map[string]sets.String)
+	for _, r := range utilapi.ClusterQueueResources(cq) {
+		flavors[string(r.Name)] = sets.NewString()
+		for _, f := range r.Flavors {
+			flavors[string(r.Name)].Insert(string(f.Name))
+		}
+	}
+	names := sets.NewString()
+	for i, w := range cq.Spec.QuotaWindows {
+		path := path.Index(i)
+		allErrs = append(allErrs, validateNameReference(w.Name, path.Child("name"))...)
+		if names.Has(w.Name) {
+			allErrs = append(allErrs, field.Duplicate(path.Child("name"), w.Name))
+		}
+		names.Insert(w.Name)
+		if _, err := time.Parse(utilapi.TimeOfDayLayout, w.Start); err != nil {
+			allErrs = append(allErrs, field.Invalid(path.Child("start"), w.Start, "must be a time of the day in the HH:MM format"))
+		}
+		if _, err := time.Parse(utilapi.TimeOfDayLayout, w.End); err != nil {
+			allErrs = append(allErrs, field.Invalid(path.Child("end"), w.End, "must be a time of the day in the HH:MM format"))
+		}
+		if w.TimeZone != "" {
+			if _, err := time.LoadLocation(w.TimeZone); err != nil {
+				allErrs = append(allErrs, field.Invalid(path.Child("timeZone"), w.TimeZone, "must be a valid IANA time zone name"))
+			}
+		}
+		for j, q := range w.Quotas {
+			path := path.Child("quotas").Index(j)
+			rFlavors, ok := flavors[string(q.Resource)]
+			if !ok {
+				allErrs = append(allErrs, field.NotFound(path.Child("resource"), q.Resource))
+				continue
+			}
+			if !rFlavors.Has(string(q.Flavor)) {
+				allErrs = append(allErrs, field.NotSupported(path.Child("flavor"), q.Flavor, rFlavors.List()))
+				continue
+			}
+			allErrs = append(allErrs, validateFlavorQuota(q.Flavor, q.Quota, path.Child("quota"))...)
+		}
+	}
+	return allErrs
+}
+
+// validateBudgets validates that there is at most one budget for each
+// resource, flavor and period, with a non-negative limit.
+func validateBudgets(budgets []kueue.Budget, path *field.Path) field.ErrorList {
+	var allErrs field.ErrorList
+	seen := sets.NewString()
+	for i, b := range budgets {
+		path := path.Index(i)
+		key := fmt.Sprintf("%s/%s/%s", b.Resource, b.Flavor, b.Period)
+		if seen.Has(key) {
+			allErrs = append(allErrs, field.Duplicate(path, key))
+		}
+		seen.Insert(key)
+		allErrs = append(allErrs, validateResourceQuantity(b.Limit, path.Child("limit"))...)
+	}
+	return allErrs
+}
+
+// validateFlavorFallbacks validates that each flavor falls back at most once,
+// to another flavor that the ClusterQueue defines for all the resources of
+// the flavor.
+func validateFlavorFallbacks(cq *kueue.ClusterQueue, path *field.Path) field.ErrorList {
+	var allErrs field.ErrorList
+	flavors := make(map[string]sets.String)
+	for _, r := range utilapi.ClusterQueueResources(cq) {
+		flavors[string(r.Name)] = sets.NewString()
+		for _, f := range r.Flavors {
+			flavors[string(r.Name)].Insert(string(f.Name))
+		}
+	}
+	seen := sets.NewString()
+	for i, fb := range cq.Spec.FlavorFallbacks {
+		path := path.Index(i)
+		if seen.Has(string(fb.Flavor)) {
+			allErrs = append(allErrs, field.Duplicate(path.Child("flavor"), fb.Flavor))
+		}
+		seen.Insert(string(fb.Flavor))
+		if fb.FallbackFlavor == fb.Flavor {
+			allErrs = append(allErrs, field.Invalid(path.Child("fallbackFlavor"), fb.FallbackFlavor, "must be different from the flavor"))
+		}
+		if fb.MaxEvictions < 1 || fb.MaxEvictions > 15 {
+			allErrs = append(allErrs, field.Invalid(path.Child("maxEvictions"), fb.MaxEvictions, "must be between 1 and 15"))
+		}
+		if fb.Window.Duration <= 0 {
+			allErrs = append(allErrs, field.Invalid(path.Child("window"), fb.Window, "must be greater than 0"))
+		}
+		found := false
+		for _, rFlavors := range flavors {
+			if !rFlavors.Has(string(fb.Flavor)) {
+				continue
+			}
+			found = true
+			if !rFlavors.Has(string(fb.FallbackFlavor)) {
+				allErrs = append(allErrs, field.NotSupported(path.Child("fallbackFlavor"), fb.FallbackFlavor, rFlavors.List()))
+				break
+			}
+		}
+		if !found {
+			allErrs = append(allErrs, field.NotFound(path.Child("flavor"), fb.Flavor))
+		}
+	}
+	return allErrs
+}
+
+func validateFlavorQuota(flavor kueue.ResourceFlavorReference, quota kueue.Quota, path *field.Path) field.ErrorList {
+	var allErrs field.ErrorList
+	allErrs = append(allErrs, validateResourceQuantity(quota.Min, path.Child("min"))...)
+
+	if quota.Max != nil {
+		allErrs = append(allErrs, validateResourceQuantity(*quota.Max, path.Child("max"))...)
+		if quota.Min.Cmp(*quota.Max) > 0 {
+			allErrs = append(allErrs, field.Invalid(path.Child("min"), quota.Min.String(), fmt.Sprintf("must be less than or equal to %s max", flavor)))
 		}
 	}
 	return allErrs
@@ -180,3 +419,20 @@ func validateResourceQuantity(value resource.Quantity, fldPath *field.Path) fiel
 func validateNamespaceSelector(selector *metav1.LabelSelector, path *field.Path) field.ErrorList {
 	return validation.ValidateLabelSelector(selector, path)
 }
+
+func validatePreemption(preemption *kueue.ClusterQueuePreemption, path *field.Path) field.ErrorList {
+	var allErrs field.ErrorList
+	switch preemption.ReclaimWithinCohort {
+	case "", kueue.PreemptionPolicyNever, kueue.PreemptionPolicyLowerPriority, kueue.PreemptionPolicyAny:
+	default:
+		allErrs = append(allErrs, field.NotSupported(path.Child("reclaimWithinCohort"), preemption.ReclaimWithinCohort,
+			[]string{string(kueue.PreemptionPolicyNever), string(kueue.PreemptionPolicyLowerPriority), string(kueue.PreemptionPolicyAny)}))
+	}
+	switch preemption.WithinClusterQueue {
+	case "", kueue.PreemptionPolicyNever, kueue.PreemptionPolicyLowerPriority:
+	default:
+		allErrs = append(allErrs, field.NotSupported(path.Child("withinClusterQueue"), preemption.WithinClusterQueue,
+			[]string{string(kueue.PreemptionPolicyNever), string(kueue.PreemptionPolicyLowerPriority)}))
+	}
+	return allErrs
+}
@@ -18,9 +18,12 @@ package webhooks
 
 import (
 	"testing"
+	"time"
 
 	"github.com/google/go-cmp/cmp"
 	"github.com/google/go-cmp/cmp/cmpopts"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/util/validation/field"
 
@@ -123,6 +126,24 @@ func TestValidateClusterQueue(t *testing.T) {
 				field.Invalid(resourceField.Index(0).Child("flavors").Index(0).Child("quota", "min"), "2", ""),
 			},
 		},
+		{
+			name: "flavor quota with string quantities and min less than max",
+			clusterQueue: testingutil.MakeClusterQueue("cluster-queue").
+				Resource(testingutil.MakeResource("cpu").Flavor(testingutil.MakeFlavor("x86", "500m").Max("2").Obj()).Obj()).
+				Resource(testingutil.MakeResource("memory").Flavor(testingutil.MakeFlavor("x86", "10Gi").Max("20Gi").Obj()).Obj()).
+				Obj(),
+		},
+		{
+			name: "flavor quota with string quantities and min greater than max",
+			clusterQueue: testingutil.MakeClusterQueue("cluster-queue").
+				Resource(testingutil.MakeResource("cpu").Flavor(testingutil.MakeFlavor("x86", "1500m").Max("1").Obj()).Obj()).
+				Resource(testingutil.MakeResource("memory").Flavor(testingutil.MakeFlavor("x86", "10Gi").Max("500Mi").Obj()).Obj()).
+				Obj(),
+			wantErr: field.ErrorList{
+				field.Invalid(resourceField.Index(0).Child("flavors").Index(0).Child("quota", "min"), "1500m", ""),
+				field.Invalid(resourceField.Index(1).Child("flavors").Index(0).Child("quota", "min"), "10Gi", ""),
+			},
+		},
 		{
 			name:         "empty queueing strategy is supported",
 			clusterQueue: testingutil.MakeClusterQueue("cluster-queue").Obj(),
@@ -193,6 +214,221 @@ func TestValidateClusterQueue(t *testing.T) {
 				field.Invalid(specField.Child("resources").Index(1).Child("flavors"), nil, ""),
 			},
 		},
+		{
+			name: "resource group with flavors for several resources",
+			clusterQueue: testingutil.MakeClusterQueue("cluster-queue").
+				ResourceGroup(testingutil.MakeResourceGroup("cpu", "memory").
+					Flavor("alpha", "cpu", "10", "memory", "10Gi").
+					Flavor("beta", "cpu", "20", "memory", "20Gi").Obj()).
+				Resource(testingutil.MakeResource("example.com/gpu").
+					Flavor(testingutil.MakeFlavor("gamma", "0").Obj()).Obj()).
+				Obj(),
+		},
+		{
+			name: "resource group covering a resource that is already defined",
+			clusterQueue: testingutil.MakeClusterQueue("cluster-queue").
+				Resource(testingutil.MakeResource("cpu").
+					Flavor(testingutil.MakeFlavor("alpha", "0").Obj()).Obj()).
+				ResourceGroup(testingutil.MakeResourceGroup("cpu").
+					Flavor("beta", "cpu", "10").Obj()).
+				ResourceGroup(testingutil.MakeResourceGroup("memory", "memory").
+					Flavor("gamma", "memory", "10Gi").Obj()).
+				Obj(),
+			wantErr: field.ErrorList{
+				field.Duplicate(specField.Child("resourceGroups").Index(0).Child("coveredResources").Index(0), nil),
+				field.Duplicate(specField.Child("resourceGroups").Index(1).Child("coveredResources").Index(1), nil),
+			},
+		},
+		{
+			name: "resource groups sharing a flavor",
+			clusterQueue: testingutil.MakeClusterQueue("cluster-queue").
+				ResourceGroup(testingutil.MakeResourceGroup("cpu").
+					Flavor("alpha", "cpu", "10").Obj()).
+				ResourceGroup(testingutil.MakeResourceGroup("memory").
+					Flavor("alpha", "memory", "10Gi").Obj()).
+				Obj(),
+			wantErr: field.ErrorList{
+				field.Invalid(specField.Child("resourceGroups").Index(1).Child("flavors").Index(0).Child("name"), nil, ""),
+			},
+		},
+		{
+			name: "resource group with missing or uncovered quotas",
+			clusterQueue: testingutil.MakeClusterQueue("cluster-queue").
+				ResourceGroup(testingutil.MakeResourceGroup("cpu", "memory").
+					Flavor("alpha", "cpu", "10", "example.com/gpu", "1").Obj()).
+				Obj(),
+			wantErr: field.ErrorList{
+				field.NotSupported(specField.Child("resourceGroups").Index(0).Child("flavors").Index(0).Child("resources").Index(1).Child("name"), nil, nil),
+				field.Required(specField.Child("resourceGroups").Index(0).Child("flavors").Index(0).Child("resources"), ""),
+			},
+		},
+		{
+			name: "valid preemption policies",
+			clusterQueue: testingutil.MakeClusterQueue("cluster-queue").
+				Preemption(kueue.ClusterQueuePreemption{
+					ReclaimWithinCohort: kueue.PreemptionPolicyAny,
+					WithinClusterQueue:  kueue.PreemptionPolicyLowerPriority,
+				}).Obj(),
+		},
+		{
+			name: "unsupported preemption policies",
+			clusterQueue: testingutil.MakeClusterQueue("cluster-queue").
+				Preemption(kueue.ClusterQueuePreemption{
+					ReclaimWithinCohort: "Sometimes",
+					WithinClusterQueue:  kueue.PreemptionPolicyAny,
+				}).Obj(),
+			wantErr: field.ErrorList{
+				field.NotSupported(specField.Child("preemption", "reclaimWithinCohort"), nil, nil),
+				field.NotSupported(specField.Child("preemption", "withinClusterQueue"), nil, nil),
+			},
+		},
+		{
+			name:         "backfill with StrictFIFO",
+			clusterQueue: testingutil.MakeClusterQueue("cluster-queue").QueueingStrategy(kueue.StrictFIFO).Backfill(true).Obj(),
+		},
+		{
+			name:         "backfill with BestEffortFIFO",
+			clusterQueue: testingutil.MakeClusterQueue("cluster-queue").QueueingStrategy(kueue.BestEffortFIFO).Backfill(true).Obj(),
+			wantErr: field.ErrorList{
+				field.Invalid(specField.Child("backfill"), nil, ""),
+			},
+		},
+		{
+			name:         "head blocking timeout with StrictFIFO",
+			clusterQueue: testingutil.MakeClusterQueue("cluster-queue").QueueingStrategy(kueue.StrictFIFO).HeadBlockingTimeout(time.Minute).Obj(),
+		},
+		{
+			name:         "head blocking timeout with BestEffortFIFO",
+			clusterQueue: testingutil.MakeClusterQueue("cluster-queue").QueueingStrategy(kueue.BestEffortFIFO).HeadBlockingTimeout(time.Minute).Obj(),
+			wantErr: field.ErrorList{
+				field.Invalid(specField.Child("headBlockingTimeout"), nil, ""),
+			},
+		},
+		{
+			name:         "zero head blocking timeout",
+			clusterQueue: testingutil.MakeClusterQueue("cluster-queue").QueueingStrategy(kueue.StrictFIFO).HeadBlockingTimeout(0).Obj(),
+			wantErr: field.ErrorList{
+				field.Invalid(specField.Child("headBlockingTimeout"), nil, ""),
+			},
+		},
+		{
+			name:         "valid fair sharing weight",
+			clusterQueue: testingutil.MakeClusterQueue("cluster-queue").FairWeight(resource.MustParse("0.5")).Obj(),
+		},
+		{
+			name:         "negative fair sharing weight",
+			clusterQueue: testingutil.MakeClusterQueue("cluster-queue").FairWeight(resource.MustParse("-1")).Obj(),
+			wantErr: field.ErrorList{
+				field.Invalid(specField.Child("fairSharing", "weight"), nil, ""),
+			},
+		},
+		{
+			name:         "valid priority bands",
+			clusterQueue: testingutil.MakeClusterQueue("cluster-queue").PriorityBand("high", 1000, 7).PriorityBand("low", 0, 3).Obj(),
+		},
+		{
+			name: "invalid priority bands",
+			clusterQueue: testingutil.MakeClusterQueue("cluster-queue").
+				PriorityBand("high", 1000, 7).
+				PriorityBand("high", 0, 3).
+				PriorityBand("low", 1000, -1).
+				Obj(),
+			wantErr: field.ErrorList{
+				field.Duplicate(specField.Child("priorityBands").Index(1).Child("name"), nil),
+				field.Duplicate(specField.Child("priorityBands").Index(2).Child("minPriority"), nil),
+				field.Invalid(specField.Child("priorityBands").Index(2).Child("weight"), nil, ""),
+			},
+		},
+		{
+			name:         "valid reserved tiers",
+			clusterQueue: testingutil.MakeClusterQueue("cluster-queue").ReservedTier("urgent", 1000, 20).ReservedTier("high", 100, 30).Obj(),
+		},
+		{
+			name: "invalid reserved tiers",
+			clusterQueue: testingutil.MakeClusterQueue("cluster-queue").
+				ReservedTier("urgent", 1000, 60).
+				ReservedTier("urgent", 100, 50).
+				ReservedTier("high", 1000, 0).
+				Obj(),
+			wantErr: field.ErrorList{
+				field.Duplicate(specField.Child("reservedTiers").Index(1).Child("name"), nil),
+				field.Duplicate(specField.Child("reservedTiers").Index(2).Child("minPriority"), nil),
+				field.Invalid(specField.Child("reservedTiers").Index(2).Child("percent"), nil, ""),
+				field.Invalid(specField.Child("reservedTiers"), nil, ""),
+			},
+		},
+		{
+			name: "valid quota windows",
+			clusterQueue: testingutil.MakeClusterQueue("cluster-queue").
+				Resource(testingutil.MakeResource(corev1.ResourceCPU).Flavor(testingutil.MakeFlavor("default", "10").Obj()).Obj()).
+				QuotaWindow(testingutil.MakeQuotaWindow("off-hours", "20:00", "06:00").TimeZone("UTC").Quota(corev1.ResourceCPU, "default", "20").Obj()).
+				QuotaWindow(testingutil.MakeQuotaWindow("weekend", "00:00", "00:00").Days("Saturday", "Sunday").Quota(corev1.ResourceCPU, "default", "30").Obj()).
+				Obj(),
+		},
+		{
+			name: "invalid quota windows",
+			clusterQueue: testingutil.MakeClusterQueue("cluster-queue").
+				Resource(testingutil.MakeResource(corev1.ResourceCPU).Flavor(testingutil.MakeFlavor("default", "10").Obj()).Obj()).
+				QuotaWindow(testingutil.MakeQuotaWindow("off-hours", "25:00", "6am").TimeZone("Mars/Olympus").Quota(corev1.ResourceCPU, "default", "-1").Obj()).
+				QuotaWindow(testingutil.MakeQuotaWindow("off-hours", "20:00", "06:00").Quota(corev1.ResourceMemory, "default", "1Gi").Quota(corev1.ResourceCPU, "spot", "1").Obj()).
+				Obj(),
+			wantErr: field.ErrorList{
+				field.Invalid(specField.Child("quotaWindows").Index(0).Child("start"), nil, ""),
+				field.Invalid(specField.Child("quotaWindows").Index(0).Child("end"), nil, ""),
+				field.Invalid(specField.Child("quotaWindows").Index(0).Child("timeZone"), nil, ""),
+				field.Invalid(specField.Child("quotaWindows").Index(0).Child("quotas").Index(0).Child("quota", "min"), nil, ""),
+				field.Duplicate(specField.Child("quotaWindows").Index(1).Child("name"), nil),
+				field.NotFound(specField.Child("quotaWindows").Index(1).Child("quotas").Index(0).Child("resource"), nil),
+				field.NotSupported(specField.Child("quotaWindows").Index(1).Child("quotas").Index(1).Child("flavor"), nil, nil),
+			},
+		},
+		{
+			name: "valid budgets",
+			clusterQueue: testingutil.MakeClusterQueue("cluster-queue").
+				Budget("nvidia.com/gpu", "", kueue.BudgetPeriodMonth, "10000").
+				Budget("nvidia.com/gpu", "a100", kueue.BudgetPeriodMonth, "2000").
+				Budget("nvidia.com/gpu", "", kueue.BudgetPeriodDay, "500").
+				Obj(),
+		},
+		{
+			name: "invalid budgets",
+			clusterQueue: testingutil.MakeClusterQueue("cluster-queue").
+				Budget("nvidia.com/gpu", "", kueue.BudgetPeriodMonth, "-1").
+				Budget("nvidia.com/gpu", "", kueue.BudgetPeriodMonth, "10").
+				Obj(),
+			wantErr: field.ErrorList{
+				field.Invalid(specField.Child("budgets").Index(0).Child("limit"), nil, ""),
+				field.Duplicate(specField.Child("budgets").Index(1), nil),
+			},
+		},
+		{
+			name: "valid flavor fallbacks",
+			clusterQueue: testingutil.MakeClusterQueue("cluster-queue").
+				Resource(testingutil.MakeResource(corev1.ResourceCPU).
+					Flavor(testingutil.MakeFlavor("spot", "10").Obj()).
+					Flavor(testingutil.MakeFlavor("on-demand", "10").Obj()).Obj()).
+				FlavorFallback("spot", "on-demand", 2, time.Hour).
+				Obj(),
+		},
+		{
+			name: "invalid flavor fallbacks",
+			clusterQueue: testingutil.MakeClusterQueue("cluster-queue").
+				Resource(testingutil.MakeResource(corev1.ResourceCPU).
+					Flavor(testingutil.MakeFlavor("spot", "10").Obj()).
+					Flavor(testingutil.MakeFlavor("on-demand", "10").Obj()).Obj()).
+				FlavorFallback("spot", "reserved", 0, time.Hour).
+				FlavorFallback("spot", "spot", 2, 0).
+				FlavorFallback("preemptible", "on-demand", 2, time.Hour).
+				Obj(),
+			wantErr: field.ErrorList{
+				field.Invalid(specField.Child("flavorFallbacks").Index(0).Child("maxEvictions"), nil, ""),
+				field.NotSupported(specField.Child("flavorFallbacks").Index(0).Child("fallbackFlavor"), nil, nil),
+				field.Duplicate(specField.Child("flavorFallbacks").Index(1).Child("flavor"), nil),
+				field.Invalid(specField.Child("flavorFallbacks").Index(1).Child("fallbackFlavor"), nil, ""),
+				field.Invalid(specField.Child("flavorFallbacks").Index(1).Child("window"), nil, ""),
+				field.NotFound(specField.Child("flavorFallbacks").Index(2).Child("flavor"), nil),
+			},
+		},
 	}
 
 	for _, tc := range testcases {
@@ -226,6 +462,14 @@ func TestValidateClusterQueueUpdate(t *testing.T) {
 			oldClusterQueue: testingutil.MakeClusterQueue("cluster-queue").QueueingStrategy("BestEffortFIFO").Obj(),
 			wantErr:         nil,
 		},
+		{
+			name:            "orderingPolicy cannot be updated",
+			newClusterQueue: testingutil.MakeClusterQueue("cluster-queue").OrderingPolicy("EarliestDeadlineFirst").Obj(),
+			oldClusterQueue: testingutil.MakeClusterQueue("cluster-queue").OrderingPolicy("Priority").Obj(),
+			wantErr: field.ErrorList{
+				field.Invalid(field.NewPath("spec", "orderingPolicy"), nil, ""),
+			},
+		},
 	}
 
 	for _, tc := range testcases {
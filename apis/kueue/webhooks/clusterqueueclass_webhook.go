@@ -0,0 +1,81 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package webhooks
+
+import (
+	"context"
+
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	"k8s.io/klog/v2"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/webhook"
+
+	kueue "sigs.k8s.io/kueue/apis/kueue/v1alpha2"
+)
+
+type ClusterQueueClassWebhook struct{}
+
+func setupWebhookForClusterQueueClass(mgr ctrl.Manager) error {
+	return ctrl.NewWebhookManagedBy(mgr).
+		For(&kueue.ClusterQueueClass{}).
+		WithValidator(&ClusterQueueClassWebhook{}).
+		Complete()
+}
+
+// +kubebuilder:webhook:path=/validate-kueue-x-k8s-io-v1alpha2-clusterqueueclass,mutating=false,failurePolicy=fail,sideEffects=None,groups=kueue.x-k8s.io,resources=clusterqueueclasses,verbs=create;update,versions=v1alpha2,name=vclusterqueueclass.kb.io,admissionReviewVersions=v1
+
+var _ webhook.CustomValidator = &ClusterQueueClassWebhook{}
+
+// ValidateCreate implements webhook.CustomValidator so a webhook will be registered for the type
+func (w *ClusterQueueClassWebhook) ValidateCreate(ctx context.Context, obj runtime.Object) error {
+	class := obj.(*kueue.ClusterQueueClass)
+	log := ctrl.LoggerFrom(ctx).WithName("clusterqueueclass-webhook")
+	log.V(5).Info("Validating create", "clusterQueueClass", klog.KObj(class))
+	return ValidateClusterQueueClass(class).ToAggregate()
+}
+
+// ValidateUpdate implements webhook.CustomValidator so a webhook will be registered for the type
+func (w *ClusterQueueClassWebhook) ValidateUpdate(ctx context.Context, oldObj, newObj runtime.Object) error {
+	class := newObj.(*kueue.ClusterQueueClass)
+	log := ctrl.LoggerFrom(ctx).WithName("clusterqueueclass-webhook")
+	log.V(5).Info("Validating update", "clusterQueueClass", klog.KObj(class))
+	return ValidateClusterQueueClass(class).ToAggregate()
+}
+
+// ValidateDelete implements webhook.CustomValidator so a webhook will be registered for the type
+func (w *ClusterQueueClassWebhook) ValidateDelete(ctx context.Context, obj runtime.Object) error {
+	return nil
+}
+
+// ValidateClusterQueueClass validates the fields of the class with the same
+// rules as the fields of a ClusterQueue that they replace.
+func ValidateClusterQueueClass(class *kueue.ClusterQueueClass) field.ErrorList {
+	path := field.NewPath("spec")
+
+	var allErrs field.ErrorList
+	if len(class.Spec.Cohort) != 0 {
+		allErrs = append(allErrs, validateNameReference(class.Spec.Cohort, path.Child("cohort"))...)
+	}
+	allErrs = append(allErrs, validateResources(class.Spec.Resources, path.Child("resources"))...)
+	allErrs = append(allErrs, validateResourceGroups(class.Spec.ResourceGroups, class.Spec.Resources, path.Child("resourceGroups"))...)
+	allErrs = append(allErrs, validateNamespaceSelector(class.Spec.NamespaceSelector, path.Child("namespaceSelector"))...)
+	if class.Spec.Preemption != nil {
+		allErrs = append(allErrs, validatePreemption(class.Spec.Preemption, path.Child("preemption"))...)
+	}
+	return allErrs
+}
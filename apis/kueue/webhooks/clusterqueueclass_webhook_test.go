@@ -0,0 +1,80 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package webhooks
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+
+	. "sigs.k8s.io/kueue/apis/kueue/v1alpha2"
+	testingutil "sigs.k8s.io/kueue/pkg/util/testing"
+)
+
+func TestValidateClusterQueueClass(t *testing.T) {
+	specPath := field.NewPath("spec")
+	testCases := map[string]struct {
+		class   *ClusterQueueClass
+		wantErr field.ErrorList
+	}{
+		"should accept a class with quotas, cohort, preemption and namespaceSelector": {
+			class: testingutil.MakeClusterQueueClass("team").
+				Cohort("org").
+				Resource(testingutil.MakeResource(corev1.ResourceCPU).Flavor(testingutil.MakeFlavor("default", "10").Obj()).Obj()).
+				NamespaceSelector(&metav1.LabelSelector{MatchLabels: map[string]string{"team": "a"}}).
+				Preemption(ClusterQueuePreemption{ReclaimWithinCohort: PreemptionPolicyAny}).
+				Obj(),
+		},
+		"should accept an empty class": {
+			class: testingutil.MakeClusterQueueClass("empty").Obj(),
+		},
+		"should reject an invalid cohort": {
+			class: testingutil.MakeClusterQueueClass("team").Cohort("invalid_cohort").Obj(),
+			wantErr: field.ErrorList{
+				field.Invalid(specPath.Child("cohort"), "invalid_cohort", ""),
+			},
+		},
+		"should reject a negative quota": {
+			class: testingutil.MakeClusterQueueClass("team").
+				Resource(testingutil.MakeResource(corev1.ResourceCPU).Flavor(testingutil.MakeFlavor("default", "-1").Obj()).Obj()).
+				Obj(),
+			wantErr: field.ErrorList{
+				field.Invalid(specPath.Child("resources").Index(0).Child("flavors").Index(0).Child("quota", "min"), "-1", ""),
+			},
+		},
+		"should reject an unsupported preemption policy": {
+			class: testingutil.MakeClusterQueueClass("team").
+				Preemption(ClusterQueuePreemption{WithinClusterQueue: PreemptionPolicyAny}).
+				Obj(),
+			wantErr: field.ErrorList{
+				field.NotSupported(specPath.Child("preemption", "withinClusterQueue"), "", nil),
+			},
+		},
+	}
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			errList := ValidateClusterQueueClass(tc.class)
+			if diff := cmp.Diff(tc.wantErr, errList, cmpopts.IgnoreFields(field.Error{}, "Detail", "BadValue")); diff != "" {
+				t.Errorf("ValidateClusterQueueClass() mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
@@ -0,0 +1,123 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package webhooks
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	"k8s.io/klog/v2"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/webhook"
+
+	kueue "sigs.k8s.io/kueue/apis/kueue/v1alpha2"
+)
+
+type CohortWebhook struct {
+	client client.Client
+}
+
+func setupWebhookForCohort(mgr ctrl.Manager) error {
+	return ctrl.NewWebhookManagedBy(mgr).
+		For(&kueue.Cohort{}).
+		WithValidator(&CohortWebhook{client: mgr.GetClient()}).
+		Complete()
+}
+
+// +kubebuilder:webhook:path=/validate-kueue-x-k8s-io-v1alpha2-cohort,mutating=false,failurePolicy=fail,sideEffects=None,groups=kueue.x-k8s.io,resources=cohorts,verbs=create;update,versions=v1alpha2,name=vcohort.kb.io,admissionReviewVersions=v1
+
+var _ webhook.CustomValidator = &CohortWebhook{}
+
+// ValidateCreate implements webhook.CustomValidator so a webhook will be registered for the type
+func (w *CohortWebhook) ValidateCreate(ctx context.Context, obj runtime.Object) error {
+	cohort := obj.(*kueue.Cohort)
+	log := ctrl.LoggerFrom(ctx).WithName("cohort-webhook")
+	log.V(5).Info("Validating create", "cohort", klog.KObj(cohort))
+	allErrs := ValidateCohort(cohort)
+	allErrs = append(allErrs, w.validateParentCycle(ctx, cohort)...)
+	return allErrs.ToAggregate()
+}
+
+// ValidateUpdate implements webhook.CustomValidator so a webhook will be registered for the type
+func (w *CohortWebhook) ValidateUpdate(ctx context.Context, oldObj, newObj runtime.Object) error {
+	newCohort := newObj.(*kueue.Cohort)
+	log := ctrl.LoggerFrom(ctx).WithName("cohort-webhook")
+	log.V(5).Info("Validating update", "cohort", klog.KObj(newCohort))
+	allErrs := ValidateCohort(newCohort)
+	allErrs = append(allErrs, w.validateParentCycle(ctx, newCohort)...)
+	return allErrs.ToAggregate()
+}
+
+// ValidateDelete implements webhook.CustomValidator so a webhook will be registered for the type
+func (w *CohortWebhook) ValidateDelete(ctx context.Context, obj runtime.Object) error {
+	return nil
+}
+
+func ValidateCohort(cohort *kueue.Cohort) field.ErrorList {
+	var allErrs field.ErrorList
+	if parent := cohort.Spec.Parent; parent != "" {
+		parentPath := field.NewPath("spec", "parent")
+		allErrs = append(allErrs, validateNameReference(parent, parentPath)...)
+		if parent == cohort.Name {
+			allErrs = append(allErrs, field.Invalid(parentPath, parent, "must not be the cohort itself"))
+		}
+	}
+	resourcesPath := field.NewPath("spec", "resources")
+	for i, res := range cohort.Spec.Resources {
+		path := resourcesPath.Index(i)
+		allErrs = append(allErrs, validateResourceName(res.Name, path.Child("name"))...)
+		for j, flavor := range res.Flavors {
+			path := path.Child("flavors").Index(j)
+			allErrs = append(allErrs, validateNameReference(string(flavor.Name), path.Child("name"))...)
+			allErrs = append(allErrs, validateFlavorQuota(flavor.Name, flavor.Quota, path.Child("quota"))...)
+		}
+	}
+	return allErrs
+}
+
+// validateParentCycle rejects a parent that has the cohort among its
+// ancestors, as observed from the existing Cohort objects. The parents that
+// don't have a Cohort object end the chain.
+func (w *CohortWebhook) validateParentCycle(ctx context.Context, cohort *kueue.Cohort) field.ErrorList {
+	if w.client == nil || cohort.Spec.Parent == "" || cohort.Spec.Parent == cohort.Name {
+		return nil
+	}
+	visited := sets.NewString(cohort.Name)
+	chain := []string{cohort.Name}
+	for name := cohort.Spec.Parent; name != ""; {
+		chain = append(chain, name)
+		if visited.Has(name) {
+			return field.ErrorList{field.Invalid(field.NewPath("spec", "parent"), cohort.Spec.Parent,
+				fmt.Sprintf("would create a cycle of cohorts: %s", strings.Join(chain, " -> ")))}
+		}
+		visited.Insert(name)
+		var parent kueue.Cohort
+		if err := w.client.Get(ctx, types.NamespacedName{Name: name}, &parent); err != nil {
+			// The parent doesn't have a Cohort object, or it can't be read.
+			// The cache ignores the parents that create a cycle anyway.
+			return nil
+		}
+		name = parent.Spec.Parent
+	}
+	return nil
+}
@@ -0,0 +1,140 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package webhooks
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	. "sigs.k8s.io/kueue/apis/kueue/v1alpha2"
+	testingutil "sigs.k8s.io/kueue/pkg/util/testing"
+)
+
+func TestValidateCohort(t *testing.T) {
+	resourcesPath := field.NewPath("spec", "resources")
+	testCases := map[string]struct {
+		cohort  *Cohort
+		wantErr field.ErrorList
+	}{
+		"should accept a cohort with a parent and quotas": {
+			cohort: testingutil.MakeCohort("child").
+				Parent("parent").
+				Quota(corev1.ResourceCPU, "default", "10", "20").
+				Quota(corev1.ResourceCPU, "spot", "0", "").
+				Obj(),
+		},
+		"should reject an invalid parent": {
+			cohort: testingutil.MakeCohort("child").Parent("invalid_parent").Obj(),
+			wantErr: field.ErrorList{
+				field.Invalid(field.NewPath("spec", "parent"), "invalid_parent", ""),
+			},
+		},
+		"should reject the cohort as its own parent": {
+			cohort: testingutil.MakeCohort("child").Parent("child").Obj(),
+			wantErr: field.ErrorList{
+				field.Invalid(field.NewPath("spec", "parent"), "child", ""),
+			},
+		},
+		"should reject an invalid resource name": {
+			cohort: testingutil.MakeCohort("child").Quota("@cpu", "default", "1", "").Obj(),
+			wantErr: field.ErrorList{
+				field.Invalid(resourcesPath.Index(0).Child("name"), "@cpu", ""),
+			},
+		},
+		"should reject an invalid flavor name": {
+			cohort: testingutil.MakeCohort("child").Quota(corev1.ResourceCPU, "invalid_flavor", "1", "").Obj(),
+			wantErr: field.ErrorList{
+				field.Invalid(resourcesPath.Index(0).Child("flavors").Index(0).Child("name"), "invalid_flavor", ""),
+			},
+		},
+		"should reject a negative min": {
+			cohort: testingutil.MakeCohort("child").Quota(corev1.ResourceCPU, "default", "-1", "").Obj(),
+			wantErr: field.ErrorList{
+				field.Invalid(resourcesPath.Index(0).Child("flavors").Index(0).Child("quota", "min"), "-1", ""),
+			},
+		},
+		"should reject a min greater than max": {
+			cohort: testingutil.MakeCohort("child").Quota(corev1.ResourceCPU, "default", "2", "1").Obj(),
+			wantErr: field.ErrorList{
+				field.Invalid(resourcesPath.Index(0).Child("flavors").Index(0).Child("quota", "min"), "2", ""),
+			},
+		},
+	}
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			errList := ValidateCohort(tc.cohort)
+			if diff := cmp.Diff(tc.wantErr, errList, cmpopts.IgnoreFields(field.Error{}, "Detail", "BadValue")); diff != "" {
+				t.Errorf("ValidateCohort() mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestValidateCohortParentCycle(t *testing.T) {
+	existing := []*Cohort{
+		testingutil.MakeCohort("root").Obj(),
+		testingutil.MakeCohort("org").Parent("root").Obj(),
+		testingutil.MakeCohort("team").Parent("org").Obj(),
+		testingutil.MakeCohort("orphan").Parent("missing").Obj(),
+	}
+	testCases := map[string]struct {
+		cohort  *Cohort
+		wantErr bool
+	}{
+		"should accept a parent in another tree": {
+			cohort: testingutil.MakeCohort("other").Parent("team").Obj(),
+		},
+		"should accept a parent without a Cohort object": {
+			cohort: testingutil.MakeCohort("root").Parent("missing").Obj(),
+		},
+		"should accept a parent whose ancestors don't exist": {
+			cohort: testingutil.MakeCohort("root").Parent("orphan").Obj(),
+		},
+		"should reject a descendant as parent": {
+			cohort:  testingutil.MakeCohort("root").Parent("team").Obj(),
+			wantErr: true,
+		},
+		"should reject a child as parent": {
+			cohort:  testingutil.MakeCohort("org").Parent("team").Obj(),
+			wantErr: true,
+		},
+	}
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			scheme := runtime.NewScheme()
+			if err := AddToScheme(scheme); err != nil {
+				t.Fatalf("Failed adding kueue scheme: %v", err)
+			}
+			builder := fake.NewClientBuilder().WithScheme(scheme)
+			for _, c := range existing {
+				builder = builder.WithObjects(c.DeepCopy())
+			}
+			wh := &CohortWebhook{client: builder.Build()}
+			errList := wh.validateParentCycle(context.Background(), tc.cohort)
+			if gotErr := len(errList) > 0; gotErr != tc.wantErr {
+				t.Errorf("validateParentCycle() = %v, want error: %t", errList, tc.wantErr)
+			}
+		})
+	}
+}
@@ -19,6 +19,7 @@ package webhooks
 import (
 	"context"
 
+	corev1 "k8s.io/api/core/v1"
 	apivalidation "k8s.io/apimachinery/pkg/api/validation"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/util/validation/field"
@@ -68,9 +69,42 @@ func ValidateLocalQueue(q *kueue.LocalQueue) field.ErrorList {
 	var allErrs field.ErrorList
 	clusterQueuePath := field.NewPath("spec", "clusterQueue")
 	allErrs = append(allErrs, validateNameReference(string(q.Spec.ClusterQueue), clusterQueuePath)...)
+	allErrs = append(allErrs, validateLocalQueueLimits(q.Spec.Limits, field.NewPath("spec", "limits"))...)
+	allErrs = append(allErrs, validateLocalQueueLimitsPercent(q.Spec.LimitsPercent, field.NewPath("spec", "limitsPercent"))...)
+	routingPath := field.NewPath("spec", "priorityClassRouting")
+	for i, route := range q.Spec.PriorityClassRouting {
+		allErrs = append(allErrs, validateNameReference(route.PriorityClassName, routingPath.Index(i).Child("priorityClassName"))...)
+		allErrs = append(allErrs, validateNameReference(string(route.ClusterQueue), routingPath.Index(i).Child("clusterQueue"))...)
+	}
 	return allErrs
 }
 
 func ValidateLocalQueueUpdate(newObj, oldObj *kueue.LocalQueue) field.ErrorList {
-	return apivalidation.ValidateImmutableField(newObj.Spec.ClusterQueue, oldObj.Spec.ClusterQueue, field.NewPath("spec", "clusterQueue"))
+	allErrs := apivalidation.ValidateImmutableField(newObj.Spec.ClusterQueue, oldObj.Spec.ClusterQueue, field.NewPath("spec", "clusterQueue"))
+	allErrs = append(allErrs, apivalidation.ValidateImmutableField(newObj.Spec.PriorityClassRouting, oldObj.Spec.PriorityClassRouting, field.NewPath("spec", "priorityClassRouting"))...)
+	allErrs = append(allErrs, validateLocalQueueLimits(newObj.Spec.Limits, field.NewPath("spec", "limits"))...)
+	allErrs = append(allErrs, validateLocalQueueLimitsPercent(newObj.Spec.LimitsPercent, field.NewPath("spec", "limitsPercent"))...)
+	return allErrs
+}
+
+func validateLocalQueueLimits(limits corev1.ResourceList, path *field.Path) field.ErrorList {
+	var allErrs field.ErrorList
+	for name, q := range limits {
+		path := path.Key(string(name))
+		allErrs = append(allErrs, validateResourceName(name, path)...)
+		allErrs = append(allErrs, validateResourceQuantity(q, path)...)
+	}
+	return allErrs
+}
+
+func validateLocalQueueLimitsPercent(limits map[corev1.ResourceName]int32, path *field.Path) field.ErrorList {
+	var allErrs field.ErrorList
+	for name, pct := range limits {
+		path := path.Key(string(name))
+		allErrs = append(allErrs, validateResourceName(name, path)...)
+		if pct < 1 || pct > 100 {
+			allErrs = append(allErrs, field.Invalid(path, pct, "must be between 1 and 100"))
+		}
+	}
+	return allErrs
 }
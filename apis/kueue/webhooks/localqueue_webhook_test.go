@@ -21,6 +21,7 @@ import (
 
 	"github.com/google/go-cmp/cmp"
 	"github.com/google/go-cmp/cmp/cmpopts"
+	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/util/validation/field"
 
 	. "sigs.k8s.io/kueue/apis/kueue/v1alpha2"
@@ -43,6 +44,52 @@ func TestValidateLocalQueueCreate(t *testing.T) {
 				field.Invalid(field.NewPath("spec").Child("clusterQueue"), "invalid_name", ""),
 			},
 		},
+		"should accept queue creation with limits": {
+			queue: testingutil.MakeLocalQueue(testLocalQueueName, testLocalQueueNamespace).
+				ClusterQueue("cq").
+				Limit(corev1.ResourceCPU, "10").
+				Obj(),
+		},
+		"should reject queue creation with negative limits": {
+			queue: testingutil.MakeLocalQueue(testLocalQueueName, testLocalQueueNamespace).
+				ClusterQueue("cq").
+				Limit(corev1.ResourceCPU, "-1").
+				Obj(),
+			wantErr: field.ErrorList{
+				field.Invalid(field.NewPath("spec", "limits").Key("cpu"), "-1", ""),
+			},
+		},
+		"should accept queue creation with limits as a percentage": {
+			queue: testingutil.MakeLocalQueue(testLocalQueueName, testLocalQueueNamespace).
+				ClusterQueue("cq").
+				LimitPercent(corev1.ResourceCPU, 40).
+				LimitPercent(corev1.ResourceMemory, 100).
+				Obj(),
+		},
+		"should reject queue creation with limits as a percentage out of range": {
+			queue: testingutil.MakeLocalQueue(testLocalQueueName, testLocalQueueNamespace).
+				ClusterQueue("cq").
+				LimitPercent(corev1.ResourceCPU, 0).
+				Obj(),
+			wantErr: field.ErrorList{
+				field.Invalid(field.NewPath("spec", "limitsPercent").Key("cpu"), int32(0), ""),
+			},
+		},
+		"should accept queue creation with priority class routing": {
+			queue: testingutil.MakeLocalQueue(testLocalQueueName, testLocalQueueNamespace).
+				ClusterQueue("cq").
+				RoutePriorityClass("high", "on-demand").
+				Obj(),
+		},
+		"should reject queue creation with an invalid routed clusterQueue": {
+			queue: testingutil.MakeLocalQueue(testLocalQueueName, testLocalQueueNamespace).
+				ClusterQueue("cq").
+				RoutePriorityClass("high", "invalid_cluster_queue").
+				Obj(),
+			wantErr: field.ErrorList{
+				field.Invalid(field.NewPath("spec", "priorityClassRouting").Index(0).Child("clusterQueue"), "invalid_cluster_queue", ""),
+			},
+		},
 	}
 	for name, tc := range testCases {
 		t.Run(name, func(t *testing.T) {
@@ -66,15 +113,37 @@ func TestValidateLocalQueueUpdate(t *testing.T) {
 				field.Invalid(field.NewPath("spec").Child("clusterQueue"), nil, ""),
 			},
 		},
+		"priorityClassRouting cannot be updated": {
+			before: testingutil.MakeLocalQueue(testLocalQueueName, testLocalQueueNamespace).ClusterQueue("foo").Obj(),
+			after: testingutil.MakeLocalQueue(testLocalQueueName, testLocalQueueNamespace).
+				ClusterQueue("foo").
+				RoutePriorityClass("high", "bar").
+				Obj(),
+			wantErr: field.ErrorList{
+				field.Invalid(field.NewPath("spec", "priorityClassRouting"), nil, ""),
+			},
+		},
 		"status could be updated": {
 			before:  testingutil.MakeLocalQueue(testLocalQueueName, testLocalQueueNamespace).Obj(),
 			after:   testingutil.MakeLocalQueue(testLocalQueueName, testLocalQueueNamespace).PendingWorkloads(10).Obj(),
 			wantErr: field.ErrorList{},
 		},
+		"limits could be updated": {
+			before:  testingutil.MakeLocalQueue(testLocalQueueName, testLocalQueueNamespace).Obj(),
+			after:   testingutil.MakeLocalQueue(testLocalQueueName, testLocalQueueNamespace).Limit(corev1.ResourceCPU, "10").Obj(),
+			wantErr: field.ErrorList{},
+		},
+		"limits can't be negative": {
+			before: testingutil.MakeLocalQueue(testLocalQueueName, testLocalQueueNamespace).Obj(),
+			after:  testingutil.MakeLocalQueue(testLocalQueueName, testLocalQueueNamespace).Limit(corev1.ResourceCPU, "-1").Obj(),
+			wantErr: field.ErrorList{
+				field.Invalid(field.NewPath("spec", "limits").Key("cpu"), "-1", ""),
+			},
+		},
 	}
 	for name, tc := range testCases {
 		t.Run(name, func(t *testing.T) {
-			errList := ValidateLocalQueueUpdate(tc.before, tc.after)
+			errList := ValidateLocalQueueUpdate(tc.after, tc.before)
 			if diff := cmp.Diff(tc.wantErr, errList, cmpopts.IgnoreFields(field.Error{}, "Detail", "BadValue")); diff != "" {
 				t.Errorf("ValidateLocalQueueUpdate() mismatch (-want +got):\n%s", diff)
 			}
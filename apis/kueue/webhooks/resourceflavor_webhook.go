@@ -21,6 +21,7 @@ import (
 	"strings"
 
 	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
 	metavalidation "k8s.io/apimachinery/pkg/apis/meta/v1/validation"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/util/sets"
@@ -32,6 +33,7 @@ import (
 	"sigs.k8s.io/controller-runtime/pkg/webhook"
 
 	kueue "sigs.k8s.io/kueue/apis/kueue/v1alpha2"
+	"sigs.k8s.io/kueue/pkg/constants"
 )
 
 type ResourceFlavorWebhook struct{}
@@ -93,6 +95,24 @@ func ValidateResourceFlavor(rf *kueue.ResourceFlavor) field.ErrorList {
 
 	taintsPath := field.NewPath("taints")
 	allErrs = append(allErrs, validateNodeTaints(rf.Taints, taintsPath)...)
+
+	sharedResourcesPath := field.NewPath("sharedResources")
+	for i, r := range rf.SharedResources {
+		path := sharedResourcesPath.Index(i)
+		allErrs = append(allErrs, validateResourceName(r.Name, path.Child("name"))...)
+		if r.Replicas < 1 {
+			allErrs = append(allErrs, field.Invalid(path.Child("replicas"), r.Replicas, "must be greater than or equal to 1"))
+		}
+	}
+
+	if cost, ok := rf.Annotations[constants.FlavorCostAnnotation]; ok {
+		costPath := field.NewPath("metadata", "annotations").Key(constants.FlavorCostAnnotation)
+		if q, err := resource.ParseQuantity(cost); err != nil {
+			allErrs = append(allErrs, field.Invalid(costPath, cost, err.Error()))
+		} else if q.Sign() < 0 {
+			allErrs = append(allErrs, field.Invalid(costPath, cost, "must be greater than or equal to 0"))
+		}
+	}
 	return allErrs
 }
 
@@ -25,6 +25,7 @@ import (
 	"k8s.io/apimachinery/pkg/util/validation/field"
 
 	kueue "sigs.k8s.io/kueue/apis/kueue/v1alpha2"
+	"sigs.k8s.io/kueue/pkg/constants"
 	utiltesting "sigs.k8s.io/kueue/pkg/util/testing"
 )
 
@@ -73,6 +74,36 @@ func TestValidateResourceFlavor(t *testing.T) {
 				field.Invalid(field.NewPath("nodeSelector"), "@abc", ""),
 			},
 		},
+		{
+			name: "valid cost",
+			rf:   utiltesting.MakeResourceFlavor("resource-flavor").Cost("0.35").Obj(),
+		},
+		{
+			name: "invalid cost",
+			rf:   utiltesting.MakeResourceFlavor("resource-flavor").Cost("cheap").Obj(),
+			wantErr: field.ErrorList{
+				field.Invalid(field.NewPath("metadata", "annotations").Key(constants.FlavorCostAnnotation), "cheap", ""),
+			},
+		},
+		{
+			name: "negative cost",
+			rf:   utiltesting.MakeResourceFlavor("resource-flavor").Cost("-1").Obj(),
+			wantErr: field.ErrorList{
+				field.Invalid(field.NewPath("metadata", "annotations").Key(constants.FlavorCostAnnotation), "-1", ""),
+			},
+		},
+		{
+			name: "valid shared resource",
+			rf:   utiltesting.MakeResourceFlavor("resource-flavor").SharedResource("nvidia.com/gpu", 4).Obj(),
+		},
+		{
+			name: "invalid shared resource",
+			rf:   utiltesting.MakeResourceFlavor("resource-flavor").SharedResource("@gpu", 0).Obj(),
+			wantErr: field.ErrorList{
+				field.Invalid(field.NewPath("sharedResources").Index(0).Child("name"), "@gpu", ""),
+				field.Invalid(field.NewPath("sharedResources").Index(0).Child("replicas"), int32(0), ""),
+			},
+		},
 	}
 
 	for _, tc := range testcases {
@@ -36,5 +36,13 @@ func Setup(mgr ctrl.Manager) (string, error) {
 	if err := setupWebhookForLocalQueue(mgr); err != nil {
 		return "Queue", err
 	}
+
+	if err := setupWebhookForCohort(mgr); err != nil {
+		return "Cohort", err
+	}
+
+	if err := setupWebhookForClusterQueueClass(mgr); err != nil {
+		return "ClusterQueueClass", err
+	}
 	return "", nil
 }
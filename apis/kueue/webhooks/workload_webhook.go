@@ -18,28 +18,38 @@ package webhooks
 
 import (
 	"context"
+	"fmt"
 
+	admissionv1 "k8s.io/api/admission/v1"
 	corev1 "k8s.io/api/core/v1"
 	apivalidation "k8s.io/apimachinery/pkg/api/validation"
 	metav1validation "k8s.io/apimachinery/pkg/apis/meta/v1/validation"
 	"k8s.io/apimachinery/pkg/runtime"
-	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/apimachinery/pkg/util/validation"
 	"k8s.io/apimachinery/pkg/util/validation/field"
 	"k8s.io/klog/v2"
+	"k8s.io/utils/pointer"
 	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/webhook"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
 
 	kueue "sigs.k8s.io/kueue/apis/kueue/v1alpha2"
+	"sigs.k8s.io/kueue/pkg/constants"
+	utiltolerations "sigs.k8s.io/kueue/pkg/util/tolerations"
 )
 
-type WorkloadWebhook struct{}
+type WorkloadWebhook struct {
+	client client.Client
+}
 
 func setupWebhookForWorkload(mgr ctrl.Manager) error {
+	wh := &WorkloadWebhook{client: mgr.GetClient()}
 	return ctrl.NewWebhookManagedBy(mgr).
 		For(&kueue.Workload{}).
-		WithDefaulter(&WorkloadWebhook{}).
-		WithValidator(&WorkloadWebhook{}).
+		WithDefaulter(wh).
+		WithValidator(wh).
 		Complete()
 }
 
@@ -66,6 +76,51 @@ func (w *WorkloadWebhook) Default(ctx context.Context, obj runtime.Object) error
 		setContainersDefaults(podSet.Spec.InitContainers)
 		setContainersDefaults(podSet.Spec.Containers)
 	}
+
+	// The podSets are immutable, so the tolerations of the localQueue can only
+	// be added when the workload is created.
+	if req, err := admission.RequestFromContext(ctx); err == nil && req.Operation == admissionv1.Create {
+		if err := w.addLocalQueueTolerations(ctx, wl); err != nil {
+			return err
+		}
+		if err := w.setWorkloadPriorityClassValue(ctx, wl); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// setWorkloadPriorityClassValue sets the priority of a workload created with
+// a WorkloadPriorityClass and no priority to the value of the class.
+func (w *WorkloadWebhook) setWorkloadPriorityClassValue(ctx context.Context, wl *kueue.Workload) error {
+	if w.client == nil || wl.Spec.PriorityClassSource != kueue.WorkloadPriorityClassSource ||
+		len(wl.Spec.PriorityClassName) == 0 || wl.Spec.Priority != nil {
+		return nil
+	}
+	var wpc kueue.WorkloadPriorityClass
+	if err := w.client.Get(ctx, types.NamespacedName{Name: wl.Spec.PriorityClassName}, &wpc); err != nil {
+		// The workload is rejected by the validation, as it has no priority.
+		return client.IgnoreNotFound(err)
+	}
+	wl.Spec.Priority = pointer.Int32(wpc.Value)
+	return nil
+}
+
+// addLocalQueueTolerations adds the tolerations of the localQueue of the
+// workload to all its podSets, skipping the ones that the podSets already have.
+func (w *WorkloadWebhook) addLocalQueueTolerations(ctx context.Context, wl *kueue.Workload) error {
+	if w.client == nil || len(wl.Spec.QueueName) == 0 {
+		return nil
+	}
+	var lq kueue.LocalQueue
+	if err := w.client.Get(ctx, types.NamespacedName{Name: wl.Spec.QueueName, Namespace: wl.Namespace}, &lq); err != nil {
+		// The workload is reported as inadmissible if the localQueue doesn't exist.
+		return client.IgnoreNotFound(err)
+	}
+	for i := range wl.Spec.PodSets {
+		podSpec := &wl.Spec.PodSets[i].Spec
+		podSpec.Tolerations = utiltolerations.AppendMissing(podSpec.Tolerations, lq.Spec.Tolerations)
+	}
 	return nil
 }
 
@@ -85,7 +140,7 @@ func setContainersDefaults(containers []corev1.Container) {
 	}
 }
 
-// +kubebuilder:webhook:path=/validate-kueue-x-k8s-io-v1alpha2-workload,mutating=false,failurePolicy=fail,sideEffects=None,groups=kueue.x-k8s.io,resources=workloads,verbs=create;update,versions=v1alpha2,name=vworkload.kb.io,admissionReviewVersions=v1
+// +kubebuilder:webhook:path=/validate-kueue-x-k8s-io-v1alpha2-workload,mutating=false,failurePolicy=fail,sideEffects=None,groups=kueue.x-k8s.io,resources=workloads;workloads/status,verbs=create;update,versions=v1alpha2,name=vworkload.kb.io,admissionReviewVersions=v1
 
 var _ webhook.CustomValidator = &WorkloadWebhook{}
 
@@ -94,7 +149,7 @@ func (w *WorkloadWebhook) ValidateCreate(ctx context.Context, obj runtime.Object
 	wl := obj.(*kueue.Workload)
 	log := ctrl.LoggerFrom(ctx).WithName("workload-webhook")
 	log.V(5).Info("Validating create", "workload", klog.KObj(wl))
-	return ValidateWorkload(wl).ToAggregate()
+	return ValidateWorkloadCreate(wl).ToAggregate()
 }
 
 // ValidateUpdate implements webhook.CustomValidator so a webhook will be registered for the type
@@ -111,14 +166,34 @@ func (w *WorkloadWebhook) ValidateDelete(ctx context.Context, obj runtime.Object
 	return nil
 }
 
+// ValidateWorkloadCreate validates a new workload, which can't use the
+// deprecated spec.admission field.
+func ValidateWorkloadCreate(obj *kueue.Workload) field.ErrorList {
+	allErrs := ValidateWorkload(obj)
+	if obj.Spec.Admission != nil {
+		allErrs = append(allErrs, field.Forbidden(field.NewPath("spec", "admission"), "deprecated, the admission is set in status.admission"))
+	}
+	return allErrs
+}
+
 func ValidateWorkload(obj *kueue.Workload) field.ErrorList {
 	var allErrs field.ErrorList
 	specPath := field.NewPath("spec")
 	podSetsPath := specPath.Child("podSets")
 
+	minCountPodSets := 0
 	for i, podSet := range obj.Spec.PodSets {
 		path := podSetsPath.Index(i)
 		allErrs = append(allErrs, validatePodSetName(podSet.Name, path.Child("name"))...)
+		if podSet.MinCount != nil {
+			minCountPodSets++
+			if *podSet.MinCount > podSet.Count {
+				allErrs = append(allErrs, field.Invalid(path.Child("minCount"), *podSet.MinCount, "must be less than or equal to count"))
+			}
+			if minCountPodSets > 1 {
+				allErrs = append(allErrs, field.Forbidden(path.Child("minCount"), "at most one podSet can set minCount"))
+			}
+		}
 	}
 
 	if len(obj.Spec.PriorityClassName) > 0 {
@@ -133,19 +208,47 @@ func ValidateWorkload(obj *kueue.Workload) field.ErrorList {
 		}
 	}
 
+	if len(obj.Spec.PriorityClassSource) > 0 && len(obj.Spec.PriorityClassName) == 0 {
+		allErrs = append(allErrs, field.Required(specPath.Child("priorityClassName"), "priorityClassName is required when priorityClassSource is set"))
+	}
+
 	if len(obj.Spec.QueueName) > 0 {
 		allErrs = append(allErrs, validateNameReference(string(obj.Spec.QueueName), specPath.Child("queueName"))...)
 	}
 
-	if obj.Spec.Admission != nil {
-		allErrs = append(allErrs, validateAdmission(obj, specPath.Child("admission"))...)
+	if obj.Spec.ExpectedDuration != nil && obj.Spec.ExpectedDuration.Duration < 0 {
+		allErrs = append(allErrs, field.Invalid(specPath.Child("expectedDuration"), obj.Spec.ExpectedDuration.Duration.String(), "must be greater than or equal to 0"))
+	}
+
+	if obj.Status.Admission != nil {
+		allErrs = append(allErrs, validateAdmission(obj, obj.Status.Admission, field.NewPath("status", "admission"))...)
 	}
 
 	allErrs = append(allErrs, metav1validation.ValidateConditions(obj.Status.Conditions, field.NewPath("status", "conditions"))...)
+	allErrs = append(allErrs, validateReclaimablePods(obj, field.NewPath("status", "reclaimablePods"))...)
 
 	return allErrs
 }
 
+func validateReclaimablePods(obj *kueue.Workload, path *field.Path) field.ErrorList {
+	var allErrs field.ErrorList
+	counts := make(map[string]int32, len(obj.Spec.PodSets))
+	for _, ps := range obj.Spec.PodSets {
+		counts[ps.Name] = ps.Count
+	}
+	for i, rp := range obj.Status.ReclaimablePods {
+		count, found := counts[rp.Name]
+		if !found {
+			allErrs = append(allErrs, field.NotFound(path.Index(i).Child("name"), rp.Name))
+			continue
+		}
+		if rp.Count < 0 || rp.Count > count {
+			allErrs = append(allErrs, field.Invalid(path.Index(i).Child("count"), rp.Count, "must be between 0 and the count of the podSet"))
+		}
+	}
+	return allErrs
+}
+
 func validatePodSetName(name string, fldPath *field.Path) field.ErrorList {
 	var allErrs field.ErrorList
 	// Apply the same validation as container names.
@@ -155,43 +258,109 @@ func validatePodSetName(name string, fldPath *field.Path) field.ErrorList {
 	return allErrs
 }
 
-func validateAdmission(obj *kueue.Workload, path *field.Path) field.ErrorList {
-	admission := obj.Spec.Admission
+func validateAdmission(obj *kueue.Workload, admission *kueue.Admission, path *field.Path) field.ErrorList {
 	var allErrs field.ErrorList
 	allErrs = append(allErrs, validateNameReference(string(admission.ClusterQueue), path.Child("clusterQueue"))...)
 
-	names := sets.NewString()
-	for _, ps := range obj.Spec.PodSets {
-		names.Insert(ps.Name)
+	podSets := make(map[string]*kueue.PodSet, len(obj.Spec.PodSets))
+	for i := range obj.Spec.PodSets {
+		podSets[obj.Spec.PodSets[i].Name] = &obj.Spec.PodSets[i]
 	}
 
-	for i, ps := range obj.Spec.Admission.PodSetFlavors {
-		if !names.Has(ps.Name) {
+	for i, ps := range admission.PodSetFlavors {
+		podSet, found := podSets[ps.Name]
+		if !found {
 			allErrs = append(allErrs, field.NotFound(path.Child("podSetFlavors").Index(i).Child("name"), ps.Name))
+			continue
+		}
+		if len(ps.Splits) > 0 {
+			allErrs = append(allErrs, validateSplits(&ps, podSet, path.Child("podSetFlavors").Index(i))...)
+		} else if ps.Count != nil {
+			if podSet.MinCount == nil {
+				allErrs = append(allErrs, field.Forbidden(path.Child("podSetFlavors").Index(i).Child("count"), "podSet doesn't set minCount"))
+			} else if *ps.Count < *podSet.MinCount || *ps.Count > podSet.Count {
+				allErrs = append(allErrs, field.Invalid(path.Child("podSetFlavors").Index(i).Child("count"), *ps.Count, "must be between the minCount and the count of the podSet"))
+			}
 		}
 	}
 
 	return allErrs
 }
 
+// validateSplits checks that the parts of a split podSet, the first one given
+// by the count and each of the splits, add up to all the pods of the podSet.
+func validateSplits(ps *kueue.PodSetFlavors, podSet *kueue.PodSet, path *field.Path) field.ErrorList {
+	var allErrs field.ErrorList
+	if ps.Count == nil {
+		return append(allErrs, field.Required(path.Child("count"), "must be set when the podSet is split"))
+	}
+	total := *ps.Count
+	if *ps.Count < 1 {
+		allErrs = append(allErrs, field.Invalid(path.Child("count"), *ps.Count, "must be positive"))
+	}
+	for i, split := range ps.Splits {
+		if split.Count < 1 {
+			allErrs = append(allErrs, field.Invalid(path.Child("splits").Index(i).Child("count"), split.Count, "must be positive"))
+		}
+		total += split.Count
+	}
+	if total != podSet.Count {
+		allErrs = append(allErrs, field.Invalid(path.Child("splits"), total, "the counts of the parts must add up to the count of the podSet"))
+	}
+	return allErrs
+}
+
 func ValidateWorkloadUpdate(newObj, oldObj *kueue.Workload) field.ErrorList {
 	var allErrs field.ErrorList
 	specPath := field.NewPath("spec")
 	allErrs = append(allErrs, ValidateWorkload(newObj)...)
 	allErrs = append(allErrs, apivalidation.ValidateImmutableField(newObj.Spec.PodSets, oldObj.Spec.PodSets, specPath.Child("podSets"))...)
-	if newObj.Spec.Admission != nil && oldObj.Spec.Admission != nil {
+	allErrs = append(allErrs, apivalidation.ValidateImmutableField(newObj.Spec.ManagedBy, oldObj.Spec.ManagedBy, specPath.Child("managedBy"))...)
+	// An admitted workload can only move to another queue, and ClusterQueue,
+	// through the move annotation.
+	_, moving := oldObj.Annotations[constants.MoveToQueueAnnotation]
+	if newObj.Status.Admission != nil && oldObj.Status.Admission != nil && !moving {
 		allErrs = append(allErrs, apivalidation.ValidateImmutableField(newObj.Spec.QueueName, oldObj.Spec.QueueName, specPath.Child("queueName"))...)
 	}
-	allErrs = append(allErrs, validateAdmissionUpdate(newObj.Spec.Admission, oldObj.Spec.Admission, specPath.Child("admission"))...)
+	// The deprecated admission can only be removed, when it's moved to the
+	// status.
+	if newObj.Spec.Admission != nil {
+		allErrs = append(allErrs, apivalidation.ValidateImmutableField(newObj.Spec.Admission, oldObj.Spec.Admission, specPath.Child("admission"))...)
+	}
+	allErrs = append(allErrs, validateAdmissionUpdate(newObj.Status.Admission, oldObj.Status.Admission, moving, field.NewPath("status", "admission"))...)
+	if newObj.Status.Admission != nil && oldObj.Status.Admission != nil {
+		allErrs = append(allErrs, validateReclaimablePodsUpdate(newObj.Status.ReclaimablePods, oldObj.Status.ReclaimablePods, field.NewPath("status", "reclaimablePods"))...)
+	}
 
 	return allErrs
 }
 
+// validateReclaimablePodsUpdate validates that the reclaimable pods of an
+// admitted workload don't decrease, as their quota might be in use by other
+// workloads already.
+func validateReclaimablePodsUpdate(new, old []kueue.ReclaimablePod, path *field.Path) field.ErrorList {
+	var allErrs field.ErrorList
+	newCounts := make(map[string]int32, len(new))
+	for _, rp := range new {
+		newCounts[rp.Name] = rp.Count
+	}
+	for _, rp := range old {
+		if newCounts[rp.Name] < rp.Count {
+			allErrs = append(allErrs, field.Forbidden(path, fmt.Sprintf("the reclaimable pods of podSet %s can't decrease while the workload is admitted", rp.Name)))
+		}
+	}
+	return allErrs
+}
+
 // validateAdmissionUpdate validates that admission can be set or unset, but the
-// fields within can't change.
-func validateAdmissionUpdate(new, old *kueue.Admission, path *field.Path) field.ErrorList {
+// fields within can't change, except for the clusterQueue when moving.
+func validateAdmissionUpdate(new, old *kueue.Admission, moving bool, path *field.Path) field.ErrorList {
 	if old == nil || new == nil {
 		return nil
 	}
+	if moving {
+		old = old.DeepCopy()
+		old.ClusterQueue = new.ClusterQueue
+	}
 	return apivalidation.ValidateImmutableField(new, old, path)
 }
@@ -19,12 +19,17 @@ package webhooks
 import (
 	"context"
 	"testing"
+	"time"
 
 	"github.com/google/go-cmp/cmp"
 	"github.com/google/go-cmp/cmp/cmpopts"
+	admissionv1 "k8s.io/api/admission/v1"
 	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/resource"
+	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/util/validation/field"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
 
 	kueue "sigs.k8s.io/kueue/apis/kueue/v1alpha2"
 	"sigs.k8s.io/kueue/pkg/util/pointer"
@@ -189,6 +194,157 @@ func TestWorkloadWebhookDefault(t *testing.T) {
 	}
 }
 
+func TestWorkloadWebhookDefaultLocalQueueTolerations(t *testing.T) {
+	teamToleration := corev1.Toleration{
+		Key:      "team",
+		Operator: corev1.TolerationOpEqual,
+		Value:    "a",
+		Effect:   corev1.TaintEffectNoSchedule,
+	}
+	gpuToleration := corev1.Toleration{
+		Key:      "gpu",
+		Operator: corev1.TolerationOpExists,
+		Effect:   corev1.TaintEffectNoSchedule,
+	}
+	queue := testingutil.MakeLocalQueue("team-a", testWorkloadNamespace).
+		Toleration(teamToleration).
+		Toleration(gpuToleration).
+		Obj()
+	cases := map[string]struct {
+		wl        *kueue.Workload
+		operation admissionv1.Operation
+		wantWl    *kueue.Workload
+	}{
+		"tolerations added on create": {
+			wl:        testingutil.MakeWorkload(testWorkloadName, testWorkloadNamespace).Queue("team-a").Obj(),
+			operation: admissionv1.Create,
+			wantWl: testingutil.MakeWorkload(testWorkloadName, testWorkloadNamespace).
+				Queue("team-a").
+				Toleration(teamToleration).
+				Toleration(gpuToleration).
+				Obj(),
+		},
+		"existing tolerations are not duplicated": {
+			wl: testingutil.MakeWorkload(testWorkloadName, testWorkloadNamespace).
+				Queue("team-a").
+				Toleration(gpuToleration).
+				Obj(),
+			operation: admissionv1.Create,
+			wantWl: testingutil.MakeWorkload(testWorkloadName, testWorkloadNamespace).
+				Queue("team-a").
+				Toleration(gpuToleration).
+				Toleration(teamToleration).
+				Obj(),
+		},
+		"tolerations not added on update": {
+			wl:        testingutil.MakeWorkload(testWorkloadName, testWorkloadNamespace).Queue("team-a").Obj(),
+			operation: admissionv1.Update,
+			wantWl:    testingutil.MakeWorkload(testWorkloadName, testWorkloadNamespace).Queue("team-a").Obj(),
+		},
+		"localQueue not found": {
+			wl:        testingutil.MakeWorkload(testWorkloadName, testWorkloadNamespace).Queue("team-b").Obj(),
+			operation: admissionv1.Create,
+			wantWl:    testingutil.MakeWorkload(testWorkloadName, testWorkloadNamespace).Queue("team-b").Obj(),
+		},
+	}
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			scheme := runtime.NewScheme()
+			if err := kueue.AddToScheme(scheme); err != nil {
+				t.Fatalf("Failed adding kueue scheme: %v", err)
+			}
+			cl := fake.NewClientBuilder().WithScheme(scheme).WithObjects(queue).Build()
+			wh := &WorkloadWebhook{client: cl}
+			ctx := admission.NewContextWithRequest(context.Background(), admission.Request{
+				AdmissionRequest: admissionv1.AdmissionRequest{Operation: tc.operation},
+			})
+			if err := wh.Default(ctx, tc.wl); err != nil {
+				t.Fatalf("Could not apply defaults: %v", err)
+			}
+			if diff := cmp.Diff(tc.wantWl, tc.wl); diff != "" {
+				t.Errorf("Obtained wrong defaults (-want,+got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestWorkloadWebhookDefaultWorkloadPriorityClass(t *testing.T) {
+	cases := map[string]struct {
+		wl        *kueue.Workload
+		operation admissionv1.Operation
+		wantWl    *kueue.Workload
+	}{
+		"priority set on create": {
+			wl: testingutil.MakeWorkload(testWorkloadName, testWorkloadNamespace).
+				PriorityClass("high").
+				PriorityClassSource(kueue.WorkloadPriorityClassSource).
+				Obj(),
+			operation: admissionv1.Create,
+			wantWl: testingutil.MakeWorkload(testWorkloadName, testWorkloadNamespace).
+				PriorityClass("high").
+				PriorityClassSource(kueue.WorkloadPriorityClassSource).
+				Priority(pointer.Int32(1000)).
+				Obj(),
+		},
+		"priority kept": {
+			wl: testingutil.MakeWorkload(testWorkloadName, testWorkloadNamespace).
+				PriorityClass("high").
+				PriorityClassSource(kueue.WorkloadPriorityClassSource).
+				Priority(pointer.Int32(10)).
+				Obj(),
+			operation: admissionv1.Create,
+			wantWl: testingutil.MakeWorkload(testWorkloadName, testWorkloadNamespace).
+				PriorityClass("high").
+				PriorityClassSource(kueue.WorkloadPriorityClassSource).
+				Priority(pointer.Int32(10)).
+				Obj(),
+		},
+		"pod priority class ignored": {
+			wl: testingutil.MakeWorkload(testWorkloadName, testWorkloadNamespace).
+				PriorityClass("high").
+				PriorityClassSource(kueue.PodPriorityClassSource).
+				Obj(),
+			operation: admissionv1.Create,
+			wantWl: testingutil.MakeWorkload(testWorkloadName, testWorkloadNamespace).
+				PriorityClass("high").
+				PriorityClassSource(kueue.PodPriorityClassSource).
+				Obj(),
+		},
+		"workload priority class not found": {
+			wl: testingutil.MakeWorkload(testWorkloadName, testWorkloadNamespace).
+				PriorityClass("low").
+				PriorityClassSource(kueue.WorkloadPriorityClassSource).
+				Obj(),
+			operation: admissionv1.Create,
+			wantWl: testingutil.MakeWorkload(testWorkloadName, testWorkloadNamespace).
+				PriorityClass("low").
+				PriorityClassSource(kueue.WorkloadPriorityClassSource).
+				Obj(),
+		},
+	}
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			scheme := runtime.NewScheme()
+			if err := kueue.AddToScheme(scheme); err != nil {
+				t.Fatalf("Failed adding kueue scheme: %v", err)
+			}
+			cl := fake.NewClientBuilder().WithScheme(scheme).
+				WithObjects(testingutil.MakeWorkloadPriorityClass("high").PriorityValue(1000).Obj()).
+				Build()
+			wh := &WorkloadWebhook{client: cl}
+			ctx := admission.NewContextWithRequest(context.Background(), admission.Request{
+				AdmissionRequest: admissionv1.AdmissionRequest{Operation: tc.operation},
+			})
+			if err := wh.Default(ctx, tc.wl); err != nil {
+				t.Fatalf("Could not apply defaults: %v", err)
+			}
+			if diff := cmp.Diff(tc.wantWl, tc.wl); diff != "" {
+				t.Errorf("Obtained wrong defaults (-want,+got):\n%s", diff)
+			}
+		})
+	}
+}
+
 func TestValidateWorkload(t *testing.T) {
 	specField := field.NewPath("spec")
 	podSetsField := specField.Child("podSets")
@@ -238,6 +394,14 @@ func TestValidateWorkload(t *testing.T) {
 				field.Invalid(specField.Child("priority"), nil, ""),
 			},
 		},
+		"should have priorityClassName once priorityClassSource is set": {
+			workload: testingutil.MakeWorkload(testWorkloadName, testWorkloadNamespace).
+				PriorityClassSource(kueue.WorkloadPriorityClassSource).
+				Obj(),
+			wantErr: field.ErrorList{
+				field.Required(specField.Child("priorityClassName"), ""),
+			},
+		},
 		"should have a valid queueName": {
 			workload: testingutil.MakeWorkload(testWorkloadName, testWorkloadNamespace).
 				Queue("@invalid").
@@ -246,12 +410,21 @@ func TestValidateWorkload(t *testing.T) {
 				field.Invalid(specField.Child("queueName"), nil, ""),
 			},
 		},
+		"should have a non-negative expectedDuration": {
+			workload: testingutil.MakeWorkload(testWorkloadName, testWorkloadNamespace).
+				Deadline(time.Now()).
+				ExpectedDuration(-time.Hour).
+				Obj(),
+			wantErr: field.ErrorList{
+				field.Invalid(specField.Child("expectedDuration"), nil, ""),
+			},
+		},
 		"should have a valid clusterQueue name": {
 			workload: testingutil.MakeWorkload(testWorkloadName, testWorkloadNamespace).
 				Admit(testingutil.MakeAdmission("@invalid").Obj()).
 				Obj(),
 			wantErr: field.ErrorList{
-				field.Invalid(specField.Child("admission", "clusterQueue"), nil, ""),
+				field.Invalid(field.NewPath("status", "admission").Child("clusterQueue"), nil, ""),
 			},
 		},
 		"should have a valid podSet name": {
@@ -259,7 +432,7 @@ func TestValidateWorkload(t *testing.T) {
 				Admit(testingutil.MakeAdmission("cluster-queue", "@invalid").Obj()).
 				Obj(),
 			wantErr: field.ErrorList{
-				field.NotFound(specField.Child("admission", "podSetFlavors").Index(0).Child("name"), nil),
+				field.NotFound(field.NewPath("status", "admission").Child("podSetFlavors").Index(0).Child("name"), nil),
 			},
 		},
 		"should have same podSets in admission": {
@@ -277,7 +450,118 @@ func TestValidateWorkload(t *testing.T) {
 				Admit(testingutil.MakeAdmission("cluster-queue", "main1", "main3").Obj()).
 				Obj(),
 			wantErr: field.ErrorList{
-				field.NotFound(specField.Child("admission", "podSetFlavors").Index(1).Child("name"), nil),
+				field.NotFound(field.NewPath("status", "admission").Child("podSetFlavors").Index(1).Child("name"), nil),
+			},
+		},
+		"should have a minCount not greater than count": {
+			workload: testingutil.MakeWorkload(testWorkloadName, testWorkloadNamespace).
+				Count(5).
+				MinCount(6).
+				Obj(),
+			wantErr: field.ErrorList{
+				field.Invalid(podSetsField.Index(0).Child("minCount"), nil, ""),
+			},
+		},
+		"should have minCount in at most one podSet": {
+			workload: testingutil.MakeWorkload(testWorkloadName, testWorkloadNamespace).PodSets([]kueue.PodSet{
+				{
+					Name:     "driver",
+					Count:    2,
+					MinCount: pointer.Int32(1),
+				},
+				{
+					Name:     "workers",
+					Count:    100,
+					MinCount: pointer.Int32(10),
+				},
+			}).Obj(),
+			wantErr: field.ErrorList{
+				field.Forbidden(podSetsField.Index(1).Child("minCount"), ""),
+			},
+		},
+		"should admit a count between minCount and count": {
+			workload: testingutil.MakeWorkload(testWorkloadName, testWorkloadNamespace).
+				Count(10).
+				MinCount(4).
+				Admit(testingutil.MakeAdmission("cluster-queue").Count(6).Obj()).
+				Obj(),
+		},
+		"should not admit a count lower than minCount": {
+			workload: testingutil.MakeWorkload(testWorkloadName, testWorkloadNamespace).
+				Count(10).
+				MinCount(4).
+				Admit(testingutil.MakeAdmission("cluster-queue").Count(3).Obj()).
+				Obj(),
+			wantErr: field.ErrorList{
+				field.Invalid(field.NewPath("status", "admission").Child("podSetFlavors").Index(0).Child("count"), nil, ""),
+			},
+		},
+		"should not admit a reduced count without minCount": {
+			workload: testingutil.MakeWorkload(testWorkloadName, testWorkloadNamespace).
+				Count(10).
+				Admit(testingutil.MakeAdmission("cluster-queue").Count(6).Obj()).
+				Obj(),
+			wantErr: field.ErrorList{
+				field.Forbidden(field.NewPath("status", "admission").Child("podSetFlavors").Index(0).Child("count"), ""),
+			},
+		},
+		"should admit a podSet split between flavors": {
+			workload: testingutil.MakeWorkload(testWorkloadName, testWorkloadNamespace).
+				Count(10).
+				Admit(testingutil.MakeAdmission("cluster-queue").
+					Flavor(corev1.ResourceCPU, "spot").
+					Count(6).
+					Split(corev1.ResourceCPU, "on-demand", 4).
+					Obj()).
+				Obj(),
+		},
+		"should require the count of a split podSet": {
+			workload: testingutil.MakeWorkload(testWorkloadName, testWorkloadNamespace).
+				Count(10).
+				Admit(testingutil.MakeAdmission("cluster-queue").
+					Flavor(corev1.ResourceCPU, "spot").
+					Split(corev1.ResourceCPU, "on-demand", 4).
+					Obj()).
+				Obj(),
+			wantErr: field.ErrorList{
+				field.Required(field.NewPath("status", "admission").Child("podSetFlavors").Index(0).Child("count"), ""),
+			},
+		},
+		"should not admit splits that don't add up to the podSet count": {
+			workload: testingutil.MakeWorkload(testWorkloadName, testWorkloadNamespace).
+				Count(10).
+				Admit(testingutil.MakeAdmission("cluster-queue").
+					Flavor(corev1.ResourceCPU, "spot").
+					Count(6).
+					Split(corev1.ResourceCPU, "on-demand", 3).
+					Obj()).
+				Obj(),
+			wantErr: field.ErrorList{
+				field.Invalid(field.NewPath("status", "admission").Child("podSetFlavors").Index(0).Child("splits"), nil, ""),
+			},
+		},
+		"valid reclaimable pods": {
+			workload: testingutil.MakeWorkload(testWorkloadName, testWorkloadNamespace).
+				Count(10).
+				ReclaimablePods(kueue.ReclaimablePod{Name: "main", Count: 4}).
+				Obj(),
+		},
+		"reclaimable pods should match a podSet": {
+			workload: testingutil.MakeWorkload(testWorkloadName, testWorkloadNamespace).
+				Count(10).
+				ReclaimablePods(kueue.ReclaimablePod{Name: "workers", Count: 4}).
+				Obj(),
+			wantErr: field.ErrorList{
+				field.NotFound(field.NewPath("status", "reclaimablePods").Index(0).Child("name"), nil),
+			},
+		},
+		"reclaimable pods should not exceed the podSet count": {
+			workload: testingutil.MakeWorkload(testWorkloadName, testWorkloadNamespace).
+				Count(10).
+				ReclaimablePods(kueue.ReclaimablePod{Name: "main", Count: 11}).
+				Obj(),
+			wantErr: field.ErrorList{
+				field.Invalid(field.NewPath("status", "reclaimablePods").Index(0).Child("count"), nil, ""),
 			},
 		},
 	}
@@ -291,6 +575,17 @@ func TestValidateWorkload(t *testing.T) {
 	}
 }
 
+func TestValidateWorkloadCreate(t *testing.T) {
+	wl := testingutil.MakeWorkload(testWorkloadName, testWorkloadNamespace).
+		DeprecatedAdmission(testingutil.MakeAdmission("cq").Obj()).Obj()
+	wantErr := field.ErrorList{
+		field.Forbidden(field.NewPath("spec").Child("admission"), ""),
+	}
+	if diff := cmp.Diff(wantErr, ValidateWorkloadCreate(wl), cmpopts.IgnoreFields(field.Error{}, "Detail", "BadValue")); diff != "" {
+		t.Errorf("ValidateWorkloadCreate() mismatch (-want +got):\n%s", diff)
+	}
+}
+
 func TestValidateWorkloadUpdate(t *testing.T) {
 	testCases := map[string]struct {
 		before, after *kueue.Workload
@@ -340,6 +635,13 @@ func TestValidateWorkloadUpdate(t *testing.T) {
 				field.Invalid(field.NewPath("spec").Child("podSets"), nil, ""),
 			},
 		},
+		"managedBy should not be updated": {
+			before: testingutil.MakeWorkload(testWorkloadName, testWorkloadNamespace).Obj(),
+			after:  testingutil.MakeWorkload(testWorkloadName, testWorkloadNamespace).ManagedBy("example.com/dispatcher").Obj(),
+			wantErr: field.ErrorList{
+				field.Invalid(field.NewPath("spec").Child("managedBy"), nil, ""),
+			},
+		},
 		"queueName can be updated when not admitted": {
 			before:  testingutil.MakeWorkload(testWorkloadName, testWorkloadNamespace).Queue("q1").Obj(),
 			after:   testingutil.MakeWorkload(testWorkloadName, testWorkloadNamespace).Queue("q2").Obj(),
@@ -359,6 +661,54 @@ func TestValidateWorkloadUpdate(t *testing.T) {
 				field.Invalid(field.NewPath("spec").Child("queueName"), nil, ""),
 			},
 		},
+		"queueName and clusterQueue can be updated when moving": {
+			before: testingutil.MakeWorkload(testWorkloadName, testWorkloadNamespace).Queue("q1").MoveToQueue("q2").
+				Admit(testingutil.MakeAdmission("cq1").Flavor("on-demand", "5").Obj()).Obj(),
+			after: testingutil.MakeWorkload(testWorkloadName, testWorkloadNamespace).Queue("q2").
+				Admit(testingutil.MakeAdmission("cq2").Flavor("on-demand", "5").Obj()).Obj(),
+		},
+		"flavors should not be updated when moving": {
+			before: testingutil.MakeWorkload(testWorkloadName, testWorkloadNamespace).Queue("q1").MoveToQueue("q2").
+				Admit(testingutil.MakeAdmission("cq1").Flavor("on-demand", "5").Obj()).Obj(),
+			after: testingutil.MakeWorkload(testWorkloadName, testWorkloadNamespace).Queue("q2").
+				Admit(testingutil.MakeAdmission("cq2").Flavor("spot", "5").Obj()).Obj(),
+			wantErr: field.ErrorList{
+				field.Invalid(field.NewPath("status", "admission"), nil, ""),
+			},
+		},
+		"deprecated admission can be removed": {
+			before: testingutil.MakeWorkload(testWorkloadName, testWorkloadNamespace).
+				DeprecatedAdmission(testingutil.MakeAdmission("cq").Obj()).
+				Admit(testingutil.MakeAdmission("cq").Obj()).Obj(),
+			after: testingutil.MakeWorkload(testWorkloadName, testWorkloadNamespace).
+				Admit(testingutil.MakeAdmission("cq").Obj()).Obj(),
+		},
+		"deprecated admission should not be set": {
+			before: testingutil.MakeWorkload(testWorkloadName, testWorkloadNamespace).Obj(),
+			after: testingutil.MakeWorkload(testWorkloadName, testWorkloadNamespace).
+				DeprecatedAdmission(testingutil.MakeAdmission("cq").Obj()).Obj(),
+			wantErr: field.ErrorList{
+				field.Invalid(field.NewPath("spec").Child("admission"), nil, ""),
+			},
+		},
+		"reclaimable pods can increase while admitted": {
+			before: testingutil.MakeWorkload(testWorkloadName, testWorkloadNamespace).Count(10).
+				Admit(testingutil.MakeAdmission("cq").Obj()).
+				ReclaimablePods(kueue.ReclaimablePod{Name: "main", Count: 2}).Obj(),
+			after: testingutil.MakeWorkload(testWorkloadName, testWorkloadNamespace).Count(10).
+				Admit(testingutil.MakeAdmission("cq").Obj()).
+				ReclaimablePods(kueue.ReclaimablePod{Name: "main", Count: 4}).Obj(),
+		},
+		"reclaimable pods should not decrease while admitted": {
+			before: testingutil.MakeWorkload(testWorkloadName, testWorkloadNamespace).Count(10).
+				Admit(testingutil.MakeAdmission("cq").Obj()).
+				ReclaimablePods(kueue.ReclaimablePod{Name: "main", Count: 4}).Obj(),
+			after: testingutil.MakeWorkload(testWorkloadName, testWorkloadNamespace).Count(10).
+				Admit(testingutil.MakeAdmission("cq").Obj()).Obj(),
+			wantErr: field.ErrorList{
+				field.Forbidden(field.NewPath("status", "reclaimablePods"), ""),
+			},
+		},
 		"queueName can be updated when admission is reset": {
 			before: testingutil.MakeWorkload(testWorkloadName, testWorkloadNamespace).Queue("q1").
 				Admit(testingutil.MakeAdmission("cq").Obj()).Obj(),
@@ -386,7 +736,7 @@ func TestValidateWorkloadUpdate(t *testing.T) {
 				testingutil.MakeAdmission("cluster-queue").Flavor("on-demand", "5").Obj(),
 			).Obj(),
 			wantErr: field.ErrorList{
-				field.Invalid(field.NewPath("spec").Child("admission"), nil, ""),
+				field.Invalid(field.NewPath("status", "admission"), nil, ""),
 			},
 		},
 	}
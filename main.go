@@ -22,6 +22,7 @@ import (
 	"flag"
 	"fmt"
 	"os"
+	"time"
 
 	// Import all Kubernetes client auth plugins (e.g. Azure, GCP, OIDC, etc.)
 	// to ensure that exec-entrypoint and run can make use of them.
@@ -41,17 +42,30 @@ import (
 	config "sigs.k8s.io/kueue/apis/config/v1alpha2"
 	kueue "sigs.k8s.io/kueue/apis/kueue/v1alpha2"
 	"sigs.k8s.io/kueue/apis/kueue/webhooks"
+	"sigs.k8s.io/kueue/pkg/archiver"
 	"sigs.k8s.io/kueue/pkg/cache"
+	"sigs.k8s.io/kueue/pkg/capacity"
 	"sigs.k8s.io/kueue/pkg/constants"
+	"sigs.k8s.io/kueue/pkg/controller/admissionchecks/provisioning"
 	"sigs.k8s.io/kueue/pkg/controller/core"
 	"sigs.k8s.io/kueue/pkg/controller/workload/job"
+	"sigs.k8s.io/kueue/pkg/controller/workload/jobframework"
+	"sigs.k8s.io/kueue/pkg/controller/workload/pod"
 	"sigs.k8s.io/kueue/pkg/metrics"
 	"sigs.k8s.io/kueue/pkg/queue"
 	"sigs.k8s.io/kueue/pkg/scheduler"
+	"sigs.k8s.io/kueue/pkg/scheduler/externaladmission"
+	"sigs.k8s.io/kueue/pkg/scheduler/framework"
+	"sigs.k8s.io/kueue/pkg/scheduler/rebalancer"
 	"sigs.k8s.io/kueue/pkg/util/cert"
 	"sigs.k8s.io/kueue/pkg/util/useragent"
 	"sigs.k8s.io/kueue/pkg/version"
+	"sigs.k8s.io/kueue/pkg/workload"
 	// +kubebuilder:scaffold:imports
+
+	// Register the job integrations.
+	_ "sigs.k8s.io/kueue/pkg/controller/workload/kubeflow"
+	_ "sigs.k8s.io/kueue/pkg/controller/workload/serving"
 )
 
 var (
@@ -87,7 +101,8 @@ func main() {
 
 	options, cfg := apply(configFile)
 
-	metrics.Register()
+	metrics.Register(metricsOptions(&cfg)...)
+	workload.SetResourceTransformations(cfg.Resources)
 
 	kubeConfig := ctrl.GetConfigOrDie()
 	if kubeConfig.UserAgent == "" {
@@ -111,10 +126,10 @@ func main() {
 		close(certsReady)
 	}
 
-	cCache := cache.New(mgr.GetClient(), cache.WithPodsReadyTracking(waitForPodsReady(&cfg)))
-	queues := queue.NewManager(mgr.GetClient(), cCache)
+	cCache := cache.New(mgr.GetClient(), cacheOptions(&cfg)...)
+	queues := queue.NewManager(mgr.GetClient(), cCache, queueOptions(&cfg)...)
 
-	setupIndexes(mgr)
+	setupIndexes(mgr, &cfg)
 
 	setupProbeEndpoints(mgr)
 	// Cert won't be ready until manager starts, so start a goroutine here which
@@ -139,7 +154,7 @@ func main() {
 	}
 }
 
-func setupIndexes(mgr ctrl.Manager) {
+func setupIndexes(mgr ctrl.Manager, cfg *config.Configuration) {
 	if err := queue.SetupIndexes(mgr.GetFieldIndexer()); err != nil {
 		setupLog.Error(err, "Unable to setup queue indexes")
 	}
@@ -149,6 +164,9 @@ func setupIndexes(mgr ctrl.Manager) {
 	if err := job.SetupIndexes(mgr.GetFieldIndexer()); err != nil {
 		setupLog.Error(err, "Unable to setup job indexes")
 	}
+	if err := jobframework.SetupIndexes(mgr.GetFieldIndexer(), integrations(cfg)); err != nil {
+		setupLog.Error(err, "Unable to setup integration indexes")
+	}
 }
 
 func setupControllers(mgr ctrl.Manager, cCache *cache.Cache, queues *queue.Manager, certsReady chan struct{}, cfg *config.Configuration) {
@@ -158,20 +176,50 @@ func setupControllers(mgr ctrl.Manager, cCache *cache.Cache, queues *queue.Manag
 	<-certsReady
 	setupLog.Info("Certs ready")
 
-	if failedCtrl, err := core.SetupControllers(mgr, queues, cCache); err != nil {
+	if failedCtrl, err := core.SetupControllers(mgr, queues, cCache, workloadReconcilerOptions(cfg)...); err != nil {
 		setupLog.Error(err, "Unable to create controller", "controller", failedCtrl)
 		os.Exit(1)
 	}
+	if cfg.ProvisioningRequests != nil {
+		if failedCtrl, err := provisioning.SetupControllers(mgr, cfg.ProvisioningRequests); err != nil {
+			setupLog.Error(err, "Unable to create controller", "controller", failedCtrl)
+			os.Exit(1)
+		}
+	}
 	manageJobsWithoutQueueName := cfg.ManageJobsWithoutQueueName
 	if err := job.NewReconciler(mgr.GetScheme(),
 		mgr.GetClient(),
 		mgr.GetEventRecorderFor(constants.JobControllerName),
 		job.WithManageJobsWithoutQueueName(manageJobsWithoutQueueName),
 		job.WithWaitForPodsReady(waitForPodsReady(cfg)),
+		job.WithReportSuspendMismatchOnly(reportSuspendMismatchOnly(cfg)),
+		job.WithSuspendCheckPeriod(suspendCheckPeriod(cfg)),
+		job.WithPrioritySources(cfg.PrioritySources),
 	).SetupWithManager(mgr); err != nil {
 		setupLog.Error(err, "unable to create controller", "controller", "Job")
 		os.Exit(1)
 	}
+	for _, i := range integrations(cfg) {
+		if err := jobframework.NewReconciler(mgr.GetScheme(),
+			mgr.GetClient(),
+			mgr.GetEventRecorderFor(constants.JobControllerName),
+			i,
+			jobframework.WithManageJobsWithoutQueueName(manageJobsWithoutQueueName),
+			jobframework.WithWaitForPodsReady(waitForPodsReady(cfg)),
+		).SetupWithManager(mgr); err != nil {
+			setupLog.Error(err, "unable to create controller", "controller", i.GVK.Kind)
+			os.Exit(1)
+		}
+	}
+	if podIntegrationEnabled(cfg) {
+		if err := pod.NewReconciler(mgr.GetClient(),
+			mgr.GetEventRecorderFor(constants.JobControllerName),
+			pod.WithWaitForPodsReady(waitForPodsReady(cfg)),
+		).SetupWithManager(mgr); err != nil {
+			setupLog.Error(err, "unable to create controller", "controller", "Pod")
+			os.Exit(1)
+		}
+	}
 	if failedWebhook, err := webhooks.Setup(mgr); err != nil {
 		setupLog.Error(err, "Unable to create webhook", "webhook", failedWebhook)
 		os.Exit(1)
@@ -180,6 +228,18 @@ func setupControllers(mgr ctrl.Manager, cCache *cache.Cache, queues *queue.Manag
 		setupLog.Error(err, "Unable to create webhook", "webhook", "Job")
 		os.Exit(1)
 	}
+	// The webhooks of the integrations are served even if they aren't
+	// enabled, as they are all in the webhook configuration.
+	if err := jobframework.SetupWebhooks(mgr, jobframework.WithManageJobsWithoutQueueName(manageJobsWithoutQueueName)); err != nil {
+		setupLog.Error(err, "Unable to create webhook", "webhook", "integrations")
+		os.Exit(1)
+	}
+	// The webhooks of the plain Pods are served even if the integration isn't
+	// enabled, as their failure policy is to reject the pods.
+	if err := pod.SetupWebhook(mgr, pod.WithEnabled(podIntegrationEnabled(cfg))); err != nil {
+		setupLog.Error(err, "Unable to create webhook", "webhook", "Pod")
+		os.Exit(1)
+	}
 	// +kubebuilder:scaffold:builder
 }
 
@@ -204,14 +264,166 @@ func setupScheduler(ctx context.Context, mgr ctrl.Manager, cCache *cache.Cache,
 		mgr.GetClient(),
 		mgr.GetEventRecorderFor(constants.AdmissionName),
 		scheduler.WithWaitForPodsReady(waitForPodsReady(cfg)),
+		scheduler.WithPreemptionVictimSelection(victimSelection(cfg)),
+		scheduler.WithPreemptionDryRun(cfg.Preemption != nil && cfg.Preemption.DryRun),
+		scheduler.WithFairSharing(cfg.FairSharing != nil && cfg.FairSharing.Enable),
+		scheduler.WithPlugins(plugins(cfg)...),
 	)
-	go sched.Start(ctx)
+	// The manager waits for the scheduler to drain the cycle in flight when it
+	// terminates.
+	if err := mgr.Add(sched); err != nil {
+		setupLog.Error(err, "Unable to add the scheduler to the manager")
+		os.Exit(1)
+	}
+
+	if cfg.CohortRebalancing != nil {
+		rb := rebalancer.New(
+			mgr.GetClient(),
+			cCache,
+			queues,
+			mgr.GetEventRecorderFor(constants.AdmissionName),
+			rebalancer.WithPeriod(cfg.CohortRebalancing.Period.Duration),
+			rebalancer.WithImbalanceThreshold(*cfg.CohortRebalancing.ImbalanceThreshold),
+			rebalancer.WithSustainedPeriod(cfg.CohortRebalancing.SustainedPeriod.Duration),
+			rebalancer.WithMaxEvictionsPerCohort(*cfg.CohortRebalancing.MaxEvictionsPerCohort),
+		)
+		go rb.Start(ctx)
+	}
+
+	if cfg.CapacitySnapshots != nil {
+		loc, err := time.LoadLocation(cfg.CapacitySnapshots.TimeZone)
+		if err != nil {
+			setupLog.Error(err, "Invalid time zone for the capacity snapshots")
+			os.Exit(1)
+		}
+		snapshotter := capacity.New(
+			cCache,
+			queues,
+			capacity.NewWebhook(cfg.CapacitySnapshots.URL, cfg.CapacitySnapshots.Timeout.Duration),
+			capacity.WithInterval(cfg.CapacitySnapshots.Interval.Duration),
+			capacity.WithLocation(loc),
+		)
+		go snapshotter.Start(ctx)
+	}
 }
 
 func waitForPodsReady(cfg *config.Configuration) bool {
 	return cfg.WaitForPodsReady != nil && cfg.WaitForPodsReady.Enable
 }
 
+func cacheOptions(cfg *config.Configuration) []cache.Option {
+	opts := []cache.Option{
+		cache.WithPodsReadyTracking(waitForPodsReady(cfg)),
+		cache.WithDefaultFlavorAssignmentPolicy(kueue.FlavorAssignmentPolicy(cfg.FlavorAssignmentPolicy)),
+		cache.WithFlavorDeletionPolicy(cfg.ResourceFlavorDeletionPolicy),
+	}
+	if cfg.FairSharing != nil && cfg.FairSharing.UsageHalfLifeTime != nil {
+		opts = append(opts, cache.WithUsageHalfLife(cfg.FairSharing.UsageHalfLifeTime.Duration))
+	}
+	if cfg.FlavorFungibility != nil {
+		opts = append(opts, cache.WithDefaultFlavorFungibility(kueue.FlavorFungibility{
+			WhenCanBorrow:  kueue.FlavorFungibilityPolicy(cfg.FlavorFungibility.WhenCanBorrow),
+			WhenCanPreempt: kueue.FlavorFungibilityPolicy(cfg.FlavorFungibility.WhenCanPreempt),
+		}))
+	}
+	return opts
+}
+
+func queueOptions(cfg *config.Configuration) []queue.Option {
+	opts := []queue.Option{
+		queue.WithStrictCreationOrder(cfg.StrictCreationOrder),
+		queue.WithRequeueEvictedFirst(cfg.RequeueEvictedFirst),
+		queue.WithPriorityInheritance(cfg.PriorityInheritance),
+	}
+	if cfg.RequeueBackoff != nil {
+		opts = append(opts, queue.WithRequeueBackoff(cfg.RequeueBackoff.BaseDelay.Duration, cfg.RequeueBackoff.MaxDelay.Duration))
+	}
+	if cfg.Quarantine != nil {
+		opts = append(opts, queue.WithQuarantine(*cfg.Quarantine.Threshold, cfg.Quarantine.RetryInterval.Duration))
+	}
+	if cfg.WorkloadAging != nil {
+		opts = append(opts, queue.WithWorkloadAging(*cfg.WorkloadAging.Rate, *cfg.WorkloadAging.Cap))
+	}
+	return opts
+}
+
+func workloadReconcilerOptions(cfg *config.Configuration) []core.WorkloadReconcilerOption {
+	var opts []core.WorkloadReconcilerOption
+	if cfg.Archival != nil {
+		opts = append(opts, core.WithArchiver(archiver.NewWebhook(cfg.Archival.URL, cfg.Archival.Timeout.Duration)))
+	}
+	if cfg.MaxWorkloadEvictions != nil {
+		opts = append(opts, core.WithMaxEvictions(*cfg.MaxWorkloadEvictions))
+	}
+	if waitForPodsReady(cfg) && cfg.WaitForPodsReady.Timeout != nil {
+		opts = append(opts, core.WithPodsReadyTimeout(cfg.WaitForPodsReady.Timeout.Duration))
+	}
+	return opts
+}
+
+func victimSelection(cfg *config.Configuration) config.VictimSelectionStrategy {
+	if cfg.Preemption == nil {
+		return ""
+	}
+	return cfg.Preemption.VictimSelection
+}
+
+// plugins returns the plugins compiled into the scheduler, followed by the
+// plugins enabled in the configuration.
+func plugins(cfg *config.Configuration) []framework.Plugin {
+	plugins := append([]framework.Plugin(nil), schedulerPlugins...)
+	if cfg.ExternalAdmission != nil {
+		plugins = append(plugins, externaladmission.New(cfg.ExternalAdmission))
+	}
+	return plugins
+}
+
+// integrations returns the job integrations enabled in the configuration.
+// It exits if an integration is not registered.
+func integrations(cfg *config.Configuration) []*jobframework.Integration {
+	if cfg.Integrations == nil {
+		return nil
+	}
+	var frameworks []string
+	for _, f := range cfg.Integrations.Frameworks {
+		if f != pod.FrameworkName {
+			frameworks = append(frameworks, f)
+		}
+	}
+	enabled, err := jobframework.EnableIntegrations(frameworks)
+	if err != nil {
+		setupLog.Error(err, "Unsupported framework", "supported", append(jobframework.IntegrationNames(), pod.FrameworkName))
+		os.Exit(1)
+	}
+	return enabled
+}
+
+// podIntegrationEnabled returns whether the plain Pods integration is enabled
+// in the configuration. Unlike the job integrations, it isn't built on the
+// jobframework, as the pods of a group share a single workload.
+func podIntegrationEnabled(cfg *config.Configuration) bool {
+	if cfg.Integrations == nil {
+		return false
+	}
+	for _, f := range cfg.Integrations.Frameworks {
+		if f == pod.FrameworkName {
+			return true
+		}
+	}
+	return false
+}
+
+func reportSuspendMismatchOnly(cfg *config.Configuration) bool {
+	return cfg.JobSuspendReconciliation != nil && cfg.JobSuspendReconciliation.Policy == config.JobSuspendPolicyReport
+}
+
+func suspendCheckPeriod(cfg *config.Configuration) time.Duration {
+	if cfg.JobSuspendReconciliation == nil || cfg.JobSuspendReconciliation.Period == nil {
+		return 0
+	}
+	return cfg.JobSuspendReconciliation.Period.Duration
+}
+
 func encodeConfig(cfg *config.Configuration) (string, error) {
 	codecs := serializer.NewCodecFactory(scheme)
 	const mediaType = runtime.ContentTypeYAML
@@ -255,3 +467,17 @@ func apply(configFile string) (ctrl.Options, config.Configuration) {
 
 	return options, cfg
 }
+
+func metricsOptions(cfg *config.Configuration) []metrics.Option {
+	if cfg.MetricsCardinality == nil {
+		return nil
+	}
+	opts := []metrics.Option{
+		metrics.WithFlavorLabel(!cfg.MetricsCardinality.DisableFlavorLabel),
+		metrics.WithPriorityClassLabel(!cfg.MetricsCardinality.DisablePriorityClassLabel),
+	}
+	if cfg.MetricsCardinality.MaxClusterQueues != nil {
+		opts = append(opts, metrics.WithMaxClusterQueues(int(*cfg.MetricsCardinality.MaxClusterQueues)))
+	}
+	return opts
+}
@@ -20,6 +20,7 @@ import (
 	"os"
 	"path/filepath"
 	"testing"
+	"time"
 
 	"github.com/google/go-cmp/cmp"
 	"github.com/google/go-cmp/cmp/cmpopts"
@@ -153,6 +154,149 @@ webhook:
 		t.Fatal(err)
 	}
 
+	jobSuspendReconciliationConfig := filepath.Join(tmpDir, "jobSuspendReconciliation.yaml")
+	if err := os.WriteFile(jobSuspendReconciliationConfig, []byte(`
+apiVersion: config.kueue.x-k8s.io/v1alpha2
+kind: Configuration
+namespace: kueue-system
+health:
+  healthProbeBindAddress: :8081
+metrics:
+  bindAddress: :8080
+leaderElection:
+  leaderElect: true
+  resourceName: c1f6bfd2.kueue.x-k8s.io
+jobSuspendReconciliation:
+  period: 5m
+webhook:
+  port: 9443
+`), os.FileMode(0600)); err != nil {
+		t.Fatal(err)
+	}
+
+	prioritySourcesConfig := filepath.Join(tmpDir, "prioritySources.yaml")
+	if err := os.WriteFile(prioritySourcesConfig, []byte(`
+apiVersion: config.kueue.x-k8s.io/v1alpha2
+kind: Configuration
+namespace: kueue-system
+health:
+  healthProbeBindAddress: :8081
+metrics:
+  bindAddress: :8080
+leaderElection:
+  leaderElect: true
+  resourceName: c1f6bfd2.kueue.x-k8s.io
+prioritySources:
+- LocalQueue
+- PodPriorityClass
+webhook:
+  port: 9443
+`), os.FileMode(0600)); err != nil {
+		t.Fatal(err)
+	}
+
+	cohortRebalancingConfig := filepath.Join(tmpDir, "cohortRebalancing.yaml")
+	if err := os.WriteFile(cohortRebalancingConfig, []byte(`
+apiVersion: config.kueue.x-k8s.io/v1alpha2
+kind: Configuration
+namespace: kueue-system
+health:
+  healthProbeBindAddress: :8081
+metrics:
+  bindAddress: :8080
+leaderElection:
+  leaderElect: true
+  resourceName: c1f6bfd2.kueue.x-k8s.io
+cohortRebalancing:
+  period: 30s
+  maxEvictionsPerCohort: 2
+webhook:
+  port: 9443
+`), os.FileMode(0600)); err != nil {
+		t.Fatal(err)
+	}
+
+	preemptionConfig := filepath.Join(tmpDir, "preemption.yaml")
+	if err := os.WriteFile(preemptionConfig, []byte(`
+apiVersion: config.kueue.x-k8s.io/v1alpha2
+kind: Configuration
+namespace: kueue-system
+health:
+  healthProbeBindAddress: :8081
+metrics:
+  bindAddress: :8080
+leaderElection:
+  leaderElect: true
+  resourceName: c1f6bfd2.kueue.x-k8s.io
+preemption:
+  victimSelection: FewestVictims
+webhook:
+  port: 9443
+`), os.FileMode(0600)); err != nil {
+		t.Fatal(err)
+	}
+
+	archivalConfig := filepath.Join(tmpDir, "archival.yaml")
+	if err := os.WriteFile(archivalConfig, []byte(`
+apiVersion: config.kueue.x-k8s.io/v1alpha2
+kind: Configuration
+namespace: kueue-system
+health:
+  healthProbeBindAddress: :8081
+metrics:
+  bindAddress: :8080
+leaderElection:
+  leaderElect: true
+  resourceName: c1f6bfd2.kueue.x-k8s.io
+webhook:
+  port: 9443
+archival:
+  url: https://archive.example.com/workloads
+  timeout: 30s
+`), os.FileMode(0600)); err != nil {
+		t.Fatal(err)
+	}
+
+	fairSharingConfig := filepath.Join(tmpDir, "fairSharing.yaml")
+	if err := os.WriteFile(fairSharingConfig, []byte(`
+apiVersion: config.kueue.x-k8s.io/v1alpha2
+kind: Configuration
+namespace: kueue-system
+health:
+  healthProbeBindAddress: :8081
+metrics:
+  bindAddress: :8080
+leaderElection:
+  leaderElect: true
+  resourceName: c1f6bfd2.kueue.x-k8s.io
+webhook:
+  port: 9443
+fairSharing:
+  enable: true
+`), os.FileMode(0600)); err != nil {
+		t.Fatal(err)
+	}
+
+	requeueBackoffConfig := filepath.Join(tmpDir, "requeueBackoff.yaml")
+	if err := os.WriteFile(requeueBackoffConfig, []byte(`
+apiVersion: config.kueue.x-k8s.io/v1alpha2
+kind: Configuration
+namespace: kueue-system
+health:
+  healthProbeBindAddress: :8081
+metrics:
+  bindAddress: :8080
+leaderElection:
+  leaderElect: true
+  resourceName: c1f6bfd2.kueue.x-k8s.io
+webhook:
+  port: 9443
+requeueBackoff:
+  baseDelay: 5s
+`), os.FileMode(0600)); err != nil {
+		t.Fatal(err)
+	}
+
 	defaultControlOptions := ctrl.Options{
 		Port:                   config.DefaultWebhookPort,
 		HealthProbeBindAddress: config.DefaultHealthProbeBindAddress,
@@ -172,6 +316,12 @@ webhook:
 		cmpopts.IgnoreFields(ctrl.Options{}, "Scheme", "Logger"),
 	}
 
+	defaultPrioritySources := []config.PrioritySource{
+		config.PrioritySourcePriorityClassLabel,
+		config.PrioritySourcePodPriorityClass,
+		config.PrioritySourceLocalQueue,
+	}
+
 	configCmpOpts := []cmp.Option{
 		cmpopts.IgnoreFields(config.Configuration{}, "ControllerManagerConfigurationSpec"),
 	}
@@ -188,6 +338,7 @@ webhook:
 			wantConfiguration: config.Configuration{
 				Namespace:              pointer.String(config.DefaultNamespace),
 				InternalCertManagement: enableDefaultInternalCertManagement,
+				PrioritySources:        defaultPrioritySources,
 			},
 			wantOptions: ctrl.Options{
 				Port:                   config.DefaultWebhookPort,
@@ -208,6 +359,7 @@ webhook:
 				Namespace:                  pointer.String("kueue-tenant-a"),
 				ManageJobsWithoutQueueName: false,
 				InternalCertManagement:     enableDefaultInternalCertManagement,
+				PrioritySources:            defaultPrioritySources,
 			},
 			wantOptions: defaultControlOptions,
 		},
@@ -222,6 +374,7 @@ webhook:
 				Namespace:                  pointer.String(config.DefaultNamespace),
 				ManageJobsWithoutQueueName: false,
 				InternalCertManagement:     enableDefaultInternalCertManagement,
+				PrioritySources:            defaultPrioritySources,
 			},
 			wantOptions: ctrl.Options{
 				HealthProbeBindAddress: ":38081",
@@ -246,6 +399,7 @@ webhook:
 					WebhookServiceName: pointer.String("kueue-tenant-a-webhook-service"),
 					WebhookSecretName:  pointer.String("kueue-tenant-a-webhook-server-cert"),
 				},
+				PrioritySources: defaultPrioritySources,
 			},
 			wantOptions: defaultControlOptions,
 		},
@@ -262,6 +416,7 @@ webhook:
 				InternalCertManagement: &config.InternalCertManagement{
 					Enable: pointer.Bool(false),
 				},
+				PrioritySources: defaultPrioritySources,
 			},
 			wantOptions: defaultControlOptions,
 		},
@@ -276,6 +431,7 @@ webhook:
 				Namespace:                  pointer.String("kueue-system"),
 				ManageJobsWithoutQueueName: false,
 				InternalCertManagement:     enableDefaultInternalCertManagement,
+				PrioritySources:            defaultPrioritySources,
 			},
 			wantOptions: ctrl.Options{
 				Port:                   config.DefaultWebhookPort,
@@ -299,6 +455,139 @@ webhook:
 				WaitForPodsReady: &config.WaitForPodsReady{
 					Enable: true,
 				},
+				PrioritySources: defaultPrioritySources,
+			},
+			wantOptions: defaultControlOptions,
+		},
+		{
+			name:       "jobSuspendReconciliation config",
+			configFile: jobSuspendReconciliationConfig,
+			wantConfiguration: config.Configuration{
+				TypeMeta: metav1.TypeMeta{
+					APIVersion: config.GroupVersion.String(),
+					Kind:       "Configuration",
+				},
+				Namespace:                  pointer.String(config.DefaultNamespace),
+				ManageJobsWithoutQueueName: false,
+				InternalCertManagement:     enableDefaultInternalCertManagement,
+				JobSuspendReconciliation: &config.JobSuspendReconciliation{
+					Policy: config.JobSuspendPolicySuspend,
+					Period: &metav1.Duration{Duration: 5 * time.Minute},
+				},
+				PrioritySources: defaultPrioritySources,
+			},
+			wantOptions: defaultControlOptions,
+		},
+		{
+			name:       "prioritySources config",
+			configFile: prioritySourcesConfig,
+			wantConfiguration: config.Configuration{
+				TypeMeta: metav1.TypeMeta{
+					APIVersion: config.GroupVersion.String(),
+					Kind:       "Configuration",
+				},
+				Namespace:                  pointer.String(config.DefaultNamespace),
+				ManageJobsWithoutQueueName: false,
+				InternalCertManagement:     enableDefaultInternalCertManagement,
+				PrioritySources: []config.PrioritySource{
+					config.PrioritySourceLocalQueue,
+					config.PrioritySourcePodPriorityClass,
+				},
+			},
+			wantOptions: defaultControlOptions,
+		},
+		{
+			name:       "cohortRebalancing config",
+			configFile: cohortRebalancingConfig,
+			wantConfiguration: config.Configuration{
+				TypeMeta: metav1.TypeMeta{
+					APIVersion: config.GroupVersion.String(),
+					Kind:       "Configuration",
+				},
+				Namespace:                  pointer.String(config.DefaultNamespace),
+				ManageJobsWithoutQueueName: false,
+				InternalCertManagement:     enableDefaultInternalCertManagement,
+				PrioritySources:            defaultPrioritySources,
+				CohortRebalancing: &config.CohortRebalancing{
+					Period:                &metav1.Duration{Duration: 30 * time.Second},
+					ImbalanceThreshold:    pointer.Int32(config.DefaultCohortRebalancingThreshold),
+					SustainedPeriod:       &metav1.Duration{Duration: config.DefaultCohortRebalancingSustainedPeriod},
+					MaxEvictionsPerCohort: pointer.Int32(2),
+				},
+			},
+			wantOptions: defaultControlOptions,
+		},
+		{
+			name:       "preemption config",
+			configFile: preemptionConfig,
+			wantConfiguration: config.Configuration{
+				TypeMeta: metav1.TypeMeta{
+					APIVersion: config.GroupVersion.String(),
+					Kind:       "Configuration",
+				},
+				Namespace:                  pointer.String(config.DefaultNamespace),
+				ManageJobsWithoutQueueName: false,
+				InternalCertManagement:     enableDefaultInternalCertManagement,
+				PrioritySources:            defaultPrioritySources,
+				Preemption: &config.Preemption{
+					VictimSelection: config.VictimSelectionFewestVictims,
+				},
+			},
+			wantOptions: defaultControlOptions,
+		},
+		{
+			name:       "archival config",
+			configFile: archivalConfig,
+			wantConfiguration: config.Configuration{
+				TypeMeta: metav1.TypeMeta{
+					APIVersion: config.GroupVersion.String(),
+					Kind:       "Configuration",
+				},
+				Namespace:                  pointer.String(config.DefaultNamespace),
+				ManageJobsWithoutQueueName: false,
+				InternalCertManagement:     enableDefaultInternalCertManagement,
+				PrioritySources:            defaultPrioritySources,
+				Archival: &config.Archival{
+					URL:     "https://archive.example.com/workloads",
+					Timeout: &metav1.Duration{Duration: 30 * time.Second},
+				},
+			},
+			wantOptions: defaultControlOptions,
+		},
+		{
+			name:       "fair sharing config",
+			configFile: fairSharingConfig,
+			wantConfiguration: config.Configuration{
+				TypeMeta: metav1.TypeMeta{
+					APIVersion: config.GroupVersion.String(),
+					Kind:       "Configuration",
+				},
+				Namespace:                  pointer.String(config.DefaultNamespace),
+				ManageJobsWithoutQueueName: false,
+				InternalCertManagement:     enableDefaultInternalCertManagement,
+				PrioritySources:            defaultPrioritySources,
+				FairSharing: &config.FairSharing{
+					Enable: true,
+				},
+			},
+			wantOptions: defaultControlOptions,
+		},
+		{
+			name:       "requeue backoff config",
+			configFile: requeueBackoffConfig,
+			wantConfiguration: config.Configuration{
+				TypeMeta: metav1.TypeMeta{
+					APIVersion: config.GroupVersion.String(),
+					Kind:       "Configuration",
+				},
+				Namespace:                  pointer.String(config.DefaultNamespace),
+				ManageJobsWithoutQueueName: false,
+				InternalCertManagement:     enableDefaultInternalCertManagement,
+				PrioritySources:            defaultPrioritySources,
+				RequeueBackoff: &config.RequeueBackoff{
+					BaseDelay: &metav1.Duration{Duration: 5 * time.Second},
+					MaxDelay:  &metav1.Duration{Duration: config.DefaultRequeueBackoffMaxDelay},
+				},
 			},
 			wantOptions: defaultControlOptions,
 		},
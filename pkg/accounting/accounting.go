@@ -0,0 +1,188 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package accounting tracks the accumulated usage of resources over time,
+// from the admission of the workloads until they finish, to enforce the
+// budgets of the ClusterQueues.
+package accounting
+
+import (
+	"math"
+	"time"
+
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	kueue "sigs.k8s.io/kueue/apis/kueue/v1alpha2"
+	"sigs.k8s.io/kueue/pkg/workload"
+)
+
+const secondsPerHour = 3600
+
+// PeriodStart returns the start of the period that contains t. Periods start
+// at midnight UTC, and weeks start on Monday.
+func PeriodStart(p kueue.BudgetPeriod, t time.Time) time.Time {
+	y, m, d := t.UTC().Date()
+	switch p {
+	case kueue.BudgetPeriodWeek:
+		day := time.Date(y, m, d, 0, 0, 0, 0, time.UTC)
+		// Weekday is 0 on Sunday.
+		return day.AddDate(0, 0, -(int(day.Weekday())+6)%7)
+	case kueue.BudgetPeriodMonth:
+		return time.Date(y, m, 1, 0, 0, 0, 0, time.UTC)
+	default:
+		return time.Date(y, m, d, 0, 0, 0, 0, time.UTC)
+	}
+}
+
+// NextPeriodStart returns the start of the period after the one that
+// contains t.
+func NextPeriodStart(p kueue.BudgetPeriod, t time.Time) time.Time {
+	start := PeriodStart(p, t)
+	switch p {
+	case kueue.BudgetPeriodWeek:
+		return start.AddDate(0, 0, 7)
+	case kueue.BudgetPeriodMonth:
+		return start.AddDate(0, 1, 0)
+	default:
+		return start.AddDate(0, 0, 1)
+	}
+}
+
+// Tracker accumulates the usage of a budget over its current period, in
+// resource-seconds. The usage of the workloads that stopped running is
+// settled in the tracker, while the usage of the running workloads is
+// computed when needed.
+type Tracker struct {
+	Budget kueue.Budget
+	// PeriodStart is the start of the current period.
+	PeriodStart time.Time
+
+	settled float64
+}
+
+// NewTracker returns a tracker for the budget, in the period that contains
+// now.
+func NewTracker(b kueue.Budget, now time.Time) *Tracker {
+	return &Tracker{
+		Budget:      b,
+		PeriodStart: PeriodStart(b.Period, now),
+	}
+}
+
+// SameBudget returns whether the tracker accounts the usage of the budget,
+// regardless of its limit.
+func (t *Tracker) SameBudget(b kueue.Budget) bool {
+	return t.Budget.Resource == b.Resource && t.Budget.Flavor == b.Flavor && t.Budget.Period == b.Period
+}
+
+// Roll starts a new period if the current one ended before now. It returns
+// whether a new period started.
+func (t *Tracker) Roll(now time.Time) bool {
+	start := PeriodStart(t.Budget.Period, now)
+	if !start.After(t.PeriodStart) {
+		return false
+	}
+	t.PeriodStart = start
+	t.settled = 0
+	return true
+}
+
+// Settle adds the usage of a workload that stopped running at the given time.
+func (t *Tracker) Settle(wi *workload.Info, end time.Time) {
+	t.settled += t.usage(wi, end)
+}
+
+// Used returns the usage in the current period, including the usage of the
+// running workloads until now.
+func (t *Tracker) Used(running []*workload.Info, now time.Time) float64 {
+	used := t.settled
+	for _, wi := range running {
+		used += t.usage(wi, now)
+	}
+	return used
+}
+
+// Limit returns the usage allowed in a period, in resource-seconds.
+func (t *Tracker) Limit() float64 {
+	return t.Budget.Limit.AsApproximateFloat64() * secondsPerHour
+}
+
+// Restore sets the settled usage from the status of the budget, if it's for
+// the current period. The usage of the running workloads is subtracted, as
+// it's computed from their admission time.
+func (t *Tracker) Restore(statuses []kueue.BudgetStatus, running []*workload.Info, now time.Time) {
+	for _, s := range statuses {
+		if s.Resource != t.Budget.Resource || s.Flavor != t.Budget.Flavor || s.Period != t.Budget.Period {
+			continue
+		}
+		if !s.PeriodStart.Time.Equal(t.PeriodStart) {
+			return
+		}
+		var runningUsage float64
+		for _, wi := range running {
+			runningUsage += t.usage(wi, now)
+		}
+		t.settled = math.Max(0, s.Used.AsApproximateFloat64()*secondsPerHour-runningUsage)
+		return
+	}
+}
+
+// Status returns the status of the budget, given its usage.
+func (t *Tracker) Status(used float64) kueue.BudgetStatus {
+	return kueue.BudgetStatus{
+		Resource:    t.Budget.Resource,
+		Flavor:      t.Budget.Flavor,
+		Period:      t.Budget.Period,
+		PeriodStart: metav1.NewTime(t.PeriodStart),
+		Used:        hours(used),
+		Remaining:   hours(math.Max(0, t.Limit()-used)),
+	}
+}
+
+// usage returns the usage of the budget by the workload, from its admission,
+// or the start of the period, until the end.
+func (t *Tracker) usage(wi *workload.Info, end time.Time) float64 {
+	start, ok := workload.AdmissionTime(wi.Obj)
+	if !ok {
+		return 0
+	}
+	if start.Before(t.PeriodStart) {
+		start = t.PeriodStart
+	}
+	if !end.After(start) {
+		return 0
+	}
+	var quantity float64
+	for _, ps := range wi.TotalRequests {
+		v, ok := ps.Requests[t.Budget.Resource]
+		if !ok {
+			continue
+		}
+		if t.Budget.Flavor != "" && ps.Flavors[t.Budget.Resource] != string(t.Budget.Flavor) {
+			continue
+		}
+		q := workload.ResourceQuantity(t.Budget.Resource, v)
+		quantity += q.AsApproximateFloat64()
+	}
+	return quantity * end.Sub(start).Seconds()
+}
+
+// hours returns the resource-seconds as a quantity of resource-hours, with
+// milli precision.
+func hours(seconds float64) resource.Quantity {
+	return *resource.NewMilliQuantity(int64(math.Round(seconds*1000/secondsPerHour)), resource.DecimalSI)
+}
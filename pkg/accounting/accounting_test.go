@@ -0,0 +1,197 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package accounting
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	kueue "sigs.k8s.io/kueue/apis/kueue/v1alpha2"
+	utiltesting "sigs.k8s.io/kueue/pkg/util/testing"
+	"sigs.k8s.io/kueue/pkg/workload"
+)
+
+func TestPeriods(t *testing.T) {
+	// Friday.
+	now := time.Date(2022, 10, 14, 15, 30, 0, 0, time.UTC)
+	cases := map[kueue.BudgetPeriod]struct {
+		wantStart time.Time
+		wantNext  time.Time
+	}{
+		kueue.BudgetPeriodDay: {
+			wantStart: time.Date(2022, 10, 14, 0, 0, 0, 0, time.UTC),
+			wantNext:  time.Date(2022, 10, 15, 0, 0, 0, 0, time.UTC),
+		},
+		kueue.BudgetPeriodWeek: {
+			wantStart: time.Date(2022, 10, 10, 0, 0, 0, 0, time.UTC),
+			wantNext:  time.Date(2022, 10, 17, 0, 0, 0, 0, time.UTC),
+		},
+		kueue.BudgetPeriodMonth: {
+			wantStart: time.Date(2022, 10, 1, 0, 0, 0, 0, time.UTC),
+			wantNext:  time.Date(2022, 11, 1, 0, 0, 0, 0, time.UTC),
+		},
+	}
+	for period, tc := range cases {
+		t.Run(string(period), func(t *testing.T) {
+			if got := PeriodStart(period, now); !got.Equal(tc.wantStart) {
+				t.Errorf("Got period start %v, want %v", got, tc.wantStart)
+			}
+			if got := NextPeriodStart(period, now); !got.Equal(tc.wantNext) {
+				t.Errorf("Got next period start %v, want %v", got, tc.wantNext)
+			}
+		})
+	}
+}
+
+func TestTracker(t *testing.T) {
+	monthStart := time.Date(2022, 10, 1, 0, 0, 0, 0, time.UTC)
+	admitted := func(name, flavor string, at time.Time) *workload.Info {
+		return workload.NewInfo(utiltesting.MakeWorkload(name, "").
+			Request("nvidia.com/gpu", "2").
+			Admit(utiltesting.MakeAdmission("cq").Flavor("nvidia.com/gpu", flavor).Obj()).
+			Condition(metav1.Condition{
+				Type:               kueue.WorkloadAdmitted,
+				Status:             metav1.ConditionTrue,
+				LastTransitionTime: metav1.NewTime(at),
+			}).
+			Obj())
+	}
+	// Admitted in the previous period.
+	old := admitted("old", "a100", monthStart.Add(-10*time.Hour))
+	a100 := admitted("a100", "a100", monthStart.Add(24*time.Hour))
+	t4 := admitted("t4", "t4", monthStart.Add(24*time.Hour))
+	now := monthStart.Add(34 * time.Hour)
+
+	cases := map[string]struct {
+		flavor     kueue.ResourceFlavorReference
+		settled    []*workload.Info
+		running    []*workload.Info
+		statuses   []kueue.BudgetStatus
+		wantStatus kueue.BudgetStatus
+	}{
+		"running workloads in any flavor": {
+			running: []*workload.Info{old, a100, t4},
+			wantStatus: kueue.BudgetStatus{
+				Used:      resource.MustParse("108"),
+				Remaining: resource.MustParse("892"),
+			},
+		},
+		"running workloads in a flavor": {
+			flavor:  "a100",
+			running: []*workload.Info{old, a100, t4},
+			wantStatus: kueue.BudgetStatus{
+				Flavor:    "a100",
+				Used:      resource.MustParse("88"),
+				Remaining: resource.MustParse("912"),
+			},
+		},
+		"settled workloads": {
+			settled: []*workload.Info{a100},
+			running: []*workload.Info{t4},
+			wantStatus: kueue.BudgetStatus{
+				Used:      resource.MustParse("40"),
+				Remaining: resource.MustParse("960"),
+			},
+		},
+		"restored from the status": {
+			running: []*workload.Info{t4},
+			statuses: []kueue.BudgetStatus{{
+				Resource:    "nvidia.com/gpu",
+				Period:      kueue.BudgetPeriodMonth,
+				PeriodStart: metav1.NewTime(monthStart),
+				Used:        resource.MustParse("1020"),
+			}},
+			wantStatus: kueue.BudgetStatus{
+				Used:      resource.MustParse("1020"),
+				Remaining: resource.MustParse("0"),
+			},
+		},
+		"status of the previous period": {
+			running: []*workload.Info{t4},
+			statuses: []kueue.BudgetStatus{{
+				Resource:    "nvidia.com/gpu",
+				Period:      kueue.BudgetPeriodMonth,
+				PeriodStart: metav1.NewTime(monthStart.AddDate(0, -1, 0)),
+				Used:        resource.MustParse("1020"),
+			}},
+			wantStatus: kueue.BudgetStatus{
+				Used:      resource.MustParse("20"),
+				Remaining: resource.MustParse("980"),
+			},
+		},
+	}
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			tracker := NewTracker(kueue.Budget{
+				Resource: "nvidia.com/gpu",
+				Flavor:   tc.flavor,
+				Period:   kueue.BudgetPeriodMonth,
+				Limit:    resource.MustParse("1000"),
+			}, now)
+			for _, wi := range tc.settled {
+				tracker.Settle(wi, now)
+			}
+			tracker.Restore(tc.statuses, tc.running, now)
+			got := tracker.Status(tracker.Used(tc.running, now))
+			tc.wantStatus.Resource = "nvidia.com/gpu"
+			tc.wantStatus.Period = kueue.BudgetPeriodMonth
+			tc.wantStatus.PeriodStart = metav1.NewTime(monthStart)
+			if diff := cmp.Diff(tc.wantStatus, got); diff != "" {
+				t.Errorf("Unexpected status (-want,+got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestTrackerRoll(t *testing.T) {
+	dayStart := time.Date(2022, 10, 14, 0, 0, 0, 0, time.UTC)
+	wi := workload.NewInfo(utiltesting.MakeWorkload("wl", "").
+		Request(corev1.ResourceCPU, "500m").
+		Admit(utiltesting.MakeAdmission("cq").Flavor(corev1.ResourceCPU, "default").Obj()).
+		Condition(metav1.Condition{
+			Type:               kueue.WorkloadAdmitted,
+			Status:             metav1.ConditionTrue,
+			LastTransitionTime: metav1.NewTime(dayStart.Add(-2 * time.Hour)),
+		}).
+		Obj())
+	tracker := NewTracker(kueue.Budget{
+		Resource: corev1.ResourceCPU,
+		Period:   kueue.BudgetPeriodDay,
+		Limit:    resource.MustParse("10"),
+	}, dayStart.Add(-time.Hour))
+	tracker.Settle(wi, dayStart)
+	if used := tracker.Used(nil, dayStart); used != 3600 {
+		t.Errorf("Got usage %v before rolling, want 3600", used)
+	}
+	if tracker.Roll(dayStart.Add(-time.Minute)) {
+		t.Errorf("Rolled before the end of the period")
+	}
+	if !tracker.Roll(dayStart.Add(time.Minute)) {
+		t.Errorf("Didn't roll after the end of the period")
+	}
+	if !tracker.PeriodStart.Equal(dayStart) {
+		t.Errorf("Got period start %v, want %v", tracker.PeriodStart, dayStart)
+	}
+	if used := tracker.Used(nil, dayStart.Add(time.Minute)); used != 0 {
+		t.Errorf("Got usage %v after rolling, want 0", used)
+	}
+}
@@ -0,0 +1,119 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package archiver
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	apimeta "k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+
+	kueue "sigs.k8s.io/kueue/apis/kueue/v1alpha2"
+	"sigs.k8s.io/kueue/pkg/util/useragent"
+	"sigs.k8s.io/kueue/pkg/workload"
+)
+
+// Record is the archived state of a finished Workload.
+type Record struct {
+	Namespace         string      `json:"namespace"`
+	Name              string      `json:"name"`
+	UID               types.UID   `json:"uid"`
+	CreationTimestamp metav1.Time `json:"creationTimestamp"`
+
+	// FinishedAt, Reason and Message are taken from the Finished condition
+	// of the Workload.
+	FinishedAt metav1.Time `json:"finishedAt"`
+	Reason     string      `json:"reason,omitempty"`
+	Message    string      `json:"message,omitempty"`
+
+	Spec          kueue.WorkloadSpec           `json:"spec"`
+	Admission     *kueue.Admission             `json:"admission,omitempty"`
+	ResourceUsage *kueue.WorkloadResourceUsage `json:"resourceUsage,omitempty"`
+	Conditions    []metav1.Condition           `json:"conditions,omitempty"`
+}
+
+// NewRecord returns the record of a finished Workload.
+func NewRecord(wl *kueue.Workload) *Record {
+	r := &Record{
+		Namespace:         wl.Namespace,
+		Name:              wl.Name,
+		UID:               wl.UID,
+		CreationTimestamp: wl.CreationTimestamp,
+		Spec:              *wl.Spec.DeepCopy(),
+		Admission:         wl.Status.Admission.DeepCopy(),
+		ResourceUsage:     wl.Status.ResourceUsage.DeepCopy(),
+	}
+	if r.ResourceUsage == nil {
+		r.ResourceUsage = workload.ResourceUsage(wl)
+	}
+	if cond := apimeta.FindStatusCondition(wl.Status.Conditions, kueue.WorkloadFinished); cond != nil {
+		r.FinishedAt = cond.LastTransitionTime
+		r.Reason = cond.Reason
+		r.Message = cond.Message
+	}
+	for _, c := range wl.Status.Conditions {
+		r.Conditions = append(r.Conditions, *c.DeepCopy())
+	}
+	return r
+}
+
+// Archiver stores the records of finished Workloads outside of the cluster.
+type Archiver interface {
+	Archive(ctx context.Context, r *Record) error
+}
+
+type webhookArchiver struct {
+	url    string
+	client *http.Client
+}
+
+// NewWebhook returns an Archiver that sends each record, encoded as JSON, in
+// an HTTP POST request to url.
+func NewWebhook(url string, timeout time.Duration) Archiver {
+	return &webhookArchiver{
+		url:    url,
+		client: &http.Client{Timeout: timeout},
+	}
+}
+
+func (a *webhookArchiver) Archive(ctx context.Context, r *Record) error {
+	body, err := json.Marshal(r)
+	if err != nil {
+		return fmt.Errorf("encoding record: %w", err)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, a.url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", useragent.Default())
+	resp, err := a.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("unexpected response status from %s: %s", a.url, resp.Status)
+	}
+	return nil
+}
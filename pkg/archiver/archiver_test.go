@@ -0,0 +1,118 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package archiver
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	kueue "sigs.k8s.io/kueue/apis/kueue/v1alpha2"
+	utiltesting "sigs.k8s.io/kueue/pkg/util/testing"
+)
+
+func TestWebhookArchive(t *testing.T) {
+	createdAt := time.Now().Truncate(time.Second)
+	admittedAt := createdAt.Add(time.Minute)
+	finishedAt := admittedAt.Add(time.Hour)
+	finishedCond := metav1.Condition{
+		Type:               kueue.WorkloadFinished,
+		Status:             metav1.ConditionTrue,
+		LastTransitionTime: metav1.NewTime(finishedAt),
+		Reason:             "JobFinished",
+		Message:            "Job finished successfully",
+	}
+	admittedCond := metav1.Condition{
+		Type:               kueue.WorkloadAdmitted,
+		Status:             metav1.ConditionTrue,
+		LastTransitionTime: metav1.NewTime(admittedAt),
+		Reason:             "AdmissionByKueue",
+	}
+	wl := utiltesting.MakeWorkload("foo", "ns").
+		Request(corev1.ResourceCPU, "2").
+		Admit(utiltesting.MakeAdmission("cq").Flavor(corev1.ResourceCPU, "default").Obj()).
+		Condition(admittedCond).
+		Condition(finishedCond).
+		Obj()
+	wl.CreationTimestamp = metav1.NewTime(createdAt)
+
+	cases := map[string]struct {
+		status     int
+		wantErr    bool
+		wantRecord *Record
+	}{
+		"accepted": {
+			status: http.StatusCreated,
+			wantRecord: &Record{
+				Namespace:         "ns",
+				Name:              "foo",
+				CreationTimestamp: metav1.NewTime(createdAt),
+				FinishedAt:        metav1.NewTime(finishedAt),
+				Reason:            "JobFinished",
+				Message:           "Job finished successfully",
+				Spec:              wl.Spec,
+				Admission:         wl.Status.Admission,
+				ResourceUsage: &kueue.WorkloadResourceUsage{
+					QueuedTime: metav1.Duration{Duration: time.Minute},
+					RunTime:    metav1.Duration{Duration: time.Hour},
+					AdmittedResources: map[corev1.ResourceName]map[string]resource.Quantity{
+						corev1.ResourceCPU: {"default": resource.MustParse("2")},
+					},
+				},
+				Conditions: []metav1.Condition{admittedCond, finishedCond},
+			},
+		},
+		"rejected": {
+			status:  http.StatusInternalServerError,
+			wantErr: true,
+		},
+	}
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			var gotRecord *Record
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if r.Method != http.MethodPost {
+					t.Errorf("Got method %s, want POST", r.Method)
+				}
+				if tc.status < 300 {
+					gotRecord = &Record{}
+					if err := json.NewDecoder(r.Body).Decode(gotRecord); err != nil {
+						t.Errorf("Failed decoding record: %v", err)
+					}
+				}
+				w.WriteHeader(tc.status)
+			}))
+			defer srv.Close()
+
+			err := NewWebhook(srv.URL, time.Second).Archive(context.Background(), NewRecord(wl))
+			if gotErr := err != nil; gotErr != tc.wantErr {
+				t.Fatalf("Archive returned error %v, want error: %t", err, tc.wantErr)
+			}
+			if diff := cmp.Diff(tc.wantRecord, gotRecord); diff != "" {
+				t.Errorf("Unexpected record (-want,+got):\n%s", diff)
+			}
+		})
+	}
+}
@@ -20,25 +20,38 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"math"
+	"sort"
 	"sync"
+	"time"
 
 	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
 	apimeta "k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/api/resource"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/labels"
 	"k8s.io/apimachinery/pkg/util/sets"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 
+	config "sigs.k8s.io/kueue/apis/config/v1alpha2"
 	kueue "sigs.k8s.io/kueue/apis/kueue/v1alpha2"
+	"sigs.k8s.io/kueue/pkg/accounting"
 	"sigs.k8s.io/kueue/pkg/metrics"
+	"sigs.k8s.io/kueue/pkg/util/api"
 	"sigs.k8s.io/kueue/pkg/util/pointer"
+	"sigs.k8s.io/kueue/pkg/util/priority"
 	"sigs.k8s.io/kueue/pkg/workload"
 )
 
 const (
-	workloadClusterQueueKey = "spec.admission.clusterQueue"
+	workloadClusterQueueKey = "status.admission.clusterQueue"
 	queueClusterQueueKey    = "spec.clusterQueue"
+
+	// defaultFairWeight is the fair sharing weight, in milli units, of the
+	// ClusterQueues that don't set one.
+	defaultFairWeight = 1000
 )
 
 var (
@@ -48,7 +61,11 @@ var (
 )
 
 type options struct {
-	podsReadyTracking bool
+	podsReadyTracking             bool
+	defaultFlavorAssignmentPolicy kueue.FlavorAssignmentPolicy
+	defaultFlavorFungibility      kueue.FlavorFungibility
+	flavorDeletionPolicy          config.ResourceFlavorDeletionPolicy
+	usageHalfLife                 time.Duration
 }
 
 // Option configures the reconciler.
@@ -63,7 +80,41 @@ func WithPodsReadyTracking(f bool) Option {
 	}
 }
 
-var defaultOptions = options{}
+// WithDefaultFlavorAssignmentPolicy sets the flavor assignment policy of the
+// ClusterQueues that don't set one.
+func WithDefaultFlavorAssignmentPolicy(p kueue.FlavorAssignmentPolicy) Option {
+	return func(o *options) {
+		o.defaultFlavorAssignmentPolicy = p
+	}
+}
+
+// WithDefaultFlavorFungibility sets the flavor fungibility of the
+// ClusterQueues that don't set it, field by field.
+func WithDefaultFlavorFungibility(f kueue.FlavorFungibility) Option {
+	return func(o *options) {
+		o.defaultFlavorFungibility = f
+	}
+}
+
+// WithFlavorDeletionPolicy sets what happens when a ResourceFlavor is deleted
+// while it's in use.
+func WithFlavorDeletionPolicy(p config.ResourceFlavorDeletionPolicy) Option {
+	return func(o *options) {
+		o.flavorDeletionPolicy = p
+	}
+}
+
+// WithUsageHalfLife enables tracking the historical usage of the
+// ClusterQueues, which decays exponentially with the given half-life.
+func WithUsageHalfLife(d time.Duration) Option {
+	return func(o *options) {
+		o.usageHalfLife = d
+	}
+}
+
+var defaultOptions = options{
+	flavorDeletionPolicy: config.ResourceFlavorDeletionBlock,
+}
 
 // Cache keeps track of the Workloads that got admitted through ClusterQueues.
 type Cache struct {
@@ -75,7 +126,25 @@ type Cache struct {
 	cohorts           map[string]*Cohort
 	assumedWorkloads  map[string]string
 	resourceFlavors   map[string]*kueue.ResourceFlavor
+	admissionChecks   map[string]bool
 	podsReadyTracking bool
+	// shares are the resources that the ResourceFlavors share between pods.
+	shares resourceShares
+	// reservations hold the quota reserved for the workloads waiting for the
+	// preemption of other workloads, keyed by workload.
+	reservations map[string]*workload.Info
+	// defaultFlavorAssignmentPolicy is the flavor assignment policy of the
+	// ClusterQueues that don't set one.
+	defaultFlavorAssignmentPolicy kueue.FlavorAssignmentPolicy
+	// defaultFlavorFungibility is the flavor fungibility of the ClusterQueues
+	// that don't set it.
+	defaultFlavorFungibility kueue.FlavorFungibility
+	// flavorDeletionPolicy is what happens when a ResourceFlavor is deleted
+	// while it's in use.
+	flavorDeletionPolicy config.ResourceFlavorDeletionPolicy
+	// usageHalfLife is the half-life of the historical usage of the
+	// ClusterQueues. Zero means that the historical usage is not tracked.
+	usageHalfLife time.Duration
 }
 
 func New(client client.Client, opts ...Option) *Cache {
@@ -89,7 +158,16 @@ func New(client client.Client, opts ...Option) *Cache {
 		cohorts:           make(map[string]*Cohort),
 		assumedWorkloads:  make(map[string]string),
 		resourceFlavors:   make(map[string]*kueue.ResourceFlavor),
+		admissionChecks:   make(map[string]bool),
 		podsReadyTracking: options.podsReadyTracking,
+		reservations:      make(map[string]*workload.Info),
+	}
+	c.defaultFlavorAssignmentPolicy = options.defaultFlavorAssignmentPolicy
+	c.defaultFlavorFungibility = options.defaultFlavorFungibility
+	c.flavorDeletionPolicy = options.flavorDeletionPolicy
+	c.usageHalfLife = options.usageHalfLife
+	if c.flavorDeletionPolicy == "" {
+		c.flavorDeletionPolicy = config.ResourceFlavorDeletionBlock
 	}
 	c.podsReadyCond.L = &c.RWMutex
 	return c
@@ -98,13 +176,27 @@ func New(client client.Client, opts ...Option) *Cache {
 type ResourceQuantities map[corev1.ResourceName]map[string]int64
 
 // Cohort is a set of ClusterQueues that can borrow resources from each other.
+// Cohorts can have a parent, forming trees in which the ClusterQueues under
+// the same root cohort can borrow resources from each other.
 type Cohort struct {
 	Name    string
 	members map[*ClusterQueue]struct{}
 
+	// These fields come from the Cohort object, if there is one.
+	hasObject  bool
+	parentName string
+	resources  []kueue.CohortResource
+	quota      ResourceQuantities
+	limits     ResourceQuantities
+
 	// These fields are only populated for a snapshot.
+	Parent *Cohort
+	// RequestableResources and UsedResources include the quota and usage of
+	// the descendant cohorts.
 	RequestableResources ResourceQuantities
 	UsedResources        ResourceQuantities
+	// MaxResources limit the usage of the cohort, including its descendants.
+	MaxResources ResourceQuantities
 }
 
 func newCohort(name string, size int) *Cohort {
@@ -114,6 +206,27 @@ func newCohort(name string, size int) *Cohort {
 	}
 }
 
+// Root returns the root of the tree of cohorts that the cohort belongs to.
+// It is only meaningful for a snapshot.
+func (c *Cohort) Root() *Cohort {
+	for c.Parent != nil {
+		c = c.Parent
+	}
+	return c
+}
+
+// ExceedingMax returns the first cohort, from c up to the root, whose max
+// quota for the flavor of the resource would be exceeded by val more usage,
+// or nil if there is none. It is only meaningful for a snapshot.
+func (c *Cohort) ExceedingMax(rName corev1.ResourceName, flavor string, val int64) *Cohort {
+	for ; c != nil; c = c.Parent {
+		if max, ok := c.MaxResources[rName][flavor]; ok && c.UsedResources[rName][flavor]+val > max {
+			return c
+		}
+	}
+	return nil
+}
+
 const (
 	pending     = metrics.CQStatusPending
 	active      = metrics.CQStatusActive
@@ -135,11 +248,105 @@ type ClusterQueue struct {
 	// that can be matched against the flavors.
 	LabelKeys map[corev1.ResourceName]sets.String
 	Status    metrics.ClusterQueueStatus
+	// LocalQueueLimits holds the limits of the LocalQueues pointing to this
+	// ClusterQueue that define them, keyed by namespace/name.
+	LocalQueueLimits map[string]workload.Requests
+	// LocalQueueLimitsPercent holds the limits of the LocalQueues pointing to
+	// this ClusterQueue, as a percentage of its quota, keyed by namespace/name.
+	LocalQueueLimitsPercent map[string]map[corev1.ResourceName]int32
+	Preemption              kueue.ClusterQueuePreemption
+	// FairWeight is the fair sharing weight of the ClusterQueue, in milli
+	// units.
+	FairWeight int64
+	// Backfill indicates if workloads can be admitted behind a head of the
+	// StrictFIFO ClusterQueue that doesn't fit.
+	Backfill bool
+	// HeadBlockingTimeout is how long a workload that can't be admitted can
+	// block the head of the StrictFIFO ClusterQueue. Zero means no timeout.
+	HeadBlockingTimeout time.Duration
+	// StrictFIFO indicates if the ClusterQueue uses the StrictFIFO queueing
+	// strategy.
+	StrictFIFO bool
+	// BestFit indicates if the ClusterQueue assigns the flavors with the
+	// BestFit policy, instead of FirstFit.
+	BestFit bool
+	// LowestCost indicates if the ClusterQueue assigns the cheapest flavor
+	// that fits, with the LowestCost policy, instead of FirstFit.
+	LowestCost bool
+	// TryNextFlavorWhenCanBorrow indicates if the ClusterQueue looks for a
+	// flavor that fits without borrowing before borrowing in a flavor.
+	TryNextFlavorWhenCanBorrow bool
+	// PreemptWhenCanPreempt indicates if the ClusterQueue assigns the first
+	// flavor in which preemption can make the workload fit, instead of trying
+	// the next flavors.
+	PreemptWhenCanPreempt bool
+	// SplitWhenNoFlavorFits indicates if the ClusterQueue splits the pods of
+	// a pod set between two flavors when no single flavor fits them.
+	SplitWhenNoFlavorFits bool
+	// RevocableBorrowing indicates if the workloads that the ClusterQueue
+	// admits borrowing quota are revocable.
+	RevocableBorrowing bool
+	// BorrowingMinPriority is the lowest priority of the workloads that can
+	// borrow quota from the cohort. Nil means any priority.
+	BorrowingMinPriority *int32
+	// Policies are the effective scheduling policies of the ClusterQueue.
+	Policies kueue.ClusterQueuePolicies
+	// PriorityBands split the min quota of the ClusterQueue among ranges of
+	// workload priorities.
+	PriorityBands []kueue.PriorityBand
+	// ReservedTiers reserve parts of the min quota of the ClusterQueue for the
+	// workloads with a minimum priority.
+	ReservedTiers []kueue.ReservedTier
+	// TierUsage holds the usage of the admitted workloads of each reserved
+	// tier, keyed by tier. A workload belongs to the tier with the highest
+	// minPriority that is not above its priority, if any.
+	TierUsage map[string]ResourceQuantities
+	// FlavorFallbacks restrict the workloads evicted too often from a flavor
+	// to another flavor.
+	FlavorFallbacks []kueue.FlavorFallback
+	// ExhaustedBudgets holds the flavors of each resource whose budget is
+	// exhausted. An empty flavor means all the flavors of the resource. It's
+	// only populated in a snapshot.
+	ExhaustedBudgets map[corev1.ResourceName]sets.String
+	// HistoricalUsage is the usage of each resource, summed across its
+	// flavors, decayed exponentially over time. It's only populated in a
+	// snapshot, when the historical usage is tracked.
+	HistoricalUsage map[corev1.ResourceName]int64
+	// AdmissionChecks are the names of the AdmissionChecks that the
+	// workloads must pass after their quota is reserved.
+	AdmissionChecks []string
+	// FlavorAdmissionChecks are the names of the AdmissionChecks that the
+	// workloads assigned each flavor must pass, besides the AdmissionChecks,
+	// keyed by flavor.
+	FlavorAdmissionChecks map[string]sets.String
+	// MaxWorkloadsPendingChecks limits the workloads whose quota is reserved
+	// while they wait for their AdmissionChecks. Nil means no limit.
+	MaxWorkloadsPendingChecks *int32
 
 	// The following fields are not populated in a snapshot.
 
-	admittedWorkloadsPerQueue map[string]int
-	podsReadyTracking         bool
+	admittedWorkloadsPerQueue     map[string]int
+	podsReadyTracking             bool
+	defaultFlavorAssignmentPolicy kueue.FlavorAssignmentPolicy
+	defaultFlavorFungibility      kueue.FlavorFungibility
+	// specResources are the resources of the spec, before applying the
+	// quotas of the active windows.
+	specResources      []kueue.Resource
+	shares             resourceShares
+	quotaWindows       []kueue.QuotaWindow
+	activeQuotaWindows []string
+	budgets            []*accounting.Tracker
+	usageHalfLife      time.Duration
+	// historicalUsage is the usage of each resource, summed across its
+	// flavors, decayed exponentially with usageHalfLife, as of
+	// historyUpdated.
+	historicalUsage map[corev1.ResourceName]float64
+	historyUpdated  time.Time
+	// flavorNotFound and admissionChecksInactive hold why the ClusterQueue
+	// is pending: a flavor of its quotas doesn't exist, or one of its
+	// AdmissionChecks doesn't exist or isn't active.
+	flavorNotFound          bool
+	admissionChecksInactive bool
 }
 
 type Resource struct {
@@ -164,6 +371,8 @@ type FlavorLimits struct {
 	Name string
 	Min  int64
 	Max  *int64
+	// Hold indicates that the flavor isn't assigned to new workloads.
+	Hold bool
 }
 
 func (c *Cache) newClusterQueue(cq *kueue.ClusterQueue) (*ClusterQueue, error) {
@@ -174,7 +383,10 @@ func (c *Cache) newClusterQueue(cq *kueue.ClusterQueue) (*ClusterQueue, error) {
 		admittedWorkloadsPerQueue: make(map[string]int),
 		podsReadyTracking:         c.podsReadyTracking,
 	}
-	if err := cqImpl.update(cq, c.resourceFlavors); err != nil {
+	cqImpl.defaultFlavorAssignmentPolicy = c.defaultFlavorAssignmentPolicy
+	cqImpl.defaultFlavorFungibility = c.defaultFlavorFungibility
+	cqImpl.usageHalfLife = c.usageHalfLife
+	if err := cqImpl.update(cq, c.resourceFlavors, c.admissionChecks); err != nil {
 		return nil, err
 	}
 
@@ -237,29 +449,186 @@ func (c *Cache) CleanUpOnContext(ctx context.Context) {
 func (c *Cache) AdmittedWorkloadsInLocalQueue(localQueue *kueue.LocalQueue) int32 {
 	c.Lock()
 	defer c.Unlock()
-	cq, ok := c.clusterQueues[string(localQueue.Spec.ClusterQueue)]
+	qKey := queueKey(localQueue)
+	var admitted int32
+	for name := range localQueueClusterQueues(localQueue) {
+		if cq, ok := c.clusterQueues[name]; ok {
+			admitted += int32(cq.admittedWorkloadsPerQueue[qKey])
+		}
+	}
+	return admitted
+}
+
+// PriorityBandShares returns the usage of each priority band of the
+// ClusterQueue relative to its guaranteed quota, in per mille.
+func (c *Cache) PriorityBandShares(name string) map[string]int64 {
+	c.RLock()
+	defer c.RUnlock()
+	cq, ok := c.clusterQueues[name]
+	if !ok {
+		return nil
+	}
+	return cq.PriorityBandShares()
+}
+
+// RefreshQuotaWindows applies the quotas of the windows of the ClusterQueue
+// that are active at the given time. It returns whether the quotas changed and
+// the next time at which a window starts or ends, which is zero if the
+// ClusterQueue has no windows.
+func (c *Cache) RefreshQuotaWindows(name string, now time.Time) (bool, time.Time) {
+	c.Lock()
+	defer c.Unlock()
+	cq, ok := c.clusterQueues[name]
 	if !ok {
-		return 0
+		return false, time.Time{}
 	}
-	qKey := queueKey(localQueue)
-	return int32(cq.admittedWorkloadsPerQueue[qKey])
+	return cq.refreshQuotaWindows(now), api.NextQuotaWindowTransition(cq.quotaWindows, now)
+}
+
+// SettleWorkload accounts the usage of the admitted workload until the given
+// time, at which it stopped running, in the budgets of its ClusterQueue. It
+// should be called before the workload is removed from the cache.
+func (c *Cache) SettleWorkload(w *kueue.Workload, end time.Time) {
+	if w.Status.Admission == nil {
+		return
+	}
+	c.Lock()
+	defer c.Unlock()
+	cq, ok := c.clusterQueues[string(w.Status.Admission.ClusterQueue)]
+	if !ok {
+		return
+	}
+	if _, ok := cq.Workloads[workload.Key(w)]; !ok {
+		return
+	}
+	// The cached workload might not have the Admitted condition yet.
+	wi := workload.NewInfo(w)
+	for _, t := range cq.budgets {
+		t.Settle(wi, end)
+	}
+}
+
+// RefreshBudgets starts a new period for the budgets of the ClusterQueue whose
+// period ended before the given time. It returns whether a period started
+// and the start of the next period of any budget, which is zero if the
+// ClusterQueue has no budgets.
+func (c *Cache) RefreshBudgets(name string, now time.Time) (bool, time.Time) {
+	c.Lock()
+	defer c.Unlock()
+	cq, ok := c.clusterQueues[name]
+	if !ok {
+		return false, time.Time{}
+	}
+	rolled := false
+	var next time.Time
+	for _, t := range cq.budgets {
+		if t.Roll(now) {
+			rolled = true
+		}
+		if n := accounting.NextPeriodStart(t.Budget.Period, now); next.IsZero() || n.Before(next) {
+			next = n
+		}
+	}
+	return rolled, next
+}
+
+// BudgetStatus returns the usage of the budgets of the ClusterQueue at the
+// given time.
+func (c *Cache) BudgetStatus(name string, now time.Time) []kueue.BudgetStatus {
+	c.RLock()
+	defer c.RUnlock()
+	cq, ok := c.clusterQueues[name]
+	if !ok || len(cq.budgets) == 0 {
+		return nil
+	}
+	running := cq.runningWorkloads()
+	statuses := make([]kueue.BudgetStatus, 0, len(cq.budgets))
+	for _, t := range cq.budgets {
+		statuses = append(statuses, t.Status(t.Used(running, now)))
+	}
+	return statuses
+}
+
+// AdmittedWorkloadsPerLocalQueue returns the number of admitted workloads of
+// each LocalQueue of the ClusterQueue, keyed by namespace/name.
+func (c *Cache) AdmittedWorkloadsPerLocalQueue(name string) map[string]int {
+	c.RLock()
+	defer c.RUnlock()
+	cq, ok := c.clusterQueues[name]
+	if !ok {
+		return nil
+	}
+	admitted := make(map[string]int, len(cq.admittedWorkloadsPerQueue))
+	for qKey, n := range cq.admittedWorkloadsPerQueue {
+		admitted[qKey] = n
+	}
+	return admitted
 }
 
 func (c *ClusterQueue) Active() bool {
 	return c.Status == active
 }
 
-func (c *ClusterQueue) update(in *kueue.ClusterQueue, resourceFlavors map[string]*kueue.ResourceFlavor) error {
-	c.RequestableResources = resourcesByName(in.Spec.Resources)
+func (c *ClusterQueue) update(in *kueue.ClusterQueue, resourceFlavors map[string]*kueue.ResourceFlavor, admissionChecks map[string]bool) error {
+	c.specResources = api.ClusterQueueResources(in)
+	c.quotaWindows = in.Spec.QuotaWindows
+	c.activeQuotaWindows = api.ActiveQuotaWindows(c.quotaWindows, time.Now())
+	resources := api.ApplyQuotaWindows(c.specResources, c.quotaWindows, c.activeQuotaWindows)
+	c.RequestableResources = resourcesByName(resources, c.shares)
 	c.UpdateCodependentResources()
 	nsSelector, err := metav1.LabelSelectorAsSelector(in.Spec.NamespaceSelector)
 	if err != nil {
 		return err
 	}
 	c.NamespaceSelector = nsSelector
-
-	usedResources := make(ResourceQuantities, len(in.Spec.Resources))
-	for _, r := range in.Spec.Resources {
+	c.Preemption = kueue.ClusterQueuePreemption{}
+	if in.Spec.Preemption != nil {
+		c.Preemption = *in.Spec.Preemption
+	}
+	c.StrictFIFO = in.Spec.QueueingStrategy == kueue.StrictFIFO
+	c.Backfill = c.StrictFIFO && in.Spec.Backfill
+	c.HeadBlockingTimeout = 0
+	if c.StrictFIFO && in.Spec.HeadBlockingTimeout != nil {
+		c.HeadBlockingTimeout = in.Spec.HeadBlockingTimeout.Duration
+	}
+	flavorAssignmentPolicy := in.Spec.FlavorAssignmentPolicy
+	if flavorAssignmentPolicy == "" {
+		flavorAssignmentPolicy = c.defaultFlavorAssignmentPolicy
+	}
+	c.BestFit = flavorAssignmentPolicy == kueue.BestFit
+	c.LowestCost = flavorAssignmentPolicy == kueue.LowestCost
+	fungibility := c.defaultFlavorFungibility
+	if in.Spec.FlavorFungibility != nil {
+		if in.Spec.FlavorFungibility.WhenCanBorrow != "" {
+			fungibility.WhenCanBorrow = in.Spec.FlavorFungibility.WhenCanBorrow
+		}
+		if in.Spec.FlavorFungibility.WhenCanPreempt != "" {
+			fungibility.WhenCanPreempt = in.Spec.FlavorFungibility.WhenCanPreempt
+		}
+		if in.Spec.FlavorFungibility.WhenNoFlavorFits != "" {
+			fungibility.WhenNoFlavorFits = in.Spec.FlavorFungibility.WhenNoFlavorFits
+		}
+	}
+	c.TryNextFlavorWhenCanBorrow = fungibility.WhenCanBorrow == kueue.TryNextFlavor
+	c.PreemptWhenCanPreempt = fungibility.WhenCanPreempt == kueue.Preempt
+	c.SplitWhenNoFlavorFits = fungibility.WhenNoFlavorFits == kueue.SplitPodSet
+	c.Policies = effectivePolicies(in, flavorAssignmentPolicy, fungibility)
+	metrics.ReportClusterQueuePolicies(c.Name, c.Policies)
+	c.RevocableBorrowing = in.Spec.RevocableBorrowing
+	c.BorrowingMinPriority = nil
+	if in.Spec.BorrowWithinCohort != nil {
+		c.BorrowingMinPriority = in.Spec.BorrowWithinCohort.MinPriority
+	}
+	c.PriorityBands = in.Spec.PriorityBands
+	c.FlavorFallbacks = in.Spec.FlavorFallbacks
+	c.updateBudgets(in.Spec.Budgets, time.Now())
+	c.FairWeight = defaultFairWeight
+	if in.Spec.FairSharing != nil && in.Spec.FairSharing.Weight != nil {
+		c.FairWeight = in.Spec.FairSharing.Weight.MilliValue()
+	}
+
+	usedResources := make(ResourceQuantities, len(resources))
+	for _, r := range resources {
 		if len(r.Flavors) == 0 {
 			continue
 		}
@@ -272,10 +641,88 @@ func (c *ClusterQueue) update(in *kueue.ClusterQueue, resourceFlavors map[string
 		usedResources[r.Name] = usedFlavors
 	}
 	c.UsedResources = usedResources
+	c.updateReservedTiers(in.Spec.ReservedTiers)
+	c.AdmissionChecks = in.Spec.AdmissionChecks
+	c.FlavorAdmissionChecks = flavorAdmissionChecks(c.specResources)
+	c.MaxWorkloadsPendingChecks = in.Spec.MaxWorkloadsPendingChecks
+	c.admissionChecksInactive = c.hasInactiveAdmissionChecks(admissionChecks)
 	c.UpdateWithFlavors(resourceFlavors)
 	return nil
 }
 
+// refreshQuotaWindows applies the quotas of the windows that are active at
+// the given time. It returns whether the set of active windows changed.
+func (c *ClusterQueue) refreshQuotaWindows(now time.Time) bool {
+	active := api.ActiveQuotaWindows(c.quotaWindows, now)
+	if equality.Semantic.DeepEqual(active, c.activeQuotaWindows) {
+		return false
+	}
+	c.activeQuotaWindows = active
+	c.RequestableResources = resourcesByName(api.ApplyQuotaWindows(c.specResources, c.quotaWindows, active), c.shares)
+	c.UpdateCodependentResources()
+	return true
+}
+
+// updateBudgets sets the budgets of the ClusterQueue, keeping the usage
+// accounted for the budgets that it already had.
+func (c *ClusterQueue) updateBudgets(budgets []kueue.Budget, now time.Time) {
+	trackers := make([]*accounting.Tracker, 0, len(budgets))
+	for _, b := range budgets {
+		var tracker *accounting.Tracker
+		for _, t := range c.budgets {
+			if t.SameBudget(b) {
+				tracker = t
+				tracker.Budget = b
+				break
+			}
+		}
+		if tracker == nil {
+			tracker = accounting.NewTracker(b, now)
+		}
+		trackers = append(trackers, tracker)
+	}
+	c.budgets = trackers
+}
+
+// runningWorkloads returns the admitted workloads of the ClusterQueue.
+func (c *ClusterQueue) runningWorkloads() []*workload.Info {
+	running := make([]*workload.Info, 0, len(c.Workloads))
+	for _, wi := range c.Workloads {
+		running = append(running, wi)
+	}
+	return running
+}
+
+// exhaustedBudgets returns the flavors of each resource whose budget is
+// exhausted at the given time.
+func (c *ClusterQueue) exhaustedBudgets(now time.Time) map[corev1.ResourceName]sets.String {
+	if len(c.budgets) == 0 {
+		return nil
+	}
+	running := c.runningWorkloads()
+	var exhausted map[corev1.ResourceName]sets.String
+	for _, t := range c.budgets {
+		if t.Used(running, now) < t.Limit() {
+			continue
+		}
+		if exhausted == nil {
+			exhausted = make(map[corev1.ResourceName]sets.String)
+		}
+		if exhausted[t.Budget.Resource] == nil {
+			exhausted[t.Budget.Resource] = sets.NewString()
+		}
+		exhausted[t.Budget.Resource].Insert(string(t.Budget.Flavor))
+	}
+	return exhausted
+}
+
+// BudgetExhausted returns whether the budget of the resource in the flavor is
+// exhausted, according to ExhaustedBudgets.
+func (c *ClusterQueue) BudgetExhausted(rName corev1.ResourceName, flavor string) bool {
+	flavors := c.ExhaustedBudgets[rName]
+	return flavors.Has("") || flavors.Has(flavor)
+}
+
 func (c *ClusterQueue) UpdateCodependentResources() {
 	for iName, iRes := range c.RequestableResources {
 		if len(iRes.CodependentResources) > 0 {
@@ -296,14 +743,136 @@ func (c *ClusterQueue) UpdateCodependentResources() {
 	}
 }
 
+// effectivePolicies returns the scheduling policies of the ClusterQueue, with
+// the defaults of the API for the fields that are not set.
+func effectivePolicies(in *kueue.ClusterQueue, flavorAssignmentPolicy kueue.FlavorAssignmentPolicy, fungibility kueue.FlavorFungibility) kueue.ClusterQueuePolicies {
+	p := kueue.ClusterQueuePolicies{
+		QueueingStrategy:       in.Spec.QueueingStrategy,
+		OrderingPolicy:         in.Spec.OrderingPolicy,
+		LocalQueueFairness:     in.Spec.LocalQueueFairness,
+		FlavorAssignmentPolicy: flavorAssignmentPolicy,
+		FlavorFungibility:      fungibility,
+	}
+	if in.Spec.Preemption != nil {
+		p.Preemption = *in.Spec.Preemption
+	}
+	if p.QueueingStrategy == "" {
+		p.QueueingStrategy = kueue.BestEffortFIFO
+	}
+	if p.OrderingPolicy == "" {
+		p.OrderingPolicy = kueue.PriorityOrdering
+	}
+	if p.LocalQueueFairness == "" {
+		p.LocalQueueFairness = kueue.LocalQueueFairnessNone
+	}
+	if p.FlavorAssignmentPolicy == "" {
+		p.FlavorAssignmentPolicy = kueue.FirstFit
+	}
+	if p.FlavorFungibility.WhenCanBorrow == "" {
+		p.FlavorFungibility.WhenCanBorrow = kueue.Borrow
+	}
+	if p.FlavorFungibility.WhenCanPreempt == "" {
+		p.FlavorFungibility.WhenCanPreempt = kueue.TryNextFlavor
+	}
+	if p.FlavorFungibility.WhenNoFlavorFits == "" {
+		p.FlavorFungibility.WhenNoFlavorFits = kueue.Wait
+	}
+	if p.Preemption.ReclaimWithinCohort == "" {
+		p.Preemption.ReclaimWithinCohort = kueue.PreemptionPolicyNever
+	}
+	if p.Preemption.WithinClusterQueue == "" {
+		p.Preemption.WithinClusterQueue = kueue.PreemptionPolicyNever
+	}
+	return p
+}
+
+// CanBorrow returns whether the workloads with the given priority can borrow
+// quota from the cohort.
+func (c *ClusterQueue) CanBorrow(priority int32) bool {
+	return c.BorrowingMinPriority == nil || priority >= *c.BorrowingMinPriority
+}
+
 // UpdateWithFlavors updates a ClusterQueue based on the passed ResourceFlavors set.
 // Exported only for testing.
 func (c *ClusterQueue) UpdateWithFlavors(flavors map[string]*kueue.ResourceFlavor) {
+	if shares := sharesOf(flavors); !equality.Semantic.DeepEqual(shares, c.shares) {
+		c.shares = shares
+		c.RequestableResources = resourcesByName(api.ApplyQuotaWindows(c.specResources, c.quotaWindows, c.activeQuotaWindows), shares)
+		c.UpdateCodependentResources()
+	}
+
+	c.flavorNotFound = c.updateLabelKeys(flavors)
+	c.updateStatus()
+}
+
+// UpdateWithAdmissionChecks updates a ClusterQueue based on whether each
+// AdmissionCheck, keyed by name, is active.
+// Exported only for testing.
+func (c *ClusterQueue) UpdateWithAdmissionChecks(checks map[string]bool) {
+	c.admissionChecksInactive = c.hasInactiveAdmissionChecks(checks)
+	c.updateStatus()
+}
+
+func (c *ClusterQueue) hasInactiveAdmissionChecks(checks map[string]bool) bool {
+	for _, name := range c.AdmissionChecks {
+		if !checks[name] {
+			return true
+		}
+	}
+	for _, flavorChecks := range c.FlavorAdmissionChecks {
+		for name := range flavorChecks {
+			if !checks[name] {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// AdmissionChecksForFlavors returns the names of the AdmissionChecks that a
+// workload assigned the flavors must pass: the AdmissionChecks of the
+// ClusterQueue, followed by the ones of the flavors in alphabetical order.
+func (c *ClusterQueue) AdmissionChecksForFlavors(flavors sets.String) []string {
+	extra := sets.NewString()
+	for flavor := range flavors {
+		extra = extra.Union(c.FlavorAdmissionChecks[flavor])
+	}
+	extra.Delete(c.AdmissionChecks...)
+	if extra.Len() == 0 {
+		return c.AdmissionChecks
+	}
+	checks := make([]string, 0, len(c.AdmissionChecks)+extra.Len())
+	checks = append(checks, c.AdmissionChecks...)
+	return append(checks, extra.List()...)
+}
+
+// flavorAdmissionChecks returns the AdmissionChecks of the flavors of the
+// resources, keyed by flavor. It returns nil if no flavor has checks.
+func flavorAdmissionChecks(resources []kueue.Resource) map[string]sets.String {
+	var checks map[string]sets.String
+	for _, r := range resources {
+		for _, f := range r.Flavors {
+			if len(f.AdmissionChecks) == 0 {
+				continue
+			}
+			if checks == nil {
+				checks = make(map[string]sets.String)
+			}
+			name := string(f.Name)
+			if checks[name] == nil {
+				checks[name] = sets.NewString()
+			}
+			checks[name].Insert(f.AdmissionChecks...)
+		}
+	}
+	return checks
+}
+
+func (c *ClusterQueue) updateStatus() {
 	status := active
-	if flavorNotFound := c.updateLabelKeys(flavors); flavorNotFound {
+	if c.flavorNotFound || c.admissionChecksInactive {
 		status = pending
 	}
-
 	if c.Status != terminating {
 		c.Status = status
 	}
@@ -371,10 +940,57 @@ func (c *ClusterQueue) deleteWorkload(w *kueue.Workload) {
 }
 
 func (c *ClusterQueue) updateWorkloadUsage(wi *workload.Info, m int64) {
+	// The usage until now is accounted in the history before it changes.
+	c.updateHistoricalUsage(time.Now())
+	updateUsage(wi, c.UsedResources, m)
+	c.updateTierUsage(wi, m)
+	qKey := workload.QueueKey(wi.Obj)
+	if _, ok := c.admittedWorkloadsPerQueue[qKey]; ok {
+		c.admittedWorkloadsPerQueue[qKey] += int(m)
+	}
+}
+
+// updateHistoricalUsage accounts the current usage in the historical usage
+// until the given time.
+func (c *ClusterQueue) updateHistoricalUsage(now time.Time) {
+	if c.usageHalfLife == 0 {
+		return
+	}
+	c.historicalUsage = c.historicalUsageAt(now)
+	if now.After(c.historyUpdated) {
+		c.historyUpdated = now
+	}
+}
+
+// historicalUsageAt returns the historical usage at the given time, assuming
+// that the current usage didn't change since the last update.
+func (c *ClusterQueue) historicalUsageAt(now time.Time) map[corev1.ResourceName]float64 {
+	// The history starts empty.
+	decay := 1.0
+	if !c.historyUpdated.IsZero() && now.After(c.historyUpdated) {
+		decay = math.Exp2(-float64(now.Sub(c.historyUpdated)) / float64(c.usageHalfLife))
+	}
+	usage := make(map[corev1.ResourceName]float64, len(c.UsedResources))
+	for rName, v := range c.historicalUsage {
+		usage[rName] = v * decay
+	}
+	for rName, flavors := range c.UsedResources {
+		var used int64
+		for _, v := range flavors {
+			used += v
+		}
+		usage[rName] += float64(used) * (1 - decay)
+	}
+	return usage
+}
+
+// updateUsage adds the requests of the workload, multiplied by m, to the
+// usage of the flavors assigned to it.
+func updateUsage(wi *workload.Info, usedResources ResourceQuantities, m int64) {
 	for _, ps := range wi.TotalRequests {
 		for wlRes, wlResFlv := range ps.Flavors {
 			v, wlResExist := ps.Requests[wlRes]
-			cqResFlv, cqResExist := c.UsedResources[wlRes]
+			cqResFlv, cqResExist := usedResources[wlRes]
 			if cqResExist && wlResExist {
 				if _, cqFlvExist := cqResFlv[wlResFlv]; cqFlvExist {
 					cqResFlv[wlResFlv] += v * m
@@ -382,10 +998,6 @@ func (c *ClusterQueue) updateWorkloadUsage(wi *workload.Info, m int64) {
 			}
 		}
 	}
-	qKey := workload.QueueKey(wi.Obj)
-	if _, ok := c.admittedWorkloadsPerQueue[qKey]; ok {
-		c.admittedWorkloadsPerQueue[qKey] += int(m)
-	}
 }
 
 func (c *ClusterQueue) addLocalQueue(q *kueue.LocalQueue) error {
@@ -402,73 +1014,419 @@ func (c *ClusterQueue) addLocalQueue(q *kueue.LocalQueue) error {
 		}
 	}
 	c.admittedWorkloadsPerQueue[qKey] = workloads
+	c.updateLocalQueueLimits(q)
 	return nil
 }
 
 func (c *ClusterQueue) deleteLocalQueue(q *kueue.LocalQueue) {
 	qKey := queueKey(q)
 	delete(c.admittedWorkloadsPerQueue, qKey)
+	delete(c.LocalQueueLimits, qKey)
+	delete(c.LocalQueueLimitsPercent, qKey)
 }
 
-func (c *ClusterQueue) flavorInUse(flavor string) bool {
-	for _, r := range c.RequestableResources {
-		for _, f := range r.Flavors {
-			if flavor == f.Name {
-				return true
-			}
+func (c *ClusterQueue) updateLocalQueueLimits(q *kueue.LocalQueue) {
+	qKey := queueKey(q)
+	if len(q.Spec.LimitsPercent) == 0 {
+		delete(c.LocalQueueLimitsPercent, qKey)
+	} else {
+		if c.LocalQueueLimitsPercent == nil {
+			c.LocalQueueLimitsPercent = make(map[string]map[corev1.ResourceName]int32)
 		}
+		percents := make(map[corev1.ResourceName]int32, len(q.Spec.LimitsPercent))
+		for name, pct := range q.Spec.LimitsPercent {
+			percents[name] = pct
+		}
+		c.LocalQueueLimitsPercent[qKey] = percents
 	}
-	return false
+	if len(q.Spec.Limits) == 0 {
+		delete(c.LocalQueueLimits, qKey)
+		return
+	}
+	limits := make(workload.Requests, len(q.Spec.Limits))
+	for name, quant := range q.Spec.Limits {
+		limits[name] = workload.ResourceValue(name, quant)
+	}
+	if c.LocalQueueLimits == nil {
+		c.LocalQueueLimits = make(map[string]workload.Requests)
+	}
+	c.LocalQueueLimits[qKey] = limits
 }
 
-func (c *Cache) updateClusterQueues() sets.String {
-	cqs := sets.NewString()
-
-	for _, cq := range c.clusterQueues {
-		prevStatus := cq.Status
-		// We call update on all ClusterQueues irrespective of which CQ actually use this flavor
-		// because it is not expensive to do so, and is not worth tracking which ClusterQueues use
-		// which flavors.
-		cq.UpdateWithFlavors(c.resourceFlavors)
-		curStatus := cq.Status
-		if prevStatus == pending && curStatus == active {
-			cqs.Insert(cq.Name)
+// WorkloadsPendingChecksLimitReached returns whether the workloads whose
+// quota is reserved while they wait for their admission checks reached the
+// maxWorkloadsPendingChecks of the ClusterQueue.
+func (c *ClusterQueue) WorkloadsPendingChecksLimitReached() bool {
+	if c.MaxWorkloadsPendingChecks == nil {
+		return false
+	}
+	pending := 0
+	for _, w := range c.Workloads {
+		if len(workload.PendingAdmissionChecks(w.Obj)) > 0 {
+			pending++
 		}
 	}
-	return cqs
+	return pending >= int(*c.MaxWorkloadsPendingChecks)
 }
 
-func (c *Cache) AddOrUpdateResourceFlavor(rf *kueue.ResourceFlavor) sets.String {
-	c.Lock()
-	defer c.Unlock()
-	c.resourceFlavors[rf.Name] = rf
-	return c.updateClusterQueues()
+// LocalQueueLimitExceeded returns the first resource, in alphabetical order,
+// for which admitting the workload would exceed the limits of its LocalQueue.
+// It returns false if the workload fits in the limits.
+func (c *ClusterQueue) LocalQueueLimitExceeded(wi *workload.Info) (corev1.ResourceName, bool) {
+	qKey := workload.QueueKey(wi.Obj)
+	limits := c.localQueueLimits(qKey)
+	if len(limits) == 0 {
+		return "", false
+	}
+	used := make(workload.Requests, len(limits))
+	addRequests := func(w *workload.Info) {
+		for _, ps := range w.TotalRequests {
+			for name, v := range ps.Requests {
+				used[name] += v
+			}
+		}
+	}
+	for _, w := range c.Workloads {
+		if workload.QueueKey(w.Obj) == qKey {
+			addRequests(w)
+		}
+	}
+	addRequests(wi)
+
+	names := make([]string, 0, len(limits))
+	for name := range limits {
+		names = append(names, string(name))
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		rName := corev1.ResourceName(name)
+		if used[rName] > limits[rName] {
+			return rName, true
+		}
+	}
+	return "", false
 }
 
-func (c *Cache) DeleteResourceFlavor(rf *kueue.ResourceFlavor) sets.String {
-	c.Lock()
-	defer c.Unlock()
-	delete(c.resourceFlavors, rf.Name)
-	return c.updateClusterQueues()
+// CopiesFitting returns how many times the usage fits in the unused min quota
+// of the ClusterQueue, without borrowing, and in the quota left in its cohort.
+func (c *ClusterQueue) CopiesFitting(usage ResourceQuantities) int {
+	copies := int64(math.MaxInt32)
+	fitting := func(available, v int64) {
+		if n := available / v; n < copies {
+			copies = n
+		}
+	}
+	for rName, flavors := range usage {
+		for flavor, v := range flavors {
+			if v <= 0 {
+				continue
+			}
+			quota, ok := c.flavorMin(rName, flavor)
+			if !ok {
+				return 0
+			}
+			fitting(quota-c.UsedResources[rName][flavor], v)
+			if c.Cohort == nil {
+				continue
+			}
+			root := c.Cohort.Root()
+			fitting(root.RequestableResources[rName][flavor]-root.UsedResources[rName][flavor], v)
+			for cohort := c.Cohort; cohort != nil; cohort = cohort.Parent {
+				if max, ok := cohort.MaxResources[rName][flavor]; ok {
+					fitting(max-cohort.UsedResources[rName][flavor], v)
+				}
+			}
+		}
+	}
+	if copies < 0 {
+		return 0
+	}
+	return int(copies)
 }
 
-func (c *Cache) ClusterQueueActive(name string) bool {
-	return c.clusterQueueInStatus(name, active)
+// localQueueLimits returns the limits of the LocalQueue, with its limits as a
+// percentage of the quota of the ClusterQueue converted to quantities. When a
+// resource has both, the lowest applies.
+func (c *ClusterQueue) localQueueLimits(qKey string) workload.Requests {
+	percents := c.LocalQueueLimitsPercent[qKey]
+	if len(percents) == 0 {
+		return c.LocalQueueLimits[qKey]
+	}
+	limits := make(workload.Requests, len(c.LocalQueueLimits[qKey])+len(percents))
+	for name, v := range c.LocalQueueLimits[qKey] {
+		limits[name] = v
+	}
+	for name, pct := range percents {
+		var quota int64
+		if res := c.RequestableResources[name]; res != nil {
+			for _, f := range res.Flavors {
+				quota += f.Min
+			}
+		}
+		v := quota * int64(pct) / 100
+		if limit, ok := limits[name]; !ok || v < limit {
+			limits[name] = v
+		}
+	}
+	return limits
 }
 
-func (c *Cache) ClusterQueueTerminating(name string) bool {
-	return c.clusterQueueInStatus(name, terminating)
+// FallbackFlavors returns the flavors that the workload can't be admitted in
+// anymore, because it was evicted from them too many times, along with the
+// flavor it falls back to for each of them.
+func (c *ClusterQueue) FallbackFlavors(w *kueue.Workload) map[string]string {
+	var fallbacks map[string]string
+	for _, fb := range c.FlavorFallbacks {
+		if workload.ExceedsFlavorEvictions(w, string(fb.Flavor), fb.MaxEvictions, fb.Window.Duration) {
+			if fallbacks == nil {
+				fallbacks = make(map[string]string)
+			}
+			fallbacks[string(fb.Flavor)] = string(fb.FallbackFlavor)
+		}
+	}
+	return fallbacks
 }
 
-func (c *Cache) clusterQueueInStatus(name string, status metrics.ClusterQueueStatus) bool {
-	c.RLock()
-	defer c.RUnlock()
+// PriorityBand returns the name of the priority band of the workload, or an
+// empty string if the ClusterQueue doesn't split its quota in bands.
+func (c *ClusterQueue) PriorityBand(w *kueue.Workload) string {
+	return priority.Band(c.PriorityBands, w)
+}
 
-	cq, exists := c.clusterQueues[name]
-	if !exists {
-		return false
+// PriorityBandUsage returns the usage of the admitted workloads of the
+// priority band.
+func (c *ClusterQueue) PriorityBandUsage(band string) ResourceQuantities {
+	usage := make(ResourceQuantities, len(c.UsedResources))
+	for rName, flavors := range c.UsedResources {
+		usage[rName] = make(map[string]int64, len(flavors))
+		for flavor := range flavors {
+			usage[rName][flavor] = 0
+		}
 	}
-	return cq != nil && cq.Status == status
+	for _, wi := range c.Workloads {
+		if c.PriorityBand(wi.Obj) == band {
+			updateUsage(wi, usage, 1)
+		}
+	}
+	return usage
+}
+
+// PriorityBandQuota returns the part of the min quota of the flavor that is
+// guaranteed to the priority band, in proportion to its weight.
+func (c *ClusterQueue) PriorityBandQuota(band string, rName corev1.ResourceName, flavor string) int64 {
+	quota, _ := c.flavorMin(rName, flavor)
+	var weight, totalWeight int64
+	for _, b := range c.PriorityBands {
+		w := int64(b.Weight)
+		if w <= 0 {
+			w = 1
+		}
+		totalWeight += w
+		if b.Name == band {
+			weight = w
+		}
+	}
+	if totalWeight == 0 {
+		return quota
+	}
+	return proportionalShare(quota, weight, totalWeight)
+}
+
+// updateReservedTiers sets the reserved tiers and recomputes the usage of
+// each tier from the admitted workloads.
+func (c *ClusterQueue) updateReservedTiers(tiers []kueue.ReservedTier) {
+	c.ReservedTiers = tiers
+	c.TierUsage = nil
+	if len(tiers) == 0 {
+		return
+	}
+	c.TierUsage = make(map[string]ResourceQuantities, len(tiers))
+	for _, t := range tiers {
+		usage := make(ResourceQuantities, len(c.UsedResources))
+		for rName, flavors := range c.UsedResources {
+			usage[rName] = make(map[string]int64, len(flavors))
+			for flavor := range flavors {
+				usage[rName][flavor] = 0
+			}
+		}
+		c.TierUsage[t.Name] = usage
+	}
+	for _, wi := range c.Workloads {
+		c.updateTierUsage(wi, 1)
+	}
+}
+
+// reservedTier returns the name of the reserved tier of the workload, or an
+// empty string if its priority is below the minPriority of all the tiers.
+func (c *ClusterQueue) reservedTier(w *kueue.Workload) string {
+	p := priority.Priority(w)
+	var tier *kueue.ReservedTier
+	for i := range c.ReservedTiers {
+		t := &c.ReservedTiers[i]
+		if t.MinPriority <= p && (tier == nil || t.MinPriority > tier.MinPriority) {
+			tier = t
+		}
+	}
+	if tier == nil {
+		return ""
+	}
+	return tier.Name
+}
+
+// updateTierUsage adds the requests of the workload, multiplied by m, to the
+// usage of its reserved tier.
+func (c *ClusterQueue) updateTierUsage(wi *workload.Info, m int64) {
+	if usage, ok := c.TierUsage[c.reservedTier(wi.Obj)]; ok {
+		updateUsage(wi, usage, m)
+	}
+}
+
+// ReservedQuota returns the quota of the flavor that is reserved for the
+// tiers above the given priority and that the workloads of those tiers don't
+// use, so it's not available to a workload with that priority.
+func (c *ClusterQueue) ReservedQuota(rName corev1.ResourceName, flavor string, p int32) int64 {
+	if len(c.ReservedTiers) == 0 {
+		return 0
+	}
+	quota, _ := c.flavorMin(rName, flavor)
+	var reserved, used int64
+	for _, t := range c.ReservedTiers {
+		if t.MinPriority <= p {
+			continue
+		}
+		reserved += quota * int64(t.Percent) / 100
+		used += c.TierUsage[t.Name][rName][flavor]
+	}
+	if used >= reserved {
+		return 0
+	}
+	return reserved - used
+}
+
+// PriorityBandShares returns the usage of each priority band relative to its
+// guaranteed quota, in per mille, for the resource in which it's highest.
+func (c *ClusterQueue) PriorityBandShares() map[string]int64 {
+	if len(c.PriorityBands) == 0 {
+		return nil
+	}
+	shares := make(map[string]int64, len(c.PriorityBands))
+	for _, b := range c.PriorityBands {
+		var share int64
+		for rName, flavors := range c.PriorityBandUsage(b.Name) {
+			for flavor, used := range flavors {
+				if used == 0 {
+					continue
+				}
+				s := int64(math.MaxInt32)
+				if quota := c.PriorityBandQuota(b.Name, rName, flavor); quota > 0 {
+					s = proportionalShare(1000, used, quota)
+				}
+				if s > share {
+					share = s
+				}
+			}
+		}
+		shares[b.Name] = share
+	}
+	return shares
+}
+
+func (c *ClusterQueue) flavorInUse(flavor string) bool {
+	for _, r := range c.RequestableResources {
+		for _, f := range r.Flavors {
+			if flavor == f.Name {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func (c *Cache) updateClusterQueues() sets.String {
+	cqs := sets.NewString()
+
+	for _, cq := range c.clusterQueues {
+		prevStatus := cq.Status
+		// We call update on all ClusterQueues irrespective of which CQ actually use this flavor
+		// because it is not expensive to do so, and is not worth tracking which ClusterQueues use
+		// which flavors.
+		cq.UpdateWithFlavors(c.resourceFlavors)
+		curStatus := cq.Status
+		if prevStatus == pending && curStatus == active {
+			cqs.Insert(cq.Name)
+		}
+	}
+	shares := sharesOf(c.resourceFlavors)
+	if !equality.Semantic.DeepEqual(shares, c.shares) {
+		c.shares = shares
+		for _, cohort := range c.cohorts {
+			cohort.quota, cohort.limits = cohortQuotas(cohort.resources, shares)
+		}
+	}
+	return cqs
+}
+
+func (c *Cache) AddOrUpdateResourceFlavor(rf *kueue.ResourceFlavor) sets.String {
+	c.Lock()
+	defer c.Unlock()
+	c.resourceFlavors[rf.Name] = rf
+	return c.updateClusterQueues()
+}
+
+func (c *Cache) DeleteResourceFlavor(rf *kueue.ResourceFlavor) sets.String {
+	c.Lock()
+	defer c.Unlock()
+	delete(c.resourceFlavors, rf.Name)
+	return c.updateClusterQueues()
+}
+
+// AddOrUpdateAdmissionCheck records whether the AdmissionCheck is active, and
+// returns the ClusterQueues that became active as a result.
+func (c *Cache) AddOrUpdateAdmissionCheck(ac *kueue.AdmissionCheck) sets.String {
+	c.Lock()
+	defer c.Unlock()
+	c.admissionChecks[ac.Name] = apimeta.IsStatusConditionTrue(ac.Status.Conditions, kueue.AdmissionCheckActive)
+	return c.updateClusterQueueAdmissionChecks()
+}
+
+// DeleteAdmissionCheck forgets the AdmissionCheck. The ClusterQueues that use
+// it become inactive.
+func (c *Cache) DeleteAdmissionCheck(name string) sets.String {
+	c.Lock()
+	defer c.Unlock()
+	delete(c.admissionChecks, name)
+	return c.updateClusterQueueAdmissionChecks()
+}
+
+func (c *Cache) updateClusterQueueAdmissionChecks() sets.String {
+	cqs := sets.NewString()
+	for _, cq := range c.clusterQueues {
+		prevStatus := cq.Status
+		cq.UpdateWithAdmissionChecks(c.admissionChecks)
+		if prevStatus == pending && cq.Status == active {
+			cqs.Insert(cq.Name)
+		}
+	}
+	return cqs
+}
+
+func (c *Cache) ClusterQueueActive(name string) bool {
+	return c.clusterQueueInStatus(name, active)
+}
+
+func (c *Cache) ClusterQueueTerminating(name string) bool {
+	return c.clusterQueueInStatus(name, terminating)
+}
+
+func (c *Cache) clusterQueueInStatus(name string, status metrics.ClusterQueueStatus) bool {
+	c.RLock()
+	defer c.RUnlock()
+
+	cq, exists := c.clusterQueues[name]
+	if !exists {
+		return false
+	}
+	return cq != nil && cq.Status == status
 }
 
 func (c *Cache) TerminateClusterQueue(name string) {
@@ -493,6 +1451,22 @@ func (c *Cache) ClusterQueueEmpty(name string) bool {
 	return len(cq.Workloads) == 0
 }
 
+// AdmittedWorkloads returns the workloads admitted by the provided
+// clusterQueue, including the assumed ones.
+func (c *Cache) AdmittedWorkloads(name string) []*kueue.Workload {
+	c.RLock()
+	defer c.RUnlock()
+	cq, exists := c.clusterQueues[name]
+	if !exists {
+		return nil
+	}
+	workloads := make([]*kueue.Workload, 0, len(cq.Workloads))
+	for _, wl := range cq.Workloads {
+		workloads = append(workloads, wl.Obj)
+	}
+	return workloads
+}
+
 func (c *Cache) AddClusterQueue(ctx context.Context, cq *kueue.ClusterQueue) error {
 	c.Lock()
 	defer c.Unlock()
@@ -510,32 +1484,106 @@ func (c *Cache) AddClusterQueue(ctx context.Context, cq *kueue.ClusterQueue) err
 	// On controller restart, an add ClusterQueue event may come after
 	// add queue and workload, so here we explicitly list and add existing queues
 	// and workloads.
+	queues, workloads, err := c.listClusterQueueObjects(ctx, cq.Name)
+	if err != nil {
+		return err
+	}
+	c.addClusterQueueObjects(cqImpl, queues, workloads)
+	now := time.Now()
+	running := cqImpl.runningWorkloads()
+	for _, t := range cqImpl.budgets {
+		t.Restore(cq.Status.Budgets, running, now)
+	}
+	return nil
+}
+
+// ResyncClusterQueue rebuilds the admitted workloads and the usage of the
+// ClusterQueue from the LocalQueues and workloads in the API server, to
+// recover from accounting drift. The workloads assumed by the scheduler are
+// kept, as their admission might not be in the API server yet.
+func (c *Cache) ResyncClusterQueue(ctx context.Context, cq *kueue.ClusterQueue) error {
+	c.Lock()
+	defer c.Unlock()
+	cqImpl, ok := c.clusterQueues[cq.Name]
+	if !ok {
+		return errCqNotFound
+	}
+	queues, workloads, err := c.listClusterQueueObjects(ctx, cq.Name)
+	if err != nil {
+		return err
+	}
+
+	var assumed []*kueue.Workload
+	for k, wi := range cqImpl.Workloads {
+		if _, ok := c.assumedWorkloads[k]; ok {
+			assumed = append(assumed, wi.Obj)
+		}
+	}
+	cqImpl.Workloads = make(map[string]*workload.Info)
+	cqImpl.WorkloadsNotReady = sets.NewString()
+	cqImpl.admittedWorkloadsPerQueue = make(map[string]int)
+	cqImpl.LocalQueueLimits = nil
+	cqImpl.LocalQueueLimitsPercent = nil
+	for _, usedFlavors := range cqImpl.UsedResources {
+		for flavor := range usedFlavors {
+			usedFlavors[flavor] = 0
+		}
+	}
+
+	c.addClusterQueueObjects(cqImpl, queues, workloads)
+	for _, w := range assumed {
+		if _, exist := cqImpl.Workloads[workload.Key(w)]; !exist {
+			if err := cqImpl.addWorkload(w); err != nil {
+				return err
+			}
+		}
+	}
+	reportAdmittedActiveWorkloads(cq.Name, len(cqImpl.Workloads))
+	if c.podsReadyTracking {
+		c.podsReadyCond.Broadcast()
+	}
+	return nil
+}
+
+// listClusterQueueObjects lists the LocalQueues pointing to the ClusterQueue
+// and the workloads admitted by it.
+func (c *Cache) listClusterQueueObjects(ctx context.Context, cqName string) ([]kueue.LocalQueue, []kueue.Workload, error) {
 	var queues kueue.LocalQueueList
-	if err := c.client.List(ctx, &queues, client.MatchingFields{queueClusterQueueKey: cq.Name}); err != nil {
-		return fmt.Errorf("listing queues that match the clusterQueue: %w", err)
+	if err := c.client.List(ctx, &queues, client.MatchingFields{queueClusterQueueKey: cqName}); err != nil {
+		return nil, nil, fmt.Errorf("listing queues that match the clusterQueue: %w", err)
 	}
-	for _, q := range queues.Items {
+	var workloads kueue.WorkloadList
+	if err := c.client.List(ctx, &workloads, client.MatchingFields{workloadClusterQueueKey: cqName}); err != nil {
+		return nil, nil, fmt.Errorf("listing workloads that match the queue: %w", err)
+	}
+	return queues.Items, workloads.Items, nil
+}
+
+func (c *Cache) addClusterQueueObjects(cqImpl *ClusterQueue, queues []kueue.LocalQueue, workloads []kueue.Workload) {
+	for _, q := range queues {
 		// Checking ClusterQueue name again because the field index is not available in tests.
-		if string(q.Spec.ClusterQueue) == cq.Name {
+		if localQueueClusterQueues(&q).Has(cqImpl.Name) {
 			cqImpl.admittedWorkloadsPerQueue[queueKey(&q)] = 0
+			cqImpl.updateLocalQueueLimits(&q)
 		}
 	}
-	var workloads kueue.WorkloadList
-	if err := c.client.List(ctx, &workloads, client.MatchingFields{workloadClusterQueueKey: cq.Name}); err != nil {
-		return fmt.Errorf("listing workloads that match the queue: %w", err)
-	}
-	for i, w := range workloads.Items {
+	for i := range workloads {
+		// The workloads with a deprecated admission keep their quota while
+		// they're converted.
+		w := workload.WithDeprecatedAdmission(&workloads[i])
 		// Checking ClusterQueue name again because the field index is not available in tests.
-		if w.Spec.Admission == nil || string(w.Spec.Admission.ClusterQueue) != cq.Name {
+		if w.Status.Admission == nil || string(w.Status.Admission.ClusterQueue) != cqImpl.Name {
+			continue
+		}
+		// Workloads being deleted don't hold quota.
+		if !w.DeletionTimestamp.IsZero() {
 			continue
 		}
-		c.addOrUpdateWorkload(&workloads.Items[i])
+		c.addOrUpdateWorkload(w)
 		if _, ok := cqImpl.admittedWorkloadsPerQueue[w.Spec.QueueName]; ok {
 			cqImpl.admittedWorkloadsPerQueue[w.Spec.QueueName]++
 		}
 	}
-
-	return nil
 }
 
 func (c *Cache) UpdateClusterQueue(cq *kueue.ClusterQueue) error {
@@ -545,7 +1593,7 @@ func (c *Cache) UpdateClusterQueue(cq *kueue.ClusterQueue) error {
 	if !ok {
 		return errCqNotFound
 	}
-	if err := cqImpl.update(cq, c.resourceFlavors); err != nil {
+	if err := cqImpl.update(cq, c.resourceFlavors, c.admissionChecks); err != nil {
 		return err
 	}
 
@@ -573,43 +1621,126 @@ func (c *Cache) DeleteClusterQueue(cq *kueue.ClusterQueue) {
 	metrics.ClearCacheMetrics(cq.Name)
 }
 
+// AddOrUpdateCohort sets the parent and the quotas of the cohort from the
+// Cohort object. It returns whether they changed.
+func (c *Cache) AddOrUpdateCohort(obj *kueue.Cohort) bool {
+	c.Lock()
+	defer c.Unlock()
+	cohort := c.cohorts[obj.Name]
+	if cohort == nil {
+		cohort = newCohort(obj.Name, 0)
+		c.cohorts[obj.Name] = cohort
+	}
+	quota, limits := cohortQuotas(obj.Spec.Resources, c.shares)
+	changed := !cohort.hasObject || cohort.parentName != obj.Spec.Parent ||
+		!equality.Semantic.DeepEqual(cohort.quota, quota) || !equality.Semantic.DeepEqual(cohort.limits, limits)
+	cohort.hasObject = true
+	cohort.parentName = obj.Spec.Parent
+	cohort.resources = obj.Spec.Resources
+	cohort.quota = quota
+	cohort.limits = limits
+	return changed
+}
+
+// DeleteCohort removes the parent and the quotas of the cohort, which is kept
+// while ClusterQueues belong to it. It returns whether the cohort had them.
+func (c *Cache) DeleteCohort(name string) bool {
+	c.Lock()
+	defer c.Unlock()
+	cohort := c.cohorts[name]
+	if cohort == nil || !cohort.hasObject {
+		return false
+	}
+	if len(cohort.members) == 0 {
+		delete(c.cohorts, name)
+		return true
+	}
+	cohort.hasObject = false
+	cohort.parentName = ""
+	cohort.resources = nil
+	cohort.quota = nil
+	cohort.limits = nil
+	return true
+}
+
+func cohortQuotas(resources []kueue.CohortResource, shares resourceShares) (ResourceQuantities, ResourceQuantities) {
+	var quota, limits ResourceQuantities
+	for _, r := range resources {
+		for _, f := range r.Flavors {
+			if quota == nil {
+				quota = make(ResourceQuantities)
+			}
+			replicas := shares.replicas(string(f.Name), r.Name)
+			addQuantity(quota, r.Name, string(f.Name), workload.ResourceValue(r.Name, f.Quota.Min)*replicas)
+			if f.Quota.Max != nil {
+				if limits == nil {
+					limits = make(ResourceQuantities)
+				}
+				addQuantity(limits, r.Name, string(f.Name), workload.ResourceValue(r.Name, *f.Quota.Max)*replicas)
+			}
+		}
+	}
+	return quota, limits
+}
+
 func (c *Cache) AddLocalQueue(q *kueue.LocalQueue) error {
 	c.Lock()
 	defer c.Unlock()
-	cq, ok := c.clusterQueues[string(q.Spec.ClusterQueue)]
-	if !ok {
-		return nil
+	for name := range localQueueClusterQueues(q) {
+		if cq, ok := c.clusterQueues[name]; ok {
+			if err := cq.addLocalQueue(q); err != nil {
+				return err
+			}
+		}
 	}
-	return cq.addLocalQueue(q)
+	return nil
 }
 
 func (c *Cache) DeleteLocalQueue(q *kueue.LocalQueue) {
 	c.Lock()
 	defer c.Unlock()
-	cq, ok := c.clusterQueues[string(q.Spec.ClusterQueue)]
-	if !ok {
-		return
+	for name := range localQueueClusterQueues(q) {
+		if cq, ok := c.clusterQueues[name]; ok {
+			cq.deleteLocalQueue(q)
+		}
 	}
-	cq.deleteLocalQueue(q)
 }
 
 func (c *Cache) UpdateLocalQueue(oldQ, newQ *kueue.LocalQueue) error {
-	if oldQ.Spec.ClusterQueue == newQ.Spec.ClusterQueue {
-		return nil
-	}
 	c.Lock()
 	defer c.Unlock()
-	cq, ok := c.clusterQueues[string(oldQ.Spec.ClusterQueue)]
-	if ok {
-		cq.deleteLocalQueue(oldQ)
+	oldCQs := localQueueClusterQueues(oldQ)
+	newCQs := localQueueClusterQueues(newQ)
+	for name := range oldCQs.Difference(newCQs) {
+		if cq, ok := c.clusterQueues[name]; ok {
+			cq.deleteLocalQueue(oldQ)
+		}
 	}
-	cq, ok = c.clusterQueues[string(newQ.Spec.ClusterQueue)]
-	if ok {
-		return cq.addLocalQueue(newQ)
+	for name := range newCQs {
+		cq, ok := c.clusterQueues[name]
+		if !ok {
+			continue
+		}
+		if oldCQs.Has(name) {
+			cq.updateLocalQueueLimits(newQ)
+		} else if err := cq.addLocalQueue(newQ); err != nil {
+			return err
+		}
 	}
 	return nil
 }
 
+// localQueueClusterQueues returns the names of the ClusterQueues in which the
+// workloads of the LocalQueue are queued, including the ones of its priority
+// class routing.
+func localQueueClusterQueues(q *kueue.LocalQueue) sets.String {
+	names := sets.NewString(string(q.Spec.ClusterQueue))
+	for _, r := range q.Spec.PriorityClassRouting {
+		names.Insert(string(r.ClusterQueue))
+	}
+	return names
+}
+
 func (c *Cache) AddOrUpdateWorkload(w *kueue.Workload) bool {
 	c.Lock()
 	defer c.Unlock()
@@ -617,16 +1748,17 @@ func (c *Cache) AddOrUpdateWorkload(w *kueue.Workload) bool {
 }
 
 func (c *Cache) addOrUpdateWorkload(w *kueue.Workload) bool {
-	if w.Spec.Admission == nil {
+	if w.Status.Admission == nil {
 		return false
 	}
 
-	clusterQueue, ok := c.clusterQueues[string(w.Spec.Admission.ClusterQueue)]
+	clusterQueue, ok := c.clusterQueues[string(w.Status.Admission.ClusterQueue)]
 	if !ok {
 		return false
 	}
 
 	c.cleanupAssumedState(w)
+	delete(c.reservations, workload.Key(w))
 
 	if _, exist := clusterQueue.Workloads[workload.Key(w)]; exist {
 		clusterQueue.deleteWorkload(w)
@@ -641,8 +1773,8 @@ func (c *Cache) addOrUpdateWorkload(w *kueue.Workload) bool {
 func (c *Cache) UpdateWorkload(oldWl, newWl *kueue.Workload) error {
 	c.Lock()
 	defer c.Unlock()
-	if oldWl.Spec.Admission != nil {
-		cq, ok := c.clusterQueues[string(oldWl.Spec.Admission.ClusterQueue)]
+	if oldWl.Status.Admission != nil {
+		cq, ok := c.clusterQueues[string(oldWl.Status.Admission.ClusterQueue)]
 		if !ok {
 			return fmt.Errorf("old ClusterQueue doesn't exist")
 		}
@@ -650,10 +1782,10 @@ func (c *Cache) UpdateWorkload(oldWl, newWl *kueue.Workload) error {
 	}
 	c.cleanupAssumedState(oldWl)
 
-	if newWl.Spec.Admission == nil {
+	if newWl.Status.Admission == nil {
 		return nil
 	}
-	cq, ok := c.clusterQueues[string(newWl.Spec.Admission.ClusterQueue)]
+	cq, ok := c.clusterQueues[string(newWl.Status.Admission.ClusterQueue)]
 	if !ok {
 		return fmt.Errorf("new ClusterQueue doesn't exist")
 	}
@@ -666,11 +1798,11 @@ func (c *Cache) UpdateWorkload(oldWl, newWl *kueue.Workload) error {
 func (c *Cache) DeleteWorkload(w *kueue.Workload) error {
 	c.Lock()
 	defer c.Unlock()
-	if w.Spec.Admission == nil {
+	if w.Status.Admission == nil {
 		return errWorkloadNotAdmitted
 	}
 
-	cq, ok := c.clusterQueues[string(w.Spec.Admission.ClusterQueue)]
+	cq, ok := c.clusterQueues[string(w.Status.Admission.ClusterQueue)]
 	if !ok {
 		return errCqNotFound
 	}
@@ -687,8 +1819,28 @@ func (c *Cache) DeleteWorkload(w *kueue.Workload) error {
 func (c *Cache) AssumeWorkload(w *kueue.Workload) error {
 	c.Lock()
 	defer c.Unlock()
+	return c.assumeWorkload(w)
+}
+
+// AssumeWorkloads assumes the admission of all the workloads, such as the
+// members of an admission group, or of none of them if any fails.
+func (c *Cache) AssumeWorkloads(wls []*kueue.Workload) error {
+	c.Lock()
+	defer c.Unlock()
+	for i, w := range wls {
+		if err := c.assumeWorkload(w); err != nil {
+			for _, assumed := range wls[:i] {
+				c.cleanupAssumedState(assumed)
+				c.clusterQueues[string(assumed.Status.Admission.ClusterQueue)].deleteWorkload(assumed)
+			}
+			return fmt.Errorf("assuming workload %s: %w", workload.Key(w), err)
+		}
+	}
+	return nil
+}
 
-	if w.Spec.Admission == nil {
+func (c *Cache) assumeWorkload(w *kueue.Workload) error {
+	if w.Status.Admission == nil {
 		return errWorkloadNotAdmitted
 	}
 
@@ -698,7 +1850,7 @@ func (c *Cache) AssumeWorkload(w *kueue.Workload) error {
 		return fmt.Errorf("the workload is already assumed to ClusterQueue %q", assumedCq)
 	}
 
-	cq, ok := c.clusterQueues[string(w.Spec.Admission.ClusterQueue)]
+	cq, ok := c.clusterQueues[string(w.Status.Admission.ClusterQueue)]
 	if !ok {
 		return errCqNotFound
 	}
@@ -706,10 +1858,43 @@ func (c *Cache) AssumeWorkload(w *kueue.Workload) error {
 	if err := cq.addWorkload(w); err != nil {
 		return err
 	}
-	c.assumedWorkloads[k] = string(w.Spec.Admission.ClusterQueue)
+	c.assumedWorkloads[k] = string(w.Status.Admission.ClusterQueue)
+	delete(c.reservations, k)
+	return nil
+}
+
+// ReserveQuota reserves the quota of the flavors in the admission of the
+// workload, which is waiting for the preemption of other workloads, so that
+// the quota they release isn't taken by other workloads. The reservation is
+// dropped when the workload is admitted, or with CancelQuotaReservation.
+func (c *Cache) ReserveQuota(w *kueue.Workload) error {
+	c.Lock()
+	defer c.Unlock()
+
+	if w.Status.Admission == nil {
+		return errWorkloadNotAdmitted
+	}
+	if _, ok := c.clusterQueues[string(w.Status.Admission.ClusterQueue)]; !ok {
+		return errCqNotFound
+	}
+	c.reservations[workload.Key(w)] = workload.NewInfo(w)
 	return nil
 }
 
+// CancelQuotaReservation drops the quota reserved for the workload. It returns
+// true if the workload had a reservation.
+func (c *Cache) CancelQuotaReservation(w *kueue.Workload) bool {
+	c.Lock()
+	defer c.Unlock()
+
+	k := workload.Key(w)
+	if _, ok := c.reservations[k]; !ok {
+		return false
+	}
+	delete(c.reservations, k)
+	return true
+}
+
 func (c *Cache) ForgetWorkload(w *kueue.Workload) error {
 	c.Lock()
 	defer c.Unlock()
@@ -719,11 +1904,11 @@ func (c *Cache) ForgetWorkload(w *kueue.Workload) error {
 	}
 	c.cleanupAssumedState(w)
 
-	if w.Spec.Admission == nil {
+	if w.Status.Admission == nil {
 		return errWorkloadNotAdmitted
 	}
 
-	cq, ok := c.clusterQueues[string(w.Spec.Admission.ClusterQueue)]
+	cq, ok := c.clusterQueues[string(w.Status.Admission.ClusterQueue)]
 	if !ok {
 		return errCqNotFound
 	}
@@ -734,6 +1919,132 @@ func (c *Cache) ForgetWorkload(w *kueue.Workload) error {
 	return nil
 }
 
+// MoveWorkload moves the usage of the admitted workload oldWl to the
+// ClusterQueue in the admission of newWl, if it fits in its quota, without
+// evicting it. The admission of newWl is assumed until its update is observed
+// or it's forgotten.
+func (c *Cache) MoveWorkload(oldWl, newWl *kueue.Workload) error {
+	c.Lock()
+	defer c.Unlock()
+	if oldWl.Status.Admission == nil || newWl.Status.Admission == nil {
+		return errWorkloadNotAdmitted
+	}
+	k := workload.Key(oldWl)
+	if assumedCq, assumed := c.assumedWorkloads[k]; assumed {
+		return fmt.Errorf("the workload is already assumed to ClusterQueue %q", assumedCq)
+	}
+	from, ok := c.clusterQueues[string(oldWl.Status.Admission.ClusterQueue)]
+	if !ok {
+		return errCqNotFound
+	}
+	to, ok := c.clusterQueues[string(newWl.Status.Admission.ClusterQueue)]
+	if !ok {
+		return errCqNotFound
+	}
+	if to != from {
+		if !to.Active() {
+			return fmt.Errorf("ClusterQueue %s is inactive", to.Name)
+		}
+		if err := c.fitsMovedWorkload(to, workload.NewInfo(oldWl), from); err != nil {
+			return err
+		}
+	}
+	from.deleteWorkload(oldWl)
+	if err := to.addWorkload(newWl); err != nil {
+		// Restore the usage in the original ClusterQueue.
+		_ = from.addWorkload(oldWl)
+		return err
+	}
+	c.assumedWorkloads[k] = to.Name
+	return nil
+}
+
+// fitsMovedWorkload returns an error if the usage of the workload, admitted by
+// the ClusterQueue from, doesn't fit in the quota of the ClusterQueue to, with
+// the same flavors. Beyond its min quota, the ClusterQueue can borrow from its
+// tree of cohorts, including the quota defined by the cohorts themselves.
+func (c *Cache) fitsMovedWorkload(to *ClusterQueue, wi *workload.Info, from *ClusterQueue) error {
+	usage := make(ResourceQuantities)
+	for _, ps := range wi.TotalRequests {
+		for rName, flavor := range ps.Flavors {
+			if usage[rName] == nil {
+				usage[rName] = make(map[string]int64)
+			}
+			usage[rName][flavor] += ps.Requests[rName]
+		}
+	}
+	var parents map[string]string
+	if to.Cohort != nil {
+		parents = c.cohortParents()
+	}
+	for rName, flavors := range usage {
+		for flavor, v := range flavors {
+			min, ok := to.flavorMin(rName, flavor)
+			if !ok {
+				return fmt.Errorf("flavor %s of resource %s not found in ClusterQueue %s", flavor, rName, to.Name)
+			}
+			used := to.UsedResources[rName][flavor] + v
+			if used <= min {
+				continue
+			}
+			if to.Cohort == nil {
+				return fmt.Errorf("%s doesn't fit in the quota of flavor %s in ClusterQueue %s", rName, flavor, to.Name)
+			}
+			if max := to.flavorMax(rName, flavor); max != nil && used > *max {
+				return fmt.Errorf("%s exceeds the max quota of flavor %s in ClusterQueue %s", rName, flavor, to.Name)
+			}
+			for _, name := range cohortAndAncestors(parents, to.Cohort.Name) {
+				cohort := c.cohorts[name]
+				if cohort == nil {
+					continue
+				}
+				max, ok := cohort.limits[rName][flavor]
+				if !ok {
+					continue
+				}
+				_, cohortUsed := c.cohortTreeQuota(parents, name, rName, flavor)
+				// The usage of the workload already counts in the cohort.
+				if from.Cohort == nil || !isCohortDescendant(parents, from.Cohort.Name, name) {
+					cohortUsed += v
+				}
+				if cohortUsed > max {
+					return fmt.Errorf("%s exceeds the max quota of flavor %s in cohort %s", rName, flavor, name)
+				}
+			}
+			root := rootCohort(parents, to.Cohort.Name)
+			treeMin, treeUsed := c.cohortTreeQuota(parents, root, rName, flavor)
+			// The usage of the workload already counts in its tree of cohorts.
+			if from.Cohort == nil || rootCohort(parents, from.Cohort.Name) != root {
+				treeUsed += v
+			}
+			if treeUsed > treeMin {
+				return fmt.Errorf("%s doesn't fit in the quota of flavor %s in the cohort of ClusterQueue %s", rName, flavor, to.Name)
+			}
+		}
+	}
+	return nil
+}
+
+// cohortTreeQuota returns the min quota and the usage of the flavor of the
+// resource in the cohort and its descendants. The quota includes the one
+// defined by the cohorts themselves, which any member can borrow.
+func (c *Cache) cohortTreeQuota(parents map[string]string, name string, rName corev1.ResourceName, flavor string) (int64, int64) {
+	var quota, used int64
+	for _, cohort := range c.cohorts {
+		if !isCohortDescendant(parents, cohort.Name, name) {
+			continue
+		}
+		quota += cohort.quota[rName][flavor]
+		for member := range cohort.members {
+			if memberMin, ok := member.flavorMin(rName, flavor); ok {
+				quota += memberMin
+			}
+			used += member.UsedResources[rName][flavor]
+		}
+	}
+	return quota, used
+}
+
 // Usage reports the used resources and number of workloads admitted by the ClusterQueue.
 func (c *Cache) Usage(cqObj *kueue.ClusterQueue) (kueue.UsedResources, int, error) {
 	c.RLock()
@@ -751,11 +2062,11 @@ func (c *Cache) Usage(cqObj *kueue.ClusterQueue) (kueue.UsedResources, int, erro
 		for _, flavor := range requestable.Flavors {
 			used := usedRes[flavor.Name]
 			fUsage := kueue.Usage{
-				Total: pointer.Quantity(workload.ResourceQuantity(rName, used)),
+				Total: pointer.Quantity(c.shares.quotaQuantity(flavor.Name, rName, used)),
 			}
 			borrowing := used - flavor.Min
 			if borrowing > 0 {
-				fUsage.Borrowed = pointer.Quantity(workload.ResourceQuantity(rName, borrowing))
+				fUsage.Borrowed = pointer.Quantity(c.shares.quotaQuantity(flavor.Name, rName, borrowing))
 			}
 			rUsage[flavor.Name] = fUsage
 		}
@@ -764,13 +2075,372 @@ func (c *Cache) Usage(cqObj *kueue.ClusterQueue) (kueue.UsedResources, int, erro
 	return usage, len(cq.Workloads), nil
 }
 
+// Policies returns the effective scheduling policies of the ClusterQueue.
+func (c *Cache) Policies(cqObj *kueue.ClusterQueue) (*kueue.ClusterQueuePolicies, error) {
+	c.RLock()
+	defer c.RUnlock()
+
+	cq := c.clusterQueues[cqObj.Name]
+	if cq == nil {
+		return nil, errCqNotFound
+	}
+	policies := cq.Policies
+	return &policies, nil
+}
+
+// QuotaSharing returns how the min quota of the ClusterQueue is currently
+// lent to, and borrowed from, the other members of its cohort. The quantity
+// borrowed by a member is attributed to the members with unused min quota in
+// proportion to it. It returns nil if the ClusterQueue isn't sharing quota.
+func (c *Cache) QuotaSharing(cqObj *kueue.ClusterQueue) (*kueue.QuotaSharing, error) {
+	c.RLock()
+	defer c.RUnlock()
+
+	cq := c.clusterQueues[cqObj.Name]
+	if cq == nil {
+		return nil, errCqNotFound
+	}
+	if cq.Cohort == nil {
+		return nil, nil
+	}
+
+	lentTo := make(map[string]ResourceQuantities)
+	borrowedFrom := make(map[string]ResourceQuantities)
+	for rName, res := range cq.RequestableResources {
+		for _, flavor := range res.Flavors {
+			var totalIdle int64
+			idle := make(map[string]int64)
+			borrowing := make(map[string]int64)
+			for member := range cq.Cohort.members {
+				min, ok := member.flavorMin(rName, flavor.Name)
+				if !ok {
+					continue
+				}
+				used := member.UsedResources[rName][flavor.Name]
+				if used < min {
+					idle[member.Name] = min - used
+					totalIdle += min - used
+				} else if used > min {
+					borrowing[member.Name] = used - min
+				}
+			}
+			if totalIdle == 0 {
+				continue
+			}
+			if cqIdle := idle[cq.Name]; cqIdle > 0 {
+				for name, borrowed := range borrowing {
+					addSharedQuantity(lentTo, name, rName, flavor.Name, proportionalShare(borrowed, cqIdle, totalIdle))
+				}
+			}
+			if borrowed := borrowing[cq.Name]; borrowed > 0 {
+				for name, memberIdle := range idle {
+					addSharedQuantity(borrowedFrom, name, rName, flavor.Name, proportionalShare(borrowed, memberIdle, totalIdle))
+				}
+			}
+		}
+	}
+	if len(lentTo) == 0 && len(borrowedFrom) == 0 {
+		return nil, nil
+	}
+	return &kueue.QuotaSharing{
+		LentTo:       sharedQuotaList(lentTo, c.shares),
+		BorrowedFrom: sharedQuotaList(borrowedFrom, c.shares),
+	}, nil
+}
+
+func (c *ClusterQueue) flavorMin(rName corev1.ResourceName, flavor string) (int64, bool) {
+	res := c.RequestableResources[rName]
+	if res == nil {
+		return 0, false
+	}
+	for _, f := range res.Flavors {
+		if f.Name == flavor {
+			return f.Min, true
+		}
+	}
+	return 0, false
+}
+
+func (c *ClusterQueue) flavorMax(rName corev1.ResourceName, flavor string) *int64 {
+	for _, f := range c.RequestableResources[rName].Flavors {
+		if f.Name == flavor {
+			return f.Max
+		}
+	}
+	return nil
+}
+
+// proportionalShare returns the part of the borrowed quantity that
+// corresponds to idle out of totalIdle.
+func proportionalShare(borrowed, idle, totalIdle int64) int64 {
+	// Use floating point to avoid overflowing with big quantities, such as
+	// memory in bytes.
+	return int64(float64(borrowed) * float64(idle) / float64(totalIdle))
+}
+
+func addSharedQuantity(shared map[string]ResourceQuantities, cqName string, rName corev1.ResourceName, flavor string, v int64) {
+	if v <= 0 {
+		return
+	}
+	cqShared := shared[cqName]
+	if cqShared == nil {
+		cqShared = make(ResourceQuantities)
+		shared[cqName] = cqShared
+	}
+	if cqShared[rName] == nil {
+		cqShared[rName] = make(map[string]int64)
+	}
+	cqShared[rName][flavor] += v
+}
+
+func sharedQuotaList(shared map[string]ResourceQuantities, shares resourceShares) []kueue.SharedQuota {
+	if len(shared) == 0 {
+		return nil
+	}
+	list := make([]kueue.SharedQuota, 0, len(shared))
+	for cqName, quantities := range shared {
+		resources := make(map[corev1.ResourceName]map[string]resource.Quantity, len(quantities))
+		for rName, flavors := range quantities {
+			resources[rName] = make(map[string]resource.Quantity, len(flavors))
+			for flavor, v := range flavors {
+				resources[rName][flavor] = shares.quotaQuantity(flavor, rName, v)
+			}
+		}
+		list = append(list, kueue.SharedQuota{ClusterQueue: cqName, Resources: resources})
+	}
+	sort.Slice(list, func(i, j int) bool {
+		return list[i].ClusterQueue < list[j].ClusterQueue
+	})
+	return list
+}
+
+// ClusterQueuesInCohortOf returns the names of the other ClusterQueues in the
+// cohort of the given ClusterQueue.
+func (c *Cache) ClusterQueuesInCohortOf(cqName string) []string {
+	c.RLock()
+	defer c.RUnlock()
+
+	cq := c.clusterQueues[cqName]
+	if cq == nil || cq.Cohort == nil {
+		return nil
+	}
+	var cqs []string
+	for member := range cq.Cohort.members {
+		if member.Name != cqName {
+			cqs = append(cqs, member.Name)
+		}
+	}
+	return cqs
+}
+
+// CohortsOf returns the names of the cohort of the given ClusterQueue and of
+// its ancestors, or nil if the ClusterQueue doesn't belong to a cohort.
+func (c *Cache) CohortsOf(cqName string) []string {
+	c.RLock()
+	defer c.RUnlock()
+
+	cq := c.clusterQueues[cqName]
+	if cq == nil || cq.Cohort == nil {
+		return nil
+	}
+	return cohortAndAncestors(c.cohortParents(), cq.Cohort.Name)
+}
+
+// CohortAndAncestors returns the name of the given cohort followed by the
+// names of its ancestors.
+func (c *Cache) CohortAndAncestors(name string) []string {
+	c.RLock()
+	defer c.RUnlock()
+
+	return cohortAndAncestors(c.cohortParents(), name)
+}
+
+// ClusterQueuesInCohortTree returns the names of the ClusterQueues that belong
+// to the tree of cohorts of the given cohort.
+func (c *Cache) ClusterQueuesInCohortTree(name string) []string {
+	c.RLock()
+	defer c.RUnlock()
+
+	parents := c.cohortParents()
+	root := rootCohort(parents, name)
+	var cqs []string
+	for _, cohort := range c.cohorts {
+		if rootCohort(parents, cohort.Name) != root {
+			continue
+		}
+		for member := range cohort.members {
+			cqs = append(cqs, member.Name)
+		}
+	}
+	return cqs
+}
+
+// CohortStatus returns the status of the cohort with the given name, as
+// observed from the ClusterQueues in the cohort and its descendants. The
+// status is empty if the cohort has neither ClusterQueues nor quotas.
+func (c *Cache) CohortStatus(name string) kueue.CohortStatus {
+	c.RLock()
+	defer c.RUnlock()
+
+	parents := c.cohortParents()
+	var members []string
+	quota := make(ResourceQuantities)
+	usage := make(ResourceQuantities)
+	for _, cohort := range c.cohorts {
+		if !isCohortDescendant(parents, cohort.Name, name) {
+			continue
+		}
+		for rName, flavors := range cohort.quota {
+			for flavor, v := range flavors {
+				addQuantity(quota, rName, flavor, v)
+			}
+		}
+		for member := range cohort.members {
+			members = append(members, member.Name)
+			if member.Active() {
+				for rName, res := range member.RequestableResources {
+					for _, flavor := range res.Flavors {
+						addQuantity(quota, rName, flavor.Name, flavor.Min)
+					}
+				}
+			}
+			for rName, flavors := range member.UsedResources {
+				for flavor, v := range flavors {
+					if v != 0 {
+						addQuantity(usage, rName, flavor, v)
+					}
+				}
+			}
+		}
+	}
+	sort.Strings(members)
+	return kueue.CohortStatus{
+		ClusterQueues: members,
+		Quota:         toQuantityMap(quota, c.shares),
+		Usage:         toQuantityMap(usage, c.shares),
+	}
+}
+
+// Reasons of the problems found in the tree of cohorts of a ClusterQueue.
+const (
+	ReasonCohortCycle  = "CohortCycle"
+	ReasonOrphanParent = "OrphanParent"
+)
+
+// CohortProblem returns the reason and a description of the first problem
+// found in the tree of cohorts of the ClusterQueue, from its cohort up to the
+// root, or empty strings if there is none: a parent ignored because it would
+// create a cycle, or a parent that has no Cohort object, no ClusterQueues and
+// no other child cohorts, which is likely a typo.
+func (c *Cache) CohortProblem(cqName string) (string, string) {
+	c.RLock()
+	defer c.RUnlock()
+
+	cq := c.clusterQueues[cqName]
+	if cq == nil || cq.Cohort == nil {
+		return "", ""
+	}
+	parents := c.cohortParents()
+	visited := sets.NewString()
+	for name := cq.Cohort.Name; name != "" && !visited.Has(name); name = parents[name] {
+		visited.Insert(name)
+		cohort := c.cohorts[name]
+		if cohort == nil || cohort.parentName == "" {
+			continue
+		}
+		if parents[name] != cohort.parentName {
+			return ReasonCohortCycle, fmt.Sprintf("The parent %s of cohort %s is ignored because it would create a cycle", cohort.parentName, name)
+		}
+		if c.cohorts[cohort.parentName] == nil && c.childCohorts(cohort.parentName) == 1 {
+			return ReasonOrphanParent, fmt.Sprintf("The parent %s of cohort %s has no Cohort object, no ClusterQueues and no other child cohorts", cohort.parentName, name)
+		}
+	}
+	return "", ""
+}
+
+func (c *Cache) childCohorts(name string) int {
+	n := 0
+	for _, cohort := range c.cohorts {
+		if cohort.parentName == name {
+			n++
+		}
+	}
+	return n
+}
+
+func addQuantity(q ResourceQuantities, rName corev1.ResourceName, flavor string, v int64) {
+	if q[rName] == nil {
+		q[rName] = make(map[string]int64)
+	}
+	q[rName][flavor] += v
+}
+
+func toQuantityMap(q ResourceQuantities, shares resourceShares) map[corev1.ResourceName]map[string]resource.Quantity {
+	if len(q) == 0 {
+		return nil
+	}
+	out := make(map[corev1.ResourceName]map[string]resource.Quantity, len(q))
+	for rName, flavors := range q {
+		out[rName] = make(map[string]resource.Quantity, len(flavors))
+		for flavor, v := range flavors {
+			out[rName][flavor] = shares.quotaQuantity(flavor, rName, v)
+		}
+	}
+	return out
+}
+
+// resourceShares holds, for each flavor, the number of pods that share one
+// unit of each of the resources that the flavor declares as shared. The
+// quotas of these resources are accounted in shares, so that each pod
+// requesting one unit of the resource consumes one share.
+type resourceShares map[string]map[corev1.ResourceName]int64
+
+func sharesOf(flavors map[string]*kueue.ResourceFlavor) resourceShares {
+	var shares resourceShares
+	for name, rf := range flavors {
+		for _, r := range rf.SharedResources {
+			if r.Replicas <= 1 {
+				continue
+			}
+			if shares == nil {
+				shares = make(resourceShares)
+			}
+			if shares[name] == nil {
+				shares[name] = make(map[corev1.ResourceName]int64, len(rf.SharedResources))
+			}
+			shares[name][r.Name] = int64(r.Replicas)
+		}
+	}
+	return shares
+}
+
+// replicas returns the number of shares of one unit of the resource in the
+// flavor, which is 1 if the flavor doesn't share the resource.
+func (s resourceShares) replicas(flavor string, rName corev1.ResourceName) int64 {
+	if replicas, ok := s[flavor][rName]; ok {
+		return replicas
+	}
+	return 1
+}
+
+// quotaQuantity converts an amount of shares of the resource in the flavor to
+// the units of the quotas.
+func (s resourceShares) quotaQuantity(flavor string, rName corev1.ResourceName, v int64) resource.Quantity {
+	q := workload.ResourceQuantity(rName, v)
+	replicas := s.replicas(flavor, rName)
+	if replicas == 1 {
+		return q
+	}
+	return *resource.NewMilliQuantity(q.MilliValue()/replicas, q.Format)
+}
+
 func (c *Cache) cleanupAssumedState(w *kueue.Workload) {
 	k := workload.Key(w)
 	assumedCQName, assumed := c.assumedWorkloads[k]
 	if assumed {
 		// If the workload's assigned ClusterQueue is different from the assumed
 		// one, then we should also cleanup the assumed one.
-		if w.Spec.Admission != nil && assumedCQName != string(w.Spec.Admission.ClusterQueue) {
+		if w.Status.Admission != nil && assumedCQName != string(w.Status.Admission.ClusterQueue) {
 			if assumedCQ, exist := c.clusterQueues[assumedCQName]; exist {
 				assumedCQ.deleteWorkload(w)
 			}
@@ -797,12 +2467,59 @@ func (c *Cache) deleteClusterQueueFromCohort(cq *ClusterQueue) {
 		return
 	}
 	delete(cq.Cohort.members, cq)
-	if len(cq.Cohort.members) == 0 {
+	if len(cq.Cohort.members) == 0 && !cq.Cohort.hasObject {
 		delete(c.cohorts, cq.Cohort.Name)
 	}
 	cq.Cohort = nil
 }
 
+// cohortParents returns the parent of each cohort that has one, ignoring the
+// parents that would create a cycle. The cohorts are visited by name, so that
+// the same parent is ignored every time.
+func (c *Cache) cohortParents() map[string]string {
+	names := make([]string, 0, len(c.cohorts))
+	for name, cohort := range c.cohorts {
+		if cohort.parentName != "" {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	parents := make(map[string]string, len(names))
+	for _, name := range names {
+		parent := c.cohorts[name].parentName
+		if !isCohortDescendant(parents, parent, name) {
+			parents[name] = parent
+		}
+	}
+	return parents
+}
+
+// isCohortDescendant returns whether the cohort is the ancestor cohort or one
+// of its descendants.
+func isCohortDescendant(parents map[string]string, cohort, ancestor string) bool {
+	for ; cohort != ""; cohort = parents[cohort] {
+		if cohort == ancestor {
+			return true
+		}
+	}
+	return false
+}
+
+func cohortAndAncestors(parents map[string]string, name string) []string {
+	var names []string
+	for ; name != ""; name = parents[name] {
+		names = append(names, name)
+	}
+	return names
+}
+
+func rootCohort(parents map[string]string, name string) string {
+	for parents[name] != "" {
+		name = parents[name]
+	}
+	return name
+}
+
 func (c *Cache) ClusterQueuesUsingFlavor(flavor string) []string {
 	c.RLock()
 	defer c.RUnlock()
@@ -816,6 +2533,97 @@ func (c *Cache) ClusterQueuesUsingFlavor(flavor string) []string {
 	return cqs
 }
 
+// ClusterQueuesUsingAdmissionCheck returns the names of the ClusterQueues
+// that list the AdmissionCheck, for all their workloads or for the workloads
+// assigned some of their flavors.
+func (c *Cache) ClusterQueuesUsingAdmissionCheck(name string) []string {
+	c.RLock()
+	defer c.RUnlock()
+	var cqs []string
+	for _, cq := range c.clusterQueues {
+		if cq.usesAdmissionCheck(name) {
+			cqs = append(cqs, cq.Name)
+		}
+	}
+	return cqs
+}
+
+func (c *ClusterQueue) usesAdmissionCheck(name string) bool {
+	for _, check := range c.AdmissionChecks {
+		if check == name {
+			return true
+		}
+	}
+	for _, checks := range c.FlavorAdmissionChecks {
+		if checks.Has(name) {
+			return true
+		}
+	}
+	return false
+}
+
+// ClusterQueueAdmissionChecksInactive returns whether any of the
+// AdmissionChecks of the ClusterQueue doesn't exist or isn't active.
+func (c *Cache) ClusterQueueAdmissionChecksInactive(name string) bool {
+	c.RLock()
+	defer c.RUnlock()
+	cq, ok := c.clusterQueues[name]
+	return ok && cq.admissionChecksInactive
+}
+
+// FlavorDeletionPolicy returns what happens when a ResourceFlavor is deleted
+// while it's in use.
+func (c *Cache) FlavorDeletionPolicy() config.ResourceFlavorDeletionPolicy {
+	return c.flavorDeletionPolicy
+}
+
+// AdmittedWorkloadsUsingFlavor returns the admitted workloads that are
+// assigned the flavor for any of their resources.
+func (c *Cache) AdmittedWorkloadsUsingFlavor(flavor string) []*kueue.Workload {
+	c.RLock()
+	defer c.RUnlock()
+	var workloads []*kueue.Workload
+	for _, cq := range c.clusterQueues {
+		for _, wi := range cq.Workloads {
+			if usesFlavor(wi, flavor) {
+				workloads = append(workloads, wi.Obj)
+			}
+		}
+	}
+	return workloads
+}
+
+func usesFlavor(wi *workload.Info, flavor string) bool {
+	for _, ps := range wi.TotalRequests {
+		for _, f := range ps.Flavors {
+			if f == flavor {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// TerminatingFlavors returns the flavors of the ClusterQueue whose
+// ResourceFlavor is being deleted, sorted by name.
+func (c *Cache) TerminatingFlavors(name string) []string {
+	c.RLock()
+	defer c.RUnlock()
+	cq, ok := c.clusterQueues[name]
+	if !ok {
+		return nil
+	}
+	flavors := sets.NewString()
+	for _, r := range cq.RequestableResources {
+		for _, f := range r.Flavors {
+			if rf, ok := c.resourceFlavors[f.Name]; ok && !rf.DeletionTimestamp.IsZero() {
+				flavors.Insert(f.Name)
+			}
+		}
+	}
+	return flavors.List()
+}
+
 func (c *Cache) MatchingClusterQueues(nsLabels map[string]string) sets.String {
 	c.RLock()
 	defer c.RUnlock()
@@ -830,18 +2638,22 @@ func (c *Cache) MatchingClusterQueues(nsLabels map[string]string) sets.String {
 	return cqs
 }
 
-func resourcesByName(in []kueue.Resource) map[corev1.ResourceName]*Resource {
+// resourcesByName returns the quotas of the resources, in which the quotas of
+// the shared resources count shares of a unit of the resource.
+func resourcesByName(in []kueue.Resource, shares resourceShares) map[corev1.ResourceName]*Resource {
 	out := make(map[corev1.ResourceName]*Resource, len(in))
 	for _, r := range in {
 		flavors := make([]FlavorLimits, len(r.Flavors))
 		for i := range flavors {
 			f := &r.Flavors[i]
+			replicas := shares.replicas(string(f.Name), r.Name)
 			fLimits := FlavorLimits{
 				Name: string(f.Name),
-				Min:  workload.ResourceValue(r.Name, f.Quota.Min),
+				Min:  workload.ResourceValue(r.Name, f.Quota.Min) * replicas,
+				Hold: f.Hold,
 			}
 			if f.Quota.Max != nil {
-				fLimits.Max = pointer.Int64(workload.ResourceValue(r.Name, *f.Quota.Max))
+				fLimits.Max = pointer.Int64(workload.ResourceValue(r.Name, *f.Quota.Max) * replicas)
 			}
 			flavors[i] = fLimits
 
@@ -855,11 +2667,11 @@ func resourcesByName(in []kueue.Resource) map[corev1.ResourceName]*Resource {
 
 func SetupIndexes(indexer client.FieldIndexer) error {
 	return indexer.IndexField(context.Background(), &kueue.Workload{}, workloadClusterQueueKey, func(o client.Object) []string {
-		wl := o.(*kueue.Workload)
-		if wl.Spec.Admission == nil {
+		wl := workload.WithDeprecatedAdmission(o.(*kueue.Workload))
+		if wl.Status.Admission == nil {
 			return nil
 		}
-		return []string{string(wl.Spec.Admission.ClusterQueue)}
+		return []string{string(wl.Status.Admission.ClusterQueue)}
 	})
 }
 
@@ -873,5 +2685,5 @@ func queueKey(q *kueue.LocalQueue) string {
 }
 
 func reportAdmittedActiveWorkloads(cqName string, val int) {
-	metrics.AdmittedActiveWorkloads.WithLabelValues(cqName).Set(float64(val))
+	metrics.ReportAdmittedActiveWorkloads(cqName, val)
 }
@@ -20,6 +20,7 @@ import (
 	"context"
 	"fmt"
 	"testing"
+	"time"
 
 	"github.com/google/go-cmp/cmp"
 	"github.com/google/go-cmp/cmp/cmpopts"
@@ -34,6 +35,7 @@ import (
 	"sigs.k8s.io/controller-runtime/pkg/client/fake"
 
 	kueue "sigs.k8s.io/kueue/apis/kueue/v1alpha2"
+	"sigs.k8s.io/kueue/pkg/accounting"
 	"sigs.k8s.io/kueue/pkg/metrics"
 	"sigs.k8s.io/kueue/pkg/util/pointer"
 	utiltesting "sigs.k8s.io/kueue/pkg/util/testing"
@@ -142,6 +144,7 @@ func TestCacheClusterQueueOperations(t *testing.T) {
 					LabelKeys:         map[corev1.ResourceName]sets.String{corev1.ResourceCPU: sets.NewString("cpuType")},
 					UsedResources:     ResourceQuantities{corev1.ResourceCPU: {"default": 0}},
 					Status:            active,
+					FairWeight:        1000,
 				},
 				"b": {
 					Name: "b",
@@ -152,6 +155,7 @@ func TestCacheClusterQueueOperations(t *testing.T) {
 					UsedResources:     ResourceQuantities{corev1.ResourceCPU: {"default": 0}},
 					LabelKeys:         map[corev1.ResourceName]sets.String{corev1.ResourceCPU: sets.NewString("cpuType")},
 					Status:            active,
+					FairWeight:        1000,
 				},
 				"c": {
 					Name:                 "c",
@@ -159,6 +163,7 @@ func TestCacheClusterQueueOperations(t *testing.T) {
 					NamespaceSelector:    labels.Nothing(),
 					UsedResources:        ResourceQuantities{},
 					Status:               active,
+					FairWeight:           1000,
 				},
 				"d": {
 					Name:                 "d",
@@ -166,6 +171,7 @@ func TestCacheClusterQueueOperations(t *testing.T) {
 					NamespaceSelector:    labels.Nothing(),
 					UsedResources:        ResourceQuantities{},
 					Status:               active,
+					FairWeight:           1000,
 				},
 				"e": {
 					Name: "e",
@@ -176,6 +182,7 @@ func TestCacheClusterQueueOperations(t *testing.T) {
 					UsedResources:     ResourceQuantities{corev1.ResourceCPU: {"nonexistent-flavor": 0}},
 					LabelKeys:         nil,
 					Status:            pending,
+					FairWeight:        1000,
 				},
 			},
 			wantCohorts: map[string]sets.String{
@@ -208,6 +215,7 @@ func TestCacheClusterQueueOperations(t *testing.T) {
 					LabelKeys:         map[corev1.ResourceName]sets.String{corev1.ResourceCPU: sets.NewString("cpuType")},
 					UsedResources:     ResourceQuantities{corev1.ResourceCPU: {"default": 0}},
 					Status:            active,
+					FairWeight:        1000,
 				},
 				"b": {
 					Name: "b",
@@ -218,6 +226,7 @@ func TestCacheClusterQueueOperations(t *testing.T) {
 					UsedResources:     ResourceQuantities{corev1.ResourceCPU: {"default": 0}},
 					LabelKeys:         map[corev1.ResourceName]sets.String{corev1.ResourceCPU: sets.NewString("cpuType")},
 					Status:            active,
+					FairWeight:        1000,
 				},
 				"c": {
 					Name:                 "c",
@@ -225,6 +234,7 @@ func TestCacheClusterQueueOperations(t *testing.T) {
 					NamespaceSelector:    labels.Nothing(),
 					UsedResources:        ResourceQuantities{},
 					Status:               active,
+					FairWeight:           1000,
 				},
 				"d": {
 					Name:                 "d",
@@ -232,6 +242,7 @@ func TestCacheClusterQueueOperations(t *testing.T) {
 					NamespaceSelector:    labels.Nothing(),
 					UsedResources:        ResourceQuantities{},
 					Status:               active,
+					FairWeight:           1000,
 				},
 				"e": {
 					Name: "e",
@@ -242,6 +253,7 @@ func TestCacheClusterQueueOperations(t *testing.T) {
 					UsedResources:     ResourceQuantities{corev1.ResourceCPU: {"nonexistent-flavor": 0}},
 					LabelKeys:         nil,
 					Status:            pending,
+					FairWeight:        1000,
 				},
 			},
 			wantCohorts: map[string]sets.String{
@@ -323,6 +335,7 @@ func TestCacheClusterQueueOperations(t *testing.T) {
 					LabelKeys:         map[corev1.ResourceName]sets.String{corev1.ResourceCPU: sets.NewString("cpuType", "region")},
 					UsedResources:     ResourceQuantities{corev1.ResourceCPU: {"default": 0}},
 					Status:            active,
+					FairWeight:        1000,
 				},
 				"b": {
 					Name:                 "b",
@@ -330,6 +343,7 @@ func TestCacheClusterQueueOperations(t *testing.T) {
 					NamespaceSelector:    labels.Everything(),
 					UsedResources:        ResourceQuantities{},
 					Status:               active,
+					FairWeight:           1000,
 				},
 				"c": {
 					Name:                 "c",
@@ -337,6 +351,7 @@ func TestCacheClusterQueueOperations(t *testing.T) {
 					NamespaceSelector:    labels.Nothing(),
 					UsedResources:        ResourceQuantities{},
 					Status:               active,
+					FairWeight:           1000,
 				},
 				"d": {
 					Name:                 "d",
@@ -344,6 +359,7 @@ func TestCacheClusterQueueOperations(t *testing.T) {
 					NamespaceSelector:    labels.Nothing(),
 					UsedResources:        ResourceQuantities{},
 					Status:               active,
+					FairWeight:           1000,
 				},
 				"e": {
 					Name: "e",
@@ -354,6 +370,7 @@ func TestCacheClusterQueueOperations(t *testing.T) {
 					UsedResources:     ResourceQuantities{corev1.ResourceCPU: {"default": 0}},
 					LabelKeys:         map[corev1.ResourceName]sets.String{corev1.ResourceCPU: sets.NewString("cpuType", "region")},
 					Status:            active,
+					FairWeight:        1000,
 				},
 			},
 			wantCohorts: map[string]sets.String{
@@ -383,6 +400,7 @@ func TestCacheClusterQueueOperations(t *testing.T) {
 					UsedResources:     ResourceQuantities{corev1.ResourceCPU: {"default": 0}},
 					LabelKeys:         map[corev1.ResourceName]sets.String{corev1.ResourceCPU: sets.NewString("cpuType")},
 					Status:            active,
+					FairWeight:        1000,
 				},
 				"c": {
 					Name:                 "c",
@@ -390,6 +408,7 @@ func TestCacheClusterQueueOperations(t *testing.T) {
 					NamespaceSelector:    labels.Nothing(),
 					UsedResources:        ResourceQuantities{},
 					Status:               active,
+					FairWeight:           1000,
 				},
 				"e": {
 					Name: "e",
@@ -400,6 +419,7 @@ func TestCacheClusterQueueOperations(t *testing.T) {
 					UsedResources:     ResourceQuantities{corev1.ResourceCPU: {"nonexistent-flavor": 0}},
 					LabelKeys:         nil,
 					Status:            pending,
+					FairWeight:        1000,
 				},
 			},
 			wantCohorts: map[string]sets.String{
@@ -425,6 +445,7 @@ func TestCacheClusterQueueOperations(t *testing.T) {
 					LabelKeys:         map[corev1.ResourceName]sets.String{corev1.ResourceCPU: sets.NewString("cpuType")},
 					UsedResources:     ResourceQuantities{corev1.ResourceCPU: {"default": 0}},
 					Status:            active,
+					FairWeight:        1000,
 				},
 				"b": {
 					Name: "b",
@@ -435,6 +456,7 @@ func TestCacheClusterQueueOperations(t *testing.T) {
 					UsedResources:     ResourceQuantities{corev1.ResourceCPU: {"default": 0}},
 					LabelKeys:         map[corev1.ResourceName]sets.String{corev1.ResourceCPU: sets.NewString("cpuType")},
 					Status:            active,
+					FairWeight:        1000,
 				},
 				"c": {
 					Name:                 "c",
@@ -442,6 +464,7 @@ func TestCacheClusterQueueOperations(t *testing.T) {
 					NamespaceSelector:    labels.Nothing(),
 					UsedResources:        ResourceQuantities{},
 					Status:               active,
+					FairWeight:           1000,
 				},
 				"d": {
 					Name:                 "d",
@@ -449,6 +472,7 @@ func TestCacheClusterQueueOperations(t *testing.T) {
 					NamespaceSelector:    labels.Nothing(),
 					UsedResources:        ResourceQuantities{},
 					Status:               active,
+					FairWeight:           1000,
 				},
 				"e": {
 					Name: "e",
@@ -459,6 +483,7 @@ func TestCacheClusterQueueOperations(t *testing.T) {
 					UsedResources:     ResourceQuantities{corev1.ResourceCPU: {"nonexistent-flavor": 0}},
 					LabelKeys:         nil,
 					Status:            active,
+					FairWeight:        1000,
 				},
 			},
 			wantCohorts: map[string]sets.String{
@@ -544,7 +569,114 @@ func TestCacheClusterQueueOperations(t *testing.T) {
 							"gamma": 0,
 						},
 					},
-					Status: pending,
+					Status:     pending,
+					FairWeight: 1000,
+				},
+			},
+		},
+		{
+			name: "Add ClusterQueue with a held flavor",
+			operation: func(cache *Cache) {
+				err := cache.AddClusterQueue(context.Background(),
+					&kueue.ClusterQueue{
+						ObjectMeta: metav1.ObjectMeta{
+							Name: "foo",
+						},
+						Spec: kueue.ClusterQueueSpec{
+							Resources: []kueue.Resource{
+								{
+									Name: "cpu",
+									Flavors: []kueue.Flavor{
+										{
+											Name: "foo",
+											Quota: kueue.Quota{
+												Min: resource.MustParse("10"),
+											},
+											Hold: true,
+										},
+										{
+											Name: "bar",
+											Quota: kueue.Quota{
+												Min: resource.MustParse("10"),
+											},
+										},
+									},
+								},
+							},
+						},
+					})
+				if err != nil {
+					t.Fatalf("Adding ClusterQueue: %v", err)
+				}
+			},
+			wantClusterQueues: map[string]*ClusterQueue{
+				"foo": {
+					Name:              "foo",
+					NamespaceSelector: labels.Nothing(),
+					RequestableResources: map[corev1.ResourceName]*Resource{
+						"cpu": {
+							Flavors: []FlavorLimits{
+								{Name: "foo", Min: 10000, Hold: true},
+								{Name: "bar", Min: 10000},
+							},
+						},
+					},
+					UsedResources: ResourceQuantities{
+						"cpu": map[string]int64{
+							"foo": 0,
+							"bar": 0,
+						},
+					},
+					Status:     pending,
+					FairWeight: 1000,
+				},
+			},
+		},
+		{
+			name: "Add ClusterQueue with resource groups",
+			operation: func(cache *Cache) {
+				err := cache.AddClusterQueue(context.Background(),
+					utiltesting.MakeClusterQueue("foo").
+						ResourceGroup(utiltesting.MakeResourceGroup("cpu", "memory").
+							Flavor("foo", "cpu", "10", "memory", "10Gi").
+							Flavor("bar", "cpu", "20", "memory", "20Gi").Obj()).
+						Obj())
+				if err != nil {
+					t.Fatalf("Adding ClusterQueue: %v", err)
+				}
+			},
+			wantClusterQueues: map[string]*ClusterQueue{
+				"foo": {
+					Name:              "foo",
+					NamespaceSelector: labels.Everything(),
+					RequestableResources: map[corev1.ResourceName]*Resource{
+						"cpu": {
+							Flavors: []FlavorLimits{
+								{Name: "foo", Min: 10000},
+								{Name: "bar", Min: 20000},
+							},
+							CodependentResources: sets.NewString("cpu", "memory"),
+						},
+						"memory": {
+							Flavors: []FlavorLimits{
+								{Name: "foo", Min: 10 * 1024 * 1024 * 1024},
+								{Name: "bar", Min: 20 * 1024 * 1024 * 1024},
+							},
+							CodependentResources: sets.NewString("cpu", "memory"),
+						},
+					},
+					UsedResources: ResourceQuantities{
+						"cpu": map[string]int64{
+							"bar": 0,
+							"foo": 0,
+						},
+						"memory": map[string]int64{
+							"bar": 0,
+							"foo": 0,
+						},
+					},
+					Status:     pending,
+					FairWeight: 1000,
 				},
 			},
 		},
@@ -554,7 +686,7 @@ func TestCacheClusterQueueOperations(t *testing.T) {
 			cache := New(fake.NewClientBuilder().WithScheme(scheme).Build())
 			tc.operation(cache)
 			if diff := cmp.Diff(tc.wantClusterQueues, cache.clusterQueues,
-				cmpopts.IgnoreFields(ClusterQueue{}, "Cohort", "Workloads"), cmpopts.IgnoreUnexported(ClusterQueue{})); diff != "" {
+				cmpopts.IgnoreFields(ClusterQueue{}, "Cohort", "Workloads", "Policies"), cmpopts.IgnoreUnexported(ClusterQueue{})); diff != "" {
 				t.Errorf("Unexpected clusterQueues (-want,+got):\n%s", diff)
 			}
 
@@ -947,6 +1079,61 @@ func TestCacheWorkloadOperations(t *testing.T) {
 			},
 			wantAssumedWorkloads: map[string]string{},
 		},
+		{
+			name: "assume several",
+			operation: func(cache *Cache) error {
+				return cache.AssumeWorkloads([]*kueue.Workload{
+					utiltesting.MakeWorkload("d", "").PodSets(podSets).Admit(&kueue.Admission{
+						ClusterQueue:  "one",
+						PodSetFlavors: podSetFlavors,
+					}).Obj(),
+					utiltesting.MakeWorkload("e", "").PodSets(podSets).Admit(&kueue.Admission{
+						ClusterQueue:  "one",
+						PodSetFlavors: podSetFlavors,
+					}).Obj(),
+				})
+			},
+			wantResults: map[string]result{
+				"one": {
+					Workloads:     sets.NewString("a", "b", "d", "e"),
+					UsedResources: ResourceQuantities{"cpu": {"on-demand": 30, "spot": 45}},
+				},
+				"two": {
+					Workloads:     sets.NewString("c"),
+					UsedResources: ResourceQuantities{"cpu": {"on-demand": 0, "spot": 0}},
+				},
+			},
+			wantAssumedWorkloads: map[string]string{
+				"/d": "one",
+				"/e": "one",
+			},
+		},
+		{
+			name: "assume several error reverts the assumed workloads",
+			operation: func(cache *Cache) error {
+				return cache.AssumeWorkloads([]*kueue.Workload{
+					utiltesting.MakeWorkload("d", "").PodSets(podSets).Admit(&kueue.Admission{
+						ClusterQueue:  "one",
+						PodSetFlavors: podSetFlavors,
+					}).Obj(),
+					utiltesting.MakeWorkload("e", "").PodSets(podSets).Admit(&kueue.Admission{
+						ClusterQueue: "three",
+					}).Obj(),
+				})
+			},
+			wantError: "assuming workload /e: cluster queue not found",
+			wantResults: map[string]result{
+				"one": {
+					Workloads:     sets.NewString("a", "b"),
+					UsedResources: ResourceQuantities{"cpu": {"on-demand": 10, "spot": 15}},
+				},
+				"two": {
+					Workloads:     sets.NewString("c"),
+					UsedResources: ResourceQuantities{"cpu": {"on-demand": 0, "spot": 0}},
+				},
+			},
+			wantAssumedWorkloads: map[string]string{},
+		},
 		{
 			name: "forget",
 			operation: func(cache *Cache) error {
@@ -1468,128 +1655,1170 @@ func TestCacheQueueOperations(t *testing.T) {
 	}
 }
 
-func TestClusterQueuesUsingFlavor(t *testing.T) {
-	x86Rf := utiltesting.MakeResourceFlavor("x86").Obj()
-	aarch64Rf := utiltesting.MakeResourceFlavor("aarch64").Obj()
-	x86Flavor := utiltesting.MakeFlavor(x86Rf.Name, "5").Obj()
-	aarch64Flavor := utiltesting.MakeFlavor(aarch64Rf.Name, "3").Obj()
-	fooCq := utiltesting.MakeClusterQueue("fooCq").
-		Resource(utiltesting.MakeResource("cpu").Flavor(x86Flavor).Obj()).
-		Obj()
-	barCq := utiltesting.MakeClusterQueue("barCq").Obj()
-	fizzCq := utiltesting.MakeClusterQueue("fizzCq").
-		Resource(utiltesting.MakeResource("cpu").
-			Flavor(x86Flavor).
-			Flavor(aarch64Flavor).Obj()).
+func TestCacheRoutedLocalQueue(t *testing.T) {
+	scheme := utiltesting.MustGetScheme(t)
+	cl := fake.NewClientBuilder().WithScheme(scheme).Build()
+	cache := New(cl)
+	ctx := context.Background()
+	for _, cq := range []*kueue.ClusterQueue{
+		utiltesting.MakeClusterQueue("on-demand").Obj(),
+		utiltesting.MakeClusterQueue("spot").Obj(),
+	} {
+		if err := cache.AddClusterQueue(ctx, cq); err != nil {
+			t.Fatalf("Failed adding clusterQueue: %v", err)
+		}
+	}
+	q := utiltesting.MakeLocalQueue("q", "ns").
+		ClusterQueue("on-demand").
+		RoutePriorityClass("low", "spot").
+		Limit(corev1.ResourceCPU, "2").
 		Obj()
+	if err := cache.AddLocalQueue(q); err != nil {
+		t.Fatalf("Failed adding queue: %v", err)
+	}
+	for _, wl := range []*kueue.Workload{
+		utiltesting.MakeWorkload("a", "ns").Queue("q").Admit(utiltesting.MakeAdmission("on-demand").Obj()).Obj(),
+		utiltesting.MakeWorkload("b", "ns").Queue("q").PriorityClass("low").Admit(utiltesting.MakeAdmission("spot").Obj()).Obj(),
+		utiltesting.MakeWorkload("c", "ns").Queue("q").PriorityClass("low").Admit(utiltesting.MakeAdmission("spot").Obj()).Obj(),
+	} {
+		cache.AddOrUpdateWorkload(wl)
+	}
+	if got := cache.AdmittedWorkloadsInLocalQueue(q); got != 3 {
+		t.Errorf("Got %d admitted workloads in the LocalQueue, want 3", got)
+	}
+	for _, name := range []string{"on-demand", "spot"} {
+		if _, ok := cache.clusterQueues[name].LocalQueueLimits["ns/q"]; !ok {
+			t.Errorf("ClusterQueue %s doesn't have the limits of the LocalQueue", name)
+		}
+	}
 
-	tests := []struct {
-		name                       string
-		clusterQueues              []*kueue.ClusterQueue
-		wantInUseClusterQueueNames []string
+	cache.DeleteLocalQueue(q)
+	if got := cache.AdmittedWorkloadsInLocalQueue(q); got != 0 {
+		t.Errorf("Got %d admitted workloads after deleting the LocalQueue, want 0", got)
+	}
+}
+
+func TestLocalQueueLimitExceeded(t *testing.T) {
+	cq := utiltesting.MakeClusterQueue("foo").
+		Resource(utiltesting.MakeResource(corev1.ResourceCPU).
+			Flavor(utiltesting.MakeFlavor("default", "10").Obj()).Obj()).
+		Obj()
+	queues := []*kueue.LocalQueue{
+		utiltesting.MakeLocalQueue("alpha", "ns").ClusterQueue("foo").
+			Limit(corev1.ResourceCPU, "4").
+			Limit(corev1.ResourceMemory, "4Gi").
+			Obj(),
+		utiltesting.MakeLocalQueue("beta", "ns").ClusterQueue("foo").Obj(),
+		utiltesting.MakeLocalQueue("gamma", "ns").ClusterQueue("foo").
+			Limit(corev1.ResourceCPU, "5").
+			LimitPercent(corev1.ResourceCPU, 20).
+			Obj(),
+	}
+	admitted := []*kueue.Workload{
+		utiltesting.MakeWorkload("a", "ns").Queue("alpha").
+			Request(corev1.ResourceCPU, "2").
+			Request(corev1.ResourceMemory, "1Gi").
+			Admit(utiltesting.MakeAdmission("foo").Obj()).Obj(),
+		utiltesting.MakeWorkload("b", "ns").Queue("beta").
+			Request(corev1.ResourceCPU, "10").
+			Admit(utiltesting.MakeAdmission("foo").Obj()).Obj(),
+	}
+	cases := map[string]struct {
+		workload     *kueue.Workload
+		updateLimits corev1.ResourceList
+		wantResource corev1.ResourceName
+		wantExceeded bool
 	}{
-		{
-			name: "single clusterQueue with flavor in use",
-			clusterQueues: []*kueue.ClusterQueue{
-				fooCq,
-			},
-			wantInUseClusterQueueNames: []string{fooCq.Name},
-		},
-		{
-			name: "single clusterQueue with no flavor",
-			clusterQueues: []*kueue.ClusterQueue{
-				barCq,
-			},
-		},
-		{
-			name: "multiple clusterQueues with flavor in use",
-			clusterQueues: []*kueue.ClusterQueue{
-				fooCq,
-				barCq,
-				fizzCq,
+		"fits": {
+			workload: utiltesting.MakeWorkload("c", "ns").Queue("alpha").
+				Request(corev1.ResourceCPU, "2").Obj(),
+		},
+		"exceeds cpu": {
+			workload: utiltesting.MakeWorkload("c", "ns").Queue("alpha").
+				Request(corev1.ResourceCPU, "3").Obj(),
+			wantResource: corev1.ResourceCPU,
+			wantExceeded: true,
+		},
+		"exceeds cpu and memory": {
+			workload: utiltesting.MakeWorkload("c", "ns").Queue("alpha").
+				Request(corev1.ResourceCPU, "3").
+				Request(corev1.ResourceMemory, "4Gi").Obj(),
+			wantResource: corev1.ResourceCPU,
+			wantExceeded: true,
+		},
+		"queue without limits": {
+			workload: utiltesting.MakeWorkload("c", "ns").Queue("beta").
+				Request(corev1.ResourceCPU, "100").Obj(),
+		},
+		"exceeds the limits as a percentage of the quota": {
+			workload: utiltesting.MakeWorkload("c", "ns").Queue("gamma").
+				Request(corev1.ResourceCPU, "3").Obj(),
+			wantResource: corev1.ResourceCPU,
+			wantExceeded: true,
+		},
+		"fits in the limits as a percentage of the quota": {
+			workload: utiltesting.MakeWorkload("c", "ns").Queue("gamma").
+				Request(corev1.ResourceCPU, "2").Obj(),
+		},
+		"fits after the limits are increased": {
+			workload: utiltesting.MakeWorkload("c", "ns").Queue("alpha").
+				Request(corev1.ResourceCPU, "3").Obj(),
+			updateLimits: corev1.ResourceList{
+				corev1.ResourceCPU: resource.MustParse("5"),
 			},
-			wantInUseClusterQueueNames: []string{fooCq.Name, fizzCq.Name},
 		},
 	}
-	for _, tc := range tests {
-		t.Run(tc.name, func(t *testing.T) {
-			ctx := context.Background()
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
 			scheme := runtime.NewScheme()
 			if err := kueue.AddToScheme(scheme); err != nil {
 				t.Fatalf("Failed adding kueue scheme: %v", err)
 			}
 			cache := New(fake.NewClientBuilder().WithScheme(scheme).Build())
-			cache.AddOrUpdateResourceFlavor(x86Rf)
-			cache.AddOrUpdateResourceFlavor(aarch64Rf)
-			for _, cq := range tc.clusterQueues {
-				if err := cache.AddClusterQueue(ctx, cq); err != nil {
-					t.Errorf("failed to add clusterQueue %s", cq.Name)
+			ctx := context.Background()
+			cache.AddOrUpdateResourceFlavor(utiltesting.MakeResourceFlavor("default").Obj())
+			if err := cache.AddClusterQueue(ctx, cq); err != nil {
+				t.Fatalf("Failed adding ClusterQueue: %v", err)
+			}
+			for _, q := range queues {
+				if err := cache.AddLocalQueue(q); err != nil {
+					t.Fatalf("Failed adding LocalQueue: %v", err)
 				}
 			}
-
-			cqs := cache.ClusterQueuesUsingFlavor(string(x86Flavor.Name))
-			if diff := cmp.Diff(tc.wantInUseClusterQueueNames, cqs, cmpopts.SortSlices(func(a, b string) bool {
-				return a < b
-			})); len(diff) != 0 {
-				t.Errorf("Unexpected flavor is in use by clusterQueues (-want,+got):\n%s", diff)
+			for _, wl := range admitted {
+				cache.AddOrUpdateWorkload(wl)
+			}
+			if tc.updateLimits != nil {
+				newQ := queues[0].DeepCopy()
+				newQ.Spec.Limits = tc.updateLimits
+				if err := cache.UpdateLocalQueue(queues[0], newQ); err != nil {
+					t.Fatalf("Failed updating LocalQueue: %v", err)
+				}
+			}
+			snapshot := cache.Snapshot()
+			gotResource, gotExceeded := snapshot.ClusterQueues["foo"].LocalQueueLimitExceeded(workload.NewInfo(tc.workload))
+			if gotResource != tc.wantResource || gotExceeded != tc.wantExceeded {
+				t.Errorf("LocalQueueLimitExceeded(_) = (%q, %t), want (%q, %t)", gotResource, gotExceeded, tc.wantResource, tc.wantExceeded)
 			}
 		})
 	}
 }
 
-func TestClusterQueueUpdateWithFlavors(t *testing.T) {
-	rf := utiltesting.MakeResourceFlavor("x86").Obj()
-	flavor := utiltesting.MakeFlavor(rf.Name, "5").Obj()
-
-	testcases := []struct {
-		name         string
-		curStatus    metrics.ClusterQueueStatus
-		clusterQueue *kueue.ClusterQueue
-		flavors      map[string]*kueue.ResourceFlavor
-		wantStatus   metrics.ClusterQueueStatus
+func TestWorkloadsPendingChecksLimitReached(t *testing.T) {
+	admission := utiltesting.MakeAdmission("foo").AdmissionChecks("check").Obj()
+	cases := map[string]struct {
+		limit    int32
+		admitted []*kueue.Workload
+		want     bool
 	}{
-		{
-			name:      "Pending clusterQueue updated existent flavors",
-			curStatus: pending,
-			clusterQueue: utiltesting.MakeClusterQueue("cq").
-				Resource(utiltesting.MakeResource("cpu").Flavor(flavor).Obj()).
-				Obj(),
-			flavors: map[string]*kueue.ResourceFlavor{
-				rf.Name: rf,
+		"no limit": {
+			admitted: []*kueue.Workload{
+				utiltesting.MakeWorkload("a", "ns").Admit(admission).Obj(),
+				utiltesting.MakeWorkload("b", "ns").Admit(admission).Obj(),
 			},
-			wantStatus: active,
-		},
-		{
-			name:      "Active clusterQueue updated with not found flavors",
-			curStatus: active,
-			clusterQueue: utiltesting.MakeClusterQueue("cq").
-				Resource(utiltesting.MakeResource("cpu").Flavor(flavor).Obj()).
-				Obj(),
-			flavors:    map[string]*kueue.ResourceFlavor{},
-			wantStatus: pending,
 		},
-		{
-			name:      "Terminating clusterQueue updated with existent flavors",
-			curStatus: terminating,
-			clusterQueue: utiltesting.MakeClusterQueue("cq").
-				Resource(utiltesting.MakeResource("cpu").Flavor(flavor).Obj()).
-				Obj(),
-			flavors: map[string]*kueue.ResourceFlavor{
-				rf.Name: rf,
+		"below the limit": {
+			limit: 2,
+			admitted: []*kueue.Workload{
+				utiltesting.MakeWorkload("a", "ns").Admit(admission).Obj(),
+				utiltesting.MakeWorkload("b", "ns").Admit(admission).AdmissionCheck("check", kueue.CheckStateReady).Obj(),
 			},
-			wantStatus: terminating,
 		},
-		{
-			name:      "Terminating clusterQueue updated with not found flavors",
-			curStatus: terminating,
-			clusterQueue: utiltesting.MakeClusterQueue("cq").
-				Resource(utiltesting.MakeResource("cpu").Flavor(flavor).Obj()).
-				Obj(),
-			flavors:    map[string]*kueue.ResourceFlavor{},
-			wantStatus: terminating,
+		"limit reached": {
+			limit: 2,
+			admitted: []*kueue.Workload{
+				utiltesting.MakeWorkload("a", "ns").Admit(admission).Obj(),
+				utiltesting.MakeWorkload("b", "ns").Admit(admission).AdmissionCheck("check", kueue.CheckStateRetry).Obj(),
+			},
+			want: true,
 		},
 	}
-
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			scheme := runtime.NewScheme()
+			if err := kueue.AddToScheme(scheme); err != nil {
+				t.Fatalf("Failed adding kueue scheme: %v", err)
+			}
+			cache := New(fake.NewClientBuilder().WithScheme(scheme).Build())
+			ctx := context.Background()
+			cache.AddOrUpdateResourceFlavor(utiltesting.MakeResourceFlavor("default").Obj())
+			cache.AddOrUpdateAdmissionCheck(utiltesting.MakeAdmissionCheck("check", "ctrl").Active(metav1.ConditionTrue).Obj())
+			cqWrapper := utiltesting.MakeClusterQueue("foo").
+				Resource(utiltesting.MakeResource(corev1.ResourceCPU).
+					Flavor(utiltesting.MakeFlavor("default", "10").Obj()).Obj()).
+				AdmissionChecks("check")
+			if tc.limit > 0 {
+				cqWrapper.MaxWorkloadsPendingChecks(tc.limit)
+			}
+			cq := cqWrapper.Obj()
+			if err := cache.AddClusterQueue(ctx, cq); err != nil {
+				t.Fatalf("Failed adding ClusterQueue: %v", err)
+			}
+			for _, wl := range tc.admitted {
+				cache.AddOrUpdateWorkload(wl)
+			}
+			snapshot := cache.Snapshot()
+			if got := snapshot.ClusterQueues["foo"].WorkloadsPendingChecksLimitReached(); got != tc.want {
+				t.Errorf("WorkloadsPendingChecksLimitReached() = %t, want %t", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestClusterQueueCopiesFitting(t *testing.T) {
+	cohort := &Cohort{
+		Name: "cohort",
+		RequestableResources: ResourceQuantities{
+			corev1.ResourceCPU: {"default": 20000},
+		},
+		UsedResources: ResourceQuantities{
+			corev1.ResourceCPU: {"default": 16000},
+		},
+	}
+	cases := map[string]struct {
+		cohort *Cohort
+		usage  ResourceQuantities
+		want   int
+	}{
+		"limited by the min quota": {
+			usage: ResourceQuantities{corev1.ResourceCPU: {"default": 2000}},
+			want:  3,
+		},
+		"limited by the most scarce resource": {
+			usage: ResourceQuantities{
+				corev1.ResourceCPU:    {"default": 1000},
+				corev1.ResourceMemory: {"default": 3 * utiltesting.Gi},
+			},
+			want: 2,
+		},
+		"limited by the quota left in the cohort": {
+			cohort: cohort,
+			usage:  ResourceQuantities{corev1.ResourceCPU: {"default": 2000}},
+			want:   2,
+		},
+		"limited by the max of the cohort": {
+			cohort: &Cohort{
+				Name:                 "cohort",
+				RequestableResources: cohort.RequestableResources,
+				UsedResources:        cohort.UsedResources,
+				MaxResources: ResourceQuantities{
+					corev1.ResourceCPU: {"default": 17000},
+				},
+			},
+			usage: ResourceQuantities{corev1.ResourceCPU: {"default": 1000}},
+			want:  1,
+		},
+		"flavor not in the ClusterQueue": {
+			usage: ResourceQuantities{corev1.ResourceCPU: {"spot": 1000}},
+			want:  0,
+		},
+	}
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			cq := &ClusterQueue{
+				Name:   "cq",
+				Cohort: tc.cohort,
+				RequestableResources: map[corev1.ResourceName]*Resource{
+					corev1.ResourceCPU: {
+						Flavors: []FlavorLimits{{Name: "default", Min: 10000}},
+					},
+					corev1.ResourceMemory: {
+						Flavors: []FlavorLimits{{Name: "default", Min: 10 * utiltesting.Gi}},
+					},
+				},
+				UsedResources: ResourceQuantities{
+					corev1.ResourceCPU:    {"default": 4000},
+					corev1.ResourceMemory: {"default": 2 * utiltesting.Gi},
+				},
+			}
+			if got := cq.CopiesFitting(tc.usage); got != tc.want {
+				t.Errorf("CopiesFitting(_) = %d, want %d", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestClusterQueuePriorityBandShares(t *testing.T) {
+	admitted := func(name string, prio int32) *workload.Info {
+		return workload.NewInfo(utiltesting.MakeWorkload(name, "").
+			Request(corev1.ResourceCPU, "2").
+			Priority(&prio).
+			Admit(utiltesting.MakeAdmission("cq").Flavor(corev1.ResourceCPU, "default").Obj()).
+			Obj())
+	}
+	cases := map[string]struct {
+		workloads []*workload.Info
+		want      map[string]int64
+	}{
+		"no usage": {
+			want: map[string]int64{"high": 0, "low": 0},
+		},
+		"bands within and above their guaranteed quota": {
+			workloads: []*workload.Info{
+				admitted("high-1", 1000),
+				admitted("high-2", 2000),
+				admitted("high-3", 1000),
+				admitted("high-4", 1000),
+				admitted("low", 0),
+			},
+			want: map[string]int64{"high": 1142, "low": 666},
+		},
+		"priority below the lowest band": {
+			workloads: []*workload.Info{
+				admitted("lowest", -10),
+			},
+			want: map[string]int64{"high": 0, "low": 666},
+		},
+	}
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			cq := &ClusterQueue{
+				Name: "cq",
+				RequestableResources: map[corev1.ResourceName]*Resource{
+					corev1.ResourceCPU: {
+						Flavors: []FlavorLimits{{Name: "default", Min: 10000}},
+					},
+				},
+				UsedResources: ResourceQuantities{
+					corev1.ResourceCPU: {"default": 0},
+				},
+				Workloads: make(map[string]*workload.Info),
+				PriorityBands: []kueue.PriorityBand{
+					{Name: "high", MinPriority: 1000, Weight: 7},
+					{Name: "low", MinPriority: 0, Weight: 3},
+				},
+			}
+			for _, wi := range tc.workloads {
+				cq.Workloads[workload.Key(wi.Obj)] = wi
+			}
+			if diff := cmp.Diff(tc.want, cq.PriorityBandShares()); diff != "" {
+				t.Errorf("Unexpected priority band shares (-want,+got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestQuotaSharing(t *testing.T) {
+	cqs := []*kueue.ClusterQueue{
+		utiltesting.MakeClusterQueue("a").Cohort("one").
+			Resource(utiltesting.MakeResource(corev1.ResourceCPU).
+				Flavor(utiltesting.MakeFlavor("default", "10").Obj()).Obj()).
+			Obj(),
+		utiltesting.MakeClusterQueue("b").Cohort("one").
+			Resource(utiltesting.MakeResource(corev1.ResourceCPU).
+				Flavor(utiltesting.MakeFlavor("default", "6").Obj()).Obj()).
+			Obj(),
+		utiltesting.MakeClusterQueue("c").Cohort("one").
+			Resource(utiltesting.MakeResource(corev1.ResourceCPU).
+				Flavor(utiltesting.MakeFlavor("default", "10").Obj()).Obj()).
+			Obj(),
+		utiltesting.MakeClusterQueue("d").
+			Resource(utiltesting.MakeResource(corev1.ResourceCPU).
+				Flavor(utiltesting.MakeFlavor("default", "10").Obj()).Obj()).
+			Obj(),
+	}
+	admitted := []*kueue.Workload{
+		utiltesting.MakeWorkload("a1", "ns").Request(corev1.ResourceCPU, "4").
+			Admit(utiltesting.MakeAdmission("a").Flavor(corev1.ResourceCPU, "default").Obj()).Obj(),
+		utiltesting.MakeWorkload("b1", "ns").Request(corev1.ResourceCPU, "4").
+			Admit(utiltesting.MakeAdmission("b").Flavor(corev1.ResourceCPU, "default").Obj()).Obj(),
+		utiltesting.MakeWorkload("c1", "ns").Request(corev1.ResourceCPU, "14").
+			Admit(utiltesting.MakeAdmission("c").Flavor(corev1.ResourceCPU, "default").Obj()).Obj(),
+		utiltesting.MakeWorkload("d1", "ns").Request(corev1.ResourceCPU, "12").
+			Admit(utiltesting.MakeAdmission("d").Flavor(corev1.ResourceCPU, "default").Obj()).Obj(),
+	}
+	cpuQuota := func(cq, q string) kueue.SharedQuota {
+		return kueue.SharedQuota{
+			ClusterQueue: cq,
+			Resources: map[corev1.ResourceName]map[string]resource.Quantity{
+				corev1.ResourceCPU: {"default": resource.MustParse(q)},
+			},
+		}
+	}
+	cases := map[string]struct {
+		cq   string
+		want *kueue.QuotaSharing
+	}{
+		"lending a big share": {
+			cq: "a",
+			want: &kueue.QuotaSharing{
+				LentTo: []kueue.SharedQuota{cpuQuota("c", "3")},
+			},
+		},
+		"lending a small share": {
+			cq: "b",
+			want: &kueue.QuotaSharing{
+				LentTo: []kueue.SharedQuota{cpuQuota("c", "1")},
+			},
+		},
+		"borrowing": {
+			cq: "c",
+			want: &kueue.QuotaSharing{
+				BorrowedFrom: []kueue.SharedQuota{cpuQuota("a", "3"), cpuQuota("b", "1")},
+			},
+		},
+		"no cohort": {
+			cq: "d",
+		},
+	}
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			scheme := runtime.NewScheme()
+			if err := kueue.AddToScheme(scheme); err != nil {
+				t.Fatalf("Failed adding kueue scheme: %v", err)
+			}
+			cache := New(fake.NewClientBuilder().WithScheme(scheme).Build())
+			ctx := context.Background()
+			for _, cq := range cqs {
+				if err := cache.AddClusterQueue(ctx, cq); err != nil {
+					t.Fatalf("Failed adding ClusterQueue: %v", err)
+				}
+			}
+			for _, wl := range admitted {
+				cache.AddOrUpdateWorkload(wl)
+			}
+			got, err := cache.QuotaSharing(utiltesting.MakeClusterQueue(tc.cq).Obj())
+			if err != nil {
+				t.Fatalf("Couldn't get quota sharing: %v", err)
+			}
+			if diff := cmp.Diff(tc.want, got); diff != "" {
+				t.Errorf("Unexpected quota sharing (-want,+got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestQuotaReservation(t *testing.T) {
+	scheme := runtime.NewScheme()
+	if err := kueue.AddToScheme(scheme); err != nil {
+		t.Fatalf("Failed adding kueue scheme: %v", err)
+	}
+	cache := New(fake.NewClientBuilder().WithScheme(scheme).Build())
+	cache.AddOrUpdateResourceFlavor(utiltesting.MakeResourceFlavor("default").Obj())
+	ctx := context.Background()
+	cqs := []*kueue.ClusterQueue{
+		utiltesting.MakeClusterQueue("a").Cohort("one").
+			Resource(utiltesting.MakeResource(corev1.ResourceCPU).
+				Flavor(utiltesting.MakeFlavor("default", "10").Obj()).Obj()).
+			Obj(),
+		utiltesting.MakeClusterQueue("b").Cohort("one").
+			Resource(utiltesting.MakeResource(corev1.ResourceCPU).
+				Flavor(utiltesting.MakeFlavor("default", "10").Obj()).Obj()).
+			Obj(),
+	}
+	for _, cq := range cqs {
+		if err := cache.AddClusterQueue(ctx, cq); err != nil {
+			t.Fatalf("Failed adding ClusterQueue: %v", err)
+		}
+	}
+	cache.AddOrUpdateWorkload(utiltesting.MakeWorkload("admitted", "ns").Request(corev1.ResourceCPU, "2").
+		Admit(utiltesting.MakeAdmission("a").Flavor(corev1.ResourceCPU, "default").Obj()).Obj())
+	preemptor := utiltesting.MakeWorkload("preemptor", "ns").Request(corev1.ResourceCPU, "5").
+		Admit(utiltesting.MakeAdmission("a").Flavor(corev1.ResourceCPU, "default").Obj()).Obj()
+	if err := cache.ReserveQuota(preemptor); err != nil {
+		t.Fatalf("Failed reserving quota: %v", err)
+	}
+
+	snapshot := cache.Snapshot()
+	if diff := cmp.Diff(ResourceQuantities{corev1.ResourceCPU: {"default": 7_000}}, snapshot.ClusterQueues["a"].UsedResources); diff != "" {
+		t.Errorf("Unexpected usage of the ClusterQueue with the reservation (-want,+got):\n%s", diff)
+	}
+	if diff := cmp.Diff(ResourceQuantities{corev1.ResourceCPU: {"default": 7_000}}, snapshot.ClusterQueues["b"].Cohort.UsedResources); diff != "" {
+		t.Errorf("Unexpected usage of the cohort with the reservation (-want,+got):\n%s", diff)
+	}
+	r := snapshot.RemoveReservation(preemptor)
+	if r == nil {
+		t.Fatalf("RemoveReservation(_) returned nil, want the reservation")
+	}
+	if diff := cmp.Diff(ResourceQuantities{corev1.ResourceCPU: {"default": 2_000}}, snapshot.ClusterQueues["a"].Cohort.UsedResources); diff != "" {
+		t.Errorf("Unexpected usage of the cohort without the reservation (-want,+got):\n%s", diff)
+	}
+	snapshot.AddReservation(r)
+	if diff := cmp.Diff(ResourceQuantities{corev1.ResourceCPU: {"default": 7_000}}, snapshot.ClusterQueues["a"].UsedResources); diff != "" {
+		t.Errorf("Unexpected usage of the ClusterQueue after restoring the reservation (-want,+got):\n%s", diff)
+	}
+
+	if err := cache.AssumeWorkload(preemptor); err != nil {
+		t.Fatalf("Failed assuming workload: %v", err)
+	}
+	snapshot = cache.Snapshot()
+	if len(snapshot.Reservations) != 0 {
+		t.Errorf("Got %d reservations after admitting the workload, want none", len(snapshot.Reservations))
+	}
+	if diff := cmp.Diff(ResourceQuantities{corev1.ResourceCPU: {"default": 7_000}}, snapshot.ClusterQueues["a"].UsedResources); diff != "" {
+		t.Errorf("Unexpected usage of the ClusterQueue after admitting the workload (-want,+got):\n%s", diff)
+	}
+	if cache.CancelQuotaReservation(preemptor) {
+		t.Error("CancelQuotaReservation(_) returned true after admitting the workload, want false")
+	}
+}
+
+func TestMoveWorkload(t *testing.T) {
+	cqs := []*kueue.ClusterQueue{
+		utiltesting.MakeClusterQueue("src").
+			Resource(utiltesting.MakeResource(corev1.ResourceCPU).
+				Flavor(utiltesting.MakeFlavor("default", "10").Obj()).Obj()).
+			Obj(),
+		utiltesting.MakeClusterQueue("big").
+			Resource(utiltesting.MakeResource(corev1.ResourceCPU).
+				Flavor(utiltesting.MakeFlavor("default", "10").Obj()).Obj()).
+			Obj(),
+		utiltesting.MakeClusterQueue("small").
+			Resource(utiltesting.MakeResource(corev1.ResourceCPU).
+				Flavor(utiltesting.MakeFlavor("default", "2").Obj()).Obj()).
+			Obj(),
+		utiltesting.MakeClusterQueue("borrower").Cohort("one").
+			Resource(utiltesting.MakeResource(corev1.ResourceCPU).
+				Flavor(utiltesting.MakeFlavor("default", "2").Obj()).Obj()).
+			Obj(),
+		utiltesting.MakeClusterQueue("capped").Cohort("one").
+			Resource(utiltesting.MakeResource(corev1.ResourceCPU).
+				Flavor(utiltesting.MakeFlavor("default", "2").Max("3").Obj()).Obj()).
+			Obj(),
+		utiltesting.MakeClusterQueue("lender").Cohort("one").
+			Resource(utiltesting.MakeResource(corev1.ResourceCPU).
+				Flavor(utiltesting.MakeFlavor("default", "5").Obj()).Obj()).
+			Obj(),
+		utiltesting.MakeClusterQueue("spot").
+			Resource(utiltesting.MakeResource(corev1.ResourceCPU).
+				Flavor(utiltesting.MakeFlavor("spot", "10").Obj()).Obj()).
+			Obj(),
+		utiltesting.MakeClusterQueue("pooled").Cohort("pool").
+			Resource(utiltesting.MakeResource(corev1.ResourceCPU).
+				Flavor(utiltesting.MakeFlavor("default", "2").Obj()).Obj()).
+			Obj(),
+		utiltesting.MakeClusterQueue("limited").Cohort("limited").
+			Resource(utiltesting.MakeResource(corev1.ResourceCPU).
+				Flavor(utiltesting.MakeFlavor("default", "2").Obj()).Obj()).
+			Obj(),
+	}
+	cohorts := []*kueue.Cohort{
+		utiltesting.MakeCohort("pool").Quota(corev1.ResourceCPU, "default", "5", "").Obj(),
+		utiltesting.MakeCohort("limited").Parent("pool").Quota(corev1.ResourceCPU, "default", "0", "3").Obj(),
+	}
+	wl := utiltesting.MakeWorkload("wl", "ns").Request(corev1.ResourceCPU, "4").
+		Admit(utiltesting.MakeAdmission("src").Flavor(corev1.ResourceCPU, "default").Obj()).Obj()
+	cases := map[string]struct {
+		target  string
+		wantErr bool
+	}{
+		"fits in the min quota": {
+			target: "big",
+		},
+		"doesn't fit without a cohort": {
+			target:  "small",
+			wantErr: true,
+		},
+		"fits borrowing from the cohort": {
+			target: "borrower",
+		},
+		"exceeds the max quota": {
+			target:  "capped",
+			wantErr: true,
+		},
+		"flavor not in the ClusterQueue": {
+			target:  "spot",
+			wantErr: true,
+		},
+		"fits borrowing from the quota pool of the cohort": {
+			target: "pooled",
+		},
+		"exceeds the max quota of the cohort": {
+			target:  "limited",
+			wantErr: true,
+		},
+	}
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			scheme := runtime.NewScheme()
+			if err := kueue.AddToScheme(scheme); err != nil {
+				t.Fatalf("Failed adding kueue scheme: %v", err)
+			}
+			cache := New(fake.NewClientBuilder().WithScheme(scheme).Build())
+			cache.AddOrUpdateResourceFlavor(utiltesting.MakeResourceFlavor("default").Obj())
+			cache.AddOrUpdateResourceFlavor(utiltesting.MakeResourceFlavor("spot").Obj())
+			ctx := context.Background()
+			for _, cq := range cqs {
+				if err := cache.AddClusterQueue(ctx, cq); err != nil {
+					t.Fatalf("Failed adding ClusterQueue: %v", err)
+				}
+			}
+			for _, cohort := range cohorts {
+				cache.AddOrUpdateCohort(cohort)
+			}
+			cache.AddOrUpdateWorkload(wl)
+
+			moved := wl.DeepCopy()
+			moved.Status.Admission.ClusterQueue = kueue.ClusterQueueReference(tc.target)
+			err := cache.MoveWorkload(wl, moved)
+			if gotErr := err != nil; gotErr != tc.wantErr {
+				t.Fatalf("MoveWorkload(_, _) returned error %v, want error %t", err, tc.wantErr)
+			}
+			wantUsage := map[string]int64{"src": 0, tc.target: 4_000}
+			if tc.wantErr {
+				wantUsage = map[string]int64{"src": 4_000, tc.target: 0}
+			}
+			for cqName, want := range wantUsage {
+				cq := cache.clusterQueues[cqName]
+				var got int64
+				for _, v := range cq.UsedResources[corev1.ResourceCPU] {
+					got += v
+				}
+				if got != want {
+					t.Errorf("Got CPU usage %d in ClusterQueue %s, want %d", got, cqName, want)
+				}
+			}
+			if tc.wantErr {
+				return
+			}
+			if err := cache.UpdateWorkload(wl, moved); err != nil {
+				t.Fatalf("Failed updating the moved workload: %v", err)
+			}
+			if diff := cmp.Diff(sets.NewString(workload.Key(wl)), sets.StringKeySet(cache.clusterQueues[tc.target].Workloads)); diff != "" {
+				t.Errorf("Unexpected workloads in the target ClusterQueue (-want,+got):\n%s", diff)
+			}
+			if n := len(cache.clusterQueues["src"].Workloads); n != 0 {
+				t.Errorf("Got %d workloads in the source ClusterQueue, want 0", n)
+			}
+			if len(cache.assumedWorkloads) != 0 {
+				t.Errorf("Got assumed workloads %v after the update, want none", cache.assumedWorkloads)
+			}
+		})
+	}
+}
+
+func TestCohortStatus(t *testing.T) {
+	cqs := []*kueue.ClusterQueue{
+		utiltesting.MakeClusterQueue("a").Cohort("one").
+			Resource(utiltesting.MakeResource(corev1.ResourceCPU).
+				Flavor(utiltesting.MakeFlavor("default", "10").Obj()).Obj()).
+			Obj(),
+		utiltesting.MakeClusterQueue("b").Cohort("one").
+			Resource(utiltesting.MakeResource(corev1.ResourceCPU).
+				Flavor(utiltesting.MakeFlavor("default", "6").Obj()).Obj()).
+			Resource(utiltesting.MakeResource(corev1.ResourceMemory).
+				Flavor(utiltesting.MakeFlavor("default", "4Gi").Obj()).Obj()).
+			Obj(),
+		utiltesting.MakeClusterQueue("inactive").Cohort("one").
+			Resource(utiltesting.MakeResource(corev1.ResourceCPU).
+				Flavor(utiltesting.MakeFlavor("nonexistent", "10").Obj()).Obj()).
+			Obj(),
+		utiltesting.MakeClusterQueue("c").
+			Resource(utiltesting.MakeResource(corev1.ResourceCPU).
+				Flavor(utiltesting.MakeFlavor("default", "10").Obj()).Obj()).
+			Obj(),
+	}
+	admitted := []*kueue.Workload{
+		utiltesting.MakeWorkload("a1", "ns").Request(corev1.ResourceCPU, "4").
+			Admit(utiltesting.MakeAdmission("a").Flavor(corev1.ResourceCPU, "default").Obj()).Obj(),
+		utiltesting.MakeWorkload("b1", "ns").Request(corev1.ResourceCPU, "8").Request(corev1.ResourceMemory, "1Gi").
+			Admit(utiltesting.MakeAdmission("b").
+				Flavor(corev1.ResourceCPU, "default").
+				Flavor(corev1.ResourceMemory, "default").Obj()).Obj(),
+		utiltesting.MakeWorkload("c1", "ns").Request(corev1.ResourceCPU, "2").
+			Admit(utiltesting.MakeAdmission("c").Flavor(corev1.ResourceCPU, "default").Obj()).Obj(),
+	}
+	cases := map[string]struct {
+		cohort string
+		want   kueue.CohortStatus
+	}{
+		"cohort with members": {
+			cohort: "one",
+			want: kueue.CohortStatus{
+				ClusterQueues: []string{"a", "b", "inactive"},
+				Quota: map[corev1.ResourceName]map[string]resource.Quantity{
+					corev1.ResourceCPU:    {"default": resource.MustParse("16")},
+					corev1.ResourceMemory: {"default": resource.MustParse("4Gi")},
+				},
+				Usage: map[corev1.ResourceName]map[string]resource.Quantity{
+					corev1.ResourceCPU:    {"default": resource.MustParse("12")},
+					corev1.ResourceMemory: {"default": resource.MustParse("1Gi")},
+				},
+			},
+		},
+		"cohort without members": {
+			cohort: "two",
+		},
+		"parent cohort": {
+			cohort: "top",
+			want: kueue.CohortStatus{
+				ClusterQueues: []string{"a", "b", "inactive"},
+				Quota: map[corev1.ResourceName]map[string]resource.Quantity{
+					corev1.ResourceCPU:    {"default": resource.MustParse("21")},
+					corev1.ResourceMemory: {"default": resource.MustParse("4Gi")},
+				},
+				Usage: map[corev1.ResourceName]map[string]resource.Quantity{
+					corev1.ResourceCPU:    {"default": resource.MustParse("12")},
+					corev1.ResourceMemory: {"default": resource.MustParse("1Gi")},
+				},
+			},
+		},
+	}
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			scheme := runtime.NewScheme()
+			if err := kueue.AddToScheme(scheme); err != nil {
+				t.Fatalf("Failed adding kueue scheme: %v", err)
+			}
+			cache := New(fake.NewClientBuilder().WithScheme(scheme).Build())
+			cache.AddOrUpdateResourceFlavor(utiltesting.MakeResourceFlavor("default").Obj())
+			ctx := context.Background()
+			for _, cq := range cqs {
+				if err := cache.AddClusterQueue(ctx, cq); err != nil {
+					t.Fatalf("Failed adding ClusterQueue: %v", err)
+				}
+			}
+			for _, wl := range admitted {
+				cache.AddOrUpdateWorkload(wl)
+			}
+			cache.AddOrUpdateCohort(utiltesting.MakeCohort("one").Parent("top").Obj())
+			cache.AddOrUpdateCohort(utiltesting.MakeCohort("top").Quota(corev1.ResourceCPU, "default", "5", "").Obj())
+			got := cache.CohortStatus(tc.cohort)
+			if diff := cmp.Diff(tc.want, got, cmpopts.EquateEmpty()); diff != "" {
+				t.Errorf("Unexpected cohort status (-want,+got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestCacheCohortOperations(t *testing.T) {
+	scheme := runtime.NewScheme()
+	if err := kueue.AddToScheme(scheme); err != nil {
+		t.Fatalf("Failed adding kueue scheme: %v", err)
+	}
+	cache := New(fake.NewClientBuilder().WithScheme(scheme).Build())
+	ctx := context.Background()
+	if err := cache.AddClusterQueue(ctx, utiltesting.MakeClusterQueue("a").Cohort("one").Obj()); err != nil {
+		t.Fatalf("Failed adding ClusterQueue: %v", err)
+	}
+	if err := cache.AddClusterQueue(ctx, utiltesting.MakeClusterQueue("b").Cohort("two").Obj()); err != nil {
+		t.Fatalf("Failed adding ClusterQueue: %v", err)
+	}
+
+	one := utiltesting.MakeCohort("one").Parent("top").Obj()
+	if !cache.AddOrUpdateCohort(one) {
+		t.Error("Adding cohort one didn't report a change")
+	}
+	if cache.AddOrUpdateCohort(one) {
+		t.Error("Updating cohort one without changes reported a change")
+	}
+	if !cache.AddOrUpdateCohort(utiltesting.MakeCohort("two").Parent("top").Quota(corev1.ResourceCPU, "default", "1", "").Obj()) {
+		t.Error("Adding cohort two didn't report a change")
+	}
+	if diff := cmp.Diff([]string{"one", "top"}, cache.CohortsOf("a")); diff != "" {
+		t.Errorf("Unexpected cohorts of ClusterQueue a (-want,+got):\n%s", diff)
+	}
+	if diff := cmp.Diff([]string{"a", "b"}, cache.ClusterQueuesInCohortTree("one"), cmpopts.SortSlices(func(a, b string) bool { return a < b })); diff != "" {
+		t.Errorf("Unexpected ClusterQueues in the tree of cohort one (-want,+got):\n%s", diff)
+	}
+
+	if !cache.DeleteCohort("one") {
+		t.Error("Deleting cohort one didn't report a change")
+	}
+	if cache.DeleteCohort("one") {
+		t.Error("Deleting cohort one twice reported a change")
+	}
+	if _, ok := cache.cohorts["one"]; !ok {
+		t.Error("Cohort one was removed while it has ClusterQueues")
+	}
+	if diff := cmp.Diff([]string{"one"}, cache.CohortsOf("a")); diff != "" {
+		t.Errorf("Unexpected cohorts of ClusterQueue a after deleting its Cohort (-want,+got):\n%s", diff)
+	}
+
+	cache.DeleteClusterQueue(utiltesting.MakeClusterQueue("b").Cohort("two").Obj())
+	if _, ok := cache.cohorts["two"]; !ok {
+		t.Error("Cohort two was removed while it has a Cohort object")
+	}
+	cache.DeleteCohort("two")
+	if _, ok := cache.cohorts["two"]; ok {
+		t.Error("Cohort two wasn't removed without ClusterQueues nor Cohort object")
+	}
+}
+
+func TestCohortProblem(t *testing.T) {
+	cases := map[string]struct {
+		cohorts    []*kueue.Cohort
+		wantReason string
+	}{
+		"no Cohort objects": {},
+		"parent with other children": {
+			cohorts: []*kueue.Cohort{
+				utiltesting.MakeCohort("one").Parent("top").Obj(),
+				utiltesting.MakeCohort("two").Parent("top").Obj(),
+			},
+		},
+		"parent with a Cohort object": {
+			cohorts: []*kueue.Cohort{
+				utiltesting.MakeCohort("one").Parent("top").Obj(),
+				utiltesting.MakeCohort("top").Obj(),
+			},
+		},
+		"orphan parent": {
+			cohorts: []*kueue.Cohort{
+				utiltesting.MakeCohort("one").Parent("typo").Obj(),
+			},
+			wantReason: ReasonOrphanParent,
+		},
+		"orphan grandparent": {
+			cohorts: []*kueue.Cohort{
+				utiltesting.MakeCohort("one").Parent("top").Obj(),
+				utiltesting.MakeCohort("top").Parent("typo").Obj(),
+			},
+			wantReason: ReasonOrphanParent,
+		},
+		"cycle": {
+			cohorts: []*kueue.Cohort{
+				utiltesting.MakeCohort("one").Parent("top").Obj(),
+				utiltesting.MakeCohort("top").Parent("one").Obj(),
+			},
+			wantReason: ReasonCohortCycle,
+		},
+	}
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			scheme := runtime.NewScheme()
+			if err := kueue.AddToScheme(scheme); err != nil {
+				t.Fatalf("Failed adding kueue scheme: %v", err)
+			}
+			cache := New(fake.NewClientBuilder().WithScheme(scheme).Build())
+			if err := cache.AddClusterQueue(context.Background(), utiltesting.MakeClusterQueue("a").Cohort("one").Obj()); err != nil {
+				t.Fatalf("Failed adding ClusterQueue: %v", err)
+			}
+			for _, c := range tc.cohorts {
+				cache.AddOrUpdateCohort(c)
+			}
+			if reason, msg := cache.CohortProblem("a"); reason != tc.wantReason {
+				t.Errorf("CohortProblem() = %q (%s), want %q", reason, msg, tc.wantReason)
+			}
+		})
+	}
+}
+
+func TestClusterQueuesUsingFlavor(t *testing.T) {
+	x86Rf := utiltesting.MakeResourceFlavor("x86").Obj()
+	aarch64Rf := utiltesting.MakeResourceFlavor("aarch64").Obj()
+	x86Flavor := utiltesting.MakeFlavor(x86Rf.Name, "5").Obj()
+	aarch64Flavor := utiltesting.MakeFlavor(aarch64Rf.Name, "3").Obj()
+	fooCq := utiltesting.MakeClusterQueue("fooCq").
+		Resource(utiltesting.MakeResource("cpu").Flavor(x86Flavor).Obj()).
+		Obj()
+	barCq := utiltesting.MakeClusterQueue("barCq").Obj()
+	fizzCq := utiltesting.MakeClusterQueue("fizzCq").
+		Resource(utiltesting.MakeResource("cpu").
+			Flavor(x86Flavor).
+			Flavor(aarch64Flavor).Obj()).
+		Obj()
+
+	tests := []struct {
+		name                       string
+		clusterQueues              []*kueue.ClusterQueue
+		wantInUseClusterQueueNames []string
+	}{
+		{
+			name: "single clusterQueue with flavor in use",
+			clusterQueues: []*kueue.ClusterQueue{
+				fooCq,
+			},
+			wantInUseClusterQueueNames: []string{fooCq.Name},
+		},
+		{
+			name: "single clusterQueue with no flavor",
+			clusterQueues: []*kueue.ClusterQueue{
+				barCq,
+			},
+		},
+		{
+			name: "multiple clusterQueues with flavor in use",
+			clusterQueues: []*kueue.ClusterQueue{
+				fooCq,
+				barCq,
+				fizzCq,
+			},
+			wantInUseClusterQueueNames: []string{fooCq.Name, fizzCq.Name},
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			ctx := context.Background()
+			scheme := runtime.NewScheme()
+			if err := kueue.AddToScheme(scheme); err != nil {
+				t.Fatalf("Failed adding kueue scheme: %v", err)
+			}
+			cache := New(fake.NewClientBuilder().WithScheme(scheme).Build())
+			cache.AddOrUpdateResourceFlavor(x86Rf)
+			cache.AddOrUpdateResourceFlavor(aarch64Rf)
+			for _, cq := range tc.clusterQueues {
+				if err := cache.AddClusterQueue(ctx, cq); err != nil {
+					t.Errorf("failed to add clusterQueue %s", cq.Name)
+				}
+			}
+
+			cqs := cache.ClusterQueuesUsingFlavor(string(x86Flavor.Name))
+			if diff := cmp.Diff(tc.wantInUseClusterQueueNames, cqs, cmpopts.SortSlices(func(a, b string) bool {
+				return a < b
+			})); len(diff) != 0 {
+				t.Errorf("Unexpected flavor is in use by clusterQueues (-want,+got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestClusterQueueFlavorAssignmentPolicy(t *testing.T) {
+	cases := map[string]struct {
+		defaultPolicy kueue.FlavorAssignmentPolicy
+		policy        kueue.FlavorAssignmentPolicy
+		wantBestFit   bool
+	}{
+		"no policy": {},
+		"default BestFit": {
+			defaultPolicy: kueue.BestFit,
+			wantBestFit:   true,
+		},
+		"BestFit": {
+			policy:      kueue.BestFit,
+			wantBestFit: true,
+		},
+		"FirstFit overrides default BestFit": {
+			defaultPolicy: kueue.BestFit,
+			policy:        kueue.FirstFit,
+		},
+	}
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			ctx := context.Background()
+			scheme := runtime.NewScheme()
+			if err := kueue.AddToScheme(scheme); err != nil {
+				t.Fatalf("Failed adding kueue scheme: %v", err)
+			}
+			cache := New(fake.NewClientBuilder().WithScheme(scheme).Build(), WithDefaultFlavorAssignmentPolicy(tc.defaultPolicy))
+			cq := utiltesting.MakeClusterQueue("cq").FlavorAssignmentPolicy(tc.policy).Obj()
+			if err := cache.AddClusterQueue(ctx, cq); err != nil {
+				t.Fatalf("Failed adding clusterQueue: %v", err)
+			}
+			if got := cache.clusterQueues["cq"].BestFit; got != tc.wantBestFit {
+				t.Errorf("ClusterQueue.BestFit=%t, want %t", got, tc.wantBestFit)
+			}
+		})
+	}
+}
+
+func TestClusterQueueFlavorFungibility(t *testing.T) {
+	cases := map[string]struct {
+		defaultFungibility             kueue.FlavorFungibility
+		fungibility                    *kueue.FlavorFungibility
+		wantTryNextFlavorWhenCanBorrow bool
+		wantPreemptWhenCanPreempt      bool
+		wantSplitWhenNoFlavorFits      bool
+	}{
+		"no fungibility": {},
+		"default fungibility": {
+			defaultFungibility: kueue.FlavorFungibility{
+				WhenCanBorrow:  kueue.TryNextFlavor,
+				WhenCanPreempt: kueue.Preempt,
+			},
+			wantTryNextFlavorWhenCanBorrow: true,
+			wantPreemptWhenCanPreempt:      true,
+		},
+		"fungibility": {
+			fungibility: &kueue.FlavorFungibility{
+				WhenCanBorrow:  kueue.TryNextFlavor,
+				WhenCanPreempt: kueue.Preempt,
+			},
+			wantTryNextFlavorWhenCanBorrow: true,
+			wantPreemptWhenCanPreempt:      true,
+		},
+		"fields override the default one by one": {
+			defaultFungibility: kueue.FlavorFungibility{
+				WhenCanBorrow:  kueue.TryNextFlavor,
+				WhenCanPreempt: kueue.Preempt,
+			},
+			fungibility: &kueue.FlavorFungibility{
+				WhenCanPreempt: kueue.TryNextFlavor,
+			},
+			wantTryNextFlavorWhenCanBorrow: true,
+		},
+		"split pod sets": {
+			fungibility: &kueue.FlavorFungibility{
+				WhenNoFlavorFits: kueue.SplitPodSet,
+			},
+			wantSplitWhenNoFlavorFits: true,
+		},
+	}
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			ctx := context.Background()
+			scheme := runtime.NewScheme()
+			if err := kueue.AddToScheme(scheme); err != nil {
+				t.Fatalf("Failed adding kueue scheme: %v", err)
+			}
+			cache := New(fake.NewClientBuilder().WithScheme(scheme).Build(), WithDefaultFlavorFungibility(tc.defaultFungibility))
+			cq := utiltesting.MakeClusterQueue("cq").Obj()
+			cq.Spec.FlavorFungibility = tc.fungibility
+			if err := cache.AddClusterQueue(ctx, cq); err != nil {
+				t.Fatalf("Failed adding clusterQueue: %v", err)
+			}
+			cqImpl := cache.clusterQueues["cq"]
+			if cqImpl.TryNextFlavorWhenCanBorrow != tc.wantTryNextFlavorWhenCanBorrow {
+				t.Errorf("ClusterQueue.TryNextFlavorWhenCanBorrow=%t, want %t", cqImpl.TryNextFlavorWhenCanBorrow, tc.wantTryNextFlavorWhenCanBorrow)
+			}
+			if cqImpl.PreemptWhenCanPreempt != tc.wantPreemptWhenCanPreempt {
+				t.Errorf("ClusterQueue.PreemptWhenCanPreempt=%t, want %t", cqImpl.PreemptWhenCanPreempt, tc.wantPreemptWhenCanPreempt)
+			}
+			if cqImpl.SplitWhenNoFlavorFits != tc.wantSplitWhenNoFlavorFits {
+				t.Errorf("ClusterQueue.SplitWhenNoFlavorFits=%t, want %t", cqImpl.SplitWhenNoFlavorFits, tc.wantSplitWhenNoFlavorFits)
+			}
+		})
+	}
+}
+
+func TestClusterQueuePolicies(t *testing.T) {
+	ctx := context.Background()
+	scheme := runtime.NewScheme()
+	if err := kueue.AddToScheme(scheme); err != nil {
+		t.Fatalf("Failed adding kueue scheme: %v", err)
+	}
+	cache := New(fake.NewClientBuilder().WithScheme(scheme).Build(),
+		WithDefaultFlavorAssignmentPolicy(kueue.BestFit),
+		WithDefaultFlavorFungibility(kueue.FlavorFungibility{WhenCanPreempt: kueue.Preempt}))
+	cq := utiltesting.MakeClusterQueue("cq").
+		QueueingStrategy(kueue.StrictFIFO).
+		Preemption(kueue.ClusterQueuePreemption{ReclaimWithinCohort: kueue.PreemptionPolicyAny}).
+		Obj()
+	if err := cache.AddClusterQueue(ctx, cq); err != nil {
+		t.Fatalf("Failed adding clusterQueue: %v", err)
+	}
+	got, err := cache.Policies(cq)
+	if err != nil {
+		t.Fatalf("Failed getting the policies: %v", err)
+	}
+	want := &kueue.ClusterQueuePolicies{
+		QueueingStrategy:       kueue.StrictFIFO,
+		OrderingPolicy:         kueue.PriorityOrdering,
+		LocalQueueFairness:     kueue.LocalQueueFairnessNone,
+		FlavorAssignmentPolicy: kueue.BestFit,
+		FlavorFungibility: kueue.FlavorFungibility{
+			WhenCanBorrow:    kueue.Borrow,
+			WhenCanPreempt:   kueue.Preempt,
+			WhenNoFlavorFits: kueue.Wait,
+		},
+		Preemption: kueue.ClusterQueuePreemption{
+			ReclaimWithinCohort: kueue.PreemptionPolicyAny,
+			WithinClusterQueue:  kueue.PreemptionPolicyNever,
+		},
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("Unexpected policies (-want,+got):\n%s", diff)
+	}
+}
+
+func TestResyncClusterQueue(t *testing.T) {
+	ctx := context.Background()
+	scheme := runtime.NewScheme()
+	if err := kueue.AddToScheme(scheme); err != nil {
+		t.Fatalf("Failed adding kueue scheme: %v", err)
+	}
+	cq := utiltesting.MakeClusterQueue("cq").
+		Resource(utiltesting.MakeResource(corev1.ResourceCPU).
+			Flavor(utiltesting.MakeFlavor("default", "10").Obj()).
+			Obj()).
+		Obj()
+	admission := utiltesting.MakeAdmission("cq").Flavor(corev1.ResourceCPU, "default").Obj()
+	admitted := utiltesting.MakeWorkload("admitted", "ns").Request(corev1.ResourceCPU, "2").Admit(admission).Obj()
+	stale := utiltesting.MakeWorkload("stale", "ns").Request(corev1.ResourceCPU, "3").Admit(admission).Obj()
+	assumed := utiltesting.MakeWorkload("assumed", "ns").Request(corev1.ResourceCPU, "4").Admit(admission).Obj()
+	cl := fake.NewClientBuilder().WithScheme(scheme).Build()
+	cache := New(cl)
+	if err := cache.AddClusterQueue(ctx, cq); err != nil {
+		t.Fatalf("Failed adding clusterQueue: %v", err)
+	}
+	// The cache missed the admitted workload and kept one that is gone from
+	// the API server.
+	if !cache.AddOrUpdateWorkload(stale) {
+		t.Fatalf("Failed adding workload %q", stale.Name)
+	}
+	if err := cache.AssumeWorkload(assumed); err != nil {
+		t.Fatalf("Failed assuming workload: %v", err)
+	}
+	if err := cl.Create(ctx, admitted); err != nil {
+		t.Fatalf("Failed creating workload: %v", err)
+	}
+
+	if err := cache.ResyncClusterQueue(ctx, cq); err != nil {
+		t.Fatalf("Failed resyncing clusterQueue: %v", err)
+	}
+	cqImpl := cache.clusterQueues["cq"]
+	wantWorkloads := sets.NewString("ns/admitted", "ns/assumed")
+	if gotWorkloads := sets.StringKeySet(cqImpl.Workloads); !gotWorkloads.Equal(wantWorkloads) {
+		t.Errorf("Unexpected workloads after resync (want: %v, got: %v)", wantWorkloads.List(), gotWorkloads.List())
+	}
+	wantUsed := ResourceQuantities{
+		corev1.ResourceCPU: {"default": 6_000},
+	}
+	if diff := cmp.Diff(wantUsed, cqImpl.UsedResources); diff != "" {
+		t.Errorf("Unexpected used resources after resync (-want,+got):\n%s", diff)
+	}
+	if _, ok := cache.assumedWorkloads["ns/assumed"]; !ok {
+		t.Error("Assumed workload was forgotten after resync")
+	}
+
+	if err := cache.ResyncClusterQueue(ctx, utiltesting.MakeClusterQueue("missing").Obj()); err != errCqNotFound {
+		t.Errorf("Resyncing a missing clusterQueue returned %v, want %v", err, errCqNotFound)
+	}
+}
+
+func TestClusterQueueUpdateWithFlavors(t *testing.T) {
+	rf := utiltesting.MakeResourceFlavor("x86").Obj()
+	flavor := utiltesting.MakeFlavor(rf.Name, "5").Obj()
+
+	testcases := []struct {
+		name         string
+		curStatus    metrics.ClusterQueueStatus
+		clusterQueue *kueue.ClusterQueue
+		flavors      map[string]*kueue.ResourceFlavor
+		wantStatus   metrics.ClusterQueueStatus
+	}{
+		{
+			name:      "Pending clusterQueue updated existent flavors",
+			curStatus: pending,
+			clusterQueue: utiltesting.MakeClusterQueue("cq").
+				Resource(utiltesting.MakeResource("cpu").Flavor(flavor).Obj()).
+				Obj(),
+			flavors: map[string]*kueue.ResourceFlavor{
+				rf.Name: rf,
+			},
+			wantStatus: active,
+		},
+		{
+			name:      "Active clusterQueue updated with not found flavors",
+			curStatus: active,
+			clusterQueue: utiltesting.MakeClusterQueue("cq").
+				Resource(utiltesting.MakeResource("cpu").Flavor(flavor).Obj()).
+				Obj(),
+			flavors:    map[string]*kueue.ResourceFlavor{},
+			wantStatus: pending,
+		},
+		{
+			name:      "Terminating clusterQueue updated with existent flavors",
+			curStatus: terminating,
+			clusterQueue: utiltesting.MakeClusterQueue("cq").
+				Resource(utiltesting.MakeResource("cpu").Flavor(flavor).Obj()).
+				Obj(),
+			flavors: map[string]*kueue.ResourceFlavor{
+				rf.Name: rf,
+			},
+			wantStatus: terminating,
+		},
+		{
+			name:      "Terminating clusterQueue updated with not found flavors",
+			curStatus: terminating,
+			clusterQueue: utiltesting.MakeClusterQueue("cq").
+				Resource(utiltesting.MakeResource("cpu").Flavor(flavor).Obj()).
+				Obj(),
+			flavors:    map[string]*kueue.ResourceFlavor{},
+			wantStatus: terminating,
+		},
+	}
+
 	for _, tc := range testcases {
 		t.Run(tc.name, func(t *testing.T) {
 			scheme := runtime.NewScheme()
@@ -1612,6 +2841,277 @@ func TestClusterQueueUpdateWithFlavors(t *testing.T) {
 	}
 }
 
+func TestCacheAdmissionChecks(t *testing.T) {
+	ctx := context.Background()
+	scheme := runtime.NewScheme()
+	if err := kueue.AddToScheme(scheme); err != nil {
+		t.Fatalf("Failed adding kueue scheme: %v", err)
+	}
+	cache := New(fake.NewClientBuilder().WithScheme(scheme).Build())
+	cache.AddOrUpdateResourceFlavor(utiltesting.MakeResourceFlavor("default").Obj())
+	cq := utiltesting.MakeClusterQueue("cq").
+		Resource(utiltesting.MakeResource(corev1.ResourceCPU).Flavor(utiltesting.MakeFlavor("default", "5").Obj()).Obj()).
+		AdmissionChecks("provisioning", "budget").
+		Obj()
+	if err := cache.AddClusterQueue(ctx, cq); err != nil {
+		t.Fatalf("Failed adding ClusterQueue: %v", err)
+	}
+	if cache.ClusterQueueActive("cq") {
+		t.Errorf("ClusterQueue is active without its admission checks")
+	}
+
+	if got := cache.AddOrUpdateAdmissionCheck(utiltesting.MakeAdmissionCheck("provisioning", "ctrl").Active(metav1.ConditionTrue).Obj()); got.Len() != 0 {
+		t.Errorf("Got activated ClusterQueues %v with a missing admission check", got.List())
+	}
+	if got := cache.AddOrUpdateAdmissionCheck(utiltesting.MakeAdmissionCheck("budget", "ctrl").Active(metav1.ConditionFalse).Obj()); got.Len() != 0 {
+		t.Errorf("Got activated ClusterQueues %v with an inactive admission check", got.List())
+	}
+	got := cache.AddOrUpdateAdmissionCheck(utiltesting.MakeAdmissionCheck("budget", "ctrl").Active(metav1.ConditionTrue).Obj())
+	if diff := cmp.Diff([]string{"cq"}, got.List()); diff != "" {
+		t.Errorf("Unexpected activated ClusterQueues (-want,+got):\n%s", diff)
+	}
+	if !cache.ClusterQueueActive("cq") {
+		t.Errorf("ClusterQueue is inactive with all its admission checks active")
+	}
+
+	snapshot := cache.Snapshot()
+	if diff := cmp.Diff([]string{"provisioning", "budget"}, snapshot.ClusterQueues["cq"].AdmissionChecks); diff != "" {
+		t.Errorf("Unexpected admission checks in the snapshot (-want,+got):\n%s", diff)
+	}
+
+	cache.DeleteAdmissionCheck("budget")
+	if cache.ClusterQueueActive("cq") {
+		t.Errorf("ClusterQueue is active after one of its admission checks was deleted")
+	}
+}
+
+func TestCacheFlavorAdmissionChecks(t *testing.T) {
+	ctx := context.Background()
+	scheme := runtime.NewScheme()
+	if err := kueue.AddToScheme(scheme); err != nil {
+		t.Fatalf("Failed adding kueue scheme: %v", err)
+	}
+	cache := New(fake.NewClientBuilder().WithScheme(scheme).Build())
+	cache.AddOrUpdateResourceFlavor(utiltesting.MakeResourceFlavor("fixed").Obj())
+	cache.AddOrUpdateResourceFlavor(utiltesting.MakeResourceFlavor("autoscaled").Obj())
+	cache.AddOrUpdateAdmissionCheck(utiltesting.MakeAdmissionCheck("budget", "ctrl").Active(metav1.ConditionTrue).Obj())
+	cq := utiltesting.MakeClusterQueue("cq").
+		Resource(utiltesting.MakeResource(corev1.ResourceCPU).
+			Flavor(utiltesting.MakeFlavor("fixed", "5").Obj()).
+			Flavor(utiltesting.MakeFlavor("autoscaled", "5").AdmissionChecks("provisioning", "budget").Obj()).Obj()).
+		AdmissionChecks("budget").
+		Obj()
+	if err := cache.AddClusterQueue(ctx, cq); err != nil {
+		t.Fatalf("Failed adding ClusterQueue: %v", err)
+	}
+	if cache.ClusterQueueActive("cq") {
+		t.Errorf("ClusterQueue is active without the admission check of a flavor")
+	}
+	if diff := cmp.Diff([]string{"cq"}, cache.ClusterQueuesUsingAdmissionCheck("provisioning")); diff != "" {
+		t.Errorf("Unexpected ClusterQueues using the admission check of a flavor (-want,+got):\n%s", diff)
+	}
+	got := cache.AddOrUpdateAdmissionCheck(utiltesting.MakeAdmissionCheck("provisioning", "ctrl").Active(metav1.ConditionTrue).Obj())
+	if diff := cmp.Diff([]string{"cq"}, got.List()); diff != "" {
+		t.Errorf("Unexpected activated ClusterQueues (-want,+got):\n%s", diff)
+	}
+
+	snapshot := cache.Snapshot()
+	cases := map[string]struct {
+		flavors sets.String
+		want    []string
+	}{
+		"flavor without admission checks": {
+			flavors: sets.NewString("fixed"),
+			want:    []string{"budget"},
+		},
+		"flavor with admission checks": {
+			flavors: sets.NewString("autoscaled"),
+			want:    []string{"budget", "provisioning"},
+		},
+		"both flavors": {
+			flavors: sets.NewString("fixed", "autoscaled"),
+			want:    []string{"budget", "provisioning"},
+		},
+	}
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			got := snapshot.ClusterQueues["cq"].AdmissionChecksForFlavors(tc.flavors)
+			if diff := cmp.Diff(tc.want, got); diff != "" {
+				t.Errorf("Unexpected admission checks (-want,+got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestCacheRefreshQuotaWindows(t *testing.T) {
+	day := time.Date(2022, 10, 14, 0, 0, 0, 0, time.UTC)
+	scheme := runtime.NewScheme()
+	if err := kueue.AddToScheme(scheme); err != nil {
+		t.Fatalf("Failed adding kueue scheme: %v", err)
+	}
+	cache := New(fake.NewClientBuilder().WithScheme(scheme).Build())
+	cache.AddOrUpdateResourceFlavor(utiltesting.MakeResourceFlavor("default").Obj())
+	cq := utiltesting.MakeClusterQueue("cq").
+		Resource(utiltesting.MakeResource(corev1.ResourceCPU).Flavor(utiltesting.MakeFlavor("default", "10").Obj()).Obj()).
+		QuotaWindow(utiltesting.MakeQuotaWindow("nightly", "20:00", "06:00").Quota(corev1.ResourceCPU, "default", "20").Obj()).
+		Obj()
+	if err := cache.AddClusterQueue(context.Background(), cq); err != nil {
+		t.Fatalf("Adding ClusterQueue: %v", err)
+	}
+	// Start outside of the window, regardless of the current time.
+	cache.RefreshQuotaWindows("cq", day.Add(12*time.Hour))
+
+	steps := []struct {
+		now         time.Time
+		wantChanged bool
+		wantNext    time.Time
+		wantMin     int64
+	}{
+		{
+			now:      day.Add(13 * time.Hour),
+			wantNext: day.Add(20 * time.Hour),
+			wantMin:  10000,
+		},
+		{
+			now:         day.Add(21 * time.Hour),
+			wantChanged: true,
+			wantNext:    day.Add(30 * time.Hour),
+			wantMin:     20000,
+		},
+		{
+			now:         day.Add(30 * time.Hour),
+			wantChanged: true,
+			wantNext:    day.Add(44 * time.Hour),
+			wantMin:     10000,
+		},
+	}
+	for i, s := range steps {
+		gotChanged, gotNext := cache.RefreshQuotaWindows("cq", s.now)
+		if gotChanged != s.wantChanged {
+			t.Errorf("Step %d: got changed %t, want %t", i, gotChanged, s.wantChanged)
+		}
+		if !gotNext.Equal(s.wantNext) {
+			t.Errorf("Step %d: got next transition %v, want %v", i, gotNext, s.wantNext)
+		}
+		gotMin := cache.Snapshot().ClusterQueues["cq"].RequestableResources[corev1.ResourceCPU].Flavors[0].Min
+		if gotMin != s.wantMin {
+			t.Errorf("Step %d: got min quota %d, want %d", i, gotMin, s.wantMin)
+		}
+	}
+}
+
+func TestCacheSharedResources(t *testing.T) {
+	scheme := runtime.NewScheme()
+	if err := kueue.AddToScheme(scheme); err != nil {
+		t.Fatalf("Failed adding kueue scheme: %v", err)
+	}
+	cache := New(fake.NewClientBuilder().WithScheme(scheme).Build())
+	cache.AddOrUpdateResourceFlavor(utiltesting.MakeResourceFlavor("shared").SharedResource("example.com/gpu", 4).Obj())
+	cq := utiltesting.MakeClusterQueue("cq").
+		Resource(utiltesting.MakeResource("example.com/gpu").Flavor(utiltesting.MakeFlavor("shared", "2").Max("3").Obj()).Obj()).
+		Obj()
+	if err := cache.AddClusterQueue(context.Background(), cq); err != nil {
+		t.Fatalf("Adding ClusterQueue: %v", err)
+	}
+	wl := utiltesting.MakeWorkload("one", "").
+		Count(5).
+		Request("example.com/gpu", "1").
+		Admit(utiltesting.MakeAdmission("cq").Flavor("example.com/gpu", "shared").Obj()).
+		Obj()
+	if added := cache.AddOrUpdateWorkload(wl); !added {
+		t.Fatalf("Workload %s was not added", workload.Key(wl))
+	}
+
+	steps := []struct {
+		flavor    *kueue.ResourceFlavor
+		wantLimit FlavorLimits
+		wantUsage kueue.Usage
+	}{
+		{
+			wantLimit: FlavorLimits{Name: "shared", Min: 8, Max: pointer.Int64(12)},
+			wantUsage: kueue.Usage{
+				Total: pointer.Quantity(resource.MustParse("1250m")),
+			},
+		},
+		{
+			flavor:    utiltesting.MakeResourceFlavor("shared").SharedResource("example.com/gpu", 2).Obj(),
+			wantLimit: FlavorLimits{Name: "shared", Min: 4, Max: pointer.Int64(6)},
+			wantUsage: kueue.Usage{
+				Total:    pointer.Quantity(resource.MustParse("2500m")),
+				Borrowed: pointer.Quantity(resource.MustParse("500m")),
+			},
+		},
+		{
+			flavor:    utiltesting.MakeResourceFlavor("shared").Obj(),
+			wantLimit: FlavorLimits{Name: "shared", Min: 2, Max: pointer.Int64(3)},
+			wantUsage: kueue.Usage{
+				Total:    pointer.Quantity(resource.MustParse("5")),
+				Borrowed: pointer.Quantity(resource.MustParse("3")),
+			},
+		},
+	}
+	for i, s := range steps {
+		if s.flavor != nil {
+			cache.AddOrUpdateResourceFlavor(s.flavor)
+		}
+		gotLimit := cache.Snapshot().ClusterQueues["cq"].RequestableResources["example.com/gpu"].Flavors[0]
+		if diff := cmp.Diff(s.wantLimit, gotLimit); diff != "" {
+			t.Errorf("Step %d: unexpected quota (-want,+got):\n%s", i, diff)
+		}
+		usage, _, err := cache.Usage(cq)
+		if err != nil {
+			t.Fatalf("Step %d: couldn't get usage: %v", i, err)
+		}
+		if diff := cmp.Diff(s.wantUsage, usage["example.com/gpu"]["shared"]); diff != "" {
+			t.Errorf("Step %d: unexpected usage (-want,+got):\n%s", i, diff)
+		}
+	}
+}
+
+func TestCacheFlavorsBeingDeleted(t *testing.T) {
+	scheme := runtime.NewScheme()
+	if err := kueue.AddToScheme(scheme); err != nil {
+		t.Fatalf("Failed adding kueue scheme: %v", err)
+	}
+	cache := New(fake.NewClientBuilder().WithScheme(scheme).Build())
+	cache.AddOrUpdateResourceFlavor(utiltesting.MakeResourceFlavor("spot").Obj())
+	cache.AddOrUpdateResourceFlavor(utiltesting.MakeResourceFlavor("on-demand").Obj())
+	cq := utiltesting.MakeClusterQueue("cq").
+		Resource(utiltesting.MakeResource(corev1.ResourceCPU).
+			Flavor(utiltesting.MakeFlavor("spot", "10").Obj()).
+			Flavor(utiltesting.MakeFlavor("on-demand", "10").Obj()).
+			Obj()).
+		Obj()
+	if err := cache.AddClusterQueue(context.Background(), cq); err != nil {
+		t.Fatalf("Adding ClusterQueue: %v", err)
+	}
+	cache.AddOrUpdateWorkload(utiltesting.MakeWorkload("a", "").
+		Request(corev1.ResourceCPU, "1").
+		Admit(utiltesting.MakeAdmission("cq").Flavor(corev1.ResourceCPU, "spot").Obj()).
+		Obj())
+	cache.AddOrUpdateWorkload(utiltesting.MakeWorkload("b", "").
+		Request(corev1.ResourceCPU, "1").
+		Admit(utiltesting.MakeAdmission("cq").Flavor(corev1.ResourceCPU, "on-demand").Obj()).
+		Obj())
+
+	if got := cache.TerminatingFlavors("cq"); len(got) != 0 {
+		t.Errorf("Got terminating flavors %v before the deletion, want none", got)
+	}
+	spot := utiltesting.MakeResourceFlavor("spot").Obj()
+	spot.DeletionTimestamp = &metav1.Time{Time: time.Unix(1, 0)}
+	cache.AddOrUpdateResourceFlavor(spot)
+	if diff := cmp.Diff([]string{"spot"}, cache.TerminatingFlavors("cq")); diff != "" {
+		t.Errorf("Unexpected terminating flavors (-want,+got):\n%s", diff)
+	}
+	var gotWorkloads []string
+	for _, wl := range cache.AdmittedWorkloadsUsingFlavor("spot") {
+		gotWorkloads = append(gotWorkloads, wl.Name)
+	}
+	if diff := cmp.Diff([]string{"a"}, gotWorkloads); diff != "" {
+		t.Errorf("Unexpected workloads using the flavor (-want,+got):\n%s", diff)
+	}
+}
+
 func TestClusterQueueUpdateCodependentResources(t *testing.T) {
 	cases := map[string]struct {
 		cq     ClusterQueue
@@ -2081,3 +3581,154 @@ func messageOrEmpty(err error) string {
 	}
 	return err.Error()
 }
+
+func TestCacheBudgets(t *testing.T) {
+	scheme := runtime.NewScheme()
+	if err := kueue.AddToScheme(scheme); err != nil {
+		t.Fatalf("Failed adding kueue scheme: %v", err)
+	}
+	cache := New(fake.NewClientBuilder().WithScheme(scheme).Build())
+	cache.AddOrUpdateResourceFlavor(utiltesting.MakeResourceFlavor("default").Obj())
+	cq := utiltesting.MakeClusterQueue("cq").
+		Resource(utiltesting.MakeResource(corev1.ResourceCPU).Flavor(utiltesting.MakeFlavor("default", "10").Obj()).Obj()).
+		Budget(corev1.ResourceCPU, "", kueue.BudgetPeriodMonth, "2").
+		Obj()
+	if err := cache.AddClusterQueue(context.Background(), cq); err != nil {
+		t.Fatalf("Adding ClusterQueue: %v", err)
+	}
+	periodStart := accounting.PeriodStart(kueue.BudgetPeriodMonth, time.Now())
+
+	for i, name := range []string{"a", "b"} {
+		wl := utiltesting.MakeWorkload(name, "").
+			Request(corev1.ResourceCPU, "1").
+			Admit(utiltesting.MakeAdmission("cq").Flavor(corev1.ResourceCPU, "default").Obj()).
+			Condition(metav1.Condition{
+				Type:               kueue.WorkloadAdmitted,
+				Status:             metav1.ConditionTrue,
+				LastTransitionTime: metav1.NewTime(periodStart.Add(time.Minute)),
+			}).
+			Obj()
+		if !cache.AddOrUpdateWorkload(wl) {
+			t.Fatalf("Failed adding workload %s", name)
+		}
+		cache.SettleWorkload(wl, periodStart.Add(time.Hour+time.Minute))
+		if err := cache.DeleteWorkload(wl); err != nil {
+			t.Fatalf("Deleting workload %s: %v", name, err)
+		}
+		// Workloads that are not in the cache are not accounted.
+		cache.SettleWorkload(wl, periodStart.Add(2*time.Hour))
+
+		statuses := cache.BudgetStatus("cq", time.Now())
+		if len(statuses) != 1 {
+			t.Fatalf("Got %d budget statuses, want 1", len(statuses))
+		}
+		wantUsed := resource.MustParse(fmt.Sprint(i + 1))
+		if statuses[0].Used.Cmp(wantUsed) != 0 {
+			t.Errorf("After settling %s, got used %s, want %s", name, statuses[0].Used.String(), wantUsed.String())
+		}
+		gotExhausted := cache.Snapshot().ClusterQueues["cq"].BudgetExhausted(corev1.ResourceCPU, "default")
+		if wantExhausted := i == 1; gotExhausted != wantExhausted {
+			t.Errorf("After settling %s, got exhausted %t, want %t", name, gotExhausted, wantExhausted)
+		}
+	}
+
+	nextPeriod := accounting.NextPeriodStart(kueue.BudgetPeriodMonth, periodStart)
+	rolled, next := cache.RefreshBudgets("cq", nextPeriod.Add(time.Minute))
+	if !rolled {
+		t.Errorf("The budget didn't roll to the next period")
+	}
+	if wantNext := accounting.NextPeriodStart(kueue.BudgetPeriodMonth, nextPeriod); !next.Equal(wantNext) {
+		t.Errorf("Got next period %v, want %v", next, wantNext)
+	}
+	if cache.Snapshot().ClusterQueues["cq"].BudgetExhausted(corev1.ResourceCPU, "default") {
+		t.Errorf("The budget is exhausted in the next period")
+	}
+}
+
+func TestReservedTiers(t *testing.T) {
+	scheme := runtime.NewScheme()
+	if err := kueue.AddToScheme(scheme); err != nil {
+		t.Fatalf("Failed adding kueue scheme: %v", err)
+	}
+	cache := New(fake.NewClientBuilder().WithScheme(scheme).Build())
+	cache.AddOrUpdateResourceFlavor(utiltesting.MakeResourceFlavor("default").Obj())
+	cq := utiltesting.MakeClusterQueue("cq").
+		Resource(utiltesting.MakeResource(corev1.ResourceCPU).Flavor(utiltesting.MakeFlavor("default", "10").Obj()).Obj()).
+		ReservedTier("urgent", 1000, 20).
+		ReservedTier("high", 100, 30).
+		Obj()
+	if err := cache.AddClusterQueue(context.Background(), cq); err != nil {
+		t.Fatalf("Adding ClusterQueue: %v", err)
+	}
+	workloads := []*kueue.Workload{
+		utiltesting.MakeWorkload("urgent", "").Request(corev1.ResourceCPU, "1").Priority(pointer.Int32(2000)).
+			Admit(utiltesting.MakeAdmission("cq").Flavor(corev1.ResourceCPU, "default").Obj()).Obj(),
+		utiltesting.MakeWorkload("high", "").Request(corev1.ResourceCPU, "4").Priority(pointer.Int32(100)).
+			Admit(utiltesting.MakeAdmission("cq").Flavor(corev1.ResourceCPU, "default").Obj()).Obj(),
+		utiltesting.MakeWorkload("low", "").Request(corev1.ResourceCPU, "2").
+			Admit(utiltesting.MakeAdmission("cq").Flavor(corev1.ResourceCPU, "default").Obj()).Obj(),
+	}
+	for _, wl := range workloads {
+		if !cache.AddOrUpdateWorkload(wl) {
+			t.Fatalf("Failed adding workload %s", wl.Name)
+		}
+	}
+	snapshot := cache.Snapshot()
+	snapCQ := snapshot.ClusterQueues["cq"]
+	wantUsage := map[string]ResourceQuantities{
+		"urgent": {corev1.ResourceCPU: {"default": 1_000}},
+		"high":   {corev1.ResourceCPU: {"default": 4_000}},
+	}
+	if diff := cmp.Diff(wantUsage, snapCQ.TierUsage); diff != "" {
+		t.Errorf("Unexpected usage of the tiers (-want,+got):\n%s", diff)
+	}
+	// The urgent tier reserves 2 cpus and uses 1. The high tier reserves 3
+	// cpus and uses 4.
+	for p, want := range map[int32]int64{0: 0, 100: 1_000, 1000: 0} {
+		if got := snapCQ.ReservedQuota(corev1.ResourceCPU, "default", p); got != want {
+			t.Errorf("ReservedQuota(_, _, %d) = %d, want %d", p, got, want)
+		}
+	}
+
+	snapshot.RemoveWorkload(workload.NewInfo(workloads[1]))
+	if got := snapCQ.ReservedQuota(corev1.ResourceCPU, "default", 0); got != 4_000 {
+		t.Errorf("After removing a workload from the snapshot, ReservedQuota(_, _, 0) = %d, want 4000", got)
+	}
+	if got := cache.Snapshot().ClusterQueues["cq"].ReservedQuota(corev1.ResourceCPU, "default", 0); got != 0 {
+		t.Errorf("Removing a workload from the snapshot changed the cache, ReservedQuota(_, _, 0) = %d, want 0", got)
+	}
+}
+
+func TestHistoricalUsage(t *testing.T) {
+	start := time.Date(2022, 10, 14, 0, 0, 0, 0, time.UTC)
+	cq := ClusterQueue{
+		UsedResources: ResourceQuantities{
+			corev1.ResourceCPU: {"on-demand": 6000, "spot": 2000},
+		},
+		usageHalfLife: time.Hour,
+	}
+	cq.updateHistoricalUsage(start)
+	if diff := cmp.Diff(map[corev1.ResourceName]float64{corev1.ResourceCPU: 0}, cq.historicalUsage); diff != "" {
+		t.Errorf("Unexpected initial historical usage (-want,+got):\n%s", diff)
+	}
+
+	// After a half-life, the history moves half of the way to the usage.
+	cq.updateHistoricalUsage(start.Add(time.Hour))
+	if diff := cmp.Diff(map[corev1.ResourceName]float64{corev1.ResourceCPU: 4000}, cq.historicalUsage); diff != "" {
+		t.Errorf("Unexpected historical usage after using the resources (-want,+got):\n%s", diff)
+	}
+
+	// The history of an idle ClusterQueue decays.
+	cq.UsedResources[corev1.ResourceCPU]["on-demand"] = 0
+	cq.UsedResources[corev1.ResourceCPU]["spot"] = 0
+	got := cq.historicalUsageAt(start.Add(3 * time.Hour))
+	if diff := cmp.Diff(map[corev1.ResourceName]float64{corev1.ResourceCPU: 1000}, got); diff != "" {
+		t.Errorf("Unexpected historical usage after being idle (-want,+got):\n%s", diff)
+	}
+
+	// Going back in time doesn't change the history.
+	cq.updateHistoricalUsage(start)
+	if diff := cmp.Diff(map[corev1.ResourceName]float64{corev1.ResourceCPU: 4000}, cq.historicalUsage); diff != "" {
+		t.Errorf("Unexpected historical usage for an earlier time (-want,+got):\n%s", diff)
+	}
+}
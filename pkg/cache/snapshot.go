@@ -17,6 +17,10 @@ limitations under the License.
 package cache
 
 import (
+	"math"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/util/sets"
 
 	kueue "sigs.k8s.io/kueue/apis/kueue/v1alpha2"
@@ -27,6 +31,68 @@ type Snapshot struct {
 	ClusterQueues            map[string]*ClusterQueue
 	ResourceFlavors          map[string]*kueue.ResourceFlavor
 	InactiveClusterQueueSets sets.String
+	// Reservations hold the quota reserved for the workloads waiting for the
+	// preemption of other workloads, keyed by workload. The reserved quota is
+	// included in the usage of the ClusterQueues and cohorts.
+	Reservations map[string]*workload.Info
+}
+
+// RemoveWorkload removes a workload from its corresponding ClusterQueue and
+// updates the resource usage of the ClusterQueue and its cohort.
+func (s *Snapshot) RemoveWorkload(wl *workload.Info) {
+	cq := s.ClusterQueues[wl.ClusterQueue]
+	delete(cq.Workloads, workload.Key(wl.Obj))
+	updateUsage(wl, cq.UsedResources, -1)
+	cq.updateTierUsage(wl, -1)
+	for cohort := cq.Cohort; cohort != nil; cohort = cohort.Parent {
+		updateUsage(wl, cohort.UsedResources, -1)
+	}
+}
+
+// RemoveReservation removes the quota reserved for the workload from the usage
+// of its ClusterQueue and cohort, and returns the reservation. It returns nil
+// if the workload doesn't have a reservation.
+func (s *Snapshot) RemoveReservation(wl *kueue.Workload) *workload.Info {
+	k := workload.Key(wl)
+	r := s.Reservations[k]
+	if r == nil {
+		return nil
+	}
+	delete(s.Reservations, k)
+	cq := s.ClusterQueues[r.ClusterQueue]
+	updateUsage(r, cq.UsedResources, -1)
+	cq.updateTierUsage(r, -1)
+	for cohort := cq.Cohort; cohort != nil; cohort = cohort.Parent {
+		updateUsage(r, cohort.UsedResources, -1)
+	}
+	return r
+}
+
+// AddReservation adds a reservation removed with RemoveReservation back to
+// the usage of its ClusterQueue and cohort.
+func (s *Snapshot) AddReservation(r *workload.Info) {
+	if s.Reservations == nil {
+		s.Reservations = make(map[string]*workload.Info)
+	}
+	s.Reservations[workload.Key(r.Obj)] = r
+	cq := s.ClusterQueues[r.ClusterQueue]
+	updateUsage(r, cq.UsedResources, 1)
+	cq.updateTierUsage(r, 1)
+	for cohort := cq.Cohort; cohort != nil; cohort = cohort.Parent {
+		updateUsage(r, cohort.UsedResources, 1)
+	}
+}
+
+// AddWorkload adds a workload to its corresponding ClusterQueue and updates
+// the resource usage of the ClusterQueue and its cohort.
+func (s *Snapshot) AddWorkload(wl *workload.Info) {
+	cq := s.ClusterQueues[wl.ClusterQueue]
+	cq.Workloads[workload.Key(wl.Obj)] = wl
+	updateUsage(wl, cq.UsedResources, 1)
+	cq.updateTierUsage(wl, 1)
+	for cohort := cq.Cohort; cohort != nil; cohort = cohort.Parent {
+		updateUsage(wl, cohort.UsedResources, 1)
+	}
 }
 
 func (c *Cache) Snapshot() Snapshot {
@@ -45,12 +111,29 @@ func (c *Cache) Snapshot() Snapshot {
 		}
 		snap.ClusterQueues[cq.Name] = cq.snapshot()
 	}
+	for k, r := range c.reservations {
+		cq := snap.ClusterQueues[r.ClusterQueue]
+		if cq == nil {
+			continue
+		}
+		// The usage of the cohorts is accumulated below.
+		updateUsage(r, cq.UsedResources, 1)
+		cq.updateTierUsage(r, 1)
+		if snap.Reservations == nil {
+			snap.Reservations = make(map[string]*workload.Info)
+		}
+		snap.Reservations[k] = r
+	}
 	for _, rf := range c.resourceFlavors {
 		// Shallow copy is enough
 		snap.ResourceFlavors[rf.Name] = rf
 	}
+	cohorts := make(map[string]*Cohort, len(c.cohorts))
 	for _, cohort := range c.cohorts {
 		cohortCopy := newCohort(cohort.Name, len(cohort.members))
+		cohortCopy.RequestableResources = addQuantities(nil, cohort.quota)
+		// Shallow copy is enough, the limits are replaced on update.
+		cohortCopy.MaxResources = cohort.limits
 		for cq := range cohort.members {
 			if cq.Active() {
 				cqCopy := snap.ClusterQueues[cq.Name]
@@ -59,21 +142,85 @@ func (c *Cache) Snapshot() Snapshot {
 				cohortCopy.members[cqCopy] = struct{}{}
 			}
 		}
+		cohorts[cohort.Name] = cohortCopy
+	}
+	parents := c.cohortParents()
+	for name, parentName := range parents {
+		parent := cohorts[parentName]
+		if parent == nil {
+			// The parent has neither ClusterQueues nor a Cohort object.
+			parent = newCohort(parentName, 0)
+			cohorts[parentName] = parent
+		}
+		cohorts[name].Parent = parent
+	}
+	// The quota and usage of a cohort include those of its descendants.
+	// Accumulate them from the values of each cohort before any of them is
+	// added up.
+	ownRequestable := make(map[*Cohort]ResourceQuantities, len(parents))
+	ownUsed := make(map[*Cohort]ResourceQuantities, len(parents))
+	for name := range parents {
+		cohort := cohorts[name]
+		ownRequestable[cohort] = addQuantities(nil, cohort.RequestableResources)
+		ownUsed[cohort] = addQuantities(nil, cohort.UsedResources)
+	}
+	for cohort, requestable := range ownRequestable {
+		for ancestor := cohort.Parent; ancestor != nil; ancestor = ancestor.Parent {
+			ancestor.RequestableResources = addQuantities(ancestor.RequestableResources, requestable)
+			ancestor.UsedResources = addQuantities(ancestor.UsedResources, ownUsed[cohort])
+		}
 	}
 	return snap
 }
 
+// addQuantities adds the quantities in src to dst, which is created if nil,
+// and returns dst.
+func addQuantities(dst, src ResourceQuantities) ResourceQuantities {
+	if len(src) == 0 {
+		return dst
+	}
+	if dst == nil {
+		dst = make(ResourceQuantities, len(src))
+	}
+	for rName, flavors := range src {
+		for flavor, v := range flavors {
+			addQuantity(dst, rName, flavor, v)
+		}
+	}
+	return dst
+}
+
 // Snapshot creates a copy of ClusterQueue that includes references to immutable
 // objects and deep copies of changing ones. A reference to the cohort is not included.
 func (c *ClusterQueue) snapshot() *ClusterQueue {
 	cc := &ClusterQueue{
-		Name:                 c.Name,
-		RequestableResources: c.RequestableResources, // Shallow copy is enough.
-		UsedResources:        make(ResourceQuantities, len(c.UsedResources)),
-		Workloads:            make(map[string]*workload.Info, len(c.Workloads)),
-		LabelKeys:            c.LabelKeys, // Shallow copy is enough.
-		NamespaceSelector:    c.NamespaceSelector,
-		Status:               c.Status,
+		Name:                       c.Name,
+		RequestableResources:       c.RequestableResources, // Shallow copy is enough.
+		UsedResources:              make(ResourceQuantities, len(c.UsedResources)),
+		Workloads:                  make(map[string]*workload.Info, len(c.Workloads)),
+		NamespaceSelector:          c.NamespaceSelector,
+		LabelKeys:                  c.LabelKeys, // Shallow copy is enough.
+		Status:                     c.Status,
+		Preemption:                 c.Preemption,
+		FairWeight:                 c.FairWeight,
+		Backfill:                   c.Backfill,
+		HeadBlockingTimeout:        c.HeadBlockingTimeout,
+		StrictFIFO:                 c.StrictFIFO,
+		BestFit:                    c.BestFit,
+		LowestCost:                 c.LowestCost,
+		TryNextFlavorWhenCanBorrow: c.TryNextFlavorWhenCanBorrow,
+		PreemptWhenCanPreempt:      c.PreemptWhenCanPreempt,
+		SplitWhenNoFlavorFits:      c.SplitWhenNoFlavorFits,
+		RevocableBorrowing:         c.RevocableBorrowing,
+		BorrowingMinPriority:       c.BorrowingMinPriority,
+		Policies:                   c.Policies,
+		PriorityBands:              c.PriorityBands,
+		ReservedTiers:              c.ReservedTiers,
+		FlavorFallbacks:            c.FlavorFallbacks,
+		ExhaustedBudgets:           c.exhaustedBudgets(time.Now()),
+		AdmissionChecks:            c.AdmissionChecks,
+		FlavorAdmissionChecks:      c.FlavorAdmissionChecks, // Shallow copy is enough.
+		MaxWorkloadsPendingChecks:  c.MaxWorkloadsPendingChecks,
 	}
 	for res, flavors := range c.UsedResources {
 		flavorsCopy := make(map[string]int64, len(flavors))
@@ -86,9 +233,101 @@ func (c *ClusterQueue) snapshot() *ClusterQueue {
 		// Shallow copy is enough.
 		cc.Workloads[k] = v
 	}
+	if len(c.WorkloadsNotReady) > 0 {
+		cc.WorkloadsNotReady = sets.NewString(c.WorkloadsNotReady.UnsortedList()...)
+	}
+	if c.TierUsage != nil {
+		cc.TierUsage = make(map[string]ResourceQuantities, len(c.TierUsage))
+		for tier, usage := range c.TierUsage {
+			cc.TierUsage[tier] = addQuantities(nil, usage)
+		}
+	}
+	if c.usageHalfLife > 0 {
+		history := c.historicalUsageAt(time.Now())
+		cc.HistoricalUsage = make(map[corev1.ResourceName]int64, len(history))
+		for rName, v := range history {
+			cc.HistoricalUsage[rName] = int64(math.Round(v))
+		}
+	}
+	if len(c.LocalQueueLimits) > 0 {
+		cc.LocalQueueLimits = make(map[string]workload.Requests, len(c.LocalQueueLimits))
+		for k, v := range c.LocalQueueLimits {
+			// Shallow copy is enough, the limits are replaced on update.
+			cc.LocalQueueLimits[k] = v
+		}
+	}
+	if len(c.LocalQueueLimitsPercent) > 0 {
+		cc.LocalQueueLimitsPercent = make(map[string]map[corev1.ResourceName]int32, len(c.LocalQueueLimitsPercent))
+		for k, v := range c.LocalQueueLimitsPercent {
+			// Shallow copy is enough, the limits are replaced on update.
+			cc.LocalQueueLimitsPercent[k] = v
+		}
+	}
 	return cc
 }
 
+// DominantResourceShare returns the highest share, in per mille, of the
+// quota of the tree of cohorts that the ClusterQueue uses for a resource, divided by
+// the fair sharing weight of the ClusterQueue, along with the name of that
+// resource. For a ClusterQueue without a cohort, the share is relative to its
+// own min quota. It is only meaningful for a snapshot.
+func (c *ClusterQueue) DominantResourceShare() (int, corev1.ResourceName) {
+	usage := make(map[corev1.ResourceName]int64, len(c.UsedResources))
+	for rName, flavors := range c.UsedResources {
+		for _, v := range flavors {
+			usage[rName] += v
+		}
+	}
+	return c.dominantShare(usage)
+}
+
+// FairShare returns the weighted share that orders the ClusterQueues with
+// fair sharing: the dominant resource share of the historical usage, when it's
+// tracked, or of the current usage.
+func (c *ClusterQueue) FairShare() int {
+	if c.HistoricalUsage != nil {
+		share, _ := c.dominantShare(c.HistoricalUsage)
+		return share
+	}
+	share, _ := c.DominantResourceShare()
+	return share
+}
+
+// dominantShare returns the highest weighted share, in per mille, of the
+// given usage of each resource, along with the name of that resource.
+func (c *ClusterQueue) dominantShare(usage map[corev1.ResourceName]int64) (int, corev1.ResourceName) {
+	var drs int
+	var dRes corev1.ResourceName
+	for rName, used := range usage {
+		var total int64
+		if c.Cohort != nil {
+			for _, v := range c.Cohort.Root().RequestableResources[rName] {
+				total += v
+			}
+		} else if res := c.RequestableResources[rName]; res != nil {
+			for _, flv := range res.Flavors {
+				total += flv.Min
+			}
+		}
+		if total == 0 {
+			continue
+		}
+		share := int(used * 1000 / total)
+		if share > drs || (share == drs && share > 0 && rName < dRes) {
+			drs = share
+			dRes = rName
+		}
+	}
+	if drs == 0 {
+		return 0, dRes
+	}
+	if c.FairWeight == 0 {
+		// A ClusterQueue without weight using resources has the highest share.
+		return math.MaxInt, dRes
+	}
+	return int(int64(drs) * 1000 / c.FairWeight), dRes
+}
+
 func (c *ClusterQueue) accumulateResources(cohort *Cohort) {
 	if cohort.RequestableResources == nil {
 		cohort.RequestableResources = make(ResourceQuantities, len(c.RequestableResources))
@@ -18,6 +18,7 @@ package cache
 
 import (
 	"context"
+	"math"
 	"testing"
 
 	"github.com/google/go-cmp/cmp"
@@ -31,6 +32,7 @@ import (
 	"sigs.k8s.io/controller-runtime/pkg/client/fake"
 
 	kueue "sigs.k8s.io/kueue/apis/kueue/v1alpha2"
+	"sigs.k8s.io/kueue/pkg/util/pointer"
 	utiltesting "sigs.k8s.io/kueue/pkg/util/testing"
 	"sigs.k8s.io/kueue/pkg/workload"
 )
@@ -75,6 +77,9 @@ func TestSnapshot(t *testing.T) {
 			},
 			Spec: kueue.ClusterQueueSpec{
 				Cohort: "foo",
+				FairSharing: &kueue.FairSharing{
+					Weight: pointer.Quantity(resource.MustParse("2")),
+				},
 				Resources: []kueue.Resource{
 					{
 						Name: corev1.ResourceCPU,
@@ -323,6 +328,7 @@ func TestSnapshot(t *testing.T) {
 				LabelKeys:         map[corev1.ResourceName]sets.String{corev1.ResourceCPU: {"baz": {}, "foo": {}, "instance": {}}},
 				NamespaceSelector: labels.Nothing(),
 				Status:            active,
+				FairWeight:        1000,
 			},
 			"foobar": {
 				Name:   "foobar",
@@ -360,6 +366,7 @@ func TestSnapshot(t *testing.T) {
 				NamespaceSelector: labels.Nothing(),
 				LabelKeys:         map[corev1.ResourceName]sets.String{corev1.ResourceCPU: {"baz": {}, "instance": {}}},
 				Status:            active,
+				FairWeight:        2000,
 			},
 			"bar": {
 				Name: "bar",
@@ -379,6 +386,7 @@ func TestSnapshot(t *testing.T) {
 				Workloads:         map[string]*workload.Info{},
 				NamespaceSelector: labels.Nothing(),
 				Status:            active,
+				FairWeight:        1000,
 			},
 		},
 		ResourceFlavors: map[string]*kueue.ResourceFlavor{
@@ -400,7 +408,437 @@ func TestSnapshot(t *testing.T) {
 		},
 		InactiveClusterQueueSets: sets.String{"flavor-nonexistent-cq": {}},
 	}
-	if diff := cmp.Diff(wantSnapshot, snapshot, cmpopts.IgnoreUnexported(Cohort{}, ClusterQueue{})); diff != "" {
+	// The policies are covered by TestSnapshotClusterQueueSettings.
+	if diff := cmp.Diff(wantSnapshot, snapshot, cmpopts.IgnoreUnexported(Cohort{}, ClusterQueue{}), cmpopts.IgnoreFields(ClusterQueue{}, "Policies")); diff != "" {
 		t.Errorf("Unexpected Snapshot (-want,+got):\n%s", diff)
 	}
 }
+
+func TestSnapshotCohortTree(t *testing.T) {
+	scheme := runtime.NewScheme()
+	if err := kueue.AddToScheme(scheme); err != nil {
+		t.Fatalf("Failed adding kueue scheme: %s", err)
+	}
+	cache := New(fake.NewClientBuilder().WithScheme(scheme).Build())
+	cache.AddOrUpdateResourceFlavor(utiltesting.MakeResourceFlavor("default").Obj())
+	ctx := context.Background()
+	cqs := []*kueue.ClusterQueue{
+		utiltesting.MakeClusterQueue("a").Cohort("left").
+			Resource(utiltesting.MakeResource(corev1.ResourceCPU).
+				Flavor(utiltesting.MakeFlavor("default", "10").Obj()).Obj()).
+			Obj(),
+		utiltesting.MakeClusterQueue("b").Cohort("right").
+			Resource(utiltesting.MakeResource(corev1.ResourceCPU).
+				Flavor(utiltesting.MakeFlavor("default", "5").Obj()).Obj()).
+			Obj(),
+		utiltesting.MakeClusterQueue("c").Cohort("loop-a").
+			Resource(utiltesting.MakeResource(corev1.ResourceCPU).
+				Flavor(utiltesting.MakeFlavor("default", "5").Obj()).Obj()).
+			Obj(),
+	}
+	for _, cq := range cqs {
+		if err := cache.AddClusterQueue(ctx, cq); err != nil {
+			t.Fatalf("Failed adding ClusterQueue: %v", err)
+		}
+	}
+	cohorts := []*kueue.Cohort{
+		utiltesting.MakeCohort("left").Parent("root").Quota(corev1.ResourceCPU, "default", "4", "12").Obj(),
+		utiltesting.MakeCohort("right").Parent("root").Obj(),
+		utiltesting.MakeCohort("loop-a").Parent("loop-b").Obj(),
+		utiltesting.MakeCohort("loop-b").Parent("loop-a").Obj(),
+	}
+	for _, cohort := range cohorts {
+		cache.AddOrUpdateCohort(cohort)
+	}
+	cache.AddOrUpdateWorkload(utiltesting.MakeWorkload("a1", "ns").Request(corev1.ResourceCPU, "8").
+		Admit(utiltesting.MakeAdmission("a").Flavor(corev1.ResourceCPU, "default").Obj()).Obj())
+
+	snapshot := cache.Snapshot()
+	snapshot.AddWorkload(workload.NewInfo(utiltesting.MakeWorkload("b1", "ns").Request(corev1.ResourceCPU, "2").
+		Admit(utiltesting.MakeAdmission("b").Flavor(corev1.ResourceCPU, "default").Obj()).Obj()))
+
+	type cohortState struct {
+		Parent               string
+		RequestableResources ResourceQuantities
+		UsedResources        ResourceQuantities
+		MaxResources         ResourceQuantities
+	}
+	got := make(map[string]cohortState)
+	for _, cq := range snapshot.ClusterQueues {
+		for cohort := cq.Cohort; cohort != nil; cohort = cohort.Parent {
+			var parent string
+			if cohort.Parent != nil {
+				parent = cohort.Parent.Name
+			}
+			got[cohort.Name] = cohortState{
+				Parent:               parent,
+				RequestableResources: cohort.RequestableResources,
+				UsedResources:        cohort.UsedResources,
+				MaxResources:         cohort.MaxResources,
+			}
+		}
+	}
+	want := map[string]cohortState{
+		"left": {
+			Parent:               "root",
+			RequestableResources: ResourceQuantities{corev1.ResourceCPU: {"default": 14_000}},
+			UsedResources:        ResourceQuantities{corev1.ResourceCPU: {"default": 8_000}},
+			MaxResources:         ResourceQuantities{corev1.ResourceCPU: {"default": 12_000}},
+		},
+		"right": {
+			Parent:               "root",
+			RequestableResources: ResourceQuantities{corev1.ResourceCPU: {"default": 5_000}},
+			UsedResources:        ResourceQuantities{corev1.ResourceCPU: {"default": 2_000}},
+		},
+		"root": {
+			RequestableResources: ResourceQuantities{corev1.ResourceCPU: {"default": 19_000}},
+			UsedResources:        ResourceQuantities{corev1.ResourceCPU: {"default": 10_000}},
+		},
+		// The parent of loop-b is ignored, as it would create a cycle.
+		"loop-a": {
+			Parent:               "loop-b",
+			RequestableResources: ResourceQuantities{corev1.ResourceCPU: {"default": 5_000}},
+			UsedResources:        ResourceQuantities{corev1.ResourceCPU: {"default": 0}},
+		},
+		"loop-b": {
+			RequestableResources: ResourceQuantities{corev1.ResourceCPU: {"default": 5_000}},
+			UsedResources:        ResourceQuantities{corev1.ResourceCPU: {"default": 0}},
+		},
+	}
+	if diff := cmp.Diff(want, got, cmpopts.EquateEmpty()); diff != "" {
+		t.Errorf("Unexpected cohorts (-want,+got):\n%s", diff)
+	}
+
+	left := snapshot.ClusterQueues["a"].Cohort
+	if c := left.ExceedingMax(corev1.ResourceCPU, "default", 4_000); c != nil {
+		t.Errorf("ExceedingMax(4_000) = %s, want nil", c.Name)
+	}
+	if c := left.ExceedingMax(corev1.ResourceCPU, "default", 5_000); c != left {
+		t.Errorf("ExceedingMax(5_000) = %v, want cohort left", c)
+	}
+	if root := left.Root(); root.Name != "root" {
+		t.Errorf("Root() = %s, want root", root.Name)
+	}
+}
+
+func TestSnapshotCohortMove(t *testing.T) {
+	scheme := runtime.NewScheme()
+	if err := kueue.AddToScheme(scheme); err != nil {
+		t.Fatalf("Failed adding kueue scheme: %s", err)
+	}
+	cache := New(fake.NewClientBuilder().WithScheme(scheme).Build())
+	cache.AddOrUpdateResourceFlavor(utiltesting.MakeResourceFlavor("default").Obj())
+	ctx := context.Background()
+	cqs := []*kueue.ClusterQueue{
+		utiltesting.MakeClusterQueue("a").Cohort("left").
+			Resource(utiltesting.MakeResource(corev1.ResourceCPU).
+				Flavor(utiltesting.MakeFlavor("default", "10").Obj()).Obj()).
+			Obj(),
+		utiltesting.MakeClusterQueue("b").Cohort("left").
+			Resource(utiltesting.MakeResource(corev1.ResourceCPU).
+				Flavor(utiltesting.MakeFlavor("default", "5").Obj()).Obj()).
+			Obj(),
+		utiltesting.MakeClusterQueue("c").Cohort("right").
+			Resource(utiltesting.MakeResource(corev1.ResourceCPU).
+				Flavor(utiltesting.MakeFlavor("default", "5").Obj()).Obj()).
+			Obj(),
+	}
+	for _, cq := range cqs {
+		if err := cache.AddClusterQueue(ctx, cq); err != nil {
+			t.Fatalf("Failed adding ClusterQueue: %v", err)
+		}
+	}
+	cache.AddOrUpdateWorkload(utiltesting.MakeWorkload("b1", "ns").Request(corev1.ResourceCPU, "8").
+		Admit(utiltesting.MakeAdmission("b").Flavor(corev1.ResourceCPU, "default").Obj()).Obj())
+
+	// Move b, which borrows from a, to the cohort of c.
+	moved := cqs[1].DeepCopy()
+	moved.Spec.Cohort = "right"
+	if err := cache.UpdateClusterQueue(moved); err != nil {
+		t.Fatalf("Failed updating ClusterQueue: %v", err)
+	}
+
+	type cohortState struct {
+		RequestableResources ResourceQuantities
+		UsedResources        ResourceQuantities
+	}
+	snapshot := cache.Snapshot()
+	got := make(map[string]cohortState)
+	for _, cq := range snapshot.ClusterQueues {
+		got[cq.Cohort.Name] = cohortState{
+			RequestableResources: cq.Cohort.RequestableResources,
+			UsedResources:        cq.Cohort.UsedResources,
+		}
+	}
+	want := map[string]cohortState{
+		"left": {
+			RequestableResources: ResourceQuantities{corev1.ResourceCPU: {"default": 10_000}},
+			UsedResources:        ResourceQuantities{corev1.ResourceCPU: {"default": 0}},
+		},
+		"right": {
+			RequestableResources: ResourceQuantities{corev1.ResourceCPU: {"default": 10_000}},
+			UsedResources:        ResourceQuantities{corev1.ResourceCPU: {"default": 8_000}},
+		},
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("Unexpected cohorts (-want,+got):\n%s", diff)
+	}
+	if diff := cmp.Diff([]string{"c"}, cache.ClusterQueuesInCohortOf("b")); diff != "" {
+		t.Errorf("Unexpected members of the new cohort (-want,+got):\n%s", diff)
+	}
+}
+
+func TestSnapshotClusterQueueSettings(t *testing.T) {
+	scheme := runtime.NewScheme()
+	if err := kueue.AddToScheme(scheme); err != nil {
+		t.Fatalf("Failed adding kueue scheme: %s", err)
+	}
+	cache := New(fake.NewClientBuilder().WithScheme(scheme).Build(), WithPodsReadyTracking(true))
+	cache.AddOrUpdateResourceFlavor(utiltesting.MakeResourceFlavor("on-demand").Obj())
+	cache.AddOrUpdateResourceFlavor(utiltesting.MakeResourceFlavor("spot").Obj())
+	ctx := context.Background()
+	cq := utiltesting.MakeClusterQueue("cq").
+		Resource(utiltesting.MakeResource(corev1.ResourceCPU).
+			Flavor(utiltesting.MakeFlavor("on-demand", "4").Obj()).
+			Flavor(utiltesting.MakeFlavor("spot", "4").Obj()).Obj()).
+		QueueingStrategy(kueue.StrictFIFO).
+		Obj()
+	cq.Spec.FlavorFungibility = &kueue.FlavorFungibility{WhenNoFlavorFits: kueue.SplitPodSet}
+	if err := cache.AddClusterQueue(ctx, cq); err != nil {
+		t.Fatalf("Failed adding ClusterQueue: %v", err)
+	}
+	cache.AddOrUpdateWorkload(utiltesting.MakeWorkload("not-ready", "ns").Request(corev1.ResourceCPU, "1").
+		Admit(utiltesting.MakeAdmission("cq").Flavor(corev1.ResourceCPU, "on-demand").Obj()).Obj())
+
+	snapshot := cache.Snapshot()
+	got := snapshot.ClusterQueues["cq"]
+	want := cache.clusterQueues["cq"]
+	if diff := cmp.Diff(want.Policies, got.Policies); diff != "" {
+		t.Errorf("Unexpected policies in the snapshot (-want,+got):\n%s", diff)
+	}
+	if !got.SplitWhenNoFlavorFits || !got.StrictFIFO {
+		t.Errorf("Snapshot has SplitWhenNoFlavorFits=%t, StrictFIFO=%t, want both true", got.SplitWhenNoFlavorFits, got.StrictFIFO)
+	}
+	if diff := cmp.Diff(sets.NewString("ns/not-ready"), got.WorkloadsNotReady); diff != "" {
+		t.Errorf("Unexpected workloads not ready in the snapshot (-want,+got):\n%s", diff)
+	}
+
+	// The snapshot doesn't share the changing state with the cache.
+	got.WorkloadsNotReady.Insert("ns/other")
+	got.UsedResources[corev1.ResourceCPU]["on-demand"] = 0
+	if want.WorkloadsNotReady.Has("ns/other") {
+		t.Error("Changing the workloads not ready of the snapshot changed the cache")
+	}
+	if used := want.UsedResources[corev1.ResourceCPU]["on-demand"]; used != 1_000 {
+		t.Errorf("Changing the usage of the snapshot changed the cache, usage is %d", used)
+	}
+	if got.Cohort != nil {
+		t.Errorf("Snapshot of a ClusterQueue without cohort has cohort %q", got.Cohort.Name)
+	}
+}
+
+func TestSnapshotMutationLeavesCache(t *testing.T) {
+	scheme := runtime.NewScheme()
+	if err := kueue.AddToScheme(scheme); err != nil {
+		t.Fatalf("Failed adding kueue scheme: %s", err)
+	}
+	cache := New(fake.NewClientBuilder().WithScheme(scheme).Build(), WithPodsReadyTracking(true))
+	cache.AddOrUpdateResourceFlavor(utiltesting.MakeResourceFlavor("default").Obj())
+	ctx := context.Background()
+	for _, cq := range []*kueue.ClusterQueue{
+		utiltesting.MakeClusterQueue("a").
+			Cohort("cohort").
+			Resource(utiltesting.MakeResource(corev1.ResourceCPU).
+				Flavor(utiltesting.MakeFlavor("default", "4").Obj()).Obj()).
+			Obj(),
+		utiltesting.MakeClusterQueue("b").
+			Cohort("cohort").
+			Resource(utiltesting.MakeResource(corev1.ResourceCPU).
+				Flavor(utiltesting.MakeFlavor("default", "4").Obj()).Obj()).
+			Obj(),
+	} {
+		if err := cache.AddClusterQueue(ctx, cq); err != nil {
+			t.Fatalf("Failed adding ClusterQueue: %v", err)
+		}
+	}
+	cache.AddOrUpdateWorkload(utiltesting.MakeWorkload("admitted", "ns").Request(corev1.ResourceCPU, "2").
+		Admit(utiltesting.MakeAdmission("a").Flavor(corev1.ResourceCPU, "default").Obj()).Obj())
+
+	wantSnapshot := cache.Snapshot()
+	snapshot := cache.Snapshot()
+	cq := snapshot.ClusterQueues["a"]
+	snapshot.RemoveWorkload(cq.Workloads["ns/admitted"])
+	snapshot.AddWorkload(workload.NewInfo(utiltesting.MakeWorkload("new", "ns").Request(corev1.ResourceCPU, "3").
+		Admit(utiltesting.MakeAdmission("b").Flavor(corev1.ResourceCPU, "default").Obj()).Obj()))
+	cq.WorkloadsNotReady = sets.NewString("ns/new")
+
+	if diff := cmp.Diff(wantSnapshot, cache.Snapshot(), cmpopts.IgnoreUnexported(Cohort{}, ClusterQueue{})); diff != "" {
+		t.Errorf("Changing the snapshot changed the cache (-want,+got):\n%s", diff)
+	}
+}
+
+func TestDominantResourceShare(t *testing.T) {
+	cohort := &Cohort{
+		Name: "cohort",
+		RequestableResources: ResourceQuantities{
+			corev1.ResourceCPU: {
+				"on-demand": 6,
+				"spot":      4,
+			},
+			corev1.ResourceMemory: {
+				"default": 100,
+			},
+		},
+	}
+	cases := map[string]struct {
+		cq        ClusterQueue
+		wantShare int
+		wantRes   corev1.ResourceName
+	}{
+		"no usage": {
+			cq: ClusterQueue{
+				Cohort:     cohort,
+				FairWeight: 1000,
+				UsedResources: ResourceQuantities{
+					corev1.ResourceCPU: {"on-demand": 0, "spot": 0},
+				},
+			},
+		},
+		"cpu is dominant": {
+			cq: ClusterQueue{
+				Cohort:     cohort,
+				FairWeight: 1000,
+				UsedResources: ResourceQuantities{
+					corev1.ResourceCPU:    {"on-demand": 3, "spot": 2},
+					corev1.ResourceMemory: {"default": 20},
+				},
+			},
+			wantShare: 500,
+			wantRes:   corev1.ResourceCPU,
+		},
+		"memory is dominant": {
+			cq: ClusterQueue{
+				Cohort:     cohort,
+				FairWeight: 1000,
+				UsedResources: ResourceQuantities{
+					corev1.ResourceCPU:    {"on-demand": 1},
+					corev1.ResourceMemory: {"default": 75},
+				},
+			},
+			wantShare: 750,
+			wantRes:   corev1.ResourceMemory,
+		},
+		"without cohort": {
+			cq: ClusterQueue{
+				FairWeight: 1000,
+				RequestableResources: map[corev1.ResourceName]*Resource{
+					corev1.ResourceCPU: {
+						Flavors: []FlavorLimits{
+							{Name: "on-demand", Min: 3},
+							{Name: "spot", Min: 1},
+						},
+					},
+				},
+				UsedResources: ResourceQuantities{
+					corev1.ResourceCPU: {"on-demand": 1, "spot": 1},
+				},
+			},
+			wantShare: 500,
+			wantRes:   corev1.ResourceCPU,
+		},
+		"weighted": {
+			cq: ClusterQueue{
+				Cohort:     cohort,
+				FairWeight: 2000,
+				UsedResources: ResourceQuantities{
+					corev1.ResourceCPU: {"on-demand": 3, "spot": 2},
+				},
+			},
+			wantShare: 250,
+			wantRes:   corev1.ResourceCPU,
+		},
+		"zero weight": {
+			cq: ClusterQueue{
+				Cohort: cohort,
+				UsedResources: ResourceQuantities{
+					corev1.ResourceCPU: {"on-demand": 1},
+				},
+			},
+			wantShare: math.MaxInt,
+			wantRes:   corev1.ResourceCPU,
+		},
+		"zero weight without usage": {
+			cq: ClusterQueue{
+				Cohort: cohort,
+				UsedResources: ResourceQuantities{
+					corev1.ResourceCPU: {"on-demand": 0},
+				},
+			},
+		},
+	}
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			share, res := tc.cq.DominantResourceShare()
+			if share != tc.wantShare || res != tc.wantRes {
+				t.Errorf("DominantResourceShare() = %d, %q, want %d, %q", share, res, tc.wantShare, tc.wantRes)
+			}
+		})
+	}
+}
+
+func TestFairShare(t *testing.T) {
+	cohort := &Cohort{
+		Name: "cohort",
+		RequestableResources: ResourceQuantities{
+			corev1.ResourceCPU: {"default": 10},
+		},
+	}
+	cases := map[string]struct {
+		cq        ClusterQueue
+		wantShare int
+	}{
+		"current usage": {
+			cq: ClusterQueue{
+				Cohort:     cohort,
+				FairWeight: 1000,
+				UsedResources: ResourceQuantities{
+					corev1.ResourceCPU: {"default": 2},
+				},
+			},
+			wantShare: 200,
+		},
+		"historical usage": {
+			cq: ClusterQueue{
+				Cohort:     cohort,
+				FairWeight: 1000,
+				UsedResources: ResourceQuantities{
+					corev1.ResourceCPU: {"default": 2},
+				},
+				HistoricalUsage: map[corev1.ResourceName]int64{
+					corev1.ResourceCPU: 7,
+				},
+			},
+			wantShare: 700,
+		},
+		"idle with historical usage": {
+			cq: ClusterQueue{
+				Cohort:     cohort,
+				FairWeight: 2000,
+				UsedResources: ResourceQuantities{
+					corev1.ResourceCPU: {"default": 0},
+				},
+				HistoricalUsage: map[corev1.ResourceName]int64{
+					corev1.ResourceCPU: 4,
+				},
+			},
+			wantShare: 200,
+		},
+	}
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			if got := tc.cq.FairShare(); got != tc.wantShare {
+				t.Errorf("FairShare() = %d, want %d", got, tc.wantShare)
+			}
+		})
+	}
+}
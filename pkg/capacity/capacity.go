@@ -0,0 +1,247 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package capacity periodically takes snapshots of the queue depth, usage and
+// borrowing of the ClusterQueues, to build capacity planning reports.
+package capacity
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	ctrl "sigs.k8s.io/controller-runtime"
+
+	kueue "sigs.k8s.io/kueue/apis/kueue/v1alpha2"
+	"sigs.k8s.io/kueue/pkg/cache"
+	"sigs.k8s.io/kueue/pkg/queue"
+	"sigs.k8s.io/kueue/pkg/util/useragent"
+	"sigs.k8s.io/kueue/pkg/workload"
+)
+
+// Sample is the state of the active ClusterQueues at a point in time.
+type Sample struct {
+	Time metav1.Time `json:"time"`
+	// Weekday and Hour are the day of the week and the hour of the day of
+	// Time, in the time zone of the snapshotter.
+	Weekday string `json:"weekday"`
+	Hour    int    `json:"hour"`
+
+	ClusterQueues []ClusterQueueSample `json:"clusterQueues"`
+}
+
+// ClusterQueueSample is the state of a ClusterQueue at a point in time.
+type ClusterQueueSample struct {
+	Name   string `json:"name"`
+	Cohort string `json:"cohort,omitempty"`
+	// PendingWorkloads is the queue depth of the ClusterQueue.
+	PendingWorkloads  int `json:"pendingWorkloads"`
+	AdmittedWorkloads int `json:"admittedWorkloads"`
+	// Flavors holds the quota, usage and borrowing of each resource and
+	// flavor of the ClusterQueue, ordered by resource and flavor.
+	Flavors []FlavorSample `json:"flavors,omitempty"`
+}
+
+// FlavorSample is the quota, usage and borrowing of a resource in a flavor.
+type FlavorSample struct {
+	Resource corev1.ResourceName `json:"resource"`
+	Flavor   string              `json:"flavor"`
+	Min      resource.Quantity   `json:"min"`
+	Used     resource.Quantity   `json:"used"`
+	// Borrowed is the usage above the min quota.
+	Borrowed resource.Quantity `json:"borrowed"`
+}
+
+// Sink stores the samples outside of the cluster.
+type Sink interface {
+	Record(ctx context.Context, s *Sample) error
+}
+
+type webhookSink struct {
+	url    string
+	client *http.Client
+}
+
+// NewWebhook returns a Sink that sends each sample, encoded as JSON, in an
+// HTTP POST request to url.
+func NewWebhook(url string, timeout time.Duration) Sink {
+	return &webhookSink{
+		url:    url,
+		client: &http.Client{Timeout: timeout},
+	}
+}
+
+func (w *webhookSink) Record(ctx context.Context, s *Sample) error {
+	body, err := json.Marshal(s)
+	if err != nil {
+		return fmt.Errorf("encoding sample: %w", err)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, w.url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", useragent.Default())
+	resp, err := w.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("unexpected response status from %s: %s", w.url, resp.Status)
+	}
+	return nil
+}
+
+// Snapshotter takes a sample of the ClusterQueues at the multiples of its
+// interval since midnight, and records it in a sink.
+type Snapshotter struct {
+	cache    *cache.Cache
+	queues   *queue.Manager
+	sink     Sink
+	interval time.Duration
+	location *time.Location
+}
+
+type options struct {
+	interval time.Duration
+	location *time.Location
+}
+
+// Option configures the snapshotter.
+type Option func(*options)
+
+// WithInterval sets the time between samples.
+func WithInterval(d time.Duration) Option {
+	return func(o *options) {
+		o.interval = d
+	}
+}
+
+// WithLocation sets the time zone in which the samples are aligned and
+// their day of the week and hour are reported.
+func WithLocation(l *time.Location) Option {
+	return func(o *options) {
+		o.location = l
+	}
+}
+
+var defaultOptions = options{
+	interval: 15 * time.Minute,
+	location: time.UTC,
+}
+
+func New(cache *cache.Cache, queues *queue.Manager, sink Sink, opts ...Option) *Snapshotter {
+	options := defaultOptions
+	for _, opt := range opts {
+		opt(&options)
+	}
+	return &Snapshotter{
+		cache:    cache,
+		queues:   queues,
+		sink:     sink,
+		interval: options.interval,
+		location: options.location,
+	}
+}
+
+func (s *Snapshotter) Start(ctx context.Context) {
+	log := ctrl.LoggerFrom(ctx).WithName("capacity-snapshotter")
+	for {
+		next := nextSampleTime(time.Now(), s.interval, s.location)
+		timer := time.NewTimer(time.Until(next))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return
+		case <-timer.C:
+		}
+		if err := s.sink.Record(ctx, s.sample(next)); err != nil {
+			log.Error(err, "Failed to record a capacity snapshot", "time", next)
+		}
+	}
+}
+
+// nextSampleTime returns the first multiple of the interval since midnight,
+// in the location, after now.
+func nextSampleTime(now time.Time, interval time.Duration, loc *time.Location) time.Time {
+	now = now.In(loc)
+	y, m, d := now.Date()
+	midnight := time.Date(y, m, d, 0, 0, 0, 0, loc)
+	elapsed := now.Sub(midnight)
+	next := midnight.Add((elapsed/interval + 1) * interval)
+	if nextMidnight := midnight.AddDate(0, 0, 1); next.After(nextMidnight) {
+		// The day isn't a multiple of the interval, or it's shorter because of
+		// a daylight saving time change.
+		next = nextMidnight
+	}
+	return next
+}
+
+func (s *Snapshotter) sample(t time.Time) *Sample {
+	t = t.In(s.location)
+	sample := &Sample{
+		Time:    metav1.NewTime(t),
+		Weekday: t.Weekday().String(),
+		Hour:    t.Hour(),
+	}
+	snapshot := s.cache.Snapshot()
+	for _, cq := range snapshot.ClusterQueues {
+		cqSample := ClusterQueueSample{
+			Name:              cq.Name,
+			PendingWorkloads:  s.queues.Pending(&kueue.ClusterQueue{ObjectMeta: metav1.ObjectMeta{Name: cq.Name}}),
+			AdmittedWorkloads: len(cq.Workloads),
+		}
+		if cq.Cohort != nil {
+			cqSample.Cohort = cq.Cohort.Name
+		}
+		for rName, res := range cq.RequestableResources {
+			for _, flv := range res.Flavors {
+				used := cq.UsedResources[rName][flv.Name]
+				borrowed := used - flv.Min
+				if borrowed < 0 {
+					borrowed = 0
+				}
+				cqSample.Flavors = append(cqSample.Flavors, FlavorSample{
+					Resource: rName,
+					Flavor:   flv.Name,
+					Min:      workload.ResourceQuantity(rName, flv.Min),
+					Used:     workload.ResourceQuantity(rName, used),
+					Borrowed: workload.ResourceQuantity(rName, borrowed),
+				})
+			}
+		}
+		sort.Slice(cqSample.Flavors, func(i, j int) bool {
+			a, b := cqSample.Flavors[i], cqSample.Flavors[j]
+			if a.Resource != b.Resource {
+				return a.Resource < b.Resource
+			}
+			return a.Flavor < b.Flavor
+		})
+		sample.ClusterQueues = append(sample.ClusterQueues, cqSample)
+	}
+	sort.Slice(sample.ClusterQueues, func(i, j int) bool {
+		return sample.ClusterQueues[i].Name < sample.ClusterQueues[j].Name
+	})
+	return sample
+}
@@ -0,0 +1,221 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package capacity
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	kueue "sigs.k8s.io/kueue/apis/kueue/v1alpha2"
+	"sigs.k8s.io/kueue/pkg/cache"
+	"sigs.k8s.io/kueue/pkg/queue"
+	utiltesting "sigs.k8s.io/kueue/pkg/util/testing"
+)
+
+func TestNextSampleTime(t *testing.T) {
+	madrid, err := time.LoadLocation("Europe/Madrid")
+	if err != nil {
+		t.Fatalf("Loading time zone: %v", err)
+	}
+	cases := map[string]struct {
+		now      time.Time
+		interval time.Duration
+		loc      *time.Location
+		want     time.Time
+	}{
+		"next quarter hour": {
+			now:      time.Date(2022, 10, 14, 15, 7, 30, 0, time.UTC),
+			interval: 15 * time.Minute,
+			loc:      time.UTC,
+			want:     time.Date(2022, 10, 14, 15, 15, 0, 0, time.UTC),
+		},
+		"on a multiple of the interval": {
+			now:      time.Date(2022, 10, 14, 15, 15, 0, 0, time.UTC),
+			interval: 15 * time.Minute,
+			loc:      time.UTC,
+			want:     time.Date(2022, 10, 14, 15, 30, 0, 0, time.UTC),
+		},
+		"aligned to the midnight of the time zone": {
+			now:      time.Date(2022, 10, 14, 15, 7, 0, 0, time.UTC),
+			interval: 5 * time.Hour,
+			loc:      madrid,
+			want:     time.Date(2022, 10, 14, 20, 0, 0, 0, madrid),
+		},
+		"interval that doesn't divide the day": {
+			now:      time.Date(2022, 10, 14, 22, 0, 0, 0, time.UTC),
+			interval: 7 * time.Hour,
+			loc:      time.UTC,
+			want:     time.Date(2022, 10, 15, 0, 0, 0, 0, time.UTC),
+		},
+	}
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			if got := nextSampleTime(tc.now, tc.interval, tc.loc); !got.Equal(tc.want) {
+				t.Errorf("nextSampleTime(_) = %v, want %v", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestSample(t *testing.T) {
+	ctx := context.Background()
+	scheme := runtime.NewScheme()
+	if err := kueue.AddToScheme(scheme); err != nil {
+		t.Fatalf("Failed adding kueue scheme: %v", err)
+	}
+	clusterQueues := []kueue.ClusterQueue{
+		*utiltesting.MakeClusterQueue("a").
+			Cohort("cohort").
+			Resource(utiltesting.MakeResource(corev1.ResourceCPU).
+				Flavor(utiltesting.MakeFlavor("default", "4").Obj()).Obj()).
+			Obj(),
+		*utiltesting.MakeClusterQueue("b").
+			Cohort("cohort").
+			Resource(utiltesting.MakeResource(corev1.ResourceCPU).
+				Flavor(utiltesting.MakeFlavor("default", "4").Obj()).Obj()).
+			Obj(),
+	}
+	queues := []kueue.LocalQueue{
+		*utiltesting.MakeLocalQueue("a", "ns").ClusterQueue("a").Obj(),
+		*utiltesting.MakeLocalQueue("b", "ns").ClusterQueue("b").Obj(),
+	}
+	workloads := []kueue.Workload{
+		*utiltesting.MakeWorkload("a1", "ns").Queue("a").Request(corev1.ResourceCPU, "1").Obj(),
+		*utiltesting.MakeWorkload("a2", "ns").Queue("a").Request(corev1.ResourceCPU, "1").Obj(),
+		*utiltesting.MakeWorkload("b1", "ns").Queue("b").Request(corev1.ResourceCPU, "6").
+			Admit(utiltesting.MakeAdmission("b").Flavor(corev1.ResourceCPU, "default").Obj()).Obj(),
+	}
+	cl := fake.NewClientBuilder().WithScheme(scheme).
+		WithLists(&kueue.WorkloadList{Items: workloads}, &kueue.LocalQueueList{Items: queues}).
+		Build()
+	cqCache := cache.New(cl)
+	qManager := queue.NewManager(cl, cqCache)
+	cqCache.AddOrUpdateResourceFlavor(utiltesting.MakeResourceFlavor("default").Obj())
+	for i := range clusterQueues {
+		if err := cqCache.AddClusterQueue(ctx, &clusterQueues[i]); err != nil {
+			t.Fatalf("Inserting clusterQueue %s in cache: %v", clusterQueues[i].Name, err)
+		}
+		if err := qManager.AddClusterQueue(ctx, &clusterQueues[i]); err != nil {
+			t.Fatalf("Inserting clusterQueue %s in manager: %v", clusterQueues[i].Name, err)
+		}
+	}
+	for i := range queues {
+		if err := qManager.AddLocalQueue(ctx, &queues[i]); err != nil {
+			t.Fatalf("Inserting queue %s/%s in manager: %v", queues[i].Namespace, queues[i].Name, err)
+		}
+	}
+
+	madrid, err := time.LoadLocation("Europe/Madrid")
+	if err != nil {
+		t.Fatalf("Loading time zone: %v", err)
+	}
+	now := time.Date(2022, 10, 14, 15, 0, 0, 0, time.UTC)
+	s := New(cqCache, qManager, nil, WithLocation(madrid))
+	got := s.sample(now)
+	want := &Sample{
+		Time:    metav1.NewTime(now.In(madrid)),
+		Weekday: "Friday",
+		Hour:    17,
+		ClusterQueues: []ClusterQueueSample{
+			{
+				Name:             "a",
+				Cohort:           "cohort",
+				PendingWorkloads: 2,
+				Flavors: []FlavorSample{{
+					Resource: corev1.ResourceCPU,
+					Flavor:   "default",
+					Min:      resource.MustParse("4"),
+					Used:     resource.MustParse("0"),
+					Borrowed: resource.MustParse("0"),
+				}},
+			},
+			{
+				Name:              "b",
+				Cohort:            "cohort",
+				AdmittedWorkloads: 1,
+				Flavors: []FlavorSample{{
+					Resource: corev1.ResourceCPU,
+					Flavor:   "default",
+					Min:      resource.MustParse("4"),
+					Used:     resource.MustParse("6"),
+					Borrowed: resource.MustParse("2"),
+				}},
+			},
+		},
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("Unexpected sample (-want,+got):\n%s", diff)
+	}
+}
+
+func TestWebhookRecord(t *testing.T) {
+	sample := &Sample{
+		Time:    metav1.NewTime(time.Date(2022, 10, 14, 15, 0, 0, 0, time.UTC)),
+		Weekday: "Friday",
+		Hour:    15,
+		ClusterQueues: []ClusterQueueSample{{
+			Name:             "cq",
+			PendingWorkloads: 3,
+		}},
+	}
+	cases := map[string]struct {
+		status  int
+		wantErr bool
+	}{
+		"accepted": {
+			status: http.StatusNoContent,
+		},
+		"rejected": {
+			status:  http.StatusServiceUnavailable,
+			wantErr: true,
+		},
+	}
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			var got Sample
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if r.Method != http.MethodPost {
+					t.Errorf("Got method %s, want POST", r.Method)
+				}
+				if err := json.NewDecoder(r.Body).Decode(&got); err != nil {
+					t.Errorf("Decoding sample: %v", err)
+				}
+				w.WriteHeader(tc.status)
+			}))
+			defer srv.Close()
+
+			err := NewWebhook(srv.URL, time.Second).Record(context.Background(), sample)
+			if gotErr := err != nil; gotErr != tc.wantErr {
+				t.Fatalf("Record returned error %v, want error %t", err, tc.wantErr)
+			}
+			if diff := cmp.Diff(*sample, got); diff != "" {
+				t.Errorf("Unexpected sample received (-want,+got):\n%s", diff)
+			}
+		})
+	}
+}
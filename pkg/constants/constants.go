@@ -24,9 +24,133 @@ const (
 	// TODO(#23): Use the kubernetes.io domain when graduating APIs to beta.
 	QueueAnnotation = "kueue.x-k8s.io/queue-name"
 
-	KueueName         = "kueue"
-	JobControllerName = KueueName + "-job-controller"
-	AdmissionName     = KueueName + "-admission"
+	// DefaultQueueAnnotation is the annotation in a Namespace that holds the
+	// name of the LocalQueue, in the Namespace, that Kueue sets in the queue
+	// name annotation of the Jobs created without one.
+	DefaultQueueAnnotation = "kueue.x-k8s.io/default-queue-name"
+
+	// CronJobSaturationPolicyAnnotation is the annotation in a CronJob that
+	// defines what happens to the Jobs it creates while their LocalQueue is
+	// saturated. The possible values are "Skip" and "Delay".
+	CronJobSaturationPolicyAnnotation = "kueue.x-k8s.io/saturation-policy"
+
+	// CronJobSaturationThresholdAnnotation is the annotation in a CronJob that
+	// holds the number of pending workloads in the LocalQueue from which the
+	// LocalQueue is considered saturated.
+	CronJobSaturationThresholdAnnotation = "kueue.x-k8s.io/saturation-threshold"
+
+	// CronJobDelayedRunAnnotation is the annotation that Kueue sets in a Job
+	// created by a CronJob when it delays the run because the LocalQueue is
+	// saturated, so that the delay is only reported once.
+	CronJobDelayedRunAnnotation = "kueue.x-k8s.io/delayed-run"
+
+	// PriorityClassLabel is the label in a Job that holds the name of the
+	// PriorityClass used for its workload, regardless of the priorityClassName
+	// of its pod template.
+	PriorityClassLabel = "kueue.x-k8s.io/priority-class"
+
+	// WorkloadPriorityClassLabel is the label in a Job that holds the name of
+	// the WorkloadPriorityClass of its workload. It takes precedence over the
+	// PriorityClasses, and only sets the priority of the workload in Kueue,
+	// not the priority of the pods.
+	WorkloadPriorityClassLabel = "kueue.x-k8s.io/workload-priority-class"
+
+	// DeadlineAnnotation is the annotation in a Job that holds the time, in
+	// RFC 3339 format, by which its workload should complete.
+	DeadlineAnnotation = "kueue.x-k8s.io/deadline"
+
+	// ExpectedDurationAnnotation is the annotation in a Job that holds how long
+	// its workload is expected to run, such as "2h30m".
+	ExpectedDurationAnnotation = "kueue.x-k8s.io/expected-duration"
+
+	// JobMinParallelismAnnotation is the annotation in a Job that holds the
+	// minimum parallelism with which the Job can be partially admitted.
+	JobMinParallelismAnnotation = "kueue.x-k8s.io/job-min-parallelism"
+
+	// ResyncAnnotation is the annotation in a ClusterQueue that, when its value
+	// changes, makes Kueue rebuild the admitted workloads and usage it tracks
+	// for the ClusterQueue from the objects in the API server.
+	ResyncAnnotation = "kueue.x-k8s.io/resync"
+
+	// MoveToQueueAnnotation is the annotation in an admitted Workload that holds
+	// the name of the LocalQueue, in the namespace of the Workload, to move it
+	// to. Kueue moves the Workload and its usage to the ClusterQueue of the
+	// LocalQueue without evicting it, once it fits in the quota of the
+	// ClusterQueue, and then removes the annotation.
+	MoveToQueueAnnotation = "kueue.x-k8s.io/move-to-queue"
+
+	// AdmissionGroupLabel is the label in a Workload, or in the Job that owns
+	// it, that holds the name of the admission group the Workload belongs to.
+	// The Workloads of an admission group are admitted together or not at all.
+	AdmissionGroupLabel = "kueue.x-k8s.io/admission-group"
+
+	// AdmissionGroupSizeAnnotation is the annotation in a Workload, or in the
+	// Job that owns it, that holds the number of Workloads in its admission
+	// group.
+	AdmissionGroupSizeAnnotation = "kueue.x-k8s.io/admission-group-size"
+
+	// DependsOnAnnotation is the annotation in a Workload, or in the Job that
+	// owns it, that holds the name of the Workload, in the same namespace,
+	// that it depends on. With priority inheritance, the Workload is ordered
+	// in its ClusterQueue with the priority of that Workload, if it's higher.
+	DependsOnAnnotation = "kueue.x-k8s.io/depends-on"
+
+	// PodTemplateHashAnnotation is the annotation in a Workload that holds the
+	// hash of the pod template of the Job it was created from, used to detect
+	// changes to the Job after the Workload was created.
+	PodTemplateHashAnnotation = "kueue.x-k8s.io/pod-template-hash"
+
+	// FlavorCostAnnotation is the annotation in a ResourceFlavor that holds its
+	// relative cost, as a decimal number such as "0.35", used by the
+	// ClusterQueues with the LowestCost flavor assignment policy.
+	FlavorCostAnnotation = "kueue.x-k8s.io/cost-per-cpu-hour"
+
+	// HeldReplicasAnnotation is the annotation in a Deployment or a
+	// StatefulSet that holds the number of replicas to run once its Workload
+	// is admitted. Kueue keeps them scaled to zero while the annotation is
+	// set.
+	HeldReplicasAnnotation = "kueue.x-k8s.io/held-replicas"
+
+	// ManagedByAnnotation is the annotation in a Job that holds the managedBy
+	// of its Workload, the name of the controller that admits it instead of
+	// Kueue, such as a multi-cluster dispatcher.
+	ManagedByAnnotation = "kueue.x-k8s.io/managed-by"
+
+	// PodGroupNameLabel is the label in a plain Pod that holds the name of the
+	// pod group it belongs to. The Pods of a group share a single Workload,
+	// and they only start running once the Workload is admitted. A Pod
+	// without the label is a group on its own.
+	PodGroupNameLabel = "kueue.x-k8s.io/pod-group-name"
+
+	// PodGroupTotalCountAnnotation is the annotation in a plain Pod that holds
+	// the number of Pods in its pod group. The Workload of the group is only
+	// created once all of them exist. It defaults to 1.
+	PodGroupTotalCountAnnotation = "kueue.x-k8s.io/pod-group-total-count"
+
+	// ManagedLabel is the label that Kueue sets in the plain Pods that it
+	// manages, so that they can be watched apart from the rest of the Pods.
+	ManagedLabel = "kueue.x-k8s.io/managed"
+
+	// AdmissionGateNodeSelector is the node selector that Kueue adds to a
+	// plain Pod to keep it from being scheduled until its pod group is
+	// admitted. No node is expected to have the label.
+	AdmissionGateNodeSelector = "kueue.x-k8s.io/admission-gate"
+
+	// PodAdmittedAnnotation is the annotation in a plain Pod, recreated by
+	// Kueue without the admission gate, that holds the UID of the admitted
+	// Workload of its pod group.
+	PodAdmittedAnnotation = "kueue.x-k8s.io/admitted-workload"
+
+	// ArchivalFinalizer is the finalizer that prevents the deletion of a
+	// Workload until its record is archived, when archival is enabled.
+	ArchivalFinalizer = "kueue.x-k8s.io/archival"
+
+	KueueName                  = "kueue"
+	JobControllerName          = KueueName + "-job-controller"
+	WorkloadControllerName     = KueueName + "-workload-controller"
+	ClusterQueueControllerName = KueueName + "-clusterqueue-controller"
+	AdmissionName              = KueueName + "-admission"
+	ProvisioningControllerName = KueueName + "-provisioning-controller"
 
 	// UpdatesBatchPeriod is the batch period to hold workload updates
 	// before syncing a Queue and ClusterQueue objects.
@@ -0,0 +1,68 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package provisioning
+
+import (
+	"context"
+
+	apimeta "k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	kueue "sigs.k8s.io/kueue/apis/kueue/v1alpha2"
+)
+
+// AdmissionCheckReconciler sets the Active condition of the AdmissionChecks
+// performed by this package, so that the ClusterQueues that use them can
+// admit Workloads.
+type AdmissionCheckReconciler struct {
+	client client.Client
+}
+
+func NewAdmissionCheckReconciler(client client.Client) *AdmissionCheckReconciler {
+	return &AdmissionCheckReconciler{client: client}
+}
+
+//+kubebuilder:rbac:groups=kueue.x-k8s.io,resources=admissionchecks,verbs=get;list;watch
+//+kubebuilder:rbac:groups=kueue.x-k8s.io,resources=admissionchecks/status,verbs=get;update;patch
+
+func (r *AdmissionCheckReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	var ac kueue.AdmissionCheck
+	if err := r.client.Get(ctx, req.NamespacedName, &ac); err != nil {
+		return ctrl.Result{}, client.IgnoreNotFound(err)
+	}
+	if ac.Spec.ControllerName != ControllerName || apimeta.IsStatusConditionTrue(ac.Status.Conditions, kueue.AdmissionCheckActive) {
+		return ctrl.Result{}, nil
+	}
+	apimeta.SetStatusCondition(&ac.Status.Conditions, metav1.Condition{
+		Type:    kueue.AdmissionCheckActive,
+		Status:  metav1.ConditionTrue,
+		Reason:  "Active",
+		Message: "The controller is ready to create ProvisioningRequests",
+	})
+	ctrl.LoggerFrom(ctx).V(2).Info("Activating AdmissionCheck", "admissionCheck", ac.Name)
+	return ctrl.Result{}, client.IgnoreNotFound(r.client.Status().Update(ctx, &ac))
+}
+
+// SetupWithManager sets up the controller with the Manager.
+func (r *AdmissionCheckReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewControllerManagedBy(mgr).
+		Named("provisioningrequest_admissioncheck").
+		For(&kueue.AdmissionCheck{}).
+		Complete(r)
+}
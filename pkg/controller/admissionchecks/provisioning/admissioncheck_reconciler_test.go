@@ -0,0 +1,67 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package provisioning
+
+import (
+	"context"
+	"testing"
+
+	"github.com/go-logr/logr/testr"
+	apimeta "k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	kueue "sigs.k8s.io/kueue/apis/kueue/v1alpha2"
+	utiltesting "sigs.k8s.io/kueue/pkg/util/testing"
+)
+
+func TestAdmissionCheckReconciler(t *testing.T) {
+	cases := map[string]struct {
+		controllerName string
+		wantActive     bool
+	}{
+		"provisioning check": {
+			controllerName: ControllerName,
+			wantActive:     true,
+		},
+		"check of another controller": {
+			controllerName: "example.com/other",
+		},
+	}
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			ctx := ctrl.LoggerInto(context.Background(), testr.New(t))
+			cl := fake.NewClientBuilder().WithScheme(newScheme(t)).WithObjects(
+				utiltesting.MakeAdmissionCheck("check", tc.controllerName).Obj(),
+			).Build()
+			r := NewAdmissionCheckReconciler(cl)
+
+			key := types.NamespacedName{Name: "check"}
+			if _, err := r.Reconcile(ctx, ctrl.Request{NamespacedName: key}); err != nil {
+				t.Fatalf("Reconcile returned error: %v", err)
+			}
+			var ac kueue.AdmissionCheck
+			if err := cl.Get(ctx, key, &ac); err != nil {
+				t.Fatalf("Failed getting the AdmissionCheck: %v", err)
+			}
+			if gotActive := apimeta.IsStatusConditionTrue(ac.Status.Conditions, kueue.AdmissionCheckActive); gotActive != tc.wantActive {
+				t.Errorf("AdmissionCheck active: %t, want %t", gotActive, tc.wantActive)
+			}
+		})
+	}
+}
@@ -0,0 +1,464 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package provisioning implements the admission checks that provision the
+// nodes for the pods of a Workload through the ProvisioningRequests of the
+// cluster autoscaler. The ProvisioningRequests are handled as unstructured
+// objects, so that Kueue doesn't depend on the autoscaler APIs.
+package provisioning
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strconv"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	apimeta "k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
+	"k8s.io/klog/v2"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+
+	config "sigs.k8s.io/kueue/apis/config/v1alpha2"
+	kueue "sigs.k8s.io/kueue/apis/kueue/v1alpha2"
+	"sigs.k8s.io/kueue/pkg/constants"
+	"sigs.k8s.io/kueue/pkg/controller/workload/jobframework"
+	"sigs.k8s.io/kueue/pkg/workload"
+)
+
+const (
+	// ControllerName is the controllerName of the AdmissionChecks performed
+	// by this package.
+	ControllerName = "kueue.x-k8s.io/provisioning-request"
+
+	// checkAnnotation is the annotation of the ProvisioningRequests with the
+	// name of the admission check they were created for.
+	checkAnnotation = "kueue.x-k8s.io/admission-check"
+
+	// attemptAnnotation is the annotation of the ProvisioningRequests with
+	// the attempt, starting at 1, of the admission check they were created
+	// for.
+	attemptAnnotation = "kueue.x-k8s.io/provisioning-attempt"
+
+	// The conditions of the ProvisioningRequests set by the autoscaler.
+	provisionedCondition = "Provisioned"
+	failedCondition      = "Failed"
+
+	// maxRetryBackoff caps the exponential backoff between attempts.
+	maxRetryBackoff = time.Hour
+)
+
+// GVK is the ProvisioningRequest API of the cluster autoscaler.
+var GVK = schema.GroupVersionKind{Group: "autoscaling.x-k8s.io", Version: "v1beta1", Kind: "ProvisioningRequest"}
+
+// Controller performs the admission checks with the controllerName
+// kueue.x-k8s.io/provisioning-request. For each Workload with reserved
+// quota, it creates a ProvisioningRequest for the pods of the Workload, with
+// the node labels of their assigned flavors, and sets the admission check to
+// Ready once the autoscaler provisioned the capacity. A ProvisioningRequest
+// that fails is created again, with an exponential backoff, up to
+// maxRetries times; after that, the admission check is set to Retry, so that
+// the Workload is evicted and requeued.
+type Controller struct {
+	client       client.Client
+	record       record.EventRecorder
+	className    string
+	parameters   map[string]string
+	maxRetries   int32
+	retryBackoff time.Duration
+}
+
+func NewController(client client.Client, recorder record.EventRecorder, cfg *config.ProvisioningRequests) *Controller {
+	return &Controller{
+		client:       client,
+		record:       recorder,
+		className:    cfg.ProvisioningClassName,
+		parameters:   cfg.Parameters,
+		maxRetries:   *cfg.MaxRetries,
+		retryBackoff: cfg.RetryBackoff.Duration,
+	}
+}
+
+// SetupControllers sets up the controllers of the ProvisioningRequest
+// admission checks. It returns the name of the controller that failed to
+// create and an error, if any.
+func SetupControllers(mgr ctrl.Manager, cfg *config.ProvisioningRequests) (string, error) {
+	if err := NewAdmissionCheckReconciler(mgr.GetClient()).SetupWithManager(mgr); err != nil {
+		return "ProvisioningAdmissionCheck", err
+	}
+	recorder := mgr.GetEventRecorderFor(constants.ProvisioningControllerName)
+	if err := NewController(mgr.GetClient(), recorder, cfg).SetupWithManager(mgr); err != nil {
+		return "ProvisioningRequest", err
+	}
+	return "", nil
+}
+
+//+kubebuilder:rbac:groups=autoscaling.x-k8s.io,resources=provisioningrequests,verbs=get;list;watch;create;delete
+//+kubebuilder:rbac:groups="",resources=podtemplates,verbs=get;list;watch;create;delete
+//+kubebuilder:rbac:groups=kueue.x-k8s.io,resources=workloads,verbs=get;list;watch
+//+kubebuilder:rbac:groups=kueue.x-k8s.io,resources=workloads/status,verbs=get;update;patch
+
+func (c *Controller) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	var wl kueue.Workload
+	if err := c.client.Get(ctx, req.NamespacedName, &wl); err != nil {
+		return ctrl.Result{}, client.IgnoreNotFound(err)
+	}
+	log := ctrl.LoggerFrom(ctx).WithValues("workload", klog.KObj(&wl))
+	ctx = ctrl.LoggerInto(ctx, log)
+
+	requests, err := c.ownedRequests(ctx, &wl)
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+	reserved := apimeta.FindStatusCondition(wl.Status.Conditions, kueue.WorkloadQuotaReserved)
+	if wl.Status.Admission == nil || reserved == nil || reserved.Status != metav1.ConditionTrue ||
+		apimeta.IsStatusConditionTrue(wl.Status.Conditions, kueue.WorkloadFinished) {
+		// The requests are from a previous quota reservation. They are
+		// deleted, so that the next reservation starts from the first
+		// attempt.
+		return ctrl.Result{}, c.deleteRequests(ctx, requests)
+	}
+
+	var stale []*unstructured.Unstructured
+	checkRequests := make(map[string][]*unstructured.Unstructured)
+	for _, r := range requests {
+		if created := r.GetCreationTimestamp(); created.Before(&reserved.LastTransitionTime) {
+			stale = append(stale, r)
+			continue
+		}
+		check := r.GetAnnotations()[checkAnnotation]
+		checkRequests[check] = append(checkRequests[check], r)
+	}
+	if err := c.deleteRequests(ctx, stale); err != nil {
+		return ctrl.Result{}, err
+	}
+
+	checks, err := c.provisioningChecks(ctx, &wl)
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+	var result ctrl.Result
+	statusChanged := false
+	for _, name := range checks {
+		state := workload.FindAdmissionCheck(wl.Status.AdmissionChecks, name)
+		if state == nil || state.State != kueue.CheckStatePending {
+			continue
+		}
+		newState, retryAfter, err := c.syncCheck(ctx, &wl, name, checkRequests[name])
+		if err != nil {
+			return ctrl.Result{}, err
+		}
+		if retryAfter > 0 && (result.RequeueAfter == 0 || retryAfter < result.RequeueAfter) {
+			result.RequeueAfter = retryAfter
+		}
+		if newState.State != state.State || newState.Message != state.Message {
+			workload.SetAdmissionCheckState(&wl.Status.AdmissionChecks, newState)
+			statusChanged = true
+		}
+	}
+	if statusChanged {
+		if err := c.client.Status().Update(ctx, &wl); err != nil {
+			return ctrl.Result{}, client.IgnoreNotFound(err)
+		}
+	}
+	return result, nil
+}
+
+// SetupWithManager sets up the controller with the Manager.
+func (c *Controller) SetupWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewControllerManagedBy(mgr).
+		Named("provisioningrequest_workload").
+		For(&kueue.Workload{}).
+		Owns(newRequest()).
+		Complete(c)
+}
+
+// syncCheck creates the ProvisioningRequests of the admission check for the
+// workload, as needed, given the requests already created for the check,
+// sorted by attempt. It returns the new state of the check and, when the next
+// attempt must wait for the backoff, how long.
+func (c *Controller) syncCheck(ctx context.Context, wl *kueue.Workload, check string, requests []*unstructured.Unstructured) (kueue.AdmissionCheckState, time.Duration, error) {
+	state := kueue.AdmissionCheckState{Name: check, State: kueue.CheckStatePending}
+	if len(requests) == 0 {
+		name, err := c.createRequest(ctx, wl, check, 1)
+		state.Message = fmt.Sprintf("Waiting for ProvisioningRequest %s", name)
+		return state, 0, err
+	}
+	last := requests[len(requests)-1]
+	conditions := requestConditions(last)
+	if apimeta.IsStatusConditionTrue(conditions, provisionedCondition) {
+		state.State = kueue.CheckStateReady
+		state.Message = fmt.Sprintf("Capacity provisioned by ProvisioningRequest %s", last.GetName())
+		return state, 0, nil
+	}
+	failed := apimeta.FindStatusCondition(conditions, failedCondition)
+	if failed == nil || failed.Status != metav1.ConditionTrue {
+		state.Message = fmt.Sprintf("Waiting for ProvisioningRequest %s", last.GetName())
+		return state, 0, nil
+	}
+	attempt := requestAttempt(last)
+	if attempt > c.maxRetries {
+		state.State = kueue.CheckStateRetry
+		state.Message = fmt.Sprintf("ProvisioningRequest %s failed: %s", last.GetName(), failed.Message)
+		return state, 0, nil
+	}
+	if wait := time.Until(failed.LastTransitionTime.Add(c.backoff(attempt))); wait > 0 {
+		state.Message = fmt.Sprintf("Retrying after ProvisioningRequest %s failed: %s", last.GetName(), failed.Message)
+		return state, wait, nil
+	}
+	name, err := c.createRequest(ctx, wl, check, attempt+1)
+	state.Message = fmt.Sprintf("Waiting for ProvisioningRequest %s", name)
+	return state, 0, err
+}
+
+// backoff returns the time to wait before the attempt that follows the given
+// failed attempt.
+func (c *Controller) backoff(attempt int32) time.Duration {
+	backoff := c.retryBackoff
+	for i := int32(1); i < attempt && backoff < maxRetryBackoff; i++ {
+		backoff *= 2
+	}
+	if backoff > maxRetryBackoff {
+		return maxRetryBackoff
+	}
+	return backoff
+}
+
+// createRequest creates the given attempt of the ProvisioningRequest of the
+// admission check for the workload, with a PodTemplate for each part of its
+// admitted pod sets. It returns the name of the ProvisioningRequest.
+func (c *Controller) createRequest(ctx context.Context, wl *kueue.Workload, check string, attempt int32) (string, error) {
+	name := fmt.Sprintf("%s-%s-%d", wl.Name, check, attempt)
+	podSets, err := c.createPodTemplates(ctx, wl, name)
+	if err != nil {
+		return name, err
+	}
+	req := newRequest()
+	req.SetNamespace(wl.Namespace)
+	req.SetName(name)
+	req.SetAnnotations(map[string]string{
+		checkAnnotation:   check,
+		attemptAnnotation: strconv.Itoa(int(attempt)),
+	})
+	if err := controllerutil.SetControllerReference(wl, req, c.client.Scheme()); err != nil {
+		return name, err
+	}
+	spec := map[string]interface{}{
+		"provisioningClassName": c.className,
+		"podSets":               podSets,
+	}
+	if len(c.parameters) > 0 {
+		parameters := make(map[string]interface{}, len(c.parameters))
+		for k, v := range c.parameters {
+			parameters[k] = v
+		}
+		spec["parameters"] = parameters
+	}
+	req.Object["spec"] = spec
+	if err := c.client.Create(ctx, req); err != nil {
+		if apierrors.IsAlreadyExists(err) {
+			return name, nil
+		}
+		return name, err
+	}
+	ctrl.LoggerFrom(ctx).V(2).Info("Created ProvisioningRequest", "provisioningRequest", klog.KObj(req), "attempt", attempt)
+	c.record.Eventf(wl, corev1.EventTypeNormal, "ProvisioningRequestCreated", "Created ProvisioningRequest %s for the admission check %s", name, check)
+	return name, nil
+}
+
+// createPodTemplates creates the PodTemplates of the ProvisioningRequest for
+// the workload: one for each part of each admitted pod set, with the node
+// labels of the flavors assigned to the part. It returns the podSets of the
+// ProvisioningRequest.
+func (c *Controller) createPodTemplates(ctx context.Context, wl *kueue.Workload, requestName string) ([]interface{}, error) {
+	var podSets []interface{}
+	for i := range wl.Status.Admission.PodSetFlavors {
+		psFlavors := &wl.Status.Admission.PodSetFlavors[i]
+		ps := findPodSet(wl, psFlavors.Name)
+		if ps == nil {
+			continue
+		}
+		partsLabels, err := jobframework.PodSetPartsNodeLabels(ctx, c.client, psFlavors)
+		if err != nil {
+			return nil, err
+		}
+		counts := partCounts(ps, psFlavors)
+		for j, labels := range partsLabels {
+			if counts[j] == 0 {
+				continue
+			}
+			name := fmt.Sprintf("%s-%s", requestName, ps.Name)
+			if j > 0 {
+				name = fmt.Sprintf("%s-%d", name, j)
+			}
+			tmpl := &corev1.PodTemplate{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      name,
+					Namespace: wl.Namespace,
+				},
+				Template: corev1.PodTemplateSpec{
+					Spec: *ps.Spec.DeepCopy(),
+				},
+			}
+			if len(labels) > 0 && tmpl.Template.Spec.NodeSelector == nil {
+				tmpl.Template.Spec.NodeSelector = make(map[string]string, len(labels))
+			}
+			for k, v := range labels {
+				tmpl.Template.Spec.NodeSelector[k] = v
+			}
+			if err := controllerutil.SetControllerReference(wl, tmpl, c.client.Scheme()); err != nil {
+				return nil, err
+			}
+			if err := c.client.Create(ctx, tmpl); err != nil && !apierrors.IsAlreadyExists(err) {
+				return nil, err
+			}
+			podSets = append(podSets, map[string]interface{}{
+				"podTemplateRef": map[string]interface{}{"name": name},
+				"count":          int64(counts[j]),
+			})
+		}
+	}
+	return podSets, nil
+}
+
+// deleteRequests deletes the ProvisioningRequests and their PodTemplates.
+func (c *Controller) deleteRequests(ctx context.Context, requests []*unstructured.Unstructured) error {
+	for _, req := range requests {
+		podSets, _, _ := unstructured.NestedSlice(req.Object, "spec", "podSets")
+		for _, ps := range podSets {
+			psMap, ok := ps.(map[string]interface{})
+			if !ok {
+				continue
+			}
+			name, _, _ := unstructured.NestedString(psMap, "podTemplateRef", "name")
+			if name == "" {
+				continue
+			}
+			tmpl := &corev1.PodTemplate{ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: req.GetNamespace()}}
+			if err := c.client.Delete(ctx, tmpl); client.IgnoreNotFound(err) != nil {
+				return err
+			}
+		}
+		if err := c.client.Delete(ctx, req); client.IgnoreNotFound(err) != nil {
+			return err
+		}
+		ctrl.LoggerFrom(ctx).V(2).Info("Deleted ProvisioningRequest", "provisioningRequest", klog.KObj(req))
+	}
+	return nil
+}
+
+// ownedRequests returns the ProvisioningRequests of the workload, sorted by
+// attempt.
+func (c *Controller) ownedRequests(ctx context.Context, wl *kueue.Workload) ([]*unstructured.Unstructured, error) {
+	list := &unstructured.UnstructuredList{}
+	list.SetGroupVersionKind(GVK.GroupVersion().WithKind(GVK.Kind + "List"))
+	if err := c.client.List(ctx, list, client.InNamespace(wl.Namespace)); err != nil {
+		return nil, err
+	}
+	var requests []*unstructured.Unstructured
+	for i := range list.Items {
+		if metav1.IsControlledBy(&list.Items[i], wl) {
+			requests = append(requests, &list.Items[i])
+		}
+	}
+	sort.Slice(requests, func(a, b int) bool {
+		return requestAttempt(requests[a]) < requestAttempt(requests[b])
+	})
+	return requests, nil
+}
+
+// provisioningChecks returns the admission checks of the admission of the
+// workload that are performed by this controller.
+func (c *Controller) provisioningChecks(ctx context.Context, wl *kueue.Workload) ([]string, error) {
+	var checks []string
+	for _, name := range wl.Status.Admission.AdmissionChecks {
+		var ac kueue.AdmissionCheck
+		if err := c.client.Get(ctx, types.NamespacedName{Name: name}, &ac); err != nil {
+			if apierrors.IsNotFound(err) {
+				continue
+			}
+			return nil, err
+		}
+		if ac.Spec.ControllerName == ControllerName {
+			checks = append(checks, name)
+		}
+	}
+	return checks, nil
+}
+
+func newRequest() *unstructured.Unstructured {
+	obj := &unstructured.Unstructured{}
+	obj.SetGroupVersionKind(GVK)
+	return obj
+}
+
+func requestAttempt(req *unstructured.Unstructured) int32 {
+	attempt, err := strconv.Atoi(req.GetAnnotations()[attemptAnnotation])
+	if err != nil {
+		return 0
+	}
+	return int32(attempt)
+}
+
+func requestConditions(req *unstructured.Unstructured) []metav1.Condition {
+	raw, _, _ := unstructured.NestedSlice(req.Object, "status", "conditions")
+	conditions := make([]metav1.Condition, 0, len(raw))
+	for _, r := range raw {
+		rMap, ok := r.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		var cond metav1.Condition
+		if err := runtime.DefaultUnstructuredConverter.FromUnstructured(rMap, &cond); err == nil {
+			conditions = append(conditions, cond)
+		}
+	}
+	return conditions
+}
+
+func findPodSet(wl *kueue.Workload, name string) *kueue.PodSet {
+	for i := range wl.Spec.PodSets {
+		if wl.Spec.PodSets[i].Name == name {
+			return &wl.Spec.PodSets[i]
+		}
+	}
+	return nil
+}
+
+// partCounts returns the number of pods admitted in each part of the pod
+// set: first the part admitted with the flavors of the pod set, followed by
+// its splits.
+func partCounts(ps *kueue.PodSet, psFlavors *kueue.PodSetFlavors) []int32 {
+	counts := make([]int32, 0, len(psFlavors.Splits)+1)
+	first := ps.Count
+	if psFlavors.Count != nil {
+		first = *psFlavors.Count
+	}
+	counts = append(counts, first)
+	for _, split := range psFlavors.Splits {
+		counts = append(counts, split.Count)
+	}
+	return counts
+}
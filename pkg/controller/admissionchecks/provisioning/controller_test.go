@@ -0,0 +1,351 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package provisioning
+
+import (
+	"context"
+	"sort"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/go-logr/logr/testr"
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
+	"k8s.io/utils/pointer"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	config "sigs.k8s.io/kueue/apis/config/v1alpha2"
+	kueue "sigs.k8s.io/kueue/apis/kueue/v1alpha2"
+	utiltesting "sigs.k8s.io/kueue/pkg/util/testing"
+)
+
+var testConfig = &config.ProvisioningRequests{
+	ProvisioningClassName: "check-capacity.autoscaling.x-k8s.io",
+	MaxRetries:            pointer.Int32(1),
+	RetryBackoff:          &metav1.Duration{Duration: time.Minute},
+}
+
+func newScheme(t *testing.T) *runtime.Scheme {
+	t.Helper()
+	scheme := runtime.NewScheme()
+	if err := kueue.AddToScheme(scheme); err != nil {
+		t.Fatalf("Failed adding kueue scheme: %v", err)
+	}
+	if err := corev1.AddToScheme(scheme); err != nil {
+		t.Fatalf("Failed adding core scheme: %v", err)
+	}
+	return scheme
+}
+
+// makeRequest returns the given attempt of the ProvisioningRequest of the
+// admission check "provisioning" for the workload, with the conditions.
+func makeRequest(wl *kueue.Workload, attempt int, created time.Time, conditions ...metav1.Condition) *unstructured.Unstructured {
+	req := newRequest()
+	req.SetNamespace(wl.Namespace)
+	req.SetName(wl.Name + "-provisioning-" + strconv.Itoa(attempt))
+	req.SetCreationTimestamp(metav1.NewTime(created))
+	req.SetAnnotations(map[string]string{
+		checkAnnotation:   "provisioning",
+		attemptAnnotation: strconv.Itoa(attempt),
+	})
+	req.SetOwnerReferences([]metav1.OwnerReference{{
+		APIVersion: kueue.GroupVersion.String(),
+		Kind:       "Workload",
+		Name:       wl.Name,
+		UID:        wl.UID,
+		Controller: pointer.Bool(true),
+	}})
+	rawConditions := make([]interface{}, 0, len(conditions))
+	for _, c := range conditions {
+		raw, _ := runtime.DefaultUnstructuredConverter.ToUnstructured(&c)
+		rawConditions = append(rawConditions, raw)
+	}
+	_ = unstructured.SetNestedSlice(req.Object, rawConditions, "status", "conditions")
+	return req
+}
+
+func TestReconcile(t *testing.T) {
+	now := time.Now().Truncate(time.Second)
+	reserved := metav1.Condition{
+		Type:               kueue.WorkloadQuotaReserved,
+		Status:             metav1.ConditionTrue,
+		Reason:             "QuotaReserved",
+		LastTransitionTime: metav1.NewTime(now.Add(-time.Hour)),
+	}
+	baseWl := utiltesting.MakeWorkload("wl", "ns").
+		Request(corev1.ResourceCPU, "1").
+		Admit(utiltesting.MakeAdmission("cq").Flavor(corev1.ResourceCPU, "spot").AdmissionChecks("provisioning", "other").Obj()).
+		Condition(reserved).
+		AdmissionCheck("provisioning", kueue.CheckStatePending).
+		AdmissionCheck("other", kueue.CheckStatePending).
+		Obj()
+	baseWl.UID = "wl-uid"
+	failed := func(at time.Time) metav1.Condition {
+		return metav1.Condition{
+			Type:               failedCondition,
+			Status:             metav1.ConditionTrue,
+			Reason:             "NoCapacity",
+			Message:            "no capacity",
+			LastTransitionTime: metav1.NewTime(at),
+		}
+	}
+	created := now.Add(-30 * time.Minute)
+
+	cases := map[string]struct {
+		workload     *kueue.Workload
+		requests     []*unstructured.Unstructured
+		wantRequests []string
+		wantCheck    *kueue.AdmissionCheckState
+		wantRequeue  bool
+	}{
+		"creates the first request": {
+			workload:     baseWl,
+			wantRequests: []string{"wl-provisioning-1"},
+			wantCheck: &kueue.AdmissionCheckState{
+				Name:    "provisioning",
+				State:   kueue.CheckStatePending,
+				Message: "Waiting for ProvisioningRequest wl-provisioning-1",
+			},
+		},
+		"waits for the request": {
+			workload:     baseWl,
+			requests:     []*unstructured.Unstructured{makeRequest(baseWl, 1, created)},
+			wantRequests: []string{"wl-provisioning-1"},
+			wantCheck: &kueue.AdmissionCheckState{
+				Name:    "provisioning",
+				State:   kueue.CheckStatePending,
+				Message: "Waiting for ProvisioningRequest wl-provisioning-1",
+			},
+		},
+		"capacity provisioned": {
+			workload: baseWl,
+			requests: []*unstructured.Unstructured{makeRequest(baseWl, 1, created, metav1.Condition{
+				Type:               provisionedCondition,
+				Status:             metav1.ConditionTrue,
+				Reason:             "Provisioned",
+				LastTransitionTime: metav1.NewTime(now),
+			})},
+			wantRequests: []string{"wl-provisioning-1"},
+			wantCheck: &kueue.AdmissionCheckState{
+				Name:    "provisioning",
+				State:   kueue.CheckStateReady,
+				Message: "Capacity provisioned by ProvisioningRequest wl-provisioning-1",
+			},
+		},
+		"waits for the backoff after a failure": {
+			workload:     baseWl,
+			requests:     []*unstructured.Unstructured{makeRequest(baseWl, 1, created, failed(now))},
+			wantRequests: []string{"wl-provisioning-1"},
+			wantCheck: &kueue.AdmissionCheckState{
+				Name:    "provisioning",
+				State:   kueue.CheckStatePending,
+				Message: "Retrying after ProvisioningRequest wl-provisioning-1 failed: no capacity",
+			},
+			wantRequeue: true,
+		},
+		"retries after the backoff": {
+			workload:     baseWl,
+			requests:     []*unstructured.Unstructured{makeRequest(baseWl, 1, created, failed(now.Add(-2*time.Minute)))},
+			wantRequests: []string{"wl-provisioning-1", "wl-provisioning-2"},
+			wantCheck: &kueue.AdmissionCheckState{
+				Name:    "provisioning",
+				State:   kueue.CheckStatePending,
+				Message: "Waiting for ProvisioningRequest wl-provisioning-2",
+			},
+		},
+		"retries exhausted": {
+			workload: baseWl,
+			requests: []*unstructured.Unstructured{
+				makeRequest(baseWl, 1, created, failed(now.Add(-10*time.Minute))),
+				makeRequest(baseWl, 2, created, failed(now.Add(-2*time.Minute))),
+			},
+			wantRequests: []string{"wl-provisioning-1", "wl-provisioning-2"},
+			wantCheck: &kueue.AdmissionCheckState{
+				Name:    "provisioning",
+				State:   kueue.CheckStateRetry,
+				Message: "ProvisioningRequest wl-provisioning-2 failed: no capacity",
+			},
+		},
+		"deletes the requests of a previous reservation": {
+			workload: baseWl,
+			requests: []*unstructured.Unstructured{
+				makeRequest(baseWl, 2, now.Add(-2*time.Hour), failed(now.Add(-2*time.Hour))),
+			},
+			wantRequests: []string{"wl-provisioning-1"},
+			wantCheck: &kueue.AdmissionCheckState{
+				Name:    "provisioning",
+				State:   kueue.CheckStatePending,
+				Message: "Waiting for ProvisioningRequest wl-provisioning-1",
+			},
+		},
+		"deletes the requests without quota reservation": {
+			workload: func() *kueue.Workload {
+				wl := baseWl.DeepCopy()
+				wl.Status.Admission = nil
+				wl.Status.AdmissionChecks = nil
+				return wl
+			}(),
+			requests: []*unstructured.Unstructured{makeRequest(baseWl, 1, created)},
+		},
+	}
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			ctx := ctrl.LoggerInto(context.Background(), testr.New(t))
+			builder := fake.NewClientBuilder().WithScheme(newScheme(t)).WithObjects(
+				tc.workload.DeepCopy(),
+				utiltesting.MakeResourceFlavor("spot").Label("instance", "spot").Obj(),
+				utiltesting.MakeAdmissionCheck("provisioning", ControllerName).Obj(),
+				utiltesting.MakeAdmissionCheck("other", "example.com/other").Obj(),
+			)
+			for _, req := range tc.requests {
+				builder = builder.WithObjects(req.DeepCopy())
+			}
+			cl := builder.Build()
+			c := NewController(cl, record.NewFakeRecorder(10), testConfig)
+
+			key := client.ObjectKeyFromObject(tc.workload)
+			result, err := c.Reconcile(ctx, ctrl.Request{NamespacedName: types.NamespacedName(key)})
+			if err != nil {
+				t.Fatalf("Reconcile returned error: %v", err)
+			}
+			if gotRequeue := result.RequeueAfter > 0; gotRequeue != tc.wantRequeue {
+				t.Errorf("Reconcile requeued after %v, want requeue: %t", result.RequeueAfter, tc.wantRequeue)
+			}
+
+			list := &unstructured.UnstructuredList{}
+			list.SetGroupVersionKind(GVK.GroupVersion().WithKind(GVK.Kind + "List"))
+			if err := cl.List(ctx, list, client.InNamespace("ns")); err != nil {
+				t.Fatalf("Failed listing ProvisioningRequests: %v", err)
+			}
+			var gotRequests []string
+			for _, req := range list.Items {
+				gotRequests = append(gotRequests, req.GetName())
+			}
+			sort.Strings(gotRequests)
+			if diff := cmp.Diff(tc.wantRequests, gotRequests); diff != "" {
+				t.Errorf("Unexpected ProvisioningRequests (-want,+got):\n%s", diff)
+			}
+
+			var gotWl kueue.Workload
+			if err := cl.Get(ctx, key, &gotWl); err != nil {
+				t.Fatalf("Failed getting workload: %v", err)
+			}
+			var gotCheck *kueue.AdmissionCheckState
+			for i := range gotWl.Status.AdmissionChecks {
+				if gotWl.Status.AdmissionChecks[i].Name == "provisioning" {
+					gotCheck = &gotWl.Status.AdmissionChecks[i]
+				}
+			}
+			if diff := cmp.Diff(tc.wantCheck, gotCheck, cmpopts.IgnoreFields(kueue.AdmissionCheckState{}, "LastTransitionTime")); diff != "" {
+				t.Errorf("Unexpected admission check (-want,+got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestCreateRequest(t *testing.T) {
+	ctx := ctrl.LoggerInto(context.Background(), testr.New(t))
+	wl := utiltesting.MakeWorkload("wl", "ns").
+		Request(corev1.ResourceCPU, "1").
+		Count(5).
+		Admit(utiltesting.MakeAdmission("cq").
+			Flavor(corev1.ResourceCPU, "spot").
+			Count(3).
+			Split(corev1.ResourceCPU, "on-demand", 2).
+			AdmissionChecks("provisioning").
+			Obj()).
+		Obj()
+	wl.UID = "wl-uid"
+	cl := fake.NewClientBuilder().WithScheme(newScheme(t)).WithObjects(
+		wl,
+		utiltesting.MakeResourceFlavor("spot").Label("instance", "spot").Obj(),
+		utiltesting.MakeResourceFlavor("on-demand").Label("instance", "on-demand").Obj(),
+	).Build()
+	c := NewController(cl, record.NewFakeRecorder(10), &config.ProvisioningRequests{
+		ProvisioningClassName: "queued-provisioning.example.com",
+		Parameters:            map[string]string{"maxRunDuration": "3600"},
+		MaxRetries:            pointer.Int32(3),
+		RetryBackoff:          &metav1.Duration{Duration: time.Minute},
+	})
+
+	name, err := c.createRequest(ctx, wl, "provisioning", 1)
+	if err != nil {
+		t.Fatalf("Failed creating the ProvisioningRequest: %v", err)
+	}
+	req := newRequest()
+	if err := cl.Get(ctx, types.NamespacedName{Namespace: "ns", Name: name}, req); err != nil {
+		t.Fatalf("Failed getting the ProvisioningRequest: %v", err)
+	}
+	wantSpec := map[string]interface{}{
+		"provisioningClassName": "queued-provisioning.example.com",
+		"parameters":            map[string]interface{}{"maxRunDuration": "3600"},
+		"podSets": []interface{}{
+			map[string]interface{}{
+				"podTemplateRef": map[string]interface{}{"name": "wl-provisioning-1-main"},
+				"count":          int64(3),
+			},
+			map[string]interface{}{
+				"podTemplateRef": map[string]interface{}{"name": "wl-provisioning-1-main-1"},
+				"count":          int64(2),
+			},
+		},
+	}
+	if diff := cmp.Diff(wantSpec, req.Object["spec"]); diff != "" {
+		t.Errorf("Unexpected spec of the ProvisioningRequest (-want,+got):\n%s", diff)
+	}
+	if !metav1.IsControlledBy(req, wl) {
+		t.Errorf("The ProvisioningRequest is not controlled by the workload")
+	}
+
+	wantNodeSelectors := map[string]map[string]string{
+		"wl-provisioning-1-main":   {"instance": "spot"},
+		"wl-provisioning-1-main-1": {"instance": "on-demand"},
+	}
+	for tmplName, want := range wantNodeSelectors {
+		var tmpl corev1.PodTemplate
+		if err := cl.Get(ctx, types.NamespacedName{Namespace: "ns", Name: tmplName}, &tmpl); err != nil {
+			t.Fatalf("Failed getting the PodTemplate %s: %v", tmplName, err)
+		}
+		if diff := cmp.Diff(want, tmpl.Template.Spec.NodeSelector); diff != "" {
+			t.Errorf("Unexpected node selector of the PodTemplate %s (-want,+got):\n%s", tmplName, diff)
+		}
+	}
+}
+
+func TestBackoff(t *testing.T) {
+	c := NewController(nil, nil, testConfig)
+	cases := map[int32]time.Duration{
+		1:  time.Minute,
+		2:  2 * time.Minute,
+		3:  4 * time.Minute,
+		10: maxRetryBackoff,
+	}
+	for attempt, want := range cases {
+		if got := c.backoff(attempt); got != want {
+			t.Errorf("backoff(%d) = %v, want %v", attempt, got, want)
+		}
+	}
+}
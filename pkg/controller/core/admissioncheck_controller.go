@@ -0,0 +1,80 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package core
+
+import (
+	"context"
+
+	"github.com/go-logr/logr"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/klog/v2"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	kueue "sigs.k8s.io/kueue/apis/kueue/v1alpha2"
+	"sigs.k8s.io/kueue/pkg/cache"
+	"sigs.k8s.io/kueue/pkg/queue"
+)
+
+// AdmissionCheckReconciler reconciles an AdmissionCheck object. It keeps
+// track of which AdmissionChecks are active, as reported by their
+// controllers, so that the ClusterQueues that use them are only active while
+// all of them are.
+type AdmissionCheckReconciler struct {
+	log      logr.Logger
+	qManager *queue.Manager
+	cache    *cache.Cache
+	client   client.Client
+}
+
+func NewAdmissionCheckReconciler(client client.Client, qMgr *queue.Manager, cache *cache.Cache) *AdmissionCheckReconciler {
+	return &AdmissionCheckReconciler{
+		log:      ctrl.Log.WithName("admissioncheck-reconciler"),
+		qManager: qMgr,
+		cache:    cache,
+		client:   client,
+	}
+}
+
+//+kubebuilder:rbac:groups=kueue.x-k8s.io,resources=admissionchecks,verbs=get;list;watch
+
+func (r *AdmissionCheckReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	var ac kueue.AdmissionCheck
+	if err := r.client.Get(ctx, req.NamespacedName, &ac); err != nil {
+		if apierrors.IsNotFound(err) {
+			r.cache.DeleteAdmissionCheck(req.Name)
+		}
+		return ctrl.Result{}, client.IgnoreNotFound(err)
+	}
+	log := ctrl.LoggerFrom(ctx).WithValues("admissionCheck", klog.KObj(&ac))
+	log.V(2).Info("Reconciling AdmissionCheck")
+
+	if cqNames := r.cache.AddOrUpdateAdmissionCheck(&ac); len(cqNames) > 0 {
+		r.qManager.QueueInadmissibleWorkloads(ctx, cqNames)
+		// The workloads of the ClusterQueues that became active are not
+		// necessarily inadmissible; wake up the scheduler in any case.
+		r.qManager.Broadcast()
+	}
+	return ctrl.Result{}, nil
+}
+
+// SetupWithManager sets up the controller with the Manager.
+func (r *AdmissionCheckReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewControllerManagedBy(mgr).
+		For(&kueue.AdmissionCheck{}).
+		Complete(r)
+}
@@ -18,6 +18,8 @@ package core
 
 import (
 	"context"
+	"fmt"
+	"time"
 
 	"github.com/go-logr/logr"
 	corev1 "k8s.io/api/core/v1"
@@ -26,21 +28,37 @@ import (
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/client-go/tools/record"
 	"k8s.io/client-go/util/workqueue"
 	"k8s.io/klog/v2"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
 	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
 	"sigs.k8s.io/controller-runtime/pkg/reconcile"
 	"sigs.k8s.io/controller-runtime/pkg/source"
 
 	kueue "sigs.k8s.io/kueue/apis/kueue/v1alpha2"
 	"sigs.k8s.io/kueue/pkg/cache"
 	"sigs.k8s.io/kueue/pkg/constants"
+	"sigs.k8s.io/kueue/pkg/metrics"
 	"sigs.k8s.io/kueue/pkg/queue"
+	"sigs.k8s.io/kueue/pkg/workload"
 )
 
+// ReasonCohortChanged is the reason of the event recorded when a ClusterQueue
+// moves to another cohort.
+const ReasonCohortChanged = "CohortChanged"
+
+// ReasonClusterQueueDeleted is the reason of the Evicted condition of the
+// workloads evicted to drain a ClusterQueue that is being deleted.
+const ReasonClusterQueueDeleted = "ClusterQueueDeleted"
+
+// budgetStatusRefreshInterval is how often the usage of the budgets is
+// refreshed in the status of the ClusterQueues that have budgets.
+const budgetStatusRefreshInterval = time.Minute
+
 type ClusterQueueUpdateWatcher interface {
 	NotifyClusterQueueUpdate(*kueue.ClusterQueue, *kueue.ClusterQueue)
 }
@@ -51,8 +69,10 @@ type ClusterQueueReconciler struct {
 	log        logr.Logger
 	qManager   *queue.Manager
 	cache      *cache.Cache
+	recorder   record.EventRecorder
 	wlUpdateCh chan event.GenericEvent
 	rfUpdateCh chan event.GenericEvent
+	cqUpdateCh chan event.GenericEvent
 	watchers   []ClusterQueueUpdateWatcher
 }
 
@@ -60,6 +80,7 @@ func NewClusterQueueReconciler(
 	client client.Client,
 	qMgr *queue.Manager,
 	cache *cache.Cache,
+	recorder record.EventRecorder,
 	watchers ...ClusterQueueUpdateWatcher,
 ) *ClusterQueueReconciler {
 	return &ClusterQueueReconciler{
@@ -67,8 +88,10 @@ func NewClusterQueueReconciler(
 		log:        ctrl.Log.WithName("cluster-queue-reconciler"),
 		qManager:   qMgr,
 		cache:      cache,
+		recorder:   recorder,
 		wlUpdateCh: make(chan event.GenericEvent, updateChBuffer),
 		rfUpdateCh: make(chan event.GenericEvent, updateChBuffer),
+		cqUpdateCh: make(chan event.GenericEvent, updateChBuffer),
 		watchers:   watchers,
 	}
 }
@@ -111,8 +134,9 @@ func (r *ClusterQueueReconciler) Reconcile(ctx context.Context, req ctrl.Request
 				if err := r.client.Update(ctx, &cqObj); err != nil {
 					return ctrl.Result{}, client.IgnoreNotFound(err)
 				}
+				return ctrl.Result{}, nil
 			}
-			return ctrl.Result{}, nil
+			return ctrl.Result{}, r.terminate(ctx, &cqObj)
 		}
 	}
 
@@ -127,6 +151,11 @@ func (r *ClusterQueueReconciler) Reconcile(ctx context.Context, req ctrl.Request
 		if err := r.updateCqStatusIfChanged(ctx, newCQObj, metav1.ConditionFalse, "Terminating", msg); err != nil {
 			return ctrl.Result{}, client.IgnoreNotFound(err)
 		}
+	} else if r.cache.ClusterQueueAdmissionChecksInactive(newCQObj.Name) {
+		msg := "Can't admit new workloads; some admission checks are not found or not active"
+		if err := r.updateCqStatusIfChanged(ctx, newCQObj, metav1.ConditionFalse, "AdmissionCheckInactive", msg); err != nil {
+			return ctrl.Result{}, client.IgnoreNotFound(err)
+		}
 	} else {
 		msg := "Can't admit new workloads; some flavors are not found"
 		if err := r.updateCqStatusIfChanged(ctx, newCQObj, metav1.ConditionFalse, "FlavorNotFound", msg); err != nil {
@@ -134,9 +163,69 @@ func (r *ClusterQueueReconciler) Reconcile(ctx context.Context, req ctrl.Request
 		}
 	}
 
+	// Re-evaluate the quota windows when they start or end: the quota they
+	// change can make the inadmissible workloads fit.
+	now := time.Now()
+	changed, next := r.cache.RefreshQuotaWindows(cqObj.Name, now)
+	if changed {
+		log.V(2).Info("Quota windows changed, requeueing inadmissible workloads")
+		r.qManager.QueueInadmissibleWorkloads(ctx, sets.NewString(cqObj.Name))
+	}
+	// Likewise, a new budget period can make them admissible.
+	rolled, nextPeriod := r.cache.RefreshBudgets(cqObj.Name, now)
+	if rolled {
+		log.V(2).Info("Budget period started, requeueing inadmissible workloads")
+		r.qManager.QueueInadmissibleWorkloads(ctx, sets.NewString(cqObj.Name))
+	}
+	if !nextPeriod.IsZero() {
+		// Refresh the usage of the budgets in the status periodically.
+		if refresh := now.Add(budgetStatusRefreshInterval); refresh.Before(nextPeriod) {
+			nextPeriod = refresh
+		}
+		if next.IsZero() || nextPeriod.Before(next) {
+			next = nextPeriod
+		}
+	}
+	if !next.IsZero() {
+		return ctrl.Result{RequeueAfter: time.Until(next)}, nil
+	}
 	return ctrl.Result{}, nil
 }
 
+// terminate reports the progress of the deletion of the clusterQueue in its
+// status and, if the deletion policy is Drain, evicts its admitted workloads.
+// The finalizer is removed once the workloads are gone from the cache.
+func (r *ClusterQueueReconciler) terminate(ctx context.Context, cq *kueue.ClusterQueue) error {
+	log := ctrl.LoggerFrom(ctx)
+	workloads := r.cache.AdmittedWorkloads(cq.Name)
+	drain := cq.Spec.DeletionPolicy == kueue.DrainDeletion
+	reason := "Terminating"
+	msg := fmt.Sprintf("Can't admit new workloads; clusterQueue is terminating, waiting for %d admitted workloads to finish", len(workloads))
+	if drain {
+		reason = "Draining"
+		msg = fmt.Sprintf("Can't admit new workloads; clusterQueue is terminating, evicting %d admitted workloads", len(workloads))
+	}
+	if err := r.updateCqStatusIfChanged(ctx, cq.DeepCopy(), metav1.ConditionFalse, reason, msg); err != nil {
+		return client.IgnoreNotFound(err)
+	}
+	if !drain {
+		return nil
+	}
+
+	var errs []error
+	for _, wl := range workloads {
+		if err := client.IgnoreNotFound(workload.Evict(ctx, r.client, wl, ReasonClusterQueueDeleted, "Evicted because the ClusterQueue is being deleted")); err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		log.V(2).Info("Evicted workload from terminating clusterQueue", "workload", klog.KObj(wl))
+	}
+	if len(errs) > 0 {
+		return fmt.Errorf("evicting %d workloads: %w", len(errs), errs[0])
+	}
+	return nil
+}
+
 func (r *ClusterQueueReconciler) NotifyWorkloadUpdate(w *kueue.Workload) {
 	r.wlUpdateCh <- event.GenericEvent{Object: w}
 }
@@ -207,15 +296,55 @@ func (r *ClusterQueueReconciler) Update(e event.UpdateEvent) bool {
 	}
 	defer r.notifyWatchers(oldCq, newCq)
 
+	cohortChanged := oldCq.Spec.Cohort != newCq.Spec.Cohort
+	var oldMembers []string
+	if cohortChanged {
+		oldMembers = r.cache.ClusterQueuesInCohortOf(newCq.Name)
+	}
 	if err := r.cache.UpdateClusterQueue(newCq); err != nil {
 		log.Error(err, "Failed to update clusterQueue in cache")
 	}
+	if resyncRequested(oldCq, newCq) {
+		log.V(2).Info("Resyncing clusterQueue in cache", "resync", newCq.Annotations[constants.ResyncAnnotation])
+		ctx := ctrl.LoggerInto(context.Background(), log)
+		if err := r.cache.ResyncClusterQueue(ctx, newCq); err != nil {
+			log.Error(err, "Failed to resync clusterQueue in cache")
+		}
+	}
+	// Updating the clusterQueue in the queue manager requeues the inadmissible
+	// workloads of the cohort, which might fit after a resync.
+	// It also requeues those of the old cohort when the clusterQueue moves.
 	if err := r.qManager.UpdateClusterQueue(context.Background(), newCq); err != nil {
 		log.Error(err, "Failed to update clusterQueue in queue manager")
 	}
+	if cohortChanged {
+		r.rebalanceCohorts(log, oldCq, newCq, oldMembers)
+	}
 	return true
 }
 
+// rebalanceCohorts records the move of the clusterQueue to another cohort and
+// signals the controller to reconcile the members of the old and new cohorts,
+// as the quota they share with the clusterQueue changed.
+func (r *ClusterQueueReconciler) rebalanceCohorts(log logr.Logger, oldCq, newCq *kueue.ClusterQueue, oldMembers []string) {
+	newMembers := r.cache.ClusterQueuesInCohortOf(newCq.Name)
+	admitted := len(r.cache.AdmittedWorkloads(newCq.Name))
+	log.V(2).Info("ClusterQueue moved to another cohort", "oldCohort", oldCq.Spec.Cohort, "newCohort", newCq.Spec.Cohort, "admittedWorkloads", admitted)
+	r.recorder.Eventf(newCq, corev1.EventTypeNormal, ReasonCohortChanged,
+		"Moved from cohort %q to cohort %q with the usage of %d admitted workloads; requeued the inadmissible workloads of both cohorts",
+		oldCq.Spec.Cohort, newCq.Spec.Cohort, admitted)
+	for _, name := range append(oldMembers, newMembers...) {
+		r.cqUpdateCh <- event.GenericEvent{Object: &kueue.ClusterQueue{ObjectMeta: metav1.ObjectMeta{Name: name}}}
+	}
+}
+
+// resyncRequested returns whether the value of the resync annotation was set
+// or changed in the update of the clusterQueue.
+func resyncRequested(oldCq, newCq *kueue.ClusterQueue) bool {
+	v := newCq.Annotations[constants.ResyncAnnotation]
+	return v != "" && v != oldCq.Annotations[constants.ResyncAnnotation]
+}
+
 func (r *ClusterQueueReconciler) Generic(e event.GenericEvent) bool {
 	r.log.V(2).Info("Got generic event", "obj", klog.KObj(e.Object), "kind", e.Object.GetObjectKind().GroupVersionKind())
 	return true
@@ -227,6 +356,7 @@ func (r *ClusterQueueReconciler) Generic(e event.GenericEvent) bool {
 // receive events.
 type cqWorkloadHandler struct {
 	qManager *queue.Manager
+	cache    *cache.Cache
 }
 
 func (h *cqWorkloadHandler) Create(event.CreateEvent, workqueue.RateLimitingInterface) {
@@ -244,12 +374,19 @@ func (h *cqWorkloadHandler) Generic(e event.GenericEvent, q workqueue.RateLimiti
 	if req != nil {
 		q.AddAfter(*req, constants.UpdatesBatchPeriod)
 	}
+	if w.Status.Admission != nil {
+		// The quota that the ClusterQueue shares with its cohort changed, so
+		// the status of the other members might need to be updated too.
+		for _, name := range h.cache.ClusterQueuesInCohortOf(string(w.Status.Admission.ClusterQueue)) {
+			q.AddAfter(reconcile.Request{NamespacedName: types.NamespacedName{Name: name}}, constants.UpdatesBatchPeriod)
+		}
+	}
 }
 
 func (h *cqWorkloadHandler) requestForWorkloadClusterQueue(w *kueue.Workload) *reconcile.Request {
 	var name string
-	if w.Spec.Admission != nil {
-		name = string(w.Spec.Admission.ClusterQueue)
+	if w.Status.Admission != nil {
+		name = string(w.Status.Admission.ClusterQueue)
 	} else {
 		var ok bool
 		name, ok = h.qManager.ClusterQueueForWorkload(w)
@@ -284,7 +421,10 @@ func (h *cqNamespaceHandler) Update(e event.UpdateEvent, q workqueue.RateLimitin
 			cqs.Insert(cq)
 		}
 	}
-	h.qManager.QueueInadmissibleWorkloads(context.Background(), cqs)
+	if len(cqs) == 0 {
+		return
+	}
+	h.qManager.QueueInadmissibleWorkloadsInNamespace(context.Background(), cqs, newNs.Name)
 }
 
 func (h *cqNamespaceHandler) Delete(event.DeleteEvent, workqueue.RateLimitingInterface) {
@@ -323,10 +463,82 @@ func (h *cqResourceFlavorHandler) Generic(e event.GenericEvent, q workqueue.Rate
 	}
 }
 
+// cqCohortHandler signals the controller to reconcile the ClusterQueues in the
+// trees of cohorts that a Cohort joins or leaves, as their cohorts might become
+// valid or invalid.
+type cqCohortHandler struct {
+	cache *cache.Cache
+}
+
+func (h *cqCohortHandler) Create(e event.CreateEvent, q workqueue.RateLimitingInterface) {
+	h.addClusterQueuesInTrees(e.Object, q)
+}
+
+func (h *cqCohortHandler) Update(e event.UpdateEvent, q workqueue.RateLimitingInterface) {
+	h.addClusterQueuesInTrees(e.ObjectOld, q)
+	h.addClusterQueuesInTrees(e.ObjectNew, q)
+}
+
+func (h *cqCohortHandler) Delete(e event.DeleteEvent, q workqueue.RateLimitingInterface) {
+	h.addClusterQueuesInTrees(e.Object, q)
+}
+
+func (h *cqCohortHandler) Generic(event.GenericEvent, workqueue.RateLimitingInterface) {
+}
+
+func (h *cqCohortHandler) addClusterQueuesInTrees(obj client.Object, q workqueue.RateLimitingInterface) {
+	cohort, ok := obj.(*kueue.Cohort)
+	if !ok {
+		return
+	}
+	names := sets.NewString(h.cache.ClusterQueuesInCohortTree(cohort.Name)...)
+	if cohort.Spec.Parent != "" {
+		names.Insert(h.cache.ClusterQueuesInCohortTree(cohort.Spec.Parent)...)
+	}
+	// Give the Cohort reconciler time to update the cache.
+	for name := range names {
+		q.AddAfter(reconcile.Request{NamespacedName: types.NamespacedName{Name: name}}, constants.UpdatesBatchPeriod)
+	}
+}
+
+// cqAdmissionCheckHandler signals the controller to reconcile the
+// ClusterQueues that use an AdmissionCheck when it changes, as they might
+// become active or inactive.
+type cqAdmissionCheckHandler struct {
+	cache *cache.Cache
+}
+
+func (h *cqAdmissionCheckHandler) Create(e event.CreateEvent, q workqueue.RateLimitingInterface) {
+	h.addClusterQueuesUsing(e.Object, q)
+}
+
+func (h *cqAdmissionCheckHandler) Update(e event.UpdateEvent, q workqueue.RateLimitingInterface) {
+	h.addClusterQueuesUsing(e.ObjectNew, q)
+}
+
+func (h *cqAdmissionCheckHandler) Delete(e event.DeleteEvent, q workqueue.RateLimitingInterface) {
+	h.addClusterQueuesUsing(e.Object, q)
+}
+
+func (h *cqAdmissionCheckHandler) Generic(event.GenericEvent, workqueue.RateLimitingInterface) {
+}
+
+func (h *cqAdmissionCheckHandler) addClusterQueuesUsing(obj client.Object, q workqueue.RateLimitingInterface) {
+	ac, ok := obj.(*kueue.AdmissionCheck)
+	if !ok {
+		return
+	}
+	// Give the AdmissionCheck reconciler time to update the cache.
+	for _, name := range h.cache.ClusterQueuesUsingAdmissionCheck(ac.Name) {
+		q.AddAfter(reconcile.Request{NamespacedName: types.NamespacedName{Name: name}}, constants.UpdatesBatchPeriod)
+	}
+}
+
 // SetupWithManager sets up the controller with the Manager.
 func (r *ClusterQueueReconciler) SetupWithManager(mgr ctrl.Manager) error {
 	wHandler := cqWorkloadHandler{
 		qManager: r.qManager,
+		cache:    r.cache,
 	}
 	nsHandler := cqNamespaceHandler{
 		qManager: r.qManager,
@@ -338,8 +550,11 @@ func (r *ClusterQueueReconciler) SetupWithManager(mgr ctrl.Manager) error {
 	return ctrl.NewControllerManagedBy(mgr).
 		For(&kueue.ClusterQueue{}).
 		Watches(&source.Kind{Type: &corev1.Namespace{}}, &nsHandler).
+		Watches(&source.Kind{Type: &kueue.Cohort{}}, &cqCohortHandler{cache: r.cache}).
+		Watches(&source.Kind{Type: &kueue.AdmissionCheck{}}, &cqAdmissionCheckHandler{cache: r.cache}).
 		Watches(&source.Channel{Source: r.wlUpdateCh}, &wHandler).
 		Watches(&source.Channel{Source: r.rfUpdateCh}, &rfHandler).
+		Watches(&source.Channel{Source: r.cqUpdateCh}, &handler.EnqueueRequestForObject{}).
 		WithEventFilter(r).
 		Complete(r)
 }
@@ -359,7 +574,21 @@ func (r *ClusterQueueReconciler) updateCqStatusIfChanged(
 		// but we didn't process that event yet.
 		return err
 	}
+	quotaSharing, err := r.cache.QuotaSharing(cq)
+	if err != nil {
+		r.log.Error(err, "Failed getting quota sharing from cache")
+		return err
+	}
+	policies, err := r.cache.Policies(cq)
+	if err != nil {
+		r.log.Error(err, "Failed getting the effective policies from cache")
+		return err
+	}
 	cq.Status.UsedResources = usage
+	cq.Status.QuotaSharing = quotaSharing
+	cq.Status.EffectivePolicies = policies
+	cq.Status.Budgets = r.cache.BudgetStatus(cq.Name, time.Now())
+	metrics.ReportClusterQueueBudgets(cq.Name, cq.Status.Budgets)
 	cq.Status.AdmittedWorkloads = int32(workloads)
 	cq.Status.PendingWorkloads = int32(pendingWorkloads)
 	meta.SetStatusCondition(&cq.Status.Conditions, metav1.Condition{
@@ -368,6 +597,36 @@ func (r *ClusterQueueReconciler) updateCqStatusIfChanged(
 		Reason:  reason,
 		Message: msg,
 	})
+	if flavors := r.cache.TerminatingFlavors(cq.Name); len(flavors) > 0 {
+		meta.SetStatusCondition(&cq.Status.Conditions, metav1.Condition{
+			Type:    kueue.ClusterQueueDegraded,
+			Status:  metav1.ConditionTrue,
+			Reason:  "FlavorTerminating",
+			Message: fmt.Sprintf("Flavors %v are being deleted and aren't assigned to new workloads", flavors),
+		})
+	} else if meta.FindStatusCondition(cq.Status.Conditions, kueue.ClusterQueueDegraded) != nil {
+		meta.SetStatusCondition(&cq.Status.Conditions, metav1.Condition{
+			Type:    kueue.ClusterQueueDegraded,
+			Status:  metav1.ConditionFalse,
+			Reason:  "FlavorsAvailable",
+			Message: "All the flavors can be assigned to new workloads",
+		})
+	}
+	if reason, msg := r.cache.CohortProblem(cq.Name); reason != "" {
+		meta.SetStatusCondition(&cq.Status.Conditions, metav1.Condition{
+			Type:    kueue.ClusterQueueInvalidCohort,
+			Status:  metav1.ConditionTrue,
+			Reason:  reason,
+			Message: msg,
+		})
+	} else if meta.FindStatusCondition(cq.Status.Conditions, kueue.ClusterQueueInvalidCohort) != nil {
+		meta.SetStatusCondition(&cq.Status.Conditions, metav1.Condition{
+			Type:    kueue.ClusterQueueInvalidCohort,
+			Status:  metav1.ConditionFalse,
+			Reason:  "ValidCohort",
+			Message: "The tree of cohorts has neither cycles nor orphan parents",
+		})
+	}
 	if !equality.Semantic.DeepEqual(cq.Status, oldStatus) {
 		return r.client.Status().Update(ctx, cq)
 	}
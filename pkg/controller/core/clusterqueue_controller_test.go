@@ -19,19 +19,24 @@ package core
 import (
 	"context"
 	"testing"
+	"time"
 
 	"github.com/go-logr/logr/testr"
 	"github.com/google/go-cmp/cmp"
 	"github.com/google/go-cmp/cmp/cmpopts"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
 	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
 
 	kueue "sigs.k8s.io/kueue/apis/kueue/v1alpha2"
 	"sigs.k8s.io/kueue/pkg/cache"
 	"sigs.k8s.io/kueue/pkg/queue"
 	testingutil "sigs.k8s.io/kueue/pkg/util/testing"
+	"sigs.k8s.io/kueue/pkg/workload"
 )
 
 func TestUpdateCqStatusIfChanged(t *testing.T) {
@@ -43,6 +48,21 @@ func TestUpdateCqStatusIfChanged(t *testing.T) {
 			*testingutil.MakeWorkload("beta", "").Queue(lqName).Obj(),
 		},
 	}
+	wantPolicies := &kueue.ClusterQueuePolicies{
+		QueueingStrategy:       kueue.StrictFIFO,
+		OrderingPolicy:         kueue.PriorityOrdering,
+		LocalQueueFairness:     kueue.LocalQueueFairnessNone,
+		FlavorAssignmentPolicy: kueue.FirstFit,
+		FlavorFungibility: kueue.FlavorFungibility{
+			WhenCanBorrow:    kueue.Borrow,
+			WhenCanPreempt:   kueue.TryNextFlavor,
+			WhenNoFlavorFits: kueue.Wait,
+		},
+		Preemption: kueue.ClusterQueuePreemption{
+			ReclaimWithinCohort: kueue.PreemptionPolicyNever,
+			WithinClusterQueue:  kueue.PreemptionPolicyNever,
+		},
+	}
 
 	testCases := map[string]struct {
 		cqStatus           kueue.ClusterQueueStatus
@@ -58,8 +78,9 @@ func TestUpdateCqStatusIfChanged(t *testing.T) {
 			newReason:          "FlavorNotFound",
 			newMessage:         "Can't admit new workloads; some flavors are not found",
 			wantCqStatus: kueue.ClusterQueueStatus{
-				UsedResources:    kueue.UsedResources{},
-				PendingWorkloads: int32(len(defaultWls.Items)),
+				EffectivePolicies: wantPolicies,
+				UsedResources:     kueue.UsedResources{},
+				PendingWorkloads:  int32(len(defaultWls.Items)),
 				Conditions: []metav1.Condition{{
 					Type:    kueue.ClusterQueueActive,
 					Status:  metav1.ConditionFalse,
@@ -83,8 +104,9 @@ func TestUpdateCqStatusIfChanged(t *testing.T) {
 			newReason:          "Ready",
 			newMessage:         "Can admit new workloads",
 			wantCqStatus: kueue.ClusterQueueStatus{
-				UsedResources:    kueue.UsedResources{},
-				PendingWorkloads: int32(len(defaultWls.Items)),
+				EffectivePolicies: wantPolicies,
+				UsedResources:     kueue.UsedResources{},
+				PendingWorkloads:  int32(len(defaultWls.Items)),
 				Conditions: []metav1.Condition{{
 					Type:    kueue.ClusterQueueActive,
 					Status:  metav1.ConditionTrue,
@@ -108,8 +130,9 @@ func TestUpdateCqStatusIfChanged(t *testing.T) {
 			newReason:          "Terminating",
 			newMessage:         "Can't admit new workloads; clusterQueue is terminating",
 			wantCqStatus: kueue.ClusterQueueStatus{
-				UsedResources:    kueue.UsedResources{},
-				PendingWorkloads: int32(len(defaultWls.Items)),
+				EffectivePolicies: wantPolicies,
+				UsedResources:     kueue.UsedResources{},
+				PendingWorkloads:  int32(len(defaultWls.Items)),
 				Conditions: []metav1.Condition{{
 					Type:    kueue.ClusterQueueActive,
 					Status:  metav1.ConditionFalse,
@@ -133,8 +156,9 @@ func TestUpdateCqStatusIfChanged(t *testing.T) {
 			newReason:          "Ready",
 			newMessage:         "Can admit new workloads",
 			wantCqStatus: kueue.ClusterQueueStatus{
-				UsedResources:    kueue.UsedResources{},
-				PendingWorkloads: int32(len(defaultWls.Items)),
+				EffectivePolicies: wantPolicies,
+				UsedResources:     kueue.UsedResources{},
+				PendingWorkloads:  int32(len(defaultWls.Items)),
 				Conditions: []metav1.Condition{{
 					Type:    kueue.ClusterQueueActive,
 					Status:  metav1.ConditionTrue,
@@ -159,8 +183,9 @@ func TestUpdateCqStatusIfChanged(t *testing.T) {
 			newReason:          "Ready",
 			newMessage:         "Can admit new workloads",
 			wantCqStatus: kueue.ClusterQueueStatus{
-				UsedResources:    kueue.UsedResources{},
-				PendingWorkloads: int32(len(defaultWls.Items) + 1),
+				EffectivePolicies: wantPolicies,
+				UsedResources:     kueue.UsedResources{},
+				PendingWorkloads:  int32(len(defaultWls.Items) + 1),
 				Conditions: []metav1.Condition{{
 					Type:    kueue.ClusterQueueActive,
 					Status:  metav1.ConditionTrue,
@@ -224,3 +249,97 @@ func TestUpdateCqStatusIfChanged(t *testing.T) {
 		})
 	}
 }
+
+func TestReconcileTerminatingClusterQueue(t *testing.T) {
+	cqName := "test-cq"
+	lqName := "test-lq"
+
+	testCases := map[string]struct {
+		deletionPolicy kueue.DeletionPolicy
+		wantAdmitted   bool
+		wantCondition  metav1.Condition
+	}{
+		"default policy waits for the admitted workloads": {
+			wantAdmitted: true,
+			wantCondition: metav1.Condition{
+				Type:    kueue.ClusterQueueActive,
+				Status:  metav1.ConditionFalse,
+				Reason:  "Terminating",
+				Message: "Can't admit new workloads; clusterQueue is terminating, waiting for 1 admitted workloads to finish",
+			},
+		},
+		"drain policy evicts the admitted workloads": {
+			deletionPolicy: kueue.DrainDeletion,
+			wantCondition: metav1.Condition{
+				Type:    kueue.ClusterQueueActive,
+				Status:  metav1.ConditionFalse,
+				Reason:  "Draining",
+				Message: "Can't admit new workloads; clusterQueue is terminating, evicting 1 admitted workloads",
+			},
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			cq := testingutil.MakeClusterQueue(cqName).DeletionPolicy(tc.deletionPolicy).Obj()
+			cq.Finalizers = []string{kueue.ResourceInUseFinalizerName}
+			cq.DeletionTimestamp = &metav1.Time{Time: time.Now()}
+			lq := testingutil.MakeLocalQueue(lqName, "").ClusterQueue(cqName).Obj()
+			wl := testingutil.MakeWorkload("alpha", "").Queue(lqName).Admit(testingutil.MakeAdmission(cqName).Obj()).Obj()
+			log := testr.NewWithOptions(t, testr.Options{
+				Verbosity: 2,
+			})
+			ctx := ctrl.LoggerInto(context.Background(), log)
+			scheme := runtime.NewScheme()
+			if err := kueue.AddToScheme(scheme); err != nil {
+				t.Fatalf("Failed adding kueue scheme: %v", err)
+			}
+			cl := fake.NewClientBuilder().WithScheme(scheme).WithObjects(lq, cq, wl).Build()
+			cqCache := cache.New(cl)
+			qManager := queue.NewManager(cl, cqCache)
+			if err := cqCache.AddClusterQueue(ctx, cq); err != nil {
+				t.Fatalf("Inserting clusterQueue in cache: %v", err)
+			}
+			if err := qManager.AddClusterQueue(ctx, cq); err != nil {
+				t.Fatalf("Inserting clusterQueue in manager: %v", err)
+			}
+			cqCache.AddOrUpdateWorkload(wl)
+			r := &ClusterQueueReconciler{
+				client:   cl,
+				log:      log,
+				cache:    cqCache,
+				qManager: qManager,
+			}
+
+			if _, err := r.Reconcile(ctx, ctrl.Request{NamespacedName: types.NamespacedName{Name: cqName}}); err != nil {
+				t.Fatalf("Reconciling: %v", err)
+			}
+
+			var gotCq kueue.ClusterQueue
+			if err := cl.Get(ctx, client.ObjectKeyFromObject(cq), &gotCq); err != nil {
+				t.Fatalf("Getting clusterQueue: %v", err)
+			}
+			if !controllerutil.ContainsFinalizer(&gotCq, kueue.ResourceInUseFinalizerName) {
+				t.Error("The finalizer was removed while the clusterQueue had admitted workloads")
+			}
+			if diff := cmp.Diff([]metav1.Condition{tc.wantCondition}, gotCq.Status.Conditions,
+				cmpopts.IgnoreFields(metav1.Condition{}, "LastTransitionTime")); diff != "" {
+				t.Errorf("Unexpected conditions (-want,+got):\n%s", diff)
+			}
+			if gotCq.Status.AdmittedWorkloads != 1 {
+				t.Errorf("Got %d admitted workloads in the status, want 1", gotCq.Status.AdmittedWorkloads)
+			}
+
+			var gotWl kueue.Workload
+			if err := cl.Get(ctx, client.ObjectKeyFromObject(wl), &gotWl); err != nil {
+				t.Fatalf("Getting workload: %v", err)
+			}
+			if admitted := gotWl.Status.Admission != nil; admitted != tc.wantAdmitted {
+				t.Errorf("Workload admitted: %t, want %t", admitted, tc.wantAdmitted)
+			}
+			if evicted := workload.IsEvicted(&gotWl); evicted == tc.wantAdmitted {
+				t.Errorf("Workload evicted: %t, want %t", evicted, !tc.wantAdmitted)
+			}
+		})
+	}
+}
@@ -0,0 +1,150 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package core
+
+import (
+	"context"
+	"sort"
+
+	"github.com/go-logr/logr"
+	"k8s.io/apimachinery/pkg/api/equality"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/util/workqueue"
+	"k8s.io/klog/v2"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	"sigs.k8s.io/controller-runtime/pkg/source"
+
+	kueue "sigs.k8s.io/kueue/apis/kueue/v1alpha2"
+)
+
+// ClusterQueueClassReconciler reconciles a ClusterQueueClass object, applying
+// its settings to the ClusterQueues of the class.
+type ClusterQueueClassReconciler struct {
+	log    logr.Logger
+	client client.Client
+}
+
+func NewClusterQueueClassReconciler(client client.Client) *ClusterQueueClassReconciler {
+	return &ClusterQueueClassReconciler{
+		log:    ctrl.Log.WithName("clusterqueueclass-reconciler"),
+		client: client,
+	}
+}
+
+//+kubebuilder:rbac:groups=kueue.x-k8s.io,resources=clusterqueueclasses,verbs=get;list;watch
+//+kubebuilder:rbac:groups=kueue.x-k8s.io,resources=clusterqueueclasses/status,verbs=get;update;patch
+
+func (r *ClusterQueueClassReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	var class kueue.ClusterQueueClass
+	if err := r.client.Get(ctx, req.NamespacedName, &class); err != nil {
+		// The ClusterQueues of a deleted class keep the settings they got
+		// from it.
+		return ctrl.Result{}, client.IgnoreNotFound(err)
+	}
+	log := ctrl.LoggerFrom(ctx).WithValues("clusterQueueClass", klog.KObj(&class))
+	log.V(2).Info("Reconciling ClusterQueueClass")
+
+	var cqs kueue.ClusterQueueList
+	if err := r.client.List(ctx, &cqs); err != nil {
+		return ctrl.Result{}, err
+	}
+	var members []string
+	for i := range cqs.Items {
+		cq := &cqs.Items[i]
+		if cq.Spec.ClassName != class.Name {
+			continue
+		}
+		members = append(members, cq.Name)
+		spec := cq.Spec.DeepCopy()
+		applyClusterQueueClass(spec, &class.Spec)
+		if equality.Semantic.DeepEqual(&cq.Spec, spec) {
+			continue
+		}
+		log.V(2).Info("Applying the class to the ClusterQueue", "clusterQueue", klog.KObj(cq))
+		cq.Spec = *spec
+		if err := r.client.Update(ctx, cq); err != nil {
+			return ctrl.Result{}, client.IgnoreNotFound(err)
+		}
+	}
+
+	sort.Strings(members)
+	if equality.Semantic.DeepEqual(class.Status.ClusterQueues, members) {
+		return ctrl.Result{}, nil
+	}
+	class.Status.ClusterQueues = members
+	err := r.client.Status().Update(ctx, &class)
+	return ctrl.Result{}, client.IgnoreNotFound(err)
+}
+
+// applyClusterQueueClass replaces the fields of the ClusterQueue spec that are
+// set in the class. The resources and resourceGroups are replaced together.
+func applyClusterQueueClass(spec *kueue.ClusterQueueSpec, class *kueue.ClusterQueueClassSpec) {
+	if len(class.Resources) > 0 || len(class.ResourceGroups) > 0 {
+		classSpec := class.DeepCopy()
+		spec.Resources = classSpec.Resources
+		spec.ResourceGroups = classSpec.ResourceGroups
+	}
+	if len(class.Cohort) > 0 {
+		spec.Cohort = class.Cohort
+	}
+	if class.NamespaceSelector != nil {
+		spec.NamespaceSelector = class.NamespaceSelector.DeepCopy()
+	}
+	if class.Preemption != nil {
+		spec.Preemption = class.Preemption.DeepCopy()
+	}
+}
+
+// cqClassClusterQueueHandler signals the controller to reconcile the classes
+// that a ClusterQueue joins or leaves, so that new ClusterQueues get the
+// settings of their class and the status lists the current members.
+type cqClassClusterQueueHandler struct{}
+
+func (h *cqClassClusterQueueHandler) Create(e event.CreateEvent, q workqueue.RateLimitingInterface) {
+	h.addClassOfClusterQueue(e.Object, q)
+}
+
+func (h *cqClassClusterQueueHandler) Update(e event.UpdateEvent, q workqueue.RateLimitingInterface) {
+	h.addClassOfClusterQueue(e.ObjectOld, q)
+	h.addClassOfClusterQueue(e.ObjectNew, q)
+}
+
+func (h *cqClassClusterQueueHandler) Delete(e event.DeleteEvent, q workqueue.RateLimitingInterface) {
+	h.addClassOfClusterQueue(e.Object, q)
+}
+
+func (h *cqClassClusterQueueHandler) Generic(event.GenericEvent, workqueue.RateLimitingInterface) {
+}
+
+func (h *cqClassClusterQueueHandler) addClassOfClusterQueue(obj client.Object, q workqueue.RateLimitingInterface) {
+	cq, ok := obj.(*kueue.ClusterQueue)
+	if !ok || len(cq.Spec.ClassName) == 0 {
+		return
+	}
+	q.Add(reconcile.Request{NamespacedName: types.NamespacedName{Name: cq.Spec.ClassName}})
+}
+
+// SetupWithManager sets up the controller with the Manager.
+func (r *ClusterQueueClassReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewControllerManagedBy(mgr).
+		For(&kueue.ClusterQueueClass{}).
+		Watches(&source.Kind{Type: &kueue.ClusterQueue{}}, &cqClassClusterQueueHandler{}).
+		Complete(r)
+}
@@ -0,0 +1,143 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package core
+
+import (
+	"context"
+	"testing"
+
+	"github.com/go-logr/logr/testr"
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	kueue "sigs.k8s.io/kueue/apis/kueue/v1alpha2"
+	testingutil "sigs.k8s.io/kueue/pkg/util/testing"
+)
+
+func TestReconcileClusterQueueClass(t *testing.T) {
+	class := testingutil.MakeClusterQueueClass("gpu").
+		Cohort("research").
+		Resource(testingutil.MakeResource(corev1.ResourceCPU).
+			Flavor(testingutil.MakeFlavor("default", "10").Obj()).Obj()).
+		NamespaceSelector(&metav1.LabelSelector{}).
+		Obj()
+
+	cases := map[string]struct {
+		clusterQueues []*kueue.ClusterQueue
+		wantSpecs     map[string]kueue.ClusterQueueSpec
+		wantMembers   []string
+	}{
+		"applies the class to its clusterQueues": {
+			clusterQueues: []*kueue.ClusterQueue{
+				testingutil.MakeClusterQueue("b").ClassName("gpu").Obj(),
+				testingutil.MakeClusterQueue("a").ClassName("gpu").
+					Cohort("other").
+					Resource(testingutil.MakeResource(corev1.ResourceMemory).
+						Flavor(testingutil.MakeFlavor("default", "5Gi").Obj()).Obj()).
+					Obj(),
+			},
+			wantSpecs: map[string]kueue.ClusterQueueSpec{
+				"a": *testingutil.MakeClusterQueue("a").ClassName("gpu").
+					Cohort("research").
+					Resource(testingutil.MakeResource(corev1.ResourceCPU).
+						Flavor(testingutil.MakeFlavor("default", "10").Obj()).Obj()).
+					NamespaceSelector(&metav1.LabelSelector{}).
+					Obj().Spec.DeepCopy(),
+				"b": *testingutil.MakeClusterQueue("b").ClassName("gpu").
+					Cohort("research").
+					Resource(testingutil.MakeResource(corev1.ResourceCPU).
+						Flavor(testingutil.MakeFlavor("default", "10").Obj()).Obj()).
+					NamespaceSelector(&metav1.LabelSelector{}).
+					Obj().Spec.DeepCopy(),
+			},
+			wantMembers: []string{"a", "b"},
+		},
+		"keeps the fields not set in the class": {
+			clusterQueues: []*kueue.ClusterQueue{
+				testingutil.MakeClusterQueue("a").ClassName("gpu").
+					QueueingStrategy(kueue.StrictFIFO).
+					Obj(),
+			},
+			wantSpecs: map[string]kueue.ClusterQueueSpec{
+				"a": *testingutil.MakeClusterQueue("a").ClassName("gpu").
+					QueueingStrategy(kueue.StrictFIFO).
+					Cohort("research").
+					Resource(testingutil.MakeResource(corev1.ResourceCPU).
+						Flavor(testingutil.MakeFlavor("default", "10").Obj()).Obj()).
+					NamespaceSelector(&metav1.LabelSelector{}).
+					Obj().Spec.DeepCopy(),
+			},
+			wantMembers: []string{"a"},
+		},
+		"ignores clusterQueues of other classes": {
+			clusterQueues: []*kueue.ClusterQueue{
+				testingutil.MakeClusterQueue("a").ClassName("cpu").Cohort("other").Obj(),
+				testingutil.MakeClusterQueue("b").Cohort("other").Obj(),
+			},
+			wantSpecs: map[string]kueue.ClusterQueueSpec{
+				"a": *testingutil.MakeClusterQueue("a").ClassName("cpu").Cohort("other").Obj().Spec.DeepCopy(),
+				"b": *testingutil.MakeClusterQueue("b").Cohort("other").Obj().Spec.DeepCopy(),
+			},
+		},
+	}
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			ctx := ctrl.LoggerInto(context.Background(), testr.New(t))
+			scheme := runtime.NewScheme()
+			if err := kueue.AddToScheme(scheme); err != nil {
+				t.Fatalf("Failed adding kueue scheme: %v", err)
+			}
+			builder := fake.NewClientBuilder().WithScheme(scheme).WithObjects(class.DeepCopy())
+			for _, cq := range tc.clusterQueues {
+				builder = builder.WithObjects(cq)
+			}
+			cl := builder.Build()
+			r := NewClusterQueueClassReconciler(cl)
+
+			if _, err := r.Reconcile(ctx, ctrl.Request{NamespacedName: types.NamespacedName{Name: class.Name}}); err != nil {
+				t.Fatalf("Reconciling: %v", err)
+			}
+
+			var cqs kueue.ClusterQueueList
+			if err := cl.List(ctx, &cqs); err != nil {
+				t.Fatalf("Listing clusterQueues: %v", err)
+			}
+			gotSpecs := make(map[string]kueue.ClusterQueueSpec, len(cqs.Items))
+			for _, cq := range cqs.Items {
+				gotSpecs[cq.Name] = cq.Spec
+			}
+			if diff := cmp.Diff(tc.wantSpecs, gotSpecs, cmpopts.EquateEmpty()); diff != "" {
+				t.Errorf("Unexpected clusterQueue specs (-want,+got):\n%s", diff)
+			}
+
+			var gotClass kueue.ClusterQueueClass
+			if err := cl.Get(ctx, client.ObjectKeyFromObject(class), &gotClass); err != nil {
+				t.Fatalf("Getting clusterQueueClass: %v", err)
+			}
+			if diff := cmp.Diff(tc.wantMembers, gotClass.Status.ClusterQueues, cmpopts.EquateEmpty()); diff != "" {
+				t.Errorf("Unexpected members in the status (-want,+got):\n%s", diff)
+			}
+		})
+	}
+}
@@ -0,0 +1,204 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package core
+
+import (
+	"context"
+
+	"github.com/go-logr/logr"
+	"k8s.io/apimachinery/pkg/api/equality"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/client-go/util/workqueue"
+	"k8s.io/klog/v2"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	"sigs.k8s.io/controller-runtime/pkg/source"
+
+	kueue "sigs.k8s.io/kueue/apis/kueue/v1alpha2"
+	"sigs.k8s.io/kueue/pkg/cache"
+	"sigs.k8s.io/kueue/pkg/constants"
+	"sigs.k8s.io/kueue/pkg/queue"
+)
+
+// CohortReconciler reconciles a Cohort object
+type CohortReconciler struct {
+	log        logr.Logger
+	qManager   *queue.Manager
+	cache      *cache.Cache
+	client     client.Client
+	wlUpdateCh chan event.GenericEvent
+}
+
+func NewCohortReconciler(client client.Client, qMgr *queue.Manager, cache *cache.Cache) *CohortReconciler {
+	return &CohortReconciler{
+		log:        ctrl.Log.WithName("cohort-reconciler"),
+		qManager:   qMgr,
+		cache:      cache,
+		client:     client,
+		wlUpdateCh: make(chan event.GenericEvent, updateChBuffer),
+	}
+}
+
+//+kubebuilder:rbac:groups=kueue.x-k8s.io,resources=cohorts,verbs=get;list;watch
+//+kubebuilder:rbac:groups=kueue.x-k8s.io,resources=cohorts/status,verbs=get;update;patch
+
+func (r *CohortReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	var cohort kueue.Cohort
+	if err := r.client.Get(ctx, req.NamespacedName, &cohort); err != nil {
+		if apierrors.IsNotFound(err) && r.cache.DeleteCohort(req.Name) {
+			r.qManager.DeleteCohort(req.Name)
+			r.queueInadmissibleWorkloadsInTree(ctx, req.Name)
+		}
+		return ctrl.Result{}, client.IgnoreNotFound(err)
+	}
+	log := ctrl.LoggerFrom(ctx).WithValues("cohort", klog.KObj(&cohort))
+	log.V(2).Info("Reconciling Cohort")
+
+	if r.cache.AddOrUpdateCohort(&cohort) {
+		// The quota available to the tree of the cohort changed.
+		r.qManager.AddOrUpdateCohort(&cohort)
+		r.queueInadmissibleWorkloadsInTree(ctx, cohort.Name)
+	}
+
+	status := r.cache.CohortStatus(cohort.Name)
+	if equality.Semantic.DeepEqual(cohort.Status, status) {
+		return ctrl.Result{}, nil
+	}
+	cohort.Status = status
+	err := r.client.Status().Update(ctx, &cohort)
+	return ctrl.Result{}, client.IgnoreNotFound(err)
+}
+
+func (r *CohortReconciler) queueInadmissibleWorkloadsInTree(ctx context.Context, name string) {
+	if cqs := r.cache.ClusterQueuesInCohortTree(name); len(cqs) > 0 {
+		r.qManager.QueueInadmissibleWorkloads(ctx, sets.NewString(cqs...))
+	}
+}
+
+func (r *CohortReconciler) NotifyWorkloadUpdate(w *kueue.Workload) {
+	// Only admitted workloads contribute to the usage of a cohort.
+	if w.Status.Admission != nil {
+		r.wlUpdateCh <- event.GenericEvent{Object: w}
+	}
+}
+
+// cohortWorkloadHandler signals the controller to reconcile the Cohort,
+// and its ancestors, of the ClusterQueue that admitted the workload in the
+// event.
+type cohortWorkloadHandler struct {
+	cache *cache.Cache
+}
+
+func (h *cohortWorkloadHandler) Create(event.CreateEvent, workqueue.RateLimitingInterface) {
+}
+
+func (h *cohortWorkloadHandler) Update(event.UpdateEvent, workqueue.RateLimitingInterface) {
+}
+
+func (h *cohortWorkloadHandler) Delete(event.DeleteEvent, workqueue.RateLimitingInterface) {
+}
+
+func (h *cohortWorkloadHandler) Generic(e event.GenericEvent, q workqueue.RateLimitingInterface) {
+	w := e.Object.(*kueue.Workload)
+	for _, name := range h.cache.CohortsOf(string(w.Status.Admission.ClusterQueue)) {
+		q.AddAfter(reconcile.Request{NamespacedName: types.NamespacedName{Name: name}}, constants.UpdatesBatchPeriod)
+	}
+}
+
+// cohortClusterQueueHandler signals the controller to reconcile the Cohorts,
+// and their ancestors, that a ClusterQueue joins or leaves.
+type cohortClusterQueueHandler struct {
+	cache *cache.Cache
+}
+
+func (h *cohortClusterQueueHandler) Create(e event.CreateEvent, q workqueue.RateLimitingInterface) {
+	h.addCohortOfClusterQueue(e.Object, q)
+}
+
+func (h *cohortClusterQueueHandler) Update(e event.UpdateEvent, q workqueue.RateLimitingInterface) {
+	h.addCohortOfClusterQueue(e.ObjectOld, q)
+	h.addCohortOfClusterQueue(e.ObjectNew, q)
+}
+
+func (h *cohortClusterQueueHandler) Delete(e event.DeleteEvent, q workqueue.RateLimitingInterface) {
+	h.addCohortOfClusterQueue(e.Object, q)
+}
+
+func (h *cohortClusterQueueHandler) Generic(event.GenericEvent, workqueue.RateLimitingInterface) {
+}
+
+func (h *cohortClusterQueueHandler) addCohortOfClusterQueue(obj client.Object, q workqueue.RateLimitingInterface) {
+	cq, ok := obj.(*kueue.ClusterQueue)
+	if !ok || len(cq.Spec.Cohort) == 0 {
+		return
+	}
+	addCohortAndAncestors(h.cache, cq.Spec.Cohort, q)
+}
+
+// cohortParentHandler signals the controller to reconcile the ancestors that
+// a Cohort joins or leaves.
+type cohortParentHandler struct {
+	cache *cache.Cache
+}
+
+func (h *cohortParentHandler) Create(e event.CreateEvent, q workqueue.RateLimitingInterface) {
+	h.addParentOfCohort(e.Object, q)
+}
+
+func (h *cohortParentHandler) Update(e event.UpdateEvent, q workqueue.RateLimitingInterface) {
+	h.addParentOfCohort(e.ObjectOld, q)
+	h.addParentOfCohort(e.ObjectNew, q)
+}
+
+func (h *cohortParentHandler) Delete(e event.DeleteEvent, q workqueue.RateLimitingInterface) {
+	h.addParentOfCohort(e.Object, q)
+}
+
+func (h *cohortParentHandler) Generic(event.GenericEvent, workqueue.RateLimitingInterface) {
+}
+
+func (h *cohortParentHandler) addParentOfCohort(obj client.Object, q workqueue.RateLimitingInterface) {
+	cohort, ok := obj.(*kueue.Cohort)
+	if !ok || len(cohort.Spec.Parent) == 0 {
+		return
+	}
+	addCohortAndAncestors(h.cache, cohort.Spec.Parent, q)
+}
+
+func addCohortAndAncestors(c *cache.Cache, name string, q workqueue.RateLimitingInterface) {
+	// Give the reconcilers time to update the cache.
+	for _, name := range c.CohortAndAncestors(name) {
+		q.AddAfter(reconcile.Request{NamespacedName: types.NamespacedName{Name: name}}, constants.UpdatesBatchPeriod)
+	}
+}
+
+// SetupWithManager sets up the controller with the Manager.
+func (r *CohortReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	wHandler := cohortWorkloadHandler{
+		cache: r.cache,
+	}
+	return ctrl.NewControllerManagedBy(mgr).
+		For(&kueue.Cohort{}).
+		Watches(&source.Kind{Type: &kueue.Cohort{}}, &cohortParentHandler{cache: r.cache}).
+		Watches(&source.Kind{Type: &kueue.ClusterQueue{}}, &cohortClusterQueueHandler{cache: r.cache}).
+		Watches(&source.Channel{Source: r.wlUpdateCh}, &wHandler).
+		Complete(r)
+}
@@ -20,14 +20,16 @@ import (
 	ctrl "sigs.k8s.io/controller-runtime"
 
 	"sigs.k8s.io/kueue/pkg/cache"
+	"sigs.k8s.io/kueue/pkg/constants"
 	"sigs.k8s.io/kueue/pkg/queue"
 )
 
 const updateChBuffer = 10
 
 // SetupControllers sets up the core controllers. It returns the name of the
-// controller that failed to create and an error, if any.
-func SetupControllers(mgr ctrl.Manager, qManager *queue.Manager, cc *cache.Cache) (string, error) {
+// controller that failed to create and an error, if any. The options are
+// passed to the Workload reconciler.
+func SetupControllers(mgr ctrl.Manager, qManager *queue.Manager, cc *cache.Cache, wlOpts ...WorkloadReconcilerOption) (string, error) {
 	rfRec := NewResourceFlavorReconciler(mgr.GetClient(), qManager, cc)
 	if err := rfRec.SetupWithManager(mgr); err != nil {
 		return "ResourceFlavor", err
@@ -36,12 +38,25 @@ func SetupControllers(mgr ctrl.Manager, qManager *queue.Manager, cc *cache.Cache
 	if err := qRec.SetupWithManager(mgr); err != nil {
 		return "LocalQueue", err
 	}
-	cqRec := NewClusterQueueReconciler(mgr.GetClient(), qManager, cc, rfRec)
+	cqRec := NewClusterQueueReconciler(mgr.GetClient(), qManager, cc, mgr.GetEventRecorderFor(constants.ClusterQueueControllerName), rfRec)
 	rfRec.AddUpdateWatcher(cqRec)
 	if err := cqRec.SetupWithManager(mgr); err != nil {
 		return "ClusterQueue", err
 	}
-	if err := NewWorkloadReconciler(mgr.GetClient(), qManager, cc, qRec, cqRec).SetupWithManager(mgr); err != nil {
+	cohortRec := NewCohortReconciler(mgr.GetClient(), qManager, cc)
+	if err := cohortRec.SetupWithManager(mgr); err != nil {
+		return "Cohort", err
+	}
+	if err := NewClusterQueueClassReconciler(mgr.GetClient()).SetupWithManager(mgr); err != nil {
+		return "ClusterQueueClass", err
+	}
+	if err := NewAdmissionCheckReconciler(mgr.GetClient(), qManager, cc).SetupWithManager(mgr); err != nil {
+		return "AdmissionCheck", err
+	}
+	wlOpts = append(wlOpts,
+		WithWorkloadUpdateWatchers(qRec, cqRec, cohortRec, rfRec),
+		WithEventRecorder(mgr.GetEventRecorderFor(constants.WorkloadControllerName)))
+	if err := NewWorkloadReconciler(mgr.GetClient(), qManager, cc, wlOpts...).SetupWithManager(mgr); err != nil {
 		return "Workload", err
 	}
 	return "", nil
@@ -22,6 +22,7 @@ import (
 	"github.com/go-logr/logr"
 	"k8s.io/apimachinery/pkg/api/equality"
 	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/sets"
 	"k8s.io/client-go/util/workqueue"
 	"k8s.io/klog/v2"
 	ctrl "sigs.k8s.io/controller-runtime"
@@ -100,6 +101,10 @@ func (r *LocalQueueReconciler) Create(e event.CreateEvent) bool {
 	}
 	log := r.log.WithValues("localQueue", klog.KObj(q))
 	log.V(2).Info("LocalQueue create event")
+	if !q.DeletionTimestamp.IsZero() {
+		log.V(2).Info("LocalQueue is being deleted; ignored")
+		return true
+	}
 	ctx := logr.NewContext(context.Background(), log)
 	if err := r.queues.AddLocalQueue(ctx, q); err != nil {
 		log.Error(err, "Failed to add localQueue to the queueing system")
@@ -130,6 +135,14 @@ func (r *LocalQueueReconciler) Update(e event.UpdateEvent) bool {
 	}
 	log := r.log.WithValues("localQueue", klog.KObj(q))
 	log.V(2).Info("Queue update event")
+	if !q.DeletionTimestamp.IsZero() {
+		// Finalizers can delay the removal of the LocalQueue, typically when
+		// its namespace is deleted. Its workloads shouldn't be admitted in
+		// the meantime.
+		r.queues.DeleteLocalQueue(q)
+		r.cache.DeleteLocalQueue(q)
+		return true
+	}
 	if err := r.queues.UpdateLocalQueue(q); err != nil {
 		log.Error(err, "Failed to update queue in the queueing system")
 	}
@@ -137,6 +150,11 @@ func (r *LocalQueueReconciler) Update(e event.UpdateEvent) bool {
 	if err := r.cache.UpdateLocalQueue(oldQ, q); err != nil {
 		log.Error(err, "Failed to update localQueue in the cache")
 	}
+	if !equality.Semantic.DeepEqual(oldQ.Spec.Limits, q.Spec.Limits) || !equality.Semantic.DeepEqual(oldQ.Spec.LimitsPercent, q.Spec.LimitsPercent) {
+		// Workloads that exceeded the old limits might be admissible now.
+		ctx := logr.NewContext(context.Background(), log)
+		r.queues.QueueInadmissibleWorkloads(ctx, sets.NewString(queue.ClusterQueuesOf(q)...))
+	}
 	return true
 }
 
@@ -18,6 +18,7 @@ package core
 
 import (
 	"context"
+	"fmt"
 
 	"github.com/go-logr/logr"
 	"k8s.io/apimachinery/pkg/types"
@@ -31,11 +32,19 @@ import (
 	"sigs.k8s.io/controller-runtime/pkg/reconcile"
 	"sigs.k8s.io/controller-runtime/pkg/source"
 
+	config "sigs.k8s.io/kueue/apis/config/v1alpha2"
 	kueue "sigs.k8s.io/kueue/apis/kueue/v1alpha2"
 	"sigs.k8s.io/kueue/pkg/cache"
 	"sigs.k8s.io/kueue/pkg/queue"
+	"sigs.k8s.io/kueue/pkg/util/api"
+	"sigs.k8s.io/kueue/pkg/workload"
 )
 
+// ReasonResourceFlavorDeleted is the reason of the Evicted condition of the
+// workloads evicted because a ResourceFlavor assigned to them is being
+// deleted.
+const ReasonResourceFlavorDeleted = "ResourceFlavorDeleted"
+
 type ResourceFlavorUpdateWatcher interface {
 	NotifyResourceFlavorUpdate(*kueue.ResourceFlavor)
 }
@@ -47,6 +56,7 @@ type ResourceFlavorReconciler struct {
 	cache      *cache.Cache
 	client     client.Client
 	cqUpdateCh chan event.GenericEvent
+	wlUpdateCh chan event.GenericEvent
 	watchers   []ResourceFlavorUpdateWatcher
 }
 
@@ -61,11 +71,14 @@ func NewResourceFlavorReconciler(
 		client:     client,
 		qManager:   qMgr,
 		cqUpdateCh: make(chan event.GenericEvent, updateChBuffer),
+		wlUpdateCh: make(chan event.GenericEvent, updateChBuffer),
 	}
 }
 
 //+kubebuilder:rbac:groups=kueue.x-k8s.io,resources=resourceflavors,verbs=get;list;watch;update;delete
 //+kubebuilder:rbac:groups=kueue.x-k8s.io,resources=resourceflavors/finalizers,verbs=update
+//+kubebuilder:rbac:groups=kueue.x-k8s.io,resources=workloads,verbs=get;list;watch;update
+//+kubebuilder:rbac:groups=kueue.x-k8s.io,resources=workloads/status,verbs=get;update;patch
 
 func (r *ResourceFlavorReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
 	var flavor kueue.ResourceFlavor
@@ -88,12 +101,8 @@ func (r *ResourceFlavorReconciler) Reconcile(ctx context.Context, req ctrl.Reque
 		}
 	} else {
 		if controllerutil.ContainsFinalizer(&flavor, kueue.ResourceInUseFinalizerName) {
-			if cqs := r.cache.ClusterQueuesUsingFlavor(flavor.Name); len(cqs) != 0 {
-				log.V(3).Info("resourceFlavor is still in use", "ClusterQueues", cqs)
-				// We avoid to return error here to prevent backoff requeue, which is passive and wasteful.
-				// Instead, we drive the removal of finalizer by ClusterQueue Update/Delete events
-				// when resourceFlavor is no longer in use.
-				return ctrl.Result{}, nil
+			if inUse, err := r.inUse(ctx, &flavor); inUse || err != nil {
+				return ctrl.Result{}, err
 			}
 
 			controllerutil.RemoveFinalizer(&flavor, kueue.ResourceInUseFinalizerName)
@@ -106,6 +115,48 @@ func (r *ResourceFlavorReconciler) Reconcile(ctx context.Context, req ctrl.Reque
 	return ctrl.Result{}, nil
 }
 
+// inUse returns whether the ResourceFlavor that is being deleted is still in
+// use, according to the deletion policy. With the Evict policy, it evicts
+// the admitted workloads that use it.
+func (r *ResourceFlavorReconciler) inUse(ctx context.Context, flavor *kueue.ResourceFlavor) (bool, error) {
+	log := ctrl.LoggerFrom(ctx)
+	policy := r.cache.FlavorDeletionPolicy()
+	if policy == config.ResourceFlavorDeletionBlock {
+		if cqs := r.cache.ClusterQueuesUsingFlavor(flavor.Name); len(cqs) != 0 {
+			log.V(3).Info("resourceFlavor is still in use", "ClusterQueues", cqs)
+			// We avoid to return error here to prevent backoff requeue, which is passive and wasteful.
+			// Instead, we drive the removal of finalizer by ClusterQueue Update/Delete events
+			// when resourceFlavor is no longer in use.
+			return true, nil
+		}
+		return false, nil
+	}
+
+	// The removal of the finalizer is driven by the Workload events, when
+	// the workloads that use the resourceFlavor are evicted or finish.
+	workloads := r.cache.AdmittedWorkloadsUsingFlavor(flavor.Name)
+	if len(workloads) == 0 {
+		return false, nil
+	}
+	log.V(3).Info("resourceFlavor is still used by admitted workloads", "workloads", len(workloads))
+	if policy != config.ResourceFlavorDeletionEvict {
+		return true, nil
+	}
+	msg := fmt.Sprintf("Evicted because the ResourceFlavor %s is being deleted", flavor.Name)
+	var errs []error
+	for _, wl := range workloads {
+		if err := client.IgnoreNotFound(workload.Evict(ctx, r.client, wl, ReasonResourceFlavorDeleted, msg)); err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		log.V(2).Info("Evicted workload using deleted resourceFlavor", "workload", klog.KObj(wl))
+	}
+	if len(errs) > 0 {
+		return true, fmt.Errorf("evicting %d workloads: %w", len(errs), errs[0])
+	}
+	return true, nil
+}
+
 func (r *ResourceFlavorReconciler) AddUpdateWatcher(watchers ...ResourceFlavorUpdateWatcher) {
 	r.watchers = watchers
 }
@@ -165,6 +216,10 @@ func (r *ResourceFlavorReconciler) Update(e event.UpdateEvent) bool {
 	log.V(2).Info("ResourceFlavor update event")
 
 	if flv.DeletionTimestamp != nil {
+		if r.cache.FlavorDeletionPolicy() != config.ResourceFlavorDeletionBlock {
+			// Stop assigning the resourceFlavor to new workloads.
+			r.cache.AddOrUpdateResourceFlavor(flv.DeepCopy())
+		}
 		return true
 	}
 
@@ -179,6 +234,15 @@ func (r *ResourceFlavorReconciler) Generic(e event.GenericEvent) bool {
 	return true
 }
 
+// NotifyWorkloadUpdate listens for the workload events, to remove the
+// finalizer of the resourceFlavors that are being deleted when the admitted
+// workloads that use them are gone.
+func (r *ResourceFlavorReconciler) NotifyWorkloadUpdate(w *kueue.Workload) {
+	if w.Status.Admission != nil && r.cache.FlavorDeletionPolicy() != config.ResourceFlavorDeletionBlock {
+		r.wlUpdateCh <- event.GenericEvent{Object: w}
+	}
+}
+
 // NotifyClusterQueueUpdate will listen for the update/delete events of clusterQueues to help
 // verifying whether resourceFlavors are no longer in use by clusterQueues. There're mainly
 // two reasons for this, 1) a clusterQueue is deleted 2) a clusterQueue is updated with
@@ -229,7 +293,7 @@ func (h *cqHandler) Generic(e event.GenericEvent, q workqueue.RateLimitingInterf
 		return
 	}
 
-	for _, resource := range cq.Spec.Resources {
+	for _, resource := range api.ClusterQueueResources(cq) {
 		for _, flavor := range resource.Flavors {
 			if cqs := h.cache.ClusterQueuesUsingFlavor(string(flavor.Name)); len(cqs) == 0 {
 				req := reconcile.Request{
@@ -243,6 +307,33 @@ func (h *cqHandler) Generic(e event.GenericEvent, q workqueue.RateLimitingInterf
 	}
 }
 
+// rfWorkloadHandler signals the controller to reconcile the resourceFlavors
+// assigned to the workload in the event.
+// Since the events come from a channel Source, only the Generic handler will
+// receive events.
+type rfWorkloadHandler struct{}
+
+func (h *rfWorkloadHandler) Create(event.CreateEvent, workqueue.RateLimitingInterface) {
+}
+
+func (h *rfWorkloadHandler) Update(event.UpdateEvent, workqueue.RateLimitingInterface) {
+}
+
+func (h *rfWorkloadHandler) Delete(event.DeleteEvent, workqueue.RateLimitingInterface) {
+}
+
+func (h *rfWorkloadHandler) Generic(e event.GenericEvent, q workqueue.RateLimitingInterface) {
+	w := e.Object.(*kueue.Workload)
+	if w.Status.Admission == nil {
+		return
+	}
+	for _, ps := range w.Status.Admission.PodSetFlavors {
+		for _, flavor := range ps.Flavors {
+			q.Add(reconcile.Request{NamespacedName: types.NamespacedName{Name: flavor}})
+		}
+	}
+}
+
 // SetupWithManager sets up the controller with the Manager.
 func (r *ResourceFlavorReconciler) SetupWithManager(mgr ctrl.Manager) error {
 	handler := cqHandler{
@@ -251,13 +342,14 @@ func (r *ResourceFlavorReconciler) SetupWithManager(mgr ctrl.Manager) error {
 	return ctrl.NewControllerManagedBy(mgr).
 		For(&kueue.ResourceFlavor{}).
 		Watches(&source.Channel{Source: r.cqUpdateCh}, &handler).
+		Watches(&source.Channel{Source: r.wlUpdateCh}, &rfWorkloadHandler{}).
 		WithEventFilter(r).
 		Complete(r)
 }
 
 func resourceFlavors(cq *kueue.ClusterQueue) sets.String {
 	flavors := sets.NewString()
-	for _, resource := range cq.Spec.Resources {
+	for _, resource := range api.ClusterQueueResources(cq) {
 		for _, flavor := range resource.Flavors {
 			flavors.Insert(string(flavor.Name))
 		}
@@ -19,20 +19,30 @@ package core
 import (
 	"context"
 	"fmt"
+	"strings"
+	"time"
 
 	"github.com/go-logr/logr"
+	corev1 "k8s.io/api/core/v1"
 	nodev1 "k8s.io/api/node/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
 	apimeta "k8s.io/apimachinery/pkg/api/meta"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
 	"k8s.io/klog/v2"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
 	"sigs.k8s.io/controller-runtime/pkg/event"
 
 	kueue "sigs.k8s.io/kueue/apis/kueue/v1alpha2"
+	"sigs.k8s.io/kueue/pkg/archiver"
 	"sigs.k8s.io/kueue/pkg/cache"
+	"sigs.k8s.io/kueue/pkg/constants"
+	"sigs.k8s.io/kueue/pkg/metrics"
 	"sigs.k8s.io/kueue/pkg/queue"
+	"sigs.k8s.io/kueue/pkg/util/pointer"
 	"sigs.k8s.io/kueue/pkg/workload"
 )
 
@@ -43,26 +53,101 @@ const (
 	finished = "finished"
 )
 
+const (
+	// ReasonAdmissionCheckRetry is the reason of the Evicted condition of the
+	// workloads evicted because one of their admission checks is in the
+	// Retry state.
+	ReasonAdmissionCheckRetry = "AdmissionCheckRetry"
+	// ReasonAdmissionCheckRejected is the reason of the Evicted condition of
+	// the workloads evicted and deactivated because one of their admission
+	// checks rejected them.
+	ReasonAdmissionCheckRejected = "AdmissionCheckRejected"
+)
+
 type WorkloadUpdateWatcher interface {
 	NotifyWorkloadUpdate(*kueue.Workload)
 }
 
 // WorkloadReconciler reconciles a Workload object
 type WorkloadReconciler struct {
-	log      logr.Logger
-	queues   *queue.Manager
-	cache    *cache.Cache
-	client   client.Client
-	watchers []WorkloadUpdateWatcher
+	log          logr.Logger
+	queues       *queue.Manager
+	cache        *cache.Cache
+	client       client.Client
+	watchers     []WorkloadUpdateWatcher
+	archiver     archiver.Archiver
+	recorder     record.EventRecorder
+	maxEvictions *int32
+	// podsReadyTimeout is how long an admitted workload can wait for its
+	// pods to be ready before it's evicted. Zero means no timeout.
+	podsReadyTimeout time.Duration
+}
+
+type workloadReconcilerOptions struct {
+	watchers         []WorkloadUpdateWatcher
+	archiver         archiver.Archiver
+	recorder         record.EventRecorder
+	maxEvictions     *int32
+	podsReadyTimeout time.Duration
+}
+
+// WorkloadReconcilerOption configures the reconciler.
+type WorkloadReconcilerOption func(*workloadReconcilerOptions)
+
+// WithWorkloadUpdateWatchers adds watchers that are notified of the changes
+// in the Workloads.
+func WithWorkloadUpdateWatchers(watchers ...WorkloadUpdateWatcher) WorkloadReconcilerOption {
+	return func(o *workloadReconcilerOptions) {
+		o.watchers = append(o.watchers, watchers...)
+	}
+}
+
+// WithArchiver sets the archiver for the records of the finished Workloads.
+// Workloads can't be deleted until their record is archived.
+func WithArchiver(a archiver.Archiver) WorkloadReconcilerOption {
+	return func(o *workloadReconcilerOptions) {
+		o.archiver = a
+	}
+}
+
+// WithEventRecorder sets the recorder for the events of the Workloads.
+func WithEventRecorder(recorder record.EventRecorder) WorkloadReconcilerOption {
+	return func(o *workloadReconcilerOptions) {
+		o.recorder = recorder
+	}
+}
+
+// WithMaxEvictions sets the number of times a Workload can be evicted before
+// it is deactivated.
+func WithMaxEvictions(n int32) WorkloadReconcilerOption {
+	return func(o *workloadReconcilerOptions) {
+		o.maxEvictions = &n
+	}
+}
+
+// WithPodsReadyTimeout sets how long an admitted Workload can wait for its
+// pods to be ready before it is evicted.
+func WithPodsReadyTimeout(d time.Duration) WorkloadReconcilerOption {
+	return func(o *workloadReconcilerOptions) {
+		o.podsReadyTimeout = d
+	}
 }
 
-func NewWorkloadReconciler(client client.Client, queues *queue.Manager, cache *cache.Cache, watchers ...WorkloadUpdateWatcher) *WorkloadReconciler {
+func NewWorkloadReconciler(client client.Client, queues *queue.Manager, cache *cache.Cache, opts ...WorkloadReconcilerOption) *WorkloadReconciler {
+	var options workloadReconcilerOptions
+	for _, opt := range opts {
+		opt(&options)
+	}
 	return &WorkloadReconciler{
-		log:      ctrl.Log.WithName("workload-reconciler"),
-		client:   client,
-		queues:   queues,
-		cache:    cache,
-		watchers: watchers,
+		log:              ctrl.Log.WithName("workload-reconciler"),
+		client:           client,
+		queues:           queues,
+		cache:            cache,
+		watchers:         options.watchers,
+		archiver:         options.archiver,
+		recorder:         options.recorder,
+		maxEvictions:     options.maxEvictions,
+		podsReadyTimeout: options.podsReadyTimeout,
 	}
 }
 
@@ -82,38 +167,312 @@ func (r *WorkloadReconciler) Reconcile(ctx context.Context, req ctrl.Request) (c
 	ctx = ctrl.LoggerInto(ctx, log)
 	log.V(2).Info("Reconciling Workload")
 
+	if !wl.DeletionTimestamp.IsZero() || (r.archiver == nil && controllerutil.ContainsFinalizer(&wl, constants.ArchivalFinalizer)) {
+		err := r.finalizeArchival(ctx, &wl)
+		return ctrl.Result{}, client.IgnoreNotFound(err)
+	}
+
+	if workload.HasDeprecatedAdmission(&wl) {
+		err := r.convertAdmission(ctx, &wl)
+		return ctrl.Result{}, client.IgnoreNotFound(err)
+	}
+
 	status := workloadStatus(&wl)
+	if r.archiver != nil && status != finished && !controllerutil.ContainsFinalizer(&wl, constants.ArchivalFinalizer) {
+		controllerutil.AddFinalizer(&wl, constants.ArchivalFinalizer)
+		err := r.client.Update(ctx, &wl)
+		return ctrl.Result{}, client.IgnoreNotFound(err)
+	}
+
 	switch status {
 	case pending:
+		if resetQuotaReservation(&wl) {
+			// The workload was evicted; it has to pass the admission checks
+			// again the next time it's admitted.
+			err := r.client.Status().Update(ctx, &wl)
+			return ctrl.Result{}, client.IgnoreNotFound(err)
+		}
+		if !workload.IsManagedByKueue(&wl) {
+			// The admission of the workload is up to its manager.
+			return ctrl.Result{}, nil
+		}
+		if !workload.IsActive(&wl) {
+			// Restart the count of evictions, so that the workload can be
+			// evicted again once it is activated.
+			changed := workload.SetPendingConditions(&wl, kueue.WorkloadReasonInactive, "The workload is inactive")
+			if wl.Status.Evictions != 0 {
+				wl.Status.Evictions = 0
+				changed = true
+			}
+			if !changed {
+				return ctrl.Result{}, nil
+			}
+			err := r.client.Status().Update(ctx, &wl)
+			return ctrl.Result{}, client.IgnoreNotFound(err)
+		}
+		if r.maxEvictions != nil && wl.Status.Evictions > *r.maxEvictions {
+			err := r.deactivate(ctx, &wl)
+			return ctrl.Result{}, client.IgnoreNotFound(err)
+		}
+
 		if !r.queues.QueueForWorkloadExists(&wl) {
-			err := workload.UpdateStatusIfChanged(ctx, r.client, &wl, kueue.WorkloadAdmitted, metav1.ConditionFalse,
-				"Inadmissible", fmt.Sprintf("Queue %s doesn't exist", wl.Spec.QueueName))
+			err := r.updatePendingStatusIfChanged(ctx, &wl, kueue.WorkloadReasonInadmissible,
+				fmt.Sprintf("Queue %s doesn't exist", wl.Spec.QueueName))
 			return ctrl.Result{}, client.IgnoreNotFound(err)
 		}
 
 		cqName, cqOk := r.queues.ClusterQueueForWorkload(&wl)
 		if !cqOk {
-			err := workload.UpdateStatusIfChanged(ctx, r.client, &wl, kueue.WorkloadAdmitted, metav1.ConditionFalse,
-				"Inadmissible", fmt.Sprintf("ClusterQueue %s doesn't exist", cqName))
+			err := r.updatePendingStatusIfChanged(ctx, &wl, kueue.WorkloadReasonInadmissible,
+				fmt.Sprintf("ClusterQueue %s doesn't exist", cqName))
 			return ctrl.Result{}, client.IgnoreNotFound(err)
 		}
 
 		if !r.cache.ClusterQueueActive(cqName) {
-			err := workload.UpdateStatusIfChanged(ctx, r.client, &wl, kueue.WorkloadAdmitted, metav1.ConditionFalse,
-				"Inadmissible", fmt.Sprintf("ClusterQueue %s is inactive", cqName))
+			err := r.updatePendingStatusIfChanged(ctx, &wl, kueue.WorkloadReasonInadmissible,
+				fmt.Sprintf("ClusterQueue %s is inactive", cqName))
 			return ctrl.Result{}, client.IgnoreNotFound(err)
 		}
 	case admitted:
-		msg := fmt.Sprintf("Admitted by ClusterQueue %s", wl.Spec.Admission.ClusterQueue)
-		err := workload.UpdateStatusIfChanged(ctx, r.client, &wl, kueue.WorkloadAdmitted, metav1.ConditionTrue, "AdmissionByKueue", msg)
+		if !workload.IsActive(&wl) && workload.IsManagedByKueue(&wl) {
+			err := r.evictDeactivated(ctx, &wl)
+			return ctrl.Result{}, client.IgnoreNotFound(err)
+		}
+		if queueName := wl.Annotations[constants.MoveToQueueAnnotation]; queueName != "" {
+			err := r.moveWorkload(ctx, &wl, queueName)
+			return ctrl.Result{}, client.IgnoreNotFound(err)
+		}
+		if check := workload.FindAdmissionCheckInState(&wl, kueue.CheckStateRejected); check != nil {
+			err := r.rejectWorkload(ctx, &wl, check)
+			return ctrl.Result{}, client.IgnoreNotFound(err)
+		}
+		if check := workload.FindAdmissionCheckInState(&wl, kueue.CheckStateRetry); check != nil {
+			msg := fmt.Sprintf("Evicted to retry the admission check %s: %s", check.Name, check.Message)
+			err := workload.Evict(ctx, r.client, &wl, ReasonAdmissionCheckRetry, msg)
+			return ctrl.Result{}, client.IgnoreNotFound(err)
+		}
+		statusChanged := workload.SyncAdmissionChecks(&wl)
+		if workload.SetQuotaReservedCondition(&wl, metav1.ConditionTrue, kueue.WorkloadReasonQuotaReserved,
+			fmt.Sprintf("Quota reserved in ClusterQueue %s", wl.Status.Admission.ClusterQueue)) {
+			statusChanged = true
+		}
+		if pendingChecks := workload.PendingAdmissionChecks(&wl); len(pendingChecks) > 0 {
+			msg := fmt.Sprintf("Waiting for the admission checks %s", strings.Join(pendingChecks, ", "))
+			if statusChanged {
+				err := workload.UpdateStatus(ctx, r.client, &wl, kueue.WorkloadAdmitted, metav1.ConditionFalse, kueue.WorkloadReasonAdmissionChecksPending, msg)
+				return ctrl.Result{}, client.IgnoreNotFound(err)
+			}
+			err := workload.UpdateStatusIfChanged(ctx, r.client, &wl, kueue.WorkloadAdmitted, metav1.ConditionFalse, kueue.WorkloadReasonAdmissionChecksPending, msg)
+			return ctrl.Result{}, client.IgnoreNotFound(err)
+		}
+		var requeueAfter time.Duration
+		// The timeout starts when the Admitted condition is set, once the
+		// admission checks passed.
+		if r.podsReadyTimeout > 0 && apimeta.IsStatusConditionTrue(wl.Status.Conditions, kueue.WorkloadAdmitted) &&
+			!apimeta.IsStatusConditionTrue(wl.Status.Conditions, kueue.WorkloadPodsReady) {
+			requeueAfter = r.podsReadyTimeoutRemaining(&wl)
+			if requeueAfter <= 0 {
+				err := r.evictForPodsReadyTimeout(ctx, &wl)
+				return ctrl.Result{}, client.IgnoreNotFound(err)
+			}
+		}
+		msg := fmt.Sprintf("Admitted by ClusterQueue %s", wl.Status.Admission.ClusterQueue)
+		if statusChanged || wl.Status.RequeueState != nil || workload.IsEvicted(&wl) || workload.IsQuarantined(&wl) {
+			// Restart the requeueing backoff, end the quarantine and forget the
+			// previous eviction, in case the workload is evicted again.
+			wl.Status.RequeueState = nil
+			apimeta.RemoveStatusCondition(&wl.Status.Conditions, kueue.WorkloadEvicted)
+			apimeta.RemoveStatusCondition(&wl.Status.Conditions, kueue.WorkloadQuarantined)
+			err := workload.UpdateStatus(ctx, r.client, &wl, kueue.WorkloadAdmitted, metav1.ConditionTrue, kueue.WorkloadReasonAdmitted, msg)
+			return ctrl.Result{RequeueAfter: requeueAfter}, client.IgnoreNotFound(err)
+		}
+		err := workload.UpdateStatusIfChanged(ctx, r.client, &wl, kueue.WorkloadAdmitted, metav1.ConditionTrue, kueue.WorkloadReasonAdmitted, msg)
+		return ctrl.Result{RequeueAfter: requeueAfter}, client.IgnoreNotFound(err)
+	case finished:
+		if wl.Status.ResourceUsage == nil {
+			if usage := workload.ResourceUsage(&wl); usage != nil {
+				wl.Status.ResourceUsage = usage
+				if err := r.client.Status().Update(ctx, &wl); err != nil {
+					return ctrl.Result{}, client.IgnoreNotFound(err)
+				}
+				log.V(2).Info("Recorded resource usage of finished workload", "queuedTime", usage.QueuedTime.Duration, "runTime", usage.RunTime.Duration)
+				metrics.FinishedWorkload(wl.Status.Admission.ClusterQueue, usage.RunTime.Duration, usage.AdmittedResources)
+			}
+		}
+		err := r.finalizeArchival(ctx, &wl)
 		return ctrl.Result{}, client.IgnoreNotFound(err)
 	}
 
 	return ctrl.Result{}, nil
 }
 
+// moveWorkload moves the admitted workload to the LocalQueue, and its usage to
+// the ClusterQueue of the LocalQueue, without evicting it. The usage is moved
+// in the cache before the workload is updated, so that the quota isn't taken
+// by other workloads in the meantime. Until the workload fits in the quota of
+// the ClusterQueue, the move is retried with backoff.
+func (r *WorkloadReconciler) moveWorkload(ctx context.Context, wl *kueue.Workload, queueName string) error {
+	log := ctrl.LoggerFrom(ctx)
+	newWl := wl.DeepCopy()
+	delete(newWl.Annotations, constants.MoveToQueueAnnotation)
+	newWl.Spec.QueueName = queueName
+	cqName, ok := r.queues.ClusterQueueForWorkload(newWl)
+	if !ok {
+		return fmt.Errorf("moving to LocalQueue %s: the LocalQueue or its ClusterQueue doesn't exist", queueName)
+	}
+	var ns corev1.Namespace
+	if err := r.client.Get(ctx, types.NamespacedName{Name: wl.Namespace}, &ns); err != nil {
+		return fmt.Errorf("moving to LocalQueue %s: %w", queueName, err)
+	}
+	if !r.cache.MatchingClusterQueues(ns.Labels).Has(cqName) {
+		return fmt.Errorf("moving to LocalQueue %s: the namespace doesn't match the selector of ClusterQueue %s", queueName, cqName)
+	}
+	newWl.Status.Admission.ClusterQueue = kueue.ClusterQueueReference(cqName)
+	if err := r.cache.MoveWorkload(wl, newWl); err != nil {
+		return fmt.Errorf("moving to LocalQueue %s: %w", queueName, err)
+	}
+	// The admission is updated first; if updating the spec fails, the move
+	// annotation stays and the move is retried.
+	if err := r.client.Status().Update(ctx, newWl); err != nil {
+		// Move the usage back to the original ClusterQueue.
+		if err := r.cache.UpdateWorkload(newWl, wl); err != nil {
+			log.Error(err, "Failed to revert the move of the workload in the cache")
+		}
+		return err
+	}
+	// The status update ignores the changes to the spec and metadata, and
+	// resets them from the response.
+	newWl.Spec.QueueName = queueName
+	delete(newWl.Annotations, constants.MoveToQueueAnnotation)
+	if err := r.client.Update(ctx, newWl); err != nil {
+		return err
+	}
+	log.V(2).Info("Moved admitted workload", "queue", queueName, "clusterQueue", cqName)
+	return nil
+}
+
+// convertAdmission moves the admission of the workload from the deprecated
+// spec.admission field to status.admission. The status is updated before the
+// spec, so that the admission is never lost: if removing the deprecated
+// admission fails, the retry finds the admission in the status and only
+// removes it from the spec.
+func (r *WorkloadReconciler) convertAdmission(ctx context.Context, wl *kueue.Workload) error {
+	if wl.Status.Admission == nil {
+		wl.Status.Admission = wl.Spec.Admission.DeepCopy()
+		if err := r.client.Status().Update(ctx, wl); err != nil {
+			return err
+		}
+	}
+	wl.Spec.Admission = nil
+	if err := r.client.Update(ctx, wl); err != nil {
+		return err
+	}
+	ctrl.LoggerFrom(ctx).V(2).Info("Moved the admission of the workload to its status")
+	return nil
+}
+
+// deactivate sets the workload inactive, so that it stops being requeued after
+// it was evicted more times than the maximum.
+func (r *WorkloadReconciler) deactivate(ctx context.Context, wl *kueue.Workload) error {
+	newWl := wl.DeepCopy()
+	newWl.Spec.Active = pointer.Bool(false)
+	if err := r.client.Update(ctx, newWl); err != nil {
+		return err
+	}
+	msg := fmt.Sprintf("Deactivated after being evicted %d times", wl.Status.Evictions)
+	ctrl.LoggerFrom(ctx).V(2).Info("Deactivated workload", "evictions", wl.Status.Evictions)
+	r.recorder.Event(newWl, corev1.EventTypeWarning, "Deactivated", msg)
+	return nil
+}
+
+// evictDeactivated evicts the admitted workload that was deactivated, so that
+// its job is suspended and its quota is released. The workload isn't queued
+// again until it's activated.
+func (r *WorkloadReconciler) evictDeactivated(ctx context.Context, wl *kueue.Workload) error {
+	msg := "Evicted because the workload was deactivated"
+	if err := workload.Evict(ctx, r.client, wl, kueue.WorkloadEvictedByDeactivation, msg); err != nil {
+		return err
+	}
+	ctrl.LoggerFrom(ctx).V(2).Info("Evicted deactivated workload")
+	r.recorder.Event(wl, corev1.EventTypeNormal, kueue.WorkloadEvictedByDeactivation, msg)
+	return nil
+}
+
+// rejectWorkload evicts the workload and deactivates it, because one of its
+// admission checks rejected it and would do so again.
+func (r *WorkloadReconciler) rejectWorkload(ctx context.Context, wl *kueue.Workload, check *kueue.AdmissionCheckState) error {
+	newWl := wl.DeepCopy()
+	newWl.Spec.Active = pointer.Bool(false)
+	if err := r.client.Update(ctx, newWl); err != nil {
+		return err
+	}
+	msg := fmt.Sprintf("Rejected by the admission check %s: %s", check.Name, check.Message)
+	ctrl.LoggerFrom(ctx).V(2).Info("Deactivated workload rejected by an admission check", "admissionCheck", check.Name)
+	r.recorder.Event(newWl, corev1.EventTypeWarning, ReasonAdmissionCheckRejected, msg)
+	return workload.Evict(ctx, r.client, newWl, ReasonAdmissionCheckRejected, msg)
+}
+
+// podsReadyTimeoutRemaining returns how long the admitted workload can still
+// wait for its pods to be ready.
+func (r *WorkloadReconciler) podsReadyTimeoutRemaining(wl *kueue.Workload) time.Duration {
+	cond := apimeta.FindStatusCondition(wl.Status.Conditions, kueue.WorkloadAdmitted)
+	return r.podsReadyTimeout - time.Since(cond.LastTransitionTime.Time)
+}
+
+// evictForPodsReadyTimeout evicts the admitted workload whose pods weren't
+// ready within the timeout, so that its job is stopped and the quota is
+// available to other workloads while it's queued again.
+func (r *WorkloadReconciler) evictForPodsReadyTimeout(ctx context.Context, wl *kueue.Workload) error {
+	msg := fmt.Sprintf("Evicted because the pods weren't ready within %s", r.podsReadyTimeout)
+	if err := workload.Evict(ctx, r.client, wl, kueue.WorkloadEvictedByPodsReadyTimeout, msg); err != nil {
+		return err
+	}
+	ctrl.LoggerFrom(ctx).V(2).Info("Evicted workload whose pods weren't ready", "timeout", r.podsReadyTimeout)
+	r.recorder.Event(wl, corev1.EventTypeNormal, kueue.WorkloadEvictedByPodsReadyTimeout, msg)
+	return nil
+}
+
+// updatePendingStatusIfChanged sets the QuotaReserved and Admitted conditions
+// of the pending workload to false with the reason, and updates its status if
+// they changed.
+func (r *WorkloadReconciler) updatePendingStatusIfChanged(ctx context.Context, wl *kueue.Workload, reason, message string) error {
+	if !workload.SetPendingConditions(wl, reason, message) {
+		return nil
+	}
+	return r.client.Status().Update(ctx, wl)
+}
+
+// resetQuotaReservation clears the states of the admission checks of a
+// workload that has no admission, after it was evicted, and sets its
+// QuotaReserved condition to false. It returns whether the status changed.
+func resetQuotaReservation(wl *kueue.Workload) bool {
+	changed := workload.SyncAdmissionChecks(wl)
+	if apimeta.IsStatusConditionTrue(wl.Status.Conditions, kueue.WorkloadQuotaReserved) {
+		workload.SetQuotaReservedCondition(wl, metav1.ConditionFalse, kueue.WorkloadReasonPending, "The workload has no quota reserved")
+		changed = true
+	}
+	return changed
+}
+
+// finalizeArchival archives the record of the workload, if it finished and
+// archival is enabled, and removes the archival finalizer. The finalizer is
+// also removed from workloads that are deleted before finishing.
+func (r *WorkloadReconciler) finalizeArchival(ctx context.Context, wl *kueue.Workload) error {
+	if !controllerutil.ContainsFinalizer(wl, constants.ArchivalFinalizer) {
+		return nil
+	}
+	if r.archiver != nil && workloadStatus(wl) == finished {
+		if err := r.archiver.Archive(ctx, archiver.NewRecord(wl)); err != nil {
+			return fmt.Errorf("archiving workload: %w", err)
+		}
+		ctrl.LoggerFrom(ctx).V(2).Info("Archived finished workload")
+	}
+	controllerutil.RemoveFinalizer(wl, constants.ArchivalFinalizer)
+	return r.client.Update(ctx, wl)
+}
+
 func (r *WorkloadReconciler) Create(e event.CreateEvent) bool {
-	wl := e.Object.(*kueue.Workload)
+	wl := workload.WithDeprecatedAdmission(e.Object.(*kueue.Workload))
 	defer r.notifyWatchers(wl)
 	status := workloadStatus(wl)
 	log := r.log.WithValues("workload", klog.KObj(wl), "queue", wl.Spec.QueueName, "status", status)
@@ -122,11 +481,15 @@ func (r *WorkloadReconciler) Create(e event.CreateEvent) bool {
 	if status == finished {
 		return true
 	}
+	if !wl.DeletionTimestamp.IsZero() {
+		log.V(2).Info("Workload is being deleted; ignored")
+		return true
+	}
 
 	wlCopy := wl.DeepCopy()
 	handlePodOverhead(r.log, wlCopy, r.client)
 
-	if wl.Spec.Admission == nil {
+	if wl.Status.Admission == nil {
 		if !r.queues.AddOrUpdateWorkload(wlCopy) {
 			log.V(2).Info("Queue for workload didn't exist; ignored for now")
 		}
@@ -140,7 +503,7 @@ func (r *WorkloadReconciler) Create(e event.CreateEvent) bool {
 }
 
 func (r *WorkloadReconciler) Delete(e event.DeleteEvent) bool {
-	wl := e.Object.(*kueue.Workload)
+	wl := workload.WithDeprecatedAdmission(e.Object.(*kueue.Workload))
 	defer r.notifyWatchers(wl)
 	status := "unknown"
 	if !e.DeleteStateUnknown {
@@ -153,7 +516,8 @@ func (r *WorkloadReconciler) Delete(e event.DeleteEvent) bool {
 	// When assigning a clusterQueue to a workload, we assume it in the cache. If
 	// the state is unknown, the workload could have been assumed and we need
 	// to clear it from the cache.
-	if wl.Spec.Admission != nil || e.DeleteStateUnknown {
+	if wl.Status.Admission != nil || e.DeleteStateUnknown {
+		r.cache.SettleWorkload(wl, time.Now())
 		if err := r.cache.DeleteWorkload(wl); err != nil {
 			if !e.DeleteStateUnknown {
 				log.Error(err, "Failed to delete workload from cache")
@@ -166,15 +530,20 @@ func (r *WorkloadReconciler) Delete(e event.DeleteEvent) bool {
 
 	// Even if the state is unknown, the last cached state tells us whether the
 	// workload was in the queues and should be cleared from them.
-	if wl.Spec.Admission == nil {
+	if wl.Status.Admission == nil {
 		r.queues.DeleteWorkload(wl)
+		if r.cache.CancelQuotaReservation(wl) {
+			r.queues.QueueAssociatedInadmissibleWorkloads(ctx, wl)
+		}
 	}
 	return true
 }
 
 func (r *WorkloadReconciler) Update(e event.UpdateEvent) bool {
-	oldWl := e.ObjectOld.(*kueue.Workload)
-	wl := e.ObjectNew.(*kueue.Workload)
+	// The workloads with a deprecated admission keep their quota while they're
+	// converted.
+	oldWl := workload.WithDeprecatedAdmission(e.ObjectOld.(*kueue.Workload))
+	wl := workload.WithDeprecatedAdmission(e.ObjectNew.(*kueue.Workload))
 	defer r.notifyWatchers(oldWl)
 	defer r.notifyWatchers(wl)
 
@@ -190,11 +559,11 @@ func (r *WorkloadReconciler) Update(e event.UpdateEvent) bool {
 	if prevStatus != status {
 		log = log.WithValues("prevStatus", prevStatus)
 	}
-	if wl.Spec.Admission != nil {
-		log = log.WithValues("clusterQueue", wl.Spec.Admission.ClusterQueue)
+	if wl.Status.Admission != nil {
+		log = log.WithValues("clusterQueue", wl.Status.Admission.ClusterQueue)
 	}
-	if oldWl.Spec.Admission != nil && (wl.Spec.Admission == nil || wl.Spec.Admission.ClusterQueue != oldWl.Spec.Admission.ClusterQueue) {
-		log = log.WithValues("prevClusterQueue", oldWl.Spec.Admission.ClusterQueue)
+	if oldWl.Status.Admission != nil && (wl.Status.Admission == nil || wl.Status.Admission.ClusterQueue != oldWl.Status.Admission.ClusterQueue) {
+		log = log.WithValues("prevClusterQueue", oldWl.Status.Admission.ClusterQueue)
 	}
 	log.V(2).Info("Workload update event")
 
@@ -204,6 +573,11 @@ func (r *WorkloadReconciler) Update(e event.UpdateEvent) bool {
 
 	switch {
 	case status == finished:
+		finishedAt := time.Now()
+		if cond := apimeta.FindStatusCondition(wl.Status.Conditions, kueue.WorkloadFinished); cond != nil {
+			finishedAt = cond.LastTransitionTime.Time
+		}
+		r.cache.SettleWorkload(oldWl, finishedAt)
 		if err := r.cache.DeleteWorkload(oldWl); err != nil && prevStatus == admitted {
 			log.Error(err, "Failed to delete workload from cache")
 		}
@@ -212,6 +586,29 @@ func (r *WorkloadReconciler) Update(e event.UpdateEvent) bool {
 		// trigger the move of associated inadmissibleWorkloads if required.
 		r.queues.QueueAssociatedInadmissibleWorkloads(ctx, wl)
 
+	case !wl.DeletionTimestamp.IsZero():
+		// Finalizers, for example the ones of the Job integrations, can delay
+		// the removal of a workload, typically when its namespace is deleted.
+		// The workload can't run anymore, so release its quota right away.
+		// The admission is looked up in both objects because the scheduler
+		// could have admitted the workload after it was marked for deletion.
+		released := false
+		for _, w := range []*kueue.Workload{oldWl, wl} {
+			if w.Status.Admission == nil {
+				continue
+			}
+			r.cache.SettleWorkload(w, time.Now())
+			if r.cache.DeleteWorkload(w) == nil {
+				released = true
+			}
+		}
+		r.queues.DeleteWorkload(oldWl)
+		if released {
+			log.V(2).Info("Released the quota of workload being deleted")
+			// trigger the move of associated inadmissibleWorkloads if required.
+			r.queues.QueueAssociatedInadmissibleWorkloads(ctx, wl)
+		}
+
 	case prevStatus == pending && status == pending:
 		if !r.queues.UpdateWorkload(oldWl, wlCopy) {
 			log.V(2).Info("Queue for updated workload didn't exist; ignoring for now")
@@ -224,6 +621,10 @@ func (r *WorkloadReconciler) Update(e event.UpdateEvent) bool {
 		}
 
 	case prevStatus == admitted && status == pending:
+		if reason := workload.EvictionReason(wl); reason != "" {
+			log.V(2).Info("Workload evicted, queueing it again", "reason", reason)
+		}
+		r.cache.SettleWorkload(oldWl, time.Now())
 		if err := r.cache.DeleteWorkload(oldWl); err != nil {
 			log.Error(err, "Failed to delete workload from cache")
 		}
@@ -240,6 +641,18 @@ func (r *WorkloadReconciler) Update(e event.UpdateEvent) bool {
 		if err := r.cache.UpdateWorkload(oldWl, wlCopy); err != nil {
 			log.Error(err, "Updating workload in cache")
 		}
+		if prevStatus == admitted && oldWl.Status.Admission.ClusterQueue != wl.Status.Admission.ClusterQueue {
+			// The workload was moved; its quota is available in the previous
+			// ClusterQueue.
+			r.queues.QueueAssociatedInadmissibleWorkloads(ctx, oldWl)
+		} else if prevStatus == admitted && !equality.Semantic.DeepEqual(oldWl.Status.ReclaimablePods, wl.Status.ReclaimablePods) {
+			// The quota of the reclaimable pods was released.
+			r.queues.QueueAssociatedInadmissibleWorkloads(ctx, wl)
+		} else if prevStatus == admitted && len(workload.PendingAdmissionChecks(oldWl)) > 0 && len(workload.PendingAdmissionChecks(wl)) == 0 {
+			// The workload stopped waiting for its admission checks, making
+			// room under the maxWorkloadsPendingChecks of the ClusterQueue.
+			r.queues.QueueAssociatedInadmissibleWorkloads(ctx, wl)
+		}
 	}
 
 	return true
@@ -268,7 +681,7 @@ func workloadStatus(w *kueue.Workload) string {
 	if apimeta.IsStatusConditionTrue(w.Status.Conditions, kueue.WorkloadFinished) {
 		return finished
 	}
-	if w.Spec.Admission != nil {
+	if w.Status.Admission != nil {
 		return admitted
 	}
 	return pending
@@ -0,0 +1,893 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package core
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/go-logr/logr/testr"
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+
+	kueue "sigs.k8s.io/kueue/apis/kueue/v1alpha2"
+	"sigs.k8s.io/kueue/pkg/archiver"
+	"sigs.k8s.io/kueue/pkg/cache"
+	"sigs.k8s.io/kueue/pkg/constants"
+	"sigs.k8s.io/kueue/pkg/queue"
+	"sigs.k8s.io/kueue/pkg/util/pointer"
+	testingutil "sigs.k8s.io/kueue/pkg/util/testing"
+	"sigs.k8s.io/kueue/pkg/workload"
+)
+
+type fakeArchiver struct {
+	err      error
+	archived []string
+}
+
+func (a *fakeArchiver) Archive(_ context.Context, r *archiver.Record) error {
+	if a.err != nil {
+		return a.err
+	}
+	a.archived = append(a.archived, r.Namespace+"/"+r.Name)
+	return nil
+}
+
+// failingUpdateClient fails the given number of updates of the objects,
+// while the updates of their status succeed.
+type failingUpdateClient struct {
+	client.Client
+	failures int
+}
+
+func (c *failingUpdateClient) Update(ctx context.Context, obj client.Object, opts ...client.UpdateOption) error {
+	if c.failures > 0 {
+		c.failures--
+		return errors.New("injected update error")
+	}
+	return c.Client.Update(ctx, obj, opts...)
+}
+
+func TestReconcileArchival(t *testing.T) {
+	now := time.Now()
+	finishedWl := func() *testingutil.WorkloadWrapper {
+		return testingutil.MakeWorkload("wl", "ns").
+			Admit(testingutil.MakeAdmission("cq").Flavor(corev1.ResourceCPU, "default").Obj()).
+			Condition(metav1.Condition{
+				Type:               kueue.WorkloadFinished,
+				Status:             metav1.ConditionTrue,
+				LastTransitionTime: metav1.NewTime(now),
+				Reason:             "JobFinished",
+			})
+	}
+
+	cases := map[string]struct {
+		workload       *kueue.Workload
+		archiver       *fakeArchiver
+		wantErr        bool
+		wantDeleted    bool
+		wantFinalizers []string
+		wantArchived   []string
+	}{
+		"add finalizer to pending workload": {
+			workload:       testingutil.MakeWorkload("wl", "ns").Obj(),
+			archiver:       &fakeArchiver{},
+			wantFinalizers: []string{constants.ArchivalFinalizer},
+		},
+		"archive finished workload": {
+			workload:     finishedWl().Finalizers(constants.ArchivalFinalizer).Obj(),
+			archiver:     &fakeArchiver{},
+			wantArchived: []string{"ns/wl"},
+		},
+		"keep finalizer when archival fails": {
+			workload:       finishedWl().Finalizers(constants.ArchivalFinalizer).Obj(),
+			archiver:       &fakeArchiver{err: errors.New("unavailable")},
+			wantErr:        true,
+			wantFinalizers: []string{constants.ArchivalFinalizer},
+		},
+		"don't archive finished workload without finalizer": {
+			workload: finishedWl().Obj(),
+			archiver: &fakeArchiver{},
+		},
+		"remove finalizer from workload deleted before finishing": {
+			workload: testingutil.MakeWorkload("wl", "ns").
+				Finalizers(constants.ArchivalFinalizer).
+				Deleted(now).
+				Obj(),
+			archiver:    &fakeArchiver{},
+			wantDeleted: true,
+		},
+		"remove finalizer when archival is disabled": {
+			workload: finishedWl().Finalizers(constants.ArchivalFinalizer).Obj(),
+		},
+	}
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			ctx := ctrl.LoggerInto(context.Background(), testr.New(t))
+			scheme := runtime.NewScheme()
+			if err := kueue.AddToScheme(scheme); err != nil {
+				t.Fatalf("Failed adding kueue scheme: %v", err)
+			}
+			cl := fake.NewClientBuilder().WithScheme(scheme).WithObjects(tc.workload).Build()
+			cqCache := cache.New(cl)
+			var opts []WorkloadReconcilerOption
+			if tc.archiver != nil {
+				opts = append(opts, WithArchiver(tc.archiver))
+			}
+			r := NewWorkloadReconciler(cl, queue.NewManager(cl, cqCache), cqCache, opts...)
+
+			key := client.ObjectKeyFromObject(tc.workload)
+			_, err := r.Reconcile(ctx, ctrl.Request{NamespacedName: types.NamespacedName(key)})
+			if gotErr := err != nil; gotErr != tc.wantErr {
+				t.Fatalf("Reconcile returned error %v, want error: %t", err, tc.wantErr)
+			}
+
+			var gotWl kueue.Workload
+			err = cl.Get(ctx, key, &gotWl)
+			if gotDeleted := apierrors.IsNotFound(err); gotDeleted != tc.wantDeleted {
+				t.Errorf("Workload deleted: %t, want %t", gotDeleted, tc.wantDeleted)
+			} else if err != nil && !gotDeleted {
+				t.Fatalf("Failed getting workload: %v", err)
+			}
+			if diff := cmp.Diff(tc.wantFinalizers, gotWl.Finalizers, cmpopts.EquateEmpty()); diff != "" {
+				t.Errorf("Unexpected finalizers (-want,+got):\n%s", diff)
+			}
+			var gotArchived []string
+			if tc.archiver != nil {
+				gotArchived = tc.archiver.archived
+			}
+			if diff := cmp.Diff(tc.wantArchived, gotArchived, cmpopts.EquateEmpty()); diff != "" {
+				t.Errorf("Unexpected archived workloads (-want,+got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestUpdateWorkloadBeingDeleted(t *testing.T) {
+	now := time.Now()
+	cq := testingutil.MakeClusterQueue("cq").
+		NamespaceSelector(&metav1.LabelSelector{}).
+		Resource(testingutil.MakeResource(corev1.ResourceCPU).
+			Flavor(testingutil.MakeFlavor("default", "10").Obj()).Obj()).
+		Obj()
+	lq := testingutil.MakeLocalQueue("lq", "ns").ClusterQueue("cq").Obj()
+
+	cases := map[string]struct {
+		workload     *kueue.Workload
+		wantAdmitted int
+		wantPending  int
+	}{
+		"admitted workload": {
+			workload: testingutil.MakeWorkload("wl", "ns").Queue("lq").
+				Request(corev1.ResourceCPU, "1").
+				Admit(testingutil.MakeAdmission("cq").Flavor(corev1.ResourceCPU, "default").Obj()).
+				Finalizers("example.com/finalizer").
+				Obj(),
+			wantAdmitted: 1,
+		},
+		"pending workload": {
+			workload: testingutil.MakeWorkload("wl", "ns").Queue("lq").
+				Request(corev1.ResourceCPU, "1").
+				Finalizers("example.com/finalizer").
+				Obj(),
+			wantPending: 1,
+		},
+	}
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			ctx := ctrl.LoggerInto(context.Background(), testr.New(t))
+			scheme := runtime.NewScheme()
+			if err := kueue.AddToScheme(scheme); err != nil {
+				t.Fatalf("Failed adding kueue scheme: %v", err)
+			}
+			cl := fake.NewClientBuilder().WithScheme(scheme).WithObjects(cq, lq).Build()
+			cqCache := cache.New(cl)
+			qManager := queue.NewManager(cl, cqCache)
+			if err := cqCache.AddClusterQueue(ctx, cq); err != nil {
+				t.Fatalf("Inserting clusterQueue in cache: %v", err)
+			}
+			if err := qManager.AddClusterQueue(ctx, cq); err != nil {
+				t.Fatalf("Inserting clusterQueue in manager: %v", err)
+			}
+			if err := qManager.AddLocalQueue(ctx, lq); err != nil {
+				t.Fatalf("Inserting localQueue in manager: %v", err)
+			}
+			r := NewWorkloadReconciler(cl, qManager, cqCache)
+			r.Create(event.CreateEvent{Object: tc.workload})
+			if _, admitted, _ := cqCache.Usage(cq); admitted != tc.wantAdmitted {
+				t.Fatalf("Got %d admitted workloads before the deletion, want %d", admitted, tc.wantAdmitted)
+			}
+			if pending := qManager.Pending(cq); pending != tc.wantPending {
+				t.Fatalf("Got %d pending workloads before the deletion, want %d", pending, tc.wantPending)
+			}
+
+			deleted := tc.workload.DeepCopy()
+			deleted.DeletionTimestamp = &metav1.Time{Time: now}
+			r.Update(event.UpdateEvent{ObjectOld: tc.workload, ObjectNew: deleted})
+			if _, admitted, _ := cqCache.Usage(cq); admitted != 0 {
+				t.Errorf("Got %d admitted workloads after the deletion, want 0", admitted)
+			}
+			if pending := qManager.Pending(cq); pending != 0 {
+				t.Errorf("Got %d pending workloads after the deletion, want 0", pending)
+			}
+		})
+	}
+}
+
+func TestReconcileMoveWorkload(t *testing.T) {
+	cqA := testingutil.MakeClusterQueue("cq-a").
+		Resource(testingutil.MakeResource(corev1.ResourceCPU).
+			Flavor(testingutil.MakeFlavor("default", "10").Obj()).Obj()).
+		Obj()
+	lqA := testingutil.MakeLocalQueue("lq-a", "ns").ClusterQueue("cq-a").Obj()
+	cases := map[string]struct {
+		targetQuota   string
+		wantQueue     string
+		wantCq        string
+		wantErr       bool
+		wantAnnotated bool
+	}{
+		"fits in the target ClusterQueue": {
+			targetQuota: "10",
+			wantQueue:   "lq-b",
+			wantCq:      "cq-b",
+		},
+		"doesn't fit in the target ClusterQueue": {
+			targetQuota:   "2",
+			wantQueue:     "lq-a",
+			wantCq:        "cq-a",
+			wantErr:       true,
+			wantAnnotated: true,
+		},
+	}
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			ctx := ctrl.LoggerInto(context.Background(), testr.New(t))
+			cqB := testingutil.MakeClusterQueue("cq-b").
+				Resource(testingutil.MakeResource(corev1.ResourceCPU).
+					Flavor(testingutil.MakeFlavor("default", tc.targetQuota).Obj()).Obj()).
+				Obj()
+			lqB := testingutil.MakeLocalQueue("lq-b", "ns").ClusterQueue("cq-b").Obj()
+			wl := testingutil.MakeWorkload("wl", "ns").Queue("lq-a").MoveToQueue("lq-b").
+				Request(corev1.ResourceCPU, "4").
+				Admit(testingutil.MakeAdmission("cq-a").Flavor(corev1.ResourceCPU, "default").Obj()).
+				Obj()
+			scheme := runtime.NewScheme()
+			if err := kueue.AddToScheme(scheme); err != nil {
+				t.Fatalf("Failed adding kueue scheme: %v", err)
+			}
+			if err := corev1.AddToScheme(scheme); err != nil {
+				t.Fatalf("Failed adding core scheme: %v", err)
+			}
+			cl := fake.NewClientBuilder().WithScheme(scheme).
+				WithObjects(cqA, cqB, lqA, lqB, wl, &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "ns"}}).
+				Build()
+			cqCache := cache.New(cl)
+			cqCache.AddOrUpdateResourceFlavor(testingutil.MakeResourceFlavor("default").Obj())
+			qManager := queue.NewManager(cl, cqCache)
+			for _, cq := range []*kueue.ClusterQueue{cqA, cqB} {
+				if err := cqCache.AddClusterQueue(ctx, cq); err != nil {
+					t.Fatalf("Inserting clusterQueue in cache: %v", err)
+				}
+				if err := qManager.AddClusterQueue(ctx, cq); err != nil {
+					t.Fatalf("Inserting clusterQueue in manager: %v", err)
+				}
+			}
+			for _, lq := range []*kueue.LocalQueue{lqA, lqB} {
+				if err := qManager.AddLocalQueue(ctx, lq); err != nil {
+					t.Fatalf("Inserting localQueue in manager: %v", err)
+				}
+			}
+			cqCache.AddOrUpdateWorkload(wl)
+			r := NewWorkloadReconciler(cl, qManager, cqCache)
+
+			_, err := r.Reconcile(ctx, ctrl.Request{NamespacedName: types.NamespacedName{Namespace: "ns", Name: "wl"}})
+			if gotErr := err != nil; gotErr != tc.wantErr {
+				t.Fatalf("Reconcile returned error %v, want error %t", err, tc.wantErr)
+			}
+			var got kueue.Workload
+			if err := cl.Get(ctx, client.ObjectKeyFromObject(wl), &got); err != nil {
+				t.Fatalf("Failed getting the workload: %v", err)
+			}
+			if got.Spec.QueueName != tc.wantQueue || string(got.Status.Admission.ClusterQueue) != tc.wantCq {
+				t.Errorf("Got workload in queue %s and ClusterQueue %s, want %s and %s", got.Spec.QueueName, got.Status.Admission.ClusterQueue, tc.wantQueue, tc.wantCq)
+			}
+			if _, annotated := got.Annotations[constants.MoveToQueueAnnotation]; annotated != tc.wantAnnotated {
+				t.Errorf("Got move annotation %t, want %t", annotated, tc.wantAnnotated)
+			}
+			r.Update(event.UpdateEvent{ObjectOld: wl, ObjectNew: &got})
+			for _, cq := range []*kueue.ClusterQueue{cqA, cqB} {
+				want := 0
+				if cq.Name == tc.wantCq {
+					want = 1
+				}
+				if _, admitted, _ := cqCache.Usage(cq); admitted != want {
+					t.Errorf("Got %d admitted workloads in ClusterQueue %s, want %d", admitted, cq.Name, want)
+				}
+			}
+		})
+	}
+}
+
+func TestReconcileDeactivation(t *testing.T) {
+	cases := map[string]struct {
+		workload      *kueue.Workload
+		maxEvictions  *int32
+		wantActive    bool
+		wantAdmission bool
+		wantEvictions int32
+		wantEvents    []string
+	}{
+		"evicted less times than the maximum": {
+			workload:      testingutil.MakeWorkload("wl", "ns").Queue("lq").Evictions(2).Obj(),
+			maxEvictions:  pointer.Int32(2),
+			wantActive:    true,
+			wantEvictions: 2,
+		},
+		"evicted more times than the maximum": {
+			workload:      testingutil.MakeWorkload("wl", "ns").Queue("lq").Evictions(3).Obj(),
+			maxEvictions:  pointer.Int32(2),
+			wantEvictions: 3,
+			wantEvents:    []string{"Warning Deactivated Deactivated after being evicted 3 times"},
+		},
+		"no maximum": {
+			workload:      testingutil.MakeWorkload("wl", "ns").Queue("lq").Evictions(3).Obj(),
+			wantActive:    true,
+			wantEvictions: 3,
+		},
+		"workload managed by another controller is left to it": {
+			workload:      testingutil.MakeWorkload("wl", "ns").Queue("lq").ManagedBy("example.com/dispatcher").Evictions(3).Obj(),
+			maxEvictions:  pointer.Int32(2),
+			wantActive:    true,
+			wantEvictions: 3,
+		},
+		"inactive workload restarts the evictions": {
+			workload:     testingutil.MakeWorkload("wl", "ns").Queue("lq").Active(false).Evictions(3).Obj(),
+			maxEvictions: pointer.Int32(2),
+		},
+		"admitted inactive workload is evicted": {
+			workload:      testingutil.MakeWorkload("wl", "ns").Queue("lq").Admit(testingutil.MakeAdmission("cq").Obj()).Active(false).Obj(),
+			wantEvictions: 1,
+			wantEvents:    []string{"Normal Deactivated Evicted because the workload was deactivated"},
+		},
+		"admitted inactive workload managed by another controller is left to it": {
+			workload: testingutil.MakeWorkload("wl", "ns").
+				Queue("lq").
+				ManagedBy("example.com/dispatcher").
+				Admit(testingutil.MakeAdmission("cq").Obj()).
+				Active(false).
+				Obj(),
+			wantAdmission: true,
+		},
+	}
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			ctx := ctrl.LoggerInto(context.Background(), testr.New(t))
+			scheme := runtime.NewScheme()
+			if err := kueue.AddToScheme(scheme); err != nil {
+				t.Fatalf("Failed adding kueue scheme: %v", err)
+			}
+			cl := fake.NewClientBuilder().WithScheme(scheme).WithObjects(tc.workload).Build()
+			cqCache := cache.New(cl)
+			recorder := record.NewFakeRecorder(10)
+			opts := []WorkloadReconcilerOption{WithEventRecorder(recorder)}
+			if tc.maxEvictions != nil {
+				opts = append(opts, WithMaxEvictions(*tc.maxEvictions))
+			}
+			r := NewWorkloadReconciler(cl, queue.NewManager(cl, cqCache), cqCache, opts...)
+
+			key := client.ObjectKeyFromObject(tc.workload)
+			if _, err := r.Reconcile(ctx, ctrl.Request{NamespacedName: types.NamespacedName(key)}); err != nil {
+				t.Fatalf("Reconcile returned error: %v", err)
+			}
+
+			var gotWl kueue.Workload
+			if err := cl.Get(ctx, key, &gotWl); err != nil {
+				t.Fatalf("Failed getting workload: %v", err)
+			}
+			if gotActive := workload.IsActive(&gotWl); gotActive != tc.wantActive {
+				t.Errorf("Workload active: %t, want %t", gotActive, tc.wantActive)
+			}
+			if gotAdmission := gotWl.Status.Admission != nil; gotAdmission != tc.wantAdmission {
+				t.Errorf("Workload has admission: %t, want %t", gotAdmission, tc.wantAdmission)
+			}
+			if gotWl.Status.Evictions != tc.wantEvictions {
+				t.Errorf("Got %d evictions, want %d", gotWl.Status.Evictions, tc.wantEvictions)
+			}
+			close(recorder.Events)
+			var gotEvents []string
+			for e := range recorder.Events {
+				gotEvents = append(gotEvents, e)
+			}
+			if diff := cmp.Diff(tc.wantEvents, gotEvents); diff != "" {
+				t.Errorf("Unexpected events (-want,+got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestUpdateInactiveWorkload(t *testing.T) {
+	cq := testingutil.MakeClusterQueue("cq").
+		NamespaceSelector(&metav1.LabelSelector{}).
+		Resource(testingutil.MakeResource(corev1.ResourceCPU).
+			Flavor(testingutil.MakeFlavor("default", "10").Obj()).Obj()).
+		Obj()
+	lq := testingutil.MakeLocalQueue("lq", "ns").ClusterQueue("cq").Obj()
+	ctx := ctrl.LoggerInto(context.Background(), testr.New(t))
+	scheme := runtime.NewScheme()
+	if err := kueue.AddToScheme(scheme); err != nil {
+		t.Fatalf("Failed adding kueue scheme: %v", err)
+	}
+	cl := fake.NewClientBuilder().WithScheme(scheme).WithObjects(cq, lq).Build()
+	cqCache := cache.New(cl)
+	qManager := queue.NewManager(cl, cqCache)
+	if err := qManager.AddClusterQueue(ctx, cq); err != nil {
+		t.Fatalf("Inserting clusterQueue in manager: %v", err)
+	}
+	if err := qManager.AddLocalQueue(ctx, lq); err != nil {
+		t.Fatalf("Inserting localQueue in manager: %v", err)
+	}
+	r := NewWorkloadReconciler(cl, qManager, cqCache)
+
+	wl := testingutil.MakeWorkload("wl", "ns").Queue("lq").Request(corev1.ResourceCPU, "1").Obj()
+	r.Create(event.CreateEvent{Object: wl})
+	if pending := qManager.Pending(cq); pending != 1 {
+		t.Fatalf("Got %d pending workloads after the creation, want 1", pending)
+	}
+
+	inactive := wl.DeepCopy()
+	inactive.Spec.Active = pointer.Bool(false)
+	r.Update(event.UpdateEvent{ObjectOld: wl, ObjectNew: inactive})
+	if pending := qManager.Pending(cq); pending != 0 {
+		t.Errorf("Got %d pending workloads after the deactivation, want 0", pending)
+	}
+
+	active := inactive.DeepCopy()
+	active.Spec.Active = pointer.Bool(true)
+	r.Update(event.UpdateEvent{ObjectOld: inactive, ObjectNew: active})
+	if pending := qManager.Pending(cq); pending != 1 {
+		t.Errorf("Got %d pending workloads after the activation, want 1", pending)
+	}
+}
+
+func TestReconcileDeprecatedAdmission(t *testing.T) {
+	admission := testingutil.MakeAdmission("cq").Flavor(corev1.ResourceCPU, "default").Obj()
+	cases := map[string]struct {
+		workload       *kueue.Workload
+		updateFailures int
+	}{
+		"admission only in the spec": {
+			workload: testingutil.MakeWorkload("wl", "ns").Queue("lq").DeprecatedAdmission(admission).Obj(),
+		},
+		"admission already in the status": {
+			workload: testingutil.MakeWorkload("wl", "ns").Queue("lq").
+				DeprecatedAdmission(admission).Admit(admission).Obj(),
+		},
+		"removing the admission from the spec fails once": {
+			workload:       testingutil.MakeWorkload("wl", "ns").Queue("lq").DeprecatedAdmission(admission).Obj(),
+			updateFailures: 1,
+		},
+	}
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			ctx := ctrl.LoggerInto(context.Background(), testr.New(t))
+			scheme := runtime.NewScheme()
+			if err := kueue.AddToScheme(scheme); err != nil {
+				t.Fatalf("Failed adding kueue scheme: %v", err)
+			}
+			cl := &failingUpdateClient{
+				Client:   fake.NewClientBuilder().WithScheme(scheme).WithObjects(tc.workload).Build(),
+				failures: tc.updateFailures,
+			}
+			cqCache := cache.New(cl)
+			r := NewWorkloadReconciler(cl, queue.NewManager(cl, cqCache), cqCache)
+
+			key := client.ObjectKeyFromObject(tc.workload)
+			for i := 0; i < tc.updateFailures; i++ {
+				if _, err := r.Reconcile(ctx, ctrl.Request{NamespacedName: types.NamespacedName(key)}); err == nil {
+					t.Fatal("Reconcile succeeded, want the injected error")
+				}
+				// The admission is in the status before the spec is updated.
+				var gotWl kueue.Workload
+				if err := cl.Get(ctx, key, &gotWl); err != nil {
+					t.Fatalf("Failed getting workload: %v", err)
+				}
+				if diff := cmp.Diff(admission, gotWl.Status.Admission); diff != "" {
+					t.Errorf("Unexpected admission in the status after the failure (-want,+got):\n%s", diff)
+				}
+			}
+			if _, err := r.Reconcile(ctx, ctrl.Request{NamespacedName: types.NamespacedName(key)}); err != nil {
+				t.Fatalf("Reconcile returned error: %v", err)
+			}
+
+			var gotWl kueue.Workload
+			if err := cl.Get(ctx, key, &gotWl); err != nil {
+				t.Fatalf("Failed getting workload: %v", err)
+			}
+			if gotWl.Spec.Admission != nil {
+				t.Errorf("Workload kept the deprecated admission %v", gotWl.Spec.Admission)
+			}
+			if diff := cmp.Diff(admission, gotWl.Status.Admission); diff != "" {
+				t.Errorf("Unexpected admission in the status (-want,+got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestCreateWorkloadWithDeprecatedAdmission(t *testing.T) {
+	cq := testingutil.MakeClusterQueue("cq").
+		NamespaceSelector(&metav1.LabelSelector{}).
+		Resource(testingutil.MakeResource(corev1.ResourceCPU).
+			Flavor(testingutil.MakeFlavor("default", "10").Obj()).Obj()).
+		Obj()
+	lq := testingutil.MakeLocalQueue("lq", "ns").ClusterQueue("cq").Obj()
+	ctx := ctrl.LoggerInto(context.Background(), testr.New(t))
+	scheme := runtime.NewScheme()
+	if err := kueue.AddToScheme(scheme); err != nil {
+		t.Fatalf("Failed adding kueue scheme: %v", err)
+	}
+	cl := fake.NewClientBuilder().WithScheme(scheme).WithObjects(cq, lq).Build()
+	cqCache := cache.New(cl)
+	cqCache.AddOrUpdateResourceFlavor(testingutil.MakeResourceFlavor("default").Obj())
+	if err := cqCache.AddClusterQueue(ctx, cq); err != nil {
+		t.Fatalf("Inserting clusterQueue in cache: %v", err)
+	}
+	qManager := queue.NewManager(cl, cqCache)
+	if err := qManager.AddClusterQueue(ctx, cq); err != nil {
+		t.Fatalf("Inserting clusterQueue in manager: %v", err)
+	}
+	if err := qManager.AddLocalQueue(ctx, lq); err != nil {
+		t.Fatalf("Inserting localQueue in manager: %v", err)
+	}
+	r := NewWorkloadReconciler(cl, qManager, cqCache)
+
+	// The workload isn't queued again while its admission is converted, and
+	// its quota is counted.
+	wl := testingutil.MakeWorkload("wl", "ns").Queue("lq").Request(corev1.ResourceCPU, "1").
+		DeprecatedAdmission(testingutil.MakeAdmission("cq").Flavor(corev1.ResourceCPU, "default").Obj()).
+		Obj()
+	r.Create(event.CreateEvent{Object: wl})
+	if pending := qManager.Pending(cq); pending != 0 {
+		t.Errorf("Got %d pending workloads after the creation, want 0", pending)
+	}
+	if _, admitted, _ := cqCache.Usage(cq); admitted != 1 {
+		t.Errorf("Got %d admitted workloads after the creation, want 1", admitted)
+	}
+
+	// Moving the admission to the status doesn't change the usage.
+	converted := wl.DeepCopy()
+	converted.Status.Admission = converted.Spec.Admission
+	r.Update(event.UpdateEvent{ObjectOld: wl, ObjectNew: converted})
+	wl = converted.DeepCopy()
+	converted.Spec.Admission = nil
+	r.Update(event.UpdateEvent{ObjectOld: wl, ObjectNew: converted})
+	if _, admitted, _ := cqCache.Usage(cq); admitted != 1 {
+		t.Errorf("Got %d admitted workloads after the conversion, want 1", admitted)
+	}
+	if pending := qManager.Pending(cq); pending != 0 {
+		t.Errorf("Got %d pending workloads after the conversion, want 0", pending)
+	}
+}
+
+func TestReconcileAdmissionChecks(t *testing.T) {
+	admission := testingutil.MakeAdmission("cq").AdmissionChecks("provisioning").Obj()
+	cases := map[string]struct {
+		workload          *kueue.Workload
+		wantAdmission     bool
+		wantActive        bool
+		wantChecks        []kueue.AdmissionCheckState
+		wantConditions    []metav1.Condition
+		wantEvents        []string
+		ignoreEvictedCond bool
+	}{
+		"checks pending": {
+			workload:      testingutil.MakeWorkload("wl", "ns").Queue("lq").Admit(admission).Obj(),
+			wantAdmission: true,
+			wantActive:    true,
+			wantChecks: []kueue.AdmissionCheckState{
+				{Name: "provisioning", State: kueue.CheckStatePending},
+			},
+			wantConditions: []metav1.Condition{
+				{
+					Type:    kueue.WorkloadQuotaReserved,
+					Status:  metav1.ConditionTrue,
+					Reason:  "QuotaReserved",
+					Message: "Quota reserved in ClusterQueue cq",
+				},
+				{
+					Type:    kueue.WorkloadAdmitted,
+					Status:  metav1.ConditionFalse,
+					Reason:  "AdmissionChecksPending",
+					Message: "Waiting for the admission checks provisioning",
+				},
+			},
+		},
+		"checks ready": {
+			workload: testingutil.MakeWorkload("wl", "ns").
+				Queue("lq").
+				Admit(admission).
+				AdmissionCheck("provisioning", kueue.CheckStateReady).
+				Obj(),
+			wantAdmission: true,
+			wantActive:    true,
+			wantChecks: []kueue.AdmissionCheckState{
+				{Name: "provisioning", State: kueue.CheckStateReady},
+			},
+			wantConditions: []metav1.Condition{
+				{
+					Type:    kueue.WorkloadQuotaReserved,
+					Status:  metav1.ConditionTrue,
+					Reason:  "QuotaReserved",
+					Message: "Quota reserved in ClusterQueue cq",
+				},
+				{
+					Type:    kueue.WorkloadAdmitted,
+					Status:  metav1.ConditionTrue,
+					Reason:  "AdmissionByKueue",
+					Message: "Admitted by ClusterQueue cq",
+				},
+			},
+		},
+		"check to retry": {
+			workload: testingutil.MakeWorkload("wl", "ns").
+				Queue("lq").
+				Admit(admission).
+				AdmissionCheck("provisioning", kueue.CheckStateRetry).
+				Obj(),
+			wantActive: true,
+			wantConditions: []metav1.Condition{
+				{
+					Type:    kueue.WorkloadEvicted,
+					Status:  metav1.ConditionTrue,
+					Reason:  ReasonAdmissionCheckRetry,
+					Message: "Evicted to retry the admission check provisioning: ",
+				},
+				{
+					Type:    kueue.WorkloadAdmitted,
+					Status:  metav1.ConditionFalse,
+					Reason:  ReasonAdmissionCheckRetry,
+					Message: "Evicted to retry the admission check provisioning: ",
+				},
+			},
+		},
+		"check rejected": {
+			workload: testingutil.MakeWorkload("wl", "ns").
+				Queue("lq").
+				Admit(admission).
+				AdmissionCheck("provisioning", kueue.CheckStateRejected).
+				Obj(),
+			wantConditions: []metav1.Condition{
+				{
+					Type:    kueue.WorkloadEvicted,
+					Status:  metav1.ConditionTrue,
+					Reason:  ReasonAdmissionCheckRejected,
+					Message: "Rejected by the admission check provisioning: ",
+				},
+				{
+					Type:    kueue.WorkloadAdmitted,
+					Status:  metav1.ConditionFalse,
+					Reason:  ReasonAdmissionCheckRejected,
+					Message: "Rejected by the admission check provisioning: ",
+				},
+			},
+			wantEvents: []string{"Warning AdmissionCheckRejected Rejected by the admission check provisioning: "},
+		},
+	}
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			ctx := ctrl.LoggerInto(context.Background(), testr.New(t))
+			scheme := runtime.NewScheme()
+			if err := kueue.AddToScheme(scheme); err != nil {
+				t.Fatalf("Failed adding kueue scheme: %v", err)
+			}
+			cl := fake.NewClientBuilder().WithScheme(scheme).WithObjects(tc.workload).Build()
+			cqCache := cache.New(cl)
+			recorder := record.NewFakeRecorder(10)
+			r := NewWorkloadReconciler(cl, queue.NewManager(cl, cqCache), cqCache, WithEventRecorder(recorder))
+
+			key := client.ObjectKeyFromObject(tc.workload)
+			if _, err := r.Reconcile(ctx, ctrl.Request{NamespacedName: types.NamespacedName(key)}); err != nil {
+				t.Fatalf("Reconcile returned error: %v", err)
+			}
+
+			var gotWl kueue.Workload
+			if err := cl.Get(ctx, key, &gotWl); err != nil {
+				t.Fatalf("Failed getting workload: %v", err)
+			}
+			if gotAdmission := gotWl.Status.Admission != nil; gotAdmission != tc.wantAdmission {
+				t.Errorf("Workload has admission: %t, want %t", gotAdmission, tc.wantAdmission)
+			}
+			if gotActive := workload.IsActive(&gotWl); gotActive != tc.wantActive {
+				t.Errorf("Workload active: %t, want %t", gotActive, tc.wantActive)
+			}
+			if diff := cmp.Diff(tc.wantChecks, gotWl.Status.AdmissionChecks,
+				cmpopts.IgnoreFields(kueue.AdmissionCheckState{}, "LastTransitionTime")); diff != "" {
+				t.Errorf("Unexpected admission checks (-want,+got):\n%s", diff)
+			}
+			if diff := cmp.Diff(tc.wantConditions, gotWl.Status.Conditions,
+				cmpopts.IgnoreFields(metav1.Condition{}, "LastTransitionTime")); diff != "" {
+				t.Errorf("Unexpected conditions (-want,+got):\n%s", diff)
+			}
+			close(recorder.Events)
+			var gotEvents []string
+			for e := range recorder.Events {
+				gotEvents = append(gotEvents, e)
+			}
+			if diff := cmp.Diff(tc.wantEvents, gotEvents); diff != "" {
+				t.Errorf("Unexpected events (-want,+got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestReconcilePodsReadyTimeout(t *testing.T) {
+	now := time.Now()
+	admitted := func(at time.Time) *testingutil.WorkloadWrapper {
+		return testingutil.MakeWorkload("wl", "ns").
+			Queue("lq").
+			Admit(testingutil.MakeAdmission("cq").Obj()).
+			Condition(metav1.Condition{
+				Type:               kueue.WorkloadQuotaReserved,
+				Status:             metav1.ConditionTrue,
+				Reason:             kueue.WorkloadReasonQuotaReserved,
+				Message:            "Quota reserved in ClusterQueue cq",
+				LastTransitionTime: metav1.NewTime(at),
+			}).
+			Condition(metav1.Condition{
+				Type:               kueue.WorkloadAdmitted,
+				Status:             metav1.ConditionTrue,
+				Reason:             kueue.WorkloadReasonAdmitted,
+				Message:            "Admitted by ClusterQueue cq",
+				LastTransitionTime: metav1.NewTime(at),
+			})
+	}
+	cases := map[string]struct {
+		workload       *kueue.Workload
+		wantAdmission  bool
+		wantRequeue    bool
+		wantConditions []metav1.Condition
+		wantEvents     []string
+	}{
+		"waiting for pods ready": {
+			workload:      admitted(now.Add(-time.Minute)).Obj(),
+			wantAdmission: true,
+			wantRequeue:   true,
+			wantConditions: []metav1.Condition{
+				{
+					Type:    kueue.WorkloadQuotaReserved,
+					Status:  metav1.ConditionTrue,
+					Reason:  kueue.WorkloadReasonQuotaReserved,
+					Message: "Quota reserved in ClusterQueue cq",
+				},
+				{
+					Type:    kueue.WorkloadAdmitted,
+					Status:  metav1.ConditionTrue,
+					Reason:  kueue.WorkloadReasonAdmitted,
+					Message: "Admitted by ClusterQueue cq",
+				},
+			},
+		},
+		"pods ready": {
+			workload: admitted(now.Add(-time.Hour)).
+				Condition(metav1.Condition{
+					Type:   kueue.WorkloadPodsReady,
+					Status: metav1.ConditionTrue,
+					Reason: kueue.WorkloadReasonPodsReady,
+				}).
+				Obj(),
+			wantAdmission: true,
+			wantConditions: []metav1.Condition{
+				{
+					Type:    kueue.WorkloadQuotaReserved,
+					Status:  metav1.ConditionTrue,
+					Reason:  kueue.WorkloadReasonQuotaReserved,
+					Message: "Quota reserved in ClusterQueue cq",
+				},
+				{
+					Type:    kueue.WorkloadAdmitted,
+					Status:  metav1.ConditionTrue,
+					Reason:  kueue.WorkloadReasonAdmitted,
+					Message: "Admitted by ClusterQueue cq",
+				},
+				{
+					Type:   kueue.WorkloadPodsReady,
+					Status: metav1.ConditionTrue,
+					Reason: kueue.WorkloadReasonPodsReady,
+				},
+			},
+		},
+		"timeout exceeded": {
+			workload: admitted(now.Add(-time.Hour)).Obj(),
+			wantConditions: []metav1.Condition{
+				{
+					Type:    kueue.WorkloadQuotaReserved,
+					Status:  metav1.ConditionFalse,
+					Reason:  kueue.WorkloadEvictedByPodsReadyTimeout,
+					Message: "Evicted because the pods weren't ready within 5m0s",
+				},
+				{
+					Type:    kueue.WorkloadAdmitted,
+					Status:  metav1.ConditionFalse,
+					Reason:  kueue.WorkloadEvictedByPodsReadyTimeout,
+					Message: "Evicted because the pods weren't ready within 5m0s",
+				},
+				{
+					Type:    kueue.WorkloadEvicted,
+					Status:  metav1.ConditionTrue,
+					Reason:  kueue.WorkloadEvictedByPodsReadyTimeout,
+					Message: "Evicted because the pods weren't ready within 5m0s",
+				},
+			},
+			wantEvents: []string{"Normal PodsReadyTimeout Evicted because the pods weren't ready within 5m0s"},
+		},
+	}
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			ctx := ctrl.LoggerInto(context.Background(), testr.New(t))
+			scheme := runtime.NewScheme()
+			if err := kueue.AddToScheme(scheme); err != nil {
+				t.Fatalf("Failed adding kueue scheme: %v", err)
+			}
+			cl := fake.NewClientBuilder().WithScheme(scheme).WithObjects(tc.workload).Build()
+			cqCache := cache.New(cl)
+			recorder := record.NewFakeRecorder(10)
+			r := NewWorkloadReconciler(cl, queue.NewManager(cl, cqCache), cqCache,
+				WithEventRecorder(recorder), WithPodsReadyTimeout(5*time.Minute))
+
+			key := client.ObjectKeyFromObject(tc.workload)
+			res, err := r.Reconcile(ctx, ctrl.Request{NamespacedName: types.NamespacedName(key)})
+			if err != nil {
+				t.Fatalf("Reconcile returned error: %v", err)
+			}
+			if gotRequeue := res.RequeueAfter > 0; gotRequeue != tc.wantRequeue {
+				t.Errorf("Reconcile requeued after %v, want requeue: %t", res.RequeueAfter, tc.wantRequeue)
+			}
+
+			var gotWl kueue.Workload
+			if err := cl.Get(ctx, key, &gotWl); err != nil {
+				t.Fatalf("Failed getting workload: %v", err)
+			}
+			if gotAdmission := gotWl.Status.Admission != nil; gotAdmission != tc.wantAdmission {
+				t.Errorf("Workload has admission: %t, want %t", gotAdmission, tc.wantAdmission)
+			}
+			if diff := cmp.Diff(tc.wantConditions, gotWl.Status.Conditions,
+				cmpopts.IgnoreFields(metav1.Condition{}, "LastTransitionTime")); diff != "" {
+				t.Errorf("Unexpected conditions (-want,+got):\n%s", diff)
+			}
+			close(recorder.Events)
+			var gotEvents []string
+			for e := range recorder.Events {
+				gotEvents = append(gotEvents, e)
+			}
+			if diff := cmp.Diff(tc.wantEvents, gotEvents); diff != "" {
+				t.Errorf("Unexpected events (-want,+got):\n%s", diff)
+			}
+		})
+	}
+}
@@ -0,0 +1,217 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package job
+
+import (
+	"context"
+
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/record"
+	"k8s.io/utils/pointer"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	kueue "sigs.k8s.io/kueue/apis/kueue/v1alpha2"
+	"sigs.k8s.io/kueue/pkg/constants"
+	"sigs.k8s.io/kueue/pkg/controller/workload/jobframework"
+	utiltolerations "sigs.k8s.io/kueue/pkg/util/tolerations"
+)
+
+// BatchJob implements jobframework.GenericJob for batch/v1 Jobs.
+type BatchJob batchv1.Job
+
+var _ jobframework.GenericJob = &BatchJob{}
+var _ jobframework.JobWithRestoreNodeSelectors = &BatchJob{}
+var _ jobframework.JobWithReclaimablePods = &BatchJob{}
+var _ jobframework.JobWithPartialAdmission = &BatchJob{}
+var _ jobframework.JobWithSplitPodSets = &BatchJob{}
+var _ jobframework.JobWithTolerations = &BatchJob{}
+var _ jobframework.JobWithResettableStatus = &BatchJob{}
+var _ jobframework.JobWithCustomWorkload = &BatchJob{}
+var _ jobframework.JobWithHeldWorkload = &BatchJob{}
+var _ jobframework.JobWithInvalidWorkload = &BatchJob{}
+
+func (j *BatchJob) Object() client.Object {
+	return (*batchv1.Job)(j)
+}
+
+func (j *BatchJob) QueueName() string {
+	return queueName((*batchv1.Job)(j))
+}
+
+func (j *BatchJob) IsSuspended() bool {
+	return jobSuspended((*batchv1.Job)(j))
+}
+
+func (j *BatchJob) Suspend() {
+	j.Spec.Suspend = pointer.Bool(true)
+}
+
+func (j *BatchJob) RunWithNodeSelectors(nodeSelectors []map[string]string) {
+	if len(nodeSelectors) > 0 && len(nodeSelectors[0]) > 0 {
+		if j.Spec.Template.Spec.NodeSelector == nil {
+			j.Spec.Template.Spec.NodeSelector = make(map[string]string, len(nodeSelectors[0]))
+		}
+		for k, v := range nodeSelectors[0] {
+			j.Spec.Template.Spec.NodeSelector[k] = v
+		}
+	}
+	j.Spec.Suspend = pointer.Bool(false)
+}
+
+func (j *BatchJob) PodSets() []kueue.PodSet {
+	return []kueue.PodSet{
+		{
+			Spec:  *j.Spec.Template.Spec.DeepCopy(),
+			Count: podsCount(&j.Spec),
+		},
+	}
+}
+
+func (j *BatchJob) EquivalentToWorkload(wl *kueue.Workload) bool {
+	return jobAndWorkloadEqual((*batchv1.Job)(j), wl)
+}
+
+func (j *BatchJob) Finished() (metav1.Condition, bool) {
+	condType, finished := jobFinishedCondition((*batchv1.Job)(j))
+	if !finished {
+		return metav1.Condition{}, false
+	}
+	return generateFinishedCondition(condType), true
+}
+
+func (j *BatchJob) PodsReady() bool {
+	return podsReady((*batchv1.Job)(j))
+}
+
+// RestoreNodeSelectors sets the node selector of the pod template back to the
+// one of the pod set.
+func (j *BatchJob) RestoreNodeSelectors(podSets []kueue.PodSet) {
+	if len(podSets) == 0 || equality.Semantic.DeepEqual(j.Spec.Template.Spec.NodeSelector, podSets[0].Spec.NodeSelector) {
+		return
+	}
+	j.Spec.Template.Spec.NodeSelector = map[string]string{}
+	for k, v := range podSets[0].Spec.NodeSelector {
+		j.Spec.Template.Spec.NodeSelector[k] = v
+	}
+}
+
+// ReclaimablePods returns the pods whose quota is no longer needed, because
+// fewer pods than the parallelism remain to succeed.
+func (j *BatchJob) ReclaimablePods(wl *kueue.Workload) []kueue.ReclaimablePod {
+	return reclaimablePods((*batchv1.Job)(j), wl)
+}
+
+// RunWithPodSetCounts sets the parallelism to the admitted count, which is
+// lower than the one of the pod set if the workload is partially admitted.
+func (j *BatchJob) RunWithPodSetCounts(counts []int32) {
+	if len(counts) > 0 {
+		j.Spec.Parallelism = pointer.Int32(counts[0])
+	}
+}
+
+// RestorePodSetCounts sets the parallelism reduced by a partial admission
+// back to the count of the pod set.
+func (j *BatchJob) RestorePodSetCounts(podSets []kueue.PodSet) {
+	if len(podSets) > 0 && *j.Spec.Parallelism < podSets[0].Count {
+		j.Spec.Parallelism = pointer.Int32(podSets[0].Count)
+	}
+}
+
+// RunWithNodeAffinities requires the pods to run on the nodes of any of the
+// parts of a split pod set.
+func (j *BatchJob) RunWithNodeAffinities(affinities []*corev1.NodeSelector) {
+	if len(affinities) > 0 && affinities[0] != nil {
+		j.Spec.Template.Spec.Affinity = withRequiredNodeAffinity(j.Spec.Template.Spec.Affinity, affinities[0])
+	}
+}
+
+// RestoreNodeAffinities sets the affinity changed by the admission of a split
+// pod set back to the one of the pod set.
+func (j *BatchJob) RestoreNodeAffinities(podSets []kueue.PodSet) {
+	if len(podSets) > 0 && !equality.Semantic.DeepEqual(j.Spec.Template.Spec.Affinity, podSets[0].Spec.Affinity) {
+		j.Spec.Template.Spec.Affinity = podSets[0].Spec.Affinity.DeepCopy()
+	}
+}
+
+// AddTolerations appends the tolerations of the pod set, such as the ones
+// added from the LocalQueue, that the pod template doesn't have.
+func (j *BatchJob) AddTolerations(podSets []kueue.PodSet) {
+	if len(podSets) > 0 {
+		j.Spec.Template.Spec.Tolerations = utiltolerations.AppendMissing(j.Spec.Template.Spec.Tolerations, podSets[0].Spec.Tolerations)
+	}
+}
+
+// ResetStatus resets the start time, as the scheduling directives of the pod
+// template can only be updated while it's unset.
+func (j *BatchJob) ResetStatus() bool {
+	if j.Status.StartTime == nil {
+		return false
+	}
+	j.Status.StartTime = nil
+	return true
+}
+
+// CustomizeWorkload names the workload after the Job, which the dependencies
+// between Jobs refer to, and sets the fields taken from the annotations and
+// labels of the Job.
+func (j *BatchJob) CustomizeWorkload(wl *kueue.Workload) error {
+	job := (*batchv1.Job)(j)
+	wl.Name = job.Name
+	var err error
+	if wl.Spec.Deadline, wl.Spec.ExpectedDuration, err = deadlineFromAnnotations(job); err != nil {
+		return err
+	}
+	if wl.Spec.PodSets[0].MinCount, err = minCountFromAnnotations(job); err != nil {
+		return err
+	}
+	wl.Annotations = map[string]string{constants.PodTemplateHashAnnotation: podTemplateHash(&job.Spec.Template)}
+	// Propagate the admission group, so that the workload is admitted along
+	// with the workloads of the other jobs in the group.
+	if group, ok := job.Labels[constants.AdmissionGroupLabel]; ok {
+		wl.Labels = map[string]string{constants.AdmissionGroupLabel: group}
+		wl.Annotations[constants.AdmissionGroupSizeAnnotation] = job.Annotations[constants.AdmissionGroupSizeAnnotation]
+	}
+	if dependency, ok := job.Annotations[constants.DependsOnAnnotation]; ok {
+		wl.Annotations[constants.DependsOnAnnotation] = dependency
+	}
+	return nil
+}
+
+// HoldWorkload holds the workload of a Job created by a CronJob whose
+// LocalQueue is saturated, and of a suspended Job that still has active pods.
+func (j *BatchJob) HoldWorkload(ctx context.Context, c client.Client, recorder record.EventRecorder) (bool, ctrl.Result, error) {
+	job := (*batchv1.Job)(j)
+	if held, result, err := handleCronJobSaturation(ctx, c, recorder, job); held || err != nil {
+		return held, result, err
+	}
+	// Wait until there are no active pods.
+	if job.Status.Active != 0 {
+		ctrl.LoggerFrom(ctx).V(2).Info("Job is suspended but still has active pods, waiting")
+		return true, ctrl.Result{}, nil
+	}
+	return false, ctrl.Result{}, nil
+}
+
+// KeepInvalidWorkload keeps the admitted workload of a running Job whose pod
+// template changed, as long as the Job runs the pods it was admitted with.
+func (j *BatchJob) KeepInvalidWorkload(wl *kueue.Workload) bool {
+	return jobAndWorkloadCountsEqual((*batchv1.Job)(j), wl)
+}
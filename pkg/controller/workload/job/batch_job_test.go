@@ -0,0 +1,68 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package job
+
+import (
+	"testing"
+
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/utils/pointer"
+
+	kueue "sigs.k8s.io/kueue/apis/kueue/v1alpha2"
+	"sigs.k8s.io/kueue/pkg/controller/workload/jobframework"
+	"sigs.k8s.io/kueue/pkg/controller/workload/jobframework/conformance"
+	utiltesting "sigs.k8s.io/kueue/pkg/util/testing"
+)
+
+func TestBatchJobConformance(t *testing.T) {
+	newJob := func() *batchv1.Job {
+		return utiltesting.MakeJob("job", "ns").
+			Queue("queue").
+			Parallelism(3).
+			NodeSelector("provisioning", "spot").
+			Request(corev1.ResourceCPU, "1").
+			Obj()
+	}
+	conformance.Run(t, conformance.Suite{
+		NewJob: func() jobframework.GenericJob {
+			return (*BatchJob)(newJob())
+		},
+		WantPodSets: []kueue.PodSet{
+			{
+				Spec:  newJob().Spec.Template.Spec,
+				Count: 3,
+			},
+		},
+		WantQueueName: "queue",
+		SetPodsReady: func(job jobframework.GenericJob) {
+			j := job.(*BatchJob)
+			j.Status.Ready = pointer.Int32(*j.Spec.Parallelism)
+		},
+		SetFinished: func(job jobframework.GenericJob, success bool) {
+			j := job.(*BatchJob)
+			condType := batchv1.JobComplete
+			if !success {
+				condType = batchv1.JobFailed
+			}
+			j.Status.Conditions = append(j.Status.Conditions, batchv1.JobCondition{
+				Type:   condType,
+				Status: corev1.ConditionTrue,
+			})
+		},
+	})
+}
@@ -0,0 +1,223 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package job
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
+	"k8s.io/client-go/util/workqueue"
+	"k8s.io/klog/v2"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	kueue "sigs.k8s.io/kueue/apis/kueue/v1alpha2"
+	"sigs.k8s.io/kueue/pkg/constants"
+	"sigs.k8s.io/kueue/pkg/queue"
+)
+
+type saturationPolicy string
+
+const (
+	// saturationPolicySkip deletes the Jobs created while the LocalQueue is
+	// saturated, so the run is skipped.
+	saturationPolicySkip saturationPolicy = "Skip"
+	// saturationPolicyDelay holds the Jobs created while the LocalQueue is
+	// saturated without creating their Workloads, until the LocalQueue drains
+	// below the threshold.
+	saturationPolicyDelay saturationPolicy = "Delay"
+
+	// saturationRecheckPeriod is how often a delayed Job checks again
+	// whether its LocalQueue is still saturated. The delayed Jobs are also
+	// reconciled when their queues drain, see delayedRunHandler, so this is a
+	// fallback for the missed events.
+	saturationRecheckPeriod = 30 * time.Second
+)
+
+type saturationSettings struct {
+	policy    saturationPolicy
+	threshold int32
+}
+
+// cronJobSaturationSettings returns the saturation settings declared in the
+// annotations of the CronJob. It returns nil if the CronJob doesn't opt-in.
+func cronJobSaturationSettings(cj *batchv1.CronJob) (*saturationSettings, error) {
+	policy, ok := cj.Annotations[constants.CronJobSaturationPolicyAnnotation]
+	if !ok {
+		return nil, nil
+	}
+	s := &saturationSettings{policy: saturationPolicy(policy)}
+	if s.policy != saturationPolicySkip && s.policy != saturationPolicyDelay {
+		return nil, fmt.Errorf("invalid %s annotation %q, must be %s or %s",
+			constants.CronJobSaturationPolicyAnnotation, policy, saturationPolicySkip, saturationPolicyDelay)
+	}
+	threshold, err := strconv.ParseInt(cj.Annotations[constants.CronJobSaturationThresholdAnnotation], 10, 32)
+	if err != nil || threshold < 0 {
+		return nil, fmt.Errorf("invalid %s annotation %q, must be a non-negative integer",
+			constants.CronJobSaturationThresholdAnnotation, cj.Annotations[constants.CronJobSaturationThresholdAnnotation])
+	}
+	s.threshold = int32(threshold)
+	return s, nil
+}
+
+func (s *saturationSettings) saturated(lq *kueue.LocalQueue) bool {
+	return lq.Status.PendingWorkloads >= s.threshold
+}
+
+// handleCronJobSaturation applies the saturation policy of the CronJob that
+// owns the job, if any. It returns true if the job shouldn't get a Workload
+// for now, along with the result to return from the reconciliation.
+func handleCronJobSaturation(ctx context.Context, c client.Client, recorder record.EventRecorder, job *batchv1.Job) (bool, ctrl.Result, error) {
+	log := ctrl.LoggerFrom(ctx)
+	owner := metav1.GetControllerOf(job)
+	if owner == nil || owner.APIVersion != "batch/v1" || owner.Kind != "CronJob" {
+		return false, ctrl.Result{}, nil
+	}
+	var cj batchv1.CronJob
+	if err := c.Get(ctx, types.NamespacedName{Name: owner.Name, Namespace: job.Namespace}, &cj); err != nil {
+		return false, ctrl.Result{}, client.IgnoreNotFound(err)
+	}
+	settings, err := cronJobSaturationSettings(&cj)
+	if err != nil {
+		log.Error(err, "Ignoring the saturation policy of the CronJob", "cronJob", owner.Name)
+		return false, ctrl.Result{}, nil
+	}
+	if settings == nil {
+		return false, ctrl.Result{}, nil
+	}
+
+	var lq kueue.LocalQueue
+	if err := c.Get(ctx, types.NamespacedName{Name: queueName(job), Namespace: job.Namespace}, &lq); err != nil {
+		// Without a queue, the workload is reported as inadmissible.
+		return false, ctrl.Result{}, client.IgnoreNotFound(err)
+	}
+	if !settings.saturated(&lq) {
+		return false, ctrl.Result{}, nil
+	}
+
+	msg := fmt.Sprintf("LocalQueue %s has %d pending workloads, the saturation threshold is %d",
+		lq.Name, lq.Status.PendingWorkloads, settings.threshold)
+	if settings.policy == saturationPolicySkip {
+		log.V(2).Info("Skipping CronJob run as the LocalQueue is saturated", "cronJob", owner.Name)
+		if err := c.Delete(ctx, job, client.PropagationPolicy(metav1.DeletePropagationBackground)); err != nil {
+			return true, ctrl.Result{}, client.IgnoreNotFound(err)
+		}
+		recorder.Eventf(&cj, corev1.EventTypeNormal, "SkippedRun", "Skipped Job %s: %s", job.Name, msg)
+		return true, ctrl.Result{}, nil
+	}
+
+	log.V(2).Info("Delaying CronJob run as the LocalQueue is saturated", "cronJob", owner.Name)
+	if _, delayed := job.Annotations[constants.CronJobDelayedRunAnnotation]; !delayed {
+		// Mark the job, so that the rechecks don't report the delay again.
+		if job.Annotations == nil {
+			job.Annotations = make(map[string]string, 1)
+		}
+		job.Annotations[constants.CronJobDelayedRunAnnotation] = "true"
+		if err := c.Update(ctx, job); err != nil {
+			return true, ctrl.Result{}, client.IgnoreNotFound(err)
+		}
+		recorder.Eventf(job, corev1.EventTypeNormal, "DelayedRun", "Delaying the creation of the Workload: %s", msg)
+	}
+	return true, ctrl.Result{RequeueAfter: saturationRecheckPeriod}, nil
+}
+
+// delayedRunHandler enqueues the delayed Jobs of CronJobs when their
+// LocalQueue or its ClusterQueues drain, so that they get their Workloads as
+// soon as the LocalQueue is no longer saturated.
+type delayedRunHandler struct {
+	client client.Client
+}
+
+func (h *delayedRunHandler) Create(event.CreateEvent, workqueue.RateLimitingInterface) {
+}
+
+func (h *delayedRunHandler) Update(e event.UpdateEvent, q workqueue.RateLimitingInterface) {
+	ctx := context.Background()
+	switch newObj := e.ObjectNew.(type) {
+	case *kueue.LocalQueue:
+		oldObj, ok := e.ObjectOld.(*kueue.LocalQueue)
+		if !ok || newObj.Status.PendingWorkloads >= oldObj.Status.PendingWorkloads {
+			return
+		}
+		h.addDelayedRuns(ctx, newObj, q)
+	case *kueue.ClusterQueue:
+		oldObj, ok := e.ObjectOld.(*kueue.ClusterQueue)
+		if !ok || !clusterQueueDrained(oldObj, newObj) {
+			return
+		}
+		h.addDelayedRunsOfClusterQueue(ctx, newObj.Name, q)
+	}
+}
+
+func (h *delayedRunHandler) Delete(event.DeleteEvent, workqueue.RateLimitingInterface) {
+}
+
+func (h *delayedRunHandler) Generic(event.GenericEvent, workqueue.RateLimitingInterface) {
+}
+
+// clusterQueueDrained returns whether the ClusterQueue has fewer pending
+// workloads, or its usage changed, so that the pending workloads of its
+// LocalQueues might be admitted.
+func clusterQueueDrained(oldCq, newCq *kueue.ClusterQueue) bool {
+	return newCq.Status.PendingWorkloads < oldCq.Status.PendingWorkloads ||
+		!equality.Semantic.DeepEqual(oldCq.Status.UsedResources, newCq.Status.UsedResources)
+}
+
+// addDelayedRunsOfClusterQueue enqueues the delayed Jobs of the LocalQueues
+// that point to the ClusterQueue.
+func (h *delayedRunHandler) addDelayedRunsOfClusterQueue(ctx context.Context, cqName string, q workqueue.RateLimitingInterface) {
+	var queues kueue.LocalQueueList
+	if err := h.client.List(ctx, &queues); err != nil {
+		ctrl.LoggerFrom(ctx).Error(err, "Listing the LocalQueues of the ClusterQueue", "clusterQueue", cqName)
+		return
+	}
+	for i := range queues.Items {
+		lq := &queues.Items[i]
+		for _, name := range queue.ClusterQueuesOf(lq) {
+			if name == cqName {
+				h.addDelayedRuns(ctx, lq, q)
+				break
+			}
+		}
+	}
+}
+
+// addDelayedRuns enqueues the delayed Jobs submitted to the LocalQueue.
+func (h *delayedRunHandler) addDelayedRuns(ctx context.Context, lq *kueue.LocalQueue, q workqueue.RateLimitingInterface) {
+	var jobs batchv1.JobList
+	if err := h.client.List(ctx, &jobs, client.InNamespace(lq.Namespace)); err != nil {
+		ctrl.LoggerFrom(ctx).Error(err, "Listing the delayed Jobs of the LocalQueue", "localQueue", klog.KObj(lq))
+		return
+	}
+	for i := range jobs.Items {
+		job := &jobs.Items[i]
+		if _, delayed := job.Annotations[constants.CronJobDelayedRunAnnotation]; !delayed || queueName(job) != lq.Name {
+			continue
+		}
+		q.Add(reconcile.Request{NamespacedName: client.ObjectKeyFromObject(job)})
+	}
+}
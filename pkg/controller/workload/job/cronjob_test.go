@@ -0,0 +1,274 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package job
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/record"
+	"k8s.io/client-go/util/workqueue"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	kueue "sigs.k8s.io/kueue/apis/kueue/v1alpha2"
+	"sigs.k8s.io/kueue/pkg/constants"
+	utiltesting "sigs.k8s.io/kueue/pkg/util/testing"
+)
+
+func TestCronJobSaturationSettings(t *testing.T) {
+	testcases := map[string]struct {
+		annotations map[string]string
+		want        *saturationSettings
+		wantErr     bool
+	}{
+		"no annotations": {},
+		"skip": {
+			annotations: map[string]string{
+				constants.CronJobSaturationPolicyAnnotation:    "Skip",
+				constants.CronJobSaturationThresholdAnnotation: "5",
+			},
+			want: &saturationSettings{policy: saturationPolicySkip, threshold: 5},
+		},
+		"delay": {
+			annotations: map[string]string{
+				constants.CronJobSaturationPolicyAnnotation:    "Delay",
+				constants.CronJobSaturationThresholdAnnotation: "0",
+			},
+			want: &saturationSettings{policy: saturationPolicyDelay, threshold: 0},
+		},
+		"invalid policy": {
+			annotations: map[string]string{
+				constants.CronJobSaturationPolicyAnnotation:    "Drop",
+				constants.CronJobSaturationThresholdAnnotation: "5",
+			},
+			wantErr: true,
+		},
+		"missing threshold": {
+			annotations: map[string]string{
+				constants.CronJobSaturationPolicyAnnotation: "Skip",
+			},
+			wantErr: true,
+		},
+		"negative threshold": {
+			annotations: map[string]string{
+				constants.CronJobSaturationPolicyAnnotation:    "Skip",
+				constants.CronJobSaturationThresholdAnnotation: "-1",
+			},
+			wantErr: true,
+		},
+	}
+	for name, tc := range testcases {
+		t.Run(name, func(t *testing.T) {
+			cj := &batchv1.CronJob{ObjectMeta: metav1.ObjectMeta{Annotations: tc.annotations}}
+			got, err := cronJobSaturationSettings(cj)
+			if gotErr := err != nil; gotErr != tc.wantErr {
+				t.Fatalf("Unexpected error (want: %v, got: %v)", tc.wantErr, err)
+			}
+			if diff := cmp.Diff(tc.want, got, cmp.AllowUnexported(saturationSettings{})); diff != "" {
+				t.Errorf("Unexpected settings (-want,+got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestReconcileCronJobSaturation(t *testing.T) {
+	const msg = "LocalQueue lq has 5 pending workloads, the saturation threshold is 5"
+	cases := map[string]struct {
+		policy          string
+		pendingInQueue  int32
+		wantJobDeleted  bool
+		wantDelayedMark bool
+		wantRequeue     bool
+		wantWorkload    bool
+		wantEvents      []string
+	}{
+		"skip deletes the job": {
+			policy:         "Skip",
+			pendingInQueue: 5,
+			wantJobDeleted: true,
+			wantEvents:     []string{"Normal SkippedRun Skipped Job job: " + msg},
+		},
+		"delay keeps the job suspended and reports the delay once": {
+			policy:          "Delay",
+			pendingInQueue:  5,
+			wantRequeue:     true,
+			wantDelayedMark: true,
+			wantEvents:      []string{"Normal DelayedRun Delaying the creation of the Workload: " + msg},
+		},
+		"the queue isn't saturated": {
+			policy:         "Delay",
+			pendingInQueue: 4,
+			wantWorkload:   true,
+			wantEvents:     []string{"Normal CreatedWorkload Created Workload: ns/job"},
+		},
+	}
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			scheme := utiltesting.MustGetScheme(t)
+			if err := batchv1.AddToScheme(scheme); err != nil {
+				t.Fatalf("Failed adding batch scheme: %v", err)
+			}
+			ctx := context.Background()
+			cj := &batchv1.CronJob{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "cron",
+					Namespace: "ns",
+					UID:       "cron-uid",
+					Annotations: map[string]string{
+						constants.CronJobSaturationPolicyAnnotation:    tc.policy,
+						constants.CronJobSaturationThresholdAnnotation: "5",
+					},
+				},
+			}
+			job := utiltesting.MakeJob("job", "ns").Queue("lq").Request(corev1.ResourceCPU, "1").Obj()
+			job.OwnerReferences = []metav1.OwnerReference{*metav1.NewControllerRef(cj, batchv1.SchemeGroupVersion.WithKind("CronJob"))}
+			lq := utiltesting.MakeLocalQueue("lq", "ns").ClusterQueue("cq").PendingWorkloads(tc.pendingInQueue).Obj()
+			cl := fake.NewClientBuilder().WithScheme(scheme).WithObjects(cj, job, lq).Build()
+			recorder := record.NewFakeRecorder(10)
+			r := NewReconciler(scheme, cl, recorder)
+
+			// The second reconciliation is the recheck of a delayed job.
+			var results []ctrl.Result
+			for i := 0; i < 2; i++ {
+				result, err := r.Reconcile(ctx, ctrl.Request{NamespacedName: client.ObjectKeyFromObject(job)})
+				if err != nil {
+					t.Fatalf("Reconcile returned error: %v", err)
+				}
+				results = append(results, result)
+			}
+			for _, result := range results {
+				if gotRequeue := result.RequeueAfter == saturationRecheckPeriod; gotRequeue != tc.wantRequeue {
+					t.Errorf("Reconcile returned %+v, want requeue: %t", result, tc.wantRequeue)
+				}
+			}
+
+			var gotJob batchv1.Job
+			err := cl.Get(ctx, client.ObjectKeyFromObject(job), &gotJob)
+			if gotDeleted := apierrors.IsNotFound(err); gotDeleted != tc.wantJobDeleted {
+				t.Fatalf("Job deleted: %t, want %t (error: %v)", gotDeleted, tc.wantJobDeleted, err)
+			}
+			if !tc.wantJobDeleted {
+				if gotJob.Spec.Suspend == nil || !*gotJob.Spec.Suspend {
+					t.Error("The job isn't suspended")
+				}
+				if _, gotMark := gotJob.Annotations[constants.CronJobDelayedRunAnnotation]; gotMark != tc.wantDelayedMark {
+					t.Errorf("Job has the delayed run annotation: %t, want %t", gotMark, tc.wantDelayedMark)
+				}
+			}
+			var workloads kueue.WorkloadList
+			if err := cl.List(ctx, &workloads); err != nil {
+				t.Fatalf("Failed listing workloads: %v", err)
+			}
+			if gotWorkload := len(workloads.Items) > 0; gotWorkload != tc.wantWorkload {
+				t.Errorf("Workload created: %t, want %t", gotWorkload, tc.wantWorkload)
+			}
+			close(recorder.Events)
+			var gotEvents []string
+			for e := range recorder.Events {
+				gotEvents = append(gotEvents, e)
+			}
+			if diff := cmp.Diff(tc.wantEvents, gotEvents); diff != "" {
+				t.Errorf("Unexpected events (-want,+got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestDelayedRunHandler(t *testing.T) {
+	lq := utiltesting.MakeLocalQueue("lq", "ns").ClusterQueue("cq").PendingWorkloads(5).Obj()
+	drainedLq := utiltesting.MakeLocalQueue("lq", "ns").ClusterQueue("cq").PendingWorkloads(4).Obj()
+	cq := utiltesting.MakeClusterQueue("cq").Obj()
+	usedCq := utiltesting.MakeClusterQueue("cq").Obj()
+	used := resource.MustParse("1")
+	usedCq.Status.UsedResources = kueue.UsedResources{
+		corev1.ResourceCPU: {"default": kueue.Usage{Total: &used}},
+	}
+	otherCq := utiltesting.MakeClusterQueue("other").Obj()
+	otherUsedCq := utiltesting.MakeClusterQueue("other").Obj()
+	otherUsedCq.Status.UsedResources = usedCq.Status.UsedResources
+	cases := map[string]struct {
+		oldObj   client.Object
+		newObj   client.Object
+		wantJobs []string
+	}{
+		"the LocalQueue drained": {
+			oldObj:   lq,
+			newObj:   drainedLq,
+			wantJobs: []string{"delayed"},
+		},
+		"the LocalQueue got more pending workloads": {
+			oldObj: drainedLq,
+			newObj: lq,
+		},
+		"the usage of the ClusterQueue changed": {
+			oldObj:   usedCq,
+			newObj:   cq,
+			wantJobs: []string{"delayed"},
+		},
+		"the ClusterQueue didn't change": {
+			oldObj: cq,
+			newObj: cq,
+		},
+		"the usage of another ClusterQueue changed": {
+			oldObj: otherUsedCq,
+			newObj: otherCq,
+		},
+	}
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			scheme := utiltesting.MustGetScheme(t)
+			if err := batchv1.AddToScheme(scheme); err != nil {
+				t.Fatalf("Failed adding batch scheme: %v", err)
+			}
+			cl := fake.NewClientBuilder().WithScheme(scheme).WithObjects(
+				lq,
+				utiltesting.MakeJob("delayed", "ns").Queue("lq").Annotation(constants.CronJobDelayedRunAnnotation, "true").Obj(),
+				utiltesting.MakeJob("running", "ns").Queue("lq").Obj(),
+				utiltesting.MakeJob("other-queue", "ns").Queue("other").Annotation(constants.CronJobDelayedRunAnnotation, "true").Obj(),
+				utiltesting.MakeJob("other-ns", "other").Queue("lq").Annotation(constants.CronJobDelayedRunAnnotation, "true").Obj(),
+			).Build()
+			q := workqueue.NewRateLimitingQueue(workqueue.DefaultControllerRateLimiter())
+			defer q.ShutDown()
+			h := &delayedRunHandler{client: cl}
+
+			h.Update(event.UpdateEvent{ObjectOld: tc.oldObj, ObjectNew: tc.newObj}, q)
+			var gotJobs []string
+			for q.Len() > 0 {
+				item, _ := q.Get()
+				req := item.(reconcile.Request)
+				if req.Namespace != "ns" {
+					t.Errorf("Got request for a job in namespace %q", req.Namespace)
+				}
+				gotJobs = append(gotJobs, req.Name)
+				q.Done(item)
+			}
+			if diff := cmp.Diff(tc.wantJobs, gotJobs); diff != "" {
+				t.Errorf("Unexpected enqueued jobs (-want,+got):\n%s", diff)
+			}
+		})
+	}
+}
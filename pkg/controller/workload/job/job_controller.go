@@ -18,45 +18,53 @@ package job
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
+	"hash/fnv"
+	"strconv"
+	"time"
 
 	batchv1 "k8s.io/api/batch/v1"
 	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/equality"
-	apierrors "k8s.io/apimachinery/pkg/api/errors"
-	apimeta "k8s.io/apimachinery/pkg/api/meta"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
-	"k8s.io/apimachinery/pkg/types"
-	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/apimachinery/pkg/util/rand"
 	"k8s.io/client-go/tools/record"
-	"k8s.io/klog/v2"
 	"k8s.io/utils/pointer"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/source"
 
+	config "sigs.k8s.io/kueue/apis/config/v1alpha2"
 	kueue "sigs.k8s.io/kueue/apis/kueue/v1alpha2"
 	"sigs.k8s.io/kueue/pkg/constants"
-	utilpriority "sigs.k8s.io/kueue/pkg/util/priority"
-	"sigs.k8s.io/kueue/pkg/workload"
+	"sigs.k8s.io/kueue/pkg/controller/workload/jobframework"
 )
 
-var (
-	ownerKey = ".metadata.controller"
-)
+// batchIntegration runs the batch/v1 Jobs through the JobReconciler of the
+// job framework. It isn't registered, as the Jobs are always managed.
+var batchIntegration = &jobframework.Integration{
+	Name:   "batch/job",
+	GVK:    batchv1.SchemeGroupVersion.WithKind("Job"),
+	NewJob: func() jobframework.GenericJob { return &BatchJob{} },
+}
 
 // JobReconciler reconciles a Job object
 type JobReconciler struct {
+	*jobframework.JobReconciler
 	client                     client.Client
-	scheme                     *runtime.Scheme
-	record                     record.EventRecorder
 	manageJobsWithoutQueueName bool
-	waitForPodsReady           bool
+	suspendCheckPeriod         time.Duration
 }
 
 type options struct {
 	manageJobsWithoutQueueName bool
 	waitForPodsReady           bool
+	reportSuspendMismatchOnly  bool
+	suspendCheckPeriod         time.Duration
+	prioritySources            []config.PrioritySource
 }
 
 // Option configures the reconciler.
@@ -79,7 +87,42 @@ func WithWaitForPodsReady(f bool) Option {
 	}
 }
 
-var defaultOptions = options{}
+// WithReportSuspendMismatchOnly indicates if the controller should only
+// record a warning event, instead of suspending, for running jobs whose
+// workload is not admitted.
+func WithReportSuspendMismatchOnly(f bool) Option {
+	return func(o *options) {
+		o.reportSuspendMismatchOnly = f
+	}
+}
+
+// WithSuspendCheckPeriod sets the period of a loop that checks the suspend
+// state of all the running jobs. The loop is disabled if the period is zero.
+func WithSuspendCheckPeriod(d time.Duration) Option {
+	return func(o *options) {
+		o.suspendCheckPeriod = d
+	}
+}
+
+// WithPrioritySources sets the sources, in order of precedence, from which
+// the PriorityClass of the workloads is taken.
+func WithPrioritySources(sources []config.PrioritySource) Option {
+	return func(o *options) {
+		o.prioritySources = sources
+	}
+}
+
+// defaultPrioritySources are the priority sources of the default
+// configuration.
+var defaultPrioritySources = func() []config.PrioritySource {
+	var cfg config.Configuration
+	config.SetDefaults_Configuration(&cfg)
+	return cfg.PrioritySources
+}()
+
+var defaultOptions = options{
+	prioritySources: defaultPrioritySources,
+}
 
 func NewReconciler(
 	scheme *runtime.Scheme,
@@ -93,162 +136,77 @@ func NewReconciler(
 	}
 
 	return &JobReconciler{
-		scheme:                     scheme,
+		JobReconciler: jobframework.NewReconciler(scheme, client, record, batchIntegration,
+			jobframework.WithManageJobsWithoutQueueName(options.manageJobsWithoutQueueName),
+			jobframework.WithWaitForPodsReady(options.waitForPodsReady),
+			jobframework.WithReportSuspendMismatchOnly(options.reportSuspendMismatchOnly),
+			jobframework.WithPrioritySources(options.prioritySources),
+		),
 		client:                     client,
-		record:                     record,
 		manageJobsWithoutQueueName: options.manageJobsWithoutQueueName,
-		waitForPodsReady:           options.waitForPodsReady,
+		suspendCheckPeriod:         options.suspendCheckPeriod,
 	}
 }
 
 // SetupWithManager sets up the controller with the Manager. It indexes workloads
-// based on the owning jobs.
+// based on the owning jobs. It also watches the queues, to reconcile the
+// delayed Jobs of CronJobs when their queues drain.
+// If the suspend check period is set, it also adds the loop that periodically
+// checks the suspend state of the running jobs.
 func (r *JobReconciler) SetupWithManager(mgr ctrl.Manager) error {
-	return ctrl.NewControllerManagedBy(mgr).
+	delayedRuns := &delayedRunHandler{client: r.client}
+	b := ctrl.NewControllerManagedBy(mgr).
 		For(&batchv1.Job{}).
 		Owns(&kueue.Workload{}).
-		Complete(r)
+		Watches(&source.Kind{Type: &kueue.LocalQueue{}}, delayedRuns).
+		Watches(&source.Kind{Type: &kueue.ClusterQueue{}}, delayedRuns)
+	if r.suspendCheckPeriod > 0 {
+		checker := newSuspendStateChecker(r.client, r.suspendCheckPeriod, r.manageJobsWithoutQueueName)
+		if err := mgr.Add(checker); err != nil {
+			return err
+		}
+		b = b.Watches(&source.Channel{Source: checker.ch}, &handler.EnqueueRequestForObject{})
+	}
+	return b.Complete(r)
 }
 
+// SetupIndexes indexes the workloads based on the owning Jobs.
 func SetupIndexes(indexer client.FieldIndexer) error {
-	return indexer.IndexField(context.Background(), &kueue.Workload{}, ownerKey, func(o client.Object) []string {
-		// grab the Workload object, extract the owner...
-		wl := o.(*kueue.Workload)
-		owner := metav1.GetControllerOf(wl)
-		if owner == nil {
-			return nil
-		}
-		// ...make sure it's a Job...
-		if owner.APIVersion != "batch/v1" || owner.Kind != "Job" {
-			return nil
-		}
-		// ...and if so, return it
-		return []string{owner.Name}
-	})
+	return jobframework.SetupIndexes(indexer, []*jobframework.Integration{batchIntegration})
 }
 
 //+kubebuilder:rbac:groups=scheduling.k8s.io,resources=priorityclasses,verbs=list;get;watch
 //+kubebuilder:rbac:groups="",resources=events,verbs=create;watch;update
-//+kubebuilder:rbac:groups=batch,resources=jobs,verbs=get;list;watch;update;patch
+//+kubebuilder:rbac:groups=batch,resources=jobs,verbs=get;list;watch;update;patch;delete
+//+kubebuilder:rbac:groups=batch,resources=cronjobs,verbs=get;list;watch
 //+kubebuilder:rbac:groups=batch,resources=jobs/status,verbs=get
 //+kubebuilder:rbac:groups=batch,resources=jobs/finalizers,verbs=get;update;patch
 //+kubebuilder:rbac:groups=kueue.x-k8s.io,resources=workloads,verbs=get;list;watch;create;update;patch;delete
+//+kubebuilder:rbac:groups=kueue.x-k8s.io,resources=workloadpriorityclasses,verbs=get;list;watch
 //+kubebuilder:rbac:groups=kueue.x-k8s.io,resources=workloads/status,verbs=get;update;patch
 //+kubebuilder:rbac:groups=kueue.x-k8s.io,resources=workloads/finalizers,verbs=update
 //+kubebuilder:rbac:groups=kueue.x-k8s.io,resources=resourceflavors,verbs=get;list;watch
-
-func (r *JobReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
-	var job batchv1.Job
-	if err := r.client.Get(ctx, req.NamespacedName, &job); err != nil {
-		// we'll ignore not-found errors, since there is nothing to do.
-		return ctrl.Result{}, client.IgnoreNotFound(err)
-	}
-
-	log := ctrl.LoggerFrom(ctx).WithValues("job", klog.KObj(&job))
-	ctx = ctrl.LoggerInto(ctx, log)
-	if queueName(&job) == "" && !r.manageJobsWithoutQueueName {
-		log.V(3).Info(fmt.Sprintf("%s annotation is not set, ignoring the job", constants.QueueAnnotation))
-		return ctrl.Result{}, nil
-	}
-
-	log.V(2).Info("Reconciling Job")
-
-	var childWorkloads kueue.WorkloadList
-	if err := r.client.List(ctx, &childWorkloads, client.InNamespace(req.Namespace),
-		client.MatchingFields{ownerKey: req.Name}); err != nil {
-		log.Error(err, "Unable to list child workloads")
-		return ctrl.Result{}, err
-	}
-
-	// 1. make sure there is only a single existing instance of the workload
-	wl, err := r.ensureAtMostOneWorkload(ctx, &job, childWorkloads)
-	if err != nil {
-		log.Error(err, "Getting existing workloads")
-		return ctrl.Result{}, err
-	}
-
-	jobFinishedCond, jobFinished := jobFinishedCondition(&job)
-	// 2. create new workload if none exists
-	if wl == nil {
-		// Nothing to do if the job is finished
-		if jobFinished {
-			return ctrl.Result{}, nil
-		}
-		err := r.handleJobWithNoWorkload(ctx, &job)
-		if err != nil {
-			log.Error(err, "Handling job with no workload")
-		}
-		return ctrl.Result{}, err
-	}
-
-	// 3. handle a finished job
-	if jobFinished {
-		if apimeta.IsStatusConditionTrue(wl.Status.Conditions, kueue.WorkloadFinished) {
-			return ctrl.Result{}, nil
-		}
-		condition := generateFinishedCondition(jobFinishedCond)
-		apimeta.SetStatusCondition(&wl.Status.Conditions, condition)
-		err := r.client.Status().Update(ctx, wl)
-		if err != nil {
-			log.Error(err, "Updating workload status")
-		}
-		return ctrl.Result{}, err
+//+kubebuilder:rbac:groups=kueue.x-k8s.io,resources=localqueues,verbs=get;list;watch
+//+kubebuilder:rbac:groups=kueue.x-k8s.io,resources=clusterqueues,verbs=get;list;watch
+
+// reclaimablePods returns the number of pods of the job whose quota is no
+// longer needed, because fewer pods than the parallelism remain to succeed.
+func reclaimablePods(job *batchv1.Job, wl *kueue.Workload) []kueue.ReclaimablePod {
+	parallelism := pointer.Int32Deref(job.Spec.Parallelism, 1)
+	if parallelism <= 1 || job.Status.Succeeded == 0 || len(wl.Spec.PodSets) != 1 {
+		return nil
 	}
-
-	// handle a job when waitForPodsReady is enabled
-	if r.waitForPodsReady {
-		log.V(5).Info("Handling a job when waitForPodsReady is enabled")
-		condition := generatePodsReadyCondition(&job, wl)
-		// optimization to avoid sending the update request if the status didn't change
-		if !apimeta.IsStatusConditionPresentAndEqual(wl.Status.Conditions, condition.Type, condition.Status) {
-			log.V(3).Info(fmt.Sprintf("Updating the PodsReady condition with status: %v", condition.Status))
-			apimeta.SetStatusCondition(&wl.Status.Conditions, condition)
-			if err := r.client.Status().Update(ctx, wl); err != nil {
-				log.Error(err, "Updating workload status")
-			}
-		}
+	remaining := pointer.Int32Deref(job.Spec.Completions, parallelism) - job.Status.Succeeded
+	if remaining >= parallelism {
+		return nil
 	}
-
-	// 4. Handle a not finished job
-	if jobSuspended(&job) {
-		// start the job if the workload has been admitted, and the job is still suspended
-		if wl.Spec.Admission != nil {
-			log.V(2).Info("Job admitted, unsuspending")
-			err := r.startJob(ctx, wl, &job)
-			if err != nil {
-				log.Error(err, "Unsuspending job")
-			}
-			return ctrl.Result{}, err
-		}
-
-		// update queue name if changed.
-		q := queueName(&job)
-		if wl.Spec.QueueName != q {
-			log.V(2).Info("Job changed queues, updating workload")
-			wl.Spec.QueueName = q
-			err := r.client.Update(ctx, wl)
-			if err != nil {
-				log.Error(err, "Updating workload queue")
-			}
-			return ctrl.Result{}, err
-		}
-		log.V(3).Info("Job is suspended and workload not yet admitted by a clusterQueue, nothing to do")
-		return ctrl.Result{}, nil
+	if remaining < 0 {
+		remaining = 0
 	}
-
-	if wl.Spec.Admission == nil {
-		// the job must be suspended if the workload is not yet admitted.
-		log.V(2).Info("Running job is not admitted by a cluster queue, suspending")
-		err := r.stopJob(ctx, wl, &job, "Not admitted by cluster queue")
-		if err != nil {
-			log.Error(err, "Suspending job with non admitted workload")
-		}
-		return ctrl.Result{}, err
-	}
-
-	// workload is admitted and job is running, nothing to do.
-	log.V(3).Info("Job running with admitted workload, nothing to do")
-	return ctrl.Result{}, nil
+	return []kueue.ReclaimablePod{{
+		Name:  wl.Spec.PodSets[0].Name,
+		Count: parallelism - remaining,
+	}}
 }
 
 // podsReady checks if all pods are ready or succeeded
@@ -257,212 +215,58 @@ func podsReady(job *batchv1.Job) bool {
 	return job.Status.Succeeded+ready >= podsCount(&job.Spec)
 }
 
-// stopJob sends updates to suspend the job, reset the startTime so we can update the scheduling directives
-// later when unsuspending and resets the nodeSelector to its previous state based on what is available in
-// the workload (which should include the original affinities that the job had).
-func (r *JobReconciler) stopJob(ctx context.Context, w *kueue.Workload,
-	job *batchv1.Job, eventMsg string) error {
-	job.Spec.Suspend = pointer.BoolPtr(true)
-	if err := r.client.Update(ctx, job); err != nil {
-		return err
-	}
-	r.record.Eventf(job, corev1.EventTypeNormal, "Stopped", eventMsg)
-
-	// Reset start time so we can update the scheduling directives later when unsuspending.
-	if job.Status.StartTime != nil {
-		job.Status.StartTime = nil
-		if err := r.client.Status().Update(ctx, job); err != nil {
-			return err
-		}
-	}
-
-	if w != nil && !equality.Semantic.DeepEqual(job.Spec.Template.Spec.NodeSelector,
-		w.Spec.PodSets[0].Spec.NodeSelector) {
-		job.Spec.Template.Spec.NodeSelector = map[string]string{}
-		for k, v := range w.Spec.PodSets[0].Spec.NodeSelector {
-			job.Spec.Template.Spec.NodeSelector[k] = v
-		}
-		return r.client.Update(ctx, job)
-	}
-
-	return nil
-}
-
-func (r *JobReconciler) startJob(ctx context.Context, w *kueue.Workload, job *batchv1.Job) error {
-	log := ctrl.LoggerFrom(ctx)
-
-	if len(w.Spec.PodSets) != 1 {
-		return fmt.Errorf("one podset must exist, found %d", len(w.Spec.PodSets))
-	}
-	nodeSelector, err := r.getNodeSelectors(ctx, w)
-	if err != nil {
-		return err
-	}
-	if len(nodeSelector) != 0 {
-		if job.Spec.Template.Spec.NodeSelector == nil {
-			job.Spec.Template.Spec.NodeSelector = nodeSelector
-		} else {
-			for k, v := range nodeSelector {
-				job.Spec.Template.Spec.NodeSelector[k] = v
-			}
-		}
-
-	} else {
-		log.V(3).Info("no nodeSelectors to inject")
-	}
-
-	job.Spec.Suspend = pointer.BoolPtr(false)
-	if err := r.client.Update(ctx, job); err != nil {
-		return err
-	}
-
-	r.record.Eventf(job, corev1.EventTypeNormal, "Started",
-		"Admitted by clusterQueue %v", w.Spec.Admission.ClusterQueue)
-	return nil
-}
-
-func (r *JobReconciler) getNodeSelectors(ctx context.Context, w *kueue.Workload) (map[string]string, error) {
-	if len(w.Spec.Admission.PodSetFlavors[0].Flavors) == 0 {
-		return nil, nil
-	}
-
-	processedFlvs := sets.NewString()
-	nodeSelector := map[string]string{}
-	for _, flvName := range w.Spec.Admission.PodSetFlavors[0].Flavors {
-		if processedFlvs.Has(flvName) {
-			continue
-		}
-		// Lookup the ResourceFlavors to fetch the node affinity labels to apply on the job.
-		flv := kueue.ResourceFlavor{}
-		if err := r.client.Get(ctx, types.NamespacedName{Name: flvName}, &flv); err != nil {
-			return nil, err
-		}
-		for k, v := range flv.NodeSelector {
-			nodeSelector[k] = v
-		}
-		processedFlvs.Insert(flvName)
-	}
-	return nodeSelector, nil
-}
-
-func (r *JobReconciler) handleJobWithNoWorkload(ctx context.Context, job *batchv1.Job) error {
-	log := ctrl.LoggerFrom(ctx)
-
-	// Wait until there are no active pods.
-	if job.Status.Active != 0 {
-		log.V(2).Info("Job is suspended but still has active pods, waiting")
-		return nil
-	}
-
-	// Create the corresponding workload.
-	wl, err := ConstructWorkloadFor(ctx, r.client, job, r.scheme)
-	if err != nil {
-		return err
-	}
-	if err = r.client.Create(ctx, wl); err != nil {
-		return err
+// ConstructWorkloadFor returns the workload for the job, taking its
+// PriorityClass from the first of the prioritySources that provides one.
+// If prioritySources is nil, the default sources are used.
+func ConstructWorkloadFor(ctx context.Context, client client.Client,
+	job *batchv1.Job, scheme *runtime.Scheme, prioritySources []config.PrioritySource) (*kueue.Workload, error) {
+	if prioritySources == nil {
+		prioritySources = defaultPrioritySources
 	}
-
-	r.record.Eventf(job, corev1.EventTypeNormal, "CreatedWorkload",
-		"Created Workload: %v", workload.Key(wl))
-	return nil
+	return jobframework.ConstructWorkloadWithPrioritySources(ctx, client, (*BatchJob)(job), batchIntegration.GVK, scheme, prioritySources)
 }
 
-// ensureAtMostOneWorkload finds a matching workload and deletes redundant ones.
-func (r *JobReconciler) ensureAtMostOneWorkload(ctx context.Context, job *batchv1.Job, workloads kueue.WorkloadList) (*kueue.Workload, error) {
-	log := ctrl.LoggerFrom(ctx)
-
-	// Find a matching workload first if there is one.
-	var toDelete []*kueue.Workload
-	var match *kueue.Workload
-	for i := range workloads.Items {
-		w := &workloads.Items[i]
-		owner := metav1.GetControllerOf(w)
-		// Indexes don't work in unit tests, so we explicitly check for the
-		// owner here.
-		if owner.Name != job.Name {
-			continue
-		}
-		if match == nil && jobAndWorkloadEqual(job, w) {
-			match = w
-		} else {
-			toDelete = append(toDelete, w)
-		}
-	}
-
-	// If there is no matching workload and the job is running, suspend it.
-	if match == nil && !jobSuspended(job) {
-		log.V(2).Info("job with no matching workload, suspending")
-		var w *kueue.Workload
-		if len(workloads.Items) == 1 {
-			// The job may have been modified and hence the existing workload
-			// doesn't match the job anymore. All bets are off if there are more
-			// than one workload...
-			w = &workloads.Items[0]
-		}
-		if err := r.stopJob(ctx, w, job, "No matching Workload"); err != nil {
-			log.Error(err, "stopping job")
+// deadlineFromAnnotations returns the deadline and expected duration of the
+// workload for the job, as set in the job annotations.
+func deadlineFromAnnotations(job *batchv1.Job) (*metav1.Time, *metav1.Duration, error) {
+	var deadline *metav1.Time
+	var expectedDuration *metav1.Duration
+	if v, ok := job.Annotations[constants.DeadlineAnnotation]; ok {
+		t, err := time.Parse(time.RFC3339, v)
+		if err != nil {
+			return nil, nil, fmt.Errorf("parsing annotation %s: %w", constants.DeadlineAnnotation, err)
 		}
+		deadline = &metav1.Time{Time: t}
 	}
-
-	// Delete duplicate workload instances.
-	existedWls := 0
-	for i := range toDelete {
-		err := r.client.Delete(ctx, toDelete[i])
-		if err == nil || !apierrors.IsNotFound(err) {
-			existedWls++
-		}
-		if err != nil && !apierrors.IsNotFound(err) {
-			log.Error(err, "Failed to delete workload")
-		}
-		if err == nil {
-			r.record.Eventf(job, corev1.EventTypeNormal, "DeletedWorkload",
-				"Deleted not matching Workload: %v", workload.Key(toDelete[i]))
+	if v, ok := job.Annotations[constants.ExpectedDurationAnnotation]; ok {
+		d, err := time.ParseDuration(v)
+		if err != nil {
+			return nil, nil, fmt.Errorf("parsing annotation %s: %w", constants.ExpectedDurationAnnotation, err)
 		}
-	}
-
-	if existedWls != 0 {
-		if match == nil {
-			return nil, fmt.Errorf("no matching workload was found, tried deleting %d existing workload(s)", existedWls)
+		if d < 0 {
+			return nil, nil, fmt.Errorf("annotation %s must not be negative", constants.ExpectedDurationAnnotation)
 		}
-		return nil, fmt.Errorf("only one workload should exist, found %d", len(workloads.Items))
+		expectedDuration = &metav1.Duration{Duration: d}
 	}
-
-	return match, nil
+	return deadline, expectedDuration, nil
 }
 
-func ConstructWorkloadFor(ctx context.Context, client client.Client,
-	job *batchv1.Job, scheme *runtime.Scheme) (*kueue.Workload, error) {
-	w := &kueue.Workload{
-		ObjectMeta: metav1.ObjectMeta{
-			Name:      job.Name,
-			Namespace: job.Namespace,
-		},
-		Spec: kueue.WorkloadSpec{
-			PodSets: []kueue.PodSet{
-				{
-					Spec:  *job.Spec.Template.Spec.DeepCopy(),
-					Count: podsCount(&job.Spec),
-				},
-			},
-			QueueName: queueName(job),
-		},
+// minCountFromAnnotations returns the minimum number of pods with which the
+// workload for the job can be partially admitted, as set in the job
+// annotations.
+func minCountFromAnnotations(job *batchv1.Job) (*int32, error) {
+	v, ok := job.Annotations[constants.JobMinParallelismAnnotation]
+	if !ok {
+		return nil, nil
 	}
-
-	// Populate priority from priority class.
-	priorityClassName, p, err := utilpriority.GetPriorityFromPriorityClass(
-		ctx, client, job.Spec.Template.Spec.PriorityClassName)
+	minCount, err := strconv.ParseInt(v, 10, 32)
 	if err != nil {
-		return nil, err
+		return nil, fmt.Errorf("parsing annotation %s: %w", constants.JobMinParallelismAnnotation, err)
 	}
-	w.Spec.Priority = &p
-	w.Spec.PriorityClassName = priorityClassName
-
-	if err := ctrl.SetControllerReference(job, w, scheme); err != nil {
-		return nil, err
+	if minCount < 1 || int32(minCount) > podsCount(&job.Spec) {
+		return nil, fmt.Errorf("annotation %s must be between 1 and the number of pods of the job", constants.JobMinParallelismAnnotation)
 	}
-
-	return w, nil
+	return pointer.Int32(int32(minCount)), nil
 }
 
 func podsCount(jobSpec *batchv1.JobSpec) int32 {
@@ -474,21 +278,6 @@ func podsCount(jobSpec *batchv1.JobSpec) int32 {
 	return podsCount
 }
 
-func generatePodsReadyCondition(job *batchv1.Job, wl *kueue.Workload) metav1.Condition {
-	conditionStatus := metav1.ConditionFalse
-	message := "Not all pods are ready or succeeded"
-	if podsReady(job) && wl.Spec.Admission != nil {
-		conditionStatus = metav1.ConditionTrue
-		message = "All pods are ready or succeeded"
-	}
-	return metav1.Condition{
-		Type:    kueue.WorkloadPodsReady,
-		Status:  conditionStatus,
-		Reason:  "PodsReady",
-		Message: message,
-	}
-}
-
 func generateFinishedCondition(jobStatus batchv1.JobConditionType) metav1.Condition {
 	message := "Job finished successfully"
 	if jobStatus == batchv1.JobFailed {
@@ -497,7 +286,7 @@ func generateFinishedCondition(jobStatus batchv1.JobConditionType) metav1.Condit
 	return metav1.Condition{
 		Type:    kueue.WorkloadFinished,
 		Status:  metav1.ConditionTrue,
-		Reason:  "JobFinished",
+		Reason:  kueue.WorkloadReasonJobFinished,
 		Message: message,
 	}
 }
@@ -517,13 +306,35 @@ func jobSuspended(j *batchv1.Job) bool {
 }
 
 func jobAndWorkloadEqual(job *batchv1.Job, wl *kueue.Workload) bool {
+	return jobAndWorkloadCountsEqual(job, wl) && jobAndWorkloadTemplatesEqual(job, wl)
+}
+
+func jobAndWorkloadCountsEqual(job *batchv1.Job, wl *kueue.Workload) bool {
 	if len(wl.Spec.PodSets) != 1 {
 		return false
 	}
-	if *job.Spec.Parallelism != wl.Spec.PodSets[0].Count {
-		return false
+	if ps := &wl.Spec.PodSets[0]; *job.Spec.Parallelism != ps.Count {
+		// A running job might have a parallelism reduced by a partial admission.
+		if jobSuspended(job) || ps.MinCount == nil || *job.Spec.Parallelism < *ps.MinCount || *job.Spec.Parallelism > ps.Count {
+			return false
+		}
+	}
+	return true
+}
+
+func jobAndWorkloadTemplatesEqual(job *batchv1.Job, wl *kueue.Workload) bool {
+	if hash, ok := wl.Annotations[constants.PodTemplateHashAnnotation]; ok {
+		template := &job.Spec.Template
+		if !jobSuspended(job) && splitAdmission(wl) {
+			// The node affinity of the running job has the terms injected for
+			// the parts of the split pod set.
+			template = template.DeepCopy()
+			template.Spec.Affinity = wl.Spec.PodSets[0].Spec.Affinity
+		}
+		return hash == podTemplateHash(template)
 	}
 
+	// The workload was created without a hash.
 	// nodeSelector may change, hence we are not checking for
 	// equality of the whole job.Spec.Template.Spec.
 	if !equality.Semantic.DeepEqual(job.Spec.Template.Spec.InitContainers,
@@ -534,6 +345,55 @@ func jobAndWorkloadEqual(job *batchv1.Job, wl *kueue.Workload) bool {
 		wl.Spec.PodSets[0].Spec.Containers)
 }
 
+// podTemplateHash returns a hash of the pod template, leaving out the
+// nodeSelector and the tolerations, which are updated when the job starts.
+func podTemplateHash(template *corev1.PodTemplateSpec) string {
+	t := template.DeepCopy()
+	t.Spec.NodeSelector = nil
+	t.Spec.Tolerations = nil
+	// Marshalling a PodTemplateSpec doesn't fail.
+	data, _ := json.Marshal(t)
+	hasher := fnv.New32a()
+	hasher.Write(data)
+	return rand.SafeEncodeString(fmt.Sprint(hasher.Sum32()))
+}
+
+// splitAdmission returns whether the workload is admitted with its pod set
+// split between flavors.
+func splitAdmission(wl *kueue.Workload) bool {
+	return wl.Status.Admission != nil && len(wl.Status.Admission.PodSetFlavors) > 0 &&
+		len(wl.Status.Admission.PodSetFlavors[0].Splits) > 0
+}
+
+// withRequiredNodeAffinity returns a copy of the affinity that also requires
+// the nodes to match one of the terms of the node selector. As the terms of a
+// node selector are ORed, each of them is combined with each of the existing
+// terms.
+func withRequiredNodeAffinity(affinity *corev1.Affinity, selector *corev1.NodeSelector) *corev1.Affinity {
+	affinity = affinity.DeepCopy()
+	if affinity == nil {
+		affinity = &corev1.Affinity{}
+	}
+	if affinity.NodeAffinity == nil {
+		affinity.NodeAffinity = &corev1.NodeAffinity{}
+	}
+	required := affinity.NodeAffinity.RequiredDuringSchedulingIgnoredDuringExecution
+	if required == nil || len(required.NodeSelectorTerms) == 0 {
+		affinity.NodeAffinity.RequiredDuringSchedulingIgnoredDuringExecution = selector.DeepCopy()
+		return affinity
+	}
+	terms := make([]corev1.NodeSelectorTerm, 0, len(required.NodeSelectorTerms)*len(selector.NodeSelectorTerms))
+	for _, existing := range required.NodeSelectorTerms {
+		for _, term := range selector.NodeSelectorTerms {
+			combined := existing.DeepCopy()
+			combined.MatchExpressions = append(combined.MatchExpressions, term.MatchExpressions...)
+			terms = append(terms, *combined)
+		}
+	}
+	required.NodeSelectorTerms = terms
+	return affinity
+}
+
 func queueName(job *batchv1.Job) string {
 	return job.Annotations[constants.QueueAnnotation]
 }
@@ -17,11 +17,28 @@ limitations under the License.
 package job
 
 import (
+	"context"
 	"testing"
+	"time"
 
+	"github.com/google/go-cmp/cmp"
 	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	schedulingv1 "k8s.io/api/scheduling/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	apimeta "k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/tools/record"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
 
+	config "sigs.k8s.io/kueue/apis/config/v1alpha2"
+	kueue "sigs.k8s.io/kueue/apis/kueue/v1alpha2"
+	"sigs.k8s.io/kueue/pkg/constants"
 	"sigs.k8s.io/kueue/pkg/util/pointer"
+	utiltesting "sigs.k8s.io/kueue/pkg/util/testing"
 )
 
 func TestPodsReady(t *testing.T) {
@@ -147,3 +164,604 @@ func TestPodsReady(t *testing.T) {
 		})
 	}
 }
+
+func TestConstructWorkloadForPrioritySources(t *testing.T) {
+	queue := utiltesting.MakeLocalQueue("main", "ns").PriorityClass("queue-default").Obj()
+	cases := map[string]struct {
+		job        *batchv1.Job
+		sources    []config.PrioritySource
+		wantName   string
+		wantSource string
+	}{
+		"label has precedence": {
+			job: utiltesting.MakeJob("job", "ns").Queue("main").
+				Label(constants.PriorityClassLabel, "from-label").
+				PriorityClass("from-pod").Obj(),
+			sources:    defaultPrioritySources,
+			wantName:   "from-label",
+			wantSource: "PriorityClassLabel",
+		},
+		"pod priority class": {
+			job:        utiltesting.MakeJob("job", "ns").Queue("main").PriorityClass("from-pod").Obj(),
+			sources:    defaultPrioritySources,
+			wantName:   "from-pod",
+			wantSource: "PodPriorityClass",
+		},
+		"localQueue default": {
+			job:        utiltesting.MakeJob("job", "ns").Queue("main").Obj(),
+			sources:    defaultPrioritySources,
+			wantName:   "queue-default",
+			wantSource: "LocalQueue",
+		},
+		"localQueue not found": {
+			job:        utiltesting.MakeJob("job", "ns").Queue("other").Obj(),
+			sources:    defaultPrioritySources,
+			wantSource: "Default",
+		},
+		"custom order": {
+			job: utiltesting.MakeJob("job", "ns").Queue("main").
+				Label(constants.PriorityClassLabel, "from-label").
+				PriorityClass("from-pod").Obj(),
+			sources:    []config.PrioritySource{config.PrioritySourceLocalQueue, config.PrioritySourcePodPriorityClass},
+			wantName:   "queue-default",
+			wantSource: "LocalQueue",
+		},
+		"source not configured": {
+			job: utiltesting.MakeJob("job", "ns").Queue("main").
+				Label(constants.PriorityClassLabel, "from-label").Obj(),
+			sources:    []config.PrioritySource{config.PrioritySourcePodPriorityClass},
+			wantSource: "Default",
+		},
+	}
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			scheme := utiltesting.MustGetScheme(t)
+			if err := batchv1.AddToScheme(scheme); err != nil {
+				t.Fatalf("Failed adding batch scheme: %v", err)
+			}
+			if err := schedulingv1.AddToScheme(scheme); err != nil {
+				t.Fatalf("Failed adding scheduling scheme: %v", err)
+			}
+			cl := fake.NewClientBuilder().WithScheme(scheme).WithObjects(
+				queue,
+				utiltesting.MakePriorityClass("from-label").PriorityValue(300).Obj(),
+				utiltesting.MakePriorityClass("from-pod").PriorityValue(200).Obj(),
+				utiltesting.MakePriorityClass("queue-default").PriorityValue(100).Obj(),
+			).Build()
+			wl, err := ConstructWorkloadFor(context.Background(), cl, tc.job, scheme, tc.sources)
+			if err != nil {
+				t.Fatalf("Failed constructing the workload: %v", err)
+			}
+			if wl.Spec.PriorityClassName != tc.wantName || wl.Status.PrioritySource != tc.wantSource {
+				t.Errorf("Unexpected priority class (%q, %q), want (%q, %q)", wl.Spec.PriorityClassName, wl.Status.PrioritySource, tc.wantName, tc.wantSource)
+			}
+		})
+	}
+}
+
+func TestConstructWorkloadForWorkloadPriorityClass(t *testing.T) {
+	scheme := runtime.NewScheme()
+	if err := kueue.AddToScheme(scheme); err != nil {
+		t.Fatalf("Failed adding kueue scheme: %v", err)
+	}
+	if err := batchv1.AddToScheme(scheme); err != nil {
+		t.Fatalf("Failed adding batch scheme: %v", err)
+	}
+	cl := fake.NewClientBuilder().WithScheme(scheme).
+		WithObjects(utiltesting.MakeWorkloadPriorityClass("high").PriorityValue(1000).Obj()).
+		Build()
+	job := utiltesting.MakeJob("job", "ns").Queue("main").
+		Label(constants.WorkloadPriorityClassLabel, "high").
+		PriorityClass("from-pod").Obj()
+
+	wl, err := ConstructWorkloadFor(context.Background(), cl, job, scheme, nil)
+	if err != nil {
+		t.Fatalf("Failed constructing the workload: %v", err)
+	}
+	if wl.Spec.PriorityClassName != "high" || wl.Spec.PriorityClassSource != kueue.WorkloadPriorityClassSource {
+		t.Errorf("Unexpected priority class (%q, %q), want (%q, %q)", wl.Spec.PriorityClassName, wl.Spec.PriorityClassSource, "high", kueue.WorkloadPriorityClassSource)
+	}
+	if wl.Spec.Priority == nil || *wl.Spec.Priority != 1000 {
+		t.Errorf("Unexpected priority %v, want 1000", wl.Spec.Priority)
+	}
+	if wl.Status.PrioritySource != "WorkloadPriorityClassLabel" {
+		t.Errorf("Unexpected priority source %q, want %q", wl.Status.PrioritySource, "WorkloadPriorityClassLabel")
+	}
+}
+
+func TestReconcileSetsMissingPrioritySource(t *testing.T) {
+	scheme := utiltesting.MustGetScheme(t)
+	if err := batchv1.AddToScheme(scheme); err != nil {
+		t.Fatalf("Failed adding batch scheme: %v", err)
+	}
+	if err := schedulingv1.AddToScheme(scheme); err != nil {
+		t.Fatalf("Failed adding scheduling scheme: %v", err)
+	}
+	ctx := context.Background()
+	queue := utiltesting.MakeLocalQueue("main", "ns").PriorityClass("queue-default").Obj()
+	priorityClass := utiltesting.MakePriorityClass("queue-default").PriorityValue(100).Obj()
+	job := utiltesting.MakeJob("job", "ns").Queue("main").Request(corev1.ResourceCPU, "1").Obj()
+	job.UID = "job-uid"
+	cl := fake.NewClientBuilder().WithScheme(scheme).WithObjects(queue, priorityClass, job).Build()
+	wl, err := ConstructWorkloadFor(ctx, cl, job, scheme, nil)
+	if err != nil {
+		t.Fatalf("Failed constructing the workload: %v", err)
+	}
+	// The status update with the priority source failed after the creation.
+	wl.Status.PrioritySource = ""
+	if err := cl.Create(ctx, wl); err != nil {
+		t.Fatalf("Failed creating the workload: %v", err)
+	}
+	r := NewReconciler(scheme, cl, record.NewFakeRecorder(10))
+
+	if _, err := r.Reconcile(ctx, ctrl.Request{NamespacedName: client.ObjectKeyFromObject(job)}); err != nil {
+		t.Fatalf("Reconcile returned error: %v", err)
+	}
+	var gotWl kueue.Workload
+	if err := cl.Get(ctx, client.ObjectKeyFromObject(wl), &gotWl); err != nil {
+		t.Fatalf("Failed getting the workload: %v", err)
+	}
+	if gotWl.Status.PrioritySource != "LocalQueue" {
+		t.Errorf("Unexpected priority source %q, want %q", gotWl.Status.PrioritySource, "LocalQueue")
+	}
+}
+
+func TestDeadlineFromAnnotations(t *testing.T) {
+	deadline := time.Date(2022, time.December, 1, 6, 0, 0, 0, time.UTC)
+	cases := map[string]struct {
+		job                  *batchv1.Job
+		wantDeadline         *metav1.Time
+		wantExpectedDuration *metav1.Duration
+		wantErr              bool
+	}{
+		"no annotations": {
+			job: utiltesting.MakeJob("job", "ns").Obj(),
+		},
+		"deadline and expected duration": {
+			job: utiltesting.MakeJob("job", "ns").
+				Annotation(constants.DeadlineAnnotation, "2022-12-01T06:00:00Z").
+				Annotation(constants.ExpectedDurationAnnotation, "2h30m").
+				Obj(),
+			wantDeadline:         &metav1.Time{Time: deadline},
+			wantExpectedDuration: &metav1.Duration{Duration: 150 * time.Minute},
+		},
+		"invalid deadline": {
+			job: utiltesting.MakeJob("job", "ns").
+				Annotation(constants.DeadlineAnnotation, "tomorrow").
+				Obj(),
+			wantErr: true,
+		},
+		"negative expected duration": {
+			job: utiltesting.MakeJob("job", "ns").
+				Annotation(constants.ExpectedDurationAnnotation, "-1h").
+				Obj(),
+			wantErr: true,
+		},
+	}
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			gotDeadline, gotExpectedDuration, err := deadlineFromAnnotations(tc.job)
+			if gotErr := err != nil; gotErr != tc.wantErr {
+				t.Fatalf("deadlineFromAnnotations(_) returned error %v, want error %t", err, tc.wantErr)
+			}
+			if diff := cmp.Diff(tc.wantDeadline, gotDeadline); diff != "" {
+				t.Errorf("Unexpected deadline (-want,+got):\n%s", diff)
+			}
+			if diff := cmp.Diff(tc.wantExpectedDuration, gotExpectedDuration); diff != "" {
+				t.Errorf("Unexpected expected duration (-want,+got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestMinCountFromAnnotations(t *testing.T) {
+	minCount := int32(2)
+	cases := map[string]struct {
+		job     *batchv1.Job
+		want    *int32
+		wantErr bool
+	}{
+		"no annotation": {
+			job: utiltesting.MakeJob("job", "ns").Parallelism(5).Obj(),
+		},
+		"min parallelism": {
+			job: utiltesting.MakeJob("job", "ns").
+				Parallelism(5).
+				Annotation(constants.JobMinParallelismAnnotation, "2").
+				Obj(),
+			want: &minCount,
+		},
+		"invalid min parallelism": {
+			job: utiltesting.MakeJob("job", "ns").
+				Parallelism(5).
+				Annotation(constants.JobMinParallelismAnnotation, "two").
+				Obj(),
+			wantErr: true,
+		},
+		"min parallelism greater than parallelism": {
+			job: utiltesting.MakeJob("job", "ns").
+				Parallelism(5).
+				Annotation(constants.JobMinParallelismAnnotation, "6").
+				Obj(),
+			wantErr: true,
+		},
+	}
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			got, err := minCountFromAnnotations(tc.job)
+			if gotErr := err != nil; gotErr != tc.wantErr {
+				t.Fatalf("minCountFromAnnotations(_) returned error %v, want error %t", err, tc.wantErr)
+			}
+			if diff := cmp.Diff(tc.want, got); diff != "" {
+				t.Errorf("Unexpected min count (-want,+got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestReclaimablePods(t *testing.T) {
+	wl := utiltesting.MakeWorkload("job", "ns").Obj()
+	cases := map[string]struct {
+		job  *batchv1.Job
+		want []kueue.ReclaimablePod
+	}{
+		"single pod": {
+			job: &batchv1.Job{
+				Spec: batchv1.JobSpec{
+					Parallelism: pointer.Int32(1),
+					Completions: pointer.Int32(3),
+				},
+				Status: batchv1.JobStatus{
+					Succeeded: 2,
+				},
+			},
+		},
+		"no pods succeeded": {
+			job: &batchv1.Job{
+				Spec: batchv1.JobSpec{
+					Parallelism: pointer.Int32(3),
+					Completions: pointer.Int32(3),
+				},
+			},
+		},
+		"more completions remaining than parallelism": {
+			job: &batchv1.Job{
+				Spec: batchv1.JobSpec{
+					Parallelism: pointer.Int32(3),
+					Completions: pointer.Int32(10),
+				},
+				Status: batchv1.JobStatus{
+					Succeeded: 5,
+				},
+			},
+		},
+		"fewer completions remaining than parallelism": {
+			job: &batchv1.Job{
+				Spec: batchv1.JobSpec{
+					Parallelism: pointer.Int32(3),
+					Completions: pointer.Int32(10),
+				},
+				Status: batchv1.JobStatus{
+					Succeeded: 8,
+				},
+			},
+			want: []kueue.ReclaimablePod{{
+				Name:  kueue.DefaultPodSetName,
+				Count: 1,
+			}},
+		},
+		"no completions": {
+			job: &batchv1.Job{
+				Spec: batchv1.JobSpec{
+					Parallelism: pointer.Int32(3),
+				},
+				Status: batchv1.JobStatus{
+					Succeeded: 1,
+				},
+			},
+			want: []kueue.ReclaimablePod{{
+				Name:  kueue.DefaultPodSetName,
+				Count: 1,
+			}},
+		},
+	}
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			got := reclaimablePods(tc.job, wl)
+			if diff := cmp.Diff(tc.want, got); diff != "" {
+				t.Errorf("Unexpected reclaimable pods (-want,+got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestJobAndWorkloadEqualWithPartialAdmission(t *testing.T) {
+	wl := utiltesting.MakeWorkload("job", "ns").Count(5).MinCount(2).Obj()
+	cases := map[string]struct {
+		job  *batchv1.Job
+		want bool
+	}{
+		"full parallelism": {
+			job:  utiltesting.MakeJob("job", "ns").Parallelism(5).Obj(),
+			want: true,
+		},
+		"running with reduced parallelism": {
+			job:  utiltesting.MakeJob("job", "ns").Parallelism(3).Suspend(false).Obj(),
+			want: true,
+		},
+		"running with parallelism lower than minCount": {
+			job:  utiltesting.MakeJob("job", "ns").Parallelism(1).Suspend(false).Obj(),
+			want: false,
+		},
+		"suspended with reduced parallelism": {
+			job:  utiltesting.MakeJob("job", "ns").Parallelism(3).Obj(),
+			want: false,
+		},
+	}
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			job := tc.job
+			job.Spec.Template.Spec.Containers = wl.Spec.PodSets[0].Spec.Containers
+			if got := jobAndWorkloadEqual(job, wl); got != tc.want {
+				t.Errorf("jobAndWorkloadEqual(_, _) = %t, want %t", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestJobAndWorkloadTemplatesEqual(t *testing.T) {
+	baseJob := utiltesting.MakeJob("job", "ns").Request(corev1.ResourceCPU, "1").Obj()
+	cases := map[string]struct {
+		job  *batchv1.Job
+		wl   *kueue.Workload
+		want bool
+	}{
+		"same pod template": {
+			job:  baseJob.DeepCopy(),
+			wl:   utiltesting.MakeWorkload("job", "ns").PodTemplateHash(podTemplateHash(&baseJob.Spec.Template)).Obj(),
+			want: true,
+		},
+		"changed container": {
+			job:  utiltesting.MakeJob("job", "ns").Request(corev1.ResourceCPU, "2").Obj(),
+			wl:   utiltesting.MakeWorkload("job", "ns").PodTemplateHash(podTemplateHash(&baseJob.Spec.Template)).Obj(),
+			want: false,
+		},
+		"changed nodeSelector and tolerations": {
+			job: utiltesting.MakeJob("job", "ns").Request(corev1.ResourceCPU, "1").
+				NodeSelector("instance", "spot").
+				Toleration(corev1.Toleration{Key: "spot", Operator: corev1.TolerationOpExists}).
+				Obj(),
+			wl:   utiltesting.MakeWorkload("job", "ns").PodTemplateHash(podTemplateHash(&baseJob.Spec.Template)).Obj(),
+			want: true,
+		},
+		"running job with the node affinity of a split admission": {
+			job: func() *batchv1.Job {
+				job := utiltesting.MakeJob("job", "ns").Request(corev1.ResourceCPU, "1").Suspend(false).Obj()
+				job.Spec.Template.Spec.Affinity = withRequiredNodeAffinity(nil, &corev1.NodeSelector{
+					NodeSelectorTerms: []corev1.NodeSelectorTerm{
+						{MatchExpressions: []corev1.NodeSelectorRequirement{{Key: "instance", Operator: corev1.NodeSelectorOpIn, Values: []string{"spot"}}}},
+						{MatchExpressions: []corev1.NodeSelectorRequirement{{Key: "instance", Operator: corev1.NodeSelectorOpIn, Values: []string{"on-demand"}}}},
+					},
+				})
+				return job
+			}(),
+			wl: utiltesting.MakeWorkload("job", "ns").
+				PodTemplateHash(podTemplateHash(&baseJob.Spec.Template)).
+				Admit(utiltesting.MakeAdmission("cq").
+					Flavor(corev1.ResourceCPU, "spot").
+					Count(1).
+					Split(corev1.ResourceCPU, "on-demand", 1).
+					Obj()).
+				Obj(),
+			want: true,
+		},
+		"workload without hash": {
+			job: baseJob.DeepCopy(),
+			wl: utiltesting.MakeWorkload("job", "ns").PodSets([]kueue.PodSet{{
+				Name:  "main",
+				Count: 1,
+				Spec:  *baseJob.Spec.Template.Spec.DeepCopy(),
+			}}).Obj(),
+			want: true,
+		},
+	}
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			if got := jobAndWorkloadTemplatesEqual(tc.job, tc.wl); got != tc.want {
+				t.Errorf("jobAndWorkloadTemplatesEqual(_, _) = %t, want %t", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestWithRequiredNodeAffinity(t *testing.T) {
+	spot := corev1.NodeSelectorRequirement{Key: "instance", Operator: corev1.NodeSelectorOpIn, Values: []string{"spot"}}
+	onDemand := corev1.NodeSelectorRequirement{Key: "instance", Operator: corev1.NodeSelectorOpIn, Values: []string{"on-demand"}}
+	zoneA := corev1.NodeSelectorRequirement{Key: "zone", Operator: corev1.NodeSelectorOpIn, Values: []string{"a"}}
+	zoneB := corev1.NodeSelectorRequirement{Key: "zone", Operator: corev1.NodeSelectorOpIn, Values: []string{"b"}}
+	selector := &corev1.NodeSelector{
+		NodeSelectorTerms: []corev1.NodeSelectorTerm{
+			{MatchExpressions: []corev1.NodeSelectorRequirement{spot}},
+			{MatchExpressions: []corev1.NodeSelectorRequirement{onDemand}},
+		},
+	}
+	cases := map[string]struct {
+		affinity *corev1.Affinity
+		want     *corev1.Affinity
+	}{
+		"no affinity": {
+			want: &corev1.Affinity{
+				NodeAffinity: &corev1.NodeAffinity{
+					RequiredDuringSchedulingIgnoredDuringExecution: selector,
+				},
+			},
+		},
+		"existing terms": {
+			affinity: &corev1.Affinity{
+				NodeAffinity: &corev1.NodeAffinity{
+					RequiredDuringSchedulingIgnoredDuringExecution: &corev1.NodeSelector{
+						NodeSelectorTerms: []corev1.NodeSelectorTerm{
+							{MatchExpressions: []corev1.NodeSelectorRequirement{zoneA}},
+							{MatchExpressions: []corev1.NodeSelectorRequirement{zoneB}},
+						},
+					},
+				},
+			},
+			want: &corev1.Affinity{
+				NodeAffinity: &corev1.NodeAffinity{
+					RequiredDuringSchedulingIgnoredDuringExecution: &corev1.NodeSelector{
+						NodeSelectorTerms: []corev1.NodeSelectorTerm{
+							{MatchExpressions: []corev1.NodeSelectorRequirement{zoneA, spot}},
+							{MatchExpressions: []corev1.NodeSelectorRequirement{zoneA, onDemand}},
+							{MatchExpressions: []corev1.NodeSelectorRequirement{zoneB, spot}},
+							{MatchExpressions: []corev1.NodeSelectorRequirement{zoneB, onDemand}},
+						},
+					},
+				},
+			},
+		},
+	}
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			original := tc.affinity.DeepCopy()
+			got := withRequiredNodeAffinity(tc.affinity, selector)
+			if diff := cmp.Diff(tc.want, got); diff != "" {
+				t.Errorf("Unexpected affinity (-want,+got):\n%s", diff)
+			}
+			if diff := cmp.Diff(original, tc.affinity); diff != "" {
+				t.Errorf("withRequiredNodeAffinity modified the original affinity (-want,+got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestReconcileWithChangedPodTemplate(t *testing.T) {
+	oldJob := utiltesting.MakeJob("job", "ns").Request(corev1.ResourceCPU, "1").Obj()
+	cases := map[string]struct {
+		job         *batchv1.Job
+		admitted    bool
+		wantKept    bool
+		wantInvalid bool
+	}{
+		"running job with admitted workload": {
+			job:         utiltesting.MakeJob("job", "ns").Request(corev1.ResourceCPU, "2").Suspend(false).Obj(),
+			admitted:    true,
+			wantKept:    true,
+			wantInvalid: true,
+		},
+		"suspended job with pending workload": {
+			job: utiltesting.MakeJob("job", "ns").Request(corev1.ResourceCPU, "2").Obj(),
+		},
+		"suspended job with admitted workload": {
+			job:      utiltesting.MakeJob("job", "ns").Request(corev1.ResourceCPU, "2").Obj(),
+			admitted: true,
+		},
+	}
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			scheme := utiltesting.MustGetScheme(t)
+			if err := batchv1.AddToScheme(scheme); err != nil {
+				t.Fatalf("Failed adding batch scheme: %v", err)
+			}
+			ctx := context.Background()
+			tc.job.UID = "job-uid"
+			wl := utiltesting.MakeWorkload("job", "ns").PodTemplateHash(podTemplateHash(&oldJob.Spec.Template)).Obj()
+			wl.OwnerReferences = []metav1.OwnerReference{*metav1.NewControllerRef(tc.job, batchv1.SchemeGroupVersion.WithKind("Job"))}
+			if tc.admitted {
+				wl.Status.Admission = utiltesting.MakeAdmission("cq").Obj()
+			}
+			cl := fake.NewClientBuilder().WithScheme(scheme).WithObjects(tc.job, wl).Build()
+			r := NewReconciler(scheme, cl, record.NewFakeRecorder(10), WithManageJobsWithoutQueueName(true))
+
+			_, err := r.Reconcile(ctx, ctrl.Request{NamespacedName: client.ObjectKeyFromObject(tc.job)})
+			var got kueue.Workload
+			getErr := cl.Get(ctx, client.ObjectKeyFromObject(wl), &got)
+			if !tc.wantKept {
+				if !apierrors.IsNotFound(getErr) {
+					t.Errorf("Workload with a stale pod template wasn't deleted, got error %v", getErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Reconcile returned error: %v", err)
+			}
+			if getErr != nil {
+				t.Fatalf("Failed getting the workload: %v", getErr)
+			}
+			if gotInvalid := apimeta.IsStatusConditionTrue(got.Status.Conditions, kueue.WorkloadInvalid); gotInvalid != tc.wantInvalid {
+				t.Errorf("Workload has the InvalidWorkload condition %t, want %t", gotInvalid, tc.wantInvalid)
+			}
+		})
+	}
+}
+
+func TestReconcileWithDuplicateWorkloads(t *testing.T) {
+	now := time.Now().Truncate(time.Second)
+	cases := map[string]struct {
+		admitted string
+		wantKept string
+	}{
+		"keeps the oldest workload": {
+			wantKept: "job",
+		},
+		"keeps the admitted workload": {
+			admitted: "job-dup",
+			wantKept: "job-dup",
+		},
+	}
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			scheme := utiltesting.MustGetScheme(t)
+			if err := batchv1.AddToScheme(scheme); err != nil {
+				t.Fatalf("Failed adding batch scheme: %v", err)
+			}
+			ctx := context.Background()
+			job := utiltesting.MakeJob("job", "ns").Request(corev1.ResourceCPU, "1").Obj()
+			job.UID = "job-uid"
+			oldJob := job.DeepCopy()
+			oldJob.UID = "old-job-uid"
+			hash := podTemplateHash(&job.Spec.Template)
+			workloads := []*kueue.Workload{
+				utiltesting.MakeWorkload("job", "ns").PodTemplateHash(hash).Creation(now).Obj(),
+				utiltesting.MakeWorkload("job-dup", "ns").PodTemplateHash(hash).Creation(now.Add(time.Second)).Obj(),
+				utiltesting.MakeWorkload("job-old", "ns").PodTemplateHash(hash).Creation(now.Add(-time.Second)).Obj(),
+			}
+			gvk := batchv1.SchemeGroupVersion.WithKind("Job")
+			builder := fake.NewClientBuilder().WithScheme(scheme).WithObjects(job)
+			for _, wl := range workloads {
+				owner := job
+				if wl.Name == "job-old" {
+					owner = oldJob
+				}
+				wl.OwnerReferences = []metav1.OwnerReference{*metav1.NewControllerRef(owner, gvk)}
+				if wl.Name == tc.admitted {
+					wl.Status.Admission = utiltesting.MakeAdmission("cq").Obj()
+				}
+				builder = builder.WithObjects(wl)
+			}
+			cl := builder.Build()
+			r := NewReconciler(scheme, cl, record.NewFakeRecorder(10), WithManageJobsWithoutQueueName(true))
+			req := ctrl.Request{NamespacedName: client.ObjectKeyFromObject(job)}
+
+			if _, err := r.Reconcile(ctx, req); err == nil {
+				t.Fatal("Reconcile didn't return an error after deleting the duplicate workloads")
+			}
+			var got kueue.WorkloadList
+			if err := cl.List(ctx, &got); err != nil {
+				t.Fatalf("Failed listing workloads: %v", err)
+			}
+			if len(got.Items) != 1 || got.Items[0].Name != tc.wantKept {
+				t.Fatalf("Got workloads %v after deleting the duplicates, want only %s", got.Items, tc.wantKept)
+			}
+			if _, err := r.Reconcile(ctx, req); err != nil {
+				t.Fatalf("Reconcile returned error: %v", err)
+			}
+			if err := cl.List(ctx, &got); err != nil {
+				t.Fatalf("Failed listing workloads: %v", err)
+			}
+			if len(got.Items) != 1 || got.Items[0].Name != tc.wantKept {
+				t.Errorf("Got workloads %v, want only %s", got.Items, tc.wantKept)
+			}
+		})
+	}
+}
@@ -18,18 +18,34 @@ package job
 
 import (
 	"context"
+	"fmt"
+	"sort"
 
+	admissionv1 "k8s.io/api/admission/v1"
 	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/apimachinery/pkg/util/validation/field"
 	"k8s.io/klog/v2"
 	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/webhook"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
 
+	kueue "sigs.k8s.io/kueue/apis/kueue/v1alpha2"
+	"sigs.k8s.io/kueue/pkg/constants"
+	"sigs.k8s.io/kueue/pkg/controller/workload/jobframework"
+	"sigs.k8s.io/kueue/pkg/util/api"
 	"sigs.k8s.io/kueue/pkg/util/pointer"
+	"sigs.k8s.io/kueue/pkg/workload"
 )
 
+const validateJobPath = "/validate-batch-v1-job"
+
 type JobWebhook struct {
+	client                     client.Client
 	manageJobsWithoutQueueName bool
 }
 
@@ -40,13 +56,21 @@ func SetupWebhook(mgr ctrl.Manager, opts ...Option) error {
 		opt(&options)
 	}
 	wh := &JobWebhook{
+		client:                     mgr.GetClient(),
 		manageJobsWithoutQueueName: options.manageJobsWithoutQueueName,
 	}
-	return ctrl.NewWebhookManagedBy(mgr).
+	if err := ctrl.NewWebhookManagedBy(mgr).
 		For(&batchv1.Job{}).
 		WithDefaulter(wh).
-		WithValidator(wh).
-		Complete()
+		Complete(); err != nil {
+		return err
+	}
+	// The validating webhook is registered by hand to add the quota warnings,
+	// which a CustomValidator can't return.
+	vwh := admission.WithCustomValidator(&batchv1.Job{}, wh)
+	vwh.Handler = &quotaWarningHandler{Handler: vwh.Handler, webhook: wh}
+	mgr.GetWebhookServer().Register(validateJobPath, vwh)
+	return nil
 }
 
 // +kubebuilder:webhook:path=/mutate-batch-v1-job,mutating=true,failurePolicy=fail,sideEffects=None,groups=batch,resources=jobs,verbs=create,versions=v1,name=mjob.kb.io,admissionReviewVersions=v1
@@ -59,6 +83,15 @@ func (w *JobWebhook) Default(ctx context.Context, obj runtime.Object) error {
 	log := ctrl.LoggerFrom(ctx).WithName("job-webhook")
 	log.V(5).Info("Applying defaults", "job", klog.KObj(job))
 
+	// The quota of the job is accounted through the workload of its owner.
+	if owner := metav1.GetControllerOf(job); owner != nil && jobframework.IsOwnerManagedByKueue(owner) {
+		return nil
+	}
+	if queueName(job) == "" {
+		if err := w.setDefaultQueueName(ctx, job); err != nil {
+			return err
+		}
+	}
 	if queueName(job) == "" && !w.manageJobsWithoutQueueName {
 		return nil
 	}
@@ -70,7 +103,26 @@ func (w *JobWebhook) Default(ctx context.Context, obj runtime.Object) error {
 	return nil
 }
 
-// +kubebuilder:webhook:path=/validate-batch-v1-job,mutating=false,failurePolicy=fail,sideEffects=None,groups=batch,resources=jobs,verbs=update,versions=v1,name=vjob.kb.io,admissionReviewVersions=v1
+// setDefaultQueueName sets the queue name of the job to the default queue of
+// its namespace, if the namespace has one.
+func (w *JobWebhook) setDefaultQueueName(ctx context.Context, job *batchv1.Job) error {
+	var ns corev1.Namespace
+	if err := w.client.Get(ctx, types.NamespacedName{Name: job.Namespace}, &ns); err != nil {
+		return fmt.Errorf("getting the namespace of the job: %w", err)
+	}
+	defaultQueue := ns.Annotations[constants.DefaultQueueAnnotation]
+	if defaultQueue == "" {
+		return nil
+	}
+	if job.Annotations == nil {
+		job.Annotations = make(map[string]string, 1)
+	}
+	job.Annotations[constants.QueueAnnotation] = defaultQueue
+	ctrl.LoggerFrom(ctx).WithName("job-webhook").V(5).Info("Setting the default queue of the namespace", "job", klog.KObj(job), "queue", defaultQueue)
+	return nil
+}
+
+// +kubebuilder:webhook:path=/validate-batch-v1-job,mutating=false,failurePolicy=fail,sideEffects=None,groups=batch,resources=jobs,verbs=create;update,versions=v1,name=vjob.kb.io,admissionReviewVersions=v1
 
 var _ webhook.CustomValidator = &JobWebhook{}
 
@@ -107,3 +159,198 @@ func validateUpdate(oldJob, newJob *batchv1.Job) error {
 func (w *JobWebhook) ValidateDelete(ctx context.Context, obj runtime.Object) error {
 	return nil
 }
+
+// quotaWarningHandler wraps the validating handler of the Job to warn, on
+// creation, about the requests of the Job that can never fit in the quota of
+// its ClusterQueue or in the limits of its LocalQueue.
+type quotaWarningHandler struct {
+	admission.Handler
+	webhook *JobWebhook
+	decoder *admission.Decoder
+}
+
+var _ admission.DecoderInjector = &quotaWarningHandler{}
+
+// InjectDecoder injects the decoder into the handler and the wrapped handler.
+func (h *quotaWarningHandler) InjectDecoder(d *admission.Decoder) error {
+	h.decoder = d
+	_, err := admission.InjectDecoderInto(d, h.Handler)
+	return err
+}
+
+func (h *quotaWarningHandler) Handle(ctx context.Context, req admission.Request) admission.Response {
+	resp := h.Handler.Handle(ctx, req)
+	if !resp.Allowed || req.Operation != admissionv1.Create {
+		return resp
+	}
+	job := &batchv1.Job{}
+	if err := h.decoder.Decode(req, job); err != nil {
+		return resp
+	}
+	return resp.WithWarnings(h.webhook.quotaWarnings(ctx, job)...)
+}
+
+// quotaWarnings returns the reasons why the workload of the Job can never be
+// admitted by its ClusterQueue, regardless of the usage of the quota. It
+// returns nil if the LocalQueue or the ClusterQueue can't be obtained.
+func (w *JobWebhook) quotaWarnings(ctx context.Context, job *batchv1.Job) []string {
+	log := ctrl.LoggerFrom(ctx).WithName("job-webhook")
+	qName := queueName(job)
+	if qName == "" {
+		return nil
+	}
+	var lq kueue.LocalQueue
+	if err := w.client.Get(ctx, types.NamespacedName{Namespace: job.Namespace, Name: qName}, &lq); err != nil {
+		log.V(5).Info("Skipping the quota warnings, could not get the LocalQueue", "job", klog.KObj(job), "error", err)
+		return nil
+	}
+	if len(lq.Spec.PriorityClassRouting) > 0 {
+		// The ClusterQueue depends on the priority class of the workload,
+		// which isn't known until the workload is created.
+		log.V(5).Info("Skipping the quota warnings, the LocalQueue routes workloads by priority class", "job", klog.KObj(job))
+		return nil
+	}
+	var cq kueue.ClusterQueue
+	if err := w.client.Get(ctx, types.NamespacedName{Name: string(lq.Spec.ClusterQueue)}, &cq); err != nil {
+		log.V(5).Info("Skipping the quota warnings, could not get the ClusterQueue", "job", klog.KObj(job), "error", err)
+		return nil
+	}
+	cohort := []kueue.ClusterQueue{cq}
+	if cq.Spec.Cohort != "" {
+		var cqs kueue.ClusterQueueList
+		if err := w.client.List(ctx, &cqs); err != nil {
+			log.V(5).Info("Skipping the quota warnings, could not list the ClusterQueues", "job", klog.KObj(job), "error", err)
+			return nil
+		}
+		cohort = cohort[:0]
+		for _, c := range cqs.Items {
+			if c.Spec.Cohort == cq.Spec.Cohort {
+				cohort = append(cohort, c)
+			}
+		}
+	}
+	wl := &kueue.Workload{
+		Spec: kueue.WorkloadSpec{
+			PodSets: (*BatchJob)(job).PodSets(),
+		},
+	}
+	for i := range wl.Spec.PodSets {
+		// The name is defaulted by the apiserver.
+		if wl.Spec.PodSets[i].Name == "" {
+			wl.Spec.PodSets[i].Name = kueue.DefaultPodSetName
+		}
+	}
+	// Partial admission is validated by the job controller, so an invalid
+	// minCount only results in no partial admission here.
+	wl.Spec.PodSets[0].MinCount, _ = minCountFromAnnotations(job)
+	return quotaMismatches(wl, &lq, &cq, cohort)
+}
+
+// quotaMismatches returns the reasons why the workload can never fit in the
+// quota of the ClusterQueue, which belongs to the cohort of ClusterQueues, or
+// in the limits of the LocalQueue. A pod set that supports partial admission
+// is evaluated with its minimum count.
+func quotaMismatches(wl *kueue.Workload, lq *kueue.LocalQueue, cq *kueue.ClusterQueue, cohort []kueue.ClusterQueue) []string {
+	info := workload.NewInfo(wl)
+	if idx, ok := workload.CanBePartiallyAdmitted(wl); ok {
+		info = info.WithPodSetCount(idx, *wl.Spec.PodSets[idx].MinCount)
+	}
+	capacity := maxQuota(cq, cohort)
+	var msgs []string
+	total := make(workload.Requests)
+	for _, ps := range info.TotalRequests {
+		for _, name := range sortedResources(ps.Requests) {
+			val := ps.Requests[name]
+			total[name] += val
+			limit, ok := capacity[name]
+			if !ok {
+				msgs = append(msgs, fmt.Sprintf("podSet %s requests %s, which ClusterQueue %s doesn't provide", ps.Name, name, cq.Name))
+				continue
+			}
+			if val > limit {
+				q := workload.ResourceQuantity(name, val)
+				l := workload.ResourceQuantity(name, limit)
+				msgs = append(msgs, fmt.Sprintf("podSet %s requests %s of %s, but ClusterQueue %s can provide at most %s", ps.Name, q.String(), name, cq.Name, l.String()))
+			}
+		}
+	}
+	for _, name := range sortedResources(total) {
+		limit, ok := lq.Spec.Limits[name]
+		if ok && total[name] > workload.ResourceValue(name, limit) {
+			q := workload.ResourceQuantity(name, total[name])
+			msgs = append(msgs, fmt.Sprintf("the workload requests %s of %s, but the limit of LocalQueue %s is %s", q.String(), name, lq.Name, limit.String()))
+		}
+		if pct, ok := lq.Spec.LimitsPercent[name]; ok {
+			limit := workload.ResourceQuantity(name, clusterQueueQuota(cq, name)*int64(pct)/100)
+			if total[name] > workload.ResourceValue(name, limit) {
+				q := workload.ResourceQuantity(name, total[name])
+				msgs = append(msgs, fmt.Sprintf("the workload requests %s of %s, but the limit of LocalQueue %s is %d%% of the quota of ClusterQueue %s, %s", q.String(), name, lq.Name, pct, cq.Name, limit.String()))
+			}
+		}
+	}
+	return msgs
+}
+
+// clusterQueueQuota returns the min quota of the resource in the ClusterQueue,
+// summed across all flavors.
+func clusterQueueQuota(cq *kueue.ClusterQueue, name corev1.ResourceName) int64 {
+	var quota int64
+	for _, r := range api.ClusterQueueResources(cq) {
+		if r.Name != name {
+			continue
+		}
+		for _, f := range r.Flavors {
+			quota += workload.ResourceValue(name, f.Quota.Min)
+		}
+	}
+	return quota
+}
+
+// maxQuota returns, for each resource of the ClusterQueue, the largest quota
+// that the ClusterQueue can use in any of the flavors of the resource: the
+// min quota, or the min quota of the whole cohort up to the max quota when the
+// ClusterQueue belongs to a cohort.
+func maxQuota(cq *kueue.ClusterQueue, cohort []kueue.ClusterQueue) workload.Requests {
+	cohortMin := make(map[corev1.ResourceName]map[kueue.ResourceFlavorReference]int64)
+	for i := range cohort {
+		for _, r := range api.ClusterQueueResources(&cohort[i]) {
+			if cohortMin[r.Name] == nil {
+				cohortMin[r.Name] = make(map[kueue.ResourceFlavorReference]int64)
+			}
+			for _, f := range r.Flavors {
+				cohortMin[r.Name][f.Name] += workload.ResourceValue(r.Name, f.Quota.Min)
+			}
+		}
+	}
+	resources := api.ClusterQueueResources(cq)
+	capacity := make(workload.Requests, len(resources))
+	for _, r := range resources {
+		capacity[r.Name] = 0
+		for _, f := range r.Flavors {
+			limit := workload.ResourceValue(r.Name, f.Quota.Min)
+			if cq.Spec.Cohort != "" {
+				limit = cohortMin[r.Name][f.Name]
+				if f.Quota.Max != nil {
+					if max := workload.ResourceValue(r.Name, *f.Quota.Max); max < limit {
+						limit = max
+					}
+				}
+			}
+			if limit > capacity[r.Name] {
+				capacity[r.Name] = limit
+			}
+		}
+	}
+	return capacity
+}
+
+func sortedResources(r workload.Requests) []corev1.ResourceName {
+	names := make([]corev1.ResourceName, 0, len(r))
+	for name := range r {
+		names = append(names, name)
+	}
+	sort.Slice(names, func(i, j int) bool {
+		return names[i] < names[j]
+	})
+	return names
+}
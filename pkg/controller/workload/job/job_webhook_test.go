@@ -17,13 +17,19 @@ limitations under the License.
 package job
 
 import (
+	"context"
 	"testing"
 
 	"github.com/google/go-cmp/cmp"
 	"github.com/google/go-cmp/cmp/cmpopts"
 	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/util/validation/field"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
 
+	"sigs.k8s.io/kueue/pkg/constants"
 	testingutil "sigs.k8s.io/kueue/pkg/util/testing"
 )
 
@@ -78,3 +84,142 @@ func TestValidateUpdate(t *testing.T) {
 		})
 	}
 }
+
+func TestDefault(t *testing.T) {
+	namespaces := []client.Object{
+		&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "default"}},
+		&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{
+			Name:        "team-a",
+			Annotations: map[string]string{constants.DefaultQueueAnnotation: "team-a-queue"},
+		}},
+	}
+	cases := map[string]struct {
+		job                        *batchv1.Job
+		manageJobsWithoutQueueName bool
+		wantJob                    *batchv1.Job
+	}{
+		"job with a queue name is suspended": {
+			job:     testingutil.MakeJob("job", "default").Queue("queue").Suspend(false).Obj(),
+			wantJob: testingutil.MakeJob("job", "default").Queue("queue").Obj(),
+		},
+		"job without a queue name is ignored": {
+			job:     testingutil.MakeJob("job", "default").Suspend(false).Obj(),
+			wantJob: testingutil.MakeJob("job", "default").Suspend(false).Obj(),
+		},
+		"job without a queue name is suspended when managing all jobs": {
+			job:                        testingutil.MakeJob("job", "default").Suspend(false).Obj(),
+			manageJobsWithoutQueueName: true,
+			wantJob:                    testingutil.MakeJob("job", "default").Obj(),
+		},
+		"job without a queue name gets the default queue of the namespace": {
+			job:     testingutil.MakeJob("job", "team-a").Suspend(false).Obj(),
+			wantJob: testingutil.MakeJob("job", "team-a").Queue("team-a-queue").Obj(),
+		},
+		"job with a queue name keeps it": {
+			job:     testingutil.MakeJob("job", "team-a").Queue("other").Suspend(false).Obj(),
+			wantJob: testingutil.MakeJob("job", "team-a").Queue("other").Obj(),
+		},
+	}
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			cl := fake.NewClientBuilder().WithScheme(testingutil.MustGetScheme(t)).WithObjects(namespaces...).Build()
+			w := &JobWebhook{client: cl, manageJobsWithoutQueueName: tc.manageJobsWithoutQueueName}
+			if err := w.Default(context.Background(), tc.job); err != nil {
+				t.Fatalf("Default returned error: %v", err)
+			}
+			if diff := cmp.Diff(tc.wantJob, tc.job); diff != "" {
+				t.Errorf("Unexpected job (-want,+got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestQuotaWarnings(t *testing.T) {
+	objs := []client.Object{
+		testingutil.MakeClusterQueue("standalone").
+			Resource(testingutil.MakeResource(corev1.ResourceCPU).
+				Flavor(testingutil.MakeFlavor("small", "2").Obj()).
+				Flavor(testingutil.MakeFlavor("large", "4").Obj()).Obj()).
+			Obj(),
+		testingutil.MakeClusterQueue("borrower").
+			Cohort("cohort").
+			Resource(testingutil.MakeResource(corev1.ResourceCPU).
+				Flavor(testingutil.MakeFlavor("default", "2").Max("5").Obj()).Obj()).
+			Obj(),
+		testingutil.MakeClusterQueue("lender").
+			Cohort("cohort").
+			Resource(testingutil.MakeResource(corev1.ResourceCPU).
+				Flavor(testingutil.MakeFlavor("default", "4").Obj()).Obj()).
+			Obj(),
+		testingutil.MakeLocalQueue("standalone", "default").ClusterQueue("standalone").Obj(),
+		testingutil.MakeLocalQueue("borrower", "default").ClusterQueue("borrower").Obj(),
+		testingutil.MakeLocalQueue("lender", "default").ClusterQueue("lender").Obj(),
+		testingutil.MakeLocalQueue("limited", "default").ClusterQueue("standalone").Limit(corev1.ResourceCPU, "3").Obj(),
+		testingutil.MakeLocalQueue("share", "default").ClusterQueue("standalone").LimitPercent(corev1.ResourceCPU, 25).Obj(),
+	}
+	cases := map[string]struct {
+		job          *batchv1.Job
+		wantWarnings []string
+	}{
+		"fits in the largest flavor": {
+			job: testingutil.MakeJob("job", "default").Queue("standalone").Request(corev1.ResourceCPU, "4").Obj(),
+		},
+		"exceeds the largest flavor": {
+			job: testingutil.MakeJob("job", "default").Queue("standalone").Request(corev1.ResourceCPU, "5").Obj(),
+			wantWarnings: []string{
+				"podSet main requests 5 of cpu, but ClusterQueue standalone can provide at most 4",
+			},
+		},
+		"resource not provided": {
+			job: testingutil.MakeJob("job", "default").Queue("standalone").
+				Request(corev1.ResourceCPU, "1").
+				Request(corev1.ResourceMemory, "1Gi").Obj(),
+			wantWarnings: []string{
+				"podSet main requests memory, which ClusterQueue standalone doesn't provide",
+			},
+		},
+		"fits borrowing from the cohort": {
+			job: testingutil.MakeJob("job", "default").Queue("lender").Request(corev1.ResourceCPU, "6").Obj(),
+		},
+		"exceeds the max quota": {
+			job: testingutil.MakeJob("job", "default").Queue("borrower").Request(corev1.ResourceCPU, "6").Obj(),
+			wantWarnings: []string{
+				"podSet main requests 6 of cpu, but ClusterQueue borrower can provide at most 5",
+			},
+		},
+		"fits with partial admission": {
+			job: testingutil.MakeJob("job", "default").Queue("standalone").
+				Parallelism(4).
+				Annotation(constants.JobMinParallelismAnnotation, "2").
+				Request(corev1.ResourceCPU, "2").Obj(),
+		},
+		"exceeds the LocalQueue limits": {
+			job: testingutil.MakeJob("job", "default").Queue("limited").Request(corev1.ResourceCPU, "4").Obj(),
+			wantWarnings: []string{
+				"the workload requests 4 of cpu, but the limit of LocalQueue limited is 3",
+			},
+		},
+		"exceeds the LocalQueue limits as a percentage of the ClusterQueue quota": {
+			job: testingutil.MakeJob("job", "default").Queue("share").Request(corev1.ResourceCPU, "2").Obj(),
+			wantWarnings: []string{
+				"the workload requests 2 of cpu, but the limit of LocalQueue share is 25% of the quota of ClusterQueue standalone, 1500m",
+			},
+		},
+		"LocalQueue not found": {
+			job: testingutil.MakeJob("job", "default").Queue("missing").Request(corev1.ResourceCPU, "5").Obj(),
+		},
+		"without queue name": {
+			job: testingutil.MakeJob("job", "default").Request(corev1.ResourceCPU, "5").Obj(),
+		},
+	}
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			cl := fake.NewClientBuilder().WithScheme(testingutil.MustGetScheme(t)).WithObjects(objs...).Build()
+			w := &JobWebhook{client: cl}
+			got := w.quotaWarnings(context.Background(), tc.job)
+			if diff := cmp.Diff(tc.wantWarnings, got); diff != "" {
+				t.Errorf("Unexpected warnings (-want,+got):\n%s", diff)
+			}
+		})
+	}
+}
@@ -0,0 +1,98 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package job
+
+import (
+	"context"
+	"time"
+
+	batchv1 "k8s.io/api/batch/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/wait"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+
+	"sigs.k8s.io/kueue/pkg/controller/workload/jobframework"
+)
+
+const suspendCheckChBuffer = 10
+
+// suspendStateChecker periodically triggers the reconciliation of the running
+// Jobs managed by Kueue. It is a safety net to detect the Jobs running while
+// their Workloads are not admitted, in case the controller missed the event
+// that unsuspended them.
+type suspendStateChecker struct {
+	client                     client.Client
+	period                     time.Duration
+	manageJobsWithoutQueueName bool
+	ch                         chan event.GenericEvent
+}
+
+func newSuspendStateChecker(client client.Client, period time.Duration, manageJobsWithoutQueueName bool) *suspendStateChecker {
+	return &suspendStateChecker{
+		client:                     client,
+		period:                     period,
+		manageJobsWithoutQueueName: manageJobsWithoutQueueName,
+		ch:                         make(chan event.GenericEvent, suspendCheckChBuffer),
+	}
+}
+
+// Start implements manager.Runnable.
+func (c *suspendStateChecker) Start(ctx context.Context) error {
+	log := ctrl.LoggerFrom(ctx).WithName("job-suspend-checker")
+	ctx = ctrl.LoggerInto(ctx, log)
+	wait.UntilWithContext(ctx, c.check, c.period)
+	return nil
+}
+
+func (c *suspendStateChecker) check(ctx context.Context) {
+	log := ctrl.LoggerFrom(ctx)
+	var jobs batchv1.JobList
+	if err := c.client.List(ctx, &jobs); err != nil {
+		log.Error(err, "Listing jobs")
+		return
+	}
+	checked := 0
+	for i := range jobs.Items {
+		job := &jobs.Items[i]
+		if !c.running(job) {
+			continue
+		}
+		select {
+		case c.ch <- event.GenericEvent{Object: job}:
+			checked++
+		case <-ctx.Done():
+			return
+		}
+	}
+	log.V(3).Info("Triggered the reconciliation of running jobs", "count", checked)
+}
+
+func (c *suspendStateChecker) running(job *batchv1.Job) bool {
+	if queueName(job) == "" && !c.manageJobsWithoutQueueName {
+		return false
+	}
+	if owner := metav1.GetControllerOf(job); owner != nil && jobframework.IsOwnerManagedByKueue(owner) {
+		return false
+	}
+	if jobSuspended(job) {
+		return false
+	}
+	_, finished := jobFinishedCondition(job)
+	return !finished
+}
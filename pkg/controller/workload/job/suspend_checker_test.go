@@ -0,0 +1,82 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package job
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	utiltesting "sigs.k8s.io/kueue/pkg/util/testing"
+)
+
+func TestSuspendStateCheckerCheck(t *testing.T) {
+	finishedJob := utiltesting.MakeJob("finished", "ns").Queue("main").Suspend(false).Obj()
+	finishedJob.Status.Conditions = []batchv1.JobCondition{
+		{
+			Type:   batchv1.JobComplete,
+			Status: corev1.ConditionTrue,
+		},
+	}
+	jobs := []batchv1.Job{
+		*utiltesting.MakeJob("running", "ns").Queue("main").Suspend(false).Obj(),
+		*utiltesting.MakeJob("suspended", "ns").Queue("main").Obj(),
+		*utiltesting.MakeJob("running-without-queue", "ns").Suspend(false).Obj(),
+		*finishedJob,
+	}
+	cases := map[string]struct {
+		manageJobsWithoutQueueName bool
+		wantChecked                []string
+	}{
+		"only jobs with queue name": {
+			wantChecked: []string{"running"},
+		},
+		"manage jobs without queue name": {
+			manageJobsWithoutQueueName: true,
+			wantChecked:                []string{"running", "running-without-queue"},
+		},
+	}
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			scheme := runtime.NewScheme()
+			if err := batchv1.AddToScheme(scheme); err != nil {
+				t.Fatalf("Failed adding batch scheme: %v", err)
+			}
+			cl := fake.NewClientBuilder().WithScheme(scheme).
+				WithLists(&batchv1.JobList{Items: jobs}).
+				Build()
+			checker := newSuspendStateChecker(cl, time.Minute, tc.manageJobsWithoutQueueName)
+			checker.check(context.Background())
+			close(checker.ch)
+
+			var gotChecked []string
+			for e := range checker.ch {
+				gotChecked = append(gotChecked, e.Object.GetName())
+			}
+			if diff := cmp.Diff(tc.wantChecked, gotChecked, cmpopts.SortSlices(func(a, b string) bool { return a < b })); diff != "" {
+				t.Errorf("Unexpected checked jobs (-want,+got):\n%s", diff)
+			}
+		})
+	}
+}
@@ -0,0 +1,152 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package conformance provides tests that implementations of
+// jobframework.GenericJob can run to verify that they integrate with Kueue
+// like the built-in integrations do.
+package conformance
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	kueue "sigs.k8s.io/kueue/apis/kueue/v1alpha2"
+	"sigs.k8s.io/kueue/pkg/controller/workload/jobframework"
+)
+
+const nodeSelectorKey = "kueue.x-k8s.io/conformance"
+
+// Suite describes how to exercise a GenericJob implementation.
+type Suite struct {
+	// NewJob returns a new suspended job that didn't start.
+	NewJob func() jobframework.GenericJob
+	// WantPodSets are the pod sets expected for the jobs returned by NewJob.
+	WantPodSets []kueue.PodSet
+	// WantQueueName is the queue name expected for the jobs returned by
+	// NewJob.
+	WantQueueName string
+	// SetPodsReady updates the status of the job as if all its pods were
+	// ready.
+	SetPodsReady func(jobframework.GenericJob)
+	// SetFinished updates the status of the job as if it finished,
+	// successfully or not. It's nil for the jobs that never finish, such as
+	// the serving workloads.
+	SetFinished func(job jobframework.GenericJob, success bool)
+}
+
+// Run runs the conformance tests for the suite.
+func Run(t *testing.T, s Suite) {
+	t.Run("queue name", func(t *testing.T) {
+		if got := s.NewJob().QueueName(); got != s.WantQueueName {
+			t.Errorf("QueueName()=%q, want %q", got, s.WantQueueName)
+		}
+	})
+	t.Run("suspend is honored", func(t *testing.T) {
+		job := s.NewJob()
+		if !job.IsSuspended() {
+			t.Fatal("NewJob returned a job that is not suspended")
+		}
+		job.RunWithNodeSelectors(make([]map[string]string, len(s.WantPodSets)))
+		if job.IsSuspended() {
+			t.Error("Job is suspended after RunWithNodeSelectors")
+		}
+		job.Suspend()
+		if !job.IsSuspended() {
+			t.Error("Job is not suspended after Suspend")
+		}
+	})
+	t.Run("pod sets are extracted", func(t *testing.T) {
+		if diff := cmp.Diff(s.WantPodSets, s.NewJob().PodSets()); diff != "" {
+			t.Errorf("Unexpected pod sets (-want,+got):\n%s", diff)
+		}
+	})
+	t.Run("equivalent to workload", func(t *testing.T) {
+		job := s.NewJob()
+		wl := &kueue.Workload{Spec: kueue.WorkloadSpec{PodSets: job.PodSets()}}
+		if !job.EquivalentToWorkload(wl) {
+			t.Error("Job is not equivalent to a workload with its pod sets")
+		}
+		wl.Spec.PodSets[0].Count++
+		if job.EquivalentToWorkload(wl) {
+			t.Error("Job is equivalent to a workload with a different count")
+		}
+		if job.EquivalentToWorkload(&kueue.Workload{}) {
+			t.Error("Job is equivalent to a workload without pod sets")
+		}
+	})
+	t.Run("node selectors are injected", func(t *testing.T) {
+		job := s.NewJob()
+		nodeSelectors := make([]map[string]string, len(s.WantPodSets))
+		for i := range nodeSelectors {
+			nodeSelectors[i] = map[string]string{nodeSelectorKey: fmt.Sprintf("podset-%d", i)}
+		}
+		job.RunWithNodeSelectors(nodeSelectors)
+		for i, ps := range job.PodSets() {
+			want := s.WantPodSets[i].Spec.NodeSelector
+			got := ps.Spec.NodeSelector
+			for k, v := range want {
+				if got[k] != v {
+					t.Errorf("Pod set %d lost its node selector %s=%s", i, k, v)
+				}
+			}
+			if got[nodeSelectorKey] != nodeSelectors[i][nodeSelectorKey] {
+				t.Errorf("Pod set %d has node selector %s=%s, want %s", i, nodeSelectorKey, got[nodeSelectorKey], nodeSelectors[i][nodeSelectorKey])
+			}
+		}
+	})
+	t.Run("pods ready status is synced", func(t *testing.T) {
+		job := s.NewJob()
+		if job.PodsReady() {
+			t.Error("New job has all pods ready")
+		}
+		s.SetPodsReady(job)
+		if !job.PodsReady() {
+			t.Error("Job doesn't have all pods ready after SetPodsReady")
+		}
+	})
+	if s.SetFinished == nil {
+		t.Run("never finishes", func(t *testing.T) {
+			job := s.NewJob()
+			job.RunWithNodeSelectors(make([]map[string]string, len(s.WantPodSets)))
+			if _, finished := job.Finished(); finished {
+				t.Error("Job is finished")
+			}
+		})
+		return
+	}
+	for _, success := range []bool{true, false} {
+		t.Run(fmt.Sprintf("finished status is synced, success=%t", success), func(t *testing.T) {
+			job := s.NewJob()
+			if _, finished := job.Finished(); finished {
+				t.Error("New job is finished")
+			}
+			s.SetFinished(job, success)
+			cond, finished := job.Finished()
+			if !finished {
+				t.Fatal("Job is not finished after SetFinished")
+			}
+			if cond.Type != kueue.WorkloadFinished || cond.Status != metav1.ConditionTrue {
+				t.Errorf("Finished condition is %s=%s, want %s=%s", cond.Type, cond.Status, kueue.WorkloadFinished, metav1.ConditionTrue)
+			}
+			if len(cond.Reason) == 0 || len(cond.Message) == 0 {
+				t.Error("Finished condition must have a reason and a message")
+			}
+		})
+	}
+}
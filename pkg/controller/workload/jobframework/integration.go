@@ -0,0 +1,115 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package jobframework lets new kinds of jobs be queued by Kueue without
+// changes to its core. An integration implements GenericJob for its kind,
+// verifies it with the conformance package, and registers it with
+// RegisterIntegration from the init function of its package. Once the
+// package is linked into the manager, the integration can be enabled in the
+// integrations.frameworks field of the configuration, and its jobs are
+// reconciled by a JobReconciler.
+package jobframework
+
+import (
+	"fmt"
+	"sort"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+// Integration describes a kind of job that Kueue can manage through its
+// GenericJob implementation.
+type Integration struct {
+	// Name is the name of the integration in the configuration, such as
+	// "kubeflow.org/tfjob".
+	Name string
+	// GVK is the kind of the jobs.
+	GVK schema.GroupVersionKind
+	// NewJob returns a job backed by an empty object of the kind, to get the
+	// jobs into.
+	NewJob func() GenericJob
+	// RequiresQueueName indicates that only the jobs with a queue name are
+	// managed, even if the jobs without one are managed for the rest of the
+	// integrations, as for the Deployments, most of which aren't meant to be
+	// queued.
+	RequiresQueueName bool
+}
+
+var (
+	integrations = make(map[string]*Integration)
+	// enabledKinds are the kinds of the jobs of the enabled integrations.
+	enabledKinds = make(map[schema.GroupKind]bool)
+)
+
+// RegisterIntegration registers an integration, so that it can be enabled
+// in the configuration. It's meant to be called from the init functions of
+// the packages that implement the integrations.
+func RegisterIntegration(i Integration) error {
+	if _, ok := integrations[i.Name]; ok {
+		return fmt.Errorf("integration %s is already registered", i.Name)
+	}
+	if i.NewJob == nil {
+		return fmt.Errorf("integration %s doesn't have a NewJob function", i.Name)
+	}
+	integrations[i.Name] = &i
+	return nil
+}
+
+// GetIntegration returns the registered integration with the name.
+func GetIntegration(name string) (*Integration, bool) {
+	i, ok := integrations[name]
+	return i, ok
+}
+
+// IntegrationNames returns the names of the registered integrations, sorted.
+func IntegrationNames() []string {
+	names := make([]string, 0, len(integrations))
+	for name := range integrations {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// EnableIntegrations returns the registered integrations with the names, and
+// marks them as enabled. It fails if an integration is not registered.
+func EnableIntegrations(names []string) ([]*Integration, error) {
+	enabled := make([]*Integration, 0, len(names))
+	for _, name := range names {
+		i, ok := integrations[name]
+		if !ok {
+			return nil, fmt.Errorf("integration %s is not registered", name)
+		}
+		enabled = append(enabled, i)
+	}
+	for _, i := range enabled {
+		enabledKinds[i.GVK.GroupKind()] = true
+	}
+	return enabled, nil
+}
+
+// IsOwnerManagedByKueue returns whether the owner is a job of an enabled
+// integration. The objects owned by such a job, like the batch Jobs created
+// for it, are accounted through the workload of the job, so the controllers
+// of their own kinds must neither suspend them nor create workloads for them.
+func IsOwnerManagedByKueue(owner *metav1.OwnerReference) bool {
+	gv, err := schema.ParseGroupVersion(owner.APIVersion)
+	if err != nil {
+		return false
+	}
+	return enabledKinds[gv.WithKind(owner.Kind).GroupKind()]
+}
@@ -0,0 +1,108 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package jobframework
+
+import (
+	"testing"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+func TestRegisterIntegration(t *testing.T) {
+	defer func(saved map[string]*Integration) { integrations = saved }(integrations)
+	integrations = make(map[string]*Integration)
+
+	i := Integration{
+		Name:   "example.com/myjob",
+		GVK:    schema.GroupVersionKind{Group: "example.com", Version: "v1", Kind: "MyJob"},
+		NewJob: func() GenericJob { return nil },
+	}
+	if err := RegisterIntegration(i); err != nil {
+		t.Fatalf("Registering integration: %v", err)
+	}
+	if err := RegisterIntegration(i); err == nil {
+		t.Error("Registering the same integration twice succeeded")
+	}
+	if err := RegisterIntegration(Integration{Name: "example.com/other"}); err == nil {
+		t.Error("Registering an integration without NewJob succeeded")
+	}
+	got, ok := GetIntegration("example.com/myjob")
+	if !ok || got.GVK != i.GVK {
+		t.Errorf("GetIntegration returned %v, %t", got, ok)
+	}
+	if names := IntegrationNames(); len(names) != 1 || names[0] != "example.com/myjob" {
+		t.Errorf("IntegrationNames()=%v, want [example.com/myjob]", names)
+	}
+}
+
+func TestIsOwnerManagedByKueue(t *testing.T) {
+	defer func(saved map[string]*Integration) { integrations = saved }(integrations)
+	defer func(saved map[schema.GroupKind]bool) { enabledKinds = saved }(enabledKinds)
+	integrations = make(map[string]*Integration)
+	enabledKinds = make(map[schema.GroupKind]bool)
+
+	for _, i := range []Integration{
+		{
+			Name:   "example.com/myjob",
+			GVK:    schema.GroupVersionKind{Group: "example.com", Version: "v1", Kind: "MyJob"},
+			NewJob: func() GenericJob { return nil },
+		},
+		{
+			Name:   "example.com/disabledjob",
+			GVK:    schema.GroupVersionKind{Group: "example.com", Version: "v1", Kind: "DisabledJob"},
+			NewJob: func() GenericJob { return nil },
+		},
+	} {
+		if err := RegisterIntegration(i); err != nil {
+			t.Fatalf("Registering integration: %v", err)
+		}
+	}
+	if _, err := EnableIntegrations([]string{"example.com/unknown"}); err == nil {
+		t.Error("Enabling an integration that is not registered succeeded")
+	}
+	if _, err := EnableIntegrations([]string{"example.com/myjob"}); err != nil {
+		t.Fatalf("Enabling integration: %v", err)
+	}
+
+	cases := map[string]struct {
+		owner metav1.OwnerReference
+		want  bool
+	}{
+		"job of an enabled integration": {
+			owner: metav1.OwnerReference{APIVersion: "example.com/v1", Kind: "MyJob"},
+			want:  true,
+		},
+		"job of an enabled integration in another version": {
+			owner: metav1.OwnerReference{APIVersion: "example.com/v2", Kind: "MyJob"},
+			want:  true,
+		},
+		"job of a disabled integration": {
+			owner: metav1.OwnerReference{APIVersion: "example.com/v1", Kind: "DisabledJob"},
+		},
+		"CronJob": {
+			owner: metav1.OwnerReference{APIVersion: "batch/v1", Kind: "CronJob"},
+		},
+	}
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			if got := IsOwnerManagedByKueue(&tc.owner); got != tc.want {
+				t.Errorf("IsOwnerManagedByKueue()=%t, want %t", got, tc.want)
+			}
+		})
+	}
+}
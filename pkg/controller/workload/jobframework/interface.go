@@ -0,0 +1,163 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package jobframework
+
+import (
+	"context"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/record"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	kueue "sigs.k8s.io/kueue/apis/kueue/v1alpha2"
+)
+
+// GenericJob is the interface that a job integration implements so that its
+// jobs can be queued by Kueue through a Workload.
+// The conformance package provides tests that implementations can run to
+// verify that they behave like the built-in integrations, and JobReconciler
+// runs the jobs of an Integration through this interface.
+type GenericJob interface {
+	// Object returns the job instance.
+	Object() client.Object
+	// QueueName returns the name of the LocalQueue the job is submitted to.
+	QueueName() string
+	// IsSuspended returns whether the job is suspended.
+	IsSuspended() bool
+	// Suspend suspends the job.
+	Suspend()
+	// RunWithNodeSelectors unsuspends the job and injects the node selectors,
+	// given in the same order as the pod sets, into the pod templates.
+	RunWithNodeSelectors(nodeSelectors []map[string]string)
+	// PodSets returns the pod sets of the job, as they are set in the Workload.
+	PodSets() []kueue.PodSet
+	// EquivalentToWorkload returns whether the pod sets of the workload match
+	// the ones of the job.
+	EquivalentToWorkload(wl *kueue.Workload) bool
+	// Finished returns the Finished condition for the Workload and whether
+	// the job finished.
+	Finished() (metav1.Condition, bool)
+	// PodsReady returns whether all the pods of the job are ready or
+	// succeeded.
+	PodsReady() bool
+}
+
+// JobWithRestoreNodeSelectors is implemented by the jobs that can restore
+// the node selectors of their pod templates, from the pod sets of their
+// workload, when they are suspended after running.
+type JobWithRestoreNodeSelectors interface {
+	// RestoreNodeSelectors sets the node selectors of the pod templates back
+	// to the ones of the pod sets.
+	RestoreNodeSelectors(podSets []kueue.PodSet)
+}
+
+// JobWithReclaimablePods is implemented by the jobs that can release the
+// quota of some of their pods while they run.
+type JobWithReclaimablePods interface {
+	// ReclaimablePods returns the pods of the admitted workload whose quota
+	// is no longer needed by the job.
+	ReclaimablePods(wl *kueue.Workload) []kueue.ReclaimablePod
+}
+
+// JobWithHeldScaleUp is implemented by the jobs that can scale up while they
+// run, such as a Deployment. The pods added beyond the admitted workload are
+// held, instead of stopping the job to queue a new workload.
+type JobWithHeldScaleUp interface {
+	// HoldScaleUp scales the running job back to the pods admitted for the
+	// workload, if it scaled up beyond them, returning the pods it requested
+	// and whether it was scaled back.
+	HoldScaleUp(wl *kueue.Workload) (int32, bool)
+}
+
+// IdleJob is implemented by the jobs that can be left without pods, such as
+// a Deployment scaled to zero, while they still exist.
+type IdleJob interface {
+	// IsIdle returns whether the job runs no pods. The workloads of idle
+	// jobs are deleted.
+	IsIdle() bool
+}
+
+// JobWithPartialAdmission is implemented by the jobs that can run with fewer
+// pods than their pod sets have, when their workload is partially admitted.
+type JobWithPartialAdmission interface {
+	// RunWithPodSetCounts sets the number of pods of the pod templates to the
+	// admitted counts, given in the same order as the pod sets, before the job
+	// is unsuspended.
+	RunWithPodSetCounts(counts []int32)
+	// RestorePodSetCounts sets the number of pods of the pod templates back
+	// to the counts of the pod sets.
+	RestorePodSetCounts(podSets []kueue.PodSet)
+}
+
+// JobWithSplitPodSets is implemented by the jobs whose pod sets can be split
+// between flavors, so that their pods run on the nodes of any of them.
+type JobWithSplitPodSets interface {
+	// RunWithNodeAffinities injects the required node affinities, given in
+	// the same order as the pod sets, into the pod templates. The pod sets
+	// that aren't split get nil.
+	RunWithNodeAffinities(affinities []*corev1.NodeSelector)
+	// RestoreNodeAffinities sets the affinities of the pod templates back to
+	// the ones of the pod sets.
+	RestoreNodeAffinities(podSets []kueue.PodSet)
+}
+
+// JobWithTolerations is implemented by the jobs that run with the
+// tolerations of the pod sets of their workload, which might have more than
+// the job, such as the ones added from the LocalQueue.
+type JobWithTolerations interface {
+	// AddTolerations appends the tolerations of the pod sets that the pod
+	// templates don't have.
+	AddTolerations(podSets []kueue.PodSet)
+}
+
+// JobWithResettableStatus is implemented by the jobs whose status must be
+// reset, once they are suspended, before the scheduling directives of their
+// pod templates can be restored, such as the start time of a batch Job.
+type JobWithResettableStatus interface {
+	// ResetStatus resets the status of the suspended job, returning whether
+	// it changed.
+	ResetStatus() bool
+}
+
+// JobWithCustomWorkload is implemented by the jobs that add fields of their
+// own to their workloads, such as a deadline taken from their annotations.
+type JobWithCustomWorkload interface {
+	// CustomizeWorkload updates the workload constructed for the job, before
+	// it's created. It can also change the name of the workload.
+	CustomizeWorkload(wl *kueue.Workload) error
+}
+
+// JobWithHeldWorkload is implemented by the jobs that can't always get a
+// workload as soon as they are created, such as the Jobs of a CronJob whose
+// LocalQueue is saturated.
+type JobWithHeldWorkload interface {
+	// HoldWorkload returns whether the workload for the job must not be
+	// created yet, along with the result of the reconciliation. It can delete
+	// the job.
+	HoldWorkload(ctx context.Context, c client.Client, recorder record.EventRecorder) (bool, ctrl.Result, error)
+}
+
+// JobWithInvalidWorkload is implemented by the jobs that can keep running
+// with their admitted workload, marked invalid, when their pod templates
+// change, instead of being suspended to get a new one.
+type JobWithInvalidWorkload interface {
+	// KeepInvalidWorkload returns whether the running job keeps the admitted
+	// workload that is no longer equivalent to it.
+	KeepInvalidWorkload(wl *kueue.Workload) bool
+}
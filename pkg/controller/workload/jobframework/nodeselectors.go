@@ -0,0 +1,156 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package jobframework
+
+import (
+	"context"
+	"fmt"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	kueue "sigs.k8s.io/kueue/apis/kueue/v1alpha2"
+)
+
+// NodeSelectors returns the node selectors to inject in the pod sets of a job,
+// in the same order as the pod sets of the admission, for
+// GenericJob.RunWithNodeSelectors. The node selector of a pod set merges the
+// node labels of all the flavors assigned to its resources. It fails if two of
+// the flavors require different values for the same label.
+// For a pod set split between flavors, the node selector only has the labels
+// that all the parts require with the same value. The rest are given by
+// SplitNodeAffinities.
+func NodeSelectors(ctx context.Context, c client.Client, admission *kueue.Admission) ([]map[string]string, error) {
+	flavors := make(map[string]*kueue.ResourceFlavor)
+	nodeSelectors := make([]map[string]string, len(admission.PodSetFlavors))
+	for i := range admission.PodSetFlavors {
+		ps := &admission.PodSetFlavors[i]
+		labels, err := nodeLabels(ctx, c, flavors, ps.Name, ps.Flavors)
+		if err != nil {
+			return nil, err
+		}
+		for _, split := range ps.Splits {
+			splitLabels, err := nodeLabels(ctx, c, flavors, ps.Name, split.Flavors)
+			if err != nil {
+				return nil, err
+			}
+			for k, v := range labels {
+				if splitLabels[k] != v {
+					delete(labels, k)
+				}
+			}
+		}
+		if len(labels) > 0 {
+			nodeSelectors[i] = labels
+		}
+	}
+	return nodeSelectors, nil
+}
+
+// SplitNodeAffinities returns the required node affinities to inject in the
+// pod sets of a job, in the same order as the pod sets of the admission. For
+// a pod set split between flavors, the node affinity has one term for each
+// part, matching the node labels of its flavors, so that the pods can run on
+// the nodes of any of them. The pod sets that aren't split get nil.
+func SplitNodeAffinities(ctx context.Context, c client.Client, admission *kueue.Admission) ([]*corev1.NodeSelector, error) {
+	flavors := make(map[string]*kueue.ResourceFlavor)
+	affinities := make([]*corev1.NodeSelector, len(admission.PodSetFlavors))
+	for i := range admission.PodSetFlavors {
+		ps := &admission.PodSetFlavors[i]
+		if len(ps.Splits) == 0 {
+			continue
+		}
+		parts := make([]map[corev1.ResourceName]string, 0, len(ps.Splits)+1)
+		parts = append(parts, ps.Flavors)
+		for _, split := range ps.Splits {
+			parts = append(parts, split.Flavors)
+		}
+		affinity := &corev1.NodeSelector{}
+		for _, part := range parts {
+			labels, err := nodeLabels(ctx, c, flavors, ps.Name, part)
+			if err != nil {
+				return nil, err
+			}
+			var term corev1.NodeSelectorTerm
+			for _, k := range sets.StringKeySet(labels).List() {
+				term.MatchExpressions = append(term.MatchExpressions, corev1.NodeSelectorRequirement{
+					Key:      k,
+					Operator: corev1.NodeSelectorOpIn,
+					Values:   []string{labels[k]},
+				})
+			}
+			affinity.NodeSelectorTerms = append(affinity.NodeSelectorTerms, term)
+		}
+		affinities[i] = affinity
+	}
+	return affinities, nil
+}
+
+// PodSetPartsNodeLabels returns the node labels that the pods of each part
+// of an admitted pod set must match: first the part admitted with the
+// flavors of the pod set, followed by its splits.
+func PodSetPartsNodeLabels(ctx context.Context, c client.Client, ps *kueue.PodSetFlavors) ([]map[string]string, error) {
+	flavors := make(map[string]*kueue.ResourceFlavor)
+	parts := make([]map[corev1.ResourceName]string, 0, len(ps.Splits)+1)
+	parts = append(parts, ps.Flavors)
+	for _, split := range ps.Splits {
+		parts = append(parts, split.Flavors)
+	}
+	partsLabels := make([]map[string]string, 0, len(parts))
+	for _, part := range parts {
+		labels, err := nodeLabels(ctx, c, flavors, ps.Name, part)
+		if err != nil {
+			return nil, err
+		}
+		partsLabels = append(partsLabels, labels)
+	}
+	return partsLabels, nil
+}
+
+// nodeLabels merges the node labels of the flavors assigned to the resources
+// of a pod set, getting the flavors that aren't in the given cache. It fails
+// if two of the flavors require different values for the same label.
+func nodeLabels(ctx context.Context, c client.Client, flavors map[string]*kueue.ResourceFlavor, podSet string, assigned map[corev1.ResourceName]string) (map[string]string, error) {
+	names := sets.NewString()
+	for _, name := range assigned {
+		names.Insert(name)
+	}
+	labels := make(map[string]string)
+	// The flavor that first required each label, to report conflicts.
+	labelFlavors := make(map[string]string)
+	for _, name := range names.List() {
+		if _, ok := flavors[name]; !ok {
+			flv := kueue.ResourceFlavor{}
+			if err := c.Get(ctx, types.NamespacedName{Name: name}, &flv); err != nil {
+				return nil, err
+			}
+			flavors[name] = &flv
+		}
+		for k, v := range flavors[name].NodeSelector {
+			if prev, ok := labels[k]; ok && prev != v {
+				return nil, fmt.Errorf("flavors %s and %s of pod set %s require different values for the node label %s", labelFlavors[k], name, podSet, k)
+			}
+			if _, ok := labelFlavors[k]; !ok {
+				labels[k] = v
+				labelFlavors[k] = name
+			}
+		}
+	}
+	return labels, nil
+}
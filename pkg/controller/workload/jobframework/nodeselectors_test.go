@@ -0,0 +1,165 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package jobframework
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	kueue "sigs.k8s.io/kueue/apis/kueue/v1alpha2"
+	utiltesting "sigs.k8s.io/kueue/pkg/util/testing"
+)
+
+func TestNodeSelectors(t *testing.T) {
+	scheme := runtime.NewScheme()
+	if err := kueue.AddToScheme(scheme); err != nil {
+		t.Fatalf("Failed adding kueue scheme: %v", err)
+	}
+	cl := fake.NewClientBuilder().WithScheme(scheme).WithObjects(
+		utiltesting.MakeResourceFlavor("on-demand").Label("instance", "on-demand").Label("zone", "a").Obj(),
+		utiltesting.MakeResourceFlavor("a100").Label("gpu", "a100").Label("zone", "a").Obj(),
+		utiltesting.MakeResourceFlavor("zone-b").Label("zone", "b").Obj(),
+		utiltesting.MakeResourceFlavor("default").Obj(),
+	).Build()
+
+	cases := map[string]struct {
+		admission *kueue.Admission
+		want      []map[string]string
+		wantErr   bool
+	}{
+		"no flavors": {
+			admission: utiltesting.MakeAdmission("cq").Obj(),
+			want:      []map[string]string{nil},
+		},
+		"flavor without labels": {
+			admission: utiltesting.MakeAdmission("cq").Flavor(corev1.ResourceCPU, "default").Obj(),
+			want:      []map[string]string{nil},
+		},
+		"one flavor for all the resources": {
+			admission: utiltesting.MakeAdmission("cq").
+				Flavor(corev1.ResourceCPU, "on-demand").
+				Flavor(corev1.ResourceMemory, "on-demand").
+				Obj(),
+			want: []map[string]string{{"instance": "on-demand", "zone": "a"}},
+		},
+		"a flavor for each resource group": {
+			admission: utiltesting.MakeAdmission("cq").
+				Flavor(corev1.ResourceCPU, "on-demand").
+				Flavor("nvidia.com/gpu", "a100").
+				Obj(),
+			want: []map[string]string{{"instance": "on-demand", "gpu": "a100", "zone": "a"}},
+		},
+		"flavors with conflicting labels": {
+			admission: utiltesting.MakeAdmission("cq").
+				Flavor(corev1.ResourceCPU, "on-demand").
+				Flavor("nvidia.com/gpu", "zone-b").
+				Obj(),
+			wantErr: true,
+		},
+		"several pod sets": {
+			admission: &kueue.Admission{
+				ClusterQueue: "cq",
+				PodSetFlavors: []kueue.PodSetFlavors{
+					{Name: "driver", Flavors: map[corev1.ResourceName]string{corev1.ResourceCPU: "on-demand"}},
+					{Name: "workers", Flavors: map[corev1.ResourceName]string{corev1.ResourceCPU: "zone-b"}},
+				},
+			},
+			want: []map[string]string{
+				{"instance": "on-demand", "zone": "a"},
+				{"zone": "b"},
+			},
+		},
+		"flavor not found": {
+			admission: utiltesting.MakeAdmission("cq").Flavor(corev1.ResourceCPU, "spot").Obj(),
+			wantErr:   true,
+		},
+		"pod set split between flavors keeps the common labels": {
+			admission: utiltesting.MakeAdmission("cq").
+				Flavor(corev1.ResourceCPU, "on-demand").
+				Count(6).
+				Split(corev1.ResourceCPU, "a100", 4).
+				Obj(),
+			want: []map[string]string{{"zone": "a"}},
+		},
+	}
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			got, err := NodeSelectors(context.Background(), cl, tc.admission)
+			if gotErr := err != nil; gotErr != tc.wantErr {
+				t.Fatalf("NodeSelectors() returned error %v, want error %t", err, tc.wantErr)
+			}
+			if diff := cmp.Diff(tc.want, got); diff != "" {
+				t.Errorf("Unexpected node selectors (-want,+got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestSplitNodeAffinities(t *testing.T) {
+	scheme := runtime.NewScheme()
+	if err := kueue.AddToScheme(scheme); err != nil {
+		t.Fatalf("Failed adding kueue scheme: %v", err)
+	}
+	cl := fake.NewClientBuilder().WithScheme(scheme).WithObjects(
+		utiltesting.MakeResourceFlavor("spot").Label("instance", "spot").Label("zone", "a").Obj(),
+		utiltesting.MakeResourceFlavor("on-demand").Label("instance", "on-demand").Obj(),
+	).Build()
+
+	cases := map[string]struct {
+		admission *kueue.Admission
+		want      []*corev1.NodeSelector
+	}{
+		"not split": {
+			admission: utiltesting.MakeAdmission("cq").Flavor(corev1.ResourceCPU, "spot").Obj(),
+			want:      []*corev1.NodeSelector{nil},
+		},
+		"split between two flavors": {
+			admission: utiltesting.MakeAdmission("cq").
+				Flavor(corev1.ResourceCPU, "spot").
+				Count(6).
+				Split(corev1.ResourceCPU, "on-demand", 4).
+				Obj(),
+			want: []*corev1.NodeSelector{{
+				NodeSelectorTerms: []corev1.NodeSelectorTerm{
+					{MatchExpressions: []corev1.NodeSelectorRequirement{
+						{Key: "instance", Operator: corev1.NodeSelectorOpIn, Values: []string{"spot"}},
+						{Key: "zone", Operator: corev1.NodeSelectorOpIn, Values: []string{"a"}},
+					}},
+					{MatchExpressions: []corev1.NodeSelectorRequirement{
+						{Key: "instance", Operator: corev1.NodeSelectorOpIn, Values: []string{"on-demand"}},
+					}},
+				},
+			}},
+		},
+	}
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			got, err := SplitNodeAffinities(context.Background(), cl, tc.admission)
+			if err != nil {
+				t.Fatalf("SplitNodeAffinities() returned error %v", err)
+			}
+			if diff := cmp.Diff(tc.want, got); diff != "" {
+				t.Errorf("Unexpected node affinities (-want,+got):\n%s", diff)
+			}
+		})
+	}
+}
@@ -0,0 +1,784 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package jobframework
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	apimeta "k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
+	"k8s.io/klog/v2"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	config "sigs.k8s.io/kueue/apis/config/v1alpha2"
+	kueue "sigs.k8s.io/kueue/apis/kueue/v1alpha2"
+	"sigs.k8s.io/kueue/pkg/constants"
+	utilpriority "sigs.k8s.io/kueue/pkg/util/priority"
+	"sigs.k8s.io/kueue/pkg/workload"
+)
+
+// JobReconciler reconciles the jobs of an integration through their
+// GenericJob implementation. It creates a workload for each job, keeps the
+// job suspended until the workload is admitted and then runs it with the
+// node selectors of the assigned flavors.
+type JobReconciler struct {
+	client                     client.Client
+	scheme                     *runtime.Scheme
+	record                     record.EventRecorder
+	integration                *Integration
+	manageJobsWithoutQueueName bool
+	waitForPodsReady           bool
+	reportSuspendMismatchOnly  bool
+	prioritySources            []config.PrioritySource
+}
+
+type options struct {
+	manageJobsWithoutQueueName bool
+	waitForPodsReady           bool
+	reportSuspendMismatchOnly  bool
+	prioritySources            []config.PrioritySource
+}
+
+// Option configures the reconciler and the webhook.
+type Option func(*options)
+
+// WithManageJobsWithoutQueueName indicates if the controller should reconcile
+// jobs that don't set the queue name annotation.
+func WithManageJobsWithoutQueueName(f bool) Option {
+	return func(o *options) {
+		o.manageJobsWithoutQueueName = f
+	}
+}
+
+// WithWaitForPodsReady indicates if the controller should add the PodsReady
+// condition to the workload when the corresponding job has all pods ready
+// or succeeded.
+func WithWaitForPodsReady(f bool) Option {
+	return func(o *options) {
+		o.waitForPodsReady = f
+	}
+}
+
+// WithReportSuspendMismatchOnly indicates if the controller should only
+// record a warning event, instead of suspending, for running jobs whose
+// workload is not admitted.
+func WithReportSuspendMismatchOnly(f bool) Option {
+	return func(o *options) {
+		o.reportSuspendMismatchOnly = f
+	}
+}
+
+// WithPrioritySources sets the sources, in order of precedence, from which
+// the PriorityClass of the workloads is taken. By default, it's taken from
+// the priority class label of the job or from its pod sets.
+func WithPrioritySources(sources []config.PrioritySource) Option {
+	return func(o *options) {
+		o.prioritySources = sources
+	}
+}
+
+func NewReconciler(
+	scheme *runtime.Scheme,
+	client client.Client,
+	record record.EventRecorder,
+	integration *Integration,
+	opts ...Option) *JobReconciler {
+
+	var options options
+	for _, opt := range opts {
+		opt(&options)
+	}
+
+	return &JobReconciler{
+		scheme:                     scheme,
+		client:                     client,
+		record:                     record,
+		integration:                integration,
+		manageJobsWithoutQueueName: options.manageJobsWithoutQueueName,
+		waitForPodsReady:           options.waitForPodsReady,
+		reportSuspendMismatchOnly:  options.reportSuspendMismatchOnly,
+		prioritySources:            options.prioritySources,
+	}
+}
+
+// SetupWithManager sets up the controller with the Manager.
+func (r *JobReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewControllerManagedBy(mgr).
+		Named(strings.ToLower(r.integration.GVK.Kind)).
+		For(r.integration.NewJob().Object()).
+		Owns(&kueue.Workload{}).
+		Complete(r)
+}
+
+// SetupIndexes indexes the workloads based on the owning jobs of each of the
+// integrations.
+func SetupIndexes(indexer client.FieldIndexer, integrations []*Integration) error {
+	for _, i := range integrations {
+		gvk := i.GVK
+		err := indexer.IndexField(context.Background(), &kueue.Workload{}, OwnerKey(gvk), func(o client.Object) []string {
+			wl := o.(*kueue.Workload)
+			owner := metav1.GetControllerOf(wl)
+			if owner == nil || owner.APIVersion != gvk.GroupVersion().String() || owner.Kind != gvk.Kind {
+				return nil
+			}
+			return []string{owner.Name}
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// OwnerKey returns the key of the index of the workloads by the name of
+// their owning job of the kind.
+func OwnerKey(gvk schema.GroupVersionKind) string {
+	return fmt.Sprintf(".metadata.controller.%s.%s", gvk.Group, gvk.Kind)
+}
+
+func (r *JobReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	job := r.integration.NewJob()
+	obj := job.Object()
+	if err := r.client.Get(ctx, req.NamespacedName, obj); err != nil {
+		// we'll ignore not-found errors, since there is nothing to do.
+		return ctrl.Result{}, client.IgnoreNotFound(err)
+	}
+
+	log := ctrl.LoggerFrom(ctx).WithValues(strings.ToLower(r.integration.GVK.Kind), klog.KObj(obj))
+	ctx = ctrl.LoggerInto(ctx, log)
+	if owner := metav1.GetControllerOf(obj); owner != nil && IsOwnerManagedByKueue(owner) {
+		log.V(3).Info("The job is owned by a job managed by Kueue, ignoring it")
+		return ctrl.Result{}, nil
+	}
+	if job.QueueName() == "" && (!r.manageJobsWithoutQueueName || r.integration.RequiresQueueName) {
+		log.V(3).Info(fmt.Sprintf("%s annotation is not set, ignoring the job", constants.QueueAnnotation))
+		return ctrl.Result{}, nil
+	}
+
+	log.V(2).Info("Reconciling job")
+
+	var childWorkloads kueue.WorkloadList
+	if err := r.client.List(ctx, &childWorkloads, client.InNamespace(req.Namespace),
+		client.MatchingFields{OwnerKey(r.integration.GVK): req.Name}); err != nil {
+		log.Error(err, "Unable to list child workloads")
+		return ctrl.Result{}, err
+	}
+
+	// An idle job doesn't need quota.
+	if idle, ok := job.(IdleJob); ok && idle.IsIdle() {
+		log.V(3).Info("Job is idle, releasing its workloads")
+		workloads := make([]*kueue.Workload, len(childWorkloads.Items))
+		for i := range childWorkloads.Items {
+			workloads[i] = &childWorkloads.Items[i]
+		}
+		if r.deleteWorkloads(ctx, job, workloads, "Deleted not matching Workload: %v") != 0 {
+			return ctrl.Result{}, fmt.Errorf("deleting the workloads of an idle job")
+		}
+		return ctrl.Result{}, nil
+	}
+
+	// 1. make sure there is only a single existing instance of the workload
+	wl, err := r.ensureAtMostOneWorkload(ctx, job, childWorkloads)
+	if err != nil {
+		log.Error(err, "Getting existing workloads")
+		return ctrl.Result{}, err
+	}
+	if wl != nil && workload.HasDeprecatedAdmission(wl) {
+		// The workload controller moves the admission to the status; the job
+		// is reconciled again when the workload is updated.
+		log.V(2).Info("Waiting for the admission of the workload to be moved to its status")
+		return ctrl.Result{}, nil
+	}
+	// The priority source is set in the status after the workload is created,
+	// so it's missing if that update failed.
+	if wl != nil && len(wl.Status.PrioritySource) == 0 {
+		source, err := r.prioritySource(ctx, job)
+		if err != nil {
+			log.Error(err, "Getting the priority source of the workload")
+			return ctrl.Result{}, err
+		}
+		log.V(2).Info("Setting the missing priority source of the workload", "prioritySource", source)
+		wl.Status.PrioritySource = source
+		err = r.client.Status().Update(ctx, wl)
+		if err != nil {
+			log.Error(err, "Updating the priority source of the workload")
+		}
+		return ctrl.Result{}, err
+	}
+
+	finishedCond, jobFinished := job.Finished()
+	// 2. create new workload if none exists
+	if wl == nil {
+		// Nothing to do if the job is finished
+		if jobFinished {
+			return ctrl.Result{}, nil
+		}
+		if holder, ok := job.(JobWithHeldWorkload); ok {
+			if held, result, err := holder.HoldWorkload(ctx, r.client, r.record); held || err != nil {
+				if err != nil {
+					log.Error(err, "Holding the workload of the job")
+				}
+				return result, err
+			}
+		}
+		err := r.handleJobWithNoWorkload(ctx, job)
+		if err != nil {
+			log.Error(err, "Handling job with no workload")
+		}
+		return ctrl.Result{}, err
+	}
+
+	// 3. handle a finished job
+	if jobFinished {
+		if apimeta.IsStatusConditionTrue(wl.Status.Conditions, kueue.WorkloadFinished) {
+			return ctrl.Result{}, nil
+		}
+		apimeta.SetStatusCondition(&wl.Status.Conditions, finishedCond)
+		err := r.client.Status().Update(ctx, wl)
+		if err != nil {
+			log.Error(err, "Updating workload status")
+		}
+		return ctrl.Result{}, err
+	}
+
+	// handle a job when waitForPodsReady is enabled
+	if r.waitForPodsReady {
+		condition := PodsReadyCondition(job.PodsReady(), wl)
+		// optimization to avoid sending the update request if the status didn't change
+		if !apimeta.IsStatusConditionPresentAndEqual(wl.Status.Conditions, condition.Type, condition.Status) {
+			log.V(3).Info(fmt.Sprintf("Updating the PodsReady condition with status: %v", condition.Status))
+			apimeta.SetStatusCondition(&wl.Status.Conditions, condition)
+			if err := r.client.Status().Update(ctx, wl); err != nil {
+				log.Error(err, "Updating workload status")
+			}
+		}
+	}
+
+	// 4. Handle a not finished job
+	if job.IsSuspended() {
+		// start the job if the workload has been admitted, and the job is still suspended.
+		// A workload with reserved quota waits for its admission checks.
+		if workload.IsAdmitted(wl) {
+			log.V(2).Info("Job admitted, unsuspending")
+			err := r.startJob(ctx, wl, job)
+			if err != nil {
+				log.Error(err, "Unsuspending job")
+			}
+			return ctrl.Result{}, err
+		}
+
+		// update queue name if changed.
+		if q := job.QueueName(); wl.Spec.QueueName != q {
+			log.V(2).Info("Job changed queues, updating workload")
+			wl.Spec.QueueName = q
+			err := r.client.Update(ctx, wl)
+			if err != nil {
+				log.Error(err, "Updating workload queue")
+			}
+			return ctrl.Result{}, err
+		}
+		log.V(3).Info("Job is suspended and workload not yet admitted by a clusterQueue, nothing to do")
+		return ctrl.Result{}, nil
+	}
+
+	if wl.Status.Admission == nil {
+		// The jobs of evicted workloads are always stopped, so that they
+		// release the resources of their pods.
+		if r.reportSuspendMismatchOnly && !workload.IsEvicted(wl) {
+			log.V(2).Info("Running job is not admitted by a cluster queue, reporting")
+			r.record.Eventf(obj, corev1.EventTypeWarning, "NotAdmitted", "Job is running but its workload is not admitted by a cluster queue")
+			return ctrl.Result{}, nil
+		}
+		// the job must be suspended if the workload is not yet admitted.
+		log.V(2).Info("Running job is not admitted by a cluster queue, suspending")
+		err := r.stopJob(ctx, wl, job, NotAdmittedMessage(wl))
+		if err != nil {
+			log.Error(err, "Suspending job with non admitted workload")
+		}
+		return ctrl.Result{}, err
+	}
+
+	// Hold the pods added beyond the admission of the running job.
+	if holder, ok := job.(JobWithHeldScaleUp); ok {
+		if requested, held := holder.HoldScaleUp(wl); held {
+			log.V(2).Info("Job scaled up beyond its admitted workload, holding the new pods", "requested", requested)
+			if err := r.client.Update(ctx, obj); err != nil {
+				log.Error(err, "Holding the scale up of the job")
+				return ctrl.Result{}, err
+			}
+			r.record.Eventf(obj, corev1.EventTypeNormal, "ScaleUpHeld",
+				"Scaled back to the pods admitted by clusterQueue %v, %d requested", wl.Status.Admission.ClusterQueue, requested)
+			return ctrl.Result{}, nil
+		}
+	}
+
+	// Release the quota of the pods that are no longer needed.
+	if jobWithReclaimable, ok := job.(JobWithReclaimablePods); ok {
+		if reclaimable := jobWithReclaimable.ReclaimablePods(wl); !equality.Semantic.DeepEqual(reclaimable, wl.Status.ReclaimablePods) {
+			log.V(3).Info("Updating the reclaimable pods of the workload", "reclaimablePods", reclaimable)
+			wl.Status.ReclaimablePods = reclaimable
+			err := r.client.Status().Update(ctx, wl)
+			if err != nil {
+				log.Error(err, "Updating workload reclaimable pods")
+			}
+			return ctrl.Result{}, err
+		}
+	}
+
+	// workload is admitted and job is running, nothing to do.
+	log.V(3).Info("Job running with admitted workload, nothing to do")
+	return ctrl.Result{}, nil
+}
+
+// NotAdmittedMessage returns the message of the event of a running job that is
+// stopped because its workload isn't admitted, with the reason of the eviction
+// of the workload, if it was evicted.
+func NotAdmittedMessage(wl *kueue.Workload) string {
+	if reason := workload.EvictionReason(wl); reason != "" {
+		return fmt.Sprintf("Workload evicted: %s", reason)
+	}
+	return "Not admitted by cluster queue"
+}
+
+// stopJob suspends the job and restores the parts of its pod templates that
+// were changed to run it, as far as it supports it, from the pod sets of the
+// workload, so that they can be changed again when the job is admitted again.
+func (r *JobReconciler) stopJob(ctx context.Context, w *kueue.Workload, job GenericJob, eventMsg string) error {
+	obj := job.Object()
+	job.Suspend()
+	// Restore the counts reduced by a partial admission.
+	if partial, ok := job.(JobWithPartialAdmission); ok && w != nil {
+		partial.RestorePodSetCounts(w.Spec.PodSets)
+	}
+	resettable, ok := job.(JobWithResettableStatus)
+	if !ok {
+		restoreSchedulingDirectives(job, w)
+		if err := r.client.Update(ctx, obj); err != nil {
+			return err
+		}
+		r.record.Eventf(obj, corev1.EventTypeNormal, "Stopped", eventMsg)
+		return nil
+	}
+
+	// The scheduling directives can only be restored once the job is
+	// suspended and its status is reset.
+	if err := r.client.Update(ctx, obj); err != nil {
+		return err
+	}
+	r.record.Eventf(obj, corev1.EventTypeNormal, "Stopped", eventMsg)
+	if resettable.ResetStatus() {
+		if err := r.client.Status().Update(ctx, obj); err != nil {
+			return err
+		}
+	}
+	if w == nil {
+		return nil
+	}
+	suspended := obj.DeepCopyObject()
+	restoreSchedulingDirectives(job, w)
+	if equality.Semantic.DeepEqual(suspended, obj) {
+		return nil
+	}
+	return r.client.Update(ctx, obj)
+}
+
+// restoreSchedulingDirectives sets the node selectors and the node affinities
+// of the pod templates back to the ones of the pod sets of the workload, if
+// the job supports it.
+func restoreSchedulingDirectives(job GenericJob, w *kueue.Workload) {
+	if w == nil {
+		return
+	}
+	if restorer, ok := job.(JobWithRestoreNodeSelectors); ok {
+		restorer.RestoreNodeSelectors(w.Spec.PodSets)
+	}
+	if split, ok := job.(JobWithSplitPodSets); ok {
+		split.RestoreNodeAffinities(w.Spec.PodSets)
+	}
+}
+
+func (r *JobReconciler) startJob(ctx context.Context, w *kueue.Workload, job GenericJob) error {
+	if podSets := job.PodSets(); len(w.Spec.PodSets) != len(podSets) {
+		return fmt.Errorf("%d podsets must exist, found %d", len(podSets), len(w.Spec.PodSets))
+	}
+	nodeSelectors, err := NodeSelectors(ctx, r.client, w.Status.Admission)
+	if err != nil {
+		return err
+	}
+	if split, ok := job.(JobWithSplitPodSets); ok {
+		affinities, err := SplitNodeAffinities(ctx, r.client, w.Status.Admission)
+		if err != nil {
+			return err
+		}
+		split.RunWithNodeAffinities(affinities)
+	}
+	if partial, ok := job.(JobWithPartialAdmission); ok {
+		partial.RunWithPodSetCounts(admittedCounts(w))
+	}
+	if withTolerations, ok := job.(JobWithTolerations); ok {
+		withTolerations.AddTolerations(w.Spec.PodSets)
+	}
+	job.RunWithNodeSelectors(nodeSelectors)
+	if err := r.client.Update(ctx, job.Object()); err != nil {
+		return err
+	}
+
+	r.record.Eventf(job.Object(), corev1.EventTypeNormal, "Started",
+		"Admitted by clusterQueue %v", w.Status.Admission.ClusterQueue)
+	return nil
+}
+
+// admittedCounts returns the number of admitted pods of each of the pod sets
+// of the workload, which might be partially admitted with fewer pods. The
+// counts of a split pod set add up to all its pods.
+func admittedCounts(w *kueue.Workload) []int32 {
+	counts := make([]int32, len(w.Spec.PodSets))
+	for i := range w.Spec.PodSets {
+		counts[i] = w.Spec.PodSets[i].Count
+		if i >= len(w.Status.Admission.PodSetFlavors) {
+			continue
+		}
+		if psFlavors := &w.Status.Admission.PodSetFlavors[i]; psFlavors.Count != nil && len(psFlavors.Splits) == 0 {
+			counts[i] = *psFlavors.Count
+		}
+	}
+	return counts
+}
+
+func (r *JobReconciler) handleJobWithNoWorkload(ctx context.Context, job GenericJob) error {
+	wl, err := ConstructWorkloadWithPrioritySources(ctx, r.client, job, r.integration.GVK, r.scheme, r.prioritySources)
+	if err != nil {
+		return err
+	}
+	prioritySource := wl.Status.PrioritySource
+	if err = r.client.Create(ctx, wl); err != nil {
+		return err
+	}
+	// The status is not persisted on creation.
+	wl.Status.PrioritySource = prioritySource
+	if err = r.client.Status().Update(ctx, wl); err != nil {
+		return err
+	}
+
+	r.record.Eventf(job.Object(), corev1.EventTypeNormal, "CreatedWorkload",
+		"Created Workload: %v", workload.Key(wl))
+	return nil
+}
+
+// ensureAtMostOneWorkload finds a matching workload and deletes redundant ones.
+// When several workloads match the job, the authoritative one is kept, so that
+// the quota of the job isn't counted more than once.
+func (r *JobReconciler) ensureAtMostOneWorkload(ctx context.Context, job GenericJob, workloads kueue.WorkloadList) (*kueue.Workload, error) {
+	log := ctrl.LoggerFrom(ctx)
+	obj := job.Object()
+
+	// Find a matching workload first if there is one.
+	var toDelete, duplicates, stale []*kueue.Workload
+	var match *kueue.Workload
+	for i := range workloads.Items {
+		w := &workloads.Items[i]
+		owner := metav1.GetControllerOf(w)
+		// Indexes don't work in unit tests, so we explicitly check for the
+		// owner here.
+		if owner == nil || owner.Name != obj.GetName() {
+			continue
+		}
+		// The workloads of a previous job with the same name are never
+		// reused.
+		if owner.UID != obj.GetUID() {
+			stale = append(stale, w)
+			continue
+		}
+		if !job.EquivalentToWorkload(w) {
+			toDelete = append(toDelete, w)
+			continue
+		}
+		if match == nil {
+			match = w
+		} else if isAuthoritativeWorkload(w, match) {
+			duplicates = append(duplicates, match)
+			match = w
+		} else {
+			duplicates = append(duplicates, w)
+		}
+	}
+
+	// The admitted workload of a running job whose pod templates changed can
+	// be kept, so that the running pods aren't stopped, but it's marked
+	// invalid. A suspended job gets a new workload instead.
+	if match == nil && !job.IsSuspended() && len(toDelete) == 1 {
+		w := toDelete[0]
+		if keeper, ok := job.(JobWithInvalidWorkload); ok && w.Status.Admission != nil && keeper.KeepInvalidWorkload(w) {
+			return w, r.setInvalidCondition(ctx, job, w, true)
+		}
+	}
+	if match != nil {
+		if err := r.setInvalidCondition(ctx, job, match, false); err != nil {
+			return nil, err
+		}
+	}
+
+	// If there is no matching workload and the job is running, suspend it.
+	if match == nil && !job.IsSuspended() {
+		log.V(2).Info("job with no matching workload, suspending")
+		var w *kueue.Workload
+		if len(workloads.Items) == 1 {
+			// The job may have been modified and hence the existing workload
+			// doesn't match the job anymore. All bets are off if there are more
+			// than one workload...
+			w = &workloads.Items[0]
+		}
+		if err := r.stopJob(ctx, w, job, "No matching Workload"); err != nil {
+			log.Error(err, "stopping job")
+		}
+	}
+
+	// Delete duplicate workload instances.
+	existedWls := r.deleteWorkloads(ctx, job, duplicates, "Deleted duplicate Workload: %v")
+	existedWls += r.deleteWorkloads(ctx, job, append(toDelete, stale...), "Deleted not matching Workload: %v")
+	if existedWls != 0 {
+		if match == nil {
+			return nil, fmt.Errorf("no matching workload was found, tried deleting %d existing workload(s)", existedWls)
+		}
+		return nil, fmt.Errorf("only one workload should exist, found %d", len(workloads.Items))
+	}
+
+	return match, nil
+}
+
+// deleteWorkloads deletes the workloads of the job, recording an event with
+// the message for each of them. It returns how many of them still existed.
+func (r *JobReconciler) deleteWorkloads(ctx context.Context, job GenericJob, workloads []*kueue.Workload, msg string) int {
+	existedWls := 0
+	for _, w := range workloads {
+		err := r.client.Delete(ctx, w)
+		if apierrors.IsNotFound(err) {
+			continue
+		}
+		existedWls++
+		if err != nil {
+			ctrl.LoggerFrom(ctx).Error(err, "Failed to delete workload")
+			continue
+		}
+		r.record.Eventf(job.Object(), corev1.EventTypeNormal, "DeletedWorkload", msg, workload.Key(w))
+	}
+	return existedWls
+}
+
+// isAuthoritativeWorkload returns whether the workload w should be kept over
+// the workload current, when both match the job. An admitted workload is
+// preferred, so that the job keeps its quota, and then the oldest one.
+func isAuthoritativeWorkload(w, current *kueue.Workload) bool {
+	if admitted := w.Status.Admission != nil; admitted != (current.Status.Admission != nil) {
+		return admitted
+	}
+	if !w.CreationTimestamp.Equal(&current.CreationTimestamp) {
+		return w.CreationTimestamp.Before(&current.CreationTimestamp)
+	}
+	return w.Name < current.Name
+}
+
+// setInvalidCondition sets the InvalidWorkload condition of the workload,
+// or removes it if the workload is not invalid.
+func (r *JobReconciler) setInvalidCondition(ctx context.Context, job GenericJob, w *kueue.Workload, invalid bool) error {
+	if !invalid {
+		if !apimeta.IsStatusConditionTrue(w.Status.Conditions, kueue.WorkloadInvalid) {
+			return nil
+		}
+		apimeta.RemoveStatusCondition(&w.Status.Conditions, kueue.WorkloadInvalid)
+		return r.client.Status().Update(ctx, w)
+	}
+	if apimeta.IsStatusConditionTrue(w.Status.Conditions, kueue.WorkloadInvalid) {
+		return nil
+	}
+	ctrl.LoggerFrom(ctx).V(2).Info("Pod template of the running job changed, marking its workload as invalid", "workload", klog.KObj(w))
+	apimeta.SetStatusCondition(&w.Status.Conditions, metav1.Condition{
+		Type:    kueue.WorkloadInvalid,
+		Status:  metav1.ConditionTrue,
+		Reason:  "PodTemplateChanged",
+		Message: "The pod template of the job changed after the workload was admitted",
+	})
+	if err := r.client.Status().Update(ctx, w); err != nil {
+		return err
+	}
+	r.record.Eventf(job.Object(), corev1.EventTypeWarning, "PodTemplateChanged",
+		"The pod template changed after workload %s was admitted", workload.Key(w))
+	return nil
+}
+
+// ConstructWorkload returns the workload for the job of the kind. The
+// priority is taken from the WorkloadPriorityClass named by the workload
+// priority class label of the job. Otherwise, the PriorityClass is taken from
+// the priority class label of the job or, if not set, from the first pod set
+// that sets one.
+func ConstructWorkload(ctx context.Context, client client.Client, job GenericJob, gvk schema.GroupVersionKind, scheme *runtime.Scheme) (*kueue.Workload, error) {
+	return ConstructWorkloadWithPrioritySources(ctx, client, job, gvk, scheme, nil)
+}
+
+// ConstructWorkloadWithPrioritySources is like ConstructWorkload, but the
+// PriorityClass is taken from the first of the prioritySources that provides
+// one, which is recorded as the priority source in the status of the
+// workload. If prioritySources is nil, the sources of ConstructWorkload are
+// used.
+func ConstructWorkloadWithPrioritySources(ctx context.Context, client client.Client, job GenericJob,
+	gvk schema.GroupVersionKind, scheme *runtime.Scheme, prioritySources []config.PrioritySource) (*kueue.Workload, error) {
+	obj := job.Object()
+	w := &kueue.Workload{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      WorkloadName(gvk, obj.GetName()),
+			Namespace: obj.GetNamespace(),
+		},
+		Spec: kueue.WorkloadSpec{
+			PodSets:   job.PodSets(),
+			QueueName: job.QueueName(),
+			ManagedBy: obj.GetAnnotations()[constants.ManagedByAnnotation],
+		},
+	}
+
+	if wpcName := obj.GetLabels()[constants.WorkloadPriorityClassLabel]; len(wpcName) != 0 {
+		p, err := utilpriority.GetPriorityFromWorkloadPriorityClass(ctx, client, wpcName)
+		if err != nil {
+			return nil, err
+		}
+		w.Spec.Priority = &p
+		w.Spec.PriorityClassName = wpcName
+		w.Spec.PriorityClassSource = kueue.WorkloadPriorityClassSource
+		w.Status.PrioritySource = prioritySourceWorkloadPriorityClassLabel
+	} else {
+		priorityClassName, source, err := priorityClassFromSources(ctx, client, job, prioritySources)
+		if err != nil {
+			return nil, err
+		}
+		priorityClassName, p, err := utilpriority.GetPriorityFromPriorityClass(ctx, client, priorityClassName)
+		if err != nil {
+			return nil, err
+		}
+		w.Spec.Priority = &p
+		w.Spec.PriorityClassName = priorityClassName
+		w.Spec.PriorityClassSource = utilpriority.PriorityClassSource(priorityClassName)
+		w.Status.PrioritySource = source
+	}
+
+	if customizer, ok := job.(JobWithCustomWorkload); ok {
+		if err := customizer.CustomizeWorkload(w); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := ctrl.SetControllerReference(obj, w, scheme); err != nil {
+		return nil, err
+	}
+	return w, nil
+}
+
+// prioritySourceDefault is recorded as the priority source of workloads
+// whose PriorityClass is not provided by any of the configured sources.
+const prioritySourceDefault = "Default"
+
+// prioritySourceWorkloadPriorityClassLabel is recorded as the priority source
+// of workloads whose priority is taken from a WorkloadPriorityClass.
+const prioritySourceWorkloadPriorityClassLabel = "WorkloadPriorityClassLabel"
+
+// defaultPrioritySources are the sources of the PriorityClass of the
+// workloads when the reconciler isn't configured with others.
+var defaultPrioritySources = []config.PrioritySource{
+	config.PrioritySourcePriorityClassLabel,
+	config.PrioritySourcePodPriorityClass,
+}
+
+// prioritySource returns the source of the priority of the workload for the
+// job.
+func (r *JobReconciler) prioritySource(ctx context.Context, job GenericJob) (string, error) {
+	if len(job.Object().GetLabels()[constants.WorkloadPriorityClassLabel]) != 0 {
+		return prioritySourceWorkloadPriorityClassLabel, nil
+	}
+	_, source, err := priorityClassFromSources(ctx, r.client, job, r.prioritySources)
+	return source, err
+}
+
+// priorityClassFromSources returns the name of the PriorityClass provided by
+// the first of the sources that provides one, along with the source. If
+// sources is nil, the default sources are used.
+func priorityClassFromSources(ctx context.Context, c client.Client, job GenericJob, sources []config.PrioritySource) (string, string, error) {
+	if sources == nil {
+		sources = defaultPrioritySources
+	}
+	obj := job.Object()
+	for _, source := range sources {
+		var name string
+		switch source {
+		case config.PrioritySourcePriorityClassLabel:
+			name = obj.GetLabels()[constants.PriorityClassLabel]
+		case config.PrioritySourcePodPriorityClass:
+			podSets := job.PodSets()
+			for i := 0; len(name) == 0 && i < len(podSets); i++ {
+				name = podSets[i].Spec.PriorityClassName
+			}
+		case config.PrioritySourceLocalQueue:
+			queueName := job.QueueName()
+			if len(queueName) == 0 {
+				continue
+			}
+			var lq kueue.LocalQueue
+			if err := c.Get(ctx, types.NamespacedName{Name: queueName, Namespace: obj.GetNamespace()}, &lq); err != nil {
+				if client.IgnoreNotFound(err) != nil {
+					return "", "", err
+				}
+				continue
+			}
+			name = lq.Spec.PriorityClassName
+		}
+		if len(name) != 0 {
+			return name, string(source), nil
+		}
+	}
+	return "", prioritySourceDefault, nil
+}
+
+// WorkloadName returns the name of the workload for the job of the kind.
+// The kind is part of the name so that it doesn't conflict with the
+// workloads of other kinds of jobs with the same name.
+func WorkloadName(gvk schema.GroupVersionKind, jobName string) string {
+	return fmt.Sprintf("%s-%s", strings.ToLower(gvk.Kind), jobName)
+}
+
+// PodsReadyCondition returns the PodsReady condition of the workload, which
+// is only true once the workload is admitted.
+func PodsReadyCondition(podsReady bool, wl *kueue.Workload) metav1.Condition {
+	conditionStatus := metav1.ConditionFalse
+	message := "Not all pods are ready or succeeded"
+	if podsReady && wl.Status.Admission != nil {
+		conditionStatus = metav1.ConditionTrue
+		message = "All pods are ready or succeeded"
+	}
+	return metav1.Condition{
+		Type:    kueue.WorkloadPodsReady,
+		Status:  conditionStatus,
+		Reason:  kueue.WorkloadReasonPodsReady,
+		Message: message,
+	}
+}
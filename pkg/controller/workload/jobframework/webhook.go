@@ -0,0 +1,153 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package jobframework
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/klog/v2"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+
+	"sigs.k8s.io/kueue/pkg/constants"
+)
+
+// JobWebhook suspends the new jobs of an integration that Kueue manages, so
+// that they don't start before their workload is admitted. The jobs of an
+// integration that isn't enabled are left unchanged, so that the webhook
+// configuration can include all the integrations.
+type JobWebhook struct {
+	client                     client.Client
+	integration                *Integration
+	manageJobsWithoutQueueName bool
+	decoder                    *admission.Decoder
+}
+
+var _ admission.Handler = &JobWebhook{}
+var _ admission.DecoderInjector = &JobWebhook{}
+
+// NewJobWebhook returns the mutating webhook for the jobs of the integration.
+func NewJobWebhook(c client.Client, i *Integration, opts ...Option) *JobWebhook {
+	var options options
+	for _, opt := range opts {
+		opt(&options)
+	}
+	return &JobWebhook{
+		client:                     c,
+		integration:                i,
+		manageJobsWithoutQueueName: options.manageJobsWithoutQueueName,
+	}
+}
+
+// SetupWebhooks registers the mutating webhooks of all the registered
+// integrations, in the paths returned by WebhookPath.
+func SetupWebhooks(mgr ctrl.Manager, opts ...Option) error {
+	for _, name := range IntegrationNames() {
+		i := integrations[name]
+		mgr.GetWebhookServer().Register(WebhookPath(i.GVK), &admission.Webhook{
+			Handler: NewJobWebhook(mgr.GetClient(), i, opts...),
+		})
+	}
+	return nil
+}
+
+// WebhookPath returns the path of the mutating webhook for the jobs of the
+// kind, as in the kubebuilder markers of the integration.
+func WebhookPath(gvk schema.GroupVersionKind) string {
+	return "/mutate-" + strings.ReplaceAll(gvk.Group, ".", "-") + "-" + gvk.Version + "-" + strings.ToLower(gvk.Kind)
+}
+
+// InjectDecoder injects the decoder of the requests.
+func (w *JobWebhook) InjectDecoder(d *admission.Decoder) error {
+	w.decoder = d
+	return nil
+}
+
+// Handle decodes the job of the request into a job of the integration and
+// returns the patch that applies the defaults.
+func (w *JobWebhook) Handle(ctx context.Context, req admission.Request) admission.Response {
+	job := w.integration.NewJob()
+	if err := w.decoder.Decode(req, job.Object()); err != nil {
+		return admission.Errored(http.StatusBadRequest, err)
+	}
+	if err := w.Default(ctx, job); err != nil {
+		return admission.Errored(http.StatusInternalServerError, err)
+	}
+	marshaled, err := json.Marshal(job.Object())
+	if err != nil {
+		return admission.Errored(http.StatusInternalServerError, err)
+	}
+	return admission.PatchResponseFromRaw(req.Object.Raw, marshaled)
+}
+
+// Default suspends the job if Kueue manages it, setting the default queue of
+// its namespace first if it doesn't have a queue name.
+func (w *JobWebhook) Default(ctx context.Context, job GenericJob) error {
+	obj := job.Object()
+	log := ctrl.LoggerFrom(ctx).WithName("job-webhook")
+	log.V(5).Info("Applying defaults", "kind", w.integration.GVK.Kind, "job", klog.KObj(obj))
+
+	if !enabledKinds[w.integration.GVK.GroupKind()] {
+		return nil
+	}
+	// The quota of the job is accounted through the workload of its owner.
+	if owner := metav1.GetControllerOf(obj); owner != nil && IsOwnerManagedByKueue(owner) {
+		return nil
+	}
+	if job.QueueName() == "" {
+		if err := w.setDefaultQueueName(ctx, obj); err != nil {
+			return err
+		}
+	}
+	if job.QueueName() == "" && (!w.manageJobsWithoutQueueName || w.integration.RequiresQueueName) {
+		return nil
+	}
+	if !job.IsSuspended() {
+		job.Suspend()
+	}
+	return nil
+}
+
+// setDefaultQueueName sets the queue name of the job to the default queue of
+// its namespace, if the namespace has one.
+func (w *JobWebhook) setDefaultQueueName(ctx context.Context, obj client.Object) error {
+	var ns corev1.Namespace
+	if err := w.client.Get(ctx, types.NamespacedName{Name: obj.GetNamespace()}, &ns); err != nil {
+		return fmt.Errorf("getting the namespace of the job: %w", err)
+	}
+	defaultQueue := ns.Annotations[constants.DefaultQueueAnnotation]
+	if defaultQueue == "" {
+		return nil
+	}
+	annotations := obj.GetAnnotations()
+	if annotations == nil {
+		annotations = make(map[string]string, 1)
+	}
+	annotations[constants.QueueAnnotation] = defaultQueue
+	obj.SetAnnotations(annotations)
+	ctrl.LoggerFrom(ctx).WithName("job-webhook").V(5).Info("Setting the default queue of the namespace", "kind", w.integration.GVK.Kind, "job", klog.KObj(obj), "queue", defaultQueue)
+	return nil
+}
@@ -0,0 +1,301 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package kubeflow integrates the jobs of the Kubeflow training operator
+// with Kueue. The jobs are handled as unstructured objects, so that Kueue
+// doesn't depend on the APIs of the training operator.
+package kubeflow
+
+import (
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	kueue "sigs.k8s.io/kueue/apis/kueue/v1alpha2"
+	"sigs.k8s.io/kueue/pkg/constants"
+	"sigs.k8s.io/kueue/pkg/controller/workload/jobframework"
+)
+
+const (
+	jobSucceeded = "Succeeded"
+	jobFailed    = "Failed"
+	jobRunning   = "Running"
+)
+
+// Framework describes the jobs of one of the Kubeflow training operators.
+type Framework struct {
+	// Name is the name of the framework in the configuration.
+	Name string
+	// GVK is the kind of the jobs.
+	GVK schema.GroupVersionKind
+	// ReplicaSpecsField is the field of the job spec that holds the specs of
+	// the replica types.
+	ReplicaSpecsField string
+	// ReplicaTypes are the replica types that the jobs can have, in the
+	// order of their pod sets in the workloads.
+	ReplicaTypes []string
+}
+
+var (
+	TFJob = Framework{
+		Name:              "kubeflow.org/tfjob",
+		GVK:               schema.GroupVersionKind{Group: "kubeflow.org", Version: "v1", Kind: "TFJob"},
+		ReplicaSpecsField: "tfReplicaSpecs",
+		ReplicaTypes:      []string{"Chief", "Master", "PS", "Worker", "Evaluator"},
+	}
+	PyTorchJob = Framework{
+		Name:              "kubeflow.org/pytorchjob",
+		GVK:               schema.GroupVersionKind{Group: "kubeflow.org", Version: "v1", Kind: "PyTorchJob"},
+		ReplicaSpecsField: "pytorchReplicaSpecs",
+		ReplicaTypes:      []string{"Master", "Worker"},
+	}
+	XGBoostJob = Framework{
+		Name:              "kubeflow.org/xgboostjob",
+		GVK:               schema.GroupVersionKind{Group: "kubeflow.org", Version: "v1", Kind: "XGBoostJob"},
+		ReplicaSpecsField: "xgbReplicaSpecs",
+		ReplicaTypes:      []string{"Master", "Worker"},
+	}
+	PaddleJob = Framework{
+		Name:              "kubeflow.org/paddlejob",
+		GVK:               schema.GroupVersionKind{Group: "kubeflow.org", Version: "v1", Kind: "PaddleJob"},
+		ReplicaSpecsField: "paddleReplicaSpecs",
+		ReplicaTypes:      []string{"Master", "Worker"},
+	}
+
+	frameworks = []*Framework{&TFJob, &PyTorchJob, &XGBoostJob, &PaddleJob}
+)
+
+func init() {
+	for _, f := range frameworks {
+		utilruntime.Must(jobframework.RegisterIntegration(f.Integration()))
+	}
+}
+
+// Integration returns the integration of the jobs of the framework, for the
+// jobframework.JobReconciler.
+func (f *Framework) Integration() jobframework.Integration {
+	return jobframework.Integration{
+		Name: f.Name,
+		GVK:  f.GVK,
+		NewJob: func() jobframework.GenericJob {
+			return NewJob(f, f.NewObject())
+		},
+	}
+}
+
+// NewObject returns an empty job of the framework.
+func (f *Framework) NewObject() *unstructured.Unstructured {
+	obj := &unstructured.Unstructured{}
+	obj.SetGroupVersionKind(f.GVK)
+	return obj
+}
+
+// Job implements jobframework.GenericJob for the jobs of a Kubeflow
+// training operator. Each replica type of the job is a pod set of the
+// workload, named after the replica type in lowercase.
+type Job struct {
+	obj       *unstructured.Unstructured
+	framework *Framework
+}
+
+var _ jobframework.GenericJob = &Job{}
+var _ jobframework.JobWithRestoreNodeSelectors = &Job{}
+
+//+kubebuilder:rbac:groups=kubeflow.org,resources=tfjobs;pytorchjobs;xgboostjobs;paddlejobs,verbs=get;list;watch;update;patch
+//+kubebuilder:rbac:groups=kubeflow.org,resources=tfjobs/status;pytorchjobs/status;xgboostjobs/status;paddlejobs/status,verbs=get
+//+kubebuilder:rbac:groups=kubeflow.org,resources=tfjobs/finalizers;pytorchjobs/finalizers;xgboostjobs/finalizers;paddlejobs/finalizers,verbs=get;update;patch
+
+// The jobs are suspended on creation by the webhook of the jobframework.
+// +kubebuilder:webhook:path=/mutate-kubeflow-org-v1-tfjob,mutating=true,failurePolicy=fail,sideEffects=None,groups=kubeflow.org,resources=tfjobs,verbs=create,versions=v1,name=mtfjob.kb.io,admissionReviewVersions=v1
+// +kubebuilder:webhook:path=/mutate-kubeflow-org-v1-pytorchjob,mutating=true,failurePolicy=fail,sideEffects=None,groups=kubeflow.org,resources=pytorchjobs,verbs=create,versions=v1,name=mpytorchjob.kb.io,admissionReviewVersions=v1
+// +kubebuilder:webhook:path=/mutate-kubeflow-org-v1-xgboostjob,mutating=true,failurePolicy=fail,sideEffects=None,groups=kubeflow.org,resources=xgboostjobs,verbs=create,versions=v1,name=mxgboostjob.kb.io,admissionReviewVersions=v1
+// +kubebuilder:webhook:path=/mutate-kubeflow-org-v1-paddlejob,mutating=true,failurePolicy=fail,sideEffects=None,groups=kubeflow.org,resources=paddlejobs,verbs=create,versions=v1,name=mpaddlejob.kb.io,admissionReviewVersions=v1
+
+// NewJob returns the job of the framework backed by the object.
+func NewJob(f *Framework, obj *unstructured.Unstructured) *Job {
+	return &Job{obj: obj, framework: f}
+}
+
+func (j *Job) Object() client.Object {
+	return j.obj
+}
+
+func (j *Job) QueueName() string {
+	return j.obj.GetAnnotations()[constants.QueueAnnotation]
+}
+
+func (j *Job) IsSuspended() bool {
+	suspend, _, _ := unstructured.NestedBool(j.obj.Object, "spec", "runPolicy", "suspend")
+	return suspend
+}
+
+func (j *Job) Suspend() {
+	j.setSuspend(true)
+}
+
+func (j *Job) RunWithNodeSelectors(nodeSelectors []map[string]string) {
+	for i, rType := range j.replicaTypes() {
+		if i >= len(nodeSelectors) || len(nodeSelectors[i]) == 0 {
+			continue
+		}
+		path := j.nodeSelectorPath(rType)
+		selector, _, _ := unstructured.NestedStringMap(j.obj.Object, path...)
+		if selector == nil {
+			selector = make(map[string]string, len(nodeSelectors[i]))
+		}
+		for k, v := range nodeSelectors[i] {
+			selector[k] = v
+		}
+		_ = unstructured.SetNestedStringMap(j.obj.Object, selector, path...)
+	}
+	j.setSuspend(false)
+}
+
+// RestoreNodeSelectors sets the node selectors of the pod templates back to
+// the ones of the pod sets, removing the ones injected when the job started.
+func (j *Job) RestoreNodeSelectors(podSets []kueue.PodSet) {
+	for _, rType := range j.replicaTypes() {
+		for i := range podSets {
+			if podSets[i].Name != podSetName(rType) {
+				continue
+			}
+			path := j.nodeSelectorPath(rType)
+			if len(podSets[i].Spec.NodeSelector) == 0 {
+				unstructured.RemoveNestedField(j.obj.Object, path...)
+			} else {
+				_ = unstructured.SetNestedStringMap(j.obj.Object, podSets[i].Spec.NodeSelector, path...)
+			}
+		}
+	}
+}
+
+func (j *Job) PodSets() []kueue.PodSet {
+	rTypes := j.replicaTypes()
+	podSets := make([]kueue.PodSet, 0, len(rTypes))
+	for _, rType := range rTypes {
+		podSets = append(podSets, kueue.PodSet{
+			Name:  podSetName(rType),
+			Spec:  j.podTemplate(rType).Spec,
+			Count: j.replicas(rType),
+		})
+	}
+	return podSets
+}
+
+func (j *Job) EquivalentToWorkload(wl *kueue.Workload) bool {
+	podSets := j.PodSets()
+	if len(podSets) != len(wl.Spec.PodSets) {
+		return false
+	}
+	for i := range podSets {
+		ps, wlPs := &podSets[i], &wl.Spec.PodSets[i]
+		if ps.Name != wlPs.Name || ps.Count != wlPs.Count {
+			return false
+		}
+		// nodeSelector may change, hence we are not checking for
+		// equality of the whole spec.
+		if !equality.Semantic.DeepEqual(ps.Spec.InitContainers, wlPs.Spec.InitContainers) ||
+			!equality.Semantic.DeepEqual(ps.Spec.Containers, wlPs.Spec.Containers) {
+			return false
+		}
+	}
+	return true
+}
+
+func (j *Job) Finished() (metav1.Condition, bool) {
+	message := ""
+	switch {
+	case j.hasCondition(jobSucceeded):
+		message = "Job finished successfully"
+	case j.hasCondition(jobFailed):
+		message = "Job failed"
+	default:
+		return metav1.Condition{}, false
+	}
+	return metav1.Condition{
+		Type:    kueue.WorkloadFinished,
+		Status:  metav1.ConditionTrue,
+		Reason:  kueue.WorkloadReasonJobFinished,
+		Message: message,
+	}, true
+}
+
+// PodsReady returns whether the job is running or succeeded. The training
+// operators set the Running condition once all the pods are running.
+func (j *Job) PodsReady() bool {
+	return j.hasCondition(jobRunning) || j.hasCondition(jobSucceeded)
+}
+
+func (j *Job) setSuspend(suspend bool) {
+	_ = unstructured.SetNestedField(j.obj.Object, suspend, "spec", "runPolicy", "suspend")
+}
+
+// replicaTypes returns the replica types that the job has, in the order of
+// the pod sets.
+func (j *Job) replicaTypes() []string {
+	specs, _, _ := unstructured.NestedMap(j.obj.Object, "spec", j.framework.ReplicaSpecsField)
+	var rTypes []string
+	for _, rType := range j.framework.ReplicaTypes {
+		if _, ok := specs[rType]; ok {
+			rTypes = append(rTypes, rType)
+		}
+	}
+	return rTypes
+}
+
+func (j *Job) replicas(rType string) int32 {
+	replicas, found, _ := unstructured.NestedInt64(j.obj.Object, "spec", j.framework.ReplicaSpecsField, rType, "replicas")
+	if !found {
+		// The training operators default the replicas to 1.
+		return 1
+	}
+	return int32(replicas)
+}
+
+// podTemplate returns the pod template of the replica type. The template is
+// validated by the API server, so it is always converted.
+func (j *Job) podTemplate(rType string) corev1.PodTemplateSpec {
+	var template corev1.PodTemplateSpec
+	obj, _, _ := unstructured.NestedMap(j.obj.Object, "spec", j.framework.ReplicaSpecsField, rType, "template")
+	_ = runtime.DefaultUnstructuredConverter.FromUnstructured(obj, &template)
+	return template
+}
+
+func (j *Job) nodeSelectorPath(rType string) []string {
+	return []string{"spec", j.framework.ReplicaSpecsField, rType, "template", "spec", "nodeSelector"}
+}
+
+func (j *Job) hasCondition(condType string) bool {
+	conditions, _, _ := unstructured.NestedSlice(j.obj.Object, "status", "conditions")
+	for _, c := range conditions {
+		cond, ok := c.(map[string]interface{})
+		if ok && cond["type"] == condType && cond["status"] == string(corev1.ConditionTrue) {
+			return true
+		}
+	}
+	return false
+}
+
+func podSetName(rType string) string {
+	return strings.ToLower(rType)
+}
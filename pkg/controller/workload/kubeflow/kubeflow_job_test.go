@@ -0,0 +1,216 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kubeflow
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	kueue "sigs.k8s.io/kueue/apis/kueue/v1alpha2"
+	"sigs.k8s.io/kueue/pkg/constants"
+	"sigs.k8s.io/kueue/pkg/controller/workload/jobframework"
+	"sigs.k8s.io/kueue/pkg/controller/workload/jobframework/conformance"
+)
+
+func podSpec(cpu string) corev1.PodSpec {
+	return corev1.PodSpec{
+		RestartPolicy: corev1.RestartPolicyNever,
+		NodeSelector:  map[string]string{"provisioning": "spot"},
+		Containers: []corev1.Container{{
+			Name:  "c",
+			Image: "pause",
+			Resources: corev1.ResourceRequirements{
+				Requests: corev1.ResourceList{corev1.ResourceCPU: resource.MustParse(cpu)},
+			},
+		}},
+	}
+}
+
+// makeJob returns a suspended job of the framework with the given replicas
+// for each replica type.
+func makeJob(t *testing.T, f *Framework, replicas map[string]int64) *unstructured.Unstructured {
+	t.Helper()
+	obj := f.NewObject()
+	obj.SetName("job")
+	obj.SetNamespace("ns")
+	obj.SetAnnotations(map[string]string{constants.QueueAnnotation: "queue"})
+	specs := make(map[string]interface{}, len(replicas))
+	for rType, count := range replicas {
+		template, err := runtime.DefaultUnstructuredConverter.ToUnstructured(&corev1.PodTemplateSpec{Spec: podSpec("1")})
+		if err != nil {
+			t.Fatalf("Converting pod template: %v", err)
+		}
+		specs[rType] = map[string]interface{}{
+			"replicas": count,
+			"template": template,
+		}
+	}
+	obj.Object["spec"] = map[string]interface{}{
+		f.ReplicaSpecsField: specs,
+		"runPolicy":         map[string]interface{}{"suspend": true},
+	}
+	return obj
+}
+
+func setCondition(job jobframework.GenericJob, condType string) {
+	obj := job.Object().(*unstructured.Unstructured)
+	conditions, _, _ := unstructured.NestedSlice(obj.Object, "status", "conditions")
+	conditions = append(conditions, map[string]interface{}{
+		"type":   condType,
+		"status": string(corev1.ConditionTrue),
+	})
+	_ = unstructured.SetNestedSlice(obj.Object, conditions, "status", "conditions")
+}
+
+func TestKubeflowJobConformance(t *testing.T) {
+	for _, f := range frameworks {
+		f := f
+		t.Run(f.GVK.Kind, func(t *testing.T) {
+			// Every replica type but the first, to check the order of the
+			// pod sets.
+			replicas := make(map[string]int64)
+			var wantPodSets []kueue.PodSet
+			for i, rType := range f.ReplicaTypes[1:] {
+				replicas[rType] = int64(i + 2)
+				wantPodSets = append(wantPodSets, kueue.PodSet{
+					Name:  strings.ToLower(rType),
+					Spec:  podSpec("1"),
+					Count: int32(i + 2),
+				})
+			}
+			conformance.Run(t, conformance.Suite{
+				NewJob: func() jobframework.GenericJob {
+					return NewJob(f, makeJob(t, f, replicas))
+				},
+				WantPodSets:   wantPodSets,
+				WantQueueName: "queue",
+				SetPodsReady: func(job jobframework.GenericJob) {
+					setCondition(job, jobRunning)
+				},
+				SetFinished: func(job jobframework.GenericJob, success bool) {
+					condType := jobSucceeded
+					if !success {
+						condType = jobFailed
+					}
+					setCondition(job, condType)
+				},
+			})
+		})
+	}
+}
+
+func TestRestoreNodeSelectors(t *testing.T) {
+	job := NewJob(&PyTorchJob, makeJob(t, &PyTorchJob, map[string]int64{"Master": 1, "Worker": 4}))
+	podSets := job.PodSets()
+	podSets[1].Spec.NodeSelector = nil
+	job.RunWithNodeSelectors([]map[string]string{
+		{"instance": "on-demand"},
+		{"instance": "spot"},
+	})
+	job.RestoreNodeSelectors(podSets)
+
+	want := []map[string]string{{"provisioning": "spot"}, nil}
+	var got []map[string]string
+	for _, ps := range job.PodSets() {
+		got = append(got, ps.Spec.NodeSelector)
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("Unexpected node selectors (-want,+got):\n%s", diff)
+	}
+}
+
+func TestWebhookDefault(t *testing.T) {
+	if _, err := jobframework.EnableIntegrations([]string{PyTorchJob.Name}); err != nil {
+		t.Fatalf("Enabling integration: %v", err)
+	}
+	cases := map[string]struct {
+		framework                  *Framework
+		queueName                  string
+		defaultQueue               string
+		manageJobsWithoutQueueName bool
+		wantQueueName              string
+		wantSuspended              bool
+	}{
+		"job with queue name": {
+			framework:     &PyTorchJob,
+			queueName:     "queue",
+			wantQueueName: "queue",
+			wantSuspended: true,
+		},
+		"job without queue name": {
+			framework: &PyTorchJob,
+		},
+		"job without queue name, managing jobs without queue name": {
+			framework:                  &PyTorchJob,
+			manageJobsWithoutQueueName: true,
+			wantSuspended:              true,
+		},
+		"job without queue name in a namespace with a default queue": {
+			framework:     &PyTorchJob,
+			defaultQueue:  "default",
+			wantQueueName: "default",
+			wantSuspended: true,
+		},
+		"job of an integration that isn't enabled": {
+			framework:     &TFJob,
+			queueName:     "queue",
+			wantQueueName: "queue",
+		},
+	}
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			ns := &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "ns"}}
+			if tc.defaultQueue != "" {
+				ns.Annotations = map[string]string{constants.DefaultQueueAnnotation: tc.defaultQueue}
+			}
+			scheme := runtime.NewScheme()
+			if err := corev1.AddToScheme(scheme); err != nil {
+				t.Fatalf("Failed adding core scheme: %v", err)
+			}
+			cl := fake.NewClientBuilder().WithScheme(scheme).WithObjects(ns).Build()
+			integration := tc.framework.Integration()
+			wh := jobframework.NewJobWebhook(cl, &integration,
+				jobframework.WithManageJobsWithoutQueueName(tc.manageJobsWithoutQueueName))
+
+			obj := makeJob(t, tc.framework, map[string]int64{"Master": 1})
+			obj.SetAnnotations(map[string]string{constants.QueueAnnotation: tc.queueName})
+			if tc.queueName == "" {
+				obj.SetAnnotations(nil)
+			}
+			job := NewJob(tc.framework, obj)
+			job.setSuspend(false)
+			if err := wh.Default(context.Background(), job); err != nil {
+				t.Fatalf("Default returned error: %v", err)
+			}
+			if job.QueueName() != tc.wantQueueName {
+				t.Errorf("Got queue name %q, want %q", job.QueueName(), tc.wantQueueName)
+			}
+			if job.IsSuspended() != tc.wantSuspended {
+				t.Errorf("Got suspended %t, want %t", job.IsSuspended(), tc.wantSuspended)
+			}
+		})
+	}
+}
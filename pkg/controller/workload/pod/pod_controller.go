@@ -0,0 +1,553 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package pod integrates plain Pods with Kueue. The Pods of a pod group,
+// named by the PodGroupNameLabel, share a single Workload. The webhook gates
+// the Pods with a node selector that no node matches, and the controller
+// replaces them with Pods without it once the Workload is admitted, as the
+// node selector of a Pod can't be updated.
+package pod
+
+import (
+	"context"
+	"fmt"
+	"hash/fnv"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	apimeta "k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/rand"
+	"k8s.io/apimachinery/pkg/util/validation"
+	"k8s.io/client-go/tools/record"
+	"k8s.io/client-go/util/workqueue"
+	"k8s.io/klog/v2"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	"sigs.k8s.io/controller-runtime/pkg/source"
+
+	kueue "sigs.k8s.io/kueue/apis/kueue/v1alpha2"
+	"sigs.k8s.io/kueue/pkg/constants"
+	"sigs.k8s.io/kueue/pkg/controller/workload/jobframework"
+	utilpriority "sigs.k8s.io/kueue/pkg/util/priority"
+	"sigs.k8s.io/kueue/pkg/workload"
+)
+
+const (
+	// FrameworkName is the name that enables the integration in the
+	// frameworks of the configuration.
+	FrameworkName = "pod"
+
+	podSetName = "main"
+	gateValue  = "true"
+)
+
+var gvk = corev1.SchemeGroupVersion.WithKind("Pod")
+
+// Reconciler reconciles the pod groups through their Workloads. The
+// requests are keyed by the name of the Workload of the group.
+type Reconciler struct {
+	client           client.Client
+	record           record.EventRecorder
+	waitForPodsReady bool
+}
+
+type options struct {
+	waitForPodsReady bool
+	enabled          bool
+}
+
+// Option configures the reconciler and the webhook.
+type Option func(*options)
+
+// WithEnabled indicates if the integration is enabled in the configuration.
+// The webhook leaves the pods unchanged when it isn't, as it is served
+// anyway.
+func WithEnabled(f bool) Option {
+	return func(o *options) {
+		o.enabled = f
+	}
+}
+
+// WithWaitForPodsReady indicates if the controller should add the PodsReady
+// condition to the workload when all the pods of the group are ready or
+// succeeded.
+func WithWaitForPodsReady(f bool) Option {
+	return func(o *options) {
+		o.waitForPodsReady = f
+	}
+}
+
+func NewReconciler(client client.Client, record record.EventRecorder, opts ...Option) *Reconciler {
+	var options options
+	for _, opt := range opts {
+		opt(&options)
+	}
+	return &Reconciler{
+		client:           client,
+		record:           record,
+		waitForPodsReady: options.waitForPodsReady,
+	}
+}
+
+// SetupWithManager sets up the controller with the Manager.
+func (r *Reconciler) SetupWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewControllerManagedBy(mgr).
+		Named("pod").
+		For(&kueue.Workload{}, builder.WithPredicates(predicate.NewPredicateFuncs(isPodGroupWorkload))).
+		Watches(&source.Kind{Type: &corev1.Pod{}}, &podGroupHandler{}).
+		Complete(r)
+}
+
+// WorkloadName returns the name of the Workload of the pod group.
+func WorkloadName(group string) string {
+	return jobframework.WorkloadName(gvk, group)
+}
+
+func isPodGroupWorkload(obj client.Object) bool {
+	_, ok := obj.GetLabels()[constants.PodGroupNameLabel]
+	return ok
+}
+
+// podGroupHandler enqueues the Workload of the pod group of the managed
+// pods.
+type podGroupHandler struct{}
+
+func (h *podGroupHandler) Create(e event.CreateEvent, q workqueue.RateLimitingInterface) {
+	h.addPodGroup(e.Object, q)
+}
+
+func (h *podGroupHandler) Update(e event.UpdateEvent, q workqueue.RateLimitingInterface) {
+	h.addPodGroup(e.ObjectNew, q)
+}
+
+func (h *podGroupHandler) Delete(e event.DeleteEvent, q workqueue.RateLimitingInterface) {
+	h.addPodGroup(e.Object, q)
+}
+
+func (h *podGroupHandler) Generic(event.GenericEvent, workqueue.RateLimitingInterface) {
+}
+
+func (h *podGroupHandler) addPodGroup(obj client.Object, q workqueue.RateLimitingInterface) {
+	pod, ok := obj.(*corev1.Pod)
+	if !ok || !isManaged(pod) {
+		return
+	}
+	q.Add(reconcile.Request{NamespacedName: types.NamespacedName{
+		Namespace: pod.Namespace,
+		Name:      WorkloadName(pod.Labels[constants.PodGroupNameLabel]),
+	}})
+}
+
+//+kubebuilder:rbac:groups="",resources=pods,verbs=get;list;watch;create;delete;update;patch
+//+kubebuilder:rbac:groups=scheduling.k8s.io,resources=priorityclasses,verbs=list;get;watch
+//+kubebuilder:rbac:groups=kueue.x-k8s.io,resources=workloads,verbs=get;list;watch;create;update;patch;delete
+//+kubebuilder:rbac:groups=kueue.x-k8s.io,resources=workloads/status,verbs=get;update;patch
+
+func (r *Reconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	group := strings.TrimPrefix(req.Name, WorkloadName(""))
+	log := ctrl.LoggerFrom(ctx).WithValues("podGroup", klog.KRef(req.Namespace, group))
+	ctx = ctrl.LoggerInto(ctx, log)
+	log.V(2).Info("Reconciling pod group")
+
+	var podList corev1.PodList
+	if err := r.client.List(ctx, &podList, client.InNamespace(req.Namespace), client.MatchingLabels{
+		constants.ManagedLabel:      "true",
+		constants.PodGroupNameLabel: group,
+	}); err != nil {
+		log.Error(err, "Unable to list the pods of the group")
+		return ctrl.Result{}, err
+	}
+	pods := activePods(podList.Items)
+
+	wl := &kueue.Workload{}
+	if err := r.client.Get(ctx, req.NamespacedName, wl); err != nil {
+		if !apierrors.IsNotFound(err) {
+			return ctrl.Result{}, err
+		}
+		wl = nil
+	}
+
+	// 1. delete the workload of a group whose pods are gone.
+	if len(pods) == 0 {
+		if wl == nil {
+			return ctrl.Result{}, nil
+		}
+		log.V(2).Info("The pods of the group are gone, deleting its workload")
+		return ctrl.Result{}, client.IgnoreNotFound(r.client.Delete(ctx, wl))
+	}
+
+	finishedCond, groupFinished := finished(pods)
+	// 2. create the workload once all the pods of the group exist.
+	if wl == nil {
+		if groupFinished {
+			return ctrl.Result{}, nil
+		}
+		if count := totalCount(pods[0]); int32(len(pods)) < count {
+			log.V(3).Info("Waiting for the rest of the pods of the group", "pods", len(pods), "totalCount", count)
+			return ctrl.Result{}, nil
+		}
+		err := r.createWorkload(ctx, group, pods)
+		if err != nil {
+			log.Error(err, "Creating the workload of the group")
+		}
+		return ctrl.Result{}, err
+	}
+
+	// 3. handle a finished group.
+	if groupFinished {
+		if apimeta.IsStatusConditionTrue(wl.Status.Conditions, kueue.WorkloadFinished) {
+			return ctrl.Result{}, nil
+		}
+		apimeta.SetStatusCondition(&wl.Status.Conditions, finishedCond)
+		err := r.client.Status().Update(ctx, wl)
+		if err != nil {
+			log.Error(err, "Updating workload status")
+		}
+		return ctrl.Result{}, err
+	}
+
+	if r.waitForPodsReady {
+		condition := jobframework.PodsReadyCondition(podsReady(pods), wl)
+		if !apimeta.IsStatusConditionPresentAndEqual(wl.Status.Conditions, condition.Type, condition.Status) {
+			log.V(3).Info(fmt.Sprintf("Updating the PodsReady condition with status: %v", condition.Status))
+			apimeta.SetStatusCondition(&wl.Status.Conditions, condition)
+			if err := r.client.Status().Update(ctx, wl); err != nil {
+				log.Error(err, "Updating workload status")
+			}
+		}
+	}
+
+	// 4. ungate the pods of an admitted group.
+	if workload.IsAdmitted(wl) {
+		gated := gatedPods(pods)
+		if len(gated) == 0 {
+			log.V(3).Info("Pod group running with admitted workload, nothing to do")
+			return ctrl.Result{}, nil
+		}
+		// The pods stopped on an eviction are recreated by the user.
+		if count := totalCount(pods[0]); int32(len(pods)) < count {
+			log.V(3).Info("Workload admitted, waiting for the rest of the pods of the group", "pods", len(pods), "totalCount", count)
+			return ctrl.Result{}, nil
+		}
+		log.V(2).Info("Workload admitted, ungating the pods of the group")
+		err := r.ungatePods(ctx, wl, gated)
+		if err != nil {
+			log.Error(err, "Ungating the pods of the group")
+		}
+		return ctrl.Result{}, err
+	}
+
+	// 5. stop the pods of a group whose workload isn't admitted.
+	if wl.Status.Admission == nil {
+		for _, pod := range pods {
+			if isGated(pod) || podFinished(pod) {
+				continue
+			}
+			log.V(2).Info("Running pod is not admitted by a cluster queue, deleting it", "pod", klog.KObj(pod))
+			if err := r.client.Delete(ctx, pod); client.IgnoreNotFound(err) != nil {
+				log.Error(err, "Deleting pod with non admitted workload")
+				return ctrl.Result{}, err
+			}
+			r.record.Eventf(pod, corev1.EventTypeNormal, "Stopped", jobframework.NotAdmittedMessage(wl))
+		}
+	}
+	return ctrl.Result{}, nil
+}
+
+func (r *Reconciler) createWorkload(ctx context.Context, group string, pods []*corev1.Pod) error {
+	wl, err := constructWorkload(ctx, r.client, group, pods)
+	if err != nil {
+		return err
+	}
+	if err := r.client.Create(ctx, wl); err != nil {
+		return err
+	}
+	for _, pod := range pods {
+		r.record.Eventf(pod, corev1.EventTypeNormal, "CreatedWorkload",
+			"Created Workload: %v", workload.Key(wl))
+	}
+	return nil
+}
+
+// constructWorkload returns the workload of the pod group. It has no owner,
+// as the pods are replaced when they are ungated. The priority is taken as
+// in jobframework.ConstructWorkload, from the labels and the spec of the
+// first pod.
+func constructWorkload(ctx context.Context, c client.Client, group string, pods []*corev1.Pod) (*kueue.Workload, error) {
+	pod := pods[0]
+	wl := &kueue.Workload{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      WorkloadName(group),
+			Namespace: pod.Namespace,
+			Labels:    map[string]string{constants.PodGroupNameLabel: group},
+		},
+		Spec: kueue.WorkloadSpec{
+			PodSets:   podSets(pods),
+			QueueName: queueName(pod),
+		},
+	}
+
+	if wpcName := pod.Labels[constants.WorkloadPriorityClassLabel]; len(wpcName) != 0 {
+		p, err := utilpriority.GetPriorityFromWorkloadPriorityClass(ctx, c, wpcName)
+		if err != nil {
+			return nil, err
+		}
+		wl.Spec.Priority = &p
+		wl.Spec.PriorityClassName = wpcName
+		wl.Spec.PriorityClassSource = kueue.WorkloadPriorityClassSource
+		return wl, nil
+	}
+	priorityClassName, p, err := utilpriority.GetPriorityFromPriorityClass(ctx, c, pod.Spec.PriorityClassName)
+	if err != nil {
+		return nil, err
+	}
+	wl.Spec.Priority = &p
+	wl.Spec.PriorityClassName = priorityClassName
+	wl.Spec.PriorityClassSource = utilpriority.PriorityClassSource(priorityClassName)
+	return wl, nil
+}
+
+// podSets returns the pod sets of the pod group, with a pod set for the
+// pods of each shape.
+func podSets(pods []*corev1.Pod) []kueue.PodSet {
+	var sets []kueue.PodSet
+	for _, pod := range pods {
+		spec := podSetSpec(pod)
+		if idx := podSetIndex(sets, spec); idx >= 0 {
+			sets[idx].Count++
+			continue
+		}
+		sets = append(sets, kueue.PodSet{Spec: *spec, Count: 1})
+	}
+	for i := range sets {
+		sets[i].Name = podSetName
+		if len(sets) > 1 {
+			sets[i].Name = fmt.Sprintf("%s-%d", podSetName, i)
+		}
+	}
+	return sets
+}
+
+// podSetSpec returns the spec of the pod without the admission gate.
+func podSetSpec(pod *corev1.Pod) *corev1.PodSpec {
+	spec := pod.Spec.DeepCopy()
+	delete(spec.NodeSelector, constants.AdmissionGateNodeSelector)
+	spec.NodeName = ""
+	return spec
+}
+
+// podSetIndex returns the index of the pod set for pods with the spec, or -1
+// if there is none.
+func podSetIndex(sets []kueue.PodSet, spec *corev1.PodSpec) int {
+	for i := range sets {
+		if sameShape(&sets[i].Spec, spec) {
+			return i
+		}
+	}
+	return -1
+}
+
+// sameShape returns whether the pods with the specs need the same quota
+// and can run on the same nodes. The rest of the spec, such as the volumes
+// injected for the service account, may differ between the pods.
+func sameShape(a, b *corev1.PodSpec) bool {
+	return sameContainerShapes(a.InitContainers, b.InitContainers) &&
+		sameContainerShapes(a.Containers, b.Containers) &&
+		equality.Semantic.DeepEqual(a.Overhead, b.Overhead) &&
+		equality.Semantic.DeepEqual(a.NodeSelector, b.NodeSelector) &&
+		equality.Semantic.DeepEqual(a.Affinity, b.Affinity) &&
+		equality.Semantic.DeepEqual(a.Tolerations, b.Tolerations) &&
+		a.PriorityClassName == b.PriorityClassName &&
+		equality.Semantic.DeepEqual(a.RuntimeClassName, b.RuntimeClassName)
+}
+
+func sameContainerShapes(a, b []corev1.Container) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i].Name != b[i].Name || !equality.Semantic.DeepEqual(a[i].Resources, b[i].Resources) {
+			return false
+		}
+	}
+	return true
+}
+
+// ungatePods replaces the gated pods of the admitted workload with pods
+// without the admission gate, and with the node selectors of the flavors
+// assigned to their pod sets. The replacement is created before the gated pod
+// is deleted, under a name derived from the UID of the gated pod, so that a
+// failure in between is completed by the next reconcile without losing the
+// pod.
+func (r *Reconciler) ungatePods(ctx context.Context, wl *kueue.Workload, pods []*corev1.Pod) error {
+	log := ctrl.LoggerFrom(ctx)
+	nodeSelectors, err := jobframework.NodeSelectors(ctx, r.client, wl.Status.Admission)
+	if err != nil {
+		return err
+	}
+	for _, pod := range pods {
+		idx := podSetIndex(wl.Spec.PodSets, podSetSpec(pod))
+		if idx < 0 || idx >= len(nodeSelectors) {
+			log.V(2).Info("The pod doesn't match any pod set of the workload, keeping it gated", "pod", klog.KObj(pod))
+			r.record.Eventf(pod, corev1.EventTypeWarning, "NoMatchingPodSet", "The pod doesn't match any pod set of Workload %v", workload.Key(wl))
+			continue
+		}
+		newPod := ungatedPod(pod, wl, nodeSelectors[idx])
+		created := true
+		if err := r.client.Create(ctx, newPod); err != nil {
+			if !apierrors.IsAlreadyExists(err) {
+				return fmt.Errorf("creating the ungated pod of %s: %w", klog.KObj(pod), err)
+			}
+			// Created by a previous reconcile that failed to delete the gated pod.
+			created = false
+		}
+		uid := pod.UID
+		if err := r.client.Delete(ctx, pod, client.Preconditions{UID: &uid}); client.IgnoreNotFound(err) != nil {
+			return fmt.Errorf("deleting gated pod %s: %w", klog.KObj(pod), err)
+		}
+		if created {
+			r.record.Eventf(newPod, corev1.EventTypeNormal, "Started",
+				"Admitted by clusterQueue %v", wl.Status.Admission.ClusterQueue)
+		}
+	}
+	return nil
+}
+
+// ungatedPod returns the pod to create in place of the gated one.
+func ungatedPod(pod *corev1.Pod, wl *kueue.Workload, nodeSelector map[string]string) *corev1.Pod {
+	newPod := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        ungatedPodName(pod),
+			Namespace:   pod.Namespace,
+			Labels:      make(map[string]string, len(pod.Labels)),
+			Annotations: make(map[string]string, len(pod.Annotations)+1),
+			Finalizers:  pod.Finalizers,
+		},
+		Spec: *podSetSpec(pod),
+	}
+	for k, v := range pod.Labels {
+		newPod.Labels[k] = v
+	}
+	for k, v := range pod.Annotations {
+		newPod.Annotations[k] = v
+	}
+	newPod.Annotations[constants.PodAdmittedAnnotation] = string(wl.UID)
+	if len(nodeSelector) > 0 && newPod.Spec.NodeSelector == nil {
+		newPod.Spec.NodeSelector = make(map[string]string, len(nodeSelector))
+	}
+	for k, v := range nodeSelector {
+		newPod.Spec.NodeSelector[k] = v
+	}
+	return newPod
+}
+
+// ungatedPodName returns the name of the pod that replaces the gated pod: its
+// name with a suffix from a hash of its UID, truncated to fit the maximum
+// length of a pod name.
+func ungatedPodName(pod *corev1.Pod) string {
+	hasher := fnv.New32a()
+	hasher.Write([]byte(pod.UID))
+	suffix := rand.SafeEncodeString(fmt.Sprint(hasher.Sum32()))
+	name := pod.Name
+	if maxLen := validation.DNS1123SubdomainMaxLength - len(suffix) - 1; len(name) > maxLen {
+		name = strings.TrimRight(name[:maxLen], "-.")
+	}
+	return name + "-" + suffix
+}
+
+func activePods(pods []corev1.Pod) []*corev1.Pod {
+	active := make([]*corev1.Pod, 0, len(pods))
+	for i := range pods {
+		if pods[i].DeletionTimestamp == nil {
+			active = append(active, &pods[i])
+		}
+	}
+	return active
+}
+
+func gatedPods(pods []*corev1.Pod) []*corev1.Pod {
+	var gated []*corev1.Pod
+	for _, pod := range pods {
+		if isGated(pod) {
+			gated = append(gated, pod)
+		}
+	}
+	return gated
+}
+
+func isGated(pod *corev1.Pod) bool {
+	_, ok := pod.Spec.NodeSelector[constants.AdmissionGateNodeSelector]
+	return ok
+}
+
+func podFinished(pod *corev1.Pod) bool {
+	return pod.Status.Phase == corev1.PodSucceeded || pod.Status.Phase == corev1.PodFailed
+}
+
+// finished returns the Finished condition of the workload of the group once
+// all its pods finished. The group failed if any of them failed.
+func finished(pods []*corev1.Pod) (metav1.Condition, bool) {
+	if int32(len(pods)) < totalCount(pods[0]) {
+		return metav1.Condition{}, false
+	}
+	message := "Pod group finished successfully"
+	for _, pod := range pods {
+		if !podFinished(pod) {
+			return metav1.Condition{}, false
+		}
+		if pod.Status.Phase == corev1.PodFailed {
+			message = "Pod group failed"
+		}
+	}
+	return metav1.Condition{
+		Type:    kueue.WorkloadFinished,
+		Status:  metav1.ConditionTrue,
+		Reason:  kueue.WorkloadReasonJobFinished,
+		Message: message,
+	}, true
+}
+
+// podsReady returns whether all the pods of the group run ungated, and are
+// ready or succeeded.
+func podsReady(pods []*corev1.Pod) bool {
+	ready := int32(0)
+	for _, pod := range pods {
+		if isGated(pod) {
+			continue
+		}
+		if pod.Status.Phase == corev1.PodSucceeded || podReady(pod) {
+			ready++
+		}
+	}
+	return ready >= totalCount(pods[0])
+}
+
+func podReady(pod *corev1.Pod) bool {
+	for _, c := range pod.Status.Conditions {
+		if c.Type == corev1.PodReady {
+			return c.Status == corev1.ConditionTrue
+		}
+	}
+	return false
+}
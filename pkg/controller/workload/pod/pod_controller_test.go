@@ -0,0 +1,267 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pod
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
+	corev1 "k8s.io/api/core/v1"
+	schedulingv1 "k8s.io/api/scheduling/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/validation"
+	"k8s.io/client-go/tools/record"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	kueue "sigs.k8s.io/kueue/apis/kueue/v1alpha2"
+	"sigs.k8s.io/kueue/pkg/constants"
+	utiltesting "sigs.k8s.io/kueue/pkg/util/testing"
+)
+
+func TestReconcile(t *testing.T) {
+	wlName := WorkloadName("group")
+	admission := utiltesting.MakeAdmission("cq").Flavor(corev1.ResourceCPU, "on-demand").Obj()
+	cases := map[string]struct {
+		pods         []*corev1.Pod
+		workload     *kueue.Workload
+		wantPods     []corev1.Pod
+		wantWorkload *kueue.Workload
+	}{
+		"waits for the rest of the pods of the group": {
+			pods: []*corev1.Pod{
+				utiltesting.MakePod("a", "ns").Queue("main").Group("group", 2).Managed().Gated().Obj(),
+			},
+			wantPods: []corev1.Pod{
+				*utiltesting.MakePod("a", "ns").Queue("main").Group("group", 2).Managed().Gated().Obj(),
+			},
+		},
+		"creates the workload once all the pods of the group exist": {
+			pods: []*corev1.Pod{
+				utiltesting.MakePod("a", "ns").Queue("main").Group("group", 2).Managed().Gated().Obj(),
+				utiltesting.MakePod("b", "ns").Queue("main").Group("group", 2).Managed().Gated().Obj(),
+			},
+			wantPods: []corev1.Pod{
+				*utiltesting.MakePod("a", "ns").Queue("main").Group("group", 2).Managed().Gated().Obj(),
+				*utiltesting.MakePod("b", "ns").Queue("main").Group("group", 2).Managed().Gated().Obj(),
+			},
+			wantWorkload: utiltesting.MakeWorkload(wlName, "ns").Queue("main").Priority(new(int32)).PodSets([]kueue.PodSet{{
+				Name:  "main",
+				Count: 2,
+				Spec:  utiltesting.MakePod("a", "ns").Obj().Spec,
+			}}).Obj(),
+		},
+		"ungates the pods of an admitted workload": {
+			pods: []*corev1.Pod{
+				utiltesting.MakePod("a", "ns").Queue("main").Group("group", 2).Managed().Gated().Obj(),
+				utiltesting.MakePod("b", "ns").Queue("main").Group("group", 2).Managed().Gated().Obj(),
+			},
+			workload: utiltesting.MakeWorkload(wlName, "ns").Queue("main").Count(2).Admit(admission).Obj(),
+			wantPods: []corev1.Pod{
+				*utiltesting.MakePod(ungatedName("a"), "ns").Queue("main").Group("group", 2).Managed().
+					Annotation(constants.PodAdmittedAnnotation, "wl-uid").NodeSelector("instance", "on-demand").Obj(),
+				*utiltesting.MakePod(ungatedName("b"), "ns").Queue("main").Group("group", 2).Managed().
+					Annotation(constants.PodAdmittedAnnotation, "wl-uid").NodeSelector("instance", "on-demand").Obj(),
+			},
+			wantWorkload: utiltesting.MakeWorkload(wlName, "ns").Queue("main").Count(2).Admit(admission).Obj(),
+		},
+		"deletes the gated pod whose ungated pod already exists": {
+			pods: []*corev1.Pod{
+				utiltesting.MakePod("a", "ns").Queue("main").Group("group", 1).Managed().Gated().Obj(),
+				utiltesting.MakePod(ungatedName("a"), "ns").Queue("main").Group("group", 1).Managed().
+					Annotation(constants.PodAdmittedAnnotation, "wl-uid").NodeSelector("instance", "on-demand").Obj(),
+			},
+			workload: utiltesting.MakeWorkload(wlName, "ns").Queue("main").Admit(admission).Obj(),
+			wantPods: []corev1.Pod{
+				*utiltesting.MakePod(ungatedName("a"), "ns").Queue("main").Group("group", 1).Managed().
+					Annotation(constants.PodAdmittedAnnotation, "wl-uid").NodeSelector("instance", "on-demand").Obj(),
+			},
+			wantWorkload: utiltesting.MakeWorkload(wlName, "ns").Queue("main").Admit(admission).Obj(),
+		},
+		"keeps the pods gated while the admission checks are pending": {
+			pods: []*corev1.Pod{
+				utiltesting.MakePod("a", "ns").Queue("main").Group("group", 1).Managed().Gated().Obj(),
+			},
+			workload: utiltesting.MakeWorkload(wlName, "ns").Queue("main").
+				Admit(utiltesting.MakeAdmission("cq").AdmissionChecks("check").Obj()).Obj(),
+			wantPods: []corev1.Pod{
+				*utiltesting.MakePod("a", "ns").Queue("main").Group("group", 1).Managed().Gated().Obj(),
+			},
+			wantWorkload: utiltesting.MakeWorkload(wlName, "ns").Queue("main").
+				Admit(utiltesting.MakeAdmission("cq").AdmissionChecks("check").Obj()).Obj(),
+		},
+		"deletes the running pods of an evicted workload": {
+			pods: []*corev1.Pod{
+				utiltesting.MakePod("a", "ns").Queue("main").Group("group", 2).Managed().Phase(corev1.PodRunning).Obj(),
+				utiltesting.MakePod("b", "ns").Queue("main").Group("group", 2).Managed().Phase(corev1.PodSucceeded).Obj(),
+			},
+			workload: utiltesting.MakeWorkload(wlName, "ns").Queue("main").Count(2).Obj(),
+			wantPods: []corev1.Pod{
+				*utiltesting.MakePod("b", "ns").Queue("main").Group("group", 2).Managed().Phase(corev1.PodSucceeded).Obj(),
+			},
+			wantWorkload: utiltesting.MakeWorkload(wlName, "ns").Queue("main").Count(2).Obj(),
+		},
+		"finishes the workload when all the pods finished": {
+			pods: []*corev1.Pod{
+				utiltesting.MakePod("a", "ns").Queue("main").Group("group", 2).Managed().Phase(corev1.PodSucceeded).Obj(),
+				utiltesting.MakePod("b", "ns").Queue("main").Group("group", 2).Managed().Phase(corev1.PodFailed).Obj(),
+			},
+			workload: utiltesting.MakeWorkload(wlName, "ns").Queue("main").Count(2).Admit(admission).Obj(),
+			wantPods: []corev1.Pod{
+				*utiltesting.MakePod("a", "ns").Queue("main").Group("group", 2).Managed().Phase(corev1.PodSucceeded).Obj(),
+				*utiltesting.MakePod("b", "ns").Queue("main").Group("group", 2).Managed().Phase(corev1.PodFailed).Obj(),
+			},
+			wantWorkload: utiltesting.MakeWorkload(wlName, "ns").Queue("main").Count(2).Admit(admission).
+				Condition(metav1.Condition{
+					Type:    kueue.WorkloadFinished,
+					Status:  metav1.ConditionTrue,
+					Reason:  kueue.WorkloadReasonJobFinished,
+					Message: "Pod group failed",
+				}).Obj(),
+		},
+		"deletes the workload when the pods are gone": {
+			workload: utiltesting.MakeWorkload(wlName, "ns").Queue("main").Admit(admission).Obj(),
+		},
+	}
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			scheme := utiltesting.MustGetScheme(t)
+			if err := schedulingv1.AddToScheme(scheme); err != nil {
+				t.Fatalf("Failed adding scheduling scheme: %v", err)
+			}
+			ctx := context.Background()
+			builder := fake.NewClientBuilder().WithScheme(scheme).
+				WithObjects(utiltesting.MakeResourceFlavor("on-demand").Label("instance", "on-demand").Obj())
+			for _, pod := range tc.pods {
+				pod.UID = types.UID(pod.Name + "-uid")
+				builder = builder.WithObjects(pod)
+			}
+			if tc.workload != nil {
+				tc.workload.UID = "wl-uid"
+				tc.workload.Labels = map[string]string{constants.PodGroupNameLabel: "group"}
+				builder = builder.WithObjects(tc.workload)
+			}
+			cl := builder.Build()
+			r := NewReconciler(cl, record.NewFakeRecorder(10))
+
+			if _, err := r.Reconcile(ctx, ctrl.Request{NamespacedName: types.NamespacedName{Namespace: "ns", Name: wlName}}); err != nil {
+				t.Fatalf("Reconcile returned error: %v", err)
+			}
+
+			var gotPods corev1.PodList
+			if err := cl.List(ctx, &gotPods); err != nil {
+				t.Fatalf("Failed listing pods: %v", err)
+			}
+			podOpts := []cmp.Option{
+				cmpopts.IgnoreFields(metav1.ObjectMeta{}, "ResourceVersion", "UID"),
+				cmpopts.IgnoreFields(corev1.Pod{}, "TypeMeta", "Status"),
+				cmpopts.EquateEmpty(),
+			}
+			if diff := cmp.Diff(tc.wantPods, gotPods.Items, podOpts...); diff != "" {
+				t.Errorf("Unexpected pods (-want,+got):\n%s", diff)
+			}
+
+			var gotWl kueue.Workload
+			err := cl.Get(ctx, types.NamespacedName{Namespace: "ns", Name: wlName}, &gotWl)
+			if tc.wantWorkload == nil {
+				if !apierrors.IsNotFound(err) {
+					t.Errorf("Got workload %v, error %v, want not found", gotWl, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Failed getting the workload: %v", err)
+			}
+			wlOpts := []cmp.Option{
+				cmpopts.IgnoreFields(kueue.Workload{}, "TypeMeta", "ObjectMeta"),
+				cmpopts.IgnoreFields(metav1.Condition{}, "LastTransitionTime"),
+				cmpopts.EquateEmpty(),
+			}
+			if diff := cmp.Diff(tc.wantWorkload, &gotWl, wlOpts...); diff != "" {
+				t.Errorf("Unexpected workload (-want,+got):\n%s", diff)
+			}
+			if gotWl.Labels[constants.PodGroupNameLabel] != "group" {
+				t.Errorf("Got workload labels %v, want the pod group label", gotWl.Labels)
+			}
+		})
+	}
+}
+
+// ungatedName returns the name of the ungated pod that replaces the gated pod
+// with the name, with the UID set by TestReconcile.
+func ungatedName(name string) string {
+	return ungatedPodName(&corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: name, UID: types.UID(name + "-uid")}})
+}
+
+func TestUngatedPodName(t *testing.T) {
+	cases := map[string]string{
+		"short name": "a",
+		"long name":  strings.Repeat("a", validation.DNS1123SubdomainMaxLength),
+		"long name with a separator at the cut": strings.Repeat("a", validation.DNS1123SubdomainMaxLength-12) + "." +
+			strings.Repeat("a", 11),
+	}
+	for name, podName := range cases {
+		t.Run(name, func(t *testing.T) {
+			pod := &corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: podName, UID: "uid"}}
+			got := ungatedPodName(pod)
+			if errs := validation.IsDNS1123Subdomain(got); len(errs) > 0 {
+				t.Errorf("Got invalid name %q: %v", got, errs)
+			}
+			if got == podName {
+				t.Errorf("Got the name of the gated pod")
+			}
+			if again := ungatedPodName(pod.DeepCopy()); again != got {
+				t.Errorf("Got name %q, then %q for the same pod", got, again)
+			}
+			pod.UID = "other-uid"
+			if other := ungatedPodName(pod); other == got {
+				t.Errorf("Got the same name %q for a pod with another UID", got)
+			}
+		})
+	}
+}
+
+func TestPodSets(t *testing.T) {
+	pods := []*corev1.Pod{
+		utiltesting.MakePod("a", "ns").Request(corev1.ResourceCPU, "1").Gated().Obj(),
+		utiltesting.MakePod("b", "ns").Request(corev1.ResourceCPU, "2").Gated().Obj(),
+		utiltesting.MakePod("c", "ns").Request(corev1.ResourceCPU, "1").Gated().Obj(),
+	}
+	// The volumes injected for the service account don't change the shape.
+	pods[2].Spec.Volumes = []corev1.Volume{{Name: "kube-api-access-abcde"}}
+	got := podSets(pods)
+	want := []kueue.PodSet{
+		{
+			Name:  "main-0",
+			Count: 2,
+			Spec:  utiltesting.MakePod("a", "ns").Request(corev1.ResourceCPU, "1").Obj().Spec,
+		},
+		{
+			Name:  "main-1",
+			Count: 1,
+			Spec:  utiltesting.MakePod("b", "ns").Request(corev1.ResourceCPU, "2").Obj().Spec,
+		},
+	}
+	if diff := cmp.Diff(want, got, cmpopts.EquateEmpty()); diff != "" {
+		t.Errorf("Unexpected pod sets (-want,+got):\n%s", diff)
+	}
+}
@@ -0,0 +1,209 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pod
+
+import (
+	"context"
+	"strconv"
+
+	corev1 "k8s.io/api/core/v1"
+	apivalidation "k8s.io/apimachinery/pkg/api/validation"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	"k8s.io/klog/v2"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/webhook"
+
+	kueue "sigs.k8s.io/kueue/apis/kueue/v1alpha2"
+	"sigs.k8s.io/kueue/pkg/constants"
+	"sigs.k8s.io/kueue/pkg/workload"
+)
+
+type PodWebhook struct {
+	client  client.Client
+	enabled bool
+}
+
+// SetupWebhook configures the webhook for plain Pods. The webhook is only
+// called for the Pods with the queue name label, see the objectSelector in
+// config/components/webhook. It is served even if the integration isn't
+// enabled, as it is in the webhook configuration, but then it leaves the
+// pods unchanged.
+func SetupWebhook(mgr ctrl.Manager, opts ...Option) error {
+	var options options
+	for _, opt := range opts {
+		opt(&options)
+	}
+	wh := &PodWebhook{
+		client:  mgr.GetClient(),
+		enabled: options.enabled,
+	}
+	return ctrl.NewWebhookManagedBy(mgr).
+		For(&corev1.Pod{}).
+		WithDefaulter(wh).
+		WithValidator(wh).
+		Complete()
+}
+
+// +kubebuilder:webhook:path=/mutate--v1-pod,mutating=true,failurePolicy=fail,sideEffects=None,groups="",resources=pods,verbs=create,versions=v1,name=mpod.kb.io,admissionReviewVersions=v1
+
+var _ webhook.CustomDefaulter = &PodWebhook{}
+
+// Default implements webhook.CustomDefaulter so a webhook will be registered for the type
+func (w *PodWebhook) Default(ctx context.Context, obj runtime.Object) error {
+	pod := obj.(*corev1.Pod)
+	log := ctrl.LoggerFrom(ctx).WithName("pod-webhook")
+	log.V(5).Info("Applying defaults", "pod", klog.KObj(pod))
+
+	if !w.enabled {
+		return nil
+	}
+	// The quota of the pods of Jobs and other workload controllers is
+	// accounted through their owners.
+	if metav1.GetControllerOf(pod) != nil || queueName(pod) == "" {
+		return nil
+	}
+	if pod.Labels[constants.PodGroupNameLabel] == "" && pod.Name != "" {
+		pod.Labels[constants.PodGroupNameLabel] = pod.Name
+	}
+	pod.Labels[constants.ManagedLabel] = "true"
+
+	admitted, err := w.recreatedForAdmittedWorkload(ctx, pod)
+	if err != nil {
+		return err
+	}
+	if admitted {
+		log.V(5).Info("Keeping the pod of an admitted pod group ungated", "pod", klog.KObj(pod))
+		return nil
+	}
+	delete(pod.Annotations, constants.PodAdmittedAnnotation)
+	if pod.Spec.NodeSelector == nil {
+		pod.Spec.NodeSelector = make(map[string]string, 1)
+	}
+	pod.Spec.NodeSelector[constants.AdmissionGateNodeSelector] = gateValue
+	return nil
+}
+
+// recreatedForAdmittedWorkload returns whether the pod was recreated by the
+// controller, without the admission gate, because the Workload of its pod
+// group is admitted. Any other pod is gated, even if it sets the annotation.
+func (w *PodWebhook) recreatedForAdmittedWorkload(ctx context.Context, pod *corev1.Pod) (bool, error) {
+	uid, ok := pod.Annotations[constants.PodAdmittedAnnotation]
+	if !ok {
+		return false, nil
+	}
+	var wl kueue.Workload
+	key := types.NamespacedName{Namespace: pod.Namespace, Name: WorkloadName(pod.Labels[constants.PodGroupNameLabel])}
+	if err := w.client.Get(ctx, key, &wl); err != nil {
+		return false, client.IgnoreNotFound(err)
+	}
+	return string(wl.UID) == uid && workload.IsAdmitted(&wl), nil
+}
+
+// +kubebuilder:webhook:path=/validate--v1-pod,mutating=false,failurePolicy=fail,sideEffects=None,groups="",resources=pods,verbs=create;update,versions=v1,name=vpod.kb.io,admissionReviewVersions=v1
+
+var _ webhook.CustomValidator = &PodWebhook{}
+
+// ValidateCreate implements webhook.CustomValidator so a webhook will be registered for the type
+func (w *PodWebhook) ValidateCreate(ctx context.Context, obj runtime.Object) error {
+	pod := obj.(*corev1.Pod)
+	log := ctrl.LoggerFrom(ctx).WithName("pod-webhook")
+	log.V(5).Info("Validating create", "pod", klog.KObj(pod))
+
+	if !w.enabled {
+		return nil
+	}
+	return validateCreate(pod).ToAggregate()
+}
+
+func validateCreate(pod *corev1.Pod) field.ErrorList {
+	if !isManaged(pod) {
+		return nil
+	}
+	var allErrs field.ErrorList
+	labelsPath := field.NewPath("metadata", "labels")
+	if pod.Labels[constants.PodGroupNameLabel] == "" {
+		allErrs = append(allErrs, field.Required(labelsPath.Key(constants.PodGroupNameLabel), "must be set for pods without a name"))
+	}
+	if v, ok := pod.Annotations[constants.PodGroupTotalCountAnnotation]; ok {
+		if _, err := strconv.ParseInt(v, 10, 32); err != nil {
+			allErrs = append(allErrs, field.Invalid(field.NewPath("metadata", "annotations").Key(constants.PodGroupTotalCountAnnotation), v, "must be an integer"))
+		} else if totalCount(pod) < 1 {
+			allErrs = append(allErrs, field.Invalid(field.NewPath("metadata", "annotations").Key(constants.PodGroupTotalCountAnnotation), v, "must be greater than or equal to 1"))
+		}
+	}
+	return allErrs
+}
+
+// ValidateUpdate implements webhook.CustomValidator so a webhook will be registered for the type
+func (w *PodWebhook) ValidateUpdate(ctx context.Context, oldObj, newObj runtime.Object) error {
+	oldPod := oldObj.(*corev1.Pod)
+	newPod := newObj.(*corev1.Pod)
+	log := ctrl.LoggerFrom(ctx).WithName("pod-webhook")
+	log.V(5).Info("Validating update", "pod", klog.KObj(newPod))
+
+	if !w.enabled {
+		return nil
+	}
+	return validateUpdate(oldPod, newPod).ToAggregate()
+}
+
+// validateUpdate keeps a managed pod in its pod group and queue, as the
+// Workload of the group was created for them.
+func validateUpdate(oldPod, newPod *corev1.Pod) field.ErrorList {
+	if !isManaged(oldPod) {
+		return nil
+	}
+	var allErrs field.ErrorList
+	labelsPath := field.NewPath("metadata", "labels")
+	for _, k := range []string{constants.ManagedLabel, constants.PodGroupNameLabel, constants.QueueAnnotation} {
+		allErrs = append(allErrs, apivalidation.ValidateImmutableField(newPod.Labels[k], oldPod.Labels[k], labelsPath.Key(k))...)
+	}
+	countPath := field.NewPath("metadata", "annotations").Key(constants.PodGroupTotalCountAnnotation)
+	allErrs = append(allErrs, apivalidation.ValidateImmutableField(newPod.Annotations[constants.PodGroupTotalCountAnnotation], oldPod.Annotations[constants.PodGroupTotalCountAnnotation], countPath)...)
+	return allErrs
+}
+
+// ValidateDelete implements webhook.CustomValidator so a webhook will be registered for the type
+func (w *PodWebhook) ValidateDelete(ctx context.Context, obj runtime.Object) error {
+	return nil
+}
+
+func queueName(pod *corev1.Pod) string {
+	return pod.Labels[constants.QueueAnnotation]
+}
+
+func isManaged(pod *corev1.Pod) bool {
+	return pod.Labels[constants.ManagedLabel] == "true"
+}
+
+// totalCount returns the number of pods of the pod group of the pod. An
+// invalid count counts as one.
+func totalCount(pod *corev1.Pod) int32 {
+	v, ok := pod.Annotations[constants.PodGroupTotalCountAnnotation]
+	if !ok {
+		return 1
+	}
+	count, err := strconv.ParseInt(v, 10, 32)
+	if err != nil {
+		return 1
+	}
+	return int32(count)
+}
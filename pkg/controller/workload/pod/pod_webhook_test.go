@@ -0,0 +1,189 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pod
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"sigs.k8s.io/kueue/pkg/constants"
+	utiltesting "sigs.k8s.io/kueue/pkg/util/testing"
+)
+
+func TestDefault(t *testing.T) {
+	admitted := utiltesting.MakeWorkload(WorkloadName("group"), "ns").Admit(utiltesting.MakeAdmission("cq").Obj()).Obj()
+	admitted.UID = "wl-uid"
+	job := utiltesting.MakeJob("job", "ns").Obj()
+	withController := func(pod *corev1.Pod) *corev1.Pod {
+		pod.OwnerReferences = []metav1.OwnerReference{*metav1.NewControllerRef(job, batchv1.SchemeGroupVersion.WithKind("Job"))}
+		return pod
+	}
+	cases := map[string]struct {
+		pod      *corev1.Pod
+		disabled bool
+		wantPod  *corev1.Pod
+	}{
+		"pod is ignored when the integration isn't enabled": {
+			pod:      utiltesting.MakePod("pod", "ns").Queue("main").Group("group", 2).Obj(),
+			disabled: true,
+			wantPod:  utiltesting.MakePod("pod", "ns").Queue("main").Group("group", 2).Obj(),
+		},
+		"pod without a queue name is ignored": {
+			pod:     utiltesting.MakePod("pod", "ns").Obj(),
+			wantPod: utiltesting.MakePod("pod", "ns").Obj(),
+		},
+		"pod with a controller is ignored": {
+			pod:     withController(utiltesting.MakePod("pod", "ns").Queue("main").Obj()),
+			wantPod: withController(utiltesting.MakePod("pod", "ns").Queue("main").Obj()),
+		},
+		"pod with a queue name is gated": {
+			pod:     utiltesting.MakePod("pod", "ns").Queue("main").Group("group", 2).Obj(),
+			wantPod: utiltesting.MakePod("pod", "ns").Queue("main").Group("group", 2).Managed().Gated().Obj(),
+		},
+		"pod without a group is a group on its own": {
+			pod:     utiltesting.MakePod("pod", "ns").Queue("main").Obj(),
+			wantPod: utiltesting.MakePod("pod", "ns").Queue("main").Label(constants.PodGroupNameLabel, "pod").Managed().Gated().Obj(),
+		},
+		"pod recreated for the admitted workload isn't gated": {
+			pod: utiltesting.MakePod("pod", "ns").Queue("main").Group("group", 2).
+				Annotation(constants.PodAdmittedAnnotation, "wl-uid").Obj(),
+			wantPod: utiltesting.MakePod("pod", "ns").Queue("main").Group("group", 2).Managed().
+				Annotation(constants.PodAdmittedAnnotation, "wl-uid").Obj(),
+		},
+		"pod claiming the admission of another workload is gated": {
+			pod: utiltesting.MakePod("pod", "ns").Queue("main").Group("group", 2).
+				Annotation(constants.PodAdmittedAnnotation, "other-uid").Obj(),
+			wantPod: utiltesting.MakePod("pod", "ns").Queue("main").Group("group", 2).Managed().Gated().Obj(),
+		},
+		"pod claiming the admission of a missing workload is gated": {
+			pod: utiltesting.MakePod("pod", "ns").Queue("main").Group("other", 2).
+				Annotation(constants.PodAdmittedAnnotation, "wl-uid").Obj(),
+			wantPod: utiltesting.MakePod("pod", "ns").Queue("main").Group("other", 2).Managed().Gated().Obj(),
+		},
+	}
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			cl := fake.NewClientBuilder().WithScheme(utiltesting.MustGetScheme(t)).WithObjects(admitted).Build()
+			w := &PodWebhook{client: cl, enabled: !tc.disabled}
+
+			if err := w.Default(context.Background(), tc.pod); err != nil {
+				t.Fatalf("Default returned error: %v", err)
+			}
+			if diff := cmp.Diff(tc.wantPod, tc.pod, cmpopts.EquateEmpty()); diff != "" {
+				t.Errorf("Unexpected pod (-want,+got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestValidateCreate(t *testing.T) {
+	labelsPath := field.NewPath("metadata", "labels")
+	countPath := field.NewPath("metadata", "annotations").Key(constants.PodGroupTotalCountAnnotation)
+	cases := map[string]struct {
+		pod      *corev1.Pod
+		wantErrs field.ErrorList
+	}{
+		"unmanaged pod": {
+			pod: utiltesting.MakePod("pod", "ns").Annotation(constants.PodGroupTotalCountAnnotation, "-1").Obj(),
+		},
+		"valid pod group": {
+			pod: utiltesting.MakePod("pod", "ns").Queue("main").Group("group", 2).Managed().Obj(),
+		},
+		"pod without a group": {
+			pod: utiltesting.MakePod("", "ns").Queue("main").Managed().Obj(),
+			wantErrs: field.ErrorList{
+				field.Required(labelsPath.Key(constants.PodGroupNameLabel), ""),
+			},
+		},
+		"invalid total count": {
+			pod: utiltesting.MakePod("pod", "ns").Queue("main").Managed().
+				Label(constants.PodGroupNameLabel, "group").Annotation(constants.PodGroupTotalCountAnnotation, "two").Obj(),
+			wantErrs: field.ErrorList{
+				field.Invalid(countPath, "two", ""),
+			},
+		},
+		"total count lower than one": {
+			pod: utiltesting.MakePod("pod", "ns").Queue("main").Group("group", 0).Managed().Obj(),
+			wantErrs: field.ErrorList{
+				field.Invalid(countPath, "0", ""),
+			},
+		},
+	}
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			gotErrs := validateCreate(tc.pod)
+			if diff := cmp.Diff(tc.wantErrs, gotErrs, cmpopts.IgnoreFields(field.Error{}, "Detail")); diff != "" {
+				t.Errorf("validateCreate() mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestValidateUpdate(t *testing.T) {
+	labelsPath := field.NewPath("metadata", "labels")
+	cases := map[string]struct {
+		oldPod   *corev1.Pod
+		newPod   *corev1.Pod
+		wantErrs field.ErrorList
+	}{
+		"unmanaged pod": {
+			oldPod: utiltesting.MakePod("pod", "ns").Queue("main").Obj(),
+			newPod: utiltesting.MakePod("pod", "ns").Queue("other").Obj(),
+		},
+		"other labels can change": {
+			oldPod: utiltesting.MakePod("pod", "ns").Queue("main").Group("group", 2).Managed().Obj(),
+			newPod: utiltesting.MakePod("pod", "ns").Queue("main").Group("group", 2).Managed().Label("app", "test").Obj(),
+		},
+		"group can't change": {
+			oldPod: utiltesting.MakePod("pod", "ns").Queue("main").Group("group", 2).Managed().Obj(),
+			newPod: utiltesting.MakePod("pod", "ns").Queue("main").Group("other", 2).Managed().Obj(),
+			wantErrs: field.ErrorList{
+				field.Invalid(labelsPath.Key(constants.PodGroupNameLabel), "other", ""),
+			},
+		},
+		"queue can't change": {
+			oldPod: utiltesting.MakePod("pod", "ns").Queue("main").Group("group", 2).Managed().Obj(),
+			newPod: utiltesting.MakePod("pod", "ns").Queue("other").Group("group", 2).Managed().Obj(),
+			wantErrs: field.ErrorList{
+				field.Invalid(labelsPath.Key(constants.QueueAnnotation), "other", ""),
+			},
+		},
+		"total count can't change": {
+			oldPod: utiltesting.MakePod("pod", "ns").Queue("main").Group("group", 2).Managed().Obj(),
+			newPod: utiltesting.MakePod("pod", "ns").Queue("main").Group("group", 3).Managed().Obj(),
+			wantErrs: field.ErrorList{
+				field.Invalid(field.NewPath("metadata", "annotations").Key(constants.PodGroupTotalCountAnnotation), "3", ""),
+			},
+		},
+	}
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			gotErrs := validateUpdate(tc.oldPod, tc.newPod)
+			if diff := cmp.Diff(tc.wantErrs, gotErrs, cmpopts.IgnoreFields(field.Error{}, "Detail")); diff != "" {
+				t.Errorf("validateUpdate() mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
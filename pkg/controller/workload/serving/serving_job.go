@@ -0,0 +1,262 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package serving integrates Deployments and StatefulSets with Kueue, so
+// that serving capacity is also accounted against the ClusterQueue quotas.
+// A Deployment or StatefulSet is held by scaling it to zero replicas, and
+// keeping the replicas to run in the HeldReplicasAnnotation, until its
+// Workload is admitted. Only the objects with a queue name are managed.
+package serving
+
+import (
+	"strconv"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
+	"k8s.io/utils/pointer"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	kueue "sigs.k8s.io/kueue/apis/kueue/v1alpha2"
+	"sigs.k8s.io/kueue/pkg/constants"
+	"sigs.k8s.io/kueue/pkg/controller/workload/jobframework"
+)
+
+const podSetName = "main"
+
+func init() {
+	utilruntime.Must(jobframework.RegisterIntegration(jobframework.Integration{
+		Name: "apps/deployment",
+		GVK:  appsv1.SchemeGroupVersion.WithKind("Deployment"),
+		NewJob: func() jobframework.GenericJob {
+			return NewDeploymentJob(&appsv1.Deployment{})
+		},
+		RequiresQueueName: true,
+	}))
+	utilruntime.Must(jobframework.RegisterIntegration(jobframework.Integration{
+		Name: "apps/statefulset",
+		GVK:  appsv1.SchemeGroupVersion.WithKind("StatefulSet"),
+		NewJob: func() jobframework.GenericJob {
+			return NewStatefulSetJob(&appsv1.StatefulSet{})
+		},
+		RequiresQueueName: true,
+	}))
+}
+
+// Job implements jobframework.GenericJob for Deployments and StatefulSets.
+// The workload has a single pod set with a pod for each replica.
+type Job struct {
+	obj           client.Object
+	replicas      **int32
+	template      *corev1.PodTemplateSpec
+	readyReplicas *int32
+}
+
+var _ jobframework.GenericJob = &Job{}
+var _ jobframework.JobWithRestoreNodeSelectors = &Job{}
+var _ jobframework.JobWithReclaimablePods = &Job{}
+var _ jobframework.IdleJob = &Job{}
+var _ jobframework.JobWithHeldScaleUp = &Job{}
+
+//+kubebuilder:rbac:groups=apps,resources=deployments;statefulsets,verbs=get;list;watch;update;patch
+//+kubebuilder:rbac:groups=apps,resources=deployments/finalizers;statefulsets/finalizers,verbs=get;update;patch
+
+// The objects are held on creation by the webhook of the jobframework.
+// +kubebuilder:webhook:path=/mutate-apps-v1-deployment,mutating=true,failurePolicy=fail,sideEffects=None,groups=apps,resources=deployments,verbs=create,versions=v1,name=mdeployment.kb.io,admissionReviewVersions=v1
+// +kubebuilder:webhook:path=/mutate-apps-v1-statefulset,mutating=true,failurePolicy=fail,sideEffects=None,groups=apps,resources=statefulsets,verbs=create,versions=v1,name=mstatefulset.kb.io,admissionReviewVersions=v1
+
+// NewDeploymentJob returns the job backed by the Deployment.
+func NewDeploymentJob(d *appsv1.Deployment) *Job {
+	return &Job{
+		obj:           d,
+		replicas:      &d.Spec.Replicas,
+		template:      &d.Spec.Template,
+		readyReplicas: &d.Status.ReadyReplicas,
+	}
+}
+
+// NewStatefulSetJob returns the job backed by the StatefulSet.
+func NewStatefulSetJob(s *appsv1.StatefulSet) *Job {
+	return &Job{
+		obj:           s,
+		replicas:      &s.Spec.Replicas,
+		template:      &s.Spec.Template,
+		readyReplicas: &s.Status.ReadyReplicas,
+	}
+}
+
+func (j *Job) Object() client.Object {
+	return j.obj
+}
+
+func (j *Job) QueueName() string {
+	return j.obj.GetAnnotations()[constants.QueueAnnotation]
+}
+
+// IsSuspended returns whether the job is held, scaled to zero replicas.
+func (j *Job) IsSuspended() bool {
+	_, ok := j.obj.GetAnnotations()[constants.HeldReplicasAnnotation]
+	return ok
+}
+
+// Suspend scales the job to zero replicas, keeping the replicas to run in
+// an annotation.
+func (j *Job) Suspend() {
+	if j.IsSuspended() {
+		*j.replicas = pointer.Int32(0)
+		return
+	}
+	annotations := j.obj.GetAnnotations()
+	if annotations == nil {
+		annotations = make(map[string]string, 1)
+	}
+	annotations[constants.HeldReplicasAnnotation] = strconv.Itoa(int(j.Replicas()))
+	j.obj.SetAnnotations(annotations)
+	*j.replicas = pointer.Int32(0)
+}
+
+func (j *Job) RunWithNodeSelectors(nodeSelectors []map[string]string) {
+	if len(nodeSelectors) > 0 && len(nodeSelectors[0]) > 0 {
+		if j.template.Spec.NodeSelector == nil {
+			j.template.Spec.NodeSelector = make(map[string]string, len(nodeSelectors[0]))
+		}
+		for k, v := range nodeSelectors[0] {
+			j.template.Spec.NodeSelector[k] = v
+		}
+	}
+	if j.IsSuspended() {
+		*j.replicas = pointer.Int32(j.Replicas())
+		annotations := j.obj.GetAnnotations()
+		delete(annotations, constants.HeldReplicasAnnotation)
+		j.obj.SetAnnotations(annotations)
+	}
+}
+
+// RestoreNodeSelectors sets the node selector of the pod template back to
+// the one of the pod set, removing the one injected when the job started.
+func (j *Job) RestoreNodeSelectors(podSets []kueue.PodSet) {
+	if len(podSets) != 1 {
+		return
+	}
+	j.template.Spec.NodeSelector = nil
+	for k, v := range podSets[0].Spec.NodeSelector {
+		if j.template.Spec.NodeSelector == nil {
+			j.template.Spec.NodeSelector = make(map[string]string, len(podSets[0].Spec.NodeSelector))
+		}
+		j.template.Spec.NodeSelector[k] = v
+	}
+}
+
+// Replicas returns the replicas that the job runs, or runs once admitted
+// if it's held. An invalid number of held replicas counts as zero.
+func (j *Job) Replicas() int32 {
+	if v, ok := j.obj.GetAnnotations()[constants.HeldReplicasAnnotation]; ok {
+		replicas, err := strconv.ParseInt(v, 10, 32)
+		if err != nil || replicas < 0 {
+			return 0
+		}
+		return int32(replicas)
+	}
+	return pointer.Int32Deref(*j.replicas, 1)
+}
+
+// IsIdle returns whether the job is scaled to zero, so that it doesn't need
+// quota.
+func (j *Job) IsIdle() bool {
+	return j.Replicas() == 0
+}
+
+func (j *Job) PodSets() []kueue.PodSet {
+	return []kueue.PodSet{
+		{
+			Name:  podSetName,
+			Spec:  *j.template.Spec.DeepCopy(),
+			Count: j.Replicas(),
+		},
+	}
+}
+
+// EquivalentToWorkload returns whether the pod template matches the one of
+// the workload, and the held job has the replicas of the workload. A running
+// job keeps its workload when it scales: it's held at the admitted replicas
+// when it scales up, see HoldScaleUp, and it releases the quota of the
+// removed replicas when it scales down.
+func (j *Job) EquivalentToWorkload(wl *kueue.Workload) bool {
+	if len(wl.Spec.PodSets) != 1 {
+		return false
+	}
+	ps := &wl.Spec.PodSets[0]
+	if j.IsSuspended() && ps.Count != j.Replicas() {
+		return false
+	}
+	// nodeSelector may change, hence we are not checking for
+	// equality of the whole spec.
+	return equality.Semantic.DeepEqual(j.template.Spec.InitContainers, ps.Spec.InitContainers) &&
+		equality.Semantic.DeepEqual(j.template.Spec.Containers, ps.Spec.Containers)
+}
+
+// Finished returns false, as serving workloads run until they are deleted
+// or scaled to zero.
+func (j *Job) Finished() (metav1.Condition, bool) {
+	return metav1.Condition{}, false
+}
+
+func (j *Job) PodsReady() bool {
+	return *j.readyReplicas >= j.Replicas()
+}
+
+// HoldScaleUp scales the running job back to the replicas that the workload
+// has quota for, if it scaled up beyond them, so that its running replicas
+// aren't stopped.
+func (j *Job) HoldScaleUp(wl *kueue.Workload) (int32, bool) {
+	if j.IsSuspended() || len(wl.Spec.PodSets) != 1 {
+		return 0, false
+	}
+	admitted := wl.Spec.PodSets[0].Count - reclaimableCount(wl)
+	requested := j.Replicas()
+	if requested <= admitted {
+		return 0, false
+	}
+	*j.replicas = pointer.Int32(admitted)
+	return requested, true
+}
+
+// ReclaimablePods returns the pods of the workload whose quota is no longer
+// needed, because the running job scaled down after it was admitted.
+func (j *Job) ReclaimablePods(wl *kueue.Workload) []kueue.ReclaimablePod {
+	if j.IsSuspended() || len(wl.Spec.PodSets) != 1 {
+		return nil
+	}
+	ps := &wl.Spec.PodSets[0]
+	if j.Replicas() >= ps.Count {
+		return nil
+	}
+	return []kueue.ReclaimablePod{{
+		Name:  ps.Name,
+		Count: ps.Count - j.Replicas(),
+	}}
+}
+
+func reclaimableCount(wl *kueue.Workload) int32 {
+	for _, rp := range wl.Status.ReclaimablePods {
+		if rp.Name == wl.Spec.PodSets[0].Name {
+			return rp.Count
+		}
+	}
+	return 0
+}
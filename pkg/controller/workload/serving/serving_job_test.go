@@ -0,0 +1,257 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package serving
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/utils/pointer"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	kueue "sigs.k8s.io/kueue/apis/kueue/v1alpha2"
+	"sigs.k8s.io/kueue/pkg/constants"
+	"sigs.k8s.io/kueue/pkg/controller/workload/jobframework"
+	"sigs.k8s.io/kueue/pkg/controller/workload/jobframework/conformance"
+)
+
+var podSpec = corev1.PodSpec{
+	NodeSelector: map[string]string{"provisioning": "spot"},
+	Containers: []corev1.Container{{
+		Name:  "c",
+		Image: "pause",
+		Resources: corev1.ResourceRequirements{
+			Requests: corev1.ResourceList{corev1.ResourceCPU: resource.MustParse("1")},
+		},
+	}},
+}
+
+func objectMeta(annotations map[string]string) metav1.ObjectMeta {
+	annotations[constants.QueueAnnotation] = "queue"
+	return metav1.ObjectMeta{Name: "server", Namespace: "ns", Annotations: annotations}
+}
+
+// makeDeployment returns a Deployment with the replicas, held if held is
+// set.
+func makeDeployment(replicas int32, held bool) *appsv1.Deployment {
+	annotations := make(map[string]string)
+	if held {
+		annotations[constants.HeldReplicasAnnotation] = "3"
+		replicas = 0
+	}
+	return &appsv1.Deployment{
+		ObjectMeta: objectMeta(annotations),
+		Spec: appsv1.DeploymentSpec{
+			Replicas: pointer.Int32(replicas),
+			Template: corev1.PodTemplateSpec{Spec: *podSpec.DeepCopy()},
+		},
+	}
+}
+
+func TestServingJobConformance(t *testing.T) {
+	cases := map[string]func() jobframework.GenericJob{
+		"Deployment": func() jobframework.GenericJob {
+			return NewDeploymentJob(makeDeployment(0, true))
+		},
+		"StatefulSet": func() jobframework.GenericJob {
+			return NewStatefulSetJob(&appsv1.StatefulSet{
+				ObjectMeta: objectMeta(map[string]string{constants.HeldReplicasAnnotation: "3"}),
+				Spec: appsv1.StatefulSetSpec{
+					Replicas: pointer.Int32(0),
+					Template: corev1.PodTemplateSpec{Spec: *podSpec.DeepCopy()},
+				},
+			})
+		},
+	}
+	for name, newJob := range cases {
+		t.Run(name, func(t *testing.T) {
+			conformance.Run(t, conformance.Suite{
+				NewJob: newJob,
+				WantPodSets: []kueue.PodSet{
+					{
+						Name:  "main",
+						Spec:  podSpec,
+						Count: 3,
+					},
+				},
+				WantQueueName: "queue",
+				SetPodsReady: func(job jobframework.GenericJob) {
+					*job.(*Job).readyReplicas = 3
+				},
+			})
+		})
+	}
+}
+
+func TestHoldAndRun(t *testing.T) {
+	job := NewDeploymentJob(makeDeployment(5, false))
+	job.Suspend()
+	d := job.Object().(*appsv1.Deployment)
+	if *d.Spec.Replicas != 0 || d.Annotations[constants.HeldReplicasAnnotation] != "5" {
+		t.Errorf("Held deployment has %d replicas and annotation %q, want 0 replicas and annotation \"5\"", *d.Spec.Replicas, d.Annotations[constants.HeldReplicasAnnotation])
+	}
+	if got := job.Replicas(); got != 5 {
+		t.Errorf("Replicas()=%d, want 5", got)
+	}
+	job.RunWithNodeSelectors(nil)
+	if _, ok := d.Annotations[constants.HeldReplicasAnnotation]; ok || *d.Spec.Replicas != 5 {
+		t.Errorf("Running deployment has %d replicas and annotations %v, want 5 replicas and no held replicas", *d.Spec.Replicas, d.Annotations)
+	}
+}
+
+func TestScaleAfterAdmission(t *testing.T) {
+	wl := &kueue.Workload{
+		Spec: kueue.WorkloadSpec{
+			PodSets: []kueue.PodSet{{Name: "main", Spec: podSpec, Count: 3}},
+		},
+	}
+	cases := map[string]struct {
+		replicas        int32
+		reclaimablePods []kueue.ReclaimablePod
+		wantHeld        bool
+		wantReplicas    int32
+		wantReclaimable []kueue.ReclaimablePod
+	}{
+		"same replicas": {
+			replicas:     3,
+			wantReplicas: 3,
+		},
+		"scaled down": {
+			replicas:        1,
+			wantReplicas:    1,
+			wantReclaimable: []kueue.ReclaimablePod{{Name: "main", Count: 2}},
+		},
+		"scaled up": {
+			replicas:     4,
+			wantHeld:     true,
+			wantReplicas: 3,
+		},
+		"scaled up after a scale down released the quota": {
+			replicas:        3,
+			reclaimablePods: []kueue.ReclaimablePod{{Name: "main", Count: 2}},
+			wantHeld:        true,
+			wantReplicas:    1,
+			wantReclaimable: []kueue.ReclaimablePod{{Name: "main", Count: 2}},
+		},
+	}
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			wl := wl.DeepCopy()
+			wl.Status.ReclaimablePods = tc.reclaimablePods
+			job := NewDeploymentJob(makeDeployment(tc.replicas, false))
+			if !job.EquivalentToWorkload(wl) {
+				t.Errorf("Running job is not equivalent to its workload")
+			}
+			requested, held := job.HoldScaleUp(wl)
+			if held != tc.wantHeld {
+				t.Errorf("HoldScaleUp() held=%t, want %t", held, tc.wantHeld)
+			}
+			if held && requested != tc.replicas {
+				t.Errorf("HoldScaleUp() requested=%d, want %d", requested, tc.replicas)
+			}
+			if got := job.Replicas(); got != tc.wantReplicas {
+				t.Errorf("Replicas()=%d, want %d", got, tc.wantReplicas)
+			}
+			if diff := cmp.Diff(tc.wantReclaimable, job.ReclaimablePods(wl)); diff != "" {
+				t.Errorf("Unexpected reclaimable pods (-want,+got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestHeldJobWithOtherReplicas(t *testing.T) {
+	wl := &kueue.Workload{
+		Spec: kueue.WorkloadSpec{
+			PodSets: []kueue.PodSet{{Name: "main", Spec: podSpec, Count: 5}},
+		},
+	}
+	job := NewDeploymentJob(makeDeployment(0, true))
+	if job.EquivalentToWorkload(wl) {
+		t.Errorf("Held job with 3 replicas is equivalent to a workload with 5 pods")
+	}
+	if _, held := job.HoldScaleUp(wl); held {
+		t.Errorf("HoldScaleUp() held a job that isn't running")
+	}
+}
+
+func TestWebhookDefault(t *testing.T) {
+	integration, ok := jobframework.GetIntegration("apps/deployment")
+	if !ok {
+		t.Fatal("Deployment integration is not registered")
+	}
+	if _, err := jobframework.EnableIntegrations([]string{integration.Name}); err != nil {
+		t.Fatalf("Enabling integration: %v", err)
+	}
+	cases := map[string]struct {
+		queueName    string
+		defaultQueue string
+		wantHeld     bool
+	}{
+		"deployment with queue name": {
+			queueName: "queue",
+			wantHeld:  true,
+		},
+		"deployment without queue name isn't managed": {},
+		"deployment without queue name in a namespace with a default queue": {
+			defaultQueue: "default",
+			wantHeld:     true,
+		},
+	}
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			ns := &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "ns"}}
+			if tc.defaultQueue != "" {
+				ns.Annotations = map[string]string{constants.DefaultQueueAnnotation: tc.defaultQueue}
+			}
+			scheme := runtime.NewScheme()
+			if err := corev1.AddToScheme(scheme); err != nil {
+				t.Fatalf("Failed adding core scheme: %v", err)
+			}
+			cl := fake.NewClientBuilder().WithScheme(scheme).WithObjects(ns).Build()
+			// The Deployments without a queue name aren't managed, even when
+			// the jobs without one are.
+			wh := jobframework.NewJobWebhook(cl, integration, jobframework.WithManageJobsWithoutQueueName(true))
+
+			d := makeDeployment(3, false)
+			if tc.queueName == "" {
+				d.Annotations = nil
+			} else {
+				d.Annotations[constants.QueueAnnotation] = tc.queueName
+			}
+			job := NewDeploymentJob(d)
+			if err := wh.Default(context.Background(), job); err != nil {
+				t.Fatalf("Default returned error: %v", err)
+			}
+			if job.IsSuspended() != tc.wantHeld {
+				t.Errorf("Got held %t, want %t", job.IsSuspended(), tc.wantHeld)
+			}
+			wantReplicas := int32(3)
+			if tc.wantHeld {
+				wantReplicas = 0
+			}
+			if *d.Spec.Replicas != wantReplicas || job.Replicas() != 3 {
+				t.Errorf("Got %d replicas to run %d, want %d to run 3", *d.Spec.Replicas, job.Replicas(), wantReplicas)
+			}
+		})
+	}
+}
@@ -0,0 +1,146 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package metrics
+
+import (
+	"strings"
+	"sync"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"k8s.io/apimachinery/pkg/util/sets"
+)
+
+// OtherClusterQueues is the value of the cluster_queue label for the
+// ClusterQueues that are reported after the limit of ClusterQueues with their
+// own label value is reached.
+const OtherClusterQueues = "other"
+
+// clusterQueueLabels bounds the number of values of the cluster_queue label.
+// The first ClusterQueues to be reported keep their name, until they are
+// cleared. The others are aggregated under OtherClusterQueues.
+type clusterQueueLabels struct {
+	sync.Mutex
+	// max is the number of ClusterQueues reported with their name, or 0 for
+	// no limit.
+	max   int
+	named sets.String
+	// aggregated holds the gauges reported for each ClusterQueue under
+	// OtherClusterQueues, so that their values can be summed.
+	aggregated map[string]map[gaugeKey]*aggregatedGauge
+}
+
+type gaugeKey struct {
+	gauge  *prometheus.GaugeVec
+	labels string
+}
+
+type aggregatedGauge struct {
+	gauge *prometheus.GaugeVec
+	// labels are the values of the labels after cluster_queue.
+	labels []string
+	value  float64
+}
+
+func newClusterQueueLabels(max int) *clusterQueueLabels {
+	return &clusterQueueLabels{
+		max:        max,
+		named:      sets.NewString(),
+		aggregated: make(map[string]map[gaugeKey]*aggregatedGauge),
+	}
+}
+
+// value returns the value of the cluster_queue label for the ClusterQueue.
+func (l *clusterQueueLabels) value(cqName string) string {
+	l.Lock()
+	defer l.Unlock()
+	return l.valueLocked(cqName)
+}
+
+func (l *clusterQueueLabels) valueLocked(cqName string) string {
+	if l.max == 0 || l.named.Has(cqName) {
+		return cqName
+	}
+	if _, ok := l.aggregated[cqName]; !ok {
+		if l.named.Len() < l.max {
+			l.named.Insert(cqName)
+			return cqName
+		}
+		l.aggregated[cqName] = make(map[gaugeKey]*aggregatedGauge)
+	}
+	return OtherClusterQueues
+}
+
+// setGauge sets the value of the gauge for the ClusterQueue and the rest of
+// the labels. For a ClusterQueue aggregated under OtherClusterQueues, the
+// difference with its previous value is added instead.
+func (l *clusterQueueLabels) setGauge(g *prometheus.GaugeVec, cqName string, v float64, labels ...string) {
+	l.Lock()
+	defer l.Unlock()
+	if l.valueLocked(cqName) == cqName {
+		g.WithLabelValues(append([]string{cqName}, labels...)...).Set(v)
+		return
+	}
+	gauges := l.aggregated[cqName]
+	key := gaugeKey{gauge: g, labels: strings.Join(labels, "\x00")}
+	prev, ok := gauges[key]
+	if !ok {
+		prev = &aggregatedGauge{gauge: g, labels: labels}
+		gauges[key] = prev
+	}
+	g.WithLabelValues(append([]string{OtherClusterQueues}, labels...)...).Add(v - prev.value)
+	prev.value = v
+}
+
+// resetGauge removes the values of the gauge for the ClusterQueue. For a
+// ClusterQueue aggregated under OtherClusterQueues, its values are subtracted.
+func (l *clusterQueueLabels) resetGauge(g *prometheus.GaugeVec, cqName string) {
+	l.Lock()
+	defer l.Unlock()
+	gauges, ok := l.aggregated[cqName]
+	if !ok {
+		g.DeletePartialMatch(prometheus.Labels{"cluster_queue": cqName})
+		return
+	}
+	for key, ag := range gauges {
+		if key.gauge == g {
+			subtract(ag)
+			delete(gauges, key)
+		}
+	}
+}
+
+// clear forgets the ClusterQueue, subtracting its values from the gauges if
+// it was aggregated under OtherClusterQueues. It returns whether the series
+// with the name of the ClusterQueue should be deleted.
+func (l *clusterQueueLabels) clear(cqName string) bool {
+	l.Lock()
+	defer l.Unlock()
+	gauges, ok := l.aggregated[cqName]
+	if !ok {
+		l.named.Delete(cqName)
+		return true
+	}
+	for _, ag := range gauges {
+		subtract(ag)
+	}
+	delete(l.aggregated, cqName)
+	return false
+}
+
+func subtract(ag *aggregatedGauge) {
+	ag.gauge.WithLabelValues(append([]string{OtherClusterQueues}, ag.labels...)...).Sub(ag.value)
+}
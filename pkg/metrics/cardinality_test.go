@@ -0,0 +1,81 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package metrics
+
+import (
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"k8s.io/component-base/metrics/testutil"
+)
+
+func TestClusterQueueLabels(t *testing.T) {
+	gauge := prometheus.NewGaugeVec(prometheus.GaugeOpts{Name: "test"}, []string{"cluster_queue", "status"})
+	labels := newClusterQueueLabels(1)
+
+	if got := labels.value("a"); got != "a" {
+		t.Errorf("Got label %q for the first ClusterQueue, want %q", got, "a")
+	}
+	labels.setGauge(gauge, "a", 1, "active")
+	labels.setGauge(gauge, "b", 2, "active")
+	labels.setGauge(gauge, "c", 3, "active")
+	labels.setGauge(gauge, "c", 4, "active")
+	if got := labels.value("b"); got != OtherClusterQueues {
+		t.Errorf("Got label %q for a ClusterQueue after the limit, want %q", got, OtherClusterQueues)
+	}
+	expectGauge(t, gauge, 1, "a", "active")
+	expectGauge(t, gauge, 6, OtherClusterQueues, "active")
+
+	// The slot of a cleared ClusterQueue is taken by the next new one, while
+	// the aggregated ClusterQueues stay aggregated.
+	if !labels.clear("a") {
+		t.Error("Clearing a ClusterQueue with its own label should delete its series")
+	}
+	if labels.clear("b") {
+		t.Error("Clearing an aggregated ClusterQueue shouldn't delete its series")
+	}
+	expectGauge(t, gauge, 4, OtherClusterQueues, "active")
+	if got := labels.value("c"); got != OtherClusterQueues {
+		t.Errorf("Got label %q for an aggregated ClusterQueue, want %q", got, OtherClusterQueues)
+	}
+	if got := labels.value("d"); got != "d" {
+		t.Errorf("Got label %q for a new ClusterQueue after clearing, want %q", got, "d")
+	}
+
+	labels.resetGauge(gauge, "c")
+	expectGauge(t, gauge, 0, OtherClusterQueues, "active")
+}
+
+func TestClusterQueueLabelsWithoutLimit(t *testing.T) {
+	labels := newClusterQueueLabels(0)
+	for _, name := range []string{"a", "b", "c"} {
+		if got := labels.value(name); got != name {
+			t.Errorf("Got label %q, want %q", got, name)
+		}
+	}
+}
+
+func expectGauge(t *testing.T, g *prometheus.GaugeVec, want float64, labels ...string) {
+	t.Helper()
+	got, err := testutil.GetGaugeMetricValue(g.WithLabelValues(labels...))
+	if err != nil {
+		t.Fatalf("Failed getting the gauge value: %v", err)
+	}
+	if got != want {
+		t.Errorf("Got value %v for labels %v, want %v", got, labels, want)
+	}
+}
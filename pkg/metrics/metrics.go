@@ -20,6 +20,8 @@ import (
 	"time"
 
 	"github.com/prometheus/client_golang/prometheus"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
 	"sigs.k8s.io/controller-runtime/pkg/metrics"
 
 	kueue "sigs.k8s.io/kueue/apis/kueue/v1alpha2"
@@ -28,6 +30,7 @@ import (
 
 type AdmissionResult string
 type ClusterQueueStatus string
+type InternalQueue string
 
 const (
 	AdmissionResultSuccess      AdmissionResult = "success"
@@ -46,6 +49,13 @@ const (
 	CQStatusActive ClusterQueueStatus = "active"
 	// CQStatusTerminating means the clusterQueue is in pending deletion.
 	CQStatusTerminating ClusterQueueStatus = "terminating"
+
+	// InternalQueueScheduler holds the workloads waiting in the ClusterQueues
+	// to be evaluated by the scheduler.
+	InternalQueueScheduler InternalQueue = "scheduler"
+	// InternalQueueAdmission holds the admissions assumed by the scheduler
+	// that are being applied in the apiserver.
+	InternalQueueAdmission InternalQueue = "admission"
 )
 
 var (
@@ -74,6 +84,23 @@ The label 'result' can have the following values:
 		}, []string{"result"},
 	)
 
+	workloadEvaluationDuration = prometheus.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Subsystem: constants.KueueName,
+			Name:      "workload_evaluation_duration_seconds",
+			Help:      "The time the scheduler spent evaluating a workload for admission in an admission attempt, per 'cluster_queue'",
+		}, []string{"cluster_queue"},
+	)
+
+	admittedWorkloadSchedulingAttempts = prometheus.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Subsystem: constants.KueueName,
+			Name:      "admitted_workload_scheduling_attempts",
+			Help:      "The number of times the scheduler evaluated a workload until it was admitted, including the attempt that admitted it, per 'cluster_queue'",
+			Buckets:   prometheus.ExponentialBuckets(1, 2, 11),
+		}, []string{"cluster_queue"},
+	)
+
 	// Metrics tied to the queue system.
 
 	PendingWorkloads = prometheus.NewGaugeVec(
@@ -87,6 +114,24 @@ The label 'result' can have the following values:
 		}, []string{"cluster_queue", "status"},
 	)
 
+	PendingWorkloadsByPriorityClass = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Subsystem: constants.KueueName,
+			Name:      "pending_workloads_by_priority_class",
+			Help: `The number of pending workloads, per 'cluster_queue', 'priority_class' and 'status'.
+'priority_class' is empty for the workloads without a priority class.
+'status' has the same values as in pending_workloads`,
+		}, []string{"cluster_queue", "priority_class", "status"},
+	)
+
+	QuarantinedWorkloads = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Subsystem: constants.KueueName,
+			Name:      "quarantined_workloads",
+			Help:      "The number of pending workloads that are quarantined after failing to be admitted too many consecutive times, per 'cluster_queue'",
+		}, []string{"cluster_queue"},
+	)
+
 	AdmittedWorkloadsTotal = prometheus.NewCounterVec(
 		prometheus.CounterOpts{
 			Subsystem: constants.KueueName,
@@ -103,6 +148,66 @@ The label 'result' can have the following values:
 		}, []string{"cluster_queue"},
 	)
 
+	AdmissionDeadlineMissesTotal = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Subsystem: constants.KueueName,
+			Name:      "admission_deadline_misses_total",
+			Help: `The total number of Workloads admitted after the latest time at which they could start to complete before their deadline, per 'cluster_queue'.
+The latest start time is the deadline of the Workload minus its expected duration.`,
+		}, []string{"cluster_queue"},
+	)
+
+	finishedWorkloadRunTime = prometheus.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Subsystem: constants.KueueName,
+			Name:      "finished_workload_run_time_seconds",
+			Help:      "The time between a Workload was admitted until it finished, per 'cluster_queue'",
+		}, []string{"cluster_queue"},
+	)
+
+	finishedWorkloadResourceSeconds = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Subsystem: constants.KueueName,
+			Name:      "finished_workload_resource_seconds_total",
+			Help: `The total amount of resources admitted for finished workloads, multiplied by their run time, per 'cluster_queue', 'flavor' and 'resource'.
+The amount of CPU is measured in cores and the amount of any other resource in its base unit.`,
+		}, []string{"cluster_queue", "flavor", "resource"},
+	)
+
+	// Metrics tied to the internal queues.
+
+	internalQueueDepth = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Subsystem: constants.KueueName,
+			Name:      "internal_queue_depth",
+			Help: `The number of items in an internal queue of Kueue, per 'component'.
+'component' can have the following values:
+- "scheduler" means the workloads waiting in the ClusterQueues to be evaluated by the scheduler.
+- "admission" means the admissions assumed by the scheduler that are being applied in the apiserver.
+The workqueues of the controllers are reported by controller-runtime in the workqueue_* metrics, per 'name'.`,
+		}, []string{"component"},
+	)
+
+	internalQueueLatency = prometheus.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Subsystem: constants.KueueName,
+			Name:      "internal_queue_latency_seconds",
+			Help: `The time an item stays in an internal queue of Kueue, per 'component'.
+For "scheduler", the time since the workload was queued until it's evaluated by the scheduler.
+For "admission", the time since the workload was assumed until its admission is applied in the apiserver.`,
+		}, []string{"component"},
+	)
+
+	internalQueueRetriesTotal = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Subsystem: constants.KueueName,
+			Name:      "internal_queue_retries_total",
+			Help: `The total number of items requeued in an internal queue of Kueue, per 'component'.
+For "scheduler", the workloads requeued after an admission attempt.
+For "admission", the admissions that failed to be applied in the apiserver.`,
+		}, []string{"component"},
+	)
+
 	// Metrics tied to the cache.
 
 	AdmittedActiveWorkloads = prometheus.NewGaugeVec(
@@ -121,6 +226,72 @@ The label 'result' can have the following values:
 For a ClusterQueue, the metric only reports a value of 1 for one of the statuses.`,
 		}, []string{"cluster_queue", "status"},
 	)
+
+	ClusterQueuePolicies = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Subsystem: constants.KueueName,
+			Name:      "cluster_queue_policies",
+			Help: `Reports 'cluster_queue' with a value of 1 and its effective scheduling policies, after applying the defaults, as labels.
+'queueing_strategy', 'ordering_policy' and 'flavor_assignment_policy' have the values of the fields with the same names in the spec of the ClusterQueue.
+'reclaim_within_cohort' and 'within_cluster_queue' have the values of its preemption policies.`,
+		}, []string{"cluster_queue", "queueing_strategy", "ordering_policy", "flavor_assignment_policy", "reclaim_within_cohort", "within_cluster_queue"},
+	)
+
+	ClusterQueueBudgetRemaining = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Subsystem: constants.KueueName,
+			Name:      "cluster_queue_budget_remaining",
+			Help: `The remaining budget of the 'resource' in the 'flavor' for the current 'period', per 'cluster_queue', in resource-hours.
+An empty 'flavor' is for a budget that applies to all the flavors.`,
+		}, []string{"cluster_queue", "resource", "flavor", "period"},
+	)
+)
+
+// Option configures the labels of the metrics.
+type Option func(*options)
+
+type options struct {
+	maxClusterQueues   int
+	flavorLabel        bool
+	priorityClassLabel bool
+}
+
+var defaultOptions = options{
+	flavorLabel:        true,
+	priorityClassLabel: true,
+}
+
+// WithMaxClusterQueues limits the number of ClusterQueues reported with their
+// name in the cluster_queue label. The metrics of the ClusterQueues reported
+// after the limit is reached are aggregated under OtherClusterQueues.
+// A limit of 0 means no limit.
+func WithMaxClusterQueues(n int) Option {
+	return func(o *options) {
+		o.maxClusterQueues = n
+	}
+}
+
+// WithFlavorLabel sets whether the metrics per flavor report the name of the
+// flavor. Otherwise, they report the sum for all the flavors, with an empty
+// flavor label.
+func WithFlavorLabel(enabled bool) Option {
+	return func(o *options) {
+		o.flavorLabel = enabled
+	}
+}
+
+// WithPriorityClassLabel sets whether the metrics per priority class are
+// reported.
+func WithPriorityClassLabel(enabled bool) Option {
+	return func(o *options) {
+		o.priorityClassLabel = enabled
+	}
+}
+
+var (
+	cqLabels           = newClusterQueueLabels(0)
+	flavorLabel        = true
+	priorityClassLabel = true
 )
 
 func AdmissionAttempt(result AdmissionResult, duration time.Duration) {
@@ -128,21 +299,90 @@ func AdmissionAttempt(result AdmissionResult, duration time.Duration) {
 	admissionAttemptDuration.WithLabelValues(string(result)).Observe(duration.Seconds())
 }
 
-func AdmittedWorkload(cqName kueue.ClusterQueueReference, waitTime time.Duration) {
-	AdmittedWorkloadsTotal.WithLabelValues(string(cqName)).Inc()
-	admissionWaitTime.WithLabelValues(string(cqName)).Observe(waitTime.Seconds())
+func AdmittedWorkload(cqName kueue.ClusterQueueReference, waitTime time.Duration, attempts int32) {
+	cq := cqLabels.value(string(cqName))
+	AdmittedWorkloadsTotal.WithLabelValues(cq).Inc()
+	admissionWaitTime.WithLabelValues(cq).Observe(waitTime.Seconds())
+	admittedWorkloadSchedulingAttempts.WithLabelValues(cq).Observe(float64(attempts))
+}
+
+func WorkloadEvaluated(cqName string, duration time.Duration) {
+	workloadEvaluationDuration.WithLabelValues(cqLabels.value(cqName)).Observe(duration.Seconds())
+}
+
+func AdmissionDeadlineMissed(cqName kueue.ClusterQueueReference) {
+	AdmissionDeadlineMissesTotal.WithLabelValues(cqLabels.value(string(cqName))).Inc()
+}
+
+func FinishedWorkload(cqName kueue.ClusterQueueReference, runTime time.Duration, resources map[corev1.ResourceName]map[string]resource.Quantity) {
+	cq := cqLabels.value(string(cqName))
+	finishedWorkloadRunTime.WithLabelValues(cq).Observe(runTime.Seconds())
+	for res, flavors := range resources {
+		for flv, q := range flavors {
+			if !flavorLabel {
+				flv = ""
+			}
+			finishedWorkloadResourceSeconds.WithLabelValues(cq, flv, string(res)).Add(q.AsApproximateFloat64() * runTime.Seconds())
+		}
+	}
 }
 
 func ReportPendingWorkloads(cqName string, active, inadmissible int) {
-	PendingWorkloads.WithLabelValues(cqName, PendingStatusActive).Set(float64(active))
-	PendingWorkloads.WithLabelValues(cqName, PendingStatusInadmissible).Set(float64(inadmissible))
+	cqLabels.setGauge(PendingWorkloads, cqName, float64(active), PendingStatusActive)
+	cqLabels.setGauge(PendingWorkloads, cqName, float64(inadmissible), PendingStatusInadmissible)
+}
+
+// ReportPendingWorkloadsByPriorityClass reports the number of active and
+// inadmissible pending workloads of the ClusterQueue, per priority class.
+// The priority classes without pending workloads are not reported.
+func ReportPendingWorkloadsByPriorityClass(cqName string, active, inadmissible map[string]int) {
+	if !priorityClassLabel {
+		return
+	}
+	cqLabels.resetGauge(PendingWorkloadsByPriorityClass, cqName)
+	for class, n := range active {
+		cqLabels.setGauge(PendingWorkloadsByPriorityClass, cqName, float64(n), class, PendingStatusActive)
+	}
+	for class, n := range inadmissible {
+		cqLabels.setGauge(PendingWorkloadsByPriorityClass, cqName, float64(n), class, PendingStatusInadmissible)
+	}
+}
+
+func ReportQuarantinedWorkloads(cqName string, quarantined int) {
+	cqLabels.setGauge(QuarantinedWorkloads, cqName, float64(quarantined))
+}
+
+func ReportAdmittedActiveWorkloads(cqName string, active int) {
+	cqLabels.setGauge(AdmittedActiveWorkloads, cqName, float64(active))
 }
 
 func ClearQueueSystemMetrics(cqName string) {
+	if !cqLabels.clear(cqName) {
+		return
+	}
 	PendingWorkloads.DeleteLabelValues(cqName, PendingStatusActive)
 	PendingWorkloads.DeleteLabelValues(cqName, PendingStatusInadmissible)
+	PendingWorkloadsByPriorityClass.DeletePartialMatch(prometheus.Labels{"cluster_queue": cqName})
+	QuarantinedWorkloads.DeleteLabelValues(cqName)
 	AdmittedWorkloadsTotal.DeleteLabelValues(cqName)
 	admissionWaitTime.DeleteLabelValues(cqName)
+	admittedWorkloadSchedulingAttempts.DeleteLabelValues(cqName)
+	workloadEvaluationDuration.DeleteLabelValues(cqName)
+	AdmissionDeadlineMissesTotal.DeleteLabelValues(cqName)
+	finishedWorkloadRunTime.DeleteLabelValues(cqName)
+	finishedWorkloadResourceSeconds.DeletePartialMatch(prometheus.Labels{"cluster_queue": cqName})
+}
+
+func ReportInternalQueueDepth(queue InternalQueue, depth int) {
+	internalQueueDepth.WithLabelValues(string(queue)).Set(float64(depth))
+}
+
+func InternalQueueLatency(queue InternalQueue, latency time.Duration) {
+	internalQueueLatency.WithLabelValues(string(queue)).Observe(latency.Seconds())
+}
+
+func InternalQueueRetry(queue InternalQueue) {
+	internalQueueRetriesTotal.WithLabelValues(string(queue)).Inc()
 }
 
 func ReportClusterQueueStatus(cqName string, cqStatus ClusterQueueStatus) {
@@ -151,24 +391,80 @@ func ReportClusterQueueStatus(cqName string, cqStatus ClusterQueueStatus) {
 		if status == cqStatus {
 			v = 1
 		}
-		ClusterQueueByStatus.WithLabelValues(cqName, string(status)).Set(v)
+		cqLabels.setGauge(ClusterQueueByStatus, cqName, v, string(status))
+	}
+}
+
+// ReportClusterQueuePolicies reports the effective scheduling policies of the
+// ClusterQueue, replacing the ones previously reported.
+func ReportClusterQueuePolicies(cqName string, p kueue.ClusterQueuePolicies) {
+	cqLabels.resetGauge(ClusterQueuePolicies, cqName)
+	cqLabels.setGauge(ClusterQueuePolicies, cqName, 1,
+		string(p.QueueingStrategy),
+		string(p.OrderingPolicy),
+		string(p.FlavorAssignmentPolicy),
+		string(p.Preemption.ReclaimWithinCohort),
+		string(p.Preemption.WithinClusterQueue))
+}
+
+// ReportClusterQueueBudgets reports the remaining budgets of the
+// ClusterQueue, replacing the ones previously reported.
+func ReportClusterQueueBudgets(cqName string, budgets []kueue.BudgetStatus) {
+	type budgetKey struct {
+		resource, flavor, period string
+	}
+	remaining := make(map[budgetKey]float64, len(budgets))
+	for _, b := range budgets {
+		flv := string(b.Flavor)
+		if !flavorLabel {
+			flv = ""
+		}
+		remaining[budgetKey{string(b.Resource), flv, string(b.Period)}] += b.Remaining.AsApproximateFloat64()
+	}
+	cqLabels.resetGauge(ClusterQueueBudgetRemaining, cqName)
+	for k, v := range remaining {
+		cqLabels.setGauge(ClusterQueueBudgetRemaining, cqName, v, k.resource, k.flavor, k.period)
 	}
 }
 
 func ClearCacheMetrics(cqName string) {
+	if !cqLabels.clear(cqName) {
+		return
+	}
+	ClusterQueuePolicies.DeletePartialMatch(prometheus.Labels{"cluster_queue": cqName})
+	ClusterQueueBudgetRemaining.DeletePartialMatch(prometheus.Labels{"cluster_queue": cqName})
 	AdmittedActiveWorkloads.DeleteLabelValues(cqName)
 	for _, status := range CQStatuses {
 		ClusterQueueByStatus.DeleteLabelValues(cqName, string(status))
 	}
 }
 
-func Register() {
+func Register(opts ...Option) {
+	o := defaultOptions
+	for _, opt := range opts {
+		opt(&o)
+	}
+	cqLabels = newClusterQueueLabels(o.maxClusterQueues)
+	flavorLabel = o.flavorLabel
+	priorityClassLabel = o.priorityClassLabel
 	metrics.Registry.MustRegister(
 		admissionAttemptsTotal,
 		admissionAttemptDuration,
+		workloadEvaluationDuration,
+		admittedWorkloadSchedulingAttempts,
 		PendingWorkloads,
+		PendingWorkloadsByPriorityClass,
+		QuarantinedWorkloads,
 		AdmittedActiveWorkloads,
+		ClusterQueuePolicies,
+		ClusterQueueBudgetRemaining,
 		AdmittedWorkloadsTotal,
 		admissionWaitTime,
+		AdmissionDeadlineMissesTotal,
+		finishedWorkloadRunTime,
+		finishedWorkloadResourceSeconds,
+		internalQueueDepth,
+		internalQueueLatency,
+		internalQueueRetriesTotal,
 	)
 }
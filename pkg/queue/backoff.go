@@ -0,0 +1,79 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package queue
+
+import (
+	"fmt"
+	"time"
+
+	apimeta "k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/wait"
+
+	kueue "sigs.k8s.io/kueue/apis/kueue/v1alpha2"
+	"sigs.k8s.io/kueue/pkg/workload"
+)
+
+// backoffJitter is the maximum factor of the requeueing backoff that is added
+// to it as a jitter, so that workloads that fail together are not requeued
+// together.
+const backoffJitter = 0.1
+
+// InBackoff returns whether the workload is held back by its requeueing
+// backoff at the given time.
+func InBackoff(info *workload.Info, now time.Time) bool {
+	state := info.Obj.Status.RequeueState
+	return state != nil && state.RequeueAt != nil && now.Before(state.RequeueAt.Time)
+}
+
+// nextRequeueState returns the requeue state of a workload that failed to be
+// admitted once more. The delay doubles with each failed attempt, starting
+// from base, up to max. If base is zero, the failed attempts are only counted.
+func nextRequeueState(state *kueue.RequeueState, base, max time.Duration, now time.Time) *kueue.RequeueState {
+	var count int32 = 1
+	if state != nil {
+		count = state.Count + 1
+	}
+	if base == 0 {
+		return &kueue.RequeueState{Count: count}
+	}
+	delay := base
+	for i := int32(1); i < count && delay < max; i++ {
+		delay *= 2
+	}
+	if delay > max {
+		delay = max
+	}
+	requeueAt := metav1.NewTime(now.Add(wait.Jitter(delay, backoffJitter)))
+	return &kueue.RequeueState{
+		Count:     count,
+		RequeueAt: &requeueAt,
+	}
+}
+
+// quarantine holds the workload back until its next retry, after the given
+// interval, and marks it with the Quarantined condition.
+func quarantine(w *kueue.Workload, interval time.Duration, now time.Time) {
+	requeueAt := metav1.NewTime(now.Add(wait.Jitter(interval, backoffJitter)))
+	w.Status.RequeueState.RequeueAt = &requeueAt
+	apimeta.SetStatusCondition(&w.Status.Conditions, metav1.Condition{
+		Type:    kueue.WorkloadQuarantined,
+		Status:  metav1.ConditionTrue,
+		Reason:  "AdmissionAttemptsExceeded",
+		Message: fmt.Sprintf("Failed to be admitted %d consecutive times, retrying every %v", w.Status.RequeueState.Count, interval),
+	})
+}
@@ -0,0 +1,89 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package queue
+
+import (
+	"testing"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	kueue "sigs.k8s.io/kueue/apis/kueue/v1alpha2"
+	"sigs.k8s.io/kueue/pkg/workload"
+)
+
+func TestNextRequeueState(t *testing.T) {
+	now := time.Now()
+	cases := map[string]struct {
+		state     *kueue.RequeueState
+		wantCount int32
+		wantDelay time.Duration
+	}{
+		"first requeue": {
+			wantCount: 1,
+			wantDelay: time.Second,
+		},
+		"delay doubles": {
+			state:     &kueue.RequeueState{Count: 3},
+			wantCount: 4,
+			wantDelay: 8 * time.Second,
+		},
+		"delay is capped": {
+			state:     &kueue.RequeueState{Count: 100},
+			wantCount: 101,
+			wantDelay: time.Minute,
+		},
+	}
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			got := nextRequeueState(tc.state, time.Second, time.Minute, now)
+			if got.Count != tc.wantCount {
+				t.Errorf("Got count %d, want %d", got.Count, tc.wantCount)
+			}
+			delay := got.RequeueAt.Sub(now)
+			maxDelay := time.Duration(float64(tc.wantDelay) * (1 + backoffJitter))
+			if delay < tc.wantDelay || delay > maxDelay {
+				t.Errorf("Got delay %v, want between %v and %v", delay, tc.wantDelay, maxDelay)
+			}
+		})
+	}
+}
+
+func TestInBackoff(t *testing.T) {
+	now := time.Now()
+	cases := map[string]struct {
+		state *kueue.RequeueState
+		want  bool
+	}{
+		"no requeue state": {},
+		"backoff not expired": {
+			state: &kueue.RequeueState{Count: 1, RequeueAt: &metav1.Time{Time: now.Add(time.Second)}},
+			want:  true,
+		},
+		"backoff expired": {
+			state: &kueue.RequeueState{Count: 1, RequeueAt: &metav1.Time{Time: now}},
+		},
+	}
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			info := &workload.Info{Obj: &kueue.Workload{Status: kueue.WorkloadStatus{RequeueState: tc.state}}}
+			if got := InBackoff(info, now); got != tc.want {
+				t.Errorf("InBackoff(_, _) = %t, want %t", got, tc.want)
+			}
+		})
+	}
+}
@@ -17,6 +17,8 @@ limitations under the License.
 package queue
 
 import (
+	"time"
+
 	kueue "sigs.k8s.io/kueue/apis/kueue/v1alpha2"
 	"sigs.k8s.io/kueue/pkg/workload"
 )
@@ -31,8 +33,8 @@ var _ ClusterQueue = &ClusterQueueBestEffortFIFO{}
 
 const BestEffortFIFO = kueue.BestEffortFIFO
 
-func newClusterQueueBestEffortFIFO(cq *kueue.ClusterQueue) (ClusterQueue, error) {
-	cqImpl := newClusterQueueImpl(keyFunc, byCreationTime)
+func newClusterQueueBestEffortFIFO(cq *kueue.ClusterQueue, lessFunc func(a, b interface{}) bool) (ClusterQueue, error) {
+	cqImpl := newClusterQueueImpl(keyFunc, lessFunc)
 	cqBE := &ClusterQueueBestEffortFIFO{
 		clusterQueueBase: cqImpl,
 	}
@@ -42,5 +44,9 @@ func newClusterQueueBestEffortFIFO(cq *kueue.ClusterQueue) (ClusterQueue, error)
 }
 
 func (cq *ClusterQueueBestEffortFIFO) RequeueIfNotPresent(wInfo *workload.Info, reason RequeueReason) bool {
-	return cq.requeueIfNotPresent(wInfo, reason == RequeueReasonFailedAfterNomination)
+	if InBackoff(wInfo, time.Now()) {
+		// The workload is queued again once its requeueing backoff expires.
+		return cq.addInadmissibleIfNotPresent(wInfo)
+	}
+	return cq.requeueIfNotPresent(wInfo, reason == RequeueReasonFailedAfterNomination || reason == RequeueReasonPendingPreemption)
 }
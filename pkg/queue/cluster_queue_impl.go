@@ -18,6 +18,8 @@ package queue
 
 import (
 	"context"
+	"sort"
+	"time"
 
 	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/equality"
@@ -28,7 +30,9 @@ import (
 	"sigs.k8s.io/controller-runtime/pkg/client"
 
 	kueue "sigs.k8s.io/kueue/apis/kueue/v1alpha2"
+	"sigs.k8s.io/kueue/pkg/metrics"
 	"sigs.k8s.io/kueue/pkg/util/heap"
+	utilpriority "sigs.k8s.io/kueue/pkg/util/priority"
 	"sigs.k8s.io/kueue/pkg/workload"
 )
 
@@ -36,12 +40,16 @@ import (
 // interface. It can be inherited and overwritten by other types.
 type clusterQueueBase struct {
 	heap              heap.Heap
+	lessFunc          func(a, b interface{}) bool
 	cohort            string
 	namespaceSelector labels.Selector
 
 	// inadmissibleWorkloads are workloads that have been tried at least once and couldn't be admitted.
 	inadmissibleWorkloads map[string]*workload.Info
 
+	// queuedAt holds the time at which the workloads in the heap were pushed.
+	queuedAt map[string]time.Time
+
 	// popCycle identifies the last call to Pop. It's incremented when calling Pop.
 	// popCycle and queueInadmissibleCycle are used to track when there is a requeueing
 	// of inadmissible workloads while a workload is being scheduled.
@@ -50,13 +58,64 @@ type clusterQueueBase struct {
 	// queueInadmissibleCycle stores the popId at the time when
 	// QueueInadmissibleWorkloads is called.
 	queueInadmissibleCycle int64
+
+	// localQueueFairness is how the workloads of the LocalQueues are
+	// interleaved. localQueueTurns holds the turn of each LocalQueue, keyed by
+	// namespace/name: the popCycle in which a workload of the LocalQueue was
+	// last popped for RoundRobin, or its number of admitted workloads for
+	// Usage.
+	localQueueFairness kueue.LocalQueueFairness
+	localQueueTurns    map[string]int64
+
+	// priorityBands split the quota of the ClusterQueue among ranges of
+	// priorities. priorityBandShares holds the usage of each band relative to
+	// its guaranteed quota, and the workloads of the bands with a lower share
+	// go first.
+	priorityBands      []kueue.PriorityBand
+	priorityBandShares map[string]int64
 }
 
 func newClusterQueueImpl(keyFunc func(obj interface{}) string, lessFunc func(a, b interface{}) bool) *clusterQueueBase {
-	return &clusterQueueBase{
-		heap:                   heap.New(keyFunc, lessFunc),
+	c := &clusterQueueBase{
 		inadmissibleWorkloads:  make(map[string]*workload.Info),
+		queuedAt:               make(map[string]time.Time),
 		queueInadmissibleCycle: -1,
+		localQueueTurns:        make(map[string]int64),
+	}
+	c.lessFunc = c.byPriorityBandShare(c.byLocalQueueTurn(lessFunc))
+	c.heap = heap.New(keyFunc, c.lessFunc)
+	return c
+}
+
+// byLocalQueueTurn wraps lessFunc to sort the workloads of the LocalQueues
+// with a lower turn first, when the ClusterQueue interleaves the workloads of
+// its LocalQueues.
+func (c *clusterQueueBase) byLocalQueueTurn(lessFunc func(a, b interface{}) bool) func(a, b interface{}) bool {
+	return func(a, b interface{}) bool {
+		if c.localQueueFairness == kueue.RoundRobin || c.localQueueFairness == kueue.LocalQueueUsage {
+			turnA := c.localQueueTurns[workload.QueueKey(a.(*workload.Info).Obj)]
+			turnB := c.localQueueTurns[workload.QueueKey(b.(*workload.Info).Obj)]
+			if turnA != turnB {
+				return turnA < turnB
+			}
+		}
+		return lessFunc(a, b)
+	}
+}
+
+// byPriorityBandShare wraps lessFunc to sort the workloads of the priority
+// bands with a lower share of their guaranteed quota first, when the
+// ClusterQueue splits its quota in priority bands.
+func (c *clusterQueueBase) byPriorityBandShare(lessFunc func(a, b interface{}) bool) func(a, b interface{}) bool {
+	return func(a, b interface{}) bool {
+		if len(c.priorityBands) > 0 {
+			shareA := c.priorityBandShares[utilpriority.Band(c.priorityBands, a.(*workload.Info).Obj)]
+			shareB := c.priorityBandShares[utilpriority.Band(c.priorityBands, b.(*workload.Info).Obj)]
+			if shareA != shareB {
+				return shareA < shareB
+			}
+		}
+		return lessFunc(a, b)
 	}
 }
 
@@ -67,6 +126,16 @@ func (c *clusterQueueBase) Update(apiCQ *kueue.ClusterQueue) error {
 		return err
 	}
 	c.namespaceSelector = nsSelector
+	if fairness := apiCQ.Spec.LocalQueueFairness; fairness != c.localQueueFairness {
+		c.localQueueFairness = fairness
+		c.localQueueTurns = make(map[string]int64)
+		c.heap.Reorder()
+	}
+	if !equality.Semantic.DeepEqual(apiCQ.Spec.PriorityBands, c.priorityBands) {
+		c.priorityBands = apiCQ.Spec.PriorityBands
+		c.priorityBandShares = nil
+		c.heap.Reorder()
+	}
 	return nil
 }
 
@@ -77,7 +146,7 @@ func (c *clusterQueueBase) Cohort() string {
 func (c *clusterQueueBase) AddFromLocalQueue(q *LocalQueue) bool {
 	added := false
 	for _, info := range q.items {
-		if c.heap.PushIfNotPresent(info) {
+		if c.pushIfNotPresent(info) {
 			added = true
 		}
 	}
@@ -97,15 +166,47 @@ func (c *clusterQueueBase) PushOrUpdate(wInfo *workload.Info) {
 		// otherwise move or update in place in the queue.
 		delete(c.inadmissibleWorkloads, key)
 	}
+	if _, ok := c.queuedAt[key]; !ok {
+		c.queuedAt[key] = time.Now()
+	}
 	c.heap.PushOrUpdate(wInfo)
 }
 
 func (c *clusterQueueBase) Delete(w *kueue.Workload) {
 	key := workload.Key(w)
 	delete(c.inadmissibleWorkloads, key)
+	delete(c.queuedAt, key)
 	c.heap.Delete(key)
 }
 
+func (c *clusterQueueBase) LocalQueueFairness() kueue.LocalQueueFairness {
+	return c.localQueueFairness
+}
+
+func (c *clusterQueueBase) SetLocalQueueUsage(admitted map[string]int) {
+	if c.localQueueFairness != kueue.LocalQueueUsage {
+		return
+	}
+	turns := make(map[string]int64, len(admitted))
+	for key, n := range admitted {
+		turns[key] = int64(n)
+	}
+	c.localQueueTurns = turns
+	c.heap.Reorder()
+}
+
+func (c *clusterQueueBase) HasPriorityBands() bool {
+	return len(c.priorityBands) > 0
+}
+
+func (c *clusterQueueBase) SetPriorityBandShares(shares map[string]int64) {
+	if len(c.priorityBands) == 0 {
+		return
+	}
+	c.priorityBandShares = shares
+	c.heap.Reorder()
+}
+
 func (c *clusterQueueBase) DeleteFromLocalQueue(q *LocalQueue) {
 	for _, w := range q.items {
 		key := workload.Key(w.Obj)
@@ -116,6 +217,7 @@ func (c *clusterQueueBase) DeleteFromLocalQueue(q *LocalQueue) {
 	for _, w := range q.items {
 		c.Delete(w.Obj)
 	}
+	delete(c.localQueueTurns, q.Key)
 }
 
 // requeueIfNotPresent inserts a workload that cannot be admitted into
@@ -132,9 +234,15 @@ func (c *clusterQueueBase) requeueIfNotPresent(wInfo *workload.Info, immediate b
 			wInfo = inadmissibleWl
 			delete(c.inadmissibleWorkloads, key)
 		}
-		return c.heap.PushIfNotPresent(wInfo)
+		return c.pushIfNotPresent(wInfo)
 	}
+	return c.addInadmissibleIfNotPresent(wInfo)
+}
 
+// addInadmissibleIfNotPresent inserts a workload into inadmissibleWorkloads,
+// unless it is already in the queue.
+func (c *clusterQueueBase) addInadmissibleIfNotPresent(wInfo *workload.Info) bool {
+	key := workload.Key(wInfo.Obj)
 	if c.inadmissibleWorkloads[key] != nil {
 		return false
 	}
@@ -151,20 +259,42 @@ func (c *clusterQueueBase) requeueIfNotPresent(wInfo *workload.Info, immediate b
 // QueueInadmissibleWorkloads moves all workloads from inadmissibleWorkloads to heap.
 // If at least one workload is moved, returns true. Otherwise returns false.
 func (c *clusterQueueBase) QueueInadmissibleWorkloads(ctx context.Context, client client.Client) bool {
+	return c.queueInadmissibleWorkloads(ctx, client, "")
+}
+
+func (c *clusterQueueBase) QueueInadmissibleWorkloadsInNamespace(ctx context.Context, client client.Client, namespace string) bool {
+	return c.queueInadmissibleWorkloads(ctx, client, namespace)
+}
+
+// queueInadmissibleWorkloads moves the inadmissible workloads of the
+// namespace, or of all the namespaces if it's empty, to the heap. The labels
+// of each namespace are fetched at most once.
+func (c *clusterQueueBase) queueInadmissibleWorkloads(ctx context.Context, client client.Client, namespace string) bool {
 	c.queueInadmissibleCycle = c.popCycle
 	if len(c.inadmissibleWorkloads) == 0 {
 		return false
 	}
 
 	inadmissibleWorkloads := make(map[string]*workload.Info)
+	nsMatches := make(map[string]bool)
 	moved := false
+	now := time.Now()
 	for key, wInfo := range c.inadmissibleWorkloads {
-		ns := corev1.Namespace{}
-		err := client.Get(ctx, types.NamespacedName{Name: wInfo.Obj.Namespace}, &ns)
-		if err != nil || !c.namespaceSelector.Matches(labels.Set(ns.Labels)) {
+		if namespace != "" && wInfo.Obj.Namespace != namespace {
+			inadmissibleWorkloads[key] = wInfo
+			continue
+		}
+		matches, ok := nsMatches[wInfo.Obj.Namespace]
+		if !ok {
+			ns := corev1.Namespace{}
+			err := client.Get(ctx, types.NamespacedName{Name: wInfo.Obj.Namespace}, &ns)
+			matches = err == nil && c.namespaceSelector.Matches(labels.Set(ns.Labels))
+			nsMatches[wInfo.Obj.Namespace] = matches
+		}
+		if !matches || InBackoff(wInfo, now) {
 			inadmissibleWorkloads[key] = wInfo
 		} else {
-			moved = c.heap.PushIfNotPresent(wInfo) || moved
+			moved = c.pushIfNotPresent(wInfo) || moved
 		}
 	}
 
@@ -184,14 +314,100 @@ func (c *clusterQueueBase) PendingInadmissible() int {
 	return len(c.inadmissibleWorkloads)
 }
 
+func (c *clusterQueueBase) PendingQuarantined() int {
+	n := 0
+	for _, item := range c.heap.List() {
+		if workload.IsQuarantined(item.(*workload.Info).Obj) {
+			n++
+		}
+	}
+	for _, info := range c.inadmissibleWorkloads {
+		if workload.IsQuarantined(info.Obj) {
+			n++
+		}
+	}
+	return n
+}
+
+func (c *clusterQueueBase) PendingByPriorityClass() (map[string]int, map[string]int) {
+	active := make(map[string]int)
+	for _, item := range c.heap.List() {
+		active[item.(*workload.Info).Obj.Spec.PriorityClassName]++
+	}
+	inadmissible := make(map[string]int)
+	for _, info := range c.inadmissibleWorkloads {
+		inadmissible[info.Obj.Spec.PriorityClassName]++
+	}
+	return active, inadmissible
+}
+
 func (c *clusterQueueBase) Pop() *workload.Info {
 	c.popCycle++
 	if c.heap.Len() == 0 {
 		return nil
 	}
 
-	info := c.heap.Pop()
-	return info.(*workload.Info)
+	info := c.heap.Pop().(*workload.Info)
+	key := workload.Key(info.Obj)
+	metrics.InternalQueueLatency(metrics.InternalQueueScheduler, time.Since(c.queuedAt[key]))
+	delete(c.queuedAt, key)
+	if c.localQueueFairness == kueue.RoundRobin {
+		// The LocalQueue of the workload goes after the other LocalQueues.
+		c.localQueueTurns[workload.QueueKey(info.Obj)] = c.popCycle
+		c.heap.Reorder()
+	}
+	return info
+}
+
+func (c *clusterQueueBase) PopAdmissionGroup(group string) []*workload.Info {
+	var members []*workload.Info
+	inGroup := func(info *workload.Info) bool {
+		g, _, ok := workload.AdmissionGroup(info.Obj)
+		return ok && g == group
+	}
+	for _, item := range c.heap.List() {
+		if info := item.(*workload.Info); inGroup(info) {
+			members = append(members, info)
+		}
+	}
+	for _, info := range c.inadmissibleWorkloads {
+		if inGroup(info) {
+			members = append(members, info)
+		}
+	}
+	sort.Slice(members, func(i, j int) bool {
+		return workload.Key(members[i].Obj) < workload.Key(members[j].Obj)
+	})
+	for _, info := range members {
+		c.Delete(info.Obj)
+	}
+	return members
+}
+
+func (c *clusterQueueBase) Reorder() {
+	c.heap.Reorder()
+}
+
+// pushIfNotPresent inserts the workload into the heap, unless it's already
+// there, and records when it was queued.
+func (c *clusterQueueBase) pushIfNotPresent(wInfo *workload.Info) bool {
+	if !c.heap.PushIfNotPresent(wInfo) {
+		return false
+	}
+	c.queuedAt[workload.Key(wInfo.Obj)] = time.Now()
+	return true
+}
+
+func (c *clusterQueueBase) Sorted() []*workload.Info {
+	items := c.heap.List()
+	sort.Slice(items, func(i, j int) bool {
+		return c.lessFunc(items[i], items[j])
+	})
+	infos := make([]*workload.Info, len(items))
+	for i, item := range items {
+		infos[i] = item.(*workload.Info)
+	}
+	return infos
 }
 
 func (c *clusterQueueBase) Dump() (sets.String, bool) {
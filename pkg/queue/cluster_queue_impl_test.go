@@ -102,6 +102,22 @@ func Test_Delete(t *testing.T) {
 	}
 }
 
+func Test_PendingByPriorityClass(t *testing.T) {
+	cq := newClusterQueueImpl(keyFunc, byCreationTime)
+	cq.PushOrUpdate(workload.NewInfo(utiltesting.MakeWorkload("workload-1", defaultNamespace).PriorityClass("high").Obj()))
+	cq.PushOrUpdate(workload.NewInfo(utiltesting.MakeWorkload("workload-2", defaultNamespace).PriorityClass("high").Obj()))
+	cq.PushOrUpdate(workload.NewInfo(utiltesting.MakeWorkload("workload-3", defaultNamespace).Obj()))
+	cq.addInadmissibleIfNotPresent(workload.NewInfo(utiltesting.MakeWorkload("workload-4", defaultNamespace).PriorityClass("low").Obj()))
+
+	active, inadmissible := cq.PendingByPriorityClass()
+	if diff := cmp.Diff(map[string]int{"high": 2, "": 1}, active); diff != "" {
+		t.Errorf("Unexpected active workloads (-want,+got):\n%s", diff)
+	}
+	if diff := cmp.Diff(map[string]int{"low": 1}, inadmissible); diff != "" {
+		t.Errorf("Unexpected inadmissible workloads (-want,+got):\n%s", diff)
+	}
+}
+
 func Test_Info(t *testing.T) {
 	cq := newClusterQueueImpl(keyFunc, byCreationTime)
 	wl := utiltesting.MakeWorkload("workload-1", defaultNamespace).Obj()
@@ -201,6 +217,7 @@ func TestClusterQueueImpl(t *testing.T) {
 		workloadsToUpdate                 []*kueue.Workload
 		workloadsToDelete                 []*kueue.Workload
 		queueInadmissibleWorkloads        bool
+		queueInadmissibleInNamespace      string
 		wantActiveWorkloads               sets.String
 		wantPending                       int
 		wantInadmissibleWorkloadsRequeued bool
@@ -241,6 +258,18 @@ func TestClusterQueueImpl(t *testing.T) {
 			wantActiveWorkloads:            sets.NewString(workload.Key(workloads[0])),
 			wantPending:                    2,
 		},
+		"re-queue inadmissible workloads of a namespace": {
+			inadmissibleWorkloadsToRequeue:    []*workload.Info{workload.NewInfo(workloads[0]), workload.NewInfo(workloads[1])},
+			queueInadmissibleInNamespace:      "ns2",
+			wantActiveWorkloads:               sets.NewString(workload.Key(workloads[1])),
+			wantPending:                       2,
+			wantInadmissibleWorkloadsRequeued: true,
+		},
+		"avoid re-queueing inadmissible workloads of a namespace not matching namespace selector": {
+			inadmissibleWorkloadsToRequeue: []*workload.Info{workload.NewInfo(workloads[0]), workload.NewInfo(workloads[2])},
+			queueInadmissibleInNamespace:   "ns3",
+			wantPending:                    2,
+		},
 		"update inadmissible workload": {
 			workloadsToAdd:                 []*kueue.Workload{workloads[0]},
 			inadmissibleWorkloadsToRequeue: []*workload.Info{workload.NewInfo(workloads[1])},
@@ -310,6 +339,12 @@ func TestClusterQueueImpl(t *testing.T) {
 					t.Errorf("Unexpected requeueing of inadmissible workloads (-want,+got):\n%s", diff)
 				}
 			}
+			if test.queueInadmissibleInNamespace != "" {
+				if diff := cmp.Diff(test.wantInadmissibleWorkloadsRequeued,
+					cq.QueueInadmissibleWorkloadsInNamespace(context.Background(), cl, test.queueInadmissibleInNamespace)); diff != "" {
+					t.Errorf("Unexpected requeueing of inadmissible workloads (-want,+got):\n%s", diff)
+				}
+			}
 
 			gotWorkloads, _ := cq.Dump()
 			if diff := cmp.Diff(test.wantActiveWorkloads, gotWorkloads); diff != "" {
@@ -363,3 +398,69 @@ func TestQueueInadmissibleWorkloadsDuringScheduling(t *testing.T) {
 		t.Errorf("Unexpected active workloads after scheduling (-want,+got):\n%s", diff)
 	}
 }
+
+func TestLocalQueueFairness(t *testing.T) {
+	now := time.Now()
+	workloads := []*kueue.Workload{
+		utiltesting.MakeWorkload("a1", defaultNamespace).Queue("a").Creation(now).Obj(),
+		utiltesting.MakeWorkload("a2", defaultNamespace).Queue("a").Creation(now.Add(time.Second)).Obj(),
+		utiltesting.MakeWorkload("a3", defaultNamespace).Queue("a").Creation(now.Add(2 * time.Second)).Obj(),
+		utiltesting.MakeWorkload("b1", defaultNamespace).Queue("b").Creation(now.Add(3 * time.Second)).Obj(),
+		utiltesting.MakeWorkload("b2", defaultNamespace).Queue("b").Creation(now.Add(4 * time.Second)).Obj(),
+	}
+	cases := map[string]struct {
+		fairness kueue.LocalQueueFairness
+		admitted map[string]int
+		want     []string
+	}{
+		"none": {
+			fairness: kueue.LocalQueueFairnessNone,
+			want:     []string{"a1", "a2", "a3", "b1", "b2"},
+		},
+		"round robin": {
+			fairness: kueue.RoundRobin,
+			want:     []string{"a1", "b1", "a2", "b2", "a3"},
+		},
+		"usage": {
+			fairness: kueue.LocalQueueUsage,
+			admitted: map[string]int{
+				defaultNamespace + "/a": 2,
+				defaultNamespace + "/b": 1,
+			},
+			want: []string{"b1", "b2", "a1", "a2", "a3"},
+		},
+		"usage is ignored without the policy": {
+			fairness: kueue.RoundRobin,
+			admitted: map[string]int{
+				defaultNamespace + "/a": 2,
+			},
+			want: []string{"a1", "b1", "a2", "b2", "a3"},
+		},
+	}
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			cq, err := newClusterQueue(&kueue.ClusterQueue{
+				Spec: kueue.ClusterQueueSpec{
+					QueueingStrategy:   kueue.BestEffortFIFO,
+					LocalQueueFairness: tc.fairness,
+				},
+			}, byCreationTime)
+			if err != nil {
+				t.Fatalf("Failed creating ClusterQueue %v", err)
+			}
+			for _, w := range workloads {
+				cq.PushOrUpdate(workload.NewInfo(w))
+			}
+			if tc.admitted != nil {
+				cq.SetLocalQueueUsage(tc.admitted)
+			}
+			var got []string
+			for cq.Pending() > 0 {
+				got = append(got, cq.Pop().Obj.Name)
+			}
+			if diff := cmp.Diff(tc.want, got); diff != "" {
+				t.Errorf("Unexpected order of popped workloads (-want,+got):\n%s", diff)
+			}
+		})
+	}
+}
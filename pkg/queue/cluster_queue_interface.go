@@ -33,6 +33,13 @@ const (
 	RequeueReasonFailedAfterNomination RequeueReason = "FailedAfterNomination"
 	RequeueReasonNamespaceMismatch     RequeueReason = "NamespaceMismatch"
 	RequeueReasonGeneric               RequeueReason = ""
+	RequeueReasonPendingPreemption     RequeueReason = "PendingPreemption"
+	// RequeueReasonHeadBlockingTimeout is used when the workload blocked the
+	// head of a StrictFIFO ClusterQueue for longer than its timeout.
+	RequeueReasonHeadBlockingTimeout RequeueReason = "HeadBlockingTimeout"
+	// RequeueReasonQuarantined is used when the workload failed to be
+	// admitted too many consecutive times.
+	RequeueReasonQuarantined RequeueReason = "Quarantined"
 )
 
 // ClusterQueue is an interface for a cluster queue to store workloads waiting
@@ -59,6 +66,26 @@ type ClusterQueue interface {
 	// Pop removes the head of the queue and returns it. It returns nil if the
 	// queue is empty.
 	Pop() *workload.Info
+	// PopAdmissionGroup removes the pending workloads of the admission group,
+	// including the inadmissible ones, and returns them.
+	PopAdmissionGroup(group string) []*workload.Info
+	// Reorder restores the order of the queue, for orderings that change
+	// with time.
+	Reorder()
+	// LocalQueueFairness returns how the workloads of the LocalQueues of the
+	// ClusterQueue are interleaved.
+	LocalQueueFairness() kueue.LocalQueueFairness
+	// SetLocalQueueUsage sets the number of admitted workloads of each
+	// LocalQueue, keyed by namespace/name, which orders the workloads when the
+	// ClusterQueue interleaves them by LocalQueue usage.
+	SetLocalQueueUsage(admitted map[string]int)
+	// HasPriorityBands returns whether the ClusterQueue splits its quota in
+	// priority bands.
+	HasPriorityBands() bool
+	// SetPriorityBandShares sets the usage of each priority band relative to
+	// its guaranteed quota, which orders the workloads of the bands with a
+	// lower share first.
+	SetPriorityBandShares(shares map[string]int64)
 
 	// RequeueIfNotPresent inserts a workload that was not
 	// admitted back into the ClusterQueue. If the boolean is true,
@@ -74,6 +101,9 @@ type ClusterQueue interface {
 	// to the ClusterQueue. If at least one workload is moved,
 	// returns true. Otherwise returns false.
 	QueueInadmissibleWorkloads(ctx context.Context, client client.Client) bool
+	// QueueInadmissibleWorkloadsInNamespace is like QueueInadmissibleWorkloads,
+	// but it only moves the workloads of the given namespace.
+	QueueInadmissibleWorkloadsInNamespace(ctx context.Context, client client.Client, namespace string) bool
 
 	// Pending returns the total number of pending workloads.
 	Pending() int
@@ -85,6 +115,17 @@ type ClusterQueue interface {
 	// workloads that were already tried and are waiting for cluster conditions
 	// to change to potentially become admissible.
 	PendingInadmissible() int
+	// PendingQuarantined returns the number of pending workloads, active or
+	// inadmissible, that are quarantined.
+	PendingQuarantined() int
+	// PendingByPriorityClass returns the number of active and inadmissible
+	// pending workloads per priority class name.
+	PendingByPriorityClass() (active, inadmissible map[string]int)
+
+	// Sorted returns the workloads in the heap of this ClusterQueue, in the
+	// order in which they would be popped.
+	// Users of this method should not modify the returned objects.
+	Sorted() []*workload.Info
 
 	// Dump produces a dump of the current workloads in the heap of
 	// this ClusterQueue. It returns false if the queue is empty.
@@ -96,16 +137,26 @@ type ClusterQueue interface {
 	Info(string) *workload.Info
 }
 
-var registry = map[kueue.QueueingStrategy]func(cq *kueue.ClusterQueue) (ClusterQueue, error){
+var registry = map[kueue.QueueingStrategy]func(cq *kueue.ClusterQueue, lessFunc func(a, b interface{}) bool) (ClusterQueue, error){
 	StrictFIFO:     newClusterQueueStrictFIFO,
 	BestEffortFIFO: newClusterQueueBestEffortFIFO,
 }
 
-func newClusterQueue(cq *kueue.ClusterQueue) (ClusterQueue, error) {
+// newClusterQueue creates a ClusterQueue for the queueing strategy of cq,
+// which orders the pending workloads with lessFunc, adjusted to the ordering
+// policy of cq.
+func newClusterQueue(cq *kueue.ClusterQueue, lessFunc func(a, b interface{}) bool) (ClusterQueue, error) {
 	strategy := cq.Spec.QueueingStrategy
 	f, exist := registry[strategy]
 	if !exist {
 		return nil, fmt.Errorf("invalid QueueingStrategy %q", cq.Spec.QueueingStrategy)
 	}
-	return f(cq)
+	switch cq.Spec.OrderingPolicy {
+	case "", kueue.PriorityOrdering:
+	case kueue.EarliestDeadlineFirst:
+		lessFunc = byDeadline(lessFunc)
+	default:
+		return nil, fmt.Errorf("invalid OrderingPolicy %q", cq.Spec.OrderingPolicy)
+	}
+	return f(cq, lessFunc)
 }
@@ -17,6 +17,12 @@ limitations under the License.
 package queue
 
 import (
+	"math"
+	"time"
+
+	apimeta "k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
 	kueue "sigs.k8s.io/kueue/apis/kueue/v1alpha2"
 	utilpriority "sigs.k8s.io/kueue/pkg/util/priority"
 	"sigs.k8s.io/kueue/pkg/workload"
@@ -32,8 +38,8 @@ var _ ClusterQueue = &ClusterQueueStrictFIFO{}
 
 const StrictFIFO = kueue.StrictFIFO
 
-func newClusterQueueStrictFIFO(cq *kueue.ClusterQueue) (ClusterQueue, error) {
-	cqImpl := newClusterQueueImpl(keyFunc, byCreationTime)
+func newClusterQueueStrictFIFO(cq *kueue.ClusterQueue, lessFunc func(a, b interface{}) bool) (ClusterQueue, error) {
+	cqImpl := newClusterQueueImpl(keyFunc, lessFunc)
 	cqStrict := &ClusterQueueStrictFIFO{
 		clusterQueueBase: cqImpl,
 	}
@@ -42,6 +48,16 @@ func newClusterQueueStrictFIFO(cq *kueue.ClusterQueue) (ClusterQueue, error) {
 	return cqStrict, err
 }
 
+// Pop returns nil while the head of the ClusterQueue is held back by its
+// requeueing backoff, so that the newer workloads remain blocked behind it.
+func (cq *ClusterQueueStrictFIFO) Pop() *workload.Info {
+	if head, ok := cq.heap.Peek().(*workload.Info); ok && InBackoff(head, time.Now()) {
+		cq.popCycle++
+		return nil
+	}
+	return cq.clusterQueueBase.Pop()
+}
+
 // byCreationTime is the function used by the clusterQueue heap algorithm to sort
 // workloads. It sorts workloads based on their priority.
 // When priorities are equal, it uses workloads.creationTimestamp.
@@ -57,9 +73,101 @@ func byCreationTime(a, b interface{}) bool {
 	return objA.Obj.CreationTimestamp.Before(&objB.Obj.CreationTimestamp)
 }
 
+// byCreationTimeOnly is an alternative to byCreationTime that sorts workloads
+// based only on their creation timestamp, ignoring their priority.
+// When timestamps are equal, it uses the workload namespace and name, so that
+// the order doesn't depend on the order in which the workloads were queued.
+func byCreationTimeOnly(a, b interface{}) bool {
+	objA := a.(*workload.Info)
+	objB := b.(*workload.Info)
+	if !objA.Obj.CreationTimestamp.Equal(&objB.Obj.CreationTimestamp) {
+		return objA.Obj.CreationTimestamp.Before(&objB.Obj.CreationTimestamp)
+	}
+	return workload.Key(objA.Obj) < workload.Key(objB.Obj)
+}
+
+// byEffectivePriority is an alternative to byCreationTime that sorts
+// workloads based on the given priority function, instead of their priority.
+// When effective priorities are equal, it uses workloads.creationTimestamp.
+func byEffectivePriority(priority func(*kueue.Workload) int32) func(a, b interface{}) bool {
+	return func(a, b interface{}) bool {
+		objA := a.(*workload.Info).Obj
+		objB := b.(*workload.Info).Obj
+		p1 := priority(objA)
+		p2 := priority(objB)
+
+		if p1 != p2 {
+			return p1 > p2
+		}
+		return objA.CreationTimestamp.Before(&objB.CreationTimestamp)
+	}
+}
+
+// agedPriority returns a function that computes the effective priority of a
+// pending workload: its priority increased by rate for each minute since the
+// workload was created or last evicted, by at most maxBoost.
+// The result changes with time, so the heaps that use it need to be
+// reordered before popping their heads.
+func agedPriority(rate, maxBoost int32) func(*kueue.Workload) int32 {
+	return func(wl *kueue.Workload) int32 {
+		since := wl.CreationTimestamp.Time
+		if c := apimeta.FindStatusCondition(wl.Status.Conditions, kueue.WorkloadEvicted); c != nil && c.Status == metav1.ConditionTrue {
+			since = c.LastTransitionTime.Time
+		}
+		boost := int64(rate) * int64(time.Since(since)/time.Minute)
+		if boost > int64(maxBoost) {
+			boost = int64(maxBoost)
+		}
+		if boost < 0 {
+			boost = 0
+		}
+		p := int64(utilpriority.Priority(wl)) + boost
+		if p > math.MaxInt32 {
+			p = math.MaxInt32
+		}
+		return int32(p)
+	}
+}
+
+// evictedFirst wraps lessFunc to sort the evicted workloads before the other
+// workloads. If priority is not nil, the evicted workloads are only sorted
+// before the other workloads with the same priority.
+func evictedFirst(lessFunc func(a, b interface{}) bool, priority func(*kueue.Workload) int32) func(a, b interface{}) bool {
+	return func(a, b interface{}) bool {
+		objA := a.(*workload.Info).Obj
+		objB := b.(*workload.Info).Obj
+		if priority != nil && priority(objA) != priority(objB) {
+			return lessFunc(a, b)
+		}
+		if evictedA, evictedB := workload.IsEvicted(objA), workload.IsEvicted(objB); evictedA != evictedB {
+			return evictedA
+		}
+		return lessFunc(a, b)
+	}
+}
+
+// byDeadline wraps lessFunc to sort the workloads that have a deadline first,
+// based on the latest time at which they can start to meet it. Workloads
+// without a deadline, or with the same latest start time, are sorted with
+// lessFunc.
+func byDeadline(lessFunc func(a, b interface{}) bool) func(a, b interface{}) bool {
+	return func(a, b interface{}) bool {
+		startA, okA := workload.LatestStartTime(a.(*workload.Info).Obj)
+		startB, okB := workload.LatestStartTime(b.(*workload.Info).Obj)
+		if okA != okB {
+			return okA
+		}
+		if okA && !startA.Equal(startB) {
+			return startA.Before(startB)
+		}
+		return lessFunc(a, b)
+	}
+}
+
 // RequeueIfNotPresent requeues if the workload is not present.
 // If the reason for requeue is that the workload doesn't match the CQ's
-// namespace selector, then the requeue is not immediate.
+// namespace selector, that it blocked the head of the CQ for too long, or that
+// it's quarantined, then the requeue is not immediate.
 func (cq *ClusterQueueStrictFIFO) RequeueIfNotPresent(wInfo *workload.Info, reason RequeueReason) bool {
-	return cq.requeueIfNotPresent(wInfo, reason != RequeueReasonNamespaceMismatch)
+	return cq.requeueIfNotPresent(wInfo, reason != RequeueReasonNamespaceMismatch && reason != RequeueReasonHeadBlockingTimeout && reason != RequeueReasonQuarantined)
 }
@@ -20,10 +20,12 @@ import (
 	"testing"
 	"time"
 
+	"github.com/google/go-cmp/cmp"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/utils/pointer"
 
 	kueue "sigs.k8s.io/kueue/apis/kueue/v1alpha2"
+	utilpriority "sigs.k8s.io/kueue/pkg/util/priority"
 	utiltesting "sigs.k8s.io/kueue/pkg/util/testing"
 	"sigs.k8s.io/kueue/pkg/workload"
 )
@@ -38,7 +40,7 @@ func TestFIFOClusterQueue(t *testing.T) {
 		Spec: kueue.ClusterQueueSpec{
 			QueueingStrategy: kueue.StrictFIFO,
 		},
-	})
+	}, byCreationTime)
 	if err != nil {
 		t.Fatalf("Failed creating ClusterQueue %v", err)
 	}
@@ -101,10 +103,12 @@ func TestStrictFIFO(t *testing.T) {
 	t1 := time.Now()
 	t2 := t1.Add(time.Second)
 	for _, tt := range []struct {
-		name     string
-		w1       *kueue.Workload
-		w2       *kueue.Workload
-		expected string
+		name                string
+		strictCreationOrder bool
+		requeueEvictedFirst bool
+		w1                  *kueue.Workload
+		w2                  *kueue.Workload
+		expected            string
 	}{
 		{
 			name: "w1.priority is higher than w2.priority",
@@ -170,13 +174,170 @@ func TestStrictFIFO(t *testing.T) {
 			},
 			expected: "w2",
 		},
+		{
+			name:                "strict creation order; p1.priority is lower than p2.priority and w1.create time is earlier than w2.create time",
+			strictCreationOrder: true,
+			w1: &kueue.Workload{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:              "w1",
+					CreationTimestamp: metav1.NewTime(t1),
+				},
+				Spec: kueue.WorkloadSpec{
+					PriorityClassName: "lowPriority",
+					Priority:          pointer.Int32(lowPriority),
+				},
+			},
+			w2: &kueue.Workload{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:              "w2",
+					CreationTimestamp: metav1.NewTime(t2),
+				},
+				Spec: kueue.WorkloadSpec{
+					PriorityClassName: "highPriority",
+					Priority:          pointer.Int32(highPriority),
+				},
+			},
+			expected: "w1",
+		},
+		{
+			name:                "strict creation order; w1.create time equals w2.create time",
+			strictCreationOrder: true,
+			w1: &kueue.Workload{
+				ObjectMeta: metav1.ObjectMeta{
+					Namespace:         "ns2",
+					Name:              "w1",
+					CreationTimestamp: metav1.NewTime(t1),
+				},
+			},
+			w2: &kueue.Workload{
+				ObjectMeta: metav1.ObjectMeta{
+					Namespace:         "ns1",
+					Name:              "w2",
+					CreationTimestamp: metav1.NewTime(t1),
+				},
+			},
+			expected: "w2",
+		},
+		{
+			name: "w2 was evicted and requeueing evicted workloads first is disabled",
+			w1: &kueue.Workload{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:              "w1",
+					CreationTimestamp: metav1.NewTime(t1),
+				},
+			},
+			w2: &kueue.Workload{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:              "w2",
+					CreationTimestamp: metav1.NewTime(t2),
+				},
+				Status: kueue.WorkloadStatus{
+					Conditions: []metav1.Condition{
+						{Type: kueue.WorkloadEvicted, Status: metav1.ConditionTrue, Reason: "Preempted"},
+					},
+				},
+			},
+			expected: "w1",
+		},
+		{
+			name:                "requeue evicted first; w2 was evicted and w1.priority equals w2.priority",
+			requeueEvictedFirst: true,
+			w1: &kueue.Workload{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:              "w1",
+					CreationTimestamp: metav1.NewTime(t1),
+				},
+			},
+			w2: &kueue.Workload{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:              "w2",
+					CreationTimestamp: metav1.NewTime(t2),
+				},
+				Status: kueue.WorkloadStatus{
+					Conditions: []metav1.Condition{
+						{Type: kueue.WorkloadEvicted, Status: metav1.ConditionTrue, Reason: "Preempted"},
+					},
+				},
+			},
+			expected: "w2",
+		},
+		{
+			name:                "requeue evicted first; w2 was evicted and w1.priority is higher than w2.priority",
+			requeueEvictedFirst: true,
+			w1: &kueue.Workload{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:              "w1",
+					CreationTimestamp: metav1.NewTime(t1),
+				},
+				Spec: kueue.WorkloadSpec{
+					PriorityClassName: "highPriority",
+					Priority:          pointer.Int32(highPriority),
+				},
+			},
+			w2: &kueue.Workload{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:              "w2",
+					CreationTimestamp: metav1.NewTime(t2),
+				},
+				Spec: kueue.WorkloadSpec{
+					PriorityClassName: "lowPriority",
+					Priority:          pointer.Int32(lowPriority),
+				},
+				Status: kueue.WorkloadStatus{
+					Conditions: []metav1.Condition{
+						{Type: kueue.WorkloadEvicted, Status: metav1.ConditionTrue, Reason: "Preempted"},
+					},
+				},
+			},
+			expected: "w1",
+		},
+		{
+			name:                "requeue evicted first and strict creation order; w2 was evicted and w1.priority is higher than w2.priority",
+			strictCreationOrder: true,
+			requeueEvictedFirst: true,
+			w1: &kueue.Workload{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:              "w1",
+					CreationTimestamp: metav1.NewTime(t1),
+				},
+				Spec: kueue.WorkloadSpec{
+					PriorityClassName: "highPriority",
+					Priority:          pointer.Int32(highPriority),
+				},
+			},
+			w2: &kueue.Workload{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:              "w2",
+					CreationTimestamp: metav1.NewTime(t2),
+				},
+				Spec: kueue.WorkloadSpec{
+					PriorityClassName: "lowPriority",
+					Priority:          pointer.Int32(lowPriority),
+				},
+				Status: kueue.WorkloadStatus{
+					Conditions: []metav1.Condition{
+						{Type: kueue.WorkloadEvicted, Status: metav1.ConditionTrue, Reason: "Preempted"},
+					},
+				},
+			},
+			expected: "w2",
+		},
 	} {
 		t.Run(tt.name, func(t *testing.T) {
+			lessFunc := byCreationTime
+			priority := utilpriority.Priority
+			if tt.strictCreationOrder {
+				lessFunc = byCreationTimeOnly
+				priority = nil
+			}
+			if tt.requeueEvictedFirst {
+				lessFunc = evictedFirst(lessFunc, priority)
+			}
 			q, err := newClusterQueue(&kueue.ClusterQueue{
 				Spec: kueue.ClusterQueueSpec{
 					QueueingStrategy: kueue.StrictFIFO,
 				},
-			})
+			}, lessFunc)
 			if err != nil {
 				t.Fatalf("Failed creating ClusterQueue %v", err)
 			}
@@ -195,6 +356,141 @@ func TestStrictFIFO(t *testing.T) {
 	}
 }
 
+func TestWorkloadAging(t *testing.T) {
+	now := time.Now()
+	for _, tt := range []struct {
+		name      string
+		workloads []*kueue.Workload
+		expected  []string
+	}{
+		{
+			name: "priority is increased for each minute pending",
+			workloads: []*kueue.Workload{
+				utiltesting.MakeWorkload("w1", "").Creation(now.Add(-time.Second)).
+					Priority(pointer.Int32(20)).Obj(),
+				utiltesting.MakeWorkload("w2", "").Creation(now.Add(-3 * time.Minute)).
+					Priority(pointer.Int32(0)).Obj(),
+				utiltesting.MakeWorkload("w3", "").Creation(now.Add(-90 * time.Second)).
+					Priority(pointer.Int32(5)).Obj(),
+			},
+			expected: []string{"w2", "w1", "w3"},
+		},
+		{
+			name: "increase is capped",
+			workloads: []*kueue.Workload{
+				utiltesting.MakeWorkload("w1", "").Creation(now.Add(-time.Second)).
+					Priority(pointer.Int32(highPriority)).Obj(),
+				utiltesting.MakeWorkload("w2", "").Creation(now.Add(-24 * time.Hour)).
+					Priority(pointer.Int32(lowPriority)).Obj(),
+			},
+			expected: []string{"w1", "w2"},
+		},
+		{
+			name: "pending time restarts on eviction",
+			workloads: []*kueue.Workload{
+				utiltesting.MakeWorkload("w1", "").Creation(now.Add(-time.Minute)).
+					Priority(pointer.Int32(15)).Obj(),
+				utiltesting.MakeWorkload("w2", "").Creation(now.Add(-time.Hour)).
+					Priority(pointer.Int32(0)).
+					Condition(metav1.Condition{
+						Type:               kueue.WorkloadEvicted,
+						Status:             metav1.ConditionTrue,
+						LastTransitionTime: metav1.NewTime(now.Add(-2 * time.Minute)),
+						Reason:             "Preempted",
+					}).Obj(),
+			},
+			expected: []string{"w1", "w2"},
+		},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			q, err := newClusterQueue(&kueue.ClusterQueue{
+				Spec: kueue.ClusterQueueSpec{
+					QueueingStrategy: kueue.BestEffortFIFO,
+				},
+			}, byEffectivePriority(agedPriority(10, 100)))
+			if err != nil {
+				t.Fatalf("Failed creating ClusterQueue %v", err)
+			}
+			for _, wl := range tt.workloads {
+				q.PushOrUpdate(workload.NewInfo(wl))
+			}
+			q.Reorder()
+			var got []string
+			for wl := q.Pop(); wl != nil; wl = q.Pop() {
+				got = append(got, wl.Obj.Name)
+			}
+			if diff := cmp.Diff(tt.expected, got); diff != "" {
+				t.Errorf("Unexpected order (-want,+got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestEarliestDeadlineFirst(t *testing.T) {
+	now := time.Now()
+	for _, tt := range []struct {
+		name      string
+		workloads []*kueue.Workload
+		expected  []string
+	}{
+		{
+			name: "workloads with a deadline go first",
+			workloads: []*kueue.Workload{
+				utiltesting.MakeWorkload("w1", "").Creation(now).
+					Priority(pointer.Int32(highPriority)).Obj(),
+				utiltesting.MakeWorkload("w2", "").Creation(now.Add(time.Second)).
+					Deadline(now.Add(time.Hour)).Obj(),
+			},
+			expected: []string{"w2", "w1"},
+		},
+		{
+			name: "earliest latest start time goes first",
+			workloads: []*kueue.Workload{
+				utiltesting.MakeWorkload("w1", "").Creation(now).
+					Deadline(now.Add(2 * time.Hour)).Obj(),
+				utiltesting.MakeWorkload("w2", "").Creation(now.Add(time.Second)).
+					Deadline(now.Add(3 * time.Hour)).ExpectedDuration(2 * time.Hour).Obj(),
+				utiltesting.MakeWorkload("w3", "").Creation(now.Add(2 * time.Second)).
+					Deadline(now.Add(30 * time.Minute)).Obj(),
+			},
+			expected: []string{"w3", "w2", "w1"},
+		},
+		{
+			name: "same latest start time falls back to priority",
+			workloads: []*kueue.Workload{
+				utiltesting.MakeWorkload("w1", "").Creation(now).
+					Deadline(now.Add(time.Hour)).Priority(pointer.Int32(lowPriority)).Obj(),
+				utiltesting.MakeWorkload("w2", "").Creation(now.Add(time.Second)).
+					Deadline(now.Add(time.Hour)).Priority(pointer.Int32(highPriority)).Obj(),
+			},
+			expected: []string{"w2", "w1"},
+		},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			q, err := newClusterQueue(&kueue.ClusterQueue{
+				Spec: kueue.ClusterQueueSpec{
+					QueueingStrategy: kueue.StrictFIFO,
+					OrderingPolicy:   kueue.EarliestDeadlineFirst,
+				},
+			}, byCreationTime)
+			if err != nil {
+				t.Fatalf("Failed creating ClusterQueue %v", err)
+			}
+			for _, w := range tt.workloads {
+				q.PushOrUpdate(workload.NewInfo(w))
+			}
+
+			var got []string
+			for q.Pending() > 0 {
+				got = append(got, q.Pop().Obj.Name)
+			}
+			if diff := cmp.Diff(tt.expected, got); diff != "" {
+				t.Errorf("Unexpected order of popped workloads (-want,+got):\n%s", diff)
+			}
+		})
+	}
+}
+
 func TestStrictFIFORequeueIfNotPresent(t *testing.T) {
 	tests := map[RequeueReason]struct {
 		wantInadmissible bool
@@ -208,6 +504,9 @@ func TestStrictFIFORequeueIfNotPresent(t *testing.T) {
 		RequeueReasonGeneric: {
 			wantInadmissible: false,
 		},
+		RequeueReasonHeadBlockingTimeout: {
+			wantInadmissible: true,
+		},
 	}
 
 	for reason, test := range tests {
@@ -216,7 +515,7 @@ func TestStrictFIFORequeueIfNotPresent(t *testing.T) {
 				Spec: kueue.ClusterQueueSpec{
 					QueueingStrategy: kueue.StrictFIFO,
 				},
-			})
+			}, byCreationTime)
 			wl := utiltesting.MakeWorkload("workload-1", defaultNamespace).Obj()
 			if ok := cq.RequeueIfNotPresent(workload.NewInfo(wl), reason); !ok {
 				t.Error("failed to requeue nonexistent workload")
@@ -19,6 +19,8 @@ package queue
 import (
 	"fmt"
 
+	"k8s.io/apimachinery/pkg/util/sets"
+
 	kueue "sigs.k8s.io/kueue/apis/kueue/v1alpha2"
 	"sigs.k8s.io/kueue/pkg/workload"
 )
@@ -38,6 +40,10 @@ type LocalQueue struct {
 	Key          string
 	ClusterQueue string
 
+	// routes are the ClusterQueues of the workloads, keyed by
+	// priorityClassName, that are not queued in ClusterQueue.
+	routes map[string]string
+
 	items map[string]*workload.Info
 }
 
@@ -52,6 +58,76 @@ func newLocalQueue(q *kueue.LocalQueue) *LocalQueue {
 
 func (q *LocalQueue) update(apiQueue *kueue.LocalQueue) {
 	q.ClusterQueue = string(apiQueue.Spec.ClusterQueue)
+	q.routes = nil
+	for _, r := range apiQueue.Spec.PriorityClassRouting {
+		if q.routes == nil {
+			q.routes = make(map[string]string, len(apiQueue.Spec.PriorityClassRouting))
+		}
+		q.routes[r.PriorityClassName] = string(r.ClusterQueue)
+	}
+}
+
+// clusterQueueFor returns the name of the ClusterQueue in which the workload
+// is queued, based on its priorityClassName.
+func (q *LocalQueue) clusterQueueFor(w *kueue.Workload) string {
+	if cq, ok := q.routes[w.Spec.PriorityClassName]; ok {
+		return cq
+	}
+	return q.ClusterQueue
+}
+
+// clusterQueues returns the names of all the ClusterQueues in which the
+// workloads of the queue can be queued.
+func (q *LocalQueue) clusterQueues() sets.String {
+	names := sets.NewString(q.ClusterQueue)
+	for _, cq := range q.routes {
+		names.Insert(cq)
+	}
+	return names
+}
+
+// routedTo returns a view of the queue with only the workloads that are
+// queued in the ClusterQueue.
+func (q *LocalQueue) routedTo(cqName string) *LocalQueue {
+	if len(q.routes) == 0 {
+		return q
+	}
+	view := &LocalQueue{
+		Key:          q.Key,
+		ClusterQueue: cqName,
+		items:        make(map[string]*workload.Info),
+	}
+	for key, info := range q.items {
+		if q.clusterQueueFor(info.Obj) == cqName {
+			view.items[key] = info
+		}
+	}
+	return view
+}
+
+// routingChanged returns whether the ClusterQueues of the workloads in the
+// updated queue could be different.
+func (q *LocalQueue) routingChanged(apiQueue *kueue.LocalQueue) bool {
+	if q.ClusterQueue != string(apiQueue.Spec.ClusterQueue) || len(q.routes) != len(apiQueue.Spec.PriorityClassRouting) {
+		return true
+	}
+	for _, r := range apiQueue.Spec.PriorityClassRouting {
+		if cq, ok := q.routes[r.PriorityClassName]; !ok || cq != string(r.ClusterQueue) {
+			return true
+		}
+	}
+	return false
+}
+
+// ClusterQueuesOf returns the names of all the ClusterQueues in which the
+// workloads of the LocalQueue can be queued, including the ones of its
+// priority class routing.
+func ClusterQueuesOf(q *kueue.LocalQueue) []string {
+	names := sets.NewString(string(q.Spec.ClusterQueue))
+	for _, r := range q.Spec.PriorityClassRouting {
+		names.Insert(string(r.ClusterQueue))
+	}
+	return names.List()
 }
 
 func (q *LocalQueue) AddOrUpdate(info *workload.Info) {
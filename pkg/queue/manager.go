@@ -21,6 +21,7 @@ import (
 	"errors"
 	"fmt"
 	"sync"
+	"time"
 
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/apimachinery/pkg/util/sets"
@@ -29,6 +30,7 @@ import (
 
 	kueue "sigs.k8s.io/kueue/apis/kueue/v1alpha2"
 	"sigs.k8s.io/kueue/pkg/metrics"
+	utilpriority "sigs.k8s.io/kueue/pkg/util/priority"
 	"sigs.k8s.io/kueue/pkg/workload"
 )
 
@@ -43,6 +45,81 @@ var (
 	errClusterQueueAlreadyExists = errors.New("clusterQueue already exists")
 )
 
+type options struct {
+	strictCreationOrder bool
+	requeueEvictedFirst bool
+	requeueBackoffBase  time.Duration
+	requeueBackoffMax   time.Duration
+	agingRate           int32
+	agingCap            int32
+	priorityInheritance bool
+	quarantineThreshold int32
+	quarantineInterval  time.Duration
+}
+
+// Option configures the manager.
+type Option func(*options)
+
+// WithStrictCreationOrder indicates if the ClusterQueues should order their
+// pending workloads only by creation timestamp, regardless of their priority
+// and of the LocalQueue they were submitted to.
+func WithStrictCreationOrder(f bool) Option {
+	return func(o *options) {
+		o.strictCreationOrder = f
+	}
+}
+
+// WithRequeueEvictedFirst indicates if the ClusterQueues should place the
+// evicted workloads in front of the other pending workloads with the same
+// priority.
+func WithRequeueEvictedFirst(f bool) Option {
+	return func(o *options) {
+		o.requeueEvictedFirst = f
+	}
+}
+
+// WithRequeueBackoff sets the base and maximum delays of the exponential
+// backoff for the workloads that are requeued after failing to be admitted.
+// The backoff is disabled if base is zero.
+func WithRequeueBackoff(base, max time.Duration) Option {
+	return func(o *options) {
+		o.requeueBackoffBase = base
+		o.requeueBackoffMax = max
+	}
+}
+
+// WithQuarantine quarantines the workloads that fail to be admitted threshold
+// consecutive times, so that they are only retried every interval.
+// Quarantine is disabled if threshold is zero.
+func WithQuarantine(threshold int32, interval time.Duration) Option {
+	return func(o *options) {
+		o.quarantineThreshold = threshold
+		o.quarantineInterval = interval
+	}
+}
+
+// WithWorkloadAging makes the ClusterQueues order their pending workloads by
+// an effective priority, which increases by rate for each minute that the
+// workloads wait, up to maxBoost. Aging is disabled if rate is zero.
+func WithWorkloadAging(rate, maxBoost int32) Option {
+	return func(o *options) {
+		o.agingRate = rate
+		o.agingCap = maxBoost
+	}
+}
+
+// WithPriorityInheritance makes the ClusterQueues order their pending
+// workloads by the highest priority among their own, the ones of the other
+// pending workloads of their admission group, and the one of the pending
+// workload they depend on.
+func WithPriorityInheritance(f bool) Option {
+	return func(o *options) {
+		o.priorityInheritance = f
+	}
+}
+
+var defaultOptions = options{}
+
 type Manager struct {
 	sync.RWMutex
 	cond sync.Cond
@@ -51,19 +128,70 @@ type Manager struct {
 	statusChecker StatusChecker
 	clusterQueues map[string]ClusterQueue
 	localQueues   map[string]*LocalQueue
+	// workloadOrdering is the function used by the ClusterQueues to sort
+	// their pending workloads.
+	workloadOrdering func(a, b interface{}) bool
+	// reorderHeads indicates that the workloadOrdering changes with time, so
+	// the ClusterQueues are reordered before popping their heads.
+	reorderHeads bool
+	// inheritance tracks the priorities that the pending workloads inherit,
+	// nil if priority inheritance is disabled.
+	inheritance *priorityInheritance
+	// requeueBackoffBase and requeueBackoffMax are the delays of the backoff
+	// for the workloads that fail to be admitted. Disabled if base is zero.
+	requeueBackoffBase time.Duration
+	requeueBackoffMax  time.Duration
+	// quarantineThreshold is the number of consecutive failed admission
+	// attempts after which a workload is quarantined, and only retried every
+	// quarantineInterval. Disabled if zero.
+	quarantineThreshold int32
+	quarantineInterval  time.Duration
 
 	// Key is cohort's name. Value is a set of associated ClusterQueue names.
 	cohorts map[string]sets.String
+	// Key is cohort's name. Value is the name of its parent cohort, as set
+	// in the Cohort object.
+	cohortParents map[string]string
 }
 
-func NewManager(client client.Client, checker StatusChecker) *Manager {
-	m := &Manager{
-		client:        client,
-		statusChecker: checker,
-		localQueues:   make(map[string]*LocalQueue),
-		clusterQueues: make(map[string]ClusterQueue),
-		cohorts:       make(map[string]sets.String),
+func NewManager(client client.Client, checker StatusChecker, opts ...Option) *Manager {
+	options := defaultOptions
+	for _, opt := range opts {
+		opt(&options)
 	}
+	m := &Manager{
+		client:           client,
+		statusChecker:    checker,
+		localQueues:      make(map[string]*LocalQueue),
+		clusterQueues:    make(map[string]ClusterQueue),
+		cohorts:          make(map[string]sets.String),
+		cohortParents:    make(map[string]string),
+		workloadOrdering: byCreationTime,
+	}
+	priority := utilpriority.Priority
+	if options.agingRate > 0 {
+		priority = agedPriority(options.agingRate, options.agingCap)
+		m.workloadOrdering = byEffectivePriority(priority)
+		m.reorderHeads = true
+	}
+	if options.priorityInheritance {
+		m.inheritance = newPriorityInheritance()
+		priority = m.inheritance.priority(priority)
+		m.workloadOrdering = byEffectivePriority(priority)
+		m.reorderHeads = true
+	}
+	if options.strictCreationOrder {
+		priority = nil
+		m.workloadOrdering = byCreationTimeOnly
+		m.reorderHeads = false
+	}
+	if options.requeueEvictedFirst {
+		m.workloadOrdering = evictedFirst(m.workloadOrdering, priority)
+	}
+	m.requeueBackoffBase = options.requeueBackoffBase
+	m.requeueBackoffMax = options.requeueBackoffMax
+	m.quarantineThreshold = options.quarantineThreshold
+	m.quarantineInterval = options.quarantineInterval
 	m.cond.L = &m.RWMutex
 	return m
 }
@@ -76,7 +204,7 @@ func (m *Manager) AddClusterQueue(ctx context.Context, cq *kueue.ClusterQueue) e
 		return errClusterQueueAlreadyExists
 	}
 
-	cqImpl, err := newClusterQueue(cq)
+	cqImpl, err := newClusterQueue(cq, m.workloadOrdering)
 	if err != nil {
 		return err
 	}
@@ -96,12 +224,12 @@ func (m *Manager) AddClusterQueue(ctx context.Context, cq *kueue.ClusterQueue) e
 	addedWorkloads := false
 	for _, q := range queues.Items {
 		// Checking clusterQueue name again because the field index is not available in tests.
-		if string(q.Spec.ClusterQueue) != cq.Name {
+		if !sets.NewString(ClusterQueuesOf(&q)...).Has(cq.Name) {
 			continue
 		}
 		qImpl := m.localQueues[Key(&q)]
 		if qImpl != nil {
-			added := cqImpl.AddFromLocalQueue(qImpl)
+			added := cqImpl.AddFromLocalQueue(qImpl.routedTo(cq.Name))
 			addedWorkloads = addedWorkloads || added
 		}
 	}
@@ -128,12 +256,18 @@ func (m *Manager) UpdateClusterQueue(ctx context.Context, cq *kueue.ClusterQueue
 		return err
 	}
 	newCohort := cqImpl.Cohort()
+	queued := false
 	if oldCohort != newCohort {
 		m.updateCohort(oldCohort, newCohort, cq.Name)
+		// The usage of the ClusterQueue left the old tree of cohorts, which
+		// can make its inadmissible workloads fit.
+		if oldCohort != "" && m.rootCohort(oldCohort) != m.rootCohort(newCohort) {
+			queued = m.queueAllInadmissibleWorkloadsInCohortTree(ctx, oldCohort)
+		}
 	}
 
 	// TODO(#8): Selectively move workloads based on the exact event.
-	if m.queueAllInadmissibleWorkloadsInCohort(ctx, cqImpl) {
+	if m.queueAllInadmissibleWorkloadsInCohort(ctx, cqImpl) || queued {
 		m.reportPendingWorkloads(cq.Name, cqImpl)
 		m.Broadcast()
 	}
@@ -155,6 +289,24 @@ func (m *Manager) DeleteClusterQueue(cq *kueue.ClusterQueue) {
 	m.deleteCohort(cohort, cq.Name)
 }
 
+// AddOrUpdateCohort records the parent of the cohort, so that the inadmissible
+// workloads of a tree of cohorts are queued together.
+func (m *Manager) AddOrUpdateCohort(cohort *kueue.Cohort) {
+	m.Lock()
+	defer m.Unlock()
+	if cohort.Spec.Parent == "" {
+		delete(m.cohortParents, cohort.Name)
+		return
+	}
+	m.cohortParents[cohort.Name] = cohort.Spec.Parent
+}
+
+func (m *Manager) DeleteCohort(name string) {
+	m.Lock()
+	defer m.Unlock()
+	delete(m.cohortParents, name)
+}
+
 func (m *Manager) AddLocalQueue(ctx context.Context, q *kueue.LocalQueue) error {
 	m.Lock()
 	defer m.Unlock()
@@ -174,13 +326,12 @@ func (m *Manager) AddLocalQueue(ctx context.Context, q *kueue.LocalQueue) error
 	for _, w := range workloads.Items {
 		w := w
 		// Checking queue name again because the field index is not available in tests.
-		if w.Spec.QueueName != q.Name || w.Spec.Admission != nil {
+		if w.Spec.QueueName != q.Name || w.Status.Admission != nil || workload.HasDeprecatedAdmission(&w) || !w.DeletionTimestamp.IsZero() || !workload.IsActive(&w) || !workload.IsManagedByKueue(&w) {
 			continue
 		}
 		qImpl.AddOrUpdate(workload.NewInfo(&w))
 	}
-	cq := m.clusterQueues[qImpl.ClusterQueue]
-	if cq != nil && cq.AddFromLocalQueue(qImpl) {
+	if m.addFromLocalQueue(qImpl) {
 		m.Broadcast()
 	}
 	return nil
@@ -193,17 +344,15 @@ func (m *Manager) UpdateLocalQueue(q *kueue.LocalQueue) error {
 	if !ok {
 		return errQueueDoesNotExist
 	}
-	if qImpl.ClusterQueue != string(q.Spec.ClusterQueue) {
-		oldCQ := m.clusterQueues[qImpl.ClusterQueue]
-		if oldCQ != nil {
-			oldCQ.DeleteFromLocalQueue(qImpl)
-		}
-		newCQ := m.clusterQueues[string(q.Spec.ClusterQueue)]
-		if newCQ != nil && newCQ.AddFromLocalQueue(qImpl) {
-			m.Broadcast()
-		}
+	if !qImpl.routingChanged(q) {
+		qImpl.update(q)
+		return nil
 	}
+	m.deleteFromLocalQueue(qImpl)
 	qImpl.update(q)
+	if m.addFromLocalQueue(qImpl) {
+		m.Broadcast()
+	}
 	return nil
 }
 
@@ -215,13 +364,32 @@ func (m *Manager) DeleteLocalQueue(q *kueue.LocalQueue) {
 	if qImpl == nil {
 		return
 	}
-	cq := m.clusterQueues[qImpl.ClusterQueue]
-	if cq != nil {
-		cq.DeleteFromLocalQueue(qImpl)
-	}
+	m.deleteFromLocalQueue(qImpl)
 	delete(m.localQueues, key)
 }
 
+// addFromLocalQueue pushes the workloads of the LocalQueue to the
+// ClusterQueues they are routed to. Returns whether any workload was added.
+func (m *Manager) addFromLocalQueue(q *LocalQueue) bool {
+	added := false
+	for name := range q.clusterQueues() {
+		if cq := m.clusterQueues[name]; cq != nil && cq.AddFromLocalQueue(q.routedTo(name)) {
+			added = true
+		}
+	}
+	return added
+}
+
+// deleteFromLocalQueue removes the workloads of the LocalQueue from the
+// ClusterQueues they are routed to.
+func (m *Manager) deleteFromLocalQueue(q *LocalQueue) {
+	for name := range q.clusterQueues() {
+		if cq := m.clusterQueues[name]; cq != nil {
+			cq.DeleteFromLocalQueue(q)
+		}
+	}
+}
+
 func (m *Manager) PendingWorkloads(q *kueue.LocalQueue) (int32, error) {
 	m.RLock()
 	defer m.RUnlock()
@@ -237,7 +405,11 @@ func (m *Manager) PendingWorkloads(q *kueue.LocalQueue) (int32, error) {
 func (m *Manager) Pending(cq *kueue.ClusterQueue) int {
 	m.RLock()
 	defer m.RUnlock()
-	return m.clusterQueues[cq.Name].Pending()
+	cqImpl := m.clusterQueues[cq.Name]
+	if cqImpl == nil {
+		return 0
+	}
+	return cqImpl.Pending()
 }
 
 func (m *Manager) QueueForWorkloadExists(wl *kueue.Workload) bool {
@@ -258,8 +430,9 @@ func (m *Manager) ClusterQueueForWorkload(wl *kueue.Workload) (string, bool) {
 	if !ok {
 		return "", false
 	}
-	_, ok = m.clusterQueues[q.ClusterQueue]
-	return q.ClusterQueue, ok
+	cqName := q.clusterQueueFor(wl)
+	_, ok = m.clusterQueues[cqName]
+	return cqName, ok
 }
 
 // AddOrUpdateWorkload adds or updates workload to the corresponding queue.
@@ -276,21 +449,33 @@ func (m *Manager) addOrUpdateWorkload(w *kueue.Workload) bool {
 	if q == nil {
 		return false
 	}
+	if !workload.IsActive(w) || !workload.IsManagedByKueue(w) {
+		// Inactive workloads aren't considered for admission until they are
+		// activated again. The workloads managed by other controllers are
+		// never considered.
+		m.deleteWorkloadFromQueueAndClusterQueue(w, qKey)
+		return true
+	}
+	if m.inheritance != nil {
+		m.inheritance.add(w)
+	}
 	wInfo := workload.NewInfo(w)
 	q.AddOrUpdate(wInfo)
-	cq := m.clusterQueues[q.ClusterQueue]
+	cqName := q.clusterQueueFor(w)
+	cq := m.clusterQueues[cqName]
 	if cq == nil {
 		return false
 	}
 	cq.PushOrUpdate(wInfo)
-	m.reportPendingWorkloads(q.ClusterQueue, cq)
+	m.reportPendingWorkloads(cqName, cq)
 	m.Broadcast()
 	return true
 }
 
 // RequeueWorkload requeues the workload ensuring that the queue and the
-// workload still exist in the client cache and it's not admitted. It won't
-// requeue if the workload is already in the queue (possible if the workload was updated).
+// workload still exist in the client cache and it's neither admitted nor
+// inactive. It won't requeue if the workload is already in the queue
+// (possible if the workload was updated).
 func (m *Manager) RequeueWorkload(ctx context.Context, info *workload.Info, reason RequeueReason) bool {
 	m.Lock()
 	defer m.Unlock()
@@ -299,7 +484,7 @@ func (m *Manager) RequeueWorkload(ctx context.Context, info *workload.Info, reas
 	// Always get the newest workload to avoid requeuing the out-of-date obj.
 	err := m.client.Get(ctx, client.ObjectKeyFromObject(info.Obj), &w)
 	// Since the client is cached, the only possible error is NotFound
-	if apierrors.IsNotFound(err) || w.Spec.Admission != nil {
+	if apierrors.IsNotFound(err) || w.Status.Admission != nil || workload.HasDeprecatedAdmission(&w) || !workload.IsActive(&w) || !workload.IsManagedByKueue(&w) {
 		return false
 	}
 
@@ -307,21 +492,63 @@ func (m *Manager) RequeueWorkload(ctx context.Context, info *workload.Info, reas
 	if q == nil {
 		return false
 	}
+	// The stats in the client cache might not include the last update yet.
+	if stats := info.Obj.Status.SchedulingStats; workload.SchedulingAttempts(info.Obj) > workload.SchedulingAttempts(&w) {
+		w.Status.SchedulingStats = stats.DeepCopy()
+	}
+	failed := reason == RequeueReasonGeneric || reason == RequeueReasonFailedAfterNomination
+	if failed && (m.requeueBackoffBase > 0 || m.quarantineThreshold > 0) {
+		// The state in the client cache might not include the last update yet.
+		state := info.Obj.Status.RequeueState
+		if w.Status.RequeueState != nil && (state == nil || w.Status.RequeueState.Count > state.Count) {
+			state = w.Status.RequeueState
+		}
+		now := time.Now()
+		w.Status.RequeueState = nextRequeueState(state, m.requeueBackoffBase, m.requeueBackoffMax, now)
+		if m.quarantineThreshold > 0 && w.Status.RequeueState.Count >= m.quarantineThreshold {
+			quarantine(&w, m.quarantineInterval, now)
+			reason = RequeueReasonQuarantined
+		}
+	}
+	backoff := failed && w.Status.RequeueState != nil && w.Status.RequeueState.RequeueAt != nil
 	info.Update(&w)
+	if m.inheritance != nil {
+		m.inheritance.add(&w)
+	}
 	q.AddOrUpdate(info)
-	cq := m.clusterQueues[q.ClusterQueue]
+	cqName := q.clusterQueueFor(&w)
+	cq := m.clusterQueues[cqName]
 	if cq == nil {
 		return false
 	}
 
 	added := cq.RequeueIfNotPresent(info, reason)
-	m.reportPendingWorkloads(q.ClusterQueue, cq)
+	m.reportPendingWorkloads(cqName, cq)
+	metrics.InternalQueueRetry(metrics.InternalQueueScheduler)
 	if added {
 		m.Broadcast()
 	}
+	if backoff {
+		m.queueAfterBackoff(cqName, w.Status.RequeueState.RequeueAt.Time)
+	}
 	return added
 }
 
+// queueAfterBackoff queues the inadmissible workloads of the ClusterQueue and
+// wakes up the routines waiting for heads once the given time is reached, when
+// the backoff of a requeued workload expires.
+func (m *Manager) queueAfterBackoff(cqName string, at time.Time) {
+	time.AfterFunc(time.Until(at), func() {
+		m.Lock()
+		defer m.Unlock()
+		if cq := m.clusterQueues[cqName]; cq != nil {
+			cq.QueueInadmissibleWorkloads(context.Background(), m.client)
+			m.reportPendingWorkloads(cqName, cq)
+		}
+		m.Broadcast()
+	})
+}
+
 func (m *Manager) DeleteWorkload(w *kueue.Workload) {
 	m.Lock()
 	m.deleteWorkloadFromQueueAndClusterQueue(w, workload.QueueKey(w))
@@ -329,15 +556,19 @@ func (m *Manager) DeleteWorkload(w *kueue.Workload) {
 }
 
 func (m *Manager) deleteWorkloadFromQueueAndClusterQueue(w *kueue.Workload, qKey string) {
+	if m.inheritance != nil {
+		m.inheritance.delete(w)
+	}
 	q := m.localQueues[qKey]
 	if q == nil {
 		return
 	}
 	delete(q.items, workload.Key(w))
-	cq := m.clusterQueues[q.ClusterQueue]
+	cqName := q.clusterQueueFor(w)
+	cq := m.clusterQueues[cqName]
 	if cq != nil {
 		cq.Delete(w)
-		m.reportPendingWorkloads(q.ClusterQueue, cq)
+		m.reportPendingWorkloads(cqName, cq)
 	}
 }
 
@@ -353,7 +584,7 @@ func (m *Manager) QueueAssociatedInadmissibleWorkloads(ctx context.Context, w *k
 		return
 	}
 
-	cq := m.clusterQueues[q.ClusterQueue]
+	cq := m.clusterQueues[q.clusterQueueFor(w)]
 	if cq == nil {
 		return
 	}
@@ -389,8 +620,34 @@ func (m *Manager) QueueInadmissibleWorkloads(ctx context.Context, cqNames sets.S
 	}
 }
 
+// QueueInadmissibleWorkloadsInNamespace moves the inadmissible workloads of
+// the namespace in the given ClusterQueues to their heaps. It's used when the
+// labels of the namespace change to match the namespaceSelector of the
+// ClusterQueues: no quota was released, so the workloads of other namespaces
+// and of the rest of the cohorts stay inadmissible.
+func (m *Manager) QueueInadmissibleWorkloadsInNamespace(ctx context.Context, cqNames sets.String, namespace string) {
+	m.Lock()
+	defer m.Unlock()
+
+	var queued bool
+	for name := range cqNames {
+		cq, exists := m.clusterQueues[name]
+		if !exists {
+			continue
+		}
+		if cq.QueueInadmissibleWorkloadsInNamespace(ctx, m.client, namespace) {
+			m.reportPendingWorkloads(name, cq)
+			queued = true
+		}
+	}
+
+	if queued {
+		m.Broadcast()
+	}
+}
+
 // queueAllInadmissibleWorkloadsInCohort moves all workloads in the same
-// cohort with this ClusterQueue from inadmissibleWorkloads to heap. If the
+// tree of cohorts with this ClusterQueue from inadmissibleWorkloads to heap. If the
 // cohort of this ClusterQueue is empty, it just moves all workloads in this
 // ClusterQueue. If at least one workload is moved, returns true. Otherwise
 // returns false.
@@ -404,23 +661,55 @@ func (m *Manager) queueAllInadmissibleWorkloadsInCohort(ctx context.Context, cq
 	if cohort == "" {
 		return cq.QueueInadmissibleWorkloads(ctx, m.client)
 	}
+	return m.queueAllInadmissibleWorkloadsInCohortTree(ctx, cohort)
+}
 
+// queueAllInadmissibleWorkloadsInCohortTree moves all workloads of the
+// ClusterQueues in the tree of the cohort from inadmissibleWorkloads to heap.
+// If at least one workload is moved, returns true. Otherwise returns false.
+func (m *Manager) queueAllInadmissibleWorkloadsInCohortTree(ctx context.Context, cohort string) bool {
 	queued := false
-	for cqName := range m.cohorts[cohort] {
-		if clusterQueue, ok := m.clusterQueues[cqName]; ok {
-			queued = clusterQueue.QueueInadmissibleWorkloads(ctx, m.client) || queued
+	root := m.rootCohort(cohort)
+	for name, cqNames := range m.cohorts {
+		if m.rootCohort(name) != root {
+			continue
+		}
+		for cqName := range cqNames {
+			if clusterQueue, ok := m.clusterQueues[cqName]; ok {
+				queued = clusterQueue.QueueInadmissibleWorkloads(ctx, m.client) || queued
+			}
 		}
 	}
 	return queued
 }
 
+// rootCohort returns the root of the tree of the cohort. If the parents form
+// a cycle, the cohort with the lowest name in the cycle is the root.
+func (m *Manager) rootCohort(name string) string {
+	visited := sets.NewString(name)
+	for parent := m.cohortParents[name]; parent != ""; parent = m.cohortParents[name] {
+		if visited.Has(parent) {
+			root := parent
+			for c := m.cohortParents[parent]; c != parent; c = m.cohortParents[c] {
+				if c < root {
+					root = c
+				}
+			}
+			return root
+		}
+		visited.Insert(parent)
+		name = parent
+	}
+	return name
+}
+
 // UpdateWorkload updates the workload to the corresponding queue or adds it if
 // it didn't exist. Returns whether the queue existed.
 func (m *Manager) UpdateWorkload(oldW, w *kueue.Workload) bool {
 	m.Lock()
 	defer m.Unlock()
-	if oldW.Spec.QueueName != w.Spec.QueueName {
-		m.deleteWorkloadFromQueueAndClusterQueue(w, workload.QueueKey(oldW))
+	if oldW.Spec.QueueName != w.Spec.QueueName || oldW.Spec.PriorityClassName != w.Spec.PriorityClassName {
+		m.deleteWorkloadFromQueueAndClusterQueue(oldW, workload.QueueKey(oldW))
 	}
 	return m.addOrUpdateWorkload(w)
 }
@@ -442,6 +731,7 @@ func (m *Manager) Heads(ctx context.Context) []workload.Info {
 	for {
 		workloads := m.heads()
 		log.V(3).Info("Obtained ClusterQueue heads", "count", len(workloads))
+		m.reportSchedulerQueueDepth()
 		if len(workloads) != 0 {
 			return workloads
 		}
@@ -454,6 +744,53 @@ func (m *Manager) Heads(ctx context.Context) []workload.Info {
 	}
 }
 
+// PopAdmissionGroup removes the pending workloads of the admission group of
+// the head, which was already popped from its ClusterQueue, and returns them.
+func (m *Manager) PopAdmissionGroup(head *workload.Info) []workload.Info {
+	group, _, ok := workload.AdmissionGroup(head.Obj)
+	if !ok {
+		return nil
+	}
+	m.Lock()
+	defer m.Unlock()
+	cq := m.clusterQueues[head.ClusterQueue]
+	if cq == nil {
+		return nil
+	}
+	infos := cq.PopAdmissionGroup(group)
+	if len(infos) == 0 {
+		return nil
+	}
+	members := make([]workload.Info, len(infos))
+	for i, info := range infos {
+		members[i] = *info
+		members[i].ClusterQueue = head.ClusterQueue
+		if q := m.localQueues[workload.QueueKey(info.Obj)]; q != nil {
+			delete(q.items, workload.Key(info.Obj))
+		}
+	}
+	m.reportPendingWorkloads(head.ClusterQueue, cq)
+	return members
+}
+
+// SortedPendingWorkloads returns the workloads pending in the heap of the
+// ClusterQueue, in the order in which they would be returned as heads.
+func (m *Manager) SortedPendingWorkloads(cqName string) []workload.Info {
+	m.RLock()
+	defer m.RUnlock()
+	cq := m.clusterQueues[cqName]
+	if cq == nil {
+		return nil
+	}
+	infos := cq.Sorted()
+	workloads := make([]workload.Info, len(infos))
+	for i, info := range infos {
+		workloads[i] = *info
+		workloads[i].ClusterQueue = cqName
+	}
+	return workloads
+}
+
 // Dump is a dump of the queues and it's elements (unordered).
 // Only use for testing purposes.
 func (m *Manager) Dump() map[string]sets.String {
@@ -501,6 +838,15 @@ func (m *Manager) heads() []workload.Info {
 		if m.statusChecker != nil && !m.statusChecker.ClusterQueueActive(cqName) {
 			continue
 		}
+		if m.reorderHeads {
+			cq.Reorder()
+		}
+		if m.statusChecker != nil && cq.LocalQueueFairness() == kueue.LocalQueueUsage {
+			cq.SetLocalQueueUsage(m.statusChecker.AdmittedWorkloadsPerLocalQueue(cqName))
+		}
+		if m.statusChecker != nil && cq.HasPriorityBands() {
+			cq.SetPriorityBandShares(m.statusChecker.PriorityBandShares(cqName))
+		}
 		wl := cq.Pop()
 		if wl == nil {
 			continue
@@ -543,14 +889,31 @@ func (m *Manager) Broadcast() {
 	m.cond.Broadcast()
 }
 
+// reportSchedulerQueueDepth reports the number of workloads left in the
+// ClusterQueues for the next scheduling cycles.
+func (m *Manager) reportSchedulerQueueDepth() {
+	depth := 0
+	for _, cq := range m.clusterQueues {
+		depth += cq.PendingActive()
+	}
+	metrics.ReportInternalQueueDepth(metrics.InternalQueueScheduler, depth)
+}
+
 func (m *Manager) reportPendingWorkloads(cqName string, cq ClusterQueue) {
 	active := cq.PendingActive()
 	inadmissible := cq.PendingInadmissible()
+	activeByClass, inadmissibleByClass := cq.PendingByPriorityClass()
 	if m.statusChecker != nil && !m.statusChecker.ClusterQueueActive(cqName) {
 		inadmissible += active
 		active = 0
+		for class, n := range activeByClass {
+			inadmissibleByClass[class] += n
+		}
+		activeByClass = nil
 	}
 	metrics.ReportPendingWorkloads(cqName, active, inadmissible)
+	metrics.ReportPendingWorkloadsByPriorityClass(cqName, activeByClass, inadmissibleByClass)
+	metrics.ReportQuarantinedWorkloads(cqName, cq.PendingQuarantined())
 }
 
 func SetupIndexes(indexer client.FieldIndexer) error {
@@ -563,7 +926,7 @@ func SetupIndexes(indexer client.FieldIndexer) error {
 	}
 	err = indexer.IndexField(context.Background(), &kueue.LocalQueue{}, queueClusterQueueKey, func(o client.Object) []string {
 		q := o.(*kueue.LocalQueue)
-		return []string{string(q.Spec.ClusterQueue)}
+		return ClusterQueuesOf(q)
 	})
 	if err != nil {
 		return fmt.Errorf("setting index on clusterQueue for Queue: %w", err)
@@ -28,6 +28,7 @@ import (
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/utils/pointer"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/client/fake"
 
@@ -187,6 +188,104 @@ func TestUpdateClusterQueue(t *testing.T) {
 	}
 }
 
+// TestUpdateClusterQueueCohortMove tests that the inadmissible workloads of
+// both the old and the new cohort are requeued when a ClusterQueue moves.
+func TestUpdateClusterQueueCohortMove(t *testing.T) {
+	clusterQueues := []*kueue.ClusterQueue{
+		utiltesting.MakeClusterQueue("cq1").Cohort("alpha").Obj(),
+		utiltesting.MakeClusterQueue("cq2").Cohort("alpha").Obj(),
+		utiltesting.MakeClusterQueue("cq3").Cohort("beta").Obj(),
+	}
+	queues := []*kueue.LocalQueue{
+		utiltesting.MakeLocalQueue("foo", defaultNamespace).ClusterQueue("cq1").Obj(),
+		utiltesting.MakeLocalQueue("bar", defaultNamespace).ClusterQueue("cq2").Obj(),
+		utiltesting.MakeLocalQueue("baz", defaultNamespace).ClusterQueue("cq3").Obj(),
+	}
+	workloads := []*kueue.Workload{
+		utiltesting.MakeWorkload("a", defaultNamespace).Queue("foo").Obj(),
+		utiltesting.MakeWorkload("b", defaultNamespace).Queue("bar").Obj(),
+		utiltesting.MakeWorkload("c", defaultNamespace).Queue("baz").Obj(),
+	}
+	scheme := utiltesting.MustGetScheme(t)
+	ctx := context.Background()
+	cl := fake.NewClientBuilder().WithScheme(scheme).WithObjects(
+		&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: defaultNamespace}},
+	).Build()
+	manager := NewManager(cl, nil)
+	for _, cq := range clusterQueues {
+		if err := manager.AddClusterQueue(ctx, cq); err != nil {
+			t.Fatalf("Failed adding clusterQueue %s: %v", cq.Name, err)
+		}
+	}
+	for _, q := range queues {
+		if err := manager.AddLocalQueue(ctx, q); err != nil {
+			t.Fatalf("Failed adding queue %s: %v", q.Name, err)
+		}
+	}
+	for _, w := range workloads {
+		if err := cl.Create(ctx, w); err != nil {
+			t.Fatalf("Failed adding workload to client: %v", err)
+		}
+		manager.RequeueWorkload(ctx, workload.NewInfo(w), RequeueReasonGeneric)
+	}
+
+	// Move cq2 from alpha to beta.
+	clusterQueues[1].Spec.Cohort = "beta"
+	if err := manager.UpdateClusterQueue(ctx, clusterQueues[1]); err != nil {
+		t.Fatalf("Failed to update ClusterQueue: %v", err)
+	}
+
+	wantCohorts := map[string]sets.String{
+		"alpha": sets.NewString("cq1"),
+		"beta":  sets.NewString("cq2", "cq3"),
+	}
+	if diff := cmp.Diff(wantCohorts, manager.cohorts); diff != "" {
+		t.Errorf("Unexpected ClusterQueues in cohorts (-want,+got):\n%s", diff)
+	}
+	wantActiveWorkloads := map[string]sets.String{
+		"cq1": sets.NewString("default/a"),
+		"cq2": sets.NewString("default/b"),
+		"cq3": sets.NewString("default/c"),
+	}
+	if diff := cmp.Diff(wantActiveWorkloads, manager.Dump()); diff != "" {
+		t.Errorf("Unexpected active workloads (-want +got):\n%s", diff)
+	}
+}
+
+func TestRootCohort(t *testing.T) {
+	manager := NewManager(fake.NewClientBuilder().WithScheme(utiltesting.MustGetScheme(t)).Build(), nil)
+	for _, c := range []*kueue.Cohort{
+		utiltesting.MakeCohort("left").Parent("root").Obj(),
+		utiltesting.MakeCohort("leaf").Parent("left").Obj(),
+		utiltesting.MakeCohort("loop-a").Parent("loop-c").Obj(),
+		utiltesting.MakeCohort("loop-b").Parent("loop-a").Obj(),
+		utiltesting.MakeCohort("loop-c").Parent("loop-b").Obj(),
+		utiltesting.MakeCohort("into-loop").Parent("loop-c").Obj(),
+	} {
+		manager.AddOrUpdateCohort(c)
+	}
+	cases := map[string]string{
+		"root":      "root",
+		"left":      "root",
+		"leaf":      "root",
+		"other":     "other",
+		"loop-a":    "loop-a",
+		"loop-b":    "loop-a",
+		"loop-c":    "loop-a",
+		"into-loop": "loop-a",
+	}
+	for cohort, want := range cases {
+		if got := manager.rootCohort(cohort); got != want {
+			t.Errorf("rootCohort(%q) = %q, want %q", cohort, got, want)
+		}
+	}
+
+	manager.DeleteCohort("left")
+	if got := manager.rootCohort("leaf"); got != "left" {
+		t.Errorf("rootCohort(\"leaf\") after deleting the Cohort left = %q, want \"left\"", got)
+	}
+}
+
 // TestUpdateLocalQueue tests that workloads are transferred between clusterQueues
 // when the queue points to a different clusterQueue.
 func TestUpdateLocalQueue(t *testing.T) {
@@ -540,6 +639,254 @@ func TestRequeueWorkloadStrictFIFO(t *testing.T) {
 	}
 }
 
+func TestRequeueWorkloadBackoff(t *testing.T) {
+	scheme := utiltesting.MustGetScheme(t)
+	now := time.Now().Truncate(time.Second)
+	cases := map[string]struct {
+		strategy kueue.QueueingStrategy
+		// wantHeadsInBackoff are the heads obtained while the requeued workload
+		// is in backoff.
+		wantHeadsInBackoff sets.String
+	}{
+		"StrictFIFO blocks behind the workload in backoff": {
+			strategy:           kueue.StrictFIFO,
+			wantHeadsInBackoff: sets.NewString(),
+		},
+		"BestEffortFIFO admits other workloads during the backoff": {
+			strategy:           kueue.BestEffortFIFO,
+			wantHeadsInBackoff: sets.NewString("b"),
+		},
+	}
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			ctx, cancel := context.WithTimeout(context.Background(), headsTimeout)
+			defer cancel()
+			cl := fake.NewClientBuilder().WithScheme(scheme).WithObjects(
+				&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: defaultNamespace}},
+			).Build()
+			manager := NewManager(cl, nil, WithRequeueBackoff(500*time.Millisecond, time.Second))
+			go manager.CleanUpOnContext(ctx)
+			if err := manager.AddClusterQueue(ctx, utiltesting.MakeClusterQueue("cq").QueueingStrategy(tc.strategy).Obj()); err != nil {
+				t.Fatalf("Failed adding clusterQueue: %v", err)
+			}
+			if err := manager.AddLocalQueue(ctx, utiltesting.MakeLocalQueue("foo", defaultNamespace).ClusterQueue("cq").Obj()); err != nil {
+				t.Fatalf("Failed adding queue: %v", err)
+			}
+			for _, wl := range []*kueue.Workload{
+				utiltesting.MakeWorkload("a", defaultNamespace).Creation(now).Queue("foo").Obj(),
+				utiltesting.MakeWorkload("b", defaultNamespace).Creation(now.Add(time.Hour)).Queue("foo").Obj(),
+			} {
+				if err := cl.Create(ctx, wl); err != nil {
+					t.Fatalf("Failed adding workload to client: %v", err)
+				}
+				manager.AddOrUpdateWorkload(wl)
+			}
+
+			heads := manager.Heads(ctx)
+			if len(heads) != 1 || heads[0].Obj.Name != "a" {
+				t.Fatalf("Got heads %v, want workload a", heads)
+			}
+			info := heads[0]
+			manager.RequeueWorkload(ctx, &info, RequeueReasonFailedAfterNomination)
+			if state := info.Obj.Status.RequeueState; state == nil || state.Count != 1 {
+				t.Errorf("Got requeue state %v, want count 1", state)
+			}
+
+			backoffCtx, backoffCancel := context.WithTimeout(ctx, 100*time.Millisecond)
+			defer backoffCancel()
+			go manager.CleanUpOnContext(backoffCtx)
+			gotHeads := sets.NewString()
+			for _, h := range manager.Heads(backoffCtx) {
+				gotHeads.Insert(h.Obj.Name)
+			}
+			if diff := cmp.Diff(tc.wantHeadsInBackoff, gotHeads); diff != "" {
+				t.Errorf("Unexpected heads during the backoff (-want,+got):\n%s", diff)
+			}
+
+			heads = manager.Heads(ctx)
+			if len(heads) != 1 || heads[0].Obj.Name != "a" {
+				t.Errorf("Got heads %v after the backoff, want workload a", heads)
+			}
+		})
+	}
+}
+
+func TestRequeueWorkloadQuarantine(t *testing.T) {
+	scheme := utiltesting.MustGetScheme(t)
+	for _, strategy := range []kueue.QueueingStrategy{kueue.StrictFIFO, kueue.BestEffortFIFO} {
+		t.Run(string(strategy), func(t *testing.T) {
+			ctx, cancel := context.WithTimeout(context.Background(), headsTimeout)
+			defer cancel()
+			wl := utiltesting.MakeWorkload("a", defaultNamespace).Queue("foo").Obj()
+			cl := fake.NewClientBuilder().WithScheme(scheme).WithObjects(
+				&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: defaultNamespace}},
+				wl,
+			).Build()
+			manager := NewManager(cl, nil, WithQuarantine(2, time.Hour))
+			go manager.CleanUpOnContext(ctx)
+			if err := manager.AddClusterQueue(ctx, utiltesting.MakeClusterQueue("cq").QueueingStrategy(strategy).Obj()); err != nil {
+				t.Fatalf("Failed adding clusterQueue: %v", err)
+			}
+			if err := manager.AddLocalQueue(ctx, utiltesting.MakeLocalQueue("foo", defaultNamespace).ClusterQueue("cq").Obj()); err != nil {
+				t.Fatalf("Failed adding queue: %v", err)
+			}
+
+			for attempt := int32(1); attempt <= 2; attempt++ {
+				manager.QueueInadmissibleWorkloads(ctx, sets.NewString("cq"))
+				heads := manager.Heads(ctx)
+				if len(heads) != 1 || heads[0].Obj.Name != "a" {
+					t.Fatalf("Got heads %v in attempt %d, want workload a", heads, attempt)
+				}
+				info := heads[0]
+				manager.RequeueWorkload(ctx, &info, RequeueReasonGeneric)
+				state := info.Obj.Status.RequeueState
+				if state == nil || state.Count != attempt {
+					t.Fatalf("Got requeue state %v in attempt %d, want count %d", state, attempt, attempt)
+				}
+				wantQuarantined := attempt == 2
+				if got := workload.IsQuarantined(info.Obj); got != wantQuarantined {
+					t.Errorf("Got quarantined %t in attempt %d, want %t", got, attempt, wantQuarantined)
+				}
+				if wantQuarantined && (state.RequeueAt == nil || time.Until(state.RequeueAt.Time) < time.Hour-time.Minute) {
+					t.Errorf("Got requeue state %v, want a retry in about 1h", state)
+				} else if !wantQuarantined && state.RequeueAt != nil {
+					t.Errorf("Got requeue state %v, want no backoff", state)
+				}
+			}
+
+			if got := manager.clusterQueues["cq"].PendingQuarantined(); got != 1 {
+				t.Errorf("Got %d quarantined workloads, want 1", got)
+			}
+			manager.QueueInadmissibleWorkloads(ctx, sets.NewString("cq"))
+			quarantineCtx, quarantineCancel := context.WithTimeout(ctx, 100*time.Millisecond)
+			defer quarantineCancel()
+			go manager.CleanUpOnContext(quarantineCtx)
+			if heads := manager.Heads(quarantineCtx); len(heads) != 0 {
+				t.Errorf("Got heads %v, want none while the workload is quarantined", heads)
+			}
+		})
+	}
+}
+
+func TestQueueInadmissibleWorkloadsInNamespace(t *testing.T) {
+	scheme := utiltesting.MustGetScheme(t)
+	ctx := context.Background()
+	cl := fake.NewClientBuilder().WithScheme(scheme).WithObjects(
+		&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "ns1"}},
+		&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "ns2"}},
+	).Build()
+	manager := NewManager(cl, nil)
+	for _, cq := range []*kueue.ClusterQueue{
+		utiltesting.MakeClusterQueue("cq-a").Cohort("co").Obj(),
+		utiltesting.MakeClusterQueue("cq-b").Cohort("co").Obj(),
+	} {
+		if err := manager.AddClusterQueue(ctx, cq); err != nil {
+			t.Fatalf("Failed adding clusterQueue %s: %v", cq.Name, err)
+		}
+	}
+	for _, q := range []*kueue.LocalQueue{
+		utiltesting.MakeLocalQueue("a", "ns1").ClusterQueue("cq-a").Obj(),
+		utiltesting.MakeLocalQueue("a", "ns2").ClusterQueue("cq-a").Obj(),
+		utiltesting.MakeLocalQueue("b", "ns2").ClusterQueue("cq-b").Obj(),
+	} {
+		if err := manager.AddLocalQueue(ctx, q); err != nil {
+			t.Fatalf("Failed adding queue %s: %v", q.Name, err)
+		}
+	}
+	for _, wl := range []*kueue.Workload{
+		utiltesting.MakeWorkload("a1", "ns1").Queue("a").Obj(),
+		utiltesting.MakeWorkload("a2", "ns2").Queue("a").Obj(),
+		utiltesting.MakeWorkload("b2", "ns2").Queue("b").Obj(),
+	} {
+		manager.AddOrUpdateWorkload(wl)
+	}
+	// Make all the workloads inadmissible.
+	for _, cq := range manager.clusterQueues {
+		var popped []*workload.Info
+		for wl := cq.Pop(); wl != nil; wl = cq.Pop() {
+			popped = append(popped, wl)
+		}
+		for _, wl := range popped {
+			cq.RequeueIfNotPresent(wl, RequeueReasonGeneric)
+		}
+	}
+
+	manager.QueueInadmissibleWorkloadsInNamespace(ctx, sets.NewString("cq-a"), "ns2")
+
+	wantActive := map[string]sets.String{
+		"cq-a": sets.NewString("ns2/a2"),
+	}
+	if diff := cmp.Diff(wantActive, manager.Dump()); diff != "" {
+		t.Errorf("Unexpected active workloads (-want,+got):\n%s", diff)
+	}
+	wantInadmissible := map[string]sets.String{
+		"cq-a": sets.NewString("ns1/a1"),
+		"cq-b": sets.NewString("ns2/b2"),
+	}
+	if diff := cmp.Diff(wantInadmissible, manager.DumpInadmissible()); diff != "" {
+		t.Errorf("Unexpected inadmissible workloads (-want,+got):\n%s", diff)
+	}
+}
+
+func TestPriorityClassRouting(t *testing.T) {
+	scheme := utiltesting.MustGetScheme(t)
+	ctx := context.Background()
+	q := utiltesting.MakeLocalQueue("q", "ns").
+		ClusterQueue("on-demand").
+		RoutePriorityClass("low", "spot").
+		Obj()
+	cl := fake.NewClientBuilder().WithScheme(scheme).WithObjects(
+		q,
+		utiltesting.MakeWorkload("a", "ns").Queue("q").PriorityClass("high").Obj(),
+		utiltesting.MakeWorkload("b", "ns").Queue("q").PriorityClass("low").Obj(),
+	).Build()
+	manager := NewManager(cl, nil)
+	if err := manager.AddClusterQueue(ctx, utiltesting.MakeClusterQueue("on-demand").Obj()); err != nil {
+		t.Fatalf("Failed adding clusterQueue: %v", err)
+	}
+	if err := manager.AddLocalQueue(ctx, q); err != nil {
+		t.Fatalf("Failed adding queue: %v", err)
+	}
+	// The ClusterQueue of the route is added after the LocalQueue.
+	if err := manager.AddClusterQueue(ctx, utiltesting.MakeClusterQueue("spot").Obj()); err != nil {
+		t.Fatalf("Failed adding clusterQueue: %v", err)
+	}
+	c := utiltesting.MakeWorkload("c", "ns").Queue("q").PriorityClass("low").Obj()
+	manager.AddOrUpdateWorkload(c)
+
+	wantActive := map[string]sets.String{
+		"on-demand": sets.NewString("ns/a"),
+		"spot":      sets.NewString("ns/b", "ns/c"),
+	}
+	if diff := cmp.Diff(wantActive, manager.Dump()); diff != "" {
+		t.Errorf("Unexpected active workloads (-want,+got):\n%s", diff)
+	}
+	if cq, _ := manager.ClusterQueueForWorkload(c); cq != "spot" {
+		t.Errorf("Got ClusterQueue %q for the routed workload, want \"spot\"", cq)
+	}
+
+	// Changing the priority class moves the workload to the ClusterQueue of
+	// the new priority class.
+	newC := c.DeepCopy()
+	newC.Spec.PriorityClassName = "medium"
+	manager.UpdateWorkload(c, newC)
+	wantActive = map[string]sets.String{
+		"on-demand": sets.NewString("ns/a", "ns/c"),
+		"spot":      sets.NewString("ns/b"),
+	}
+	if diff := cmp.Diff(wantActive, manager.Dump()); diff != "" {
+		t.Errorf("Unexpected active workloads after the update (-want,+got):\n%s", diff)
+	}
+	if pending, _ := manager.PendingWorkloads(q); pending != 3 {
+		t.Errorf("Got %d pending workloads in the LocalQueue, want 3", pending)
+	}
+
+	manager.DeleteLocalQueue(q)
+	if dump := manager.Dump(); dump != nil {
+		t.Errorf("Got active workloads after deleting the LocalQueue: %v", dump)
+	}
+}
+
 func TestUpdateWorkload(t *testing.T) {
 	scheme := runtime.NewScheme()
 	if err := kueue.AddToScheme(scheme); err != nil {
@@ -737,15 +1084,22 @@ func TestHeads(t *testing.T) {
 		utiltesting.MakeClusterQueue("active-fooCq").Obj(),
 		utiltesting.MakeClusterQueue("active-barCq").Obj(),
 		utiltesting.MakeClusterQueue("pending-bazCq").Obj(),
+		utiltesting.MakeClusterQueue("active-usageCq").LocalQueueFairness(kueue.LocalQueueUsage).Obj(),
+		utiltesting.MakeClusterQueue("active-bandsCq").PriorityBand("high", 1000, 7).PriorityBand("low", 0, 3).Obj(),
 	}
 	queues := []*kueue.LocalQueue{
 		utiltesting.MakeLocalQueue("foo", "").ClusterQueue("active-fooCq").Obj(),
 		utiltesting.MakeLocalQueue("bar", "").ClusterQueue("active-barCq").Obj(),
 		utiltesting.MakeLocalQueue("baz", "").ClusterQueue("pending-bazCq").Obj(),
+		utiltesting.MakeLocalQueue("usage-a", "").ClusterQueue("active-usageCq").Obj(),
+		utiltesting.MakeLocalQueue("usage-b", "").ClusterQueue("active-usageCq").Obj(),
+		utiltesting.MakeLocalQueue("bands", "").ClusterQueue("active-bandsCq").Obj(),
 	}
 	tests := []struct {
 		name          string
 		workloads     []*kueue.Workload
+		admitted      map[string]int
+		shares        map[string]int64
 		wantWorkloads sets.String
 	}{
 		{
@@ -779,12 +1133,48 @@ func TestHeads(t *testing.T) {
 			},
 			wantWorkloads: sets.NewString("a", "b"),
 		},
+		{
+			name: "head from the LocalQueue with the lowest usage",
+			workloads: []*kueue.Workload{
+				utiltesting.MakeWorkload("usage-a1", "").Creation(now).Queue("usage-a").Obj(),
+				utiltesting.MakeWorkload("usage-b1", "").Creation(now.Add(time.Hour)).Queue("usage-b").Obj(),
+			},
+			admitted: map[string]int{
+				"/usage-a": 1,
+				"/usage-b": 0,
+			},
+			wantWorkloads: sets.NewString("usage-b1"),
+		},
+		{
+			name: "head from the priority band with the highest priority",
+			workloads: []*kueue.Workload{
+				utiltesting.MakeWorkload("bands-low", "").Creation(now).Queue("bands").Priority(pointer.Int32(0)).Obj(),
+				utiltesting.MakeWorkload("bands-high", "").Creation(now.Add(time.Hour)).Queue("bands").Priority(pointer.Int32(1000)).Obj(),
+			},
+			shares: map[string]int64{
+				"high": 500,
+				"low":  500,
+			},
+			wantWorkloads: sets.NewString("bands-high"),
+		},
+		{
+			name: "head from the priority band with the lowest share",
+			workloads: []*kueue.Workload{
+				utiltesting.MakeWorkload("bands-low", "").Creation(now).Queue("bands").Priority(pointer.Int32(0)).Obj(),
+				utiltesting.MakeWorkload("bands-high", "").Creation(now.Add(time.Hour)).Queue("bands").Priority(pointer.Int32(1000)).Obj(),
+			},
+			shares: map[string]int64{
+				"high": 1200,
+				"low":  0,
+			},
+			wantWorkloads: sets.NewString("bands-low"),
+		},
 	}
 	for _, tc := range tests {
 		t.Run(tc.name, func(t *testing.T) {
 			ctx, cancel := context.WithTimeout(context.Background(), headsTimeout)
 			defer cancel()
-			fakeC := &fakeStatusChecker{}
+			fakeC := &fakeStatusChecker{admitted: tc.admitted, shares: tc.shares}
 			manager := NewManager(fake.NewClientBuilder().WithScheme(scheme).Build(), fakeC)
 			for _, cq := range clusterQueues {
 				if err := manager.AddClusterQueue(ctx, cq); err != nil {
@@ -814,6 +1204,193 @@ func TestHeads(t *testing.T) {
 	}
 }
 
+func TestHeadsStrictCreationOrder(t *testing.T) {
+	scheme := runtime.NewScheme()
+	if err := kueue.AddToScheme(scheme); err != nil {
+		t.Fatalf("Failed adding kueue scheme: %s", err)
+	}
+	now := time.Now().Truncate(time.Second)
+	cases := map[string]struct {
+		opts     []Option
+		wantHead string
+	}{
+		"priority first": {
+			wantHead: "high",
+		},
+		"strict creation order": {
+			opts:     []Option{WithStrictCreationOrder(true)},
+			wantHead: "low",
+		},
+	}
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			ctx, cancel := context.WithTimeout(context.Background(), headsTimeout)
+			defer cancel()
+			manager := NewManager(fake.NewClientBuilder().WithScheme(scheme).Build(), &fakeStatusChecker{}, tc.opts...)
+			cq := utiltesting.MakeClusterQueue("active-cq").Obj()
+			if err := manager.AddClusterQueue(ctx, cq); err != nil {
+				t.Fatalf("Failed adding clusterQueue %s to manager: %v", cq.Name, err)
+			}
+			q := utiltesting.MakeLocalQueue("foo", "").ClusterQueue(cq.Name).Obj()
+			if err := manager.AddLocalQueue(ctx, q); err != nil {
+				t.Fatalf("Failed adding queue %s: %s", q.Name, err)
+			}
+			go manager.CleanUpOnContext(ctx)
+			manager.AddOrUpdateWorkload(utiltesting.MakeWorkload("low", "").Creation(now).Queue("foo").Priority(pointer.Int32(0)).Obj())
+			manager.AddOrUpdateWorkload(utiltesting.MakeWorkload("high", "").Creation(now.Add(time.Hour)).Queue("foo").Priority(pointer.Int32(1000)).Obj())
+
+			heads := manager.Heads(ctx)
+			if len(heads) != 1 || heads[0].Obj.Name != tc.wantHead {
+				var got []string
+				for _, h := range heads {
+					got = append(got, h.Obj.Name)
+				}
+				t.Errorf("Got heads %v, want %s", got, tc.wantHead)
+			}
+		})
+	}
+}
+
+func TestSortedPendingWorkloads(t *testing.T) {
+	scheme := runtime.NewScheme()
+	if err := kueue.AddToScheme(scheme); err != nil {
+		t.Fatalf("Failed adding kueue scheme: %s", err)
+	}
+	ctx := context.Background()
+	now := time.Now().Truncate(time.Second)
+	manager := NewManager(fake.NewClientBuilder().WithScheme(scheme).Build(), nil)
+	cq := utiltesting.MakeClusterQueue("cq").QueueingStrategy(kueue.StrictFIFO).Obj()
+	if err := manager.AddClusterQueue(ctx, cq); err != nil {
+		t.Fatalf("Failed adding clusterQueue %s to manager: %v", cq.Name, err)
+	}
+	q := utiltesting.MakeLocalQueue("foo", "").ClusterQueue("cq").Obj()
+	if err := manager.AddLocalQueue(ctx, q); err != nil {
+		t.Fatalf("Failed adding queue %s: %s", q.Name, err)
+	}
+	workloads := []*kueue.Workload{
+		utiltesting.MakeWorkload("b", "").Creation(now.Add(time.Second)).Queue("foo").Obj(),
+		utiltesting.MakeWorkload("c", "").Creation(now.Add(2 * time.Second)).Queue("foo").Priority(pointer.Int32(1)).Obj(),
+		utiltesting.MakeWorkload("a", "").Creation(now).Queue("foo").Obj(),
+	}
+	for _, wl := range workloads {
+		manager.AddOrUpdateWorkload(wl)
+	}
+
+	var got []string
+	for _, info := range manager.SortedPendingWorkloads("cq") {
+		if info.ClusterQueue != "cq" {
+			t.Errorf("Workload %s has ClusterQueue %q, want %q", info.Obj.Name, info.ClusterQueue, "cq")
+		}
+		got = append(got, info.Obj.Name)
+	}
+	if diff := cmp.Diff([]string{"c", "a", "b"}, got); diff != "" {
+		t.Errorf("Unexpected order of workloads (-want,+got):\n%s", diff)
+	}
+	if got := manager.SortedPendingWorkloads("other"); got != nil {
+		t.Errorf("SortedPendingWorkloads returned %v for a missing ClusterQueue, want nil", got)
+	}
+}
+
+func TestPopAdmissionGroup(t *testing.T) {
+	scheme := runtime.NewScheme()
+	if err := kueue.AddToScheme(scheme); err != nil {
+		t.Fatalf("Failed adding kueue scheme: %s", err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), headsTimeout)
+	defer cancel()
+	now := time.Now().Truncate(time.Second)
+	manager := NewManager(fake.NewClientBuilder().WithScheme(scheme).Build(), nil)
+	cq := utiltesting.MakeClusterQueue("cq").Obj()
+	if err := manager.AddClusterQueue(ctx, cq); err != nil {
+		t.Fatalf("Failed adding clusterQueue %s to manager: %v", cq.Name, err)
+	}
+	q := utiltesting.MakeLocalQueue("foo", "").ClusterQueue("cq").Obj()
+	if err := manager.AddLocalQueue(ctx, q); err != nil {
+		t.Fatalf("Failed adding queue %s: %s", q.Name, err)
+	}
+	workloads := []*kueue.Workload{
+		utiltesting.MakeWorkload("a", "").Creation(now).Queue("foo").AdmissionGroup("group", 3).Obj(),
+		utiltesting.MakeWorkload("b", "").Creation(now.Add(time.Second)).Queue("foo").AdmissionGroup("group", 3).Obj(),
+		utiltesting.MakeWorkload("c", "").Creation(now.Add(time.Second)).Queue("foo").Obj(),
+		utiltesting.MakeWorkload("d", "").Creation(now.Add(time.Second)).Queue("foo").AdmissionGroup("other", 2).Obj(),
+		utiltesting.MakeWorkload("e", "").Creation(now.Add(time.Second)).Queue("foo").AdmissionGroup("group", 3).Obj(),
+	}
+	for _, wl := range workloads {
+		manager.AddOrUpdateWorkload(wl)
+	}
+
+	heads := manager.Heads(ctx)
+	if len(heads) != 1 || heads[0].Obj.Name != "a" {
+		t.Fatalf("Heads returned %d workloads, want workload a", len(heads))
+	}
+	var got []string
+	for _, info := range manager.PopAdmissionGroup(&heads[0]) {
+		if info.ClusterQueue != "cq" {
+			t.Errorf("Workload %s has ClusterQueue %q, want %q", info.Obj.Name, info.ClusterQueue, "cq")
+		}
+		got = append(got, info.Obj.Name)
+	}
+	if diff := cmp.Diff([]string{"b", "e"}, got); diff != "" {
+		t.Errorf("Unexpected members of the admission group (-want,+got):\n%s", diff)
+	}
+	wantDump := map[string]sets.String{"cq": sets.NewString("/c", "/d")}
+	if diff := cmp.Diff(wantDump, manager.Dump()); diff != "" {
+		t.Errorf("Unexpected pending workloads (-want,+got):\n%s", diff)
+	}
+	if pending, err := manager.PendingWorkloads(q); err != nil || pending != 2 {
+		t.Errorf("PendingWorkloads(_) = %d, %v, want 2", pending, err)
+	}
+	if got := manager.PopAdmissionGroup(&workload.Info{Obj: workloads[2], ClusterQueue: "cq"}); got != nil {
+		t.Errorf("PopAdmissionGroup returned %v for a workload without group, want nil", got)
+	}
+}
+
+func TestPriorityInheritance(t *testing.T) {
+	scheme := runtime.NewScheme()
+	if err := kueue.AddToScheme(scheme); err != nil {
+		t.Fatalf("Failed adding kueue scheme: %s", err)
+	}
+	ctx := context.Background()
+	now := time.Now().Truncate(time.Second)
+	manager := NewManager(fake.NewClientBuilder().WithScheme(scheme).Build(), nil, WithPriorityInheritance(true))
+	cq := utiltesting.MakeClusterQueue("cq").QueueingStrategy(kueue.StrictFIFO).Obj()
+	if err := manager.AddClusterQueue(ctx, cq); err != nil {
+		t.Fatalf("Failed adding clusterQueue %s to manager: %v", cq.Name, err)
+	}
+	q := utiltesting.MakeLocalQueue("foo", "").ClusterQueue("cq").Obj()
+	if err := manager.AddLocalQueue(ctx, q); err != nil {
+		t.Fatalf("Failed adding queue %s: %s", q.Name, err)
+	}
+	workloads := []*kueue.Workload{
+		utiltesting.MakeWorkload("a", "").Creation(now).Queue("foo").Priority(pointer.Int32(5)).Obj(),
+		utiltesting.MakeWorkload("b", "").Creation(now.Add(time.Second)).Queue("foo").AdmissionGroup("group", 2).Obj(),
+		utiltesting.MakeWorkload("c", "").Creation(now.Add(2*time.Second)).Queue("foo").AdmissionGroup("group", 2).Priority(pointer.Int32(10)).Obj(),
+		utiltesting.MakeWorkload("d", "").Creation(now.Add(3 * time.Second)).Queue("foo").DependsOn("e").Obj(),
+		utiltesting.MakeWorkload("e", "").Creation(now.Add(4 * time.Second)).Queue("foo").Priority(pointer.Int32(8)).Obj(),
+	}
+	for _, wl := range workloads {
+		manager.AddOrUpdateWorkload(wl)
+	}
+	sortedNames := func() []string {
+		var names []string
+		for _, info := range manager.SortedPendingWorkloads("cq") {
+			names = append(names, info.Obj.Name)
+		}
+		return names
+	}
+	if diff := cmp.Diff([]string{"b", "c", "d", "e", "a"}, sortedNames()); diff != "" {
+		t.Errorf("Unexpected order of workloads (-want,+got):\n%s", diff)
+	}
+
+	// Without the workloads they inherit the priority from, b and d fall back
+	// to their own priority.
+	manager.DeleteWorkload(workloads[2])
+	manager.DeleteWorkload(workloads[4])
+	if diff := cmp.Diff([]string{"a", "b", "d"}, sortedNames()); diff != "" {
+		t.Errorf("Unexpected order of workloads after deletion (-want,+got):\n%s", diff)
+	}
+}
+
 var ignoreTypeMeta = cmpopts.IgnoreTypes(metav1.TypeMeta{})
 
 // TestHeadAsync ensures that Heads call is blocked until the queues are filled
@@ -1066,8 +1643,19 @@ func workloadNamesFromLQ(q *LocalQueue) sets.String {
 	return names
 }
 
-type fakeStatusChecker struct{}
+type fakeStatusChecker struct {
+	admitted map[string]int
+	shares   map[string]int64
+}
 
 func (c *fakeStatusChecker) ClusterQueueActive(name string) bool {
 	return strings.Contains(name, "active-")
 }
+
+func (c *fakeStatusChecker) AdmittedWorkloadsPerLocalQueue(string) map[string]int {
+	return c.admitted
+}
+
+func (c *fakeStatusChecker) PriorityBandShares(string) map[string]int64 {
+	return c.shares
+}
@@ -0,0 +1,92 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package queue
+
+import (
+	"k8s.io/apimachinery/pkg/util/sets"
+
+	kueue "sigs.k8s.io/kueue/apis/kueue/v1alpha2"
+	"sigs.k8s.io/kueue/pkg/constants"
+	utilpriority "sigs.k8s.io/kueue/pkg/util/priority"
+	"sigs.k8s.io/kueue/pkg/workload"
+)
+
+// priorityInheritance tracks the priorities of the pending workloads, so that
+// the members of an admission group and the workloads that depend on another
+// one are ordered with the highest priority among them.
+// It's not thread-safe; the Manager accesses it under its lock.
+type priorityInheritance struct {
+	// priorities holds the priority of the pending workloads, keyed by
+	// workload.
+	priorities map[string]int32
+	// groups holds the keys of the pending workloads of each admission group.
+	groups map[string]sets.String
+}
+
+func newPriorityInheritance() *priorityInheritance {
+	return &priorityInheritance{
+		priorities: make(map[string]int32),
+		groups:     make(map[string]sets.String),
+	}
+}
+
+func (p *priorityInheritance) add(wl *kueue.Workload) {
+	key := workload.Key(wl)
+	p.priorities[key] = utilpriority.Priority(wl)
+	if group, _, ok := workload.AdmissionGroup(wl); ok {
+		if p.groups[group] == nil {
+			p.groups[group] = sets.NewString()
+		}
+		p.groups[group].Insert(key)
+	}
+}
+
+func (p *priorityInheritance) delete(wl *kueue.Workload) {
+	key := workload.Key(wl)
+	delete(p.priorities, key)
+	if group, _, ok := workload.AdmissionGroup(wl); ok {
+		if members := p.groups[group]; members != nil {
+			members.Delete(key)
+			if members.Len() == 0 {
+				delete(p.groups, group)
+			}
+		}
+	}
+}
+
+// priority returns a function that computes the effective priority of a
+// pending workload: the highest among the one given by base, the priorities
+// of the other pending workloads of its admission group and the priority of
+// the pending workload it depends on.
+func (p *priorityInheritance) priority(base func(*kueue.Workload) int32) func(*kueue.Workload) int32 {
+	return func(wl *kueue.Workload) int32 {
+		prio := base(wl)
+		if group, _, ok := workload.AdmissionGroup(wl); ok {
+			for key := range p.groups[group] {
+				if other := p.priorities[key]; other > prio {
+					prio = other
+				}
+			}
+		}
+		if name := wl.Annotations[constants.DependsOnAnnotation]; name != "" {
+			if other, ok := p.priorities[wl.Namespace+"/"+name]; ok && other > prio {
+				prio = other
+			}
+		}
+		return prio
+	}
+}
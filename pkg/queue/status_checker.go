@@ -20,4 +20,10 @@ package queue
 type StatusChecker interface {
 	// ClusterQueueActive returns whether the clusterQueue is active.
 	ClusterQueueActive(name string) bool
+	// AdmittedWorkloadsPerLocalQueue returns the number of admitted workloads
+	// of each LocalQueue of the clusterQueue, keyed by namespace/name.
+	AdmittedWorkloadsPerLocalQueue(name string) map[string]int
+	// PriorityBandShares returns the usage of each priority band of the
+	// clusterQueue relative to its guaranteed quota.
+	PriorityBandShares(name string) map[string]int64
 }
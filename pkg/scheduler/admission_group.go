@@ -0,0 +1,183 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package scheduler
+
+import (
+	"context"
+	"fmt"
+	"sync/atomic"
+	"time"
+
+	"github.com/go-logr/logr"
+	"k8s.io/klog/v2"
+	ctrl "sigs.k8s.io/controller-runtime"
+
+	kueue "sigs.k8s.io/kueue/apis/kueue/v1alpha2"
+	"sigs.k8s.io/kueue/pkg/cache"
+	"sigs.k8s.io/kueue/pkg/constants"
+	"sigs.k8s.io/kueue/pkg/metrics"
+	"sigs.k8s.io/kueue/pkg/scheduler/flavorassigner"
+	"sigs.k8s.io/kueue/pkg/util/api"
+	"sigs.k8s.io/kueue/pkg/workload"
+)
+
+// nominateGroup takes the other pending workloads of the admission group of
+// the head in the entry and assigns flavors to all the pod sets of the group,
+// as if they were a single workload. The group is only nominated when all its
+// workloads are pending.
+func (s *Scheduler) nominateGroup(ctx context.Context, log logr.Logger, e *entry, size int, resourceFlavors map[string]*kueue.ResourceFlavor, cq *cache.ClusterQueue) {
+	e.groupMembers = s.queues.PopAdmissionGroup(&e.Info)
+	if missing := size - 1 - len(e.groupMembers); missing > 0 {
+		e.inadmissibleMsg = fmt.Sprintf("Waiting for %d more workload(s) of admission group %s", missing, e.Obj.Labels[constants.AdmissionGroupLabel])
+		return
+	}
+	for i := range e.groupMembers {
+		m := &e.groupMembers[i]
+		if rName, exceeded := cq.LocalQueueLimitExceeded(m); exceeded {
+			e.inadmissibleMsg = fmt.Sprintf("Workload %s of the admission group exceeds the %s limit of LocalQueue %s", m.Obj.Name, rName, m.Obj.Spec.QueueName)
+			return
+		}
+	}
+	info := groupInfo(e)
+	e.assignment = flavorassigner.AssignFlavorsWithScorer(log, info, resourceFlavors, cq, s.framework.FlavorScorer(ctx, info))
+	e.inadmissibleMsg = api.TruncateEventMessage(e.assignment.Message())
+	if s.fairSharing {
+		e.dominantResourceShare = cq.FairShare()
+	}
+}
+
+// groupInfo returns a workload.Info that holds the pod sets of all the
+// workloads of the admission group in the entry, head first. The pod sets are
+// named after their workloads, so that they are distinguishable in messages.
+func groupInfo(e *entry) *workload.Info {
+	info := &workload.Info{
+		Obj:          e.Obj.DeepCopy(),
+		ClusterQueue: e.ClusterQueue,
+	}
+	info.Obj.Spec.PodSets = nil
+	for _, w := range e.groupWorkloads() {
+		for _, ps := range w.Obj.Spec.PodSets {
+			ps.Name = w.Obj.Name + "/" + ps.Name
+			info.Obj.Spec.PodSets = append(info.Obj.Spec.PodSets, ps)
+		}
+		for _, psr := range w.TotalRequests {
+			psr.Name = w.Obj.Name + "/" + psr.Name
+			info.TotalRequests = append(info.TotalRequests, psr)
+		}
+	}
+	return info
+}
+
+// groupWorkloads returns the head of the entry followed by the other
+// workloads of its admission group.
+func (e *entry) groupWorkloads() []*workload.Info {
+	infos := make([]*workload.Info, 0, len(e.groupMembers)+1)
+	infos = append(infos, &e.Info)
+	for i := range e.groupMembers {
+		infos = append(infos, &e.groupMembers[i])
+	}
+	return infos
+}
+
+// groupAdmission returns copies of the workloads of the admission group in the
+// entry, with the admission given by their share of the assignment.
+func groupAdmission(e *entry) []*kueue.Workload {
+	psFlavors := e.assignment.ToAPI()
+	wls := make([]*kueue.Workload, 0, len(e.groupMembers)+1)
+	offset := 0
+	for _, w := range e.groupWorkloads() {
+		wl := w.Obj.DeepCopy()
+		flavors := psFlavors[offset : offset+len(wl.Spec.PodSets)]
+		for i := range flavors {
+			flavors[i].Name = wl.Spec.PodSets[i].Name
+		}
+		offset += len(wl.Spec.PodSets)
+		wl.Status.Admission = e.admission(flavors)
+		wls = append(wls, wl)
+	}
+	return wls
+}
+
+// admittedInfos returns the workload.Info of the workload in the entry and of
+// the other workloads of its admission group, as admitted with the flavors of
+// the assignment.
+func admittedInfos(e *entry) []*workload.Info {
+	if len(e.groupMembers) == 0 {
+		return []*workload.Info{admittedInfo(e)}
+	}
+	wls := groupAdmission(e)
+	infos := make([]*workload.Info, len(wls))
+	for i, wl := range wls {
+		infos[i] = workload.NewInfo(wl)
+	}
+	return infos
+}
+
+// admitGroup assumes the admission of all the workloads of the admission group
+// in the entry and asynchronously updates them in the apiserver. If any update
+// fails, the admission of the workloads already updated is reverted, so that
+// the group isn't partially admitted.
+func (s *Scheduler) admitGroup(ctx context.Context, e *entry) error {
+	log := ctrl.LoggerFrom(ctx)
+	wls := groupAdmission(e)
+	if err := s.cache.AssumeWorkloads(wls); err != nil {
+		return err
+	}
+	e.status = assumed
+	log.V(2).Info("Admission group assumed in the cache", "workloads", len(wls))
+
+	s.admissions.Add(1)
+	metrics.ReportInternalQueueDepth(metrics.InternalQueueAdmission, int(atomic.AddInt32(&s.admissionsInFlight, 1)))
+	assumeTime := time.Now()
+	s.admissionRoutineWrapper.Run(func() {
+		defer s.admissions.Done()
+		var err error
+		applied := 0
+		for ; applied < len(wls); applied++ {
+			if err = s.applyAdmission(ctx, workloadAdmissionFrom(wls[applied])); err != nil {
+				break
+			}
+		}
+		metrics.InternalQueueLatency(metrics.InternalQueueAdmission, time.Since(assumeTime))
+		metrics.ReportInternalQueueDepth(metrics.InternalQueueAdmission, int(atomic.AddInt32(&s.admissionsInFlight, -1)))
+		if err == nil {
+			for _, wl := range wls {
+				s.recordAdmission(log.WithValues("member", klog.KObj(wl)), wl)
+				s.framework.RunPostAdmitPlugins(ctx, wl)
+			}
+			return
+		}
+		log.Error(err, errCouldNotAdmitWL, "member", klog.KObj(wls[applied]))
+		for _, wl := range wls[:applied] {
+			revert := workloadAdmissionFrom(wl)
+			revert.Status.Admission = nil
+			// Revert the admission even if the spec changed in the meantime.
+			revert.Generation = 0
+			if err := s.applyAdmission(ctx, revert); err != nil {
+				log.Error(err, "Could not revert the admission of a workload of the admission group", "member", klog.KObj(wl))
+			}
+		}
+		// Ignore errors because the workloads or clusterQueue could have been
+		// deleted by an event.
+		for _, wl := range wls {
+			_ = s.cache.ForgetWorkload(wl)
+		}
+		metrics.InternalQueueRetry(metrics.InternalQueueAdmission)
+		s.requeueAndUpdate(log, ctx, *e)
+	})
+	return nil
+}
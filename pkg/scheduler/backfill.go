@@ -0,0 +1,137 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package scheduler
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"github.com/go-logr/logr"
+	apimeta "k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/klog/v2"
+	ctrl "sigs.k8s.io/controller-runtime"
+
+	kueue "sigs.k8s.io/kueue/apis/kueue/v1alpha2"
+	"sigs.k8s.io/kueue/pkg/cache"
+	"sigs.k8s.io/kueue/pkg/scheduler/flavorassigner"
+	"sigs.k8s.io/kueue/pkg/workload"
+)
+
+// backfill admits the workloads pending behind the head of a StrictFIFO
+// ClusterQueue, which couldn't be admitted, that fit in the available quota
+// without borrowing and are expected to finish before the head is expected to
+// start. It returns the number of admitted workloads.
+func (s *Scheduler) backfill(ctx context.Context, head *entry, snapshot *cache.Snapshot, now time.Time) int {
+	log := ctrl.LoggerFrom(ctx).WithValues("clusterQueue", klog.KRef("", head.ClusterQueue))
+	cq := snapshot.ClusterQueues[head.ClusterQueue]
+	headStart, ok := estimatedStart(log, &head.Info, cq, snapshot, now)
+	if !ok {
+		log.V(3).Info("Can't estimate the start of the head, skipping backfill", "workload", klog.KObj(head.Obj))
+		return 0
+	}
+	admitted := 0
+	for _, w := range s.queues.SortedPendingWorkloads(head.ClusterQueue) {
+		if w.Obj.Spec.ExpectedDuration == nil || now.Add(w.Obj.Spec.ExpectedDuration.Duration).After(headStart) {
+			continue
+		}
+		// The workloads of admission groups are only admitted as heads.
+		if _, _, grouped := workload.AdmissionGroup(w.Obj); grouped {
+			continue
+		}
+		e := s.nominate(ctx, []workload.Info{w}, *snapshot)[0]
+		if e.assignment.RepresentativeMode() != flavorassigner.Fit || e.assignment.Borrows() {
+			continue
+		}
+		e.status = nominated
+		log := log.WithValues("workload", klog.KObj(e.Obj))
+		if err := s.admit(ctrl.LoggerInto(ctx, log), &e); err != nil {
+			if e.status == notNominated {
+				log.V(2).Info("Workload not permitted to be backfilled", "reason", e.inadmissibleMsg)
+			} else {
+				log.Error(err, "Failed to backfill workload")
+			}
+			continue
+		}
+		log.V(2).Info("Workload backfilled", "head", klog.KObj(head.Obj), "headEstimatedStart", headStart)
+		s.queues.DeleteWorkload(e.Obj)
+		snapshot.AddWorkload(admittedInfo(&e))
+		admitted++
+	}
+	return admitted
+}
+
+// estimatedStart returns the time at which the head is expected to fit in the
+// quota of the ClusterQueue, based on the expected end of the workloads
+// admitted by the ClusterQueue. It returns false if any of the admitted
+// workloads doesn't have an expected duration, or if the head doesn't fit
+// after all of them finish.
+func estimatedStart(log logr.Logger, head *workload.Info, cq *cache.ClusterQueue, snapshot *cache.Snapshot, now time.Time) (time.Time, bool) {
+	type running struct {
+		info *workload.Info
+		end  time.Time
+	}
+	runs := make([]running, 0, len(cq.Workloads))
+	for _, w := range cq.Workloads {
+		if w.Obj.Spec.ExpectedDuration == nil {
+			return time.Time{}, false
+		}
+		end := admissionTime(w.Obj, now).Add(w.Obj.Spec.ExpectedDuration.Duration)
+		if end.Before(now) {
+			end = now
+		}
+		runs = append(runs, running{info: w, end: end})
+	}
+	sort.Slice(runs, func(i, j int) bool {
+		return runs[i].end.Before(runs[j].end)
+	})
+
+	removed := make([]*workload.Info, 0, len(runs))
+	defer func() {
+		for _, w := range removed {
+			snapshot.AddWorkload(w)
+		}
+	}()
+	for _, r := range runs {
+		snapshot.RemoveWorkload(r.info)
+		removed = append(removed, r.info)
+		assignment := flavorassigner.AssignFlavors(log, head, snapshot.ResourceFlavors, cq)
+		if assignment.RepresentativeMode() == flavorassigner.Fit {
+			return r.end, true
+		}
+	}
+	return time.Time{}, false
+}
+
+// admissionTime returns the time when the workload was admitted, or now if
+// the Admitted condition is not set yet.
+func admissionTime(wl *kueue.Workload, now time.Time) time.Time {
+	cond := apimeta.FindStatusCondition(wl.Status.Conditions, kueue.WorkloadAdmitted)
+	if cond == nil || cond.Status != metav1.ConditionTrue {
+		return now
+	}
+	return cond.LastTransitionTime.Time
+}
+
+// admittedInfo returns the workload.Info of the workload in the entry, as
+// admitted with the flavors of the assignment.
+func admittedInfo(e *entry) *workload.Info {
+	wl := e.Obj.DeepCopy()
+	wl.Status.Admission = e.admission(e.assignment.ToAPI())
+	return workload.NewInfo(wl)
+}
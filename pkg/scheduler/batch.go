@@ -0,0 +1,92 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package scheduler
+
+import (
+	"context"
+
+	"k8s.io/apimachinery/pkg/api/equality"
+
+	kueue "sigs.k8s.io/kueue/apis/kueue/v1alpha2"
+	"sigs.k8s.io/kueue/pkg/cache"
+	"sigs.k8s.io/kueue/pkg/scheduler/flavorassigner"
+	"sigs.k8s.io/kueue/pkg/workload"
+)
+
+// admissionBatch holds the flavor assignment of a workload admitted in the
+// cycle, which is reused for the identical workloads pending behind it, like
+// the workloads of an array-style submission. This saves assigning flavors to
+// each of them.
+type admissionBatch struct {
+	template   *kueue.Workload
+	assignment flavorassigner.Assignment
+	// size is the number of identical workloads that can still be admitted
+	// with the assignment, as they fit in the quota left in the ClusterQueue
+	// without borrowing.
+	size int
+}
+
+// newAdmissionBatch returns a batch for the identical workloads of the
+// admitted entry, after its usage was added to the ClusterQueue. It returns
+// nil if the assignment borrows, partially admits the workload or splits its
+// pod sets, as it depends on the quota left.
+func newAdmissionBatch(e *entry, cq *cache.ClusterQueue) *admissionBatch {
+	if len(e.groupMembers) > 0 || e.assignment.Borrows() {
+		return nil
+	}
+	for _, ps := range e.assignment.PodSets {
+		if ps.Count != nil {
+			return nil
+		}
+	}
+	size := cq.CopiesFitting(e.assignment.Usage)
+	if size == 0 {
+		return nil
+	}
+	return &admissionBatch{
+		template:   e.Obj,
+		assignment: e.assignment,
+		size:       size,
+	}
+}
+
+// matches returns whether the workload gets the same assignment as the
+// template of the batch, because it requests the same pod sets through the
+// same LocalQueue.
+func (b *admissionBatch) matches(w *workload.Info) bool {
+	return b.size > 0 &&
+		w.Obj.Namespace == b.template.Namespace &&
+		w.Obj.Spec.QueueName == b.template.Spec.QueueName &&
+		equality.Semantic.DeepEqual(w.Obj.Spec.PodSets, b.template.Spec.PodSets)
+}
+
+// nominateInBatch returns the entry for the workload with the assignment of
+// the batch, if it passes the checks that depend on the workload itself.
+// Otherwise, the workload should be nominated on its own.
+func (s *Scheduler) nominateInBatch(ctx context.Context, b *admissionBatch, w workload.Info, cq *cache.ClusterQueue) (entry, bool) {
+	if _, exceeded := cq.LocalQueueLimitExceeded(&w); exceeded {
+		return entry{}, false
+	}
+	if cq.WorkloadsPendingChecksLimitReached() {
+		return entry{}, false
+	}
+	if err := s.framework.RunPreFilterPlugins(ctx, &w, cq); err != nil {
+		return entry{}, false
+	}
+	b.size--
+	return entry{Info: w, assignment: b.assignment, admissionChecks: cq.AdmissionChecksForFlavors(b.assignment.Flavors())}, true
+}
@@ -0,0 +1,152 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package externaladmission implements a scheduler plugin that asks an
+// external endpoint, such as a budget or compliance system, to approve the
+// admission of each Workload before it's applied.
+package externaladmission
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+
+	config "sigs.k8s.io/kueue/apis/config/v1alpha2"
+	kueue "sigs.k8s.io/kueue/apis/kueue/v1alpha2"
+	"sigs.k8s.io/kueue/pkg/scheduler/framework"
+	"sigs.k8s.io/kueue/pkg/util/useragent"
+)
+
+const Name = "ExternalAdmission"
+
+// maxResponseSize bounds the size of the responses that are decoded.
+const maxResponseSize = 64 << 10
+
+// Review is the candidate admission of a Workload, sent to the endpoint.
+type Review struct {
+	Namespace string            `json:"namespace"`
+	Name      string            `json:"name"`
+	UID       types.UID         `json:"uid"`
+	Labels    map[string]string `json:"labels,omitempty"`
+
+	// Spec is the spec of the Workload, without the admission.
+	Spec      kueue.WorkloadSpec `json:"spec"`
+	Admission kueue.Admission    `json:"admission"`
+}
+
+// Response is the decision of the endpoint about the admission.
+type Response struct {
+	Allowed bool `json:"allowed"`
+	// Message is the reason why the admission is denied. It's shown in the
+	// status of the Workload.
+	Message string `json:"message,omitempty"`
+}
+
+// Plugin is a framework.PermitPlugin that sends a Review of each admission
+// to an HTTP endpoint, and only permits the admissions that the endpoint
+// allows.
+type Plugin struct {
+	url            string
+	client         *http.Client
+	ignoreFailures bool
+}
+
+var _ framework.PermitPlugin = &Plugin{}
+
+// New returns the plugin for the configuration.
+func New(cfg *config.ExternalAdmission) *Plugin {
+	return &Plugin{
+		url:            cfg.URL,
+		client:         &http.Client{Timeout: timeout(cfg)},
+		ignoreFailures: cfg.FailurePolicy == config.ExternalAdmissionIgnore,
+	}
+}
+
+func timeout(cfg *config.ExternalAdmission) time.Duration {
+	if cfg.Timeout == nil {
+		return config.DefaultExternalAdmissionTimeout
+	}
+	return cfg.Timeout.Duration
+}
+
+func (p *Plugin) Name() string {
+	return Name
+}
+
+func (p *Plugin) Permit(ctx context.Context, wl *kueue.Workload, admission *kueue.Admission) error {
+	resp, err := p.review(ctx, newReview(wl, admission))
+	if err != nil {
+		if p.ignoreFailures {
+			ctrl.LoggerFrom(ctx).Error(err, "Failed to review the admission, allowing it", "url", p.url)
+			return nil
+		}
+		return fmt.Errorf("reviewing the admission: %w", err)
+	}
+	if !resp.Allowed {
+		if resp.Message == "" {
+			return errors.New("admission denied")
+		}
+		return fmt.Errorf("admission denied: %s", resp.Message)
+	}
+	return nil
+}
+
+func newReview(wl *kueue.Workload, admission *kueue.Admission) *Review {
+	r := &Review{
+		Namespace: wl.Namespace,
+		Name:      wl.Name,
+		UID:       wl.UID,
+		Labels:    wl.Labels,
+		Spec:      *wl.Spec.DeepCopy(),
+		Admission: *admission.DeepCopy(),
+	}
+	r.Spec.Admission = nil
+	return r
+}
+
+func (p *Plugin) review(ctx context.Context, r *Review) (*Response, error) {
+	body, err := json.Marshal(r)
+	if err != nil {
+		return nil, fmt.Errorf("encoding review: %w", err)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, p.url, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", useragent.Default())
+	httpResp, err := p.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer httpResp.Body.Close()
+	if httpResp.StatusCode < 200 || httpResp.StatusCode >= 300 {
+		return nil, fmt.Errorf("unexpected response status from %s: %s", p.url, httpResp.Status)
+	}
+	var resp Response
+	if err := json.NewDecoder(io.LimitReader(httpResp.Body, maxResponseSize)).Decode(&resp); err != nil {
+		return nil, fmt.Errorf("decoding response: %w", err)
+	}
+	return &resp, nil
+}
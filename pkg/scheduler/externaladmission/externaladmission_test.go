@@ -0,0 +1,105 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package externaladmission
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	config "sigs.k8s.io/kueue/apis/config/v1alpha2"
+	utiltesting "sigs.k8s.io/kueue/pkg/util/testing"
+)
+
+func TestPermit(t *testing.T) {
+	wl := utiltesting.MakeWorkload("foo", "ns").Queue("lq").Request(corev1.ResourceCPU, "2").Obj()
+	admission := utiltesting.MakeAdmission("cq").Flavor(corev1.ResourceCPU, "default").Obj()
+
+	cases := map[string]struct {
+		status        int
+		response      *Response
+		failurePolicy config.ExternalAdmissionFailurePolicy
+		wantErr       bool
+	}{
+		"allowed": {
+			status:   http.StatusOK,
+			response: &Response{Allowed: true},
+		},
+		"denied": {
+			status:   http.StatusOK,
+			response: &Response{Message: "over budget"},
+			wantErr:  true,
+		},
+		"failure": {
+			status:        http.StatusInternalServerError,
+			failurePolicy: config.ExternalAdmissionFail,
+			wantErr:       true,
+		},
+		"ignored failure": {
+			status:        http.StatusInternalServerError,
+			failurePolicy: config.ExternalAdmissionIgnore,
+		},
+		"invalid response": {
+			status:  http.StatusOK,
+			wantErr: true,
+		},
+	}
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			var gotReview Review
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if r.Method != http.MethodPost {
+					t.Errorf("Got method %s, want POST", r.Method)
+				}
+				if err := json.NewDecoder(r.Body).Decode(&gotReview); err != nil {
+					t.Errorf("Failed decoding review: %v", err)
+				}
+				w.WriteHeader(tc.status)
+				if tc.response != nil {
+					_ = json.NewEncoder(w).Encode(tc.response)
+				}
+			}))
+			defer srv.Close()
+
+			p := New(&config.ExternalAdmission{
+				URL:           srv.URL,
+				Timeout:       &metav1.Duration{Duration: time.Second},
+				FailurePolicy: tc.failurePolicy,
+			})
+			err := p.Permit(context.Background(), wl, admission)
+			if gotErr := err != nil; gotErr != tc.wantErr {
+				t.Fatalf("Permit returned error %v, want error: %t", err, tc.wantErr)
+			}
+			wantReview := Review{
+				Namespace: "ns",
+				Name:      "foo",
+				Spec:      wl.Spec,
+				Admission: *admission,
+			}
+			if diff := cmp.Diff(wantReview, gotReview); diff != "" {
+				t.Errorf("Unexpected review (-want,+got):\n%s", diff)
+			}
+		})
+	}
+}
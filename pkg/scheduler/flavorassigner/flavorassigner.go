@@ -19,6 +19,7 @@ package flavorassigner
 import (
 	"errors"
 	"fmt"
+	"math"
 	"sort"
 	"strings"
 
@@ -26,6 +27,7 @@ import (
 	"github.com/google/go-cmp/cmp"
 	"github.com/google/go-cmp/cmp/cmpopts"
 	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/util/sets"
 	corev1helpers "k8s.io/component-helpers/scheduling/corev1"
@@ -33,6 +35,8 @@ import (
 
 	kueue "sigs.k8s.io/kueue/apis/kueue/v1alpha2"
 	"sigs.k8s.io/kueue/pkg/cache"
+	"sigs.k8s.io/kueue/pkg/constants"
+	"sigs.k8s.io/kueue/pkg/util/priority"
 	"sigs.k8s.io/kueue/pkg/workload"
 )
 
@@ -40,9 +44,9 @@ type Assignment struct {
 	PodSets     []PodSetAssignment
 	TotalBorrow cache.ResourceQuantities
 
-	// usedResources is the accumulated usage of resources as pod sets get
+	// Usage is the accumulated usage of resources as pod sets get
 	// flavors assigned.
-	usage cache.ResourceQuantities
+	Usage cache.ResourceQuantities
 
 	// representativeMode is the cached representative mode for this assignment.
 	representativeMode *FlavorAssignmentMode
@@ -52,6 +56,21 @@ func (a *Assignment) Borrows() bool {
 	return len(a.TotalBorrow) > 0
 }
 
+// Flavors returns the names of the flavors assigned to the pod sets, including
+// the flavors of their splits.
+func (a *Assignment) Flavors() sets.String {
+	flavors := sets.NewString()
+	for i := range a.PodSets {
+		ps := &a.PodSets[i]
+		for _, psa := range append([]PodSetAssignment{*ps}, ps.Splits...) {
+			for _, flv := range psa.Flavors {
+				flavors.Insert(flv.Name)
+			}
+		}
+	}
+	return flavors
+}
+
 // RepresentativeMode calculates the representative mode for the assigment as
 // the worst assignment mode among all the pod sets.
 func (a *Assignment) RepresentativeMode() FlavorAssignmentMode {
@@ -149,6 +168,12 @@ type PodSetAssignment struct {
 	Name    string
 	Flavors ResourceAssignment
 	Status  *Status
+	// Count is the number of pods assigned, when the pod set is partially
+	// admitted with fewer pods than requested or split between flavors.
+	Count *int32
+	// Splits are the other parts of the pod set, with their own flavors and
+	// counts, when the pod set is split between flavors.
+	Splits []PodSetAssignment
 }
 
 // RepresentativeMode calculates the representative mode for this assignment as
@@ -176,10 +201,37 @@ func (psa *PodSetAssignment) toAPI() kueue.PodSetFlavors {
 	for res, flvAssignment := range psa.Flavors {
 		flavors[res] = flvAssignment.Name
 	}
-	return kueue.PodSetFlavors{
-		Name:    psa.Name,
-		Flavors: flavors,
+	psFlavors := kueue.PodSetFlavors{
+		Name:          psa.Name,
+		Flavors:       flavors,
+		FlavorsReason: psa.reason(),
+		Count:         psa.Count,
+	}
+	for _, split := range psa.Splits {
+		psFlavors.Splits = append(psFlavors.Splits, kueue.PodSetSplit{
+			Flavors: split.toAPI().Flavors,
+			Count:   *split.Count,
+		})
+	}
+	return psFlavors
+}
+
+// reason joins the reasons of the assigned flavors, grouping the resources
+// that share the same reason, such as codependent resources.
+func (psa *PodSetAssignment) reason() string {
+	resourcesPerReason := make(map[string][]string)
+	for res, flvAssignment := range psa.Flavors {
+		if flvAssignment.reason != "" {
+			resourcesPerReason[flvAssignment.reason] = append(resourcesPerReason[flvAssignment.reason], string(res))
+		}
+	}
+	parts := make([]string, 0, len(resourcesPerReason))
+	for reason, resources := range resourcesPerReason {
+		sort.Strings(resources)
+		parts = append(parts, fmt.Sprintf("%s: %s", strings.Join(resources, ", "), reason))
 	}
+	sort.Strings(parts)
+	return strings.Join(parts, "; ")
 }
 
 // FlavorAssignmentMode describes whether the flavor can be assigned immediately
@@ -222,24 +274,45 @@ type FlavorAssignment struct {
 	Name   string
 	Mode   FlavorAssignmentMode
 	borrow int64
+	// leftover is the min quota of the flavor that remains unused after
+	// the assignment.
+	leftover int64
+	// reason explains why the flavor was chosen.
+	reason string
 }
 
+// FlavorScorer returns the score of a flavor that fits the resources
+// requested by a pod set. Among the flavors that fit, the one with the highest
+// score is preferred.
+type FlavorScorer func(podSet string, flavor *kueue.ResourceFlavor) int64
+
 // AssignFlavors assigns flavors for each of the resources requested in each pod set.
 // The result for each pod set is accompanied with reasons why the flavor can't
 // be assigned immediately. Each assigned flavor is accompanied with a
 // FlavorAssignmentMode.
 func AssignFlavors(log logr.Logger, wl *workload.Info, resourceFlavors map[string]*kueue.ResourceFlavor, cq *cache.ClusterQueue) Assignment {
+	return AssignFlavorsWithScorer(log, wl, resourceFlavors, cq, nil)
+}
+
+// AssignFlavorsWithScorer is like AssignFlavors, but among the flavors that
+// fit, it prefers the one with the highest score. If scorer is nil, it
+// behaves like AssignFlavors.
+func AssignFlavorsWithScorer(log logr.Logger, wl *workload.Info, resourceFlavors map[string]*kueue.ResourceFlavor, cq *cache.ClusterQueue, scorer FlavorScorer) Assignment {
 	assignment := Assignment{
 		TotalBorrow: make(cache.ResourceQuantities),
 		PodSets:     make([]PodSetAssignment, 0, len(wl.TotalRequests)),
-		usage:       make(cache.ResourceQuantities),
+		Usage:       make(cache.ResourceQuantities),
 	}
+	failed := false
+	wlPriority := priority.Priority(wl.Obj)
+	fallbacks := cq.FallbackFlavors(wl.Obj)
 	for i, podSet := range wl.TotalRequests {
+		requests := withCountingRequests(&podSet, i == 0, cq)
 		psAssignment := PodSetAssignment{
 			Name:    podSet.Name,
-			Flavors: make(ResourceAssignment, len(podSet.Requests)),
+			Flavors: make(ResourceAssignment, len(requests)),
 		}
-		for resName := range podSet.Requests {
+		for resName := range requests {
 			if _, found := psAssignment.Flavors[resName]; found {
 				// This resource got assigned the same flavor as a codependent resource.
 				// No need to compute again.
@@ -256,8 +329,8 @@ func AssignFlavors(log logr.Logger, wl *workload.Info, resourceFlavors map[strin
 			if codepResources.Len() == 0 {
 				codepResources = sets.NewString(string(resName))
 			}
-			codepReq := filterRequestedResources(podSet.Requests, codepResources)
-			flavors, status := assignment.findFlavorForCodepResources(log, codepReq, resourceFlavors, cq, &wl.Obj.Spec.PodSets[i].Spec)
+			codepReq := filterRequestedResources(requests, codepResources)
+			flavors, status := assignment.findFlavorForCodepResources(log, codepReq, resourceFlavors, cq, &wl.Obj.Spec.PodSets[i].Spec, wlPriority, fallbacks, scoreFor(scorer, podSet.Name))
 			if status.IsError() || len(flavors) == 0 {
 				psAssignment.Flavors = nil
 				psAssignment.Status = status
@@ -266,19 +339,92 @@ func AssignFlavors(log logr.Logger, wl *workload.Info, resourceFlavors map[strin
 			psAssignment.append(flavors, status)
 		}
 
-		assignment.append(podSet.Requests, &psAssignment)
-		if psAssignment.Status.IsError() || (len(podSet.Requests) > 0 && len(psAssignment.Flavors) == 0) {
-			// This assignment failed, no need to continue tracking.
+		if psAssignment.Status.IsError() {
+			assignment.append(requests, &psAssignment)
 			assignment.TotalBorrow = nil
 			return assignment
 		}
+		if len(requests) > 0 && len(psAssignment.Flavors) == 0 {
+			// The workload is admitted with all its pod sets or none. Keep
+			// assigning flavors to the rest of the pod sets only to report why
+			// each of them doesn't fit.
+			failed = true
+		}
+		assignment.append(requests, &psAssignment)
 	}
-	if len(assignment.TotalBorrow) == 0 {
+	if failed || len(assignment.TotalBorrow) == 0 {
 		assignment.TotalBorrow = nil
 	}
 	return assignment
 }
 
+// AssignSplitFlavors assigns flavors to the workload splitting the pods of one
+// of its pod sets between two flavors, if the ClusterQueue allows it: the
+// largest number of pods that fits in a single flavor and the rest in another
+// one. It only returns an assignment if the whole workload fits.
+func AssignSplitFlavors(log logr.Logger, wl *workload.Info, resourceFlavors map[string]*kueue.ResourceFlavor, cq *cache.ClusterQueue, scorer FlavorScorer) (Assignment, bool) {
+	if !cq.SplitWhenNoFlavorFits {
+		return Assignment{}, false
+	}
+	for idx, ps := range wl.Obj.Spec.PodSets {
+		if ps.Count < 2 {
+			continue
+		}
+		var count int32
+		low, high := int32(1), ps.Count-1
+		for low <= high {
+			c := low + (high-low)/2
+			if AssignFlavorsWithScorer(log, wl.WithPodSetCount(idx, c), resourceFlavors, cq, scorer).RepresentativeMode() == Fit {
+				count = c
+				low = c + 1
+			} else {
+				high = c - 1
+			}
+		}
+		if count == 0 {
+			continue
+		}
+		assignment := AssignFlavorsWithScorer(log, wl.WithSplitPodSet(idx, count), resourceFlavors, cq, scorer)
+		if assignment.RepresentativeMode() != Fit {
+			continue
+		}
+		// Fold the second part back into the pod set.
+		rest := assignment.PodSets[idx+1]
+		restCount := ps.Count - count
+		rest.Count = &restCount
+		assignment.PodSets[idx].Count = &count
+		assignment.PodSets[idx].Splits = []PodSetAssignment{rest}
+		assignment.PodSets = append(assignment.PodSets[:idx+1], assignment.PodSets[idx+2:]...)
+		log.V(3).Info("Workload fits splitting a pod set between flavors", "podSet", ps.Name, "count", count, "rest", restCount)
+		return assignment, true
+	}
+	return Assignment{}, false
+}
+
+// withCountingRequests returns the requests of the pod set, including its
+// pods and, for the first pod set, the workload, if the ClusterQueue defines
+// quota for them.
+func withCountingRequests(ps *workload.PodSetResources, first bool, cq *cache.ClusterQueue) workload.Requests {
+	_, countsPods := cq.RequestableResources[corev1.ResourcePods]
+	_, countsWorkloads := cq.RequestableResources[kueue.ResourceWorkloads]
+	countsPods = countsPods && ps.Count > 0
+	countsWorkloads = countsWorkloads && first
+	if !countsPods && !countsWorkloads {
+		return ps.Requests
+	}
+	requests := make(workload.Requests, len(ps.Requests)+2)
+	for name, v := range ps.Requests {
+		requests[name] = v
+	}
+	if countsPods {
+		requests[corev1.ResourcePods] = int64(ps.Count)
+	}
+	if countsWorkloads {
+		requests[kueue.ResourceWorkloads] = 1
+	}
+	return requests
+}
+
 func (psa *PodSetAssignment) append(flavors ResourceAssignment, status *Status) {
 	for resource, assignment := range flavors {
 		psa.Flavors[resource] = assignment
@@ -301,10 +447,10 @@ func (a *Assignment) append(requests workload.Requests, psAssignment *PodSetAssi
 			// usage from previous pod sets.
 			a.TotalBorrow[resource][flvAssignment.Name] = flvAssignment.borrow
 		}
-		if a.usage[resource] == nil {
-			a.usage[resource] = make(map[string]int64)
+		if a.Usage[resource] == nil {
+			a.Usage[resource] = make(map[string]int64)
 		}
-		a.usage[resource][flvAssignment.Name] += requests[resource]
+		a.Usage[resource][flvAssignment.Name] += requests[resource]
 	}
 }
 
@@ -312,12 +458,28 @@ func (a *Assignment) append(requests workload.Requests, psAssignment *PodSetAssi
 // request, along with the information about resources that need to be borrowed.
 // If the flavor cannot be immediately assigned, it returns a status with
 // reasons or failure.
+// The flavors are evaluated in the order declared in the ClusterQueue. When
+// several flavors have the same assignment mode, the first one is preferred,
+// unless the ClusterQueue uses the BestFit policy, in which case the flavor
+// that fits better is preferred among the ones that fit.
+// If score is not nil, the flavor with the highest score is preferred among
+// the ones that fit, and the policy of the ClusterQueue only breaks the ties.
+// Otherwise, if the ClusterQueue uses the LowestCost policy, the cheapest
+// flavor is preferred among the ones that fit.
+// The quota reserved for the tiers above the priority of the workload is not
+// available to it.
+// If the workload was evicted too many times from any of the flavors, given
+// in fallbacks, only their fallback flavors are considered.
+// Since the flavor names are unique in a resource, the choice is deterministic.
 func (a *Assignment) findFlavorForCodepResources(
 	log logr.Logger,
 	requests workload.Requests,
 	resourceFlavors map[string]*kueue.ResourceFlavor,
 	cq *cache.ClusterQueue,
-	spec *corev1.PodSpec) (ResourceAssignment, *Status) {
+	spec *corev1.PodSpec,
+	wlPriority int32,
+	fallbacks map[string]string,
+	score func(*kueue.ResourceFlavor) int64) (ResourceAssignment, *Status) {
 	status := &Status{}
 
 	// Keep any resource name as an anchor to gather flavors for.
@@ -327,10 +489,16 @@ func (a *Assignment) findFlavorForCodepResources(
 	}
 	var bestAssignment ResourceAssignment
 	bestAssignmentMode := NoFit
+	var bestScore int64
+	byCost := score == nil && cq.LowestCost
+	if byCost {
+		score = negatedCost
+	}
 
 	// We will only check against the flavors' labels for the resource.
 	// Since all the resources share the same flavors, they use the same selector.
 	selector := flavorSelector(spec, cq.LabelKeys[rName])
+	allowed := allowedFlavors(cq.RequestableResources[rName].Flavors, fallbacks)
 	for i, flvLimit := range cq.RequestableResources[rName].Flavors {
 		flavor, exist := resourceFlavors[flvLimit.Name]
 		if !exist {
@@ -338,6 +506,26 @@ func (a *Assignment) findFlavorForCodepResources(
 			status.append(fmt.Sprintf("flavor %s not found", flvLimit.Name))
 			continue
 		}
+		if allowed != nil && !allowed.Has(flvLimit.Name) {
+			if fallback, ok := fallbacks[flvLimit.Name]; ok {
+				status.append(fmt.Sprintf("evicted too many times from flavor %s, falling back to flavor %s", flvLimit.Name, fallback))
+			} else {
+				status.append(fmt.Sprintf("flavor %s is not a fallback flavor", flvLimit.Name))
+			}
+			continue
+		}
+		if onHold(requests, cq, i) {
+			status.append(fmt.Sprintf("flavor %s is on hold", flvLimit.Name))
+			continue
+		}
+		if !flavor.DeletionTimestamp.IsZero() {
+			status.append(fmt.Sprintf("flavor %s is being deleted", flvLimit.Name))
+			continue
+		}
+		if exhausted, ok := exhaustedBudget(requests, cq, flvLimit.Name); ok {
+			status.append(fmt.Sprintf("budget of %s in flavor %s is exhausted", exhausted, flvLimit.Name))
+			continue
+		}
 		taint, untolerated := corev1helpers.FindMatchingUntoleratedTaint(flavor.Taints, spec.Tolerations, func(t *corev1.Taint) bool {
 			return t.Effect == corev1.TaintEffectNoSchedule || t.Effect == corev1.TaintEffectNoExecute
 		})
@@ -360,7 +548,9 @@ func (a *Assignment) findFlavorForCodepResources(
 		for name, val := range requests {
 			codepFlvLimit := cq.RequestableResources[name].Flavors[i]
 			// Check considering the flavor usage by previous pod sets.
-			mode, borrow, s := fitsFlavorLimits(name, val+a.usage[name][flavor.Name], cq, &codepFlvLimit)
+			request := val + a.Usage[name][flavor.Name]
+			reserved := cq.ReservedQuota(name, flavor.Name, wlPriority)
+			mode, borrow, s := fitsFlavorLimits(name, request, reserved, cq.CanBorrow(wlPriority), cq, &codepFlvLimit)
 			if s != nil {
 				status.reasons = append(status.reasons, s.reasons...)
 			}
@@ -372,25 +562,194 @@ func (a *Assignment) findFlavorForCodepResources(
 				break
 			}
 
+			leftover := codepFlvLimit.Min - cq.UsedResources[name][flavor.Name] - request - reserved
+			if leftover < 0 {
+				leftover = 0
+			}
 			assignments[name] = &FlavorAssignment{
-				Name:   flavor.Name,
-				Mode:   mode,
-				borrow: borrow,
+				Name:     flavor.Name,
+				Mode:     mode,
+				borrow:   borrow,
+				leftover: leftover,
 			}
 		}
 
+		if score != nil && representativeMode == Fit {
+			s := score(flavor)
+			if bestAssignmentMode != Fit || s > bestScore || (s == bestScore && preferredOnTie(cq, assignments, bestAssignment)) {
+				bestAssignment = assignments
+				bestAssignmentMode = Fit
+				bestScore = s
+			}
+			continue
+		}
+		if cq.BestFit && representativeMode == Fit {
+			if bestAssignmentMode != Fit || fitsBetter(assignments, bestAssignment) {
+				bestAssignment = assignments
+				bestAssignmentMode = Fit
+			}
+			continue
+		}
+		if cq.TryNextFlavorWhenCanBorrow && representativeMode == Fit && borrows(assignments) {
+			// Only borrow in the first flavor that fits if no flavor fits
+			// without borrowing.
+			if bestAssignmentMode != Fit {
+				bestAssignment = assignments
+				bestAssignmentMode = Fit
+			}
+			continue
+		}
+		if representativeMode == Fit {
+			// All the resources fit in the cohort, no need to check more flavors.
+			reason := fmt.Sprintf("flavor %s is the first one that fits in the ClusterQueue order", flavor.Name)
+			if len(status.reasons) > 0 {
+				reason = fmt.Sprintf("%s (skipped: %s)", reason, status.Message())
+			}
+			for _, assignment := range assignments {
+				assignment.reason = reason
+			}
+			return assignments, nil
+		}
 		if representativeMode > bestAssignmentMode {
 			bestAssignment = assignments
 			bestAssignmentMode = representativeMode
-			if bestAssignmentMode == Fit {
-				// All the resources fit in the cohort, no need to check more flavors.
-				return bestAssignment, nil
+			if cq.PreemptWhenCanPreempt {
+				// Preempt in this flavor rather than trying the next ones.
+				break
 			}
 		}
 	}
+	if bestAssignmentMode == Fit {
+		// Only reached with a scorer, the BestFit policy or when trying the next
+		// flavors before borrowing.
+		var name string
+		for _, assignment := range bestAssignment {
+			name = assignment.Name
+			break
+		}
+		reason := fmt.Sprintf("flavor %s is the best fit in the ClusterQueue", name)
+		if byCost {
+			reason = fmt.Sprintf("flavor %s is the cheapest one that fits in the ClusterQueue", name)
+		} else if score != nil {
+			reason = fmt.Sprintf("flavor %s has the highest score among the ones that fit in the ClusterQueue", name)
+		} else if !cq.BestFit {
+			reason = fmt.Sprintf("flavor %s is the first one that fits in the ClusterQueue order, borrowing, and no flavor fits without borrowing", name)
+		}
+		if len(status.reasons) > 0 {
+			reason = fmt.Sprintf("%s (skipped: %s)", reason, status.Message())
+		}
+		for _, assignment := range bestAssignment {
+			assignment.reason = reason
+		}
+		return bestAssignment, nil
+	}
 	return bestAssignment, status
 }
 
+// scoreFor returns a function that scores the flavors for the pod set, or nil
+// if there is no scorer.
+func scoreFor(scorer FlavorScorer, podSet string) func(*kueue.ResourceFlavor) int64 {
+	if scorer == nil {
+		return nil
+	}
+	return func(flavor *kueue.ResourceFlavor) int64 {
+		return scorer(podSet, flavor)
+	}
+}
+
+// negatedCost scores the flavors by their cost, so that the cheapest one has
+// the highest score. The flavors without a valid cost have the lowest score.
+func negatedCost(flavor *kueue.ResourceFlavor) int64 {
+	cost, err := resource.ParseQuantity(flavor.Annotations[constants.FlavorCostAnnotation])
+	if err != nil {
+		return math.MinInt64
+	}
+	return -cost.MilliValue()
+}
+
+// preferredOnTie returns whether the assignment a is preferred over b, when
+// both fit and have the same score, according to the flavor fungibility
+// policy of the ClusterQueue.
+func preferredOnTie(cq *cache.ClusterQueue, a, b ResourceAssignment) bool {
+	if cq.BestFit {
+		return fitsBetter(a, b)
+	}
+	if cq.TryNextFlavorWhenCanBorrow {
+		return borrows(b) && !borrows(a)
+	}
+	return false
+}
+
+// allowedFlavors returns the fallback flavors of the flavors that the workload
+// was evicted from too many times, or nil if there are none among the given
+// flavors, in which case all of them are allowed.
+func allowedFlavors(flavors []cache.FlavorLimits, fallbacks map[string]string) sets.String {
+	var allowed sets.String
+	for _, flv := range flavors {
+		if fallback, ok := fallbacks[flv.Name]; ok {
+			if allowed == nil {
+				allowed = sets.NewString()
+			}
+			allowed.Insert(fallback)
+		}
+	}
+	return allowed
+}
+
+// onHold returns whether the i-th flavor of any of the codependent resources
+// is on hold in the ClusterQueue.
+func onHold(requests workload.Requests, cq *cache.ClusterQueue, i int) bool {
+	for name := range requests {
+		if cq.RequestableResources[name].Flavors[i].Hold {
+			return true
+		}
+	}
+	return false
+}
+
+// borrows returns whether any of the resources borrows quota from the cohort.
+func borrows(assignments ResourceAssignment) bool {
+	for _, assignment := range assignments {
+		if assignment.borrow > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// fitsBetter returns whether the assignment a requires borrowing less quota
+// than b or, if they borrow the same, leaves less unused min quota. The
+// resources are compared in the order of their names.
+func fitsBetter(a, b ResourceAssignment) bool {
+	names := make([]string, 0, len(a))
+	for name := range a {
+		names = append(names, string(name))
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		if borrowA, borrowB := a[corev1.ResourceName(name)].borrow, b[corev1.ResourceName(name)].borrow; borrowA != borrowB {
+			return borrowA < borrowB
+		}
+	}
+	for _, name := range names {
+		if leftoverA, leftoverB := a[corev1.ResourceName(name)].leftover, b[corev1.ResourceName(name)].leftover; leftoverA != leftoverB {
+			return leftoverA < leftoverB
+		}
+	}
+	return false
+}
+
+// exhaustedBudget returns a requested resource whose budget is exhausted in
+// the flavor, if any.
+func exhaustedBudget(requests workload.Requests, cq *cache.ClusterQueue, flavor string) (corev1.ResourceName, bool) {
+	for name := range requests {
+		if cq.BudgetExhausted(name, flavor) {
+			return name, true
+		}
+	}
+	return "", false
+}
+
 func flavorSelector(spec *corev1.PodSpec, allowedKeys sets.String) nodeaffinity.RequiredNodeAffinity {
 	// This function generally replicates the implementation of kube-scheduler's NodeAffintiy
 	// Filter plugin as of v1.24.
@@ -440,13 +799,16 @@ func flavorSelector(spec *corev1.PodSpec, allowedKeys sets.String) nodeaffinity.
 // fitsFlavorLimits returns how this flavor could be assigned to the resource,
 // according to the remaining quota in the ClusterQueue and cohort.
 // If it fits, also returns any borrowing required.
+// The reserved quota, which the workload can't use, has to remain available
+// in the ClusterQueue or its cohort on top of the request.
+// If the workload can't borrow, the request has to fit in the min quota.
 // If the flavor doesn't satisfy limits immediately (when waiting or preemption
 // could help), it returns a Status with reasons.
-func fitsFlavorLimits(rName corev1.ResourceName, val int64, cq *cache.ClusterQueue, flavor *cache.FlavorLimits) (FlavorAssignmentMode, int64, *Status) {
+func fitsFlavorLimits(rName corev1.ResourceName, val, reserved int64, canBorrow bool, cq *cache.ClusterQueue, flavor *cache.FlavorLimits) (FlavorAssignmentMode, int64, *Status) {
 	var status Status
 	used := cq.UsedResources[rName][flavor.Name]
 	mode := NoFit
-	if val <= flavor.Min {
+	if val+reserved <= flavor.Min {
 		// The request can be satisfied by the min quota, assuming all active
 		// workloads in the ClusterQueue are preempted.
 		mode = ClusterQueuePreempt
@@ -456,19 +818,28 @@ func fitsFlavorLimits(rName corev1.ResourceName, val int64, cq *cache.ClusterQue
 		return mode, 0, &status
 	}
 
-	if used+val <= flavor.Min {
+	if used+val+reserved <= flavor.Min {
 		// The request can be satisfied by the min quota, assuming all active
 		// workloads from other ClusterQueues in the cohort are preempted.
 		mode = CohortReclaim
 	}
+	if cq.Cohort != nil && !canBorrow && used+val > flavor.Min {
+		status.append(fmt.Sprintf("borrowing %s flavor %s requires a priority of at least %d", rName, flavor.Name, *cq.BorrowingMinPriority))
+		return mode, 0, &status
+	}
 	cohortUsed := used
 	cohortAvailable := flavor.Min
 	if cq.Cohort != nil {
-		cohortUsed = cq.Cohort.UsedResources[rName][flavor.Name]
-		cohortAvailable = cq.Cohort.RequestableResources[rName][flavor.Name]
+		if limiting := cq.Cohort.ExceedingMax(rName, flavor.Name, val); limiting != nil {
+			status.append(fmt.Sprintf("borrowing limit for %s flavor %s exceeded in cohort %s", rName, flavor.Name, limiting.Name))
+			return mode, 0, &status
+		}
+		root := cq.Cohort.Root()
+		cohortUsed = root.UsedResources[rName][flavor.Name]
+		cohortAvailable = root.RequestableResources[rName][flavor.Name]
 	}
 
-	lack := cohortUsed + val - cohortAvailable
+	lack := cohortUsed + val + reserved - cohortAvailable
 	if lack <= 0 {
 		borrow := used + val - flavor.Min
 		if borrow < 0 {
@@ -486,6 +857,10 @@ func fitsFlavorLimits(rName corev1.ResourceName, val int64, cq *cache.ClusterQue
 			msg = fmt.Sprintf("insufficient unused quota for %s flavor %s, %s more needed", rName, flavor.Name, &lackQuantity)
 		}
 	}
+	if reserved > 0 {
+		reservedQuantity := workload.ResourceQuantity(rName, reserved)
+		msg = fmt.Sprintf("%s, %s is reserved for higher priority tiers", msg, &reservedQuantity)
+	}
 	status.append(msg)
 	return mode, 0, &status
 }
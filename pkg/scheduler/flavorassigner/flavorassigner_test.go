@@ -18,6 +18,7 @@ package flavorassigner
 
 import (
 	"testing"
+	"time"
 
 	"github.com/go-logr/logr/testr"
 	"github.com/google/go-cmp/cmp"
@@ -29,6 +30,7 @@ import (
 
 	kueue "sigs.k8s.io/kueue/apis/kueue/v1alpha2"
 	"sigs.k8s.io/kueue/pkg/cache"
+	"sigs.k8s.io/kueue/pkg/constants"
 	"sigs.k8s.io/kueue/pkg/util/pointer"
 	utiltesting "sigs.k8s.io/kueue/pkg/util/testing"
 	"sigs.k8s.io/kueue/pkg/workload"
@@ -41,13 +43,15 @@ func TestAssignFlavors(t *testing.T) {
 		},
 		"one": {
 			ObjectMeta: metav1.ObjectMeta{
-				Name: "one",
+				Name:        "one",
+				Annotations: map[string]string{constants.FlavorCostAnnotation: "2"},
 			},
 			NodeSelector: map[string]string{"type": "one"},
 		},
 		"two": {
 			ObjectMeta: metav1.ObjectMeta{
-				Name: "two",
+				Name:        "two",
+				Annotations: map[string]string{constants.FlavorCostAnnotation: "0.5"},
 			},
 			NodeSelector: map[string]string{"type": "two"},
 		},
@@ -63,6 +67,12 @@ func TestAssignFlavors(t *testing.T) {
 			},
 			NodeSelector: map[string]string{"b_type": "two"},
 		},
+		"deleting": {
+			ObjectMeta: metav1.ObjectMeta{
+				Name:              "deleting",
+				DeletionTimestamp: &metav1.Time{Time: time.Unix(1, 0)},
+			},
+		},
 		"tainted": {
 			ObjectMeta: metav1.ObjectMeta{Name: "tainted"},
 			Taints: []corev1.Taint{{
@@ -73,11 +83,16 @@ func TestAssignFlavors(t *testing.T) {
 		},
 	}
 
+	evictionTime := time.Date(2022, 10, 14, 10, 0, 0, 0, time.UTC)
 	cases := map[string]struct {
-		wlPods         []kueue.PodSet
-		clusterQueue   cache.ClusterQueue
-		wantRepMode    FlavorAssignmentMode
-		wantAssignment Assignment
+		wlPods            []kueue.PodSet
+		wlPriority        *int32
+		wlEvictions       []kueue.FlavorEvictions
+		clusterQueue      cache.ClusterQueue
+		scorer            FlavorScorer
+		wantRepMode       FlavorAssignmentMode
+		wantAssignment    Assignment
+		wantPodSetFlavors []kueue.PodSetFlavors
 	}{
 		"single flavor, fits": {
 			wlPods: []kueue.PodSet{
@@ -216,6 +231,15 @@ func TestAssignFlavors(t *testing.T) {
 					},
 				}},
 			},
+			wantPodSetFlavors: []kueue.PodSetFlavors{{
+				Name: "main",
+				Flavors: map[corev1.ResourceName]string{
+					corev1.ResourceCPU:    "two",
+					corev1.ResourceMemory: "b_one",
+				},
+				FlavorsReason: "cpu: flavor two is the first one that fits in the ClusterQueue order (skipped: insufficient quota for cpu flavor one in ClusterQueue); " +
+					"memory: flavor b_one is the first one that fits in the ClusterQueue order",
+			}},
 		},
 		"multiple independent flavors, one could fit with preemption, other doesn't fit": {
 			wlPods: []kueue.PodSet{
@@ -303,6 +327,16 @@ func TestAssignFlavors(t *testing.T) {
 					},
 				}},
 			},
+			wantPodSetFlavors: []kueue.PodSetFlavors{{
+				Name: "main",
+				Flavors: map[corev1.ResourceName]string{
+					corev1.ResourceCPU:    "two",
+					corev1.ResourceMemory: "two",
+					"example.com/gpu":     "b_one",
+				},
+				FlavorsReason: "cpu, memory: flavor two is the first one that fits in the ClusterQueue order (skipped: insufficient quota for cpu flavor one in ClusterQueue); " +
+					"example.com/gpu: flavor b_one is the first one that fits in the ClusterQueue order",
+			}},
 		},
 		"some codepedent flavors, fits with different modes": {
 			wlPods: []kueue.PodSet{
@@ -452,6 +486,13 @@ func TestAssignFlavors(t *testing.T) {
 					},
 				}},
 			},
+			wantPodSetFlavors: []kueue.PodSetFlavors{{
+				Name: "main",
+				Flavors: map[corev1.ResourceName]string{
+					corev1.ResourceCPU: "two",
+				},
+				FlavorsReason: "cpu: flavor two is the first one that fits in the ClusterQueue order (skipped: untolerated taint {instance spot NoSchedule <nil>} in flavor tainted)",
+			}},
 		},
 		"multiple flavors, skip missing ResourceFlavor": {
 			wlPods: []kueue.PodSet{
@@ -764,6 +805,93 @@ func TestAssignFlavors(t *testing.T) {
 				},
 			},
 		},
+		"multiple specs, one doesn't fit": {
+			wlPods: []kueue.PodSet{
+				{
+					Count: 1,
+					Name:  "driver",
+					Spec: utiltesting.PodSpecForRequest(map[corev1.ResourceName]string{
+						corev1.ResourceCPU: "5",
+					}),
+				},
+				{
+					Count: 1,
+					Name:  "worker",
+					Spec: utiltesting.PodSpecForRequest(map[corev1.ResourceName]string{
+						corev1.ResourceCPU: "3",
+					}),
+				},
+			},
+			clusterQueue: cache.ClusterQueue{
+				RequestableResources: map[corev1.ResourceName]*cache.Resource{
+					corev1.ResourceCPU: {
+						Flavors: []cache.FlavorLimits{
+							{Name: "default", Min: 4000},
+						},
+					},
+				},
+			},
+			wantAssignment: Assignment{
+				PodSets: []PodSetAssignment{
+					{
+						Name: "driver",
+						Status: &Status{
+							reasons: []string{"insufficient quota for cpu flavor default in ClusterQueue"},
+						},
+					},
+					{
+						Name: "worker",
+						Flavors: ResourceAssignment{
+							corev1.ResourceCPU: {Name: "default", Mode: Fit},
+						},
+					},
+				},
+			},
+		},
+		"multiple specs, none fits": {
+			wlPods: []kueue.PodSet{
+				{
+					Count: 1,
+					Name:  "driver",
+					Spec: utiltesting.PodSpecForRequest(map[corev1.ResourceName]string{
+						corev1.ResourceCPU: "5",
+					}),
+				},
+				{
+					Count: 1,
+					Name:  "worker",
+					Spec: utiltesting.PodSpecForRequest(map[corev1.ResourceName]string{
+						corev1.ResourceCPU:    "3",
+						corev1.ResourceMemory: "1Gi",
+					}),
+				},
+			},
+			clusterQueue: cache.ClusterQueue{
+				RequestableResources: map[corev1.ResourceName]*cache.Resource{
+					corev1.ResourceCPU: {
+						Flavors: []cache.FlavorLimits{
+							{Name: "default", Min: 4000},
+						},
+					},
+				},
+			},
+			wantAssignment: Assignment{
+				PodSets: []PodSetAssignment{
+					{
+						Name: "driver",
+						Status: &Status{
+							reasons: []string{"insufficient quota for cpu flavor default in ClusterQueue"},
+						},
+					},
+					{
+						Name: "worker",
+						Status: &Status{
+							reasons: []string{"resource memory unavailable in ClusterQueue"},
+						},
+					},
+				},
+			},
+		},
 		"multiple specs, fits borrowing": {
 			wlPods: []kueue.PodSet{
 				{
@@ -883,6 +1011,88 @@ func TestAssignFlavors(t *testing.T) {
 				}},
 			},
 		},
+		"priority below the minimum to borrow": {
+			wlPods: []kueue.PodSet{
+				{
+					Count: 1,
+					Name:  "main",
+					Spec: utiltesting.PodSpecForRequest(map[corev1.ResourceName]string{
+						corev1.ResourceCPU: "2",
+					}),
+				},
+			},
+			wlPriority: pointer.Int32(5),
+			clusterQueue: cache.ClusterQueue{
+				BorrowingMinPriority: pointer.Int32(10),
+				RequestableResources: map[corev1.ResourceName]*cache.Resource{
+					corev1.ResourceCPU: {Flavors: []cache.FlavorLimits{{Name: "one", Min: 3000}}},
+				},
+				UsedResources: cache.ResourceQuantities{
+					corev1.ResourceCPU: {"one": 2_000},
+				},
+				Cohort: &cache.Cohort{
+					RequestableResources: cache.ResourceQuantities{
+						corev1.ResourceCPU: {"one": 10_000},
+					},
+					UsedResources: cache.ResourceQuantities{
+						corev1.ResourceCPU: {"one": 2_000},
+					},
+				},
+			},
+			wantRepMode: ClusterQueuePreempt,
+			wantAssignment: Assignment{
+				PodSets: []PodSetAssignment{{
+					Name: "main",
+					Flavors: ResourceAssignment{
+						corev1.ResourceCPU: {Name: "one", Mode: ClusterQueuePreempt},
+					},
+					Status: &Status{
+						reasons: []string{"borrowing cpu flavor one requires a priority of at least 10"},
+					},
+				}},
+			},
+		},
+		"priority at the minimum to borrow": {
+			wlPods: []kueue.PodSet{
+				{
+					Count: 1,
+					Name:  "main",
+					Spec: utiltesting.PodSpecForRequest(map[corev1.ResourceName]string{
+						corev1.ResourceCPU: "2",
+					}),
+				},
+			},
+			wlPriority: pointer.Int32(10),
+			clusterQueue: cache.ClusterQueue{
+				BorrowingMinPriority: pointer.Int32(10),
+				RequestableResources: map[corev1.ResourceName]*cache.Resource{
+					corev1.ResourceCPU: {Flavors: []cache.FlavorLimits{{Name: "one", Min: 3000}}},
+				},
+				UsedResources: cache.ResourceQuantities{
+					corev1.ResourceCPU: {"one": 2_000},
+				},
+				Cohort: &cache.Cohort{
+					RequestableResources: cache.ResourceQuantities{
+						corev1.ResourceCPU: {"one": 10_000},
+					},
+					UsedResources: cache.ResourceQuantities{
+						corev1.ResourceCPU: {"one": 2_000},
+					},
+				},
+			},
+			wantRepMode: Fit,
+			wantAssignment: Assignment{
+				PodSets: []PodSetAssignment{{
+					Name: "main",
+					Flavors: ResourceAssignment{
+						corev1.ResourceCPU: {Name: "one", Mode: Fit},
+					},
+				}},
+				TotalBorrow: cache.ResourceQuantities{
+					corev1.ResourceCPU: {"one": 1_000},
+				},
+			},
+		},
 		"past max, but can preempt in ClusterQueue": {
 			wlPods: []kueue.PodSet{
 				{
@@ -930,6 +1140,115 @@ func TestAssignFlavors(t *testing.T) {
 				}},
 			},
 		},
+		"borrow from the parent cohort": {
+			wlPods: []kueue.PodSet{
+				{
+					Count: 1,
+					Name:  "main",
+					Spec: utiltesting.PodSpecForRequest(map[corev1.ResourceName]string{
+						corev1.ResourceCPU: "5",
+					}),
+				},
+			},
+			clusterQueue: cache.ClusterQueue{
+				RequestableResources: map[corev1.ResourceName]*cache.Resource{
+					corev1.ResourceCPU: {
+						Flavors: []cache.FlavorLimits{
+							{
+								Name: "one",
+								Min:  2000,
+							},
+						},
+					},
+				},
+				Cohort: &cache.Cohort{
+					Name: "child",
+					RequestableResources: cache.ResourceQuantities{
+						corev1.ResourceCPU: {"one": 2_000},
+					},
+					Parent: &cache.Cohort{
+						Name: "parent",
+						RequestableResources: cache.ResourceQuantities{
+							corev1.ResourceCPU: {"one": 10_000},
+						},
+						UsedResources: cache.ResourceQuantities{
+							corev1.ResourceCPU: {"one": 4_000},
+						},
+					},
+				},
+			},
+			wantRepMode: Fit,
+			wantAssignment: Assignment{
+				PodSets: []PodSetAssignment{{
+					Name: "main",
+					Flavors: ResourceAssignment{
+						corev1.ResourceCPU: {Name: "one", Mode: Fit},
+					},
+				}},
+				TotalBorrow: cache.ResourceQuantities{
+					corev1.ResourceCPU: {"one": 3_000},
+				},
+			},
+		},
+		"past max of the parent cohort, but can preempt in ClusterQueue": {
+			wlPods: []kueue.PodSet{
+				{
+					Count: 1,
+					Name:  "main",
+					Spec: utiltesting.PodSpecForRequest(map[corev1.ResourceName]string{
+						corev1.ResourceCPU: "2",
+					}),
+				},
+			},
+			clusterQueue: cache.ClusterQueue{
+				RequestableResources: map[corev1.ResourceName]*cache.Resource{
+					corev1.ResourceCPU: {
+						Flavors: []cache.FlavorLimits{
+							{
+								Name: "one",
+								Min:  2000,
+							},
+						},
+					},
+				},
+				UsedResources: cache.ResourceQuantities{
+					corev1.ResourceCPU: {"one": 2_000},
+				},
+				Cohort: &cache.Cohort{
+					Name: "child",
+					RequestableResources: cache.ResourceQuantities{
+						corev1.ResourceCPU: {"one": 2_000},
+					},
+					UsedResources: cache.ResourceQuantities{
+						corev1.ResourceCPU: {"one": 2_000},
+					},
+					Parent: &cache.Cohort{
+						Name: "parent",
+						RequestableResources: cache.ResourceQuantities{
+							corev1.ResourceCPU: {"one": 100_000},
+						},
+						UsedResources: cache.ResourceQuantities{
+							corev1.ResourceCPU: {"one": 9_000},
+						},
+						MaxResources: cache.ResourceQuantities{
+							corev1.ResourceCPU: {"one": 10_000},
+						},
+					},
+				},
+			},
+			wantRepMode: ClusterQueuePreempt,
+			wantAssignment: Assignment{
+				PodSets: []PodSetAssignment{{
+					Name: "main",
+					Flavors: ResourceAssignment{
+						corev1.ResourceCPU: {Name: "one", Mode: ClusterQueuePreempt},
+					},
+					Status: &Status{
+						reasons: []string{"borrowing limit for cpu flavor one exceeded in cohort parent"},
+					},
+				}},
+			},
+		},
 		"past min, but can preempt in ClusterQueue": {
 			wlPods: []kueue.PodSet{
 				{
@@ -1011,48 +1330,817 @@ func TestAssignFlavors(t *testing.T) {
 				}},
 			},
 		},
-		"resource not listed in clusterQueue": {
+		"best fit, prefers the flavor with less unused quota left": {
 			wlPods: []kueue.PodSet{
 				{
 					Count: 1,
 					Name:  "main",
 					Spec: utiltesting.PodSpecForRequest(map[corev1.ResourceName]string{
-						"example.com/gpu": "1",
+						corev1.ResourceCPU: "2",
 					}),
 				},
 			},
 			clusterQueue: cache.ClusterQueue{
+				BestFit: true,
 				RequestableResources: map[corev1.ResourceName]*cache.Resource{
 					corev1.ResourceCPU: {
 						Flavors: []cache.FlavorLimits{
-							{Name: "one", Min: 4000},
+							{Name: "one", Min: 10_000},
+							{Name: "two", Min: 4000},
 						},
 					},
 				},
+				UsedResources: cache.ResourceQuantities{
+					corev1.ResourceCPU: {"two": 1_000},
+				},
 			},
+			wantRepMode: Fit,
 			wantAssignment: Assignment{
 				PodSets: []PodSetAssignment{{
 					Name: "main",
-					Status: &Status{
-						reasons: []string{"resource example.com/gpu unavailable in ClusterQueue"},
+					Flavors: ResourceAssignment{
+						corev1.ResourceCPU: {Name: "two", Mode: Fit},
 					},
 				}},
 			},
 		},
-		"flavor not found": {
+		"best fit, prefers the flavor that doesn't borrow": {
 			wlPods: []kueue.PodSet{
 				{
 					Count: 1,
 					Name:  "main",
 					Spec: utiltesting.PodSpecForRequest(map[corev1.ResourceName]string{
-						corev1.ResourceCPU: "1",
+						corev1.ResourceCPU: "2",
 					}),
 				},
 			},
 			clusterQueue: cache.ClusterQueue{
+				BestFit: true,
 				RequestableResources: map[corev1.ResourceName]*cache.Resource{
-					corev1.ResourceCPU: {Flavors: []cache.FlavorLimits{{Name: "nonexistent-flavor", Min: 1000}}},
-				},
+					corev1.ResourceCPU: {
+						Flavors: []cache.FlavorLimits{
+							{Name: "one", Min: 1000},
+							{Name: "two", Min: 10_000},
+						},
+					},
+				},
+				Cohort: &cache.Cohort{
+					RequestableResources: cache.ResourceQuantities{
+						corev1.ResourceCPU: {"one": 10_000, "two": 10_000},
+					},
+				},
+			},
+			wantRepMode: Fit,
+			wantAssignment: Assignment{
+				PodSets: []PodSetAssignment{{
+					Name: "main",
+					Flavors: ResourceAssignment{
+						corev1.ResourceCPU: {Name: "two", Mode: Fit},
+					},
+				}},
+			},
+		},
+		"lowest cost, prefers the cheapest flavor": {
+			wlPods: []kueue.PodSet{
+				{
+					Count: 1,
+					Name:  "main",
+					Spec: utiltesting.PodSpecForRequest(map[corev1.ResourceName]string{
+						corev1.ResourceCPU: "2",
+					}),
+				},
+			},
+			clusterQueue: cache.ClusterQueue{
+				LowestCost: true,
+				RequestableResources: map[corev1.ResourceName]*cache.Resource{
+					corev1.ResourceCPU: {
+						Flavors: []cache.FlavorLimits{
+							{Name: "one", Min: 10_000},
+							{Name: "two", Min: 10_000},
+						},
+					},
+				},
+			},
+			wantRepMode: Fit,
+			wantAssignment: Assignment{
+				PodSets: []PodSetAssignment{{
+					Name: "main",
+					Flavors: ResourceAssignment{
+						corev1.ResourceCPU: {Name: "two", Mode: Fit},
+					},
+				}},
+			},
+		},
+		"lowest cost, prefers the flavors with a cost": {
+			wlPods: []kueue.PodSet{
+				{
+					Count: 1,
+					Name:  "main",
+					Spec: utiltesting.PodSpecForRequest(map[corev1.ResourceName]string{
+						corev1.ResourceCPU: "2",
+					}),
+				},
+			},
+			clusterQueue: cache.ClusterQueue{
+				LowestCost: true,
+				RequestableResources: map[corev1.ResourceName]*cache.Resource{
+					corev1.ResourceCPU: {
+						Flavors: []cache.FlavorLimits{
+							{Name: "default", Min: 10_000},
+							{Name: "one", Min: 10_000},
+						},
+					},
+				},
+			},
+			wantRepMode: Fit,
+			wantAssignment: Assignment{
+				PodSets: []PodSetAssignment{{
+					Name: "main",
+					Flavors: ResourceAssignment{
+						corev1.ResourceCPU: {Name: "one", Mode: Fit},
+					},
+				}},
+			},
+		},
+		"lowest cost, assigns the cheapest flavor in which the workload fits": {
+			wlPods: []kueue.PodSet{
+				{
+					Count: 1,
+					Name:  "main",
+					Spec: utiltesting.PodSpecForRequest(map[corev1.ResourceName]string{
+						corev1.ResourceCPU: "2",
+					}),
+				},
+			},
+			clusterQueue: cache.ClusterQueue{
+				LowestCost: true,
+				RequestableResources: map[corev1.ResourceName]*cache.Resource{
+					corev1.ResourceCPU: {
+						Flavors: []cache.FlavorLimits{
+							{Name: "one", Min: 10_000},
+							{Name: "two", Min: 1000},
+						},
+					},
+				},
+			},
+			wantRepMode: Fit,
+			wantAssignment: Assignment{
+				PodSets: []PodSetAssignment{{
+					Name: "main",
+					Flavors: ResourceAssignment{
+						corev1.ResourceCPU: {Name: "one", Mode: Fit},
+					},
+				}},
+			},
+		},
+		"skips the flavor on hold": {
+			wlPods: []kueue.PodSet{
+				{
+					Count: 1,
+					Name:  "main",
+					Spec: utiltesting.PodSpecForRequest(map[corev1.ResourceName]string{
+						corev1.ResourceCPU: "2",
+					}),
+				},
+			},
+			clusterQueue: cache.ClusterQueue{
+				RequestableResources: map[corev1.ResourceName]*cache.Resource{
+					corev1.ResourceCPU: {
+						Flavors: []cache.FlavorLimits{
+							{Name: "one", Min: 10_000, Hold: true},
+							{Name: "two", Min: 10_000},
+						},
+					},
+				},
+			},
+			wantRepMode: Fit,
+			wantAssignment: Assignment{
+				PodSets: []PodSetAssignment{{
+					Name: "main",
+					Flavors: ResourceAssignment{
+						corev1.ResourceCPU: {Name: "two", Mode: Fit},
+					},
+				}},
+			},
+		},
+		"skips the flavor being deleted": {
+			wlPods: []kueue.PodSet{
+				{
+					Count: 1,
+					Name:  "main",
+					Spec: utiltesting.PodSpecForRequest(map[corev1.ResourceName]string{
+						corev1.ResourceCPU: "2",
+					}),
+				},
+			},
+			clusterQueue: cache.ClusterQueue{
+				RequestableResources: map[corev1.ResourceName]*cache.Resource{
+					corev1.ResourceCPU: {
+						Flavors: []cache.FlavorLimits{
+							{Name: "deleting", Min: 10_000},
+							{Name: "default", Min: 10_000},
+						},
+					},
+				},
+			},
+			wantRepMode: Fit,
+			wantAssignment: Assignment{
+				PodSets: []PodSetAssignment{{
+					Name: "main",
+					Flavors: ResourceAssignment{
+						corev1.ResourceCPU: {Name: "default", Mode: Fit},
+					},
+				}},
+			},
+		},
+		"doesn't fit when the only flavor is on hold": {
+			wlPods: []kueue.PodSet{
+				{
+					Count: 1,
+					Name:  "main",
+					Spec: utiltesting.PodSpecForRequest(map[corev1.ResourceName]string{
+						corev1.ResourceCPU: "2",
+					}),
+				},
+			},
+			clusterQueue: cache.ClusterQueue{
+				RequestableResources: map[corev1.ResourceName]*cache.Resource{
+					corev1.ResourceCPU: {
+						Flavors: []cache.FlavorLimits{
+							{Name: "one", Min: 10_000, Hold: true},
+						},
+					},
+				},
+			},
+			wantAssignment: Assignment{
+				PodSets: []PodSetAssignment{{
+					Name: "main",
+					Status: &Status{
+						reasons: []string{"flavor one is on hold"},
+					},
+				}},
+			},
+		},
+		"skips the flavor with an exhausted budget": {
+			wlPods: []kueue.PodSet{
+				{
+					Count: 1,
+					Name:  "main",
+					Spec: utiltesting.PodSpecForRequest(map[corev1.ResourceName]string{
+						corev1.ResourceCPU: "2",
+					}),
+				},
+			},
+			clusterQueue: cache.ClusterQueue{
+				RequestableResources: map[corev1.ResourceName]*cache.Resource{
+					corev1.ResourceCPU: {
+						Flavors: []cache.FlavorLimits{
+							{Name: "one", Min: 10_000},
+							{Name: "two", Min: 10_000},
+						},
+					},
+				},
+				ExhaustedBudgets: map[corev1.ResourceName]sets.String{
+					corev1.ResourceCPU: sets.NewString("one"),
+				},
+			},
+			wantRepMode: Fit,
+			wantAssignment: Assignment{
+				PodSets: []PodSetAssignment{{
+					Name: "main",
+					Flavors: ResourceAssignment{
+						corev1.ResourceCPU: {Name: "two", Mode: Fit},
+					},
+				}},
+			},
+		},
+		"doesn't fit when the budget of all the flavors is exhausted": {
+			wlPods: []kueue.PodSet{
+				{
+					Count: 1,
+					Name:  "main",
+					Spec: utiltesting.PodSpecForRequest(map[corev1.ResourceName]string{
+						corev1.ResourceCPU: "2",
+					}),
+				},
+			},
+			clusterQueue: cache.ClusterQueue{
+				RequestableResources: map[corev1.ResourceName]*cache.Resource{
+					corev1.ResourceCPU: {
+						Flavors: []cache.FlavorLimits{
+							{Name: "one", Min: 10_000},
+						},
+					},
+				},
+				ExhaustedBudgets: map[corev1.ResourceName]sets.String{
+					corev1.ResourceCPU: sets.NewString(""),
+				},
+			},
+			wantAssignment: Assignment{
+				PodSets: []PodSetAssignment{{
+					Name: "main",
+					Status: &Status{
+						reasons: []string{"budget of cpu in flavor one is exhausted"},
+					},
+				}},
+			},
+		},
+		"doesn't fit in the quota reserved for higher priority tiers": {
+			wlPods: []kueue.PodSet{
+				{
+					Count: 1,
+					Name:  "main",
+					Spec: utiltesting.PodSpecForRequest(map[corev1.ResourceName]string{
+						corev1.ResourceCPU: "2",
+					}),
+				},
+			},
+			clusterQueue: cache.ClusterQueue{
+				RequestableResources: map[corev1.ResourceName]*cache.Resource{
+					corev1.ResourceCPU: {
+						Flavors: []cache.FlavorLimits{
+							{Name: "one", Min: 10_000},
+						},
+					},
+				},
+				UsedResources: cache.ResourceQuantities{
+					corev1.ResourceCPU: {"one": 7_000},
+				},
+				ReservedTiers: []kueue.ReservedTier{{Name: "urgent", MinPriority: 1000, Percent: 20}},
+			},
+			wantRepMode: ClusterQueuePreempt,
+			wantAssignment: Assignment{
+				PodSets: []PodSetAssignment{{
+					Name: "main",
+					Flavors: ResourceAssignment{
+						corev1.ResourceCPU: {Name: "one", Mode: ClusterQueuePreempt},
+					},
+					Status: &Status{
+						reasons: []string{"insufficient unused quota for cpu flavor one, 1 more needed, 2 is reserved for higher priority tiers"},
+					},
+				}},
+			},
+		},
+		"fits in the quota reserved for its tier": {
+			wlPods: []kueue.PodSet{
+				{
+					Count: 1,
+					Name:  "main",
+					Spec: utiltesting.PodSpecForRequest(map[corev1.ResourceName]string{
+						corev1.ResourceCPU: "2",
+					}),
+				},
+			},
+			wlPriority: pointer.Int32(1000),
+			clusterQueue: cache.ClusterQueue{
+				RequestableResources: map[corev1.ResourceName]*cache.Resource{
+					corev1.ResourceCPU: {
+						Flavors: []cache.FlavorLimits{
+							{Name: "one", Min: 10_000},
+						},
+					},
+				},
+				UsedResources: cache.ResourceQuantities{
+					corev1.ResourceCPU: {"one": 7_000},
+				},
+				ReservedTiers: []kueue.ReservedTier{{Name: "urgent", MinPriority: 1000, Percent: 20}},
+			},
+			wantRepMode: Fit,
+			wantAssignment: Assignment{
+				PodSets: []PodSetAssignment{{
+					Name: "main",
+					Flavors: ResourceAssignment{
+						corev1.ResourceCPU: {Name: "one", Mode: Fit},
+					},
+				}},
+			},
+		},
+		"evicted too many times from a flavor, falls back": {
+			wlPods: []kueue.PodSet{
+				{
+					Count: 1,
+					Name:  "main",
+					Spec: utiltesting.PodSpecForRequest(map[corev1.ResourceName]string{
+						corev1.ResourceCPU: "2",
+					}),
+				},
+			},
+			wlEvictions: []kueue.FlavorEvictions{{
+				Flavor: "one",
+				Times: []metav1.Time{
+					metav1.NewTime(evictionTime),
+					metav1.NewTime(evictionTime.Add(20 * time.Minute)),
+					metav1.NewTime(evictionTime.Add(40 * time.Minute)),
+				},
+			}},
+			clusterQueue: cache.ClusterQueue{
+				RequestableResources: map[corev1.ResourceName]*cache.Resource{
+					corev1.ResourceCPU: {
+						Flavors: []cache.FlavorLimits{
+							{Name: "one", Min: 10_000},
+							{Name: "b_one", Min: 10_000},
+							{Name: "two", Min: 10_000},
+						},
+					},
+				},
+				FlavorFallbacks: []kueue.FlavorFallback{{
+					Flavor:         "one",
+					FallbackFlavor: "two",
+					MaxEvictions:   2,
+					Window:         metav1.Duration{Duration: time.Hour},
+				}},
+			},
+			wantRepMode: Fit,
+			wantAssignment: Assignment{
+				PodSets: []PodSetAssignment{{
+					Name: "main",
+					Flavors: ResourceAssignment{
+						corev1.ResourceCPU: {Name: "two", Mode: Fit},
+					},
+				}},
+			},
+		},
+		"evictions from a flavor spread beyond the window": {
+			wlPods: []kueue.PodSet{
+				{
+					Count: 1,
+					Name:  "main",
+					Spec: utiltesting.PodSpecForRequest(map[corev1.ResourceName]string{
+						corev1.ResourceCPU: "2",
+					}),
+				},
+			},
+			wlEvictions: []kueue.FlavorEvictions{{
+				Flavor: "one",
+				Times: []metav1.Time{
+					metav1.NewTime(evictionTime),
+					metav1.NewTime(evictionTime.Add(40 * time.Minute)),
+					metav1.NewTime(evictionTime.Add(80 * time.Minute)),
+				},
+			}},
+			clusterQueue: cache.ClusterQueue{
+				RequestableResources: map[corev1.ResourceName]*cache.Resource{
+					corev1.ResourceCPU: {
+						Flavors: []cache.FlavorLimits{
+							{Name: "one", Min: 10_000},
+							{Name: "two", Min: 10_000},
+						},
+					},
+				},
+				FlavorFallbacks: []kueue.FlavorFallback{{
+					Flavor:         "one",
+					FallbackFlavor: "two",
+					MaxEvictions:   2,
+					Window:         metav1.Duration{Duration: time.Hour},
+				}},
+			},
+			wantRepMode: Fit,
+			wantAssignment: Assignment{
+				PodSets: []PodSetAssignment{{
+					Name: "main",
+					Flavors: ResourceAssignment{
+						corev1.ResourceCPU: {Name: "one", Mode: Fit},
+					},
+				}},
+			},
+		},
+		"scorer prefers the flavor with the highest score": {
+			wlPods: []kueue.PodSet{
+				{
+					Count: 1,
+					Name:  "main",
+					Spec: utiltesting.PodSpecForRequest(map[corev1.ResourceName]string{
+						corev1.ResourceCPU: "2",
+					}),
+				},
+			},
+			clusterQueue: cache.ClusterQueue{
+				RequestableResources: map[corev1.ResourceName]*cache.Resource{
+					corev1.ResourceCPU: {
+						Flavors: []cache.FlavorLimits{
+							{Name: "one", Min: 10_000},
+							{Name: "two", Min: 10_000},
+							{Name: "b_one", Min: 1000},
+						},
+					},
+				},
+			},
+			scorer: func(podSet string, flavor *kueue.ResourceFlavor) int64 {
+				return map[string]int64{"one": 1, "two": 2, "b_one": 3}[flavor.Name]
+			},
+			wantRepMode: Fit,
+			wantAssignment: Assignment{
+				PodSets: []PodSetAssignment{{
+					Name: "main",
+					Flavors: ResourceAssignment{
+						corev1.ResourceCPU: {Name: "two", Mode: Fit},
+					},
+				}},
+			},
+		},
+		"scorer ties are broken by the ClusterQueue policy": {
+			wlPods: []kueue.PodSet{
+				{
+					Count: 1,
+					Name:  "main",
+					Spec: utiltesting.PodSpecForRequest(map[corev1.ResourceName]string{
+						corev1.ResourceCPU: "2",
+					}),
+				},
+			},
+			clusterQueue: cache.ClusterQueue{
+				BestFit: true,
+				RequestableResources: map[corev1.ResourceName]*cache.Resource{
+					corev1.ResourceCPU: {
+						Flavors: []cache.FlavorLimits{
+							{Name: "one", Min: 10_000},
+							{Name: "two", Min: 3000},
+						},
+					},
+				},
+			},
+			scorer: func(podSet string, flavor *kueue.ResourceFlavor) int64 {
+				return 1
+			},
+			wantRepMode: Fit,
+			wantAssignment: Assignment{
+				PodSets: []PodSetAssignment{{
+					Name: "main",
+					Flavors: ResourceAssignment{
+						corev1.ResourceCPU: {Name: "two", Mode: Fit},
+					},
+				}},
+			},
+		},
+		"try next flavor when can borrow, prefers the flavor that doesn't borrow": {
+			wlPods: []kueue.PodSet{
+				{
+					Count: 1,
+					Name:  "main",
+					Spec: utiltesting.PodSpecForRequest(map[corev1.ResourceName]string{
+						corev1.ResourceCPU: "2",
+					}),
+				},
+			},
+			clusterQueue: cache.ClusterQueue{
+				TryNextFlavorWhenCanBorrow: true,
+				RequestableResources: map[corev1.ResourceName]*cache.Resource{
+					corev1.ResourceCPU: {
+						Flavors: []cache.FlavorLimits{
+							{Name: "one", Min: 1000},
+							{Name: "two", Min: 10_000},
+						},
+					},
+				},
+				Cohort: &cache.Cohort{
+					RequestableResources: cache.ResourceQuantities{
+						corev1.ResourceCPU: {"one": 10_000, "two": 10_000},
+					},
+				},
+			},
+			wantRepMode: Fit,
+			wantAssignment: Assignment{
+				PodSets: []PodSetAssignment{{
+					Name: "main",
+					Flavors: ResourceAssignment{
+						corev1.ResourceCPU: {Name: "two", Mode: Fit},
+					},
+				}},
+			},
+		},
+		"try next flavor when can borrow, borrows in the first flavor if none fits without borrowing": {
+			wlPods: []kueue.PodSet{
+				{
+					Count: 1,
+					Name:  "main",
+					Spec: utiltesting.PodSpecForRequest(map[corev1.ResourceName]string{
+						corev1.ResourceCPU: "2",
+					}),
+				},
+			},
+			clusterQueue: cache.ClusterQueue{
+				TryNextFlavorWhenCanBorrow: true,
+				RequestableResources: map[corev1.ResourceName]*cache.Resource{
+					corev1.ResourceCPU: {
+						Flavors: []cache.FlavorLimits{
+							{Name: "one", Min: 1000},
+							{Name: "two", Min: 1000},
+						},
+					},
+				},
+				Cohort: &cache.Cohort{
+					RequestableResources: cache.ResourceQuantities{
+						corev1.ResourceCPU: {"one": 10_000, "two": 10_000},
+					},
+				},
+			},
+			wantRepMode: Fit,
+			wantAssignment: Assignment{
+				PodSets: []PodSetAssignment{{
+					Name: "main",
+					Flavors: ResourceAssignment{
+						corev1.ResourceCPU: {Name: "one", Mode: Fit},
+					},
+				}},
+				TotalBorrow: cache.ResourceQuantities{
+					corev1.ResourceCPU: {"one": 1_000},
+				},
+			},
+		},
+		"preempt when can preempt, stops at the first flavor that needs preemption": {
+			wlPods: []kueue.PodSet{
+				{
+					Count: 1,
+					Name:  "main",
+					Spec: utiltesting.PodSpecForRequest(map[corev1.ResourceName]string{
+						corev1.ResourceCPU: "2",
+					}),
+				},
+			},
+			clusterQueue: cache.ClusterQueue{
+				PreemptWhenCanPreempt: true,
+				RequestableResources: map[corev1.ResourceName]*cache.Resource{
+					corev1.ResourceCPU: {
+						Flavors: []cache.FlavorLimits{
+							{Name: "one", Min: 2000},
+							{Name: "two", Min: 10_000},
+						},
+					},
+				},
+				UsedResources: cache.ResourceQuantities{
+					corev1.ResourceCPU: {"one": 2_000},
+				},
+			},
+			wantRepMode: ClusterQueuePreempt,
+			wantAssignment: Assignment{
+				PodSets: []PodSetAssignment{{
+					Name: "main",
+					Flavors: ResourceAssignment{
+						corev1.ResourceCPU: {Name: "one", Mode: ClusterQueuePreempt},
+					},
+					Status: &Status{
+						reasons: []string{"insufficient unused quota for cpu flavor one, 2 more needed"},
+					},
+				}},
+			},
+		},
+		"try next flavor when can preempt": {
+			wlPods: []kueue.PodSet{
+				{
+					Count: 1,
+					Name:  "main",
+					Spec: utiltesting.PodSpecForRequest(map[corev1.ResourceName]string{
+						corev1.ResourceCPU: "2",
+					}),
+				},
+			},
+			clusterQueue: cache.ClusterQueue{
+				RequestableResources: map[corev1.ResourceName]*cache.Resource{
+					corev1.ResourceCPU: {
+						Flavors: []cache.FlavorLimits{
+							{Name: "one", Min: 2000},
+							{Name: "two", Min: 10_000},
+						},
+					},
+				},
+				UsedResources: cache.ResourceQuantities{
+					corev1.ResourceCPU: {"one": 2_000},
+				},
+			},
+			wantRepMode: Fit,
+			wantAssignment: Assignment{
+				PodSets: []PodSetAssignment{{
+					Name: "main",
+					Flavors: ResourceAssignment{
+						corev1.ResourceCPU: {Name: "two", Mode: Fit},
+					},
+				}},
+			},
+		},
+		"resource not listed in clusterQueue": {
+			wlPods: []kueue.PodSet{
+				{
+					Count: 1,
+					Name:  "main",
+					Spec: utiltesting.PodSpecForRequest(map[corev1.ResourceName]string{
+						"example.com/gpu": "1",
+					}),
+				},
+			},
+			clusterQueue: cache.ClusterQueue{
+				RequestableResources: map[corev1.ResourceName]*cache.Resource{
+					corev1.ResourceCPU: {
+						Flavors: []cache.FlavorLimits{
+							{Name: "one", Min: 4000},
+						},
+					},
+				},
+			},
+			wantAssignment: Assignment{
+				PodSets: []PodSetAssignment{{
+					Name: "main",
+					Status: &Status{
+						reasons: []string{"resource example.com/gpu unavailable in ClusterQueue"},
+					},
+				}},
+			},
+		},
+		"counting quotas, fits": {
+			wlPods: []kueue.PodSet{
+				{
+					Count: 2,
+					Name:  "driver",
+					Spec: utiltesting.PodSpecForRequest(map[corev1.ResourceName]string{
+						corev1.ResourceCPU: "1",
+					}),
+				},
+				{
+					Count: 4,
+					Name:  "workers",
+					Spec: utiltesting.PodSpecForRequest(map[corev1.ResourceName]string{
+						corev1.ResourceCPU: "1",
+					}),
+				},
+			},
+			clusterQueue: cache.ClusterQueue{
+				RequestableResources: map[corev1.ResourceName]*cache.Resource{
+					corev1.ResourceCPU:      {Flavors: []cache.FlavorLimits{{Name: "one", Min: 10000}, {Name: "two", Min: 10000}}},
+					corev1.ResourcePods:     {Flavors: []cache.FlavorLimits{{Name: "one", Min: 4}, {Name: "two", Min: 4}}},
+					kueue.ResourceWorkloads: {Flavors: []cache.FlavorLimits{{Name: "default", Min: 2}}},
+				},
+				UsedResources: cache.ResourceQuantities{
+					corev1.ResourcePods:     {"one": 1},
+					kueue.ResourceWorkloads: {"default": 1},
+				},
+			},
+			wantRepMode: Fit,
+			wantAssignment: Assignment{
+				PodSets: []PodSetAssignment{
+					{
+						Name: "driver",
+						Flavors: ResourceAssignment{
+							corev1.ResourceCPU:      {Name: "one", Mode: Fit},
+							corev1.ResourcePods:     {Name: "one", Mode: Fit},
+							kueue.ResourceWorkloads: {Name: "default", Mode: Fit},
+						},
+					},
+					{
+						Name: "workers",
+						Flavors: ResourceAssignment{
+							corev1.ResourceCPU:  {Name: "two", Mode: Fit},
+							corev1.ResourcePods: {Name: "two", Mode: Fit},
+						},
+					},
+				},
+			},
+		},
+		"counting quotas, too many workloads": {
+			wlPods: []kueue.PodSet{
+				{
+					Count: 1,
+					Name:  "main",
+					Spec: utiltesting.PodSpecForRequest(map[corev1.ResourceName]string{
+						corev1.ResourceCPU: "1",
+					}),
+				},
+			},
+			clusterQueue: cache.ClusterQueue{
+				RequestableResources: map[corev1.ResourceName]*cache.Resource{
+					corev1.ResourceCPU:      {Flavors: []cache.FlavorLimits{{Name: "one", Min: 10000}}},
+					kueue.ResourceWorkloads: {Flavors: []cache.FlavorLimits{{Name: "default", Min: 2}}},
+				},
+				UsedResources: cache.ResourceQuantities{
+					kueue.ResourceWorkloads: {"default": 2},
+				},
+			},
+			wantRepMode: ClusterQueuePreempt,
+			wantAssignment: Assignment{
+				PodSets: []PodSetAssignment{{
+					Name: "main",
+					Flavors: ResourceAssignment{
+						corev1.ResourceCPU:      {Name: "one", Mode: Fit},
+						kueue.ResourceWorkloads: {Name: "default", Mode: ClusterQueuePreempt},
+					},
+					Status: &Status{
+						reasons: []string{"insufficient unused quota for kueue.x-k8s.io/workloads flavor default, 1 more needed"},
+					},
+				}},
+			},
+		},
+		"flavor not found": {
+			wlPods: []kueue.PodSet{
+				{
+					Count: 1,
+					Name:  "main",
+					Spec: utiltesting.PodSpecForRequest(map[corev1.ResourceName]string{
+						corev1.ResourceCPU: "1",
+					}),
+				},
+			},
+			clusterQueue: cache.ClusterQueue{
+				RequestableResources: map[corev1.ResourceName]*cache.Resource{
+					corev1.ResourceCPU: {Flavors: []cache.FlavorLimits{{Name: "nonexistent-flavor", Min: 1000}}},
+				},
 			},
 			wantAssignment: Assignment{
 				PodSets: []PodSetAssignment{{
@@ -1072,17 +2160,99 @@ func TestAssignFlavors(t *testing.T) {
 			tc.clusterQueue.UpdateCodependentResources()
 			wlInfo := workload.NewInfo(&kueue.Workload{
 				Spec: kueue.WorkloadSpec{
-					PodSets: tc.wlPods,
+					PodSets:  tc.wlPods,
+					Priority: tc.wlPriority,
+				},
+				Status: kueue.WorkloadStatus{
+					FlavorEvictions: tc.wlEvictions,
 				},
 			})
 			tc.clusterQueue.UpdateWithFlavors(resourceFlavors)
-			assignment := AssignFlavors(log, wlInfo, resourceFlavors, &tc.clusterQueue)
+			assignment := AssignFlavorsWithScorer(log, wlInfo, resourceFlavors, &tc.clusterQueue, tc.scorer)
 			if repMode := assignment.RepresentativeMode(); repMode != tc.wantRepMode {
 				t.Errorf("e.assignFlavors(_).RepresentativeMode()=%s, want %s", repMode, tc.wantRepMode)
 			}
-			if diff := cmp.Diff(tc.wantAssignment, assignment, cmpopts.IgnoreUnexported(Assignment{}, FlavorAssignment{})); diff != "" {
+			if diff := cmp.Diff(tc.wantAssignment, assignment, cmpopts.IgnoreUnexported(Assignment{}, FlavorAssignment{}), cmpopts.IgnoreFields(Assignment{}, "Usage")); diff != "" {
 				t.Errorf("Unexpected assignment (-want,+got):\n%s", diff)
 			}
+			if tc.wantPodSetFlavors != nil {
+				if diff := cmp.Diff(tc.wantPodSetFlavors, assignment.ToAPI()); diff != "" {
+					t.Errorf("Unexpected flavors in the admission (-want,+got):\n%s", diff)
+				}
+			}
+		})
+	}
+}
+
+func TestAssignSplitFlavors(t *testing.T) {
+	resourceFlavors := map[string]*kueue.ResourceFlavor{
+		"spot":      {ObjectMeta: metav1.ObjectMeta{Name: "spot"}},
+		"on-demand": {ObjectMeta: metav1.ObjectMeta{Name: "on-demand"}},
+	}
+	six, four := int32(6), int32(4)
+	cases := map[string]struct {
+		split             bool
+		onDemandMin       int64
+		wantOK            bool
+		wantPodSetFlavors []kueue.PodSetFlavors
+	}{
+		"splits between two flavors": {
+			split:       true,
+			onDemandMin: 4000,
+			wantOK:      true,
+			wantPodSetFlavors: []kueue.PodSetFlavors{{
+				Name:          "main",
+				Flavors:       map[corev1.ResourceName]string{corev1.ResourceCPU: "spot"},
+				FlavorsReason: "cpu: flavor spot is the first one that fits in the ClusterQueue order",
+				Count:         &six,
+				Splits: []kueue.PodSetSplit{{
+					Flavors: map[corev1.ResourceName]string{corev1.ResourceCPU: "on-demand"},
+					Count:   four,
+				}},
+			}},
+		},
+		"the rest doesn't fit": {
+			split:       true,
+			onDemandMin: 3000,
+		},
+		"splitting disabled": {
+			onDemandMin: 4000,
+		},
+	}
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			log := testr.New(t)
+			cq := cache.ClusterQueue{
+				RequestableResources: map[corev1.ResourceName]*cache.Resource{
+					corev1.ResourceCPU: {Flavors: []cache.FlavorLimits{
+						{Name: "spot", Min: 6000},
+						{Name: "on-demand", Min: tc.onDemandMin},
+					}},
+				},
+				SplitWhenNoFlavorFits: tc.split,
+			}
+			cq.UpdateCodependentResources()
+			cq.UpdateWithFlavors(resourceFlavors)
+			wlInfo := workload.NewInfo(utiltesting.MakeWorkload("wl", "ns").
+				Request(corev1.ResourceCPU, "1").
+				Count(10).
+				Obj())
+			assignment, ok := AssignSplitFlavors(log, wlInfo, resourceFlavors, &cq, nil)
+			if ok != tc.wantOK {
+				t.Fatalf("AssignSplitFlavors(_) returned %t, want %t", ok, tc.wantOK)
+			}
+			if !ok {
+				return
+			}
+			if diff := cmp.Diff(tc.wantPodSetFlavors, assignment.ToAPI()); diff != "" {
+				t.Errorf("Unexpected flavors in the admission (-want,+got):\n%s", diff)
+			}
+			wantUsage := cache.ResourceQuantities{
+				corev1.ResourceCPU: {"spot": 6000, "on-demand": 4000},
+			}
+			if diff := cmp.Diff(wantUsage, assignment.Usage); diff != "" {
+				t.Errorf("Unexpected usage (-want,+got):\n%s", diff)
+			}
 		})
 	}
 }
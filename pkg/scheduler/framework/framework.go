@@ -0,0 +1,149 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package framework defines the extension points of the scheduler. Plugins
+// implementing one or more of the extension points are compiled into the
+// binary and passed to the scheduler, which runs them in the order they were
+// given at each extension point.
+package framework
+
+import (
+	"context"
+	"fmt"
+
+	kueue "sigs.k8s.io/kueue/apis/kueue/v1alpha2"
+	"sigs.k8s.io/kueue/pkg/cache"
+	"sigs.k8s.io/kueue/pkg/scheduler/flavorassigner"
+	"sigs.k8s.io/kueue/pkg/workload"
+)
+
+// Plugin is the parent type of all the scheduler plugins.
+type Plugin interface {
+	// Name returns the name of the plugin, used in logs and messages.
+	Name() string
+}
+
+// PreFilterPlugin is called for each head workload before flavors are
+// assigned to it.
+type PreFilterPlugin interface {
+	Plugin
+	// PreFilter returns an error if the workload can't be admitted by the
+	// ClusterQueue in this cycle. The workload is requeued as inadmissible,
+	// with the error as the reason.
+	PreFilter(ctx context.Context, wl *workload.Info, cq *cache.ClusterQueue) error
+}
+
+// FlavorScorePlugin scores the flavors that fit the resources requested by
+// a pod set.
+type FlavorScorePlugin interface {
+	Plugin
+	// ScoreFlavor returns the score of the flavor for the pod set of the
+	// workload. Among the flavors that fit, the one with the highest total
+	// score of all the plugins is assigned. Ties are broken by the flavor
+	// fungibility policy of the ClusterQueue.
+	ScoreFlavor(ctx context.Context, wl *workload.Info, podSet string, flavor *kueue.ResourceFlavor) int64
+}
+
+// PermitPlugin is called for each workload after its admission is decided,
+// and before it's applied.
+type PermitPlugin interface {
+	Plugin
+	// Permit returns an error if the workload can't be admitted with the
+	// admission. The workload is requeued as inadmissible, with the error as
+	// the reason.
+	Permit(ctx context.Context, wl *kueue.Workload, admission *kueue.Admission) error
+}
+
+// PostAdmitPlugin is called after the admission of a workload is applied in
+// the apiserver.
+type PostAdmitPlugin interface {
+	Plugin
+	// PostAdmit is informational; it can't revert the admission.
+	PostAdmit(ctx context.Context, wl *kueue.Workload)
+}
+
+// Framework holds the plugins of each extension point.
+type Framework struct {
+	preFilter   []PreFilterPlugin
+	flavorScore []FlavorScorePlugin
+	permit      []PermitPlugin
+	postAdmit   []PostAdmitPlugin
+}
+
+// New returns a Framework that runs each plugin at the extension points it
+// implements.
+func New(plugins ...Plugin) *Framework {
+	f := &Framework{}
+	for _, p := range plugins {
+		if pl, ok := p.(PreFilterPlugin); ok {
+			f.preFilter = append(f.preFilter, pl)
+		}
+		if pl, ok := p.(FlavorScorePlugin); ok {
+			f.flavorScore = append(f.flavorScore, pl)
+		}
+		if pl, ok := p.(PermitPlugin); ok {
+			f.permit = append(f.permit, pl)
+		}
+		if pl, ok := p.(PostAdmitPlugin); ok {
+			f.postAdmit = append(f.postAdmit, pl)
+		}
+	}
+	return f
+}
+
+// RunPreFilterPlugins runs the PreFilter plugins until one of them rejects
+// the workload, and returns its error.
+func (f *Framework) RunPreFilterPlugins(ctx context.Context, wl *workload.Info, cq *cache.ClusterQueue) error {
+	for _, pl := range f.preFilter {
+		if err := pl.PreFilter(ctx, wl, cq); err != nil {
+			return fmt.Errorf("rejected by plugin %s: %w", pl.Name(), err)
+		}
+	}
+	return nil
+}
+
+// FlavorScorer returns a scorer that sums the scores of the FlavorScore
+// plugins for the workload, or nil if there are no FlavorScore plugins.
+func (f *Framework) FlavorScorer(ctx context.Context, wl *workload.Info) flavorassigner.FlavorScorer {
+	if len(f.flavorScore) == 0 {
+		return nil
+	}
+	return func(podSet string, flavor *kueue.ResourceFlavor) int64 {
+		var score int64
+		for _, pl := range f.flavorScore {
+			score += pl.ScoreFlavor(ctx, wl, podSet, flavor)
+		}
+		return score
+	}
+}
+
+// RunPermitPlugins runs the Permit plugins until one of them rejects the
+// admission of the workload, and returns its error.
+func (f *Framework) RunPermitPlugins(ctx context.Context, wl *kueue.Workload, admission *kueue.Admission) error {
+	for _, pl := range f.permit {
+		if err := pl.Permit(ctx, wl, admission); err != nil {
+			return fmt.Errorf("rejected by plugin %s: %w", pl.Name(), err)
+		}
+	}
+	return nil
+}
+
+// RunPostAdmitPlugins runs the PostAdmit plugins for the admitted workload.
+func (f *Framework) RunPostAdmitPlugins(ctx context.Context, wl *kueue.Workload) {
+	for _, pl := range f.postAdmit {
+		pl.PostAdmit(ctx, wl)
+	}
+}
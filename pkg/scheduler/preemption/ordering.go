@@ -0,0 +1,205 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package preemption
+
+import (
+	"sort"
+	"time"
+
+	apimeta "k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	config "sigs.k8s.io/kueue/apis/config/v1alpha2"
+	kueue "sigs.k8s.io/kueue/apis/kueue/v1alpha2"
+	"sigs.k8s.io/kueue/pkg/cache"
+	"sigs.k8s.io/kueue/pkg/util/priority"
+	"sigs.k8s.io/kueue/pkg/workload"
+)
+
+// candidate holds the information used to order the Workloads that can be
+// preempted.
+type candidate struct {
+	info       *workload.Info
+	priority   int32
+	admittedAt time.Time
+	// coverage is how much of the requests of the incoming Workload, for the
+	// resources that require preemption, would be freed by preempting the
+	// candidate. Each resource and flavor contributes at most 1.
+	coverage float64
+	// cqShare is the weighted dominant resource share of the ClusterQueue of
+	// the candidate, when fair sharing is enabled.
+	cqShare int
+	// revocable indicates if the candidate was admitted borrowing revocable
+	// quota in another ClusterQueue of the cohort.
+	revocable bool
+}
+
+// comparator returns a negative number if the candidate a should be preempted
+// before b, a positive number if it should be preempted after b, or 0 if the
+// comparator can't decide.
+type comparator func(a, b *candidate) int
+
+// comparators holds the ordering criteria for each victim selection strategy.
+var comparators = map[config.VictimSelectionStrategy]comparator{
+	config.VictimSelectionLowestPriorityFirst:       chain(lowerPriorityFirst, recentlyAdmittedFirst),
+	config.VictimSelectionMostRecentlyAdmittedFirst: chain(recentlyAdmittedFirst, lowerPriorityFirst),
+	config.VictimSelectionFewestVictims:             chain(higherCoverageFirst, lowerPriorityFirst, recentlyAdmittedFirst),
+}
+
+func chain(cmps ...comparator) comparator {
+	return func(a, b *candidate) int {
+		for _, cmp := range cmps {
+			if r := cmp(a, b); r != 0 {
+				return r
+			}
+		}
+		return 0
+	}
+}
+
+func lowerPriorityFirst(a, b *candidate) int {
+	switch {
+	case a.priority < b.priority:
+		return -1
+	case a.priority > b.priority:
+		return 1
+	}
+	return 0
+}
+
+func recentlyAdmittedFirst(a, b *candidate) int {
+	switch {
+	case a.admittedAt.After(b.admittedAt):
+		return -1
+	case a.admittedAt.Before(b.admittedAt):
+		return 1
+	}
+	return 0
+}
+
+func higherCoverageFirst(a, b *candidate) int {
+	switch {
+	case a.coverage > b.coverage:
+		return -1
+	case a.coverage < b.coverage:
+		return 1
+	}
+	return 0
+}
+
+// sortCandidates returns the candidates in the order in which they should be
+// considered for preemption:
+// 0. Revocable Workloads from other ClusterQueues in the cohort.
+// 1. Workloads from other ClusterQueues in the cohort before the ones in the
+// same ClusterQueue as the preemptor.
+// 2. Workloads from ClusterQueues with a higher weighted dominant resource
+// share, as given by cqShares, if not nil.
+// 3. The criteria of the victim selection strategy.
+// 4. The Workload key, to break ties deterministically.
+func sortCandidates(candidates []*workload.Info, cq string, wlReq cache.ResourceQuantities, resPerFlv resourcesPerFlavor, less comparator, cqShares map[string]int, now time.Time) []*workload.Info {
+	cands := make([]candidate, len(candidates))
+	for i, c := range candidates {
+		cands[i] = candidate{
+			info:       c,
+			priority:   priority.Priority(c.Obj),
+			admittedAt: admissionTime(c.Obj, now),
+			coverage:   coverage(c, wlReq, resPerFlv),
+			cqShare:    cqShares[c.ClusterQueue],
+			revocable:  c.ClusterQueue != cq && isRevocable(c),
+		}
+	}
+	sort.Slice(cands, func(i, j int) bool {
+		a := &cands[i]
+		b := &cands[j]
+		if a.revocable != b.revocable {
+			return a.revocable
+		}
+		aInCQ := a.info.ClusterQueue == cq
+		bInCQ := b.info.ClusterQueue == cq
+		if aInCQ != bInCQ {
+			return !aInCQ
+		}
+		if a.cqShare != b.cqShare {
+			return a.cqShare > b.cqShare
+		}
+		if r := less(a, b); r != 0 {
+			return r < 0
+		}
+		return workload.Key(a.info.Obj) < workload.Key(b.info.Obj)
+	})
+	sorted := make([]*workload.Info, len(cands))
+	for i := range cands {
+		sorted[i] = cands[i].info
+	}
+	return sorted
+}
+
+// isRevocable returns whether the workload was admitted with revocable
+// borrowed quota.
+func isRevocable(wl *workload.Info) bool {
+	return wl.Obj.Status.Admission != nil && wl.Obj.Status.Admission.Revocable
+}
+
+// dominantResourceShares returns the weighted dominant resource share of each
+// ClusterQueue in the snapshot.
+func dominantResourceShares(snapshot *cache.Snapshot) map[string]int {
+	shares := make(map[string]int, len(snapshot.ClusterQueues))
+	for name, cq := range snapshot.ClusterQueues {
+		shares[name], _ = cq.DominantResourceShare()
+	}
+	return shares
+}
+
+// coverage returns the sum, over the resources and flavors that require
+// preemption, of the fraction of the incoming Workload requests that the
+// candidate uses, capped at 1 for each resource and flavor.
+func coverage(c *workload.Info, wlReq cache.ResourceQuantities, resPerFlv resourcesPerFlavor) float64 {
+	usage := make(cache.ResourceQuantities)
+	for _, ps := range c.TotalRequests {
+		for res, flv := range ps.Flavors {
+			if !resPerFlv[flv].Has(string(res)) {
+				continue
+			}
+			if usage[res] == nil {
+				usage[res] = make(map[string]int64)
+			}
+			usage[res][flv] += ps.Requests[res]
+		}
+	}
+	var total float64
+	for res, flvs := range usage {
+		for flv, v := range flvs {
+			req := wlReq[res][flv]
+			if req <= 0 || v >= req {
+				total++
+				continue
+			}
+			total += float64(v) / float64(req)
+		}
+	}
+	return total
+}
+
+// admissionTime returns the time when the workload was admitted, or now if
+// the Admitted condition is not set yet.
+func admissionTime(wl *kueue.Workload, now time.Time) time.Time {
+	cond := apimeta.FindStatusCondition(wl.Status.Conditions, kueue.WorkloadAdmitted)
+	if cond == nil || cond.Status != metav1.ConditionTrue {
+		return now
+	}
+	return cond.LastTransitionTime.Time
+}
@@ -0,0 +1,521 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package preemption
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/client-go/tools/record"
+	"k8s.io/klog/v2"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	config "sigs.k8s.io/kueue/apis/config/v1alpha2"
+	kueue "sigs.k8s.io/kueue/apis/kueue/v1alpha2"
+	"sigs.k8s.io/kueue/pkg/cache"
+	"sigs.k8s.io/kueue/pkg/constants"
+	"sigs.k8s.io/kueue/pkg/scheduler/flavorassigner"
+	"sigs.k8s.io/kueue/pkg/util/api"
+	"sigs.k8s.io/kueue/pkg/util/priority"
+	"sigs.k8s.io/kueue/pkg/workload"
+)
+
+type Preemptor struct {
+	client      client.Client
+	recorder    record.EventRecorder
+	victimsLess comparator
+	dryRun      bool
+	fairSharing bool
+
+	// Stubs.
+	applyPreemption func(context.Context, *kueue.Workload) error
+}
+
+type options struct {
+	victimSelection config.VictimSelectionStrategy
+	dryRun          bool
+	fairSharing     bool
+}
+
+// Option configures the preemptor.
+type Option func(*options)
+
+// WithVictimSelection sets the heuristic used to order the candidates for
+// preemption.
+func WithVictimSelection(s config.VictimSelectionStrategy) Option {
+	return func(o *options) {
+		o.victimSelection = s
+	}
+}
+
+// WithDryRun indicates if the preemptor only reports the victims, without
+// preempting them.
+func WithDryRun(f bool) Option {
+	return func(o *options) {
+		o.dryRun = f
+	}
+}
+
+// WithFairSharing indicates if the candidates from the ClusterQueues with the
+// highest weighted dominant resource share should be preempted first.
+func WithFairSharing(f bool) Option {
+	return func(o *options) {
+		o.fairSharing = f
+	}
+}
+
+var defaultOptions = options{
+	victimSelection: config.VictimSelectionLowestPriorityFirst,
+}
+
+func New(cl client.Client, recorder record.EventRecorder, opts ...Option) *Preemptor {
+	options := defaultOptions
+	for _, opt := range opts {
+		opt(&options)
+	}
+	victimsLess, ok := comparators[options.victimSelection]
+	if !ok {
+		victimsLess = comparators[config.VictimSelectionLowestPriorityFirst]
+	}
+	p := &Preemptor{
+		client:      cl,
+		recorder:    recorder,
+		victimsLess: victimsLess,
+		dryRun:      options.dryRun,
+		fairSharing: options.fairSharing,
+	}
+	p.applyPreemption = p.applyPreemptionWithSSA
+	return p
+}
+
+// Do tries to preempt workloads to free up the quota that the workload needs
+// in the flavors that were assigned with a mode other than Fit. It returns the
+// number of preempted workloads.
+func (p *Preemptor) Do(ctx context.Context, wl workload.Info, assignment flavorassigner.Assignment, snapshot *cache.Snapshot) (int, error) {
+	targets := p.GetTargets(ctx, wl, assignment, snapshot)
+	if len(targets) == 0 {
+		return 0, nil
+	}
+	cq := snapshot.ClusterQueues[wl.ClusterQueue]
+	if !p.reportVictims(ctx, wl.Obj, cq, targets) {
+		return 0, nil
+	}
+
+	return p.issuePreemptions(ctx, targets, cq.Name)
+}
+
+// GetTargets returns the workloads to preempt so that the workload fits with
+// the assignment, without preempting them. It returns none if preempting the
+// allowed candidates isn't enough for the workload to fit.
+func (p *Preemptor) GetTargets(ctx context.Context, wl workload.Info, assignment flavorassigner.Assignment, snapshot *cache.Snapshot) []*workload.Info {
+	log := ctrl.LoggerFrom(ctx)
+
+	resPerFlv := resourcesRequiringPreemption(assignment)
+	cq := snapshot.ClusterQueues[wl.ClusterQueue]
+
+	candidates := findCandidates(wl.Obj, cq, snapshot, resPerFlv, fitsInMinQuota(assignment), assignment.Usage)
+	if len(candidates) == 0 {
+		log.V(2).Info("Workload requires preemption, but there are no candidate workloads allowed for preemption",
+			"preemption", cq.Preemption)
+		return nil
+	}
+	var cqShares map[string]int
+	if p.fairSharing {
+		cqShares = dominantResourceShares(snapshot)
+	}
+	candidates = sortCandidates(candidates, cq.Name, assignment.Usage, resPerFlv, p.victimsLess, cqShares, time.Now())
+
+	targets := minimalPreemptions(&wl, assignment, snapshot, resPerFlv, candidates)
+	if len(targets) == 0 {
+		log.V(2).Info("Workload requires preemption, but there are not enough candidate workloads allowed for preemption")
+	}
+	return targets
+}
+
+// reportVictims records the victims selected to preempt for the workload, in
+// an event of the workload and in the logs. It returns whether the victims
+// can be preempted, which is not the case in dry-run mode or when there are
+// more victims than the maxVictims of the ClusterQueue, if it sets one.
+func (p *Preemptor) reportVictims(ctx context.Context, wl *kueue.Workload, cq *cache.ClusterQueue, targets []*workload.Info) bool {
+	log := ctrl.LoggerFrom(ctx)
+	victims := make([]string, len(targets))
+	for i, target := range targets {
+		victims[i] = workload.Key(target.Obj)
+	}
+	sort.Strings(victims)
+	if p.dryRun {
+		log.V(2).Info("Not preempting the selected victims in dry-run mode", "victims", victims)
+		p.recorder.Event(wl, corev1.EventTypeNormal, "PreemptionDryRun", api.TruncateEventMessage(
+			fmt.Sprintf("Would preempt %d Workloads: %s", len(victims), strings.Join(victims, ", "))))
+		return false
+	}
+	if maxVictims := cq.Preemption.MaxVictims; maxVictims != nil && len(victims) > int(*maxVictims) {
+		log.V(2).Info("Not preempting the selected victims, they exceed the maxVictims of the ClusterQueue", "victims", victims, "maxVictims", *maxVictims)
+		p.recorder.Event(wl, corev1.EventTypeWarning, "PreemptionBlocked", api.TruncateEventMessage(
+			fmt.Sprintf("Preempting %d Workloads exceeds the maxVictims %d of the ClusterQueue: %s", len(victims), *maxVictims, strings.Join(victims, ", "))))
+		return false
+	}
+	log.V(2).Info("Preempting the selected victims", "victims", victims)
+	p.recorder.Event(wl, corev1.EventTypeNormal, "Preempting", api.TruncateEventMessage(
+		fmt.Sprintf("Preempting %d Workloads: %s", len(victims), strings.Join(victims, ", "))))
+	return true
+}
+
+func (p *Preemptor) issuePreemptions(ctx context.Context, targets []*workload.Info, cqName string) (int, error) {
+	log := ctrl.LoggerFrom(ctx)
+	var errs []error
+	successfulPreemptions := 0
+	for _, target := range targets {
+		origin := "ClusterQueue"
+		if target.ClusterQueue != cqName {
+			origin = "cohort"
+		}
+		msg := fmt.Sprintf("Preempted to accommodate a Workload in the %s", origin)
+		if origin == "cohort" && isRevocable(target) {
+			msg = "Preempted to reclaim the revocable quota borrowed from the cohort"
+		}
+		if err := p.applyPreemption(ctx, target.Obj); err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		if err := workload.Evict(ctx, p.client, target.Obj, kueue.WorkloadEvictedByPreemption, msg); err != nil {
+			log.Error(err, "Could not update Workload status", "targetWorkload", klog.KObj(target.Obj))
+		}
+		log.V(3).Info("Preempted", "targetWorkload", klog.KObj(target.Obj))
+		p.recorder.Event(target.Obj, corev1.EventTypeNormal, kueue.WorkloadEvictedByPreemption, msg)
+		successfulPreemptions++
+	}
+	if len(errs) > 0 {
+		return successfulPreemptions, fmt.Errorf("preempting %d workloads: %w", len(errs), errs[0])
+	}
+	return successfulPreemptions, nil
+}
+
+// applyPreemptionWithSSA removes the admission of the workload. The admission
+// is owned by the scheduler field manager, so an apply without the field
+// clears it.
+func (p *Preemptor) applyPreemptionWithSSA(ctx context.Context, w *kueue.Workload) error {
+	wlCopy := &kueue.Workload{
+		ObjectMeta: metav1.ObjectMeta{
+			UID:       w.UID,
+			Name:      w.Name,
+			Namespace: w.Namespace,
+		},
+		TypeMeta: metav1.TypeMeta{
+			APIVersion: kueue.GroupVersion.String(),
+			Kind:       "Workload",
+		},
+	}
+	return p.client.Status().Patch(ctx, wlCopy, client.Apply, client.FieldOwner(constants.AdmissionName))
+}
+
+// minimalPreemptions implements a heuristic to find a minimal set of Workloads
+// to preempt.
+// The heuristic first removes candidates, in the input order, while their
+// ClusterQueues, or their priority bands within the ClusterQueue of the
+// incoming Workload, are still borrowing resources and while the incoming
+// Workload doesn't fit in the quota.
+// Once the Workload fits, the heuristic tries to add Workloads back, in the
+// reverse order in which they were removed, while the incoming Workload still
+// fits.
+func minimalPreemptions(wl *workload.Info, assignment flavorassigner.Assignment, snapshot *cache.Snapshot, resPerFlv resourcesPerFlavor, candidates []*workload.Info) []*workload.Info {
+	wlReq := assignment.Usage
+	cq := snapshot.ClusterQueues[wl.ClusterQueue]
+	wlBand := cq.PriorityBand(wl.Obj)
+	wlPriority := priority.Priority(wl.Obj)
+
+	// Simulate removing all candidates from the ClusterQueue and cohort.
+	var targets []*workload.Info
+	fits := false
+	for _, candWl := range candidates {
+		candCQ := snapshot.ClusterQueues[candWl.ClusterQueue]
+		if cq != candCQ && !cqIsBorrowing(candCQ, resPerFlv) {
+			continue
+		}
+		if cq == candCQ {
+			if band := cq.PriorityBand(candWl.Obj); band != wlBand && !bandIsBorrowing(cq, band, resPerFlv) {
+				continue
+			}
+		}
+		snapshot.RemoveWorkload(candWl)
+		targets = append(targets, candWl)
+		if workloadFits(wlReq, cq, wlPriority) {
+			fits = true
+			break
+		}
+	}
+	if !fits {
+		restoreSnapshot(snapshot, targets)
+		return nil
+	}
+	targets = fillBackWorkloads(targets, wlReq, cq, wlPriority, snapshot)
+	restoreSnapshot(snapshot, targets)
+	return targets
+}
+
+func fillBackWorkloads(targets []*workload.Info, wlReq cache.ResourceQuantities, cq *cache.ClusterQueue, wlPriority int32, snapshot *cache.Snapshot) []*workload.Info {
+	// In the reverse order, check if any of the workloads can be added back.
+	// The last target is the one that made the workload fit, so it's skipped.
+	for i := len(targets) - 2; i >= 0; i-- {
+		snapshot.AddWorkload(targets[i])
+		if workloadFits(wlReq, cq, wlPriority) {
+			targets = append(targets[:i], targets[i+1:]...)
+		} else {
+			snapshot.RemoveWorkload(targets[i])
+		}
+	}
+	return targets
+}
+
+func restoreSnapshot(snapshot *cache.Snapshot, targets []*workload.Info) {
+	for _, t := range targets {
+		snapshot.AddWorkload(t)
+	}
+}
+
+// fitsInMinQuota returns whether all the resources that require preemption
+// fit within the min quota of the ClusterQueue, so that the quota can be
+// reclaimed from the cohort.
+func fitsInMinQuota(assignment flavorassigner.Assignment) bool {
+	for _, ps := range assignment.PodSets {
+		for _, flvAssignment := range ps.Flavors {
+			if flvAssignment.Mode != flavorassigner.Fit && flvAssignment.Mode != flavorassigner.CohortReclaim {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// resourcesPerFlavor holds, for each flavor, the resources that require
+// preemption.
+type resourcesPerFlavor map[string]sets.String
+
+func resourcesRequiringPreemption(assignment flavorassigner.Assignment) resourcesPerFlavor {
+	resPerFlv := make(resourcesPerFlavor)
+	for _, ps := range assignment.PodSets {
+		for res, flvAssignment := range ps.Flavors {
+			if flvAssignment.Mode == flavorassigner.Fit {
+				continue
+			}
+			if resPerFlv[flvAssignment.Name] == nil {
+				resPerFlv[flvAssignment.Name] = sets.NewString()
+			}
+			resPerFlv[flvAssignment.Name].Insert(string(res))
+		}
+	}
+	return resPerFlv
+}
+
+// findCandidates obtains candidates for preemption within the ClusterQueue and
+// cohort that respect the preemption policies and are using the resources
+// that the preempting workload needs.
+// Workloads from the cohort are only considered when the preempting workload
+// fits within the min quota of the ClusterQueue. Revocable workloads from the
+// cohort are considered regardless of the reclaimWithinCohort policy and of
+// their priority.
+func findCandidates(wl *kueue.Workload, cq *cache.ClusterQueue, snapshot *cache.Snapshot, resPerFlv resourcesPerFlavor, fitsInMin bool, wlReq cache.ResourceQuantities) []*workload.Info {
+	var candidates []*workload.Info
+	wlPriority := priority.Priority(wl)
+
+	if len(cq.PriorityBands) > 0 {
+		candidates = append(candidates, priorityBandCandidates(wl, cq, resPerFlv, wlReq)...)
+	} else if cq.Preemption.WithinClusterQueue == kueue.PreemptionPolicyLowerPriority {
+		for _, candidateWl := range cq.Workloads {
+			if priority.Priority(candidateWl.Obj) >= wlPriority {
+				continue
+			}
+			if !workloadUsesResources(candidateWl, resPerFlv) {
+				continue
+			}
+			candidates = append(candidates, candidateWl)
+		}
+	}
+
+	if cq.Cohort != nil && fitsInMin {
+		reclaim := cq.Preemption.ReclaimWithinCohort
+		canReclaim := reclaim == kueue.PreemptionPolicyLowerPriority || reclaim == kueue.PreemptionPolicyAny
+		onlyLowerPriority := reclaim == kueue.PreemptionPolicyLowerPriority
+		for _, cohortCQ := range snapshot.ClusterQueues {
+			if cohortCQ == cq || cohortCQ.Cohort == nil || cohortCQ.Cohort.Root() != cq.Cohort.Root() || !cqIsBorrowing(cohortCQ, resPerFlv) {
+				// Can't reclaim quota from ClusterQueues that are not borrowing.
+				continue
+			}
+			for _, candidateWl := range cohortCQ.Workloads {
+				if !isRevocable(candidateWl) && (!canReclaim || onlyLowerPriority && priority.Priority(candidateWl.Obj) >= wlPriority) {
+					continue
+				}
+				if !workloadUsesResources(candidateWl, resPerFlv) {
+					continue
+				}
+				candidates = append(candidates, candidateWl)
+			}
+		}
+	}
+	return candidates
+}
+
+// priorityBandCandidates obtains the candidates for preemption within a
+// ClusterQueue that splits its quota in priority bands. The workloads of the
+// band of the preempting workload follow the withinClusterQueue policy. The
+// workloads of other bands are only considered while their band uses more than
+// its guaranteed quota, and, unless the preempting workload fits in the
+// guaranteed quota of its own band, when the policy allows preempting them for
+// their lower priority.
+func priorityBandCandidates(wl *kueue.Workload, cq *cache.ClusterQueue, resPerFlv resourcesPerFlavor, wlReq cache.ResourceQuantities) []*workload.Info {
+	var candidates []*workload.Info
+	wlPriority := priority.Priority(wl)
+	wlBand := cq.PriorityBand(wl)
+	lowerPriority := cq.Preemption.WithinClusterQueue == kueue.PreemptionPolicyLowerPriority
+	reclaim := fitsInBandQuota(wlReq, cq, wlBand)
+	borrowing := make(map[string]bool, len(cq.PriorityBands))
+	for _, candidateWl := range cq.Workloads {
+		if !workloadUsesResources(candidateWl, resPerFlv) {
+			continue
+		}
+		isLower := lowerPriority && priority.Priority(candidateWl.Obj) < wlPriority
+		band := cq.PriorityBand(candidateWl.Obj)
+		if band == wlBand {
+			if !isLower {
+				continue
+			}
+		} else {
+			isBorrowing, ok := borrowing[band]
+			if !ok {
+				isBorrowing = bandIsBorrowing(cq, band, resPerFlv)
+				borrowing[band] = isBorrowing
+			}
+			if !isBorrowing || !reclaim && !isLower {
+				continue
+			}
+		}
+		candidates = append(candidates, candidateWl)
+	}
+	return candidates
+}
+
+// bandIsBorrowing returns whether the priority band uses more than its
+// guaranteed quota in any of the resources that require preemption.
+func bandIsBorrowing(cq *cache.ClusterQueue, band string, resPerFlv resourcesPerFlavor) bool {
+	usage := cq.PriorityBandUsage(band)
+	for flavor, resources := range resPerFlv {
+		for res := range resources {
+			rName := corev1.ResourceName(res)
+			if usage[rName][flavor] > cq.PriorityBandQuota(band, rName, flavor) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// fitsInBandQuota returns whether the workload requests fit in the quota that
+// is guaranteed to the priority band, on top of its current usage.
+func fitsInBandQuota(wlReq cache.ResourceQuantities, cq *cache.ClusterQueue, band string) bool {
+	usage := cq.PriorityBandUsage(band)
+	for rName, flavors := range wlReq {
+		for flavor, v := range flavors {
+			if usage[rName][flavor]+v > cq.PriorityBandQuota(band, rName, flavor) {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+func cqIsBorrowing(cq *cache.ClusterQueue, resPerFlv resourcesPerFlavor) bool {
+	if cq.Cohort == nil {
+		return false
+	}
+	for rName, res := range cq.RequestableResources {
+		for _, flv := range res.Flavors {
+			if resPerFlv[flv.Name].Has(string(rName)) && cq.UsedResources[rName][flv.Name] > flv.Min {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func workloadUsesResources(wl *workload.Info, resPerFlv resourcesPerFlavor) bool {
+	for _, ps := range wl.TotalRequests {
+		for res, flv := range ps.Flavors {
+			if resPerFlv[flv].Has(string(res)) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// workloadFits determines if the workload requests would fit given the
+// requestable resources and simulated usage of the ClusterQueue and its cohort,
+// if it belongs to one, leaving available the quota reserved for the tiers
+// above the priority of the workload, and within the min quota if the
+// workload can't borrow.
+func workloadFits(wlReq cache.ResourceQuantities, cq *cache.ClusterQueue, wlPriority int32) bool {
+	for rName, rReq := range wlReq {
+		res, found := cq.RequestableResources[rName]
+		if !found {
+			return false
+		}
+		for fName, fReq := range rReq {
+			flv := findFlavor(res.Flavors, fName)
+			if flv == nil {
+				return false
+			}
+			used := cq.UsedResources[rName][fName]
+			reserved := cq.ReservedQuota(rName, fName, wlPriority)
+			if cq.Cohort == nil {
+				if used+fReq+reserved > flv.Min {
+					return false
+				}
+				continue
+			}
+			if flv.Max != nil && used+fReq > *flv.Max {
+				return false
+			}
+			if !cq.CanBorrow(wlPriority) && used+fReq > flv.Min {
+				return false
+			}
+			if cq.Cohort.ExceedingMax(rName, fName, fReq) != nil {
+				return false
+			}
+			root := cq.Cohort.Root()
+			if root.UsedResources[rName][fName]+fReq+reserved > root.RequestableResources[rName][fName] {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+func findFlavor(flavors []cache.FlavorLimits, name string) *cache.FlavorLimits {
+	for i := range flavors {
+		if flavors[i].Name == name {
+			return &flavors[i]
+		}
+	}
+	return nil
+}
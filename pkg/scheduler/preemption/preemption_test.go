@@ -0,0 +1,606 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package preemption
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/go-logr/logr/testr"
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/client-go/tools/record"
+	"k8s.io/utils/pointer"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	config "sigs.k8s.io/kueue/apis/config/v1alpha2"
+	kueue "sigs.k8s.io/kueue/apis/kueue/v1alpha2"
+	"sigs.k8s.io/kueue/pkg/cache"
+	"sigs.k8s.io/kueue/pkg/constants"
+	"sigs.k8s.io/kueue/pkg/scheduler/flavorassigner"
+	utiltesting "sigs.k8s.io/kueue/pkg/util/testing"
+	"sigs.k8s.io/kueue/pkg/workload"
+)
+
+func TestPreemption(t *testing.T) {
+	now := time.Now().Truncate(time.Second)
+	flavors := []*kueue.ResourceFlavor{
+		utiltesting.MakeResourceFlavor("default").Obj(),
+	}
+	clusterQueues := []*kueue.ClusterQueue{
+		utiltesting.MakeClusterQueue("standalone").
+			Resource(utiltesting.MakeResource(corev1.ResourceCPU).
+				Flavor(utiltesting.MakeFlavor("default", "6").Obj()).Obj()).
+			Preemption(kueue.ClusterQueuePreemption{
+				WithinClusterQueue: kueue.PreemptionPolicyLowerPriority,
+			}).
+			Obj(),
+		utiltesting.MakeClusterQueue("never").
+			Resource(utiltesting.MakeResource(corev1.ResourceCPU).
+				Flavor(utiltesting.MakeFlavor("default", "6").Obj()).Obj()).
+			Obj(),
+		utiltesting.MakeClusterQueue("c1").
+			Cohort("cohort").
+			Resource(utiltesting.MakeResource(corev1.ResourceCPU).
+				Flavor(utiltesting.MakeFlavor("default", "4").Obj()).Obj()).
+			Preemption(kueue.ClusterQueuePreemption{
+				ReclaimWithinCohort: kueue.PreemptionPolicyLowerPriority,
+				WithinClusterQueue:  kueue.PreemptionPolicyLowerPriority,
+			}).
+			Obj(),
+		utiltesting.MakeClusterQueue("c2").
+			Cohort("cohort").
+			Resource(utiltesting.MakeResource(corev1.ResourceCPU).
+				Flavor(utiltesting.MakeFlavor("default", "4").Obj()).Obj()).
+			Preemption(kueue.ClusterQueuePreemption{
+				ReclaimWithinCohort: kueue.PreemptionPolicyAny,
+			}).
+			Obj(),
+		utiltesting.MakeClusterQueue("fair-a").
+			Cohort("fair").
+			Resource(utiltesting.MakeResource(corev1.ResourceCPU).
+				Flavor(utiltesting.MakeFlavor("default", "4").Obj()).Obj()).
+			Preemption(kueue.ClusterQueuePreemption{
+				ReclaimWithinCohort: kueue.PreemptionPolicyAny,
+			}).
+			Obj(),
+		utiltesting.MakeClusterQueue("fair-b").
+			Cohort("fair").
+			Resource(utiltesting.MakeResource(corev1.ResourceCPU).
+				Flavor(utiltesting.MakeFlavor("default", "2").Obj()).Obj()).
+			FairWeight(resource.MustParse("2")).
+			Obj(),
+		utiltesting.MakeClusterQueue("fair-c").
+			Cohort("fair").
+			Resource(utiltesting.MakeResource(corev1.ResourceCPU).
+				Flavor(utiltesting.MakeFlavor("default", "2").Obj()).Obj()).
+			Obj(),
+		utiltesting.MakeClusterQueue("lender").
+			Cohort("revocable").
+			Resource(utiltesting.MakeResource(corev1.ResourceCPU).
+				Flavor(utiltesting.MakeFlavor("default", "4").Obj()).Obj()).
+			Obj(),
+		utiltesting.MakeClusterQueue("lender-lp").
+			Cohort("revocable").
+			Resource(utiltesting.MakeResource(corev1.ResourceCPU).
+				Flavor(utiltesting.MakeFlavor("default", "2").Obj()).Obj()).
+			Preemption(kueue.ClusterQueuePreemption{
+				ReclaimWithinCohort: kueue.PreemptionPolicyLowerPriority,
+			}).
+			Obj(),
+		utiltesting.MakeClusterQueue("borrower").
+			Cohort("revocable").
+			Resource(utiltesting.MakeResource(corev1.ResourceCPU).
+				Flavor(utiltesting.MakeFlavor("default", "2").Obj()).Obj()).
+			RevocableBorrowing(true).
+			Obj(),
+		utiltesting.MakeClusterQueue("bands").
+			Resource(utiltesting.MakeResource(corev1.ResourceCPU).
+				Flavor(utiltesting.MakeFlavor("default", "10").Obj()).Obj()).
+			PriorityBand("high", 1000, 7).
+			PriorityBand("low", 0, 3).
+			Obj(),
+		utiltesting.MakeClusterQueue("bands-lp").
+			Resource(utiltesting.MakeResource(corev1.ResourceCPU).
+				Flavor(utiltesting.MakeFlavor("default", "10").Obj()).Obj()).
+			PriorityBand("high", 1000, 7).
+			PriorityBand("low", 0, 3).
+			Preemption(kueue.ClusterQueuePreemption{
+				WithinClusterQueue: kueue.PreemptionPolicyLowerPriority,
+			}).
+			Obj(),
+	}
+	admittedWithCPU := func(name, cq, cpu string, prio int32, admittedAt time.Time) kueue.Workload {
+		return *utiltesting.MakeWorkload(name, "").
+			Request(corev1.ResourceCPU, cpu).
+			Priority(&prio).
+			Admit(utiltesting.MakeAdmission(cq).Flavor(corev1.ResourceCPU, "default").Obj()).
+			Condition(metav1.Condition{
+				Type:               kueue.WorkloadAdmitted,
+				Status:             metav1.ConditionTrue,
+				LastTransitionTime: metav1.NewTime(admittedAt),
+				Reason:             "Admitted",
+			}).
+			Obj()
+	}
+	admitted := func(name, cq string, prio int32, admittedAt time.Time) kueue.Workload {
+		return admittedWithCPU(name, cq, "2", prio, admittedAt)
+	}
+	admittedRevocable := func(name, cq string, prio int32, admittedAt time.Time) kueue.Workload {
+		wl := admittedWithCPU(name, cq, "2", prio, admittedAt)
+		wl.Status.Admission.Revocable = true
+		return wl
+	}
+	cases := map[string]struct {
+		admitted        []kueue.Workload
+		incoming        *kueue.Workload
+		targetCQ        string
+		victimSelection config.VictimSelectionStrategy
+		fairSharing     bool
+		wantPreempted   sets.String
+	}{
+		"preempt lowest priority": {
+			admitted: []kueue.Workload{
+				admitted("low", "standalone", -1, now),
+				admitted("mid", "standalone", 0, now),
+				admitted("high", "standalone", 1, now),
+			},
+			incoming:      utiltesting.MakeWorkload("in", "").Request(corev1.ResourceCPU, "2").Priority(pointer.Int32(1)).Obj(),
+			targetCQ:      "standalone",
+			wantPreempted: sets.NewString("/low"),
+		},
+		"preempt multiple": {
+			admitted: []kueue.Workload{
+				admitted("low", "standalone", -1, now),
+				admitted("mid", "standalone", 0, now),
+				admitted("high", "standalone", 1, now),
+			},
+			incoming:      utiltesting.MakeWorkload("in", "").Request(corev1.ResourceCPU, "4").Priority(pointer.Int32(1)).Obj(),
+			targetCQ:      "standalone",
+			wantPreempted: sets.NewString("/low", "/mid"),
+		},
+		"no preemption for equal or higher priority": {
+			admitted: []kueue.Workload{
+				admitted("mid", "standalone", 0, now),
+				admitted("high", "standalone", 1, now),
+				admitted("higher", "standalone", 2, now),
+			},
+			incoming: utiltesting.MakeWorkload("in", "").Request(corev1.ResourceCPU, "2").Priority(pointer.Int32(0)).Obj(),
+			targetCQ: "standalone",
+		},
+		"not enough low priority workloads": {
+			admitted: []kueue.Workload{
+				admitted("low", "standalone", -1, now),
+				admitted("mid", "standalone", 0, now),
+				admitted("high", "standalone", 1, now),
+			},
+			incoming: utiltesting.MakeWorkload("in", "").Request(corev1.ResourceCPU, "6").Priority(pointer.Int32(1)).Obj(),
+			targetCQ: "standalone",
+		},
+		"preemption disabled": {
+			admitted: []kueue.Workload{
+				admitted("low", "never", -1, now),
+				admitted("mid", "never", 0, now),
+				admitted("high", "never", 1, now),
+			},
+			incoming: utiltesting.MakeWorkload("in", "").Request(corev1.ResourceCPU, "2").Priority(pointer.Int32(1)).Obj(),
+			targetCQ: "never",
+		},
+		"reclaim most recently admitted from the cohort": {
+			admitted: []kueue.Workload{
+				admitted("c1-low", "c1", -1, now),
+				admitted("c2-old", "c2", 0, now.Add(-time.Hour)),
+				admitted("c2-new", "c2", 0, now),
+				admitted("c2-mid", "c2", 0, now.Add(-time.Minute)),
+			},
+			incoming:      utiltesting.MakeWorkload("in", "").Request(corev1.ResourceCPU, "2").Priority(pointer.Int32(1)).Obj(),
+			targetCQ:      "c1",
+			wantPreempted: sets.NewString("/c2-new"),
+		},
+		"reclaim only lower priority from the cohort": {
+			admitted: []kueue.Workload{
+				admitted("c1-high", "c1", 1, now),
+				admitted("c2-1", "c2", 1, now),
+				admitted("c2-2", "c2", 1, now),
+				admitted("c2-3", "c2", 1, now),
+			},
+			incoming: utiltesting.MakeWorkload("in", "").Request(corev1.ResourceCPU, "2").Priority(pointer.Int32(1)).Obj(),
+			targetCQ: "c1",
+		},
+		"reclaim any priority from the cohort": {
+			admitted: []kueue.Workload{
+				admitted("c1-1", "c1", 1, now.Add(-2*time.Hour)),
+				admitted("c1-2", "c1", 1, now.Add(-time.Hour)),
+				admitted("c1-3", "c1", 1, now),
+				admitted("c2", "c2", 1, now),
+			},
+			incoming:      utiltesting.MakeWorkload("in", "").Request(corev1.ResourceCPU, "2").Priority(pointer.Int32(0)).Obj(),
+			targetCQ:      "c2",
+			wantPreempted: sets.NewString("/c1-3"),
+		},
+		"don't reclaim from the cohort beyond the min quota": {
+			admitted: []kueue.Workload{
+				admitted("c1-1", "c1", 0, now),
+				admitted("c1-2", "c1", 0, now),
+				admitted("c1-3", "c1", 0, now),
+				admitted("c2", "c2", 1, now),
+			},
+			incoming: utiltesting.MakeWorkload("in", "").Request(corev1.ResourceCPU, "4").Priority(pointer.Int32(1)).Obj(),
+			targetCQ: "c2",
+		},
+		"prefer preempting from the cohort": {
+			admitted: []kueue.Workload{
+				admitted("c1-1", "c1", -1, now),
+				admitted("c2-1", "c2", -1, now),
+				admitted("c2-2", "c2", -1, now),
+				admitted("c2-3", "c2", -1, now),
+			},
+			incoming:      utiltesting.MakeWorkload("in", "").Request(corev1.ResourceCPU, "2").Priority(pointer.Int32(0)).Obj(),
+			targetCQ:      "c1",
+			wantPreempted: sets.NewString("/c2-1"),
+		},
+		"most recently admitted first": {
+			admitted: []kueue.Workload{
+				admitted("low", "standalone", -1, now.Add(-time.Hour)),
+				admitted("mid", "standalone", 0, now),
+				admitted("high", "standalone", 1, now),
+			},
+			incoming:        utiltesting.MakeWorkload("in", "").Request(corev1.ResourceCPU, "2").Priority(pointer.Int32(1)).Obj(),
+			targetCQ:        "standalone",
+			victimSelection: config.VictimSelectionMostRecentlyAdmittedFirst,
+			wantPreempted:   sets.NewString("/mid"),
+		},
+		"lowest priority first preempts more small workloads": {
+			admitted: []kueue.Workload{
+				admittedWithCPU("small-1", "standalone", "1", -1, now),
+				admittedWithCPU("small-2", "standalone", "1", -1, now),
+				admittedWithCPU("big-1", "standalone", "2", 0, now),
+				admittedWithCPU("big-2", "standalone", "2", 0, now),
+			},
+			incoming:        utiltesting.MakeWorkload("in", "").Request(corev1.ResourceCPU, "4").Priority(pointer.Int32(1)).Obj(),
+			targetCQ:        "standalone",
+			victimSelection: config.VictimSelectionLowestPriorityFirst,
+			wantPreempted:   sets.NewString("/small-1", "/small-2", "/big-1"),
+		},
+		"fewest victims": {
+			admitted: []kueue.Workload{
+				admittedWithCPU("small-1", "standalone", "1", -1, now),
+				admittedWithCPU("small-2", "standalone", "1", -1, now),
+				admittedWithCPU("big-1", "standalone", "2", 0, now),
+				admittedWithCPU("big-2", "standalone", "2", 0, now),
+			},
+			incoming:        utiltesting.MakeWorkload("in", "").Request(corev1.ResourceCPU, "4").Priority(pointer.Int32(1)).Obj(),
+			targetCQ:        "standalone",
+			victimSelection: config.VictimSelectionFewestVictims,
+			wantPreempted:   sets.NewString("/big-1", "/big-2"),
+		},
+		"reclaim from the cohort without fair sharing": {
+			admitted: []kueue.Workload{
+				admitted("b-1", "fair-b", 0, now),
+				admitted("b-2", "fair-b", 0, now),
+				admitted("c-1", "fair-c", 0, now),
+				admittedWithCPU("c-2", "fair-c", "1", 0, now),
+			},
+			incoming:      utiltesting.MakeWorkload("in", "").Request(corev1.ResourceCPU, "2").Obj(),
+			targetCQ:      "fair-a",
+			wantPreempted: sets.NewString("/b-1"),
+		},
+		"reclaim from the ClusterQueue with the highest weighted share": {
+			admitted: []kueue.Workload{
+				admitted("b-1", "fair-b", 0, now),
+				admitted("b-2", "fair-b", 0, now),
+				admitted("c-1", "fair-c", 0, now),
+				admittedWithCPU("c-2", "fair-c", "1", 0, now),
+			},
+			incoming:      utiltesting.MakeWorkload("in", "").Request(corev1.ResourceCPU, "2").Obj(),
+			targetCQ:      "fair-a",
+			fairSharing:   true,
+			wantPreempted: sets.NewString("/c-1"),
+		},
+		"reclaim revocable workloads regardless of the policy": {
+			admitted: []kueue.Workload{
+				admitted("lender-1", "lender", 0, now),
+				admitted("lender-lp-1", "lender-lp", 0, now),
+				admitted("borrower-1", "borrower", 1, now),
+				admittedRevocable("borrower-2", "borrower", 1, now),
+			},
+			incoming:      utiltesting.MakeWorkload("in", "").Request(corev1.ResourceCPU, "2").Priority(pointer.Int32(0)).Obj(),
+			targetCQ:      "lender",
+			wantPreempted: sets.NewString("/borrower-2"),
+		},
+		"reclaim revocable workloads before lower priority": {
+			admitted: []kueue.Workload{
+				admitted("lender-1", "lender", 0, now),
+				admitted("lender-2", "lender", 0, now),
+				admitted("borrower-low", "borrower", -1, now),
+				admittedRevocable("borrower-revocable", "borrower", 1, now),
+			},
+			incoming:      utiltesting.MakeWorkload("in", "").Request(corev1.ResourceCPU, "2").Priority(pointer.Int32(0)).Obj(),
+			targetCQ:      "lender-lp",
+			wantPreempted: sets.NewString("/borrower-revocable"),
+		},
+		"reclaim the guaranteed quota of a priority band": {
+			admitted: []kueue.Workload{
+				admitted("high-1", "bands", 1000, now.Add(-4*time.Minute)),
+				admitted("high-2", "bands", 1000, now.Add(-3*time.Minute)),
+				admitted("high-3", "bands", 1000, now.Add(-2*time.Minute)),
+				admitted("high-4", "bands", 1000, now.Add(-time.Minute)),
+				admitted("high-5", "bands", 1000, now),
+			},
+			incoming:      utiltesting.MakeWorkload("in", "").Request(corev1.ResourceCPU, "2").Priority(pointer.Int32(0)).Obj(),
+			targetCQ:      "bands",
+			wantPreempted: sets.NewString("/high-5"),
+		},
+		"no reclaim beyond the guaranteed quota of a priority band": {
+			admitted: []kueue.Workload{
+				admitted("low", "bands", 0, now),
+				admitted("high-1", "bands", 1000, now),
+				admitted("high-2", "bands", 1000, now),
+				admitted("high-3", "bands", 1000, now),
+				admitted("high-4", "bands", 1000, now),
+			},
+			incoming: utiltesting.MakeWorkload("in", "").Request(corev1.ResourceCPU, "2").Priority(pointer.Int32(0)).Obj(),
+			targetCQ: "bands",
+		},
+		"preempt lower priority from a priority band above its guaranteed quota": {
+			admitted: []kueue.Workload{
+				admitted("low-1", "bands-lp", 0, now),
+				admitted("low-2", "bands-lp", 0, now),
+				admitted("high-1", "bands-lp", 1000, now),
+				admitted("high-2", "bands-lp", 1000, now),
+				admitted("high-3", "bands-lp", 1000, now),
+			},
+			incoming:      utiltesting.MakeWorkload("in", "").Request(corev1.ResourceCPU, "2").Priority(pointer.Int32(1000)).Obj(),
+			targetCQ:      "bands-lp",
+			wantPreempted: sets.NewString("/low-1"),
+		},
+		"no preemption of lower priority from a priority band within its guaranteed quota": {
+			admitted: []kueue.Workload{
+				admitted("low", "bands-lp", 0, now),
+				admitted("high-1", "bands-lp", 1000, now),
+				admitted("high-2", "bands-lp", 1000, now),
+				admitted("high-3", "bands-lp", 1000, now),
+				admitted("high-4", "bands-lp", 1000, now),
+			},
+			incoming: utiltesting.MakeWorkload("in", "").Request(corev1.ResourceCPU, "2").Priority(pointer.Int32(1000)).Obj(),
+			targetCQ: "bands-lp",
+		},
+	}
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			log := testr.New(t)
+			ctx := ctrl.LoggerInto(context.Background(), log)
+			scheme := runtime.NewScheme()
+			if err := kueue.AddToScheme(scheme); err != nil {
+				t.Fatalf("Failed adding kueue scheme: %v", err)
+			}
+			cl := fake.NewClientBuilder().WithScheme(scheme).
+				WithLists(&kueue.WorkloadList{Items: tc.admitted}).
+				Build()
+			cqCache := cache.New(cl)
+			for _, flv := range flavors {
+				cqCache.AddOrUpdateResourceFlavor(flv)
+			}
+			for _, cq := range clusterQueues {
+				if err := cqCache.AddClusterQueue(ctx, cq); err != nil {
+					t.Fatalf("Couldn't add ClusterQueue to cache: %v", err)
+				}
+			}
+
+			broadcaster := record.NewBroadcaster()
+			recorder := broadcaster.NewRecorder(scheme, corev1.EventSource{Component: constants.AdmissionName})
+			preemptor := New(cl, recorder, WithVictimSelection(tc.victimSelection), WithFairSharing(tc.fairSharing))
+
+			var lock sync.Mutex
+			gotPreempted := sets.NewString()
+			preemptor.applyPreemption = func(ctx context.Context, w *kueue.Workload) error {
+				lock.Lock()
+				gotPreempted.Insert(workload.Key(w))
+				lock.Unlock()
+				return nil
+			}
+
+			snapshot := cqCache.Snapshot()
+			wlInfo := workload.NewInfo(tc.incoming)
+			wlInfo.ClusterQueue = tc.targetCQ
+			assignment := flavorassigner.AssignFlavors(log, wlInfo, snapshot.ResourceFlavors, snapshot.ClusterQueues[wlInfo.ClusterQueue])
+			preempted, err := preemptor.Do(ctx, *wlInfo, assignment, &snapshot)
+			if err != nil {
+				t.Fatalf("Failed doing preemption: %v", err)
+			}
+			if diff := cmp.Diff(tc.wantPreempted, gotPreempted, cmpopts.EquateEmpty()); diff != "" {
+				t.Errorf("Issued preemptions (-want,+got):\n%s", diff)
+			}
+			if preempted != tc.wantPreempted.Len() {
+				t.Errorf("Reported %d preemptions, want %d", preempted, tc.wantPreempted.Len())
+			}
+		})
+	}
+}
+
+func TestPreemptionWithoutMaxVictims(t *testing.T) {
+	now := time.Now().Truncate(time.Second)
+	log := testr.New(t)
+	ctx := ctrl.LoggerInto(context.Background(), log)
+	scheme := runtime.NewScheme()
+	if err := kueue.AddToScheme(scheme); err != nil {
+		t.Fatalf("Failed adding kueue scheme: %v", err)
+	}
+	var admitted []kueue.Workload
+	wantPreempted := sets.NewString()
+	for i := 0; i < 12; i++ {
+		name := fmt.Sprintf("low-%d", i)
+		admitted = append(admitted, *utiltesting.MakeWorkload(name, "").
+			Request(corev1.ResourceCPU, "1").
+			Admit(utiltesting.MakeAdmission("cq").Flavor(corev1.ResourceCPU, "default").Obj()).
+			Condition(metav1.Condition{
+				Type:               kueue.WorkloadAdmitted,
+				Status:             metav1.ConditionTrue,
+				LastTransitionTime: metav1.NewTime(now.Add(time.Duration(i) * time.Second)),
+				Reason:             "Admitted",
+			}).
+			Obj())
+		wantPreempted.Insert("/" + name)
+	}
+	cl := fake.NewClientBuilder().WithScheme(scheme).
+		WithLists(&kueue.WorkloadList{Items: admitted}).
+		Build()
+	cqCache := cache.New(cl)
+	cqCache.AddOrUpdateResourceFlavor(utiltesting.MakeResourceFlavor("default").Obj())
+	cq := utiltesting.MakeClusterQueue("cq").
+		Resource(utiltesting.MakeResource(corev1.ResourceCPU).
+			Flavor(utiltesting.MakeFlavor("default", "12").Obj()).Obj()).
+		Preemption(kueue.ClusterQueuePreemption{
+			WithinClusterQueue: kueue.PreemptionPolicyLowerPriority,
+		}).
+		Obj()
+	if err := cqCache.AddClusterQueue(ctx, cq); err != nil {
+		t.Fatalf("Couldn't add ClusterQueue to cache: %v", err)
+	}
+
+	preemptor := New(cl, record.NewFakeRecorder(20))
+	gotPreempted := sets.NewString()
+	preemptor.applyPreemption = func(ctx context.Context, w *kueue.Workload) error {
+		gotPreempted.Insert(workload.Key(w))
+		return nil
+	}
+
+	snapshot := cqCache.Snapshot()
+	wlInfo := workload.NewInfo(utiltesting.MakeWorkload("in", "").Request(corev1.ResourceCPU, "12").Priority(pointer.Int32(1)).Obj())
+	wlInfo.ClusterQueue = cq.Name
+	assignment := flavorassigner.AssignFlavors(log, wlInfo, snapshot.ResourceFlavors, snapshot.ClusterQueues[wlInfo.ClusterQueue])
+	preempted, err := preemptor.Do(ctx, *wlInfo, assignment, &snapshot)
+	if err != nil {
+		t.Fatalf("Failed doing preemption: %v", err)
+	}
+	if diff := cmp.Diff(wantPreempted, gotPreempted); diff != "" {
+		t.Errorf("Issued preemptions (-want,+got):\n%s", diff)
+	}
+	if preempted != wantPreempted.Len() {
+		t.Errorf("Reported %d preemptions, want %d", preempted, wantPreempted.Len())
+	}
+}
+
+func TestPreemptionReportsVictims(t *testing.T) {
+	now := time.Now().Truncate(time.Second)
+	cases := map[string]struct {
+		maxVictims    *int32
+		dryRun        bool
+		wantPreempted sets.String
+		wantEvents    []string
+	}{
+		"maxVictims not set": {
+			wantPreempted: sets.NewString("/low-3", "/low-4"),
+			wantEvents: []string{
+				"Normal Preempting Preempting 2 Workloads: /low-3, /low-4",
+				"Normal Preempted Preempted to accommodate a Workload in the ClusterQueue",
+				"Normal Preempted Preempted to accommodate a Workload in the ClusterQueue",
+			},
+		},
+		"victims exceed maxVictims": {
+			maxVictims: pointer.Int32(1),
+			wantEvents: []string{
+				"Warning PreemptionBlocked Preempting 2 Workloads exceeds the maxVictims 1 of the ClusterQueue: /low-3, /low-4",
+			},
+		},
+		"dry run": {
+			dryRun: true,
+			wantEvents: []string{
+				"Normal PreemptionDryRun Would preempt 2 Workloads: /low-3, /low-4",
+			},
+		},
+	}
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			log := testr.New(t)
+			ctx := ctrl.LoggerInto(context.Background(), log)
+			scheme := runtime.NewScheme()
+			if err := kueue.AddToScheme(scheme); err != nil {
+				t.Fatalf("Failed adding kueue scheme: %v", err)
+			}
+			var admitted []kueue.Workload
+			for i, name := range []string{"low-1", "low-2", "low-3", "low-4"} {
+				admitted = append(admitted, *utiltesting.MakeWorkload(name, "").
+					Request(corev1.ResourceCPU, "1").
+					Admit(utiltesting.MakeAdmission("cq").Flavor(corev1.ResourceCPU, "default").Obj()).
+					Condition(metav1.Condition{
+						Type:               kueue.WorkloadAdmitted,
+						Status:             metav1.ConditionTrue,
+						LastTransitionTime: metav1.NewTime(now.Add(time.Duration(i) * time.Second)),
+						Reason:             "Admitted",
+					}).
+					Obj())
+			}
+			cl := fake.NewClientBuilder().WithScheme(scheme).
+				WithLists(&kueue.WorkloadList{Items: admitted}).
+				Build()
+			cqCache := cache.New(cl)
+			cqCache.AddOrUpdateResourceFlavor(utiltesting.MakeResourceFlavor("default").Obj())
+			cq := utiltesting.MakeClusterQueue("cq").
+				Resource(utiltesting.MakeResource(corev1.ResourceCPU).
+					Flavor(utiltesting.MakeFlavor("default", "4").Obj()).Obj()).
+				Preemption(kueue.ClusterQueuePreemption{
+					WithinClusterQueue: kueue.PreemptionPolicyLowerPriority,
+					MaxVictims:         tc.maxVictims,
+				}).
+				Obj()
+			if err := cqCache.AddClusterQueue(ctx, cq); err != nil {
+				t.Fatalf("Couldn't add ClusterQueue to cache: %v", err)
+			}
+
+			recorder := record.NewFakeRecorder(10)
+			preemptor := New(cl, recorder, WithDryRun(tc.dryRun))
+			gotPreempted := sets.NewString()
+			preemptor.applyPreemption = func(ctx context.Context, w *kueue.Workload) error {
+				gotPreempted.Insert(workload.Key(w))
+				return nil
+			}
+
+			snapshot := cqCache.Snapshot()
+			wlInfo := workload.NewInfo(utiltesting.MakeWorkload("in", "").Request(corev1.ResourceCPU, "2").Priority(pointer.Int32(1)).Obj())
+			wlInfo.ClusterQueue = cq.Name
+			assignment := flavorassigner.AssignFlavors(log, wlInfo, snapshot.ResourceFlavors, snapshot.ClusterQueues[wlInfo.ClusterQueue])
+			preempted, err := preemptor.Do(ctx, *wlInfo, assignment, &snapshot)
+			if err != nil {
+				t.Fatalf("Failed doing preemption: %v", err)
+			}
+			if diff := cmp.Diff(tc.wantPreempted, gotPreempted, cmpopts.EquateEmpty()); diff != "" {
+				t.Errorf("Issued preemptions (-want,+got):\n%s", diff)
+			}
+			if preempted != tc.wantPreempted.Len() {
+				t.Errorf("Reported %d preemptions, want %d", preempted, tc.wantPreempted.Len())
+			}
+			close(recorder.Events)
+			var gotEvents []string
+			for e := range recorder.Events {
+				gotEvents = append(gotEvents, e)
+			}
+			if diff := cmp.Diff(tc.wantEvents, gotEvents); diff != "" {
+				t.Errorf("Unexpected events (-want,+got):\n%s", diff)
+			}
+		})
+	}
+}
@@ -0,0 +1,322 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package rebalancer
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	apimeta "k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/tools/record"
+	"k8s.io/klog/v2"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	config "sigs.k8s.io/kueue/apis/config/v1alpha2"
+	kueue "sigs.k8s.io/kueue/apis/kueue/v1alpha2"
+	"sigs.k8s.io/kueue/pkg/cache"
+	"sigs.k8s.io/kueue/pkg/queue"
+	"sigs.k8s.io/kueue/pkg/workload"
+)
+
+const (
+	// ReasonEvicted is the reason used in the Admitted condition and in the
+	// event of a Workload evicted to restore the balance of a cohort.
+	ReasonEvicted = "Evicted"
+)
+
+// Rebalancer periodically looks for cohorts in which a ClusterQueue with
+// pending Workloads can't use its min quota because it's borrowed by other
+// ClusterQueues. When the imbalance is sustained, it evicts the most recently
+// admitted Workloads from the ClusterQueue that borrows the most.
+type Rebalancer struct {
+	client          client.Client
+	cache           *cache.Cache
+	queues          *queue.Manager
+	recorder        record.EventRecorder
+	period          time.Duration
+	threshold       int64
+	sustainedPeriod time.Duration
+	maxEvictions    int
+
+	// imbalancedSince holds the time since when each cohort is imbalanced.
+	imbalancedSince map[string]time.Time
+}
+
+type options struct {
+	period          time.Duration
+	threshold       int32
+	sustainedPeriod time.Duration
+	maxEvictions    int32
+}
+
+// Option configures the rebalancer.
+type Option func(*options)
+
+// WithPeriod sets the interval between checks of the cohorts.
+func WithPeriod(d time.Duration) Option {
+	return func(o *options) {
+		o.period = d
+	}
+}
+
+// WithImbalanceThreshold sets the percentage of the min quota of a
+// ClusterQueue with pending Workloads that has to be used by other
+// ClusterQueues for the cohort to be considered imbalanced.
+func WithImbalanceThreshold(t int32) Option {
+	return func(o *options) {
+		o.threshold = t
+	}
+}
+
+// WithSustainedPeriod sets how long a cohort has to stay imbalanced before
+// Workloads are evicted.
+func WithSustainedPeriod(d time.Duration) Option {
+	return func(o *options) {
+		o.sustainedPeriod = d
+	}
+}
+
+// WithMaxEvictionsPerCohort sets the maximum number of Workloads evicted from
+// a cohort at a time.
+func WithMaxEvictionsPerCohort(n int32) Option {
+	return func(o *options) {
+		o.maxEvictions = n
+	}
+}
+
+// defaultOptions are the options of the default cohortRebalancing
+// configuration.
+var defaultOptions = func() options {
+	cfg := config.Configuration{CohortRebalancing: &config.CohortRebalancing{}}
+	config.SetDefaults_Configuration(&cfg)
+	return options{
+		period:          cfg.CohortRebalancing.Period.Duration,
+		threshold:       *cfg.CohortRebalancing.ImbalanceThreshold,
+		sustainedPeriod: cfg.CohortRebalancing.SustainedPeriod.Duration,
+		maxEvictions:    *cfg.CohortRebalancing.MaxEvictionsPerCohort,
+	}
+}()
+
+func New(cl client.Client, cache *cache.Cache, queues *queue.Manager, recorder record.EventRecorder, opts ...Option) *Rebalancer {
+	options := defaultOptions
+	for _, opt := range opts {
+		opt(&options)
+	}
+	return &Rebalancer{
+		client:          cl,
+		cache:           cache,
+		queues:          queues,
+		recorder:        recorder,
+		period:          options.period,
+		threshold:       int64(options.threshold),
+		sustainedPeriod: options.sustainedPeriod,
+		maxEvictions:    int(options.maxEvictions),
+		imbalancedSince: make(map[string]time.Time),
+	}
+}
+
+func (r *Rebalancer) Start(ctx context.Context) {
+	log := ctrl.LoggerFrom(ctx).WithName("rebalancer")
+	ctx = ctrl.LoggerInto(ctx, log)
+	wait.UntilWithContext(ctx, func(ctx context.Context) {
+		r.rebalance(ctx, time.Now())
+	}, r.period)
+}
+
+// imbalance describes the most starved flavor of a cohort and the
+// ClusterQueue that borrows the most of it.
+type imbalance struct {
+	starvedCQ  string
+	resource   corev1.ResourceName
+	flavor     string
+	starvation int64
+	borrower   *cache.ClusterQueue
+	borrowed   int64
+}
+
+func (r *Rebalancer) rebalance(ctx context.Context, now time.Time) {
+	log := ctrl.LoggerFrom(ctx)
+	snapshot := r.cache.Snapshot()
+
+	cohorts := make(map[string][]*cache.ClusterQueue)
+	for _, cq := range snapshot.ClusterQueues {
+		if cq.Cohort != nil {
+			root := cq.Cohort.Root().Name
+			cohorts[root] = append(cohorts[root], cq)
+		}
+	}
+	for name := range r.imbalancedSince {
+		if _, ok := cohorts[name]; !ok {
+			delete(r.imbalancedSince, name)
+		}
+	}
+
+	for name, members := range cohorts {
+		imb := r.findImbalance(members)
+		if imb == nil || imb.starvation <= r.threshold {
+			delete(r.imbalancedSince, name)
+			continue
+		}
+		since, ok := r.imbalancedSince[name]
+		if !ok {
+			r.imbalancedSince[name] = now
+			since = now
+		}
+		if now.Sub(since) < r.sustainedPeriod {
+			continue
+		}
+		cLog := log.WithValues("cohort", name, "clusterQueue", klog.KRef("", imb.borrower.Name), "resource", imb.resource, "flavor", imb.flavor)
+		evicted := r.evict(ctrl.LoggerInto(ctx, cLog), imb)
+		if evicted > 0 {
+			cLog.V(2).Info("Evicted workloads to restore the balance of the cohort", "count", evicted, "starvedClusterQueue", imb.starvedCQ)
+			delete(r.imbalancedSince, name)
+		}
+	}
+}
+
+// findImbalance returns the flavor with the highest starvation among the
+// ClusterQueues with pending workloads, as long as another member of the
+// cohort is borrowing it.
+func (r *Rebalancer) findImbalance(members []*cache.ClusterQueue) *imbalance {
+	var best *imbalance
+	for _, cq := range members {
+		if r.queues.Pending(&kueue.ClusterQueue{ObjectMeta: metav1.ObjectMeta{Name: cq.Name}}) == 0 {
+			continue
+		}
+		for resName, res := range cq.RequestableResources {
+			for _, flv := range res.Flavors {
+				if flv.Min == 0 {
+					continue
+				}
+				used := cq.UsedResources[resName][flv.Name]
+				if used >= flv.Min {
+					continue
+				}
+				starvation := (flv.Min - used) * 100 / flv.Min
+				if best != nil && (starvation < best.starvation ||
+					(starvation == best.starvation && cq.Name > best.starvedCQ)) {
+					continue
+				}
+				borrower, borrowed := topBorrower(members, resName, flv.Name)
+				if borrower == nil {
+					continue
+				}
+				best = &imbalance{
+					starvedCQ:  cq.Name,
+					resource:   resName,
+					flavor:     flv.Name,
+					starvation: starvation,
+					borrower:   borrower,
+					borrowed:   borrowed,
+				}
+			}
+		}
+	}
+	return best
+}
+
+// topBorrower returns the ClusterQueue that uses the most of the flavor above
+// its min quota, and the amount it borrows.
+func topBorrower(members []*cache.ClusterQueue, resName corev1.ResourceName, flavor string) (*cache.ClusterQueue, int64) {
+	var borrower *cache.ClusterQueue
+	var borrowed int64
+	for _, cq := range members {
+		used := cq.UsedResources[resName][flavor]
+		var min int64
+		if res := cq.RequestableResources[resName]; res != nil {
+			for _, flv := range res.Flavors {
+				if flv.Name == flavor {
+					min = flv.Min
+					break
+				}
+			}
+		}
+		if b := used - min; b > borrowed || (b == borrowed && b > 0 && borrower != nil && cq.Name < borrower.Name) {
+			borrower = cq
+			borrowed = b
+		}
+	}
+	return borrower, borrowed
+}
+
+// evict evicts the most recently admitted workloads of the borrower that use
+// the flavor, until the borrowed amount is released or the maximum number of
+// evictions is reached. It returns the number of evicted workloads.
+func (r *Rebalancer) evict(ctx context.Context, imb *imbalance) int {
+	log := ctrl.LoggerFrom(ctx)
+	candidates := candidatesForEviction(imb.borrower, imb.resource, imb.flavor)
+	evicted := 0
+	var released int64
+	for _, c := range candidates {
+		if evicted >= r.maxEvictions || released >= imb.borrowed {
+			break
+		}
+		msg := fmt.Sprintf("Evicted to return borrowed %s of flavor %s to ClusterQueue %s in the cohort", imb.resource, imb.flavor, imb.starvedCQ)
+		if err := workload.Evict(ctx, r.client, c.info.Obj, ReasonEvicted, msg); err != nil {
+			log.Error(err, "Failed to evict workload", "workload", klog.KObj(c.info.Obj))
+			continue
+		}
+		r.recorder.Event(c.info.Obj, corev1.EventTypeNormal, ReasonEvicted, msg)
+		evicted++
+		released += c.quantity
+	}
+	return evicted
+}
+
+type candidate struct {
+	info       *workload.Info
+	quantity   int64
+	admittedAt time.Time
+}
+
+func candidatesForEviction(cq *cache.ClusterQueue, resName corev1.ResourceName, flavor string) []candidate {
+	var candidates []candidate
+	for _, wl := range cq.Workloads {
+		var quantity int64
+		for _, ps := range wl.TotalRequests {
+			if ps.Flavors[resName] == flavor {
+				quantity += ps.Requests[resName]
+			}
+		}
+		if quantity == 0 {
+			continue
+		}
+		admittedAt := wl.Obj.CreationTimestamp.Time
+		if c := apimeta.FindStatusCondition(wl.Obj.Status.Conditions, kueue.WorkloadAdmitted); c != nil && c.Status == metav1.ConditionTrue {
+			admittedAt = c.LastTransitionTime.Time
+		}
+		candidates = append(candidates, candidate{
+			info:       wl,
+			quantity:   quantity,
+			admittedAt: admittedAt,
+		})
+	}
+	sort.Slice(candidates, func(i, j int) bool {
+		a, b := candidates[i], candidates[j]
+		if !a.admittedAt.Equal(b.admittedAt) {
+			return a.admittedAt.After(b.admittedAt)
+		}
+		return workload.Key(a.info.Obj) < workload.Key(b.info.Obj)
+	})
+	return candidates
+}
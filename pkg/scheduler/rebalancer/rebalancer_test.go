@@ -0,0 +1,175 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package rebalancer
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/go-logr/logr/testr"
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
+	corev1 "k8s.io/api/core/v1"
+	apimeta "k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/tools/record"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	kueue "sigs.k8s.io/kueue/apis/kueue/v1alpha2"
+	"sigs.k8s.io/kueue/pkg/cache"
+	"sigs.k8s.io/kueue/pkg/constants"
+	"sigs.k8s.io/kueue/pkg/queue"
+	utiltesting "sigs.k8s.io/kueue/pkg/util/testing"
+	"sigs.k8s.io/kueue/pkg/workload"
+)
+
+func TestRebalance(t *testing.T) {
+	now := time.Now().Truncate(time.Second)
+	clusterQueues := []kueue.ClusterQueue{
+		*utiltesting.MakeClusterQueue("a").
+			Cohort("cohort").
+			Resource(utiltesting.MakeResource(corev1.ResourceCPU).
+				Flavor(utiltesting.MakeFlavor("default", "4").Obj()).Obj()).
+			Obj(),
+		*utiltesting.MakeClusterQueue("b").
+			Cohort("cohort").
+			Resource(utiltesting.MakeResource(corev1.ResourceCPU).
+				Flavor(utiltesting.MakeFlavor("default", "4").Obj()).Obj()).
+			Obj(),
+	}
+	queues := []kueue.LocalQueue{
+		*utiltesting.MakeLocalQueue("a", "ns").ClusterQueue("a").Obj(),
+		*utiltesting.MakeLocalQueue("b", "ns").ClusterQueue("b").Obj(),
+	}
+	admitted := func(name, cq, cpu string, admittedAt time.Time) kueue.Workload {
+		return *utiltesting.MakeWorkload(name, "ns").
+			Queue(cq).
+			Request(corev1.ResourceCPU, cpu).
+			Admit(utiltesting.MakeAdmission(cq).Flavor(corev1.ResourceCPU, "default").Obj()).
+			Condition(metav1.Condition{
+				Type:               kueue.WorkloadAdmitted,
+				Status:             metav1.ConditionTrue,
+				LastTransitionTime: metav1.NewTime(admittedAt),
+				Reason:             "Admitted",
+			}).
+			Obj()
+	}
+	borrowingWorkloads := []kueue.Workload{
+		admitted("b1", "b", "2", now.Add(-3*time.Hour)),
+		admitted("b2", "b", "3", now.Add(-2*time.Hour)),
+		admitted("b3", "b", "2", now.Add(-time.Hour)),
+	}
+	pending := *utiltesting.MakeWorkload("a1", "ns").Queue("a").Request(corev1.ResourceCPU, "4").Obj()
+
+	cases := map[string]struct {
+		workloads []kueue.Workload
+		opts      []Option
+		// elapsed is the time between the first and the second check.
+		elapsed     time.Duration
+		wantEvicted []string
+	}{
+		"imbalance not sustained": {
+			workloads: append([]kueue.Workload{pending}, borrowingWorkloads...),
+			elapsed:   time.Minute,
+		},
+		"evicts the most recently admitted workload": {
+			workloads:   append([]kueue.Workload{pending}, borrowingWorkloads...),
+			elapsed:     5 * time.Minute,
+			wantEvicted: []string{"ns/b3"},
+		},
+		"evicts until the borrowed quota is released": {
+			workloads:   append([]kueue.Workload{pending}, borrowingWorkloads...),
+			opts:        []Option{WithMaxEvictionsPerCohort(3)},
+			elapsed:     5 * time.Minute,
+			wantEvicted: []string{"ns/b2", "ns/b3"},
+		},
+		"no pending workloads": {
+			workloads: borrowingWorkloads,
+			elapsed:   5 * time.Minute,
+		},
+		"starvation below threshold": {
+			workloads: append([]kueue.Workload{
+				pending,
+				admitted("a2", "a", "3500m", now.Add(-time.Hour)),
+			}, borrowingWorkloads[:2]...),
+			elapsed: 5 * time.Minute,
+		},
+		"no borrowing": {
+			workloads: append([]kueue.Workload{pending}, borrowingWorkloads[:1]...),
+			elapsed:   5 * time.Minute,
+		},
+	}
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			ctx := ctrl.LoggerInto(context.Background(), testr.New(t))
+			scheme := runtime.NewScheme()
+			if err := kueue.AddToScheme(scheme); err != nil {
+				t.Fatalf("Failed adding kueue scheme: %v", err)
+			}
+			cl := fake.NewClientBuilder().WithScheme(scheme).
+				WithLists(&kueue.WorkloadList{Items: tc.workloads}, &kueue.LocalQueueList{Items: queues}).
+				Build()
+			broadcaster := record.NewBroadcaster()
+			recorder := broadcaster.NewRecorder(scheme,
+				corev1.EventSource{Component: constants.AdmissionName})
+			cqCache := cache.New(cl)
+			qManager := queue.NewManager(cl, cqCache)
+			cqCache.AddOrUpdateResourceFlavor(utiltesting.MakeResourceFlavor("default").Obj())
+			for i := range clusterQueues {
+				if err := cqCache.AddClusterQueue(ctx, &clusterQueues[i]); err != nil {
+					t.Fatalf("Inserting clusterQueue %s in cache: %v", clusterQueues[i].Name, err)
+				}
+				if err := qManager.AddClusterQueue(ctx, &clusterQueues[i]); err != nil {
+					t.Fatalf("Inserting clusterQueue %s in manager: %v", clusterQueues[i].Name, err)
+				}
+			}
+			for i := range queues {
+				if err := qManager.AddLocalQueue(ctx, &queues[i]); err != nil {
+					t.Fatalf("Inserting queue %s/%s in manager: %v", queues[i].Namespace, queues[i].Name, err)
+				}
+			}
+
+			r := New(cl, cqCache, qManager, recorder, tc.opts...)
+			r.rebalance(ctx, now)
+			r.rebalance(ctx, now.Add(tc.elapsed))
+
+			var workloads kueue.WorkloadList
+			if err := cl.List(ctx, &workloads); err != nil {
+				t.Fatalf("Listing workloads: %v", err)
+			}
+			var gotEvicted []string
+			for _, wl := range workloads.Items {
+				cond := apimeta.FindStatusCondition(wl.Status.Conditions, kueue.WorkloadAdmitted)
+				if cond != nil && cond.Reason == ReasonEvicted {
+					if wl.Status.Admission != nil {
+						t.Errorf("Workload %s was evicted but still has an admission", workload.Key(&wl))
+					}
+					if !workload.IsEvicted(&wl) {
+						t.Errorf("Workload %s was evicted but doesn't have the Evicted condition", workload.Key(&wl))
+					}
+					gotEvicted = append(gotEvicted, workload.Key(&wl))
+				}
+			}
+			if diff := cmp.Diff(tc.wantEvicted, gotEvicted, cmpopts.SortSlices(func(a, b string) bool { return a < b })); diff != "" {
+				t.Errorf("Unexpected evicted workloads (-want,+got):\n%s", diff)
+			}
+		})
+	}
+}
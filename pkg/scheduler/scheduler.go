@@ -20,11 +20,14 @@ import (
 	"context"
 	"fmt"
 	"sort"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/go-logr/logr"
 	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/errors"
+	apimeta "k8s.io/apimachinery/pkg/api/meta"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/labels"
 	"k8s.io/apimachinery/pkg/types"
@@ -35,12 +38,15 @@ import (
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 
+	config "sigs.k8s.io/kueue/apis/config/v1alpha2"
 	kueue "sigs.k8s.io/kueue/apis/kueue/v1alpha2"
 	"sigs.k8s.io/kueue/pkg/cache"
 	"sigs.k8s.io/kueue/pkg/constants"
 	"sigs.k8s.io/kueue/pkg/metrics"
 	"sigs.k8s.io/kueue/pkg/queue"
 	"sigs.k8s.io/kueue/pkg/scheduler/flavorassigner"
+	"sigs.k8s.io/kueue/pkg/scheduler/framework"
+	"sigs.k8s.io/kueue/pkg/scheduler/preemption"
 	"sigs.k8s.io/kueue/pkg/util/api"
 	"sigs.k8s.io/kueue/pkg/util/routine"
 	"sigs.k8s.io/kueue/pkg/workload"
@@ -56,7 +62,19 @@ type Scheduler struct {
 	client                  client.Client
 	recorder                record.EventRecorder
 	admissionRoutineWrapper routine.Wrapper
+	preemptor               *preemption.Preemptor
+	framework               *framework.Framework
 	waitForPodsReady        bool
+	fairSharing             bool
+
+	// admissions tracks the admissions that are being applied in the apiserver.
+	admissions         sync.WaitGroup
+	admissionsInFlight int32
+
+	// blockingHeads holds, for each StrictFIFO ClusterQueue with a head
+	// blocking timeout, the head that couldn't be admitted in the last cycles
+	// and since when.
+	blockingHeads map[string]blockingHead
 
 	// Stubs.
 	applyAdmission func(context.Context, *kueue.Workload) error
@@ -64,6 +82,10 @@ type Scheduler struct {
 
 type options struct {
 	waitForPodsReady bool
+	victimSelection  config.VictimSelectionStrategy
+	preemptionDryRun bool
+	fairSharing      bool
+	plugins          []framework.Plugin
 }
 
 // Option configures the reconciler.
@@ -77,6 +99,38 @@ func WithWaitForPodsReady(f bool) Option {
 	}
 }
 
+// WithPreemptionVictimSelection sets the strategy used to select the
+// Workloads to preempt.
+func WithPreemptionVictimSelection(s config.VictimSelectionStrategy) Option {
+	return func(o *options) {
+		o.victimSelection = s
+	}
+}
+
+// WithPreemptionDryRun indicates if the scheduler should only report the
+// Workloads to preempt, without evicting them.
+func WithPreemptionDryRun(f bool) Option {
+	return func(o *options) {
+		o.preemptionDryRun = f
+	}
+}
+
+// WithFairSharing indicates if the scheduler should consider the workloads
+// from the ClusterQueues with the lowest dominant resource share first.
+func WithFairSharing(f bool) Option {
+	return func(o *options) {
+		o.fairSharing = f
+	}
+}
+
+// WithPlugins sets the plugins that the scheduler runs at the extension
+// points they implement.
+func WithPlugins(plugins ...framework.Plugin) Option {
+	return func(o *options) {
+		o.plugins = append(o.plugins, plugins...)
+	}
+}
+
 var defaultOptions = options{}
 
 func New(queues *queue.Manager, cache *cache.Cache, cl client.Client, recorder record.EventRecorder, opts ...Option) *Scheduler {
@@ -90,16 +144,29 @@ func New(queues *queue.Manager, cache *cache.Cache, cl client.Client, recorder r
 		client:                  cl,
 		recorder:                recorder,
 		admissionRoutineWrapper: routine.DefaultWrapper,
+		preemptor:               preemption.New(cl, recorder, preemption.WithVictimSelection(options.victimSelection), preemption.WithDryRun(options.preemptionDryRun), preemption.WithFairSharing(options.fairSharing)),
+		framework:               framework.New(options.plugins...),
 		waitForPodsReady:        options.waitForPodsReady,
+		fairSharing:             options.fairSharing,
+		blockingHeads:           make(map[string]blockingHead),
 	}
 	s.applyAdmission = s.applyAdmissionWithSSA
 	return s
 }
 
-func (s *Scheduler) Start(ctx context.Context) {
+// Start runs the scheduling cycles until ctx is done. Then, it waits for the
+// cycle in flight and the admissions it started to be applied in the apiserver,
+// so that the next leader doesn't find half-applied admissions.
+func (s *Scheduler) Start(ctx context.Context) error {
 	log := ctrl.LoggerFrom(ctx).WithName("scheduler")
 	ctx = ctrl.LoggerInto(ctx, log)
+	// Wake up the cycle waiting for heads or for PodsReady when ctx is done.
+	go s.queues.CleanUpOnContext(ctx)
+	go s.cache.CleanUpOnContext(ctx)
 	wait.UntilWithContext(ctx, s.schedule, 0)
+	log.V(2).Info("Waiting for the admissions in flight before shutting down")
+	s.admissions.Wait()
+	return nil
 }
 
 func (s *Scheduler) setAdmissionRoutineWrapper(wrapper routine.Wrapper) {
@@ -118,13 +185,19 @@ func (s *Scheduler) schedule(ctx context.Context) {
 	}
 	startTime := time.Now()
 
+	// Once the heads are popped, the API calls of the cycle are not interrupted
+	// by the termination of the manager, so that the status of the workloads is
+	// left consistent. Instead, no more workloads are admitted.
+	shutdownCtx := ctx
+	ctx = withoutCancel(ctx)
+
 	// 2. Take a snapshot of the cache.
 	snapshot := s.cache.Snapshot()
 
 	// 3. Calculate requirements (resource flavors, borrowing) for admitting workloads.
 	entries := s.nominate(ctx, headWorkloads, snapshot)
 
-	// 4. Sort entries based on borrowing and timestamps.
+	// 4. Sort entries based on borrowing, dominant resource share and timestamps.
 	sort.Sort(entryOrdering(entries))
 
 	// 5. Admit entries, ensuring that no more than one workload gets
@@ -138,19 +211,45 @@ func (s *Scheduler) schedule(ctx context.Context) {
 		if e.assignment.RepresentativeMode() == flavorassigner.NoFit {
 			continue
 		}
+		if shutdownCtx.Err() != nil {
+			e.inadmissibleMsg = "The scheduler is shutting down"
+			continue
+		}
 		c := snapshot.ClusterQueues[e.ClusterQueue]
-		if e.assignment.Borrows() && c.Cohort != nil && usedCohorts.Has(c.Cohort.Name) {
+		if e.assignment.Borrows() && c.Cohort != nil && usedCohorts.Has(c.Cohort.Root().Name) {
 			e.status = skipped
 			e.inadmissibleMsg = "workloads in the cohort that don't require borrowing were prioritized and admitted first"
 			continue
 		}
 		// Even if there was a failure, we shouldn't admit other workloads to this
-		// cohort.
+		// tree of cohorts.
 		if c.Cohort != nil {
-			usedCohorts.Insert(c.Cohort.Name)
+			usedCohorts.Insert(c.Cohort.Root().Name)
 		}
 		if e.assignment.RepresentativeMode() != flavorassigner.Fit {
-			// TODO(#43): Implement preemption.
+			// Admission groups don't preempt other workloads.
+			if len(e.groupMembers) > 0 {
+				continue
+			}
+			preemptionStart := time.Now()
+			reservation := snapshot.RemoveReservation(e.Obj)
+			preempted, err := s.preemptor.Do(ctx, e.Info, e.assignment, &snapshot)
+			if reservation != nil {
+				snapshot.AddReservation(reservation)
+			}
+			e.evaluationTime += time.Since(preemptionStart)
+			if err != nil {
+				log.Error(err, "Failed to preempt workloads", "workload", klog.KObj(e.Obj), "clusterQueue", klog.KRef("", e.ClusterQueue))
+			}
+			if preempted != 0 {
+				e.inadmissibleMsg += fmt.Sprintf(". Pending the preemption of %d workload(s)", preempted)
+				e.requeueReason = queue.RequeueReasonPendingPreemption
+				// Keep the quota released by the preempted workloads for this
+				// workload, until it's evaluated again.
+				if err := s.cache.ReserveQuota(admittedInfo(e).Obj); err != nil {
+					log.Error(err, "Failed to reserve quota", "workload", klog.KObj(e.Obj), "clusterQueue", klog.KRef("", e.ClusterQueue))
+				}
+			}
 			continue
 		}
 		if s.waitForPodsReady {
@@ -158,30 +257,87 @@ func (s *Scheduler) schedule(ctx context.Context) {
 				log.V(5).Info("Waiting for all admitted workloads to be in the PodsReady condition")
 				// Block admission until all currently admitted workloads are in
 				// PodsReady condition if the waitForPodsReady is enabled
-				if err := workload.UpdateStatus(ctx, s.client, e.Obj, kueue.WorkloadAdmitted, metav1.ConditionFalse, "Waiting", "waiting for all admitted workloads to be in PodsReady condition"); err != nil {
+				if err := workload.UpdatePendingStatus(ctx, s.client, e.Obj, kueue.WorkloadReasonWaitingForPodsReady, "waiting for all admitted workloads to be in PodsReady condition"); err != nil {
 					log.Error(err, "Could not update Workload status")
 				}
-				s.cache.WaitForPodsReady(ctx)
+				s.cache.WaitForPodsReady(shutdownCtx)
 				log.V(5).Info("Finished waiting for all admitted workloads to be in the PodsReady condition")
+				if shutdownCtx.Err() != nil {
+					e.inadmissibleMsg = "The scheduler is shutting down"
+					continue
+				}
 			}
 		}
 		e.status = nominated
 		log := log.WithValues("workload", klog.KObj(e.Obj), "clusterQueue", klog.KRef("", e.ClusterQueue))
-		if err := s.admit(ctrl.LoggerInto(ctx, log), e); err != nil {
+		if err := s.admit(ctrl.LoggerInto(ctx, log), e); err != nil && e.status != notNominated {
 			e.inadmissibleMsg = fmt.Sprintf("Failed to admit workload: %v", err)
 		}
 	}
 
-	// 6. Requeue the heads that were not scheduled.
+	// 6. Admit the workloads behind the admitted heads, as long as they fit in
+	// the quota left in their ClusterQueue.
+	for i := range entries {
+		if e := &entries[i]; e.status == assumed {
+			for _, info := range admittedInfos(e) {
+				snapshot.AddWorkload(info)
+			}
+		}
+	}
+	admittedBehindHeads := 0
+	// With waitForPodsReady, only one workload is admitted at a time.
+	if !s.waitForPodsReady {
+		for i := range entries {
+			if shutdownCtx.Err() != nil {
+				break
+			}
+			if e := &entries[i]; e.status == assumed {
+				admittedBehindHeads += s.admitBehindHead(ctx, e, &snapshot, startTime)
+			}
+		}
+	}
+
+	// 7. Backfill the StrictFIFO ClusterQueues whose head couldn't be admitted.
+	backfilled := 0
+	for i := range entries {
+		e := &entries[i]
+		if shutdownCtx.Err() != nil {
+			break
+		}
+		if e.status == assumed || e.assignment.RepresentativeMode() == flavorassigner.Fit || e.requeueReason == queue.RequeueReasonPendingPreemption {
+			continue
+		}
+		cq := snapshot.ClusterQueues[e.ClusterQueue]
+		// The start of an admission group is not estimated.
+		if cq == nil || !cq.Backfill || len(e.groupMembers) > 0 {
+			continue
+		}
+		// The snapshot doesn't include the workloads admitted in this cycle.
+		if cq.Cohort != nil && usedCohorts.Has(cq.Cohort.Root().Name) {
+			continue
+		}
+		backfilled += s.backfill(ctx, e, &snapshot, startTime)
+	}
+
+	// 8. Requeue the heads that were not scheduled.
 	result := metrics.AdmissionResultInadmissible
+	if admittedBehindHeads+backfilled > 0 {
+		result = metrics.AdmissionResultSuccess
+	}
 	for _, e := range entries {
 		log.V(3).Info("Workload evaluated for admission",
 			"workload", klog.KObj(e.Obj),
 			"clusterQueue", klog.KRef("", e.ClusterQueue),
 			"status", e.status,
-			"reason", e.inadmissibleMsg)
+			"reason", e.inadmissibleMsg,
+			"evaluationTime", e.evaluationTime)
+		metrics.WorkloadEvaluated(e.ClusterQueue, e.evaluationTime)
 		if e.status != assumed {
+			s.checkHeadBlockingTimeout(&e, snapshot.ClusterQueues[e.ClusterQueue], startTime)
 			s.requeueAndUpdate(log, ctx, e)
+			if e.requeueReason != queue.RequeueReasonPendingPreemption && s.cache.CancelQuotaReservation(e.Obj) {
+				s.queues.QueueAssociatedInadmissibleWorkloads(ctx, e.Obj)
+			}
 		} else {
 			result = metrics.AdmissionResultSuccess
 		}
@@ -189,6 +345,39 @@ func (s *Scheduler) schedule(ctx context.Context) {
 	metrics.AdmissionAttempt(result, time.Since(startTime))
 }
 
+type blockingHead struct {
+	key   string
+	since time.Time
+}
+
+// checkHeadBlockingTimeout tracks the head of the StrictFIFO ClusterQueue
+// that couldn't be admitted. If the same workload has blocked the head for
+// longer than the timeout of the ClusterQueue, it's requeued as inadmissible,
+// so that the next workload gets a chance.
+func (s *Scheduler) checkHeadBlockingTimeout(e *entry, cq *cache.ClusterQueue, now time.Time) {
+	if cq == nil || !cq.StrictFIFO || cq.HeadBlockingTimeout == 0 {
+		delete(s.blockingHeads, e.ClusterQueue)
+		return
+	}
+	// The head is waiting for the preemption of other workloads, or it's not
+	// blocking the ClusterQueue.
+	if e.requeueReason != queue.RequeueReasonGeneric {
+		return
+	}
+	key := workload.Key(e.Obj)
+	head, ok := s.blockingHeads[e.ClusterQueue]
+	if !ok || head.key != key {
+		s.blockingHeads[e.ClusterQueue] = blockingHead{key: key, since: now}
+		return
+	}
+	if now.Sub(head.since) < cq.HeadBlockingTimeout {
+		return
+	}
+	delete(s.blockingHeads, e.ClusterQueue)
+	e.requeueReason = queue.RequeueReasonHeadBlockingTimeout
+	e.inadmissibleMsg = fmt.Sprintf("%s. Moved to the inadmissible workloads after blocking the head of the ClusterQueue for more than %v", e.inadmissibleMsg, cq.HeadBlockingTimeout)
+}
+
 type entryStatus string
 
 const (
@@ -211,6 +400,32 @@ type entry struct {
 	status          entryStatus
 	inadmissibleMsg string
 	requeueReason   queue.RequeueReason
+	// groupMembers are the other workloads of the admission group of the
+	// workload, which are admitted along with it.
+	groupMembers []workload.Info
+	// revocable indicates if the workload is admitted borrowing quota in a
+	// clusterQueue with revocable borrowing.
+	revocable bool
+	// admissionChecks are the AdmissionChecks of the clusterQueue and of the
+	// assigned flavors, that the workload must pass after its quota is
+	// reserved.
+	admissionChecks []string
+	// dominantResourceShare of the clusterQueue, from its historical usage
+	// if it's tracked, only set if fair sharing is enabled.
+	dominantResourceShare int
+	// evaluationTime is the time spent evaluating the workload in the cycle.
+	evaluationTime time.Duration
+}
+
+// admission returns the admission of the workload in the entry with the given
+// flavors.
+func (e *entry) admission(podSetFlavors []kueue.PodSetFlavors) *kueue.Admission {
+	return &kueue.Admission{
+		ClusterQueue:    kueue.ClusterQueueReference(e.ClusterQueue),
+		PodSetFlavors:   podSetFlavors,
+		Revocable:       e.revocable,
+		AdmissionChecks: e.admissionChecks,
+	}
 }
 
 // nominate returns the workloads with their requirements (resource flavors, borrowing) if
@@ -219,10 +434,13 @@ func (s *Scheduler) nominate(ctx context.Context, workloads []workload.Info, sna
 	log := ctrl.LoggerFrom(ctx)
 	entries := make([]entry, 0, len(workloads))
 	for _, w := range workloads {
+		start := time.Now()
 		log := log.WithValues("workload", klog.KObj(w.Obj), "clusterQueue", klog.KRef("", w.ClusterQueue))
 		cq := snap.ClusterQueues[w.ClusterQueue]
 		ns := corev1.Namespace{}
 		e := entry{Info: w}
+		// The quota reserved for the workload is available to it.
+		reservation := snap.RemoveReservation(w.Obj)
 		if snap.InactiveClusterQueueSets.Has(w.ClusterQueue) {
 			e.inadmissibleMsg = fmt.Sprintf("ClusterQueue %s is inactive", w.ClusterQueue)
 		} else if cq == nil {
@@ -232,39 +450,181 @@ func (s *Scheduler) nominate(ctx context.Context, workloads []workload.Info, sna
 		} else if !cq.NamespaceSelector.Matches(labels.Set(ns.Labels)) {
 			e.inadmissibleMsg = "Workload namespace doesn't match ClusterQueue selector"
 			e.requeueReason = queue.RequeueReasonNamespaceMismatch
+		} else if rName, exceeded := cq.LocalQueueLimitExceeded(&w); exceeded {
+			e.inadmissibleMsg = fmt.Sprintf("Workload exceeds the %s limit of LocalQueue %s", rName, w.Obj.Spec.QueueName)
+		} else if cq.WorkloadsPendingChecksLimitReached() {
+			e.inadmissibleMsg = fmt.Sprintf("ClusterQueue %s reached its limit of %d workloads waiting for admission checks", w.ClusterQueue, *cq.MaxWorkloadsPendingChecks)
+		} else if err := s.framework.RunPreFilterPlugins(ctx, &e.Info, cq); err != nil {
+			e.inadmissibleMsg = api.TruncateEventMessage(fmt.Sprintf("Workload %v", err))
+		} else if _, size, ok := workload.AdmissionGroup(w.Obj); ok {
+			s.nominateGroup(ctx, log, &e, size, snap.ResourceFlavors, cq)
 		} else {
-			e.assignment = flavorassigner.AssignFlavors(log, &e.Info, snap.ResourceFlavors, cq)
+			scorer := s.framework.FlavorScorer(ctx, &e.Info)
+			e.assignment = flavorassigner.AssignFlavorsWithScorer(log, &e.Info, snap.ResourceFlavors, cq, scorer)
 			e.inadmissibleMsg = api.TruncateEventMessage(e.assignment.Message())
+			if e.assignment.RepresentativeMode() == flavorassigner.NoFit {
+				if assignment, ok := flavorassigner.AssignSplitFlavors(log, &e.Info, snap.ResourceFlavors, cq, scorer); ok {
+					e.assignment = assignment
+				}
+			}
+			if _, partial := workload.CanBePartiallyAdmitted(w.Obj); partial && !s.fitsOrPreempts(ctx, &e.Info, e.assignment, &snap) {
+				if assignment, ok := s.assignPartialFlavors(log, ctx, &e.Info, &snap, cq, scorer); ok {
+					e.assignment = assignment
+				}
+			}
+			if s.fairSharing {
+				e.dominantResourceShare = cq.FairShare()
+			}
 		}
+		if cq != nil {
+			e.revocable = cq.RevocableBorrowing && e.assignment.Borrows()
+			e.admissionChecks = cq.AdmissionChecksForFlavors(e.assignment.Flavors())
+		}
+		if reservation != nil {
+			snap.AddReservation(reservation)
+		}
+		e.evaluationTime = time.Since(start)
 		entries = append(entries, e)
 	}
 	return entries
 }
 
+// assignPartialFlavors looks for the largest number of pods, between the
+// minCount and the count of the pod set that supports partial admission, for
+// which the workload fits in the available quota, or fits after preempting
+// other workloads.
+func (s *Scheduler) assignPartialFlavors(log logr.Logger, ctx context.Context, wl *workload.Info, snap *cache.Snapshot, cq *cache.ClusterQueue, scorer flavorassigner.FlavorScorer) (flavorassigner.Assignment, bool) {
+	idx, ok := workload.CanBePartiallyAdmitted(wl.Obj)
+	if !ok {
+		return flavorassigner.Assignment{}, false
+	}
+	ps := &wl.Obj.Spec.PodSets[idx]
+	var best flavorassigner.Assignment
+	var bestCount int32
+	low, high := *ps.MinCount, ps.Count-1
+	for low <= high {
+		count := low + (high-low)/2
+		partial := wl.WithPodSetCount(idx, count)
+		assignment := flavorassigner.AssignFlavorsWithScorer(log, partial, snap.ResourceFlavors, cq, scorer)
+		if s.fitsOrPreempts(ctx, partial, assignment, snap) {
+			best, bestCount = assignment, count
+			low = count + 1
+		} else {
+			high = count - 1
+		}
+	}
+	if bestCount == 0 {
+		return flavorassigner.Assignment{}, false
+	}
+	best.PodSets[idx].Count = &bestCount
+	log.V(3).Info("Workload fits with partial admission", "podSet", ps.Name, "count", bestCount, "mode", best.RepresentativeMode())
+	return best, true
+}
+
+// fitsOrPreempts returns whether the workload fits with the assignment, or
+// whether there are workloads to preempt so that it fits.
+func (s *Scheduler) fitsOrPreempts(ctx context.Context, wl *workload.Info, assignment flavorassigner.Assignment, snap *cache.Snapshot) bool {
+	switch assignment.RepresentativeMode() {
+	case flavorassigner.Fit:
+		return true
+	case flavorassigner.NoFit:
+		return false
+	}
+	return len(s.preemptor.GetTargets(ctx, *wl, assignment, snap)) > 0
+}
+
+// admitBehindHead admits the workloads pending behind the admitted head of a
+// ClusterQueue, in queue order, that fit in the quota left without borrowing.
+// The workloads identical to the last admitted one get its flavors without
+// assigning them again, as long as they fit in the quota left.
+// For StrictFIFO ClusterQueues, it stops at the first workload that doesn't
+// fit. It returns the number of admitted workloads.
+func (s *Scheduler) admitBehindHead(ctx context.Context, head *entry, snapshot *cache.Snapshot, now time.Time) int {
+	log := ctrl.LoggerFrom(ctx).WithValues("clusterQueue", klog.KRef("", head.ClusterQueue))
+	cq := snapshot.ClusterQueues[head.ClusterQueue]
+	// Identical workloads reuse the assignment of the last admitted one.
+	batch := newAdmissionBatch(head, cq)
+	admitted := 0
+	for _, w := range s.queues.SortedPendingWorkloads(head.ClusterQueue) {
+		// The workloads of admission groups are only admitted as heads.
+		if _, _, grouped := workload.AdmissionGroup(w.Obj); grouped || queue.InBackoff(&w, now) {
+			if cq.StrictFIFO {
+				break
+			}
+			continue
+		}
+		var e entry
+		batched := false
+		if batch != nil && batch.matches(&w) {
+			e, batched = s.nominateInBatch(ctx, batch, w, cq)
+		}
+		if !batched {
+			e = s.nominate(ctx, []workload.Info{w}, *snapshot)[0]
+		}
+		if e.assignment.RepresentativeMode() != flavorassigner.Fit || e.assignment.Borrows() {
+			if cq.StrictFIFO {
+				break
+			}
+			continue
+		}
+		e.status = nominated
+		log := log.WithValues("workload", klog.KObj(e.Obj))
+		if err := s.admit(ctrl.LoggerInto(ctx, log), &e); err != nil {
+			if e.status != notNominated {
+				log.Error(err, "Failed to admit workload behind the head")
+				break
+			}
+			log.V(2).Info("Workload behind the head not permitted", "reason", e.inadmissibleMsg)
+			if cq.StrictFIFO {
+				break
+			}
+			continue
+		}
+		log.V(2).Info("Workload admitted behind the head", "head", klog.KObj(head.Obj), "batched", batched)
+		s.queues.DeleteWorkload(e.Obj)
+		snapshot.AddWorkload(admittedInfo(&e))
+		admitted++
+		if !batched {
+			batch = newAdmissionBatch(&e, cq)
+		}
+	}
+	return admitted
+}
+
 // admit sets the admitting clusterQueue and flavors into the workload of
 // the entry, and asynchronously updates the object in the apiserver after
 // assuming it in the cache.
 func (s *Scheduler) admit(ctx context.Context, e *entry) error {
+	if err := s.permit(ctx, e); err != nil {
+		// The workload is requeued as inadmissible.
+		e.status = notNominated
+		e.inadmissibleMsg = api.TruncateEventMessage(fmt.Sprintf("Workload %v", err))
+		return err
+	}
+	if len(e.groupMembers) > 0 {
+		return s.admitGroup(ctx, e)
+	}
 	log := ctrl.LoggerFrom(ctx)
 	newWorkload := e.Obj.DeepCopy()
-	admission := &kueue.Admission{
-		ClusterQueue:  kueue.ClusterQueueReference(e.ClusterQueue),
-		PodSetFlavors: e.assignment.ToAPI(),
-	}
-	newWorkload.Spec.Admission = admission
+	admission := e.admission(e.assignment.ToAPI())
+	newWorkload.Status.Admission = admission
 	if err := s.cache.AssumeWorkload(newWorkload); err != nil {
 		return err
 	}
 	e.status = assumed
 	log.V(2).Info("Workload assumed in the cache")
 
+	s.admissions.Add(1)
+	metrics.ReportInternalQueueDepth(metrics.InternalQueueAdmission, int(atomic.AddInt32(&s.admissionsInFlight, 1)))
+	assumeTime := time.Now()
 	s.admissionRoutineWrapper.Run(func() {
+		defer s.admissions.Done()
 		err := s.applyAdmission(ctx, workloadAdmissionFrom(newWorkload))
+		metrics.InternalQueueLatency(metrics.InternalQueueAdmission, time.Since(assumeTime))
+		metrics.ReportInternalQueueDepth(metrics.InternalQueueAdmission, int(atomic.AddInt32(&s.admissionsInFlight, -1)))
 		if err == nil {
-			waitTime := time.Since(e.Obj.CreationTimestamp.Time)
-			s.recorder.Eventf(newWorkload, corev1.EventTypeNormal, "Admitted", "Admitted by ClusterQueue %v, wait time was %.3fs", admission.ClusterQueue, waitTime.Seconds())
-			metrics.AdmittedWorkload(admission.ClusterQueue, waitTime)
-			log.V(2).Info("Workload successfully admitted and assigned flavors")
+			s.recordAdmission(log, newWorkload)
+			s.framework.RunPostAdmitPlugins(ctx, newWorkload)
 			return
 		}
 		// Ignore errors because the workload or clusterQueue could have been deleted
@@ -276,14 +636,43 @@ func (s *Scheduler) admit(ctx context.Context, e *entry) error {
 		}
 
 		log.Error(err, errCouldNotAdmitWL)
+		metrics.InternalQueueRetry(metrics.InternalQueueAdmission)
 		s.requeueAndUpdate(log, ctx, *e)
 	})
 
 	return nil
 }
 
+// permit runs the Permit plugins for the admission of the workload of the
+// entry, and of the other workloads of its admission group.
+func (s *Scheduler) permit(ctx context.Context, e *entry) error {
+	if len(e.groupMembers) > 0 {
+		for _, wl := range groupAdmission(e) {
+			if err := s.framework.RunPermitPlugins(ctx, wl, wl.Status.Admission); err != nil {
+				return fmt.Errorf("member %s %w", workload.Key(wl), err)
+			}
+		}
+		return nil
+	}
+	return s.framework.RunPermitPlugins(ctx, e.Obj, e.admission(e.assignment.ToAPI()))
+}
+
+// recordAdmission emits the event and reports the metrics for the admitted
+// workload.
+func (s *Scheduler) recordAdmission(log logr.Logger, wl *kueue.Workload) {
+	cqName := wl.Status.Admission.ClusterQueue
+	waitTime := time.Since(wl.CreationTimestamp.Time)
+	s.recorder.Eventf(wl, corev1.EventTypeNormal, "Admitted", "Admitted by ClusterQueue %v, wait time was %.3fs", cqName, waitTime.Seconds())
+	metrics.AdmittedWorkload(cqName, waitTime, workload.SchedulingAttempts(wl)+1)
+	if start, ok := workload.LatestStartTime(wl); ok && time.Now().After(start) {
+		metrics.AdmissionDeadlineMissed(cqName)
+		log.V(2).Info("Workload admitted too late to meet its deadline", "latestStartTime", start)
+	}
+	log.V(2).Info("Workload successfully admitted and assigned flavors")
+}
+
 func (s *Scheduler) applyAdmissionWithSSA(ctx context.Context, w *kueue.Workload) error {
-	return s.client.Patch(ctx, w, client.Apply, client.FieldOwner(constants.AdmissionName))
+	return s.client.Status().Patch(ctx, w, client.Apply, client.FieldOwner(constants.AdmissionName))
 }
 
 // workloadAdmissionFrom returns only the fields necessary for admission using
@@ -297,8 +686,8 @@ func workloadAdmissionFrom(w *kueue.Workload) *kueue.Workload {
 			Generation: w.Generation, // Produce a conflict if there was a change in the spec.
 		},
 		TypeMeta: w.TypeMeta,
-		Spec: kueue.WorkloadSpec{
-			Admission: w.Spec.Admission.DeepCopy(),
+		Status: kueue.WorkloadStatus{
+			Admission: w.Status.Admission.DeepCopy(),
 		},
 	}
 	if wlCopy.APIVersion == "" {
@@ -322,7 +711,8 @@ func (e entryOrdering) Swap(i, j int) {
 
 // Less is the ordering criteria:
 // 1. request under min quota before borrowing.
-// 2. FIFO on creation timestamp.
+// 2. lower dominant resource share of the clusterQueue first.
+// 3. FIFO on creation timestamp.
 func (e entryOrdering) Less(i, j int) bool {
 	a := e[i]
 	b := e[j]
@@ -332,23 +722,79 @@ func (e entryOrdering) Less(i, j int) bool {
 	if aBorrows != bBorrows {
 		return !aBorrows
 	}
-	// 2. FIFO.
+	// 2. Dominant resource share.
+	if a.dominantResourceShare != b.dominantResourceShare {
+		return a.dominantResourceShare < b.dominantResourceShare
+	}
+	// 3. FIFO.
 	return a.Obj.CreationTimestamp.Before(&b.Obj.CreationTimestamp)
 }
 
 func (s *Scheduler) requeueAndUpdate(log logr.Logger, ctx context.Context, e entry) {
+	for _, m := range e.groupMembers {
+		s.requeueAndUpdate(log, ctx, entry{Info: m, status: e.status, inadmissibleMsg: e.inadmissibleMsg, requeueReason: e.requeueReason})
+	}
 	if e.status != notNominated && e.requeueReason == queue.RequeueReasonGeneric {
 		// Failed after nomination is the only reason why a workload would be requeued downstream.
 		e.requeueReason = queue.RequeueReasonFailedAfterNomination
 	}
+	// Count the attempt in a copy, to not modify the object in the client
+	// cache. The queue manager keeps the count in the requeued workload.
+	e.Obj = e.Obj.DeepCopy()
+	workload.AddSchedulingAttempt(e.Obj, e.evaluationTime)
+	quarantined := workload.IsQuarantined(e.Obj)
 	added := s.queues.RequeueWorkload(ctx, &e.Info, e.requeueReason)
 	log.V(2).Info("Workload re-queued", "workload", klog.KObj(e.Obj), "clusterQueue", e.ClusterQueue, "queue", klog.KRef(e.Obj.Namespace, e.Obj.Spec.QueueName), "requeueReason", e.requeueReason, "added", added)
+	if !quarantined && workload.IsQuarantined(e.Obj) {
+		cond := apimeta.FindStatusCondition(e.Obj.Status.Conditions, kueue.WorkloadQuarantined)
+		log.V(2).Info("Workload quarantined", "workload", klog.KObj(e.Obj), "clusterQueue", e.ClusterQueue)
+		s.recorder.Eventf(e.Obj, corev1.EventTypeWarning, kueue.WorkloadQuarantined, cond.Message)
+	}
 
-	if e.status == notNominated {
-		err := workload.UpdateStatus(ctx, s.client, e.Obj, kueue.WorkloadAdmitted, metav1.ConditionFalse, "Pending", e.inadmissibleMsg)
-		if err != nil {
-			log.Error(err, "Could not update Workload status")
+	// The status update includes the scheduling stats and the requeue state set
+	// by the queue manager. To not write the workload in every cycle, the
+	// status is only updated when its conditions change; the queue manager
+	// keeps the stats and the requeue state in the meantime.
+	newWl := e.Obj.DeepCopy()
+	changed := !quarantined && workload.IsQuarantined(e.Obj)
+	var reason string
+	if e.requeueReason == queue.RequeueReasonHeadBlockingTimeout {
+		reason = string(queue.RequeueReasonHeadBlockingTimeout)
+	} else if e.status == notNominated {
+		reason = kueue.WorkloadReasonPending
+	}
+	if len(reason) > 0 {
+		if workload.SetPendingConditions(newWl, reason, e.inadmissibleMsg) {
+			changed = true
 		}
-		s.recorder.Eventf(e.Obj, corev1.EventTypeNormal, "Pending", e.inadmissibleMsg)
+		s.recorder.Eventf(e.Obj, corev1.EventTypeNormal, reason, e.inadmissibleMsg)
+	}
+	if !changed {
+		return
+	}
+	if err := s.client.Status().Update(ctx, newWl); client.IgnoreNotFound(err) != nil {
+		log.Error(err, "Could not update Workload status")
 	}
 }
+
+// detachedContext carries the values of its parent, such as the logger, but
+// it's never canceled.
+type detachedContext struct {
+	context.Context
+}
+
+func (detachedContext) Deadline() (time.Time, bool) {
+	return time.Time{}, false
+}
+
+func (detachedContext) Done() <-chan struct{} {
+	return nil
+}
+
+func (detachedContext) Err() error {
+	return nil
+}
+
+func withoutCancel(ctx context.Context) context.Context {
+	return detachedContext{Context: ctx}
+}
@@ -21,6 +21,7 @@ import (
 	"errors"
 	"sort"
 	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
 
@@ -175,6 +176,22 @@ func TestSchedule(t *testing.T) {
 				},
 			},
 		},
+		*utiltesting.MakeClusterQueue("backfill").
+			NamespaceSelector(&metav1.LabelSelector{
+				MatchLabels: map[string]string{"dep": "sales"},
+			}).
+			QueueingStrategy(kueue.StrictFIFO).
+			Backfill(true).
+			Resource(utiltesting.MakeResource(corev1.ResourceCPU).
+				Flavor(utiltesting.MakeFlavor("default", "10").Obj()).Obj()).
+			Obj(),
+		*utiltesting.MakeClusterQueue("best-effort").
+			NamespaceSelector(&metav1.LabelSelector{
+				MatchLabels: map[string]string{"dep": "sales"},
+			}).
+			Resource(utiltesting.MakeResource(corev1.ResourceCPU).
+				Flavor(utiltesting.MakeFlavor("default", "10").Obj()).Obj()).
+			Obj(),
 		{
 			ObjectMeta: metav1.ObjectMeta{Name: "flavor-nonexistent-cq"},
 			Spec: kueue.ClusterQueueSpec{
@@ -205,6 +222,18 @@ func TestSchedule(t *testing.T) {
 				ClusterQueue: "sales",
 			},
 		},
+		{
+			ObjectMeta: metav1.ObjectMeta{
+				Namespace: "sales",
+				Name:      "limited",
+			},
+			Spec: kueue.LocalQueueSpec{
+				ClusterQueue: "sales",
+				Limits: corev1.ResourceList{
+					corev1.ResourceCPU: resource.MustParse("5"),
+				},
+			},
+		},
 		{
 			ObjectMeta: metav1.ObjectMeta{
 				Namespace: "sales",
@@ -232,6 +261,16 @@ func TestSchedule(t *testing.T) {
 				ClusterQueue: "eng-beta",
 			},
 		},
+		{
+			ObjectMeta: metav1.ObjectMeta{
+				Namespace: "sales",
+				Name:      "backfill",
+			},
+			Spec: kueue.LocalQueueSpec{
+				ClusterQueue: "backfill",
+			},
+		},
+		*utiltesting.MakeLocalQueue("best-effort", "sales").ClusterQueue("best-effort").Obj(),
 		{
 			ObjectMeta: metav1.ObjectMeta{
 				Namespace: "sales",
@@ -251,9 +290,11 @@ func TestSchedule(t *testing.T) {
 			},
 		},
 	}
+	now := time.Now()
 	cases := map[string]struct {
 		workloads      []kueue.Workload
 		admissionError error
+		fairSharing    bool
 		// wantAssignments is a summary of all the admissions in the cache after this cycle.
 		wantAssignments map[string]kueue.Admission
 		// wantScheduled is the subset of workloads that got scheduled/admitted in this cycle.
@@ -325,6 +366,31 @@ func TestSchedule(t *testing.T) {
 				"sales": sets.NewString("sales/foo"),
 			},
 		},
+		"workload exceeds the limits of its localQueue": {
+			workloads: []kueue.Workload{
+				{
+					ObjectMeta: metav1.ObjectMeta{
+						Namespace: "sales",
+						Name:      "foo",
+					},
+					Spec: kueue.WorkloadSpec{
+						QueueName: "limited",
+						PodSets: []kueue.PodSet{
+							{
+								Name:  "one",
+								Count: 10,
+								Spec: utiltesting.PodSpecForRequest(map[corev1.ResourceName]string{
+									corev1.ResourceCPU: "1",
+								}),
+							},
+						},
+					},
+				},
+			},
+			wantLeft: map[string]sets.String{
+				"sales": sets.NewString("sales/foo"),
+			},
+		},
 		"single clusterQueue full": {
 			workloads: []kueue.Workload{
 				{
@@ -682,6 +748,320 @@ func TestSchedule(t *testing.T) {
 				"eng-beta/user-on-demand": *utiltesting.MakeAdmission("eng-beta").Flavor(corev1.ResourceCPU, "on-demand").Obj(),
 			},
 		},
+		"borrowing workloads in FIFO order without fair sharing": {
+			workloads: []kueue.Workload{
+				*utiltesting.MakeWorkload("new", "eng-alpha").
+					Queue("main").
+					Creation(now).
+					Request(corev1.ResourceCPU, "10").
+					Obj(),
+				*utiltesting.MakeWorkload("new", "eng-beta").
+					Queue("main").
+					Creation(now.Add(time.Second)).
+					Request(corev1.ResourceCPU, "55").
+					Obj(),
+				*utiltesting.MakeWorkload("user-on-demand", "eng-alpha").
+					Request(corev1.ResourceCPU, "50").
+					Admit(utiltesting.MakeAdmission("eng-alpha").Flavor(corev1.ResourceCPU, "on-demand").Obj()).
+					Obj(),
+				*utiltesting.MakeWorkload("user-spot", "eng-alpha").
+					Request(corev1.ResourceCPU, "40").
+					Admit(utiltesting.MakeAdmission("eng-alpha").Flavor(corev1.ResourceCPU, "spot").Obj()).
+					Obj(),
+			},
+			wantAssignments: map[string]kueue.Admission{
+				"eng-alpha/user-on-demand": *utiltesting.MakeAdmission("eng-alpha").Flavor(corev1.ResourceCPU, "on-demand").Obj(),
+				"eng-alpha/user-spot":      *utiltesting.MakeAdmission("eng-alpha").Flavor(corev1.ResourceCPU, "spot").Obj(),
+				"eng-alpha/new":            *utiltesting.MakeAdmission("eng-alpha").Flavor(corev1.ResourceCPU, "on-demand").Obj(),
+			},
+			wantScheduled: []string{"eng-alpha/new"},
+			wantLeft: map[string]sets.String{
+				"eng-beta": sets.NewString("eng-beta/new"),
+			},
+		},
+		"fair sharing admits from the ClusterQueue with the lowest dominant resource share": {
+			fairSharing: true,
+			workloads: []kueue.Workload{
+				*utiltesting.MakeWorkload("new", "eng-alpha").
+					Queue("main").
+					Creation(now).
+					Request(corev1.ResourceCPU, "10").
+					Obj(),
+				*utiltesting.MakeWorkload("new", "eng-beta").
+					Queue("main").
+					Creation(now.Add(time.Second)).
+					Request(corev1.ResourceCPU, "55").
+					Obj(),
+				*utiltesting.MakeWorkload("user-on-demand", "eng-alpha").
+					Request(corev1.ResourceCPU, "50").
+					Admit(utiltesting.MakeAdmission("eng-alpha").Flavor(corev1.ResourceCPU, "on-demand").Obj()).
+					Obj(),
+				*utiltesting.MakeWorkload("user-spot", "eng-alpha").
+					Request(corev1.ResourceCPU, "40").
+					Admit(utiltesting.MakeAdmission("eng-alpha").Flavor(corev1.ResourceCPU, "spot").Obj()).
+					Obj(),
+			},
+			wantAssignments: map[string]kueue.Admission{
+				"eng-alpha/user-on-demand": *utiltesting.MakeAdmission("eng-alpha").Flavor(corev1.ResourceCPU, "on-demand").Obj(),
+				"eng-alpha/user-spot":      *utiltesting.MakeAdmission("eng-alpha").Flavor(corev1.ResourceCPU, "spot").Obj(),
+				"eng-beta/new":             *utiltesting.MakeAdmission("eng-beta").Flavor(corev1.ResourceCPU, "spot").Obj(),
+			},
+			wantScheduled: []string{"eng-beta/new"},
+			wantLeft: map[string]sets.String{
+				"eng-alpha": sets.NewString("eng-alpha/new"),
+			},
+		},
+		"partial admission with the largest count that fits": {
+			workloads: []kueue.Workload{
+				*utiltesting.MakeWorkload("new", "sales").
+					Queue("main").
+					Request(corev1.ResourceCPU, "10").
+					Count(10).
+					MinCount(2).
+					Obj(),
+			},
+			wantAssignments: map[string]kueue.Admission{
+				"sales/new": *utiltesting.MakeAdmission("sales").Flavor(corev1.ResourceCPU, "default").Count(5).Obj(),
+			},
+			wantScheduled: []string{"sales/new"},
+		},
+		"no partial admission when minCount doesn't fit": {
+			workloads: []kueue.Workload{
+				*utiltesting.MakeWorkload("new", "sales").
+					Queue("main").
+					Request(corev1.ResourceCPU, "10").
+					Count(10).
+					MinCount(6).
+					Obj(),
+			},
+			wantLeft: map[string]sets.String{
+				"sales": sets.NewString("sales/new"),
+			},
+		},
+		"backfill workloads expected to finish before the head can start": {
+			workloads: []kueue.Workload{
+				*utiltesting.MakeWorkload("running", "sales").
+					Request(corev1.ResourceCPU, "8").
+					ExpectedDuration(2 * time.Hour).
+					Admit(utiltesting.MakeAdmission("backfill").Flavor(corev1.ResourceCPU, "default").Obj()).
+					Obj(),
+				*utiltesting.MakeWorkload("head", "sales").
+					Queue("backfill").
+					Creation(now).
+					Request(corev1.ResourceCPU, "6").
+					Obj(),
+				*utiltesting.MakeWorkload("short", "sales").
+					Queue("backfill").
+					Creation(now.Add(time.Second)).
+					Request(corev1.ResourceCPU, "2").
+					ExpectedDuration(time.Hour).
+					Obj(),
+				*utiltesting.MakeWorkload("long", "sales").
+					Queue("backfill").
+					Creation(now.Add(2*time.Second)).
+					Request(corev1.ResourceCPU, "1").
+					ExpectedDuration(3 * time.Hour).
+					Obj(),
+			},
+			wantAssignments: map[string]kueue.Admission{
+				"sales/running": *utiltesting.MakeAdmission("backfill").Flavor(corev1.ResourceCPU, "default").Obj(),
+				"sales/short":   *utiltesting.MakeAdmission("backfill").Flavor(corev1.ResourceCPU, "default").Obj(),
+			},
+			wantScheduled: []string{"sales/short"},
+			wantLeft: map[string]sets.String{
+				"backfill": sets.NewString("sales/head", "sales/long"),
+			},
+		},
+		"no backfill when the start of the head can't be estimated": {
+			workloads: []kueue.Workload{
+				*utiltesting.MakeWorkload("running", "sales").
+					Request(corev1.ResourceCPU, "8").
+					Admit(utiltesting.MakeAdmission("backfill").Flavor(corev1.ResourceCPU, "default").Obj()).
+					Obj(),
+				*utiltesting.MakeWorkload("head", "sales").
+					Queue("backfill").
+					Creation(now).
+					Request(corev1.ResourceCPU, "6").
+					Obj(),
+				*utiltesting.MakeWorkload("short", "sales").
+					Queue("backfill").
+					Creation(now.Add(time.Second)).
+					Request(corev1.ResourceCPU, "2").
+					ExpectedDuration(time.Hour).
+					Obj(),
+			},
+			wantAssignments: map[string]kueue.Admission{
+				"sales/running": *utiltesting.MakeAdmission("backfill").Flavor(corev1.ResourceCPU, "default").Obj(),
+			},
+			wantLeft: map[string]sets.String{
+				"backfill": sets.NewString("sales/head", "sales/short"),
+			},
+		},
+		"admit the workloads behind the head until one doesn't fit in a StrictFIFO clusterQueue": {
+			workloads: []kueue.Workload{
+				*utiltesting.MakeWorkload("a", "sales").
+					Queue("main").
+					Creation(now).
+					Request(corev1.ResourceCPU, "20").
+					Obj(),
+				*utiltesting.MakeWorkload("b", "sales").
+					Queue("main").
+					Creation(now.Add(time.Second)).
+					Request(corev1.ResourceCPU, "20").
+					Obj(),
+				*utiltesting.MakeWorkload("c", "sales").
+					Queue("main").
+					Creation(now.Add(2*time.Second)).
+					Request(corev1.ResourceCPU, "20").
+					Obj(),
+				*utiltesting.MakeWorkload("d", "sales").
+					Queue("main").
+					Creation(now.Add(3*time.Second)).
+					Request(corev1.ResourceCPU, "5").
+					Obj(),
+			},
+			wantAssignments: map[string]kueue.Admission{
+				"sales/a": *utiltesting.MakeAdmission("sales").Flavor(corev1.ResourceCPU, "default").Obj(),
+				"sales/b": *utiltesting.MakeAdmission("sales").Flavor(corev1.ResourceCPU, "default").Obj(),
+			},
+			wantScheduled: []string{"sales/a", "sales/b"},
+			wantLeft: map[string]sets.String{
+				"sales": sets.NewString("sales/c", "sales/d"),
+			},
+		},
+		"admit the workloads behind the head that fit in a BestEffortFIFO clusterQueue": {
+			workloads: []kueue.Workload{
+				*utiltesting.MakeWorkload("a", "sales").
+					Queue("best-effort").
+					Creation(now).
+					Request(corev1.ResourceCPU, "4").
+					Obj(),
+				*utiltesting.MakeWorkload("b", "sales").
+					Queue("best-effort").
+					Creation(now.Add(time.Second)).
+					Request(corev1.ResourceCPU, "8").
+					Obj(),
+				*utiltesting.MakeWorkload("c", "sales").
+					Queue("best-effort").
+					Creation(now.Add(2*time.Second)).
+					Request(corev1.ResourceCPU, "4").
+					Obj(),
+			},
+			wantAssignments: map[string]kueue.Admission{
+				"sales/a": *utiltesting.MakeAdmission("best-effort").Flavor(corev1.ResourceCPU, "default").Obj(),
+				"sales/c": *utiltesting.MakeAdmission("best-effort").Flavor(corev1.ResourceCPU, "default").Obj(),
+			},
+			wantScheduled: []string{"sales/a", "sales/c"},
+			wantLeft: map[string]sets.String{
+				"best-effort": sets.NewString("sales/b"),
+			},
+		},
+		"admit a burst of identical workloads behind the head as long as they fit": {
+			workloads: []kueue.Workload{
+				*utiltesting.MakeWorkload("a", "sales").
+					Queue("best-effort").
+					Creation(now).
+					Request(corev1.ResourceCPU, "3").
+					Obj(),
+				*utiltesting.MakeWorkload("b", "sales").
+					Queue("best-effort").
+					Creation(now.Add(time.Second)).
+					Request(corev1.ResourceCPU, "3").
+					Obj(),
+				*utiltesting.MakeWorkload("c", "sales").
+					Queue("best-effort").
+					Creation(now.Add(2*time.Second)).
+					Request(corev1.ResourceCPU, "3").
+					Obj(),
+				*utiltesting.MakeWorkload("d", "sales").
+					Queue("best-effort").
+					Creation(now.Add(3*time.Second)).
+					Request(corev1.ResourceCPU, "3").
+					Obj(),
+				*utiltesting.MakeWorkload("e", "sales").
+					Queue("best-effort").
+					Creation(now.Add(4*time.Second)).
+					Request(corev1.ResourceCPU, "1").
+					Obj(),
+			},
+			wantAssignments: map[string]kueue.Admission{
+				"sales/a": *utiltesting.MakeAdmission("best-effort").Flavor(corev1.ResourceCPU, "default").Obj(),
+				"sales/b": *utiltesting.MakeAdmission("best-effort").Flavor(corev1.ResourceCPU, "default").Obj(),
+				"sales/c": *utiltesting.MakeAdmission("best-effort").Flavor(corev1.ResourceCPU, "default").Obj(),
+				"sales/e": *utiltesting.MakeAdmission("best-effort").Flavor(corev1.ResourceCPU, "default").Obj(),
+			},
+			wantScheduled: []string{"sales/a", "sales/b", "sales/c", "sales/e"},
+			wantLeft: map[string]sets.String{
+				"best-effort": sets.NewString("sales/d"),
+			},
+		},
+		"admit the workloads of an admission group together": {
+			workloads: []kueue.Workload{
+				*utiltesting.MakeWorkload("a", "sales").
+					Queue("best-effort").
+					Creation(now).
+					AdmissionGroup("group", 2).
+					Request(corev1.ResourceCPU, "4").
+					Obj(),
+				*utiltesting.MakeWorkload("b", "sales").
+					Queue("best-effort").
+					Creation(now.Add(time.Second)).
+					AdmissionGroup("group", 2).
+					Request(corev1.ResourceCPU, "4").
+					Obj(),
+				*utiltesting.MakeWorkload("c", "sales").
+					Queue("best-effort").
+					Creation(now.Add(2*time.Second)).
+					Request(corev1.ResourceCPU, "4").
+					Obj(),
+			},
+			wantAssignments: map[string]kueue.Admission{
+				"sales/a": *utiltesting.MakeAdmission("best-effort").Flavor(corev1.ResourceCPU, "default").Obj(),
+				"sales/b": *utiltesting.MakeAdmission("best-effort").Flavor(corev1.ResourceCPU, "default").Obj(),
+			},
+			wantScheduled: []string{"sales/a", "sales/b"},
+			wantLeft: map[string]sets.String{
+				"best-effort": sets.NewString("sales/c"),
+			},
+		},
+		"admission group doesn't fit": {
+			workloads: []kueue.Workload{
+				*utiltesting.MakeWorkload("a", "sales").
+					Queue("best-effort").
+					Creation(now).
+					AdmissionGroup("group", 2).
+					Request(corev1.ResourceCPU, "6").
+					Obj(),
+				*utiltesting.MakeWorkload("b", "sales").
+					Queue("best-effort").
+					Creation(now.Add(time.Second)).
+					AdmissionGroup("group", 2).
+					Request(corev1.ResourceCPU, "6").
+					Obj(),
+			},
+			wantInadmissibleLeft: map[string]sets.String{
+				"best-effort": sets.NewString("sales/a", "sales/b"),
+			},
+		},
+		"admission group waits for its missing workloads": {
+			workloads: []kueue.Workload{
+				*utiltesting.MakeWorkload("a", "sales").
+					Queue("best-effort").
+					Creation(now).
+					AdmissionGroup("group", 3).
+					Request(corev1.ResourceCPU, "1").
+					Obj(),
+				*utiltesting.MakeWorkload("b", "sales").
+					Queue("best-effort").
+					Creation(now.Add(time.Second)).
+					AdmissionGroup("group", 3).
+					Request(corev1.ResourceCPU, "1").
+					Obj(),
+			},
+			wantInadmissibleLeft: map[string]sets.String{
+				"best-effort": sets.NewString("sales/a", "sales/b"),
+			},
+		},
 		"cannot borrow resource not listed in clusterQueue": {
 			workloads: []kueue.Workload{
 				{
@@ -873,7 +1253,7 @@ func TestSchedule(t *testing.T) {
 					t.Fatalf("Inserting clusterQueue %s in manager: %v", cq.Name, err)
 				}
 			}
-			scheduler := New(qManager, cqCache, cl, recorder)
+			scheduler := New(qManager, cqCache, cl, recorder, WithFairSharing(tc.fairSharing))
 			gotScheduled := make(map[string]kueue.Admission)
 			var mu sync.Mutex
 			scheduler.applyAdmission = func(ctx context.Context, w *kueue.Workload) error {
@@ -881,7 +1261,7 @@ func TestSchedule(t *testing.T) {
 					return tc.admissionError
 				}
 				mu.Lock()
-				gotScheduled[workload.Key(w)] = *w.Spec.Admission
+				gotScheduled[workload.Key(w)] = *w.Status.Admission
 				mu.Unlock()
 				return nil
 			}
@@ -902,7 +1282,7 @@ func TestSchedule(t *testing.T) {
 			for _, key := range tc.wantScheduled {
 				wantScheduled[key] = tc.wantAssignments[key]
 			}
-			if diff := cmp.Diff(wantScheduled, gotScheduled); diff != "" {
+			if diff := cmp.Diff(wantScheduled, gotScheduled, ignoreFlavorsReason); diff != "" {
 				t.Errorf("Unexpected scheduled workloads (-want,+got):\n%s", diff)
 			}
 
@@ -911,18 +1291,18 @@ func TestSchedule(t *testing.T) {
 			snapshot := cqCache.Snapshot()
 			for cqName, c := range snapshot.ClusterQueues {
 				for name, w := range c.Workloads {
-					if w.Obj.Spec.Admission == nil {
+					if w.Obj.Status.Admission == nil {
 						t.Errorf("Workload %s is not admitted by a clusterQueue, but it is found as member of clusterQueue %s in the cache", name, cqName)
-					} else if string(w.Obj.Spec.Admission.ClusterQueue) != cqName {
-						t.Errorf("Workload %s is admitted by clusterQueue %s, but it is found as member of clusterQueue %s in the cache", name, w.Obj.Spec.Admission.ClusterQueue, cqName)
+					} else if string(w.Obj.Status.Admission.ClusterQueue) != cqName {
+						t.Errorf("Workload %s is admitted by clusterQueue %s, but it is found as member of clusterQueue %s in the cache", name, w.Obj.Status.Admission.ClusterQueue, cqName)
 					}
-					gotAssignments[name] = *w.Obj.Spec.Admission
+					gotAssignments[name] = *w.Obj.Status.Admission
 				}
 			}
 			if len(gotAssignments) == 0 {
 				gotAssignments = nil
 			}
-			if diff := cmp.Diff(tc.wantAssignments, gotAssignments); diff != "" {
+			if diff := cmp.Diff(tc.wantAssignments, gotAssignments, ignoreFlavorsReason); diff != "" {
 				t.Errorf("Unexpected assigned clusterQueues in cache (-want,+got):\n%s", diff)
 			}
 
@@ -983,13 +1363,22 @@ func TestEntryOrdering(t *testing.T) {
 				},
 			},
 		},
+		{
+			Info: workload.Info{
+				Obj: &kueue.Workload{ObjectMeta: metav1.ObjectMeta{
+					Name:              "epsilon",
+					CreationTimestamp: metav1.NewTime(now.Add(-time.Second)),
+				}},
+			},
+			dominantResourceShare: 500,
+		},
 	}
 	sort.Sort(entryOrdering(input))
 	order := make([]string, len(input))
 	for i, e := range input {
 		order[i] = e.Obj.Name
 	}
-	wantOrder := []string{"beta", "gamma", "alpha", "delta"}
+	wantOrder := []string{"beta", "gamma", "epsilon", "alpha", "delta"}
 	if diff := cmp.Diff(wantOrder, order); diff != "" {
 		t.Errorf("Unexpected order (-want,+got):\n%s", diff)
 	}
@@ -997,6 +1386,9 @@ func TestEntryOrdering(t *testing.T) {
 
 var ignoreConditionTimestamps = cmpopts.IgnoreFields(metav1.Condition{}, "LastTransitionTime")
 
+// The reasons for the flavor choice are verified in the flavorassigner tests.
+var ignoreFlavorsReason = cmpopts.IgnoreFields(kueue.PodSetFlavors{}, "FlavorsReason")
+
 func TestRequeueAndUpdate(t *testing.T) {
 	cq := utiltesting.MakeClusterQueue("cq").Obj()
 	q1 := utiltesting.MakeLocalQueue("q1", "ns1").ClusterQueue(cq.Name).Obj()
@@ -1007,22 +1399,34 @@ func TestRequeueAndUpdate(t *testing.T) {
 		e                entry
 		wantWorkloads    map[string]sets.String
 		wantInadmissible map[string]sets.String
-		wantStatus       kueue.WorkloadStatus
+		// The status is only updated when the conditions change.
+		wantStatus kueue.WorkloadStatus
 	}{
 		{
 			name: "workload didn't fit",
 			e: entry{
 				inadmissibleMsg: "didn't fit",
+				evaluationTime:  time.Millisecond,
 			},
 			wantStatus: kueue.WorkloadStatus{
 				Conditions: []metav1.Condition{
+					{
+						Type:    kueue.WorkloadQuotaReserved,
+						Status:  metav1.ConditionFalse,
+						Reason:  kueue.WorkloadReasonPending,
+						Message: "didn't fit",
+					},
 					{
 						Type:    kueue.WorkloadAdmitted,
 						Status:  metav1.ConditionFalse,
-						Reason:  "Pending",
+						Reason:  kueue.WorkloadReasonPending,
 						Message: "didn't fit",
 					},
 				},
+				SchedulingStats: &kueue.SchedulingStats{
+					Attempts:       1,
+					EvaluationTime: metav1.Duration{Duration: time.Millisecond},
+				},
 			},
 			wantInadmissible: map[string]sets.String{
 				"cq": sets.NewString(workload.Key(w1)),
@@ -1121,3 +1525,959 @@ func TestRequeueAndUpdate(t *testing.T) {
 		})
 	}
 }
+
+func TestRequeueAndUpdateWritesOnlyChanges(t *testing.T) {
+	cq := utiltesting.MakeClusterQueue("cq").Obj()
+	q1 := utiltesting.MakeLocalQueue("q1", "ns1").ClusterQueue(cq.Name).Obj()
+	w1 := utiltesting.MakeWorkload("w1", "ns1").Queue(q1.Name).Obj()
+
+	log := testr.NewWithOptions(t, testr.Options{
+		Verbosity: 2,
+	})
+	ctx := ctrl.LoggerInto(context.Background(), log)
+	scheme := runtime.NewScheme()
+	if err := kueue.AddToScheme(scheme); err != nil {
+		t.Fatalf("Failed adding kueue scheme: %v", err)
+	}
+	if err := corev1.AddToScheme(scheme); err != nil {
+		t.Fatalf("Failed adding kueue scheme: %v", err)
+	}
+	cl := fake.NewClientBuilder().WithScheme(scheme).WithObjects(w1, q1, &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "ns1"}}).Build()
+	broadcaster := record.NewBroadcaster()
+	recorder := broadcaster.NewRecorder(scheme, corev1.EventSource{Component: constants.AdmissionName})
+	cqCache := cache.New(cl)
+	qManager := queue.NewManager(cl, cqCache)
+	scheduler := New(qManager, cqCache, cl, recorder)
+	if err := qManager.AddLocalQueue(ctx, q1); err != nil {
+		t.Fatalf("Inserting queue %s/%s in manager: %v", q1.Namespace, q1.Name, err)
+	}
+	if err := qManager.AddClusterQueue(ctx, cq); err != nil {
+		t.Fatalf("Inserting clusterQueue %s in manager: %v", cq.Name, err)
+	}
+	if err := cqCache.AddClusterQueue(ctx, cq); err != nil {
+		t.Fatalf("Inserting clusterQueue %s to cache: %v", cq.Name, err)
+	}
+
+	// requeue evaluates the workload as it is stored and requeues it as
+	// inadmissible, returning the resource version after the update, if any.
+	requeue := func(msg string) string {
+		t.Helper()
+		var wl kueue.Workload
+		if err := cl.Get(ctx, client.ObjectKeyFromObject(w1), &wl); err != nil {
+			t.Fatalf("Failed obtaining the workload: %v", err)
+		}
+		info := workload.NewInfo(&wl)
+		info.ClusterQueue = cq.Name
+		scheduler.requeueAndUpdate(log, ctx, entry{Info: *info, inadmissibleMsg: msg})
+		if err := cl.Get(ctx, client.ObjectKeyFromObject(w1), &wl); err != nil {
+			t.Fatalf("Failed obtaining the workload: %v", err)
+		}
+		return wl.ResourceVersion
+	}
+
+	first := requeue("didn't fit")
+	if again := requeue("didn't fit"); again != first {
+		t.Errorf("Requeueing with the same message updated the workload, resource version %s, want %s", again, first)
+	}
+	if changed := requeue("still doesn't fit"); changed == first {
+		t.Error("Requeueing with a new message didn't update the workload")
+	}
+}
+
+func TestCheckHeadBlockingTimeout(t *testing.T) {
+	cq := &cache.ClusterQueue{
+		Name:                "cq",
+		StrictFIFO:          true,
+		HeadBlockingTimeout: time.Minute,
+	}
+	newEntry := func(name string, reason queue.RequeueReason) *entry {
+		e := &entry{
+			Info:            *workload.NewInfo(utiltesting.MakeWorkload(name, "ns").Obj()),
+			inadmissibleMsg: "didn't fit",
+			requeueReason:   reason,
+		}
+		e.ClusterQueue = cq.Name
+		return e
+	}
+	start := time.Now()
+
+	cases := []struct {
+		name       string
+		entry      *entry
+		now        time.Time
+		wantReason queue.RequeueReason
+		wantHead   *blockingHead
+	}{
+		{
+			name:       "new head is tracked",
+			entry:      newEntry("a", queue.RequeueReasonGeneric),
+			now:        start,
+			wantReason: queue.RequeueReasonGeneric,
+			wantHead:   &blockingHead{key: "ns/a", since: start},
+		},
+		{
+			name:       "head blocking for less than the timeout",
+			entry:      newEntry("a", queue.RequeueReasonGeneric),
+			now:        start.Add(30 * time.Second),
+			wantReason: queue.RequeueReasonGeneric,
+			wantHead:   &blockingHead{key: "ns/a", since: start},
+		},
+		{
+			name:       "head waiting for preemption is not timed out",
+			entry:      newEntry("a", queue.RequeueReasonPendingPreemption),
+			now:        start.Add(2 * time.Minute),
+			wantReason: queue.RequeueReasonPendingPreemption,
+			wantHead:   &blockingHead{key: "ns/a", since: start},
+		},
+		{
+			name:       "head blocking for longer than the timeout",
+			entry:      newEntry("a", queue.RequeueReasonGeneric),
+			now:        start.Add(2 * time.Minute),
+			wantReason: queue.RequeueReasonHeadBlockingTimeout,
+		},
+		{
+			name:       "next head is tracked",
+			entry:      newEntry("b", queue.RequeueReasonGeneric),
+			now:        start.Add(3 * time.Minute),
+			wantReason: queue.RequeueReasonGeneric,
+			wantHead:   &blockingHead{key: "ns/b", since: start.Add(3 * time.Minute)},
+		},
+	}
+
+	// The cases run in order, sharing the same scheduler.
+	s := &Scheduler{blockingHeads: make(map[string]blockingHead)}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			s.checkHeadBlockingTimeout(tc.entry, cq, tc.now)
+			if tc.entry.requeueReason != tc.wantReason {
+				t.Errorf("Got requeue reason %q, want %q", tc.entry.requeueReason, tc.wantReason)
+			}
+			head, ok := s.blockingHeads[cq.Name]
+			if tc.wantHead == nil {
+				if ok {
+					t.Errorf("Unexpected blocking head %v", head)
+				}
+			} else if !ok || head != *tc.wantHead {
+				t.Errorf("Got blocking head %v, want %v", head, *tc.wantHead)
+			}
+		})
+	}
+}
+
+func TestScheduleWhileShuttingDown(t *testing.T) {
+	cq := utiltesting.MakeClusterQueue("cq").
+		Resource(utiltesting.MakeResource(corev1.ResourceCPU).
+			Flavor(utiltesting.MakeFlavor("default", "10").Obj()).Obj()).
+		Obj()
+	q1 := utiltesting.MakeLocalQueue("q1", "ns1").ClusterQueue(cq.Name).Obj()
+	w1 := utiltesting.MakeWorkload("w1", "ns1").Queue(q1.Name).Request(corev1.ResourceCPU, "1").Obj()
+
+	log := testr.NewWithOptions(t, testr.Options{
+		Verbosity: 2,
+	})
+	ctx := ctrl.LoggerInto(context.Background(), log)
+	scheme := runtime.NewScheme()
+	if err := kueue.AddToScheme(scheme); err != nil {
+		t.Fatalf("Failed adding kueue scheme: %v", err)
+	}
+	if err := corev1.AddToScheme(scheme); err != nil {
+		t.Fatalf("Failed adding kueue scheme: %v", err)
+	}
+	cl := fake.NewClientBuilder().WithScheme(scheme).WithObjects(w1, q1, &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "ns1"}}).Build()
+	broadcaster := record.NewBroadcaster()
+	recorder := broadcaster.NewRecorder(scheme, corev1.EventSource{Component: constants.AdmissionName})
+	cqCache := cache.New(cl)
+	qManager := queue.NewManager(cl, cqCache)
+	cqCache.AddOrUpdateResourceFlavor(utiltesting.MakeResourceFlavor("default").Obj())
+	if err := qManager.AddLocalQueue(ctx, q1); err != nil {
+		t.Fatalf("Inserting queue %s/%s in manager: %v", q1.Namespace, q1.Name, err)
+	}
+	if err := qManager.AddClusterQueue(ctx, cq); err != nil {
+		t.Fatalf("Inserting clusterQueue %s in manager: %v", cq.Name, err)
+	}
+	if err := cqCache.AddClusterQueue(ctx, cq); err != nil {
+		t.Fatalf("Inserting clusterQueue %s to cache: %v", cq.Name, err)
+	}
+	scheduler := New(qManager, cqCache, cl, recorder)
+	scheduler.applyAdmission = func(ctx context.Context, w *kueue.Workload) error {
+		t.Errorf("Unexpected admission of workload %s", workload.Key(w))
+		return nil
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	cancel()
+	scheduler.schedule(ctx)
+
+	wantInadmissible := map[string]sets.String{
+		"cq": sets.NewString(workload.Key(w1)),
+	}
+	if diff := cmp.Diff(wantInadmissible, qManager.DumpInadmissible()); diff != "" {
+		t.Errorf("Unexpected elements in the inadmissible stage of the cluster queue (-want,+got):\n%s", diff)
+	}
+	var updatedWl kueue.Workload
+	if err := cl.Get(context.Background(), client.ObjectKeyFromObject(w1), &updatedWl); err != nil {
+		t.Fatalf("Failed obtaining updated object: %v", err)
+	}
+	wantStatus := kueue.WorkloadStatus{
+		Conditions: []metav1.Condition{
+			{
+				Type:    kueue.WorkloadQuotaReserved,
+				Status:  metav1.ConditionFalse,
+				Reason:  kueue.WorkloadReasonPending,
+				Message: "The scheduler is shutting down",
+			},
+			{
+				Type:    kueue.WorkloadAdmitted,
+				Status:  metav1.ConditionFalse,
+				Reason:  kueue.WorkloadReasonPending,
+				Message: "The scheduler is shutting down",
+			},
+		},
+		SchedulingStats: &kueue.SchedulingStats{Attempts: 1},
+	}
+	if diff := cmp.Diff(wantStatus, updatedWl.Status, ignoreConditionTimestamps, cmpopts.IgnoreFields(kueue.SchedulingStats{}, "EvaluationTime")); diff != "" {
+		t.Errorf("Unexpected status after updating (-want,+got):\n%s", diff)
+	}
+}
+
+func TestScheduleRevertsPartialGroupAdmission(t *testing.T) {
+	cq := utiltesting.MakeClusterQueue("cq").
+		Resource(utiltesting.MakeResource(corev1.ResourceCPU).
+			Flavor(utiltesting.MakeFlavor("default", "10").Obj()).Obj()).
+		Obj()
+	q1 := utiltesting.MakeLocalQueue("q1", "ns1").ClusterQueue(cq.Name).Obj()
+	now := time.Now()
+	w1 := utiltesting.MakeWorkload("w1", "ns1").Queue(q1.Name).Creation(now).AdmissionGroup("group", 2).Request(corev1.ResourceCPU, "1").Obj()
+	w2 := utiltesting.MakeWorkload("w2", "ns1").Queue(q1.Name).Creation(now.Add(time.Second)).AdmissionGroup("group", 2).Request(corev1.ResourceCPU, "1").Obj()
+
+	log := testr.NewWithOptions(t, testr.Options{
+		Verbosity: 2,
+	})
+	ctx := ctrl.LoggerInto(context.Background(), log)
+	scheme := runtime.NewScheme()
+	if err := kueue.AddToScheme(scheme); err != nil {
+		t.Fatalf("Failed adding kueue scheme: %v", err)
+	}
+	if err := corev1.AddToScheme(scheme); err != nil {
+		t.Fatalf("Failed adding kueue scheme: %v", err)
+	}
+	cl := fake.NewClientBuilder().WithScheme(scheme).WithObjects(w1, w2, q1, &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "ns1"}}).Build()
+	broadcaster := record.NewBroadcaster()
+	recorder := broadcaster.NewRecorder(scheme, corev1.EventSource{Component: constants.AdmissionName})
+	cqCache := cache.New(cl)
+	qManager := queue.NewManager(cl, cqCache)
+	cqCache.AddOrUpdateResourceFlavor(utiltesting.MakeResourceFlavor("default").Obj())
+	if err := qManager.AddLocalQueue(ctx, q1); err != nil {
+		t.Fatalf("Inserting queue %s/%s in manager: %v", q1.Namespace, q1.Name, err)
+	}
+	if err := qManager.AddClusterQueue(ctx, cq); err != nil {
+		t.Fatalf("Inserting clusterQueue %s in manager: %v", cq.Name, err)
+	}
+	if err := cqCache.AddClusterQueue(ctx, cq); err != nil {
+		t.Fatalf("Inserting clusterQueue %s to cache: %v", cq.Name, err)
+	}
+	scheduler := New(qManager, cqCache, cl, recorder)
+	var applied []string
+	scheduler.applyAdmission = func(ctx context.Context, w *kueue.Workload) error {
+		if w.Status.Admission == nil {
+			applied = append(applied, "revert "+workload.Key(w))
+			return nil
+		}
+		applied = append(applied, "admit "+workload.Key(w))
+		if w.Name == w2.Name {
+			return errors.New("conflict")
+		}
+		return nil
+	}
+	wg := sync.WaitGroup{}
+	scheduler.setAdmissionRoutineWrapper(routine.NewWrapper(
+		func() { wg.Add(1) },
+		func() { wg.Done() },
+	))
+
+	ctx, cancel := context.WithTimeout(ctx, queueingTimeout)
+	go qManager.CleanUpOnContext(ctx)
+	defer cancel()
+
+	scheduler.schedule(ctx)
+	wg.Wait()
+
+	wantApplied := []string{"admit ns1/w1", "admit ns1/w2", "revert ns1/w1"}
+	if diff := cmp.Diff(wantApplied, applied); diff != "" {
+		t.Errorf("Unexpected admissions applied (-want,+got):\n%s", diff)
+	}
+	if got := cqCache.Snapshot().ClusterQueues[cq.Name].Workloads; len(got) != 0 {
+		t.Errorf("Got %d workloads in the cache after reverting the admission group, want none", len(got))
+	}
+	wantPending := map[string]sets.String{
+		"cq": sets.NewString(workload.Key(w1), workload.Key(w2)),
+	}
+	if diff := cmp.Diff(wantPending, qManager.Dump()); diff != "" {
+		t.Errorf("Unexpected pending workloads (-want,+got):\n%s", diff)
+	}
+}
+
+func TestScheduleHonorsQuotaReservation(t *testing.T) {
+	cq := utiltesting.MakeClusterQueue("cq").
+		Resource(utiltesting.MakeResource(corev1.ResourceCPU).
+			Flavor(utiltesting.MakeFlavor("default", "10").Obj()).Obj()).
+		Obj()
+	q1 := utiltesting.MakeLocalQueue("q1", "ns1").ClusterQueue(cq.Name).Obj()
+	now := time.Now()
+	other := utiltesting.MakeWorkload("other", "ns1").Queue(q1.Name).Creation(now).Request(corev1.ResourceCPU, "4").Obj()
+	preemptor := utiltesting.MakeWorkload("preemptor", "ns1").Queue(q1.Name).Creation(now.Add(time.Second)).Request(corev1.ResourceCPU, "8").Obj()
+
+	log := testr.NewWithOptions(t, testr.Options{
+		Verbosity: 2,
+	})
+	ctx := ctrl.LoggerInto(context.Background(), log)
+	scheme := runtime.NewScheme()
+	if err := kueue.AddToScheme(scheme); err != nil {
+		t.Fatalf("Failed adding kueue scheme: %v", err)
+	}
+	if err := corev1.AddToScheme(scheme); err != nil {
+		t.Fatalf("Failed adding kueue scheme: %v", err)
+	}
+	cl := fake.NewClientBuilder().WithScheme(scheme).WithObjects(other, preemptor, q1, &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "ns1"}}).Build()
+	broadcaster := record.NewBroadcaster()
+	recorder := broadcaster.NewRecorder(scheme, corev1.EventSource{Component: constants.AdmissionName})
+	cqCache := cache.New(cl)
+	qManager := queue.NewManager(cl, cqCache)
+	cqCache.AddOrUpdateResourceFlavor(utiltesting.MakeResourceFlavor("default").Obj())
+	if err := qManager.AddLocalQueue(ctx, q1); err != nil {
+		t.Fatalf("Inserting queue %s/%s in manager: %v", q1.Namespace, q1.Name, err)
+	}
+	if err := qManager.AddClusterQueue(ctx, cq); err != nil {
+		t.Fatalf("Inserting clusterQueue %s in manager: %v", cq.Name, err)
+	}
+	if err := cqCache.AddClusterQueue(ctx, cq); err != nil {
+		t.Fatalf("Inserting clusterQueue %s to cache: %v", cq.Name, err)
+	}
+	// The preemptor reserved the quota released by the workloads it preempted.
+	reserved := preemptor.DeepCopy()
+	reserved.Status.Admission = utiltesting.MakeAdmission(cq.Name).Flavor(corev1.ResourceCPU, "default").Obj()
+	if err := cqCache.ReserveQuota(reserved); err != nil {
+		t.Fatalf("Reserving quota: %v", err)
+	}
+	scheduler := New(qManager, cqCache, cl, recorder)
+	var admitted []string
+	scheduler.applyAdmission = func(ctx context.Context, w *kueue.Workload) error {
+		admitted = append(admitted, workload.Key(w))
+		return nil
+	}
+	wg := sync.WaitGroup{}
+	scheduler.setAdmissionRoutineWrapper(routine.NewWrapper(
+		func() { wg.Add(1) },
+		func() { wg.Done() },
+	))
+
+	ctx, cancel := context.WithTimeout(ctx, queueingTimeout)
+	go qManager.CleanUpOnContext(ctx)
+	defer cancel()
+
+	qManager.AddOrUpdateWorkload(other)
+	scheduler.schedule(ctx)
+	wg.Wait()
+	if len(admitted) != 0 {
+		t.Errorf("Admitted %v using the reserved quota, want none", admitted)
+	}
+
+	qManager.AddOrUpdateWorkload(preemptor)
+	scheduler.schedule(ctx)
+	wg.Wait()
+	if diff := cmp.Diff([]string{workload.Key(preemptor)}, admitted); diff != "" {
+		t.Errorf("Unexpected admitted workloads (-want,+got):\n%s", diff)
+	}
+	if got := cqCache.Snapshot().Reservations; len(got) != 0 {
+		t.Errorf("Got %d reservations after admitting the preemptor, want none", len(got))
+	}
+}
+
+func TestScheduleRevocableBorrowing(t *testing.T) {
+	borrower := utiltesting.MakeClusterQueue("borrower").
+		Cohort("cohort").
+		Resource(utiltesting.MakeResource(corev1.ResourceCPU).
+			Flavor(utiltesting.MakeFlavor("default", "2").Obj()).Obj()).
+		RevocableBorrowing(true).
+		Obj()
+	lender := utiltesting.MakeClusterQueue("lender").
+		Cohort("cohort").
+		Resource(utiltesting.MakeResource(corev1.ResourceCPU).
+			Flavor(utiltesting.MakeFlavor("default", "4").Obj()).Obj()).
+		Obj()
+	q1 := utiltesting.MakeLocalQueue("q1", "ns1").ClusterQueue(borrower.Name).Obj()
+	now := time.Now()
+	fits := utiltesting.MakeWorkload("fits", "ns1").Queue(q1.Name).Creation(now).Request(corev1.ResourceCPU, "2").Obj()
+	borrows := utiltesting.MakeWorkload("borrows", "ns1").Queue(q1.Name).Creation(now.Add(time.Second)).Request(corev1.ResourceCPU, "3").Obj()
+
+	log := testr.NewWithOptions(t, testr.Options{
+		Verbosity: 2,
+	})
+	ctx := ctrl.LoggerInto(context.Background(), log)
+	scheme := runtime.NewScheme()
+	if err := kueue.AddToScheme(scheme); err != nil {
+		t.Fatalf("Failed adding kueue scheme: %v", err)
+	}
+	if err := corev1.AddToScheme(scheme); err != nil {
+		t.Fatalf("Failed adding kueue scheme: %v", err)
+	}
+	cl := fake.NewClientBuilder().WithScheme(scheme).WithObjects(fits, borrows, q1, &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "ns1"}}).Build()
+	broadcaster := record.NewBroadcaster()
+	recorder := broadcaster.NewRecorder(scheme, corev1.EventSource{Component: constants.AdmissionName})
+	cqCache := cache.New(cl)
+	qManager := queue.NewManager(cl, cqCache)
+	cqCache.AddOrUpdateResourceFlavor(utiltesting.MakeResourceFlavor("default").Obj())
+	if err := qManager.AddLocalQueue(ctx, q1); err != nil {
+		t.Fatalf("Inserting queue %s/%s in manager: %v", q1.Namespace, q1.Name, err)
+	}
+	for _, cq := range []*kueue.ClusterQueue{borrower, lender} {
+		if err := qManager.AddClusterQueue(ctx, cq); err != nil {
+			t.Fatalf("Inserting clusterQueue %s in manager: %v", cq.Name, err)
+		}
+		if err := cqCache.AddClusterQueue(ctx, cq); err != nil {
+			t.Fatalf("Inserting clusterQueue %s to cache: %v", cq.Name, err)
+		}
+	}
+	scheduler := New(qManager, cqCache, cl, recorder)
+	gotRevocable := make(map[string]bool)
+	scheduler.applyAdmission = func(ctx context.Context, w *kueue.Workload) error {
+		gotRevocable[workload.Key(w)] = w.Status.Admission.Revocable
+		return nil
+	}
+	wg := sync.WaitGroup{}
+	scheduler.setAdmissionRoutineWrapper(routine.NewWrapper(
+		func() { wg.Add(1) },
+		func() { wg.Done() },
+	))
+
+	ctx, cancel := context.WithTimeout(ctx, queueingTimeout)
+	go qManager.CleanUpOnContext(ctx)
+	defer cancel()
+
+	qManager.AddOrUpdateWorkload(fits)
+	qManager.AddOrUpdateWorkload(borrows)
+	// Each cycle admits the head of the ClusterQueue.
+	for i := 0; i < 2; i++ {
+		scheduler.schedule(ctx)
+		wg.Wait()
+	}
+	wantRevocable := map[string]bool{
+		workload.Key(fits):    false,
+		workload.Key(borrows): true,
+	}
+	if diff := cmp.Diff(wantRevocable, gotRevocable); diff != "" {
+		t.Errorf("Unexpected revocable admissions (-want,+got):\n%s", diff)
+	}
+}
+
+func TestScheduleSplitPodSet(t *testing.T) {
+	cq := utiltesting.MakeClusterQueue("cq").
+		Resource(utiltesting.MakeResource(corev1.ResourceCPU).
+			Flavor(utiltesting.MakeFlavor("spot", "6").Obj()).
+			Flavor(utiltesting.MakeFlavor("on-demand", "4").Obj()).Obj()).
+		Obj()
+	cq.Spec.FlavorFungibility = &kueue.FlavorFungibility{WhenNoFlavorFits: kueue.SplitPodSet}
+	q1 := utiltesting.MakeLocalQueue("q1", "ns1").ClusterQueue(cq.Name).Obj()
+	wl := utiltesting.MakeWorkload("wl", "ns1").Queue(q1.Name).Request(corev1.ResourceCPU, "1").Count(10).Obj()
+
+	log := testr.NewWithOptions(t, testr.Options{
+		Verbosity: 2,
+	})
+	ctx := ctrl.LoggerInto(context.Background(), log)
+	scheme := runtime.NewScheme()
+	if err := kueue.AddToScheme(scheme); err != nil {
+		t.Fatalf("Failed adding kueue scheme: %v", err)
+	}
+	if err := corev1.AddToScheme(scheme); err != nil {
+		t.Fatalf("Failed adding kueue scheme: %v", err)
+	}
+	cl := fake.NewClientBuilder().WithScheme(scheme).WithObjects(wl, q1, &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "ns1"}}).Build()
+	broadcaster := record.NewBroadcaster()
+	recorder := broadcaster.NewRecorder(scheme, corev1.EventSource{Component: constants.AdmissionName})
+	cqCache := cache.New(cl)
+	qManager := queue.NewManager(cl, cqCache)
+	cqCache.AddOrUpdateResourceFlavor(utiltesting.MakeResourceFlavor("spot").Obj())
+	cqCache.AddOrUpdateResourceFlavor(utiltesting.MakeResourceFlavor("on-demand").Obj())
+	if err := qManager.AddLocalQueue(ctx, q1); err != nil {
+		t.Fatalf("Inserting queue %s/%s in manager: %v", q1.Namespace, q1.Name, err)
+	}
+	if err := qManager.AddClusterQueue(ctx, cq); err != nil {
+		t.Fatalf("Inserting clusterQueue %s in manager: %v", cq.Name, err)
+	}
+	if err := cqCache.AddClusterQueue(ctx, cq); err != nil {
+		t.Fatalf("Inserting clusterQueue %s to cache: %v", cq.Name, err)
+	}
+	scheduler := New(qManager, cqCache, cl, recorder)
+	var gotAdmission *kueue.Admission
+	scheduler.applyAdmission = func(ctx context.Context, w *kueue.Workload) error {
+		gotAdmission = w.Status.Admission
+		return nil
+	}
+	wg := sync.WaitGroup{}
+	scheduler.setAdmissionRoutineWrapper(routine.NewWrapper(
+		func() { wg.Add(1) },
+		func() { wg.Done() },
+	))
+
+	ctx, cancel := context.WithTimeout(ctx, queueingTimeout)
+	go qManager.CleanUpOnContext(ctx)
+	defer cancel()
+
+	qManager.AddOrUpdateWorkload(wl)
+	// The split policy of the ClusterQueue reaches the scheduler through the
+	// snapshot of the cache.
+	scheduler.schedule(ctx)
+	wg.Wait()
+	wantAdmission := utiltesting.MakeAdmission(cq.Name).
+		Flavor(corev1.ResourceCPU, "spot").
+		Count(6).
+		Split(corev1.ResourceCPU, "on-demand", 4).
+		Obj()
+	if diff := cmp.Diff(wantAdmission, gotAdmission, ignoreFlavorsReason); diff != "" {
+		t.Errorf("Unexpected admission (-want,+got):\n%s", diff)
+	}
+}
+
+func TestScheduleLimitsWorkloadsPendingChecks(t *testing.T) {
+	cq := utiltesting.MakeClusterQueue("cq").
+		Resource(utiltesting.MakeResource(corev1.ResourceCPU).
+			Flavor(utiltesting.MakeFlavor("default", "10").Obj()).Obj()).
+		AdmissionChecks("check").
+		MaxWorkloadsPendingChecks(2).
+		Obj()
+	q1 := utiltesting.MakeLocalQueue("q1", "ns1").ClusterQueue(cq.Name).Obj()
+	now := time.Now()
+	waiting := utiltesting.MakeWorkload("waiting", "ns1").Queue(q1.Name).Request(corev1.ResourceCPU, "1").
+		Admit(utiltesting.MakeAdmission(cq.Name).Flavor(corev1.ResourceCPU, "default").AdmissionChecks("check").Obj()).
+		AdmissionCheck("check", kueue.CheckStatePending).
+		Obj()
+	a := utiltesting.MakeWorkload("a", "ns1").Queue(q1.Name).Creation(now).Request(corev1.ResourceCPU, "1").Obj()
+	b := utiltesting.MakeWorkload("b", "ns1").Queue(q1.Name).Creation(now.Add(time.Second)).Request(corev1.ResourceCPU, "1").Obj()
+
+	log := testr.NewWithOptions(t, testr.Options{
+		Verbosity: 2,
+	})
+	ctx := ctrl.LoggerInto(context.Background(), log)
+	scheme := runtime.NewScheme()
+	if err := kueue.AddToScheme(scheme); err != nil {
+		t.Fatalf("Failed adding kueue scheme: %v", err)
+	}
+	if err := corev1.AddToScheme(scheme); err != nil {
+		t.Fatalf("Failed adding kueue scheme: %v", err)
+	}
+	cl := fake.NewClientBuilder().WithScheme(scheme).WithObjects(a, b, q1, &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "ns1"}}).Build()
+	broadcaster := record.NewBroadcaster()
+	recorder := broadcaster.NewRecorder(scheme, corev1.EventSource{Component: constants.AdmissionName})
+	cqCache := cache.New(cl)
+	qManager := queue.NewManager(cl, cqCache)
+	cqCache.AddOrUpdateResourceFlavor(utiltesting.MakeResourceFlavor("default").Obj())
+	cqCache.AddOrUpdateAdmissionCheck(utiltesting.MakeAdmissionCheck("check", "ctrl").Active(metav1.ConditionTrue).Obj())
+	if err := qManager.AddLocalQueue(ctx, q1); err != nil {
+		t.Fatalf("Inserting queue %s/%s in manager: %v", q1.Namespace, q1.Name, err)
+	}
+	if err := qManager.AddClusterQueue(ctx, cq); err != nil {
+		t.Fatalf("Inserting clusterQueue %s in manager: %v", cq.Name, err)
+	}
+	if err := cqCache.AddClusterQueue(ctx, cq); err != nil {
+		t.Fatalf("Inserting clusterQueue %s to cache: %v", cq.Name, err)
+	}
+	cqCache.AddOrUpdateWorkload(waiting)
+	scheduler := New(qManager, cqCache, cl, recorder)
+	var admitted []string
+	scheduler.applyAdmission = func(ctx context.Context, w *kueue.Workload) error {
+		admitted = append(admitted, workload.Key(w))
+		return nil
+	}
+	wg := sync.WaitGroup{}
+	scheduler.setAdmissionRoutineWrapper(routine.NewWrapper(
+		func() { wg.Add(1) },
+		func() { wg.Done() },
+	))
+
+	ctx, cancel := context.WithTimeout(ctx, queueingTimeout)
+	go qManager.CleanUpOnContext(ctx)
+	defer cancel()
+
+	qManager.AddOrUpdateWorkload(a)
+	qManager.AddOrUpdateWorkload(b)
+	// The head fills the limit, so the workload behind it isn't admitted,
+	// even if it fits in the quota.
+	scheduler.schedule(ctx)
+	wg.Wait()
+	if diff := cmp.Diff([]string{workload.Key(a)}, admitted); diff != "" {
+		t.Errorf("Unexpected admitted workloads (-want,+got):\n%s", diff)
+	}
+
+	scheduler.schedule(ctx)
+	wg.Wait()
+	if diff := cmp.Diff([]string{workload.Key(a)}, admitted); diff != "" {
+		t.Errorf("Unexpected admitted workloads once the limit is reached (-want,+got):\n%s", diff)
+	}
+	wantInadmissible := map[string]sets.String{
+		cq.Name: sets.NewString(workload.Key(b)),
+	}
+	if diff := cmp.Diff(wantInadmissible, qManager.DumpInadmissible()); diff != "" {
+		t.Errorf("Unexpected inadmissible workloads (-want,+got):\n%s", diff)
+	}
+}
+
+func TestScheduleFlavorAdmissionChecks(t *testing.T) {
+	cq := utiltesting.MakeClusterQueue("cq").
+		Resource(utiltesting.MakeResource(corev1.ResourceCPU).
+			Flavor(utiltesting.MakeFlavor("fixed", "2").Obj()).
+			Flavor(utiltesting.MakeFlavor("autoscaled", "4").AdmissionChecks("provisioning").Obj()).Obj()).
+		Obj()
+	q1 := utiltesting.MakeLocalQueue("q1", "ns1").ClusterQueue(cq.Name).Obj()
+	now := time.Now()
+	a := utiltesting.MakeWorkload("a", "ns1").Queue(q1.Name).Creation(now).Request(corev1.ResourceCPU, "2").Obj()
+	b := utiltesting.MakeWorkload("b", "ns1").Queue(q1.Name).Creation(now.Add(time.Second)).Request(corev1.ResourceCPU, "2").Obj()
+
+	log := testr.NewWithOptions(t, testr.Options{
+		Verbosity: 2,
+	})
+	ctx := ctrl.LoggerInto(context.Background(), log)
+	scheme := runtime.NewScheme()
+	if err := kueue.AddToScheme(scheme); err != nil {
+		t.Fatalf("Failed adding kueue scheme: %v", err)
+	}
+	if err := corev1.AddToScheme(scheme); err != nil {
+		t.Fatalf("Failed adding kueue scheme: %v", err)
+	}
+	cl := fake.NewClientBuilder().WithScheme(scheme).WithObjects(a, b, q1, &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "ns1"}}).Build()
+	broadcaster := record.NewBroadcaster()
+	recorder := broadcaster.NewRecorder(scheme, corev1.EventSource{Component: constants.AdmissionName})
+	cqCache := cache.New(cl)
+	qManager := queue.NewManager(cl, cqCache)
+	cqCache.AddOrUpdateResourceFlavor(utiltesting.MakeResourceFlavor("fixed").Obj())
+	cqCache.AddOrUpdateResourceFlavor(utiltesting.MakeResourceFlavor("autoscaled").Obj())
+	cqCache.AddOrUpdateAdmissionCheck(utiltesting.MakeAdmissionCheck("provisioning", "ctrl").Active(metav1.ConditionTrue).Obj())
+	if err := qManager.AddLocalQueue(ctx, q1); err != nil {
+		t.Fatalf("Inserting queue %s/%s in manager: %v", q1.Namespace, q1.Name, err)
+	}
+	if err := qManager.AddClusterQueue(ctx, cq); err != nil {
+		t.Fatalf("Inserting clusterQueue %s in manager: %v", cq.Name, err)
+	}
+	if err := cqCache.AddClusterQueue(ctx, cq); err != nil {
+		t.Fatalf("Inserting clusterQueue %s to cache: %v", cq.Name, err)
+	}
+	scheduler := New(qManager, cqCache, cl, recorder)
+	var mu sync.Mutex
+	gotChecks := make(map[string][]string)
+	scheduler.applyAdmission = func(ctx context.Context, w *kueue.Workload) error {
+		mu.Lock()
+		defer mu.Unlock()
+		gotChecks[workload.Key(w)] = w.Status.Admission.AdmissionChecks
+		return nil
+	}
+	wg := sync.WaitGroup{}
+	scheduler.setAdmissionRoutineWrapper(routine.NewWrapper(
+		func() { wg.Add(1) },
+		func() { wg.Done() },
+	))
+
+	ctx, cancel := context.WithTimeout(ctx, queueingTimeout)
+	go qManager.CleanUpOnContext(ctx)
+	defer cancel()
+
+	qManager.AddOrUpdateWorkload(a)
+	qManager.AddOrUpdateWorkload(b)
+	scheduler.schedule(ctx)
+	wg.Wait()
+	// Only the workload assigned the autoscaled flavor waits for its check.
+	wantChecks := map[string][]string{
+		workload.Key(a): nil,
+		workload.Key(b): {"provisioning"},
+	}
+	if diff := cmp.Diff(wantChecks, gotChecks); diff != "" {
+		t.Errorf("Unexpected admission checks (-want,+got):\n%s", diff)
+	}
+}
+
+func TestNominatePartialAdmissionWithPreemption(t *testing.T) {
+	lowPriority, midPriority, highPriority := int32(0), int32(10), int32(20)
+	// Preempting the three low priority workloads frees 6 CPUs.
+	partialCount := int32(6)
+	running := []kueue.Workload{
+		*utiltesting.MakeWorkload("low-1", "ns1").Priority(&lowPriority).Request(corev1.ResourceCPU, "2").
+			Admit(utiltesting.MakeAdmission("cq").Flavor(corev1.ResourceCPU, "default").Obj()).Obj(),
+		*utiltesting.MakeWorkload("low-2", "ns1").Priority(&lowPriority).Request(corev1.ResourceCPU, "2").
+			Admit(utiltesting.MakeAdmission("cq").Flavor(corev1.ResourceCPU, "default").Obj()).Obj(),
+		*utiltesting.MakeWorkload("low-3", "ns1").Priority(&lowPriority).Request(corev1.ResourceCPU, "2").
+			Admit(utiltesting.MakeAdmission("cq").Flavor(corev1.ResourceCPU, "default").Obj()).Obj(),
+		*utiltesting.MakeWorkload("high", "ns1").Priority(&highPriority).Request(corev1.ResourceCPU, "4").
+			Admit(utiltesting.MakeAdmission("cq").Flavor(corev1.ResourceCPU, "default").Obj()).Obj(),
+	}
+	cases := map[string]struct {
+		preemption kueue.ClusterQueuePreemption
+		minCount   int32
+		wantMode   flavorassigner.FlavorAssignmentMode
+		wantCount  *int32
+	}{
+		"largest count that fits after preempting the lower priority workloads": {
+			preemption: kueue.ClusterQueuePreemption{WithinClusterQueue: kueue.PreemptionPolicyLowerPriority},
+			minCount:   2,
+			wantMode:   flavorassigner.ClusterQueuePreempt,
+			wantCount:  &partialCount,
+		},
+		"minCount doesn't fit after preempting the lower priority workloads": {
+			preemption: kueue.ClusterQueuePreemption{WithinClusterQueue: kueue.PreemptionPolicyLowerPriority},
+			minCount:   7,
+			wantMode:   flavorassigner.ClusterQueuePreempt,
+		},
+		"no partial admission when the ClusterQueue doesn't preempt": {
+			preemption: kueue.ClusterQueuePreemption{WithinClusterQueue: kueue.PreemptionPolicyNever},
+			minCount:   2,
+			wantMode:   flavorassigner.ClusterQueuePreempt,
+		},
+	}
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			cq := utiltesting.MakeClusterQueue("cq").
+				Resource(utiltesting.MakeResource(corev1.ResourceCPU).
+					Flavor(utiltesting.MakeFlavor("default", "10").Obj()).Obj()).
+				Preemption(tc.preemption).
+				Obj()
+			q1 := utiltesting.MakeLocalQueue("q1", "ns1").ClusterQueue(cq.Name).Obj()
+			wl := utiltesting.MakeWorkload("wl", "ns1").Queue(q1.Name).Priority(&midPriority).
+				Request(corev1.ResourceCPU, "1").Count(10).MinCount(tc.minCount).Obj()
+
+			log := testr.NewWithOptions(t, testr.Options{
+				Verbosity: 2,
+			})
+			ctx := ctrl.LoggerInto(context.Background(), log)
+			scheme := runtime.NewScheme()
+			if err := kueue.AddToScheme(scheme); err != nil {
+				t.Fatalf("Failed adding kueue scheme: %v", err)
+			}
+			if err := corev1.AddToScheme(scheme); err != nil {
+				t.Fatalf("Failed adding kueue scheme: %v", err)
+			}
+			cl := fake.NewClientBuilder().WithScheme(scheme).
+				WithLists(&kueue.WorkloadList{Items: running}).
+				WithObjects(q1, &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "ns1"}}).
+				Build()
+			broadcaster := record.NewBroadcaster()
+			recorder := broadcaster.NewRecorder(scheme, corev1.EventSource{Component: constants.AdmissionName})
+			cqCache := cache.New(cl)
+			qManager := queue.NewManager(cl, cqCache)
+			cqCache.AddOrUpdateResourceFlavor(utiltesting.MakeResourceFlavor("default").Obj())
+			if err := cqCache.AddClusterQueue(ctx, cq); err != nil {
+				t.Fatalf("Inserting clusterQueue %s to cache: %v", cq.Name, err)
+			}
+			scheduler := New(qManager, cqCache, cl, recorder)
+
+			info := workload.NewInfo(wl)
+			info.ClusterQueue = cq.Name
+			entries := scheduler.nominate(ctx, []workload.Info{*info}, cqCache.Snapshot())
+			if len(entries) != 1 {
+				t.Fatalf("Got %d entries, want 1", len(entries))
+			}
+			assignment := entries[0].assignment
+			if got := assignment.RepresentativeMode(); got != tc.wantMode {
+				t.Errorf("Got assignment mode %s, want %s", got, tc.wantMode)
+			}
+			if len(assignment.PodSets) != 1 {
+				t.Fatalf("Got %d pod sets in the assignment, want 1", len(assignment.PodSets))
+			}
+			if diff := cmp.Diff(tc.wantCount, assignment.PodSets[0].Count); diff != "" {
+				t.Errorf("Unexpected assigned count (-want,+got):\n%s", diff)
+			}
+		})
+	}
+}
+
+// testPlugin rejects the workloads with the given name, scores the flavors by
+// name, denies the admission of the workloads with the given name and records
+// the admitted workloads.
+type testPlugin struct {
+	reject string
+	scores map[string]int64
+	deny   string
+
+	sync.Mutex
+	admitted []string
+}
+
+func (p *testPlugin) Name() string {
+	return "test"
+}
+
+func (p *testPlugin) PreFilter(_ context.Context, wl *workload.Info, _ *cache.ClusterQueue) error {
+	if wl.Obj.Name == p.reject {
+		return errors.New("workload is rejected")
+	}
+	return nil
+}
+
+func (p *testPlugin) ScoreFlavor(_ context.Context, _ *workload.Info, _ string, flavor *kueue.ResourceFlavor) int64 {
+	return p.scores[flavor.Name]
+}
+
+func (p *testPlugin) Permit(_ context.Context, wl *kueue.Workload, _ *kueue.Admission) error {
+	if wl.Name == p.deny {
+		return errors.New("admission is denied")
+	}
+	return nil
+}
+
+func (p *testPlugin) PostAdmit(_ context.Context, wl *kueue.Workload) {
+	p.Lock()
+	defer p.Unlock()
+	p.admitted = append(p.admitted, workload.Key(wl))
+}
+
+func TestSchedulePlugins(t *testing.T) {
+	cq := utiltesting.MakeClusterQueue("cq").
+		Resource(utiltesting.MakeResource(corev1.ResourceCPU).
+			Flavor(utiltesting.MakeFlavor("on-demand", "10").Obj()).
+			Flavor(utiltesting.MakeFlavor("spot", "10").Obj()).Obj()).
+		Obj()
+	q1 := utiltesting.MakeLocalQueue("q1", "ns1").ClusterQueue(cq.Name).Obj()
+	now := time.Now()
+	accepted := utiltesting.MakeWorkload("accepted", "ns1").Queue(q1.Name).Creation(now).Request(corev1.ResourceCPU, "1").Obj()
+	rejected := utiltesting.MakeWorkload("rejected", "ns1").Queue(q1.Name).Creation(now.Add(time.Second)).Request(corev1.ResourceCPU, "1").Obj()
+	denied := utiltesting.MakeWorkload("denied", "ns1").Queue(q1.Name).Creation(now.Add(2*time.Second)).Request(corev1.ResourceCPU, "1").Obj()
+
+	log := testr.NewWithOptions(t, testr.Options{
+		Verbosity: 2,
+	})
+	ctx := ctrl.LoggerInto(context.Background(), log)
+	scheme := runtime.NewScheme()
+	if err := kueue.AddToScheme(scheme); err != nil {
+		t.Fatalf("Failed adding kueue scheme: %v", err)
+	}
+	if err := corev1.AddToScheme(scheme); err != nil {
+		t.Fatalf("Failed adding kueue scheme: %v", err)
+	}
+	cl := fake.NewClientBuilder().WithScheme(scheme).WithObjects(accepted, rejected, denied, q1, &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "ns1"}}).Build()
+	broadcaster := record.NewBroadcaster()
+	recorder := broadcaster.NewRecorder(scheme, corev1.EventSource{Component: constants.AdmissionName})
+	cqCache := cache.New(cl)
+	qManager := queue.NewManager(cl, cqCache)
+	cqCache.AddOrUpdateResourceFlavor(utiltesting.MakeResourceFlavor("on-demand").Obj())
+	cqCache.AddOrUpdateResourceFlavor(utiltesting.MakeResourceFlavor("spot").Obj())
+	if err := qManager.AddLocalQueue(ctx, q1); err != nil {
+		t.Fatalf("Inserting queue %s/%s in manager: %v", q1.Namespace, q1.Name, err)
+	}
+	if err := qManager.AddClusterQueue(ctx, cq); err != nil {
+		t.Fatalf("Inserting clusterQueue %s in manager: %v", cq.Name, err)
+	}
+	if err := cqCache.AddClusterQueue(ctx, cq); err != nil {
+		t.Fatalf("Inserting clusterQueue %s to cache: %v", cq.Name, err)
+	}
+	plugin := &testPlugin{
+		reject: rejected.Name,
+		scores: map[string]int64{"spot": 1},
+		deny:   denied.Name,
+	}
+	scheduler := New(qManager, cqCache, cl, recorder, WithPlugins(plugin))
+	gotFlavors := make(map[string]string)
+	scheduler.applyAdmission = func(ctx context.Context, w *kueue.Workload) error {
+		gotFlavors[workload.Key(w)] = w.Status.Admission.PodSetFlavors[0].Flavors[corev1.ResourceCPU]
+		return nil
+	}
+	wg := sync.WaitGroup{}
+	scheduler.setAdmissionRoutineWrapper(routine.NewWrapper(
+		func() { wg.Add(1) },
+		func() { wg.Done() },
+	))
+
+	ctx, cancel := context.WithTimeout(ctx, queueingTimeout)
+	go qManager.CleanUpOnContext(ctx)
+	defer cancel()
+
+	qManager.AddOrUpdateWorkload(accepted)
+	qManager.AddOrUpdateWorkload(rejected)
+	qManager.AddOrUpdateWorkload(denied)
+	for i := 0; i < 3; i++ {
+		scheduler.schedule(ctx)
+		wg.Wait()
+	}
+	wantFlavors := map[string]string{
+		workload.Key(accepted): "spot",
+	}
+	if diff := cmp.Diff(wantFlavors, gotFlavors); diff != "" {
+		t.Errorf("Unexpected admissions (-want,+got):\n%s", diff)
+	}
+	if diff := cmp.Diff([]string{workload.Key(accepted)}, plugin.admitted); diff != "" {
+		t.Errorf("Unexpected workloads passed to PostAdmit (-want,+got):\n%s", diff)
+	}
+	wantInadmissible := map[string]sets.String{
+		cq.Name: sets.NewString(workload.Key(rejected), workload.Key(denied)),
+	}
+	if diff := cmp.Diff(wantInadmissible, qManager.DumpInadmissible()); diff != "" {
+		t.Errorf("Unexpected inadmissible workloads (-want,+got):\n%s", diff)
+	}
+}
+
+func TestStartWaitsForAdmissions(t *testing.T) {
+	cq := utiltesting.MakeClusterQueue("cq").
+		Resource(utiltesting.MakeResource(corev1.ResourceCPU).
+			Flavor(utiltesting.MakeFlavor("default", "10").Obj()).Obj()).
+		Obj()
+	q1 := utiltesting.MakeLocalQueue("q1", "ns1").ClusterQueue(cq.Name).Obj()
+	w1 := utiltesting.MakeWorkload("w1", "ns1").Queue(q1.Name).Request(corev1.ResourceCPU, "1").Obj()
+
+	log := testr.NewWithOptions(t, testr.Options{
+		Verbosity: 2,
+	})
+	ctx := ctrl.LoggerInto(context.Background(), log)
+	scheme := runtime.NewScheme()
+	if err := kueue.AddToScheme(scheme); err != nil {
+		t.Fatalf("Failed adding kueue scheme: %v", err)
+	}
+	if err := corev1.AddToScheme(scheme); err != nil {
+		t.Fatalf("Failed adding kueue scheme: %v", err)
+	}
+	cl := fake.NewClientBuilder().WithScheme(scheme).WithObjects(w1, q1, &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "ns1"}}).Build()
+	broadcaster := record.NewBroadcaster()
+	recorder := broadcaster.NewRecorder(scheme, corev1.EventSource{Component: constants.AdmissionName})
+	cqCache := cache.New(cl)
+	qManager := queue.NewManager(cl, cqCache)
+	cqCache.AddOrUpdateResourceFlavor(utiltesting.MakeResourceFlavor("default").Obj())
+	if err := qManager.AddLocalQueue(ctx, q1); err != nil {
+		t.Fatalf("Inserting queue %s/%s in manager: %v", q1.Namespace, q1.Name, err)
+	}
+	if err := qManager.AddClusterQueue(ctx, cq); err != nil {
+		t.Fatalf("Inserting clusterQueue %s in manager: %v", cq.Name, err)
+	}
+	if err := cqCache.AddClusterQueue(ctx, cq); err != nil {
+		t.Fatalf("Inserting clusterQueue %s to cache: %v", cq.Name, err)
+	}
+	scheduler := New(qManager, cqCache, cl, recorder)
+	applying := make(chan struct{})
+	release := make(chan struct{})
+	var applied int32
+	scheduler.applyAdmission = func(ctx context.Context, w *kueue.Workload) error {
+		close(applying)
+		<-release
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		atomic.AddInt32(&applied, 1)
+		return nil
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	stopped := make(chan struct{})
+	go func() {
+		if err := scheduler.Start(ctx); err != nil {
+			t.Errorf("Unexpected error from the scheduler: %v", err)
+		}
+		close(stopped)
+	}()
+
+	<-applying
+	cancel()
+	select {
+	case <-stopped:
+		t.Fatal("The scheduler stopped before the admission in flight was applied")
+	case <-time.After(100 * time.Millisecond):
+	}
+	close(release)
+	<-stopped
+	if atomic.LoadInt32(&applied) != 1 {
+		t.Errorf("The admission in flight was not applied")
+	}
+}
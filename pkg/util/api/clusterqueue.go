@@ -0,0 +1,52 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package api
+
+import (
+	kueue "sigs.k8s.io/kueue/apis/kueue/v1alpha2"
+)
+
+// ClusterQueueResources returns the resources of the ClusterQueue, including
+// the resources covered by its resource groups. The resources of a group get
+// the flavors of the group, in the same order, so they are codependent.
+func ClusterQueueResources(cq *kueue.ClusterQueue) []kueue.Resource {
+	if len(cq.Spec.ResourceGroups) == 0 {
+		return cq.Spec.Resources
+	}
+	resources := make([]kueue.Resource, 0, len(cq.Spec.Resources))
+	resources = append(resources, cq.Spec.Resources...)
+	for _, group := range cq.Spec.ResourceGroups {
+		for _, rName := range group.CoveredResources {
+			res := kueue.Resource{
+				Name:    rName,
+				Flavors: make([]kueue.Flavor, 0, len(group.Flavors)),
+			}
+			for _, f := range group.Flavors {
+				flavor := kueue.Flavor{Name: f.Name, Hold: f.Hold, AdmissionChecks: f.AdmissionChecks}
+				for _, rq := range f.Resources {
+					if rq.Name == rName {
+						flavor.Quota = rq.Quota
+						break
+					}
+				}
+				res.Flavors = append(res.Flavors, flavor)
+			}
+			resources = append(resources, res)
+		}
+	}
+	return resources
+}
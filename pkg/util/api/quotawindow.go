@@ -0,0 +1,146 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package api
+
+import (
+	"time"
+	// The base image of the manager doesn't include the time zone database.
+	_ "time/tzdata"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/util/sets"
+
+	kueue "sigs.k8s.io/kueue/apis/kueue/v1alpha2"
+)
+
+// TimeOfDayLayout is the layout of the start and end of a quota window.
+const TimeOfDayLayout = "15:04"
+
+// ActiveQuotaWindows returns the names of the windows that are active at the
+// given time, in the order in which they are listed.
+func ActiveQuotaWindows(windows []kueue.QuotaWindow, now time.Time) []string {
+	var active []string
+	for i := range windows {
+		for _, o := range occurrences(&windows[i], now) {
+			if !now.Before(o.start) && now.Before(o.end) {
+				active = append(active, windows[i].Name)
+				break
+			}
+		}
+	}
+	return active
+}
+
+// NextQuotaWindowTransition returns the earliest time after the given one at
+// which one of the windows starts or ends, or the zero time if there are no
+// windows.
+func NextQuotaWindowTransition(windows []kueue.QuotaWindow, now time.Time) time.Time {
+	var next time.Time
+	for i := range windows {
+		for _, o := range occurrences(&windows[i], now) {
+			for _, t := range []time.Time{o.start, o.end} {
+				if t.After(now) && (next.IsZero() || t.Before(next)) {
+					next = t
+				}
+			}
+		}
+	}
+	return next
+}
+
+// ApplyQuotaWindows returns the resources with the quotas of the active
+// windows replacing the quotas of the same resources and flavors. The input
+// resources are not modified.
+func ApplyQuotaWindows(resources []kueue.Resource, windows []kueue.QuotaWindow, active []string) []kueue.Resource {
+	if len(active) == 0 {
+		return resources
+	}
+	activeSet := sets.NewString(active...)
+	quotas := make(map[corev1.ResourceName]map[kueue.ResourceFlavorReference]kueue.Quota)
+	for _, w := range windows {
+		if !activeSet.Has(w.Name) {
+			continue
+		}
+		for _, q := range w.Quotas {
+			if quotas[q.Resource] == nil {
+				quotas[q.Resource] = make(map[kueue.ResourceFlavorReference]kueue.Quota)
+			}
+			quotas[q.Resource][q.Flavor] = q.Quota
+		}
+	}
+	out := make([]kueue.Resource, len(resources))
+	for i, r := range resources {
+		out[i] = r
+		rQuotas, ok := quotas[r.Name]
+		if !ok {
+			continue
+		}
+		out[i].Flavors = make([]kueue.Flavor, len(r.Flavors))
+		copy(out[i].Flavors, r.Flavors)
+		for j := range out[i].Flavors {
+			if q, ok := rQuotas[out[i].Flavors[j].Name]; ok {
+				out[i].Flavors[j].Quota = q
+			}
+		}
+	}
+	return out
+}
+
+type occurrence struct {
+	start time.Time
+	end   time.Time
+}
+
+// occurrences returns the occurrences of the window that start from the day
+// before the given time until a week later, which include the active one and
+// the next start and end.
+func occurrences(w *kueue.QuotaWindow, now time.Time) []occurrence {
+	start, err := time.Parse(TimeOfDayLayout, w.Start)
+	if err != nil {
+		return nil
+	}
+	end, err := time.Parse(TimeOfDayLayout, w.End)
+	if err != nil {
+		return nil
+	}
+	loc := time.UTC
+	if w.TimeZone != "" {
+		if l, err := time.LoadLocation(w.TimeZone); err == nil {
+			loc = l
+		}
+	}
+	days := sets.NewString()
+	for _, d := range w.Days {
+		days.Insert(string(d))
+	}
+	y, m, d := now.In(loc).Date()
+	var result []occurrence
+	for i := -1; i <= 7; i++ {
+		o := occurrence{
+			start: time.Date(y, m, d+i, start.Hour(), start.Minute(), 0, 0, loc),
+			end:   time.Date(y, m, d+i, end.Hour(), end.Minute(), 0, 0, loc),
+		}
+		if len(days) > 0 && !days.Has(o.start.Weekday().String()) {
+			continue
+		}
+		if !o.end.After(o.start) {
+			o.end = time.Date(y, m, d+i+1, end.Hour(), end.Minute(), 0, 0, loc)
+		}
+		result = append(result, o)
+	}
+	return result
+}
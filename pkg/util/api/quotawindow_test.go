@@ -0,0 +1,153 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package api
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+
+	kueue "sigs.k8s.io/kueue/apis/kueue/v1alpha2"
+)
+
+func TestQuotaWindows(t *testing.T) {
+	// Friday.
+	friday := time.Date(2022, 10, 14, 0, 0, 0, 0, time.UTC)
+	nightly := kueue.QuotaWindow{Name: "nightly", Start: "20:00", End: "06:00"}
+	weekend := kueue.QuotaWindow{Name: "weekend", Days: []kueue.Weekday{"Saturday", "Sunday"}, Start: "00:00", End: "00:00"}
+	madrid := kueue.QuotaWindow{Name: "madrid", Start: "09:00", End: "10:00", TimeZone: "Europe/Madrid"}
+	cases := map[string]struct {
+		windows    []kueue.QuotaWindow
+		now        time.Time
+		wantActive []string
+		wantNext   time.Time
+	}{
+		"no windows": {
+			now: friday,
+		},
+		"before a window": {
+			windows:  []kueue.QuotaWindow{nightly},
+			now:      friday.Add(12 * time.Hour),
+			wantNext: friday.Add(20 * time.Hour),
+		},
+		"in a window that started the day before": {
+			windows:    []kueue.QuotaWindow{nightly},
+			now:        friday.Add(time.Hour),
+			wantActive: []string{"nightly"},
+			wantNext:   friday.Add(6 * time.Hour),
+		},
+		"at the end of a window": {
+			windows:  []kueue.QuotaWindow{nightly},
+			now:      friday.Add(6 * time.Hour),
+			wantNext: friday.Add(20 * time.Hour),
+		},
+		"before a window on some days": {
+			windows:  []kueue.QuotaWindow{weekend},
+			now:      friday.Add(12 * time.Hour),
+			wantNext: friday.Add(24 * time.Hour),
+		},
+		"in a window on some days": {
+			windows:    []kueue.QuotaWindow{weekend, nightly},
+			now:        friday.Add(46 * time.Hour),
+			wantActive: []string{"weekend", "nightly"},
+			wantNext:   friday.Add(48 * time.Hour),
+		},
+		"in a window in another time zone": {
+			windows:    []kueue.QuotaWindow{madrid},
+			now:        friday.Add(7*time.Hour + 30*time.Minute),
+			wantActive: []string{"madrid"},
+			wantNext:   friday.Add(8 * time.Hour),
+		},
+	}
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			gotActive := ActiveQuotaWindows(tc.windows, tc.now)
+			if diff := cmp.Diff(tc.wantActive, gotActive); diff != "" {
+				t.Errorf("Unexpected active windows (-want,+got):\n%s", diff)
+			}
+			gotNext := NextQuotaWindowTransition(tc.windows, tc.now)
+			if !gotNext.Equal(tc.wantNext) {
+				t.Errorf("Got next transition %v, want %v", gotNext, tc.wantNext)
+			}
+		})
+	}
+}
+
+func TestApplyQuotaWindows(t *testing.T) {
+	resources := []kueue.Resource{
+		{
+			Name: corev1.ResourceCPU,
+			Flavors: []kueue.Flavor{
+				{Name: "on-demand", Quota: kueue.Quota{Min: resource.MustParse("10")}},
+				{Name: "spot", Quota: kueue.Quota{Min: resource.MustParse("5")}},
+			},
+		},
+		{
+			Name: corev1.ResourceMemory,
+			Flavors: []kueue.Flavor{
+				{Name: "on-demand", Quota: kueue.Quota{Min: resource.MustParse("10Gi")}},
+			},
+		},
+	}
+	windows := []kueue.QuotaWindow{
+		{
+			Name: "nightly",
+			Quotas: []kueue.WindowQuota{
+				{Resource: corev1.ResourceCPU, Flavor: "spot", Quota: kueue.Quota{Min: resource.MustParse("20")}},
+			},
+		},
+		{
+			Name: "weekend",
+			Quotas: []kueue.WindowQuota{
+				{Resource: corev1.ResourceCPU, Flavor: "spot", Quota: kueue.Quota{Min: resource.MustParse("30")}},
+			},
+		},
+	}
+	cases := map[string]struct {
+		active   []string
+		wantSpot resource.Quantity
+	}{
+		"no active windows": {
+			wantSpot: resource.MustParse("5"),
+		},
+		"one active window": {
+			active:   []string{"nightly"},
+			wantSpot: resource.MustParse("20"),
+		},
+		"the last active window wins": {
+			active:   []string{"nightly", "weekend"},
+			wantSpot: resource.MustParse("30"),
+		},
+	}
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			got := ApplyQuotaWindows(resources, windows, tc.active)
+			if got[0].Flavors[1].Quota.Min.Cmp(tc.wantSpot) != 0 {
+				t.Errorf("Got spot cpu quota %s, want %s", got[0].Flavors[1].Quota.Min.String(), tc.wantSpot.String())
+			}
+			if diff := cmp.Diff(resources[1], got[1]); diff != "" {
+				t.Errorf("Unexpected memory resource (-want,+got):\n%s", diff)
+			}
+			if resources[0].Flavors[1].Quota.Min.Cmp(resource.MustParse("5")) != 0 {
+				t.Errorf("The input resources were modified")
+			}
+		})
+	}
+}
@@ -149,6 +149,20 @@ func (h *Heap) Pop() interface{} {
 	return heap.Pop(&h.data)
 }
 
+// Peek returns the head of the heap without removing it.
+func (h *Heap) Peek() interface{} {
+	if h.Len() == 0 {
+		return nil
+	}
+	return h.data.items[h.data.keys[0]].obj
+}
+
+// Reorder restores the order of the heap. It's needed when the result of the
+// less function for the items in the heap changes, for example, with time.
+func (h *Heap) Reorder() {
+	heap.Init(&h.data)
+}
+
 // Get returns the requested item, exists, error.
 func (h *Heap) Get(obj interface{}) (item interface{}) {
 	key := h.data.keyFunc(obj)
@@ -216,6 +216,47 @@ func TestHeap_Update(t *testing.T) {
 	}
 }
 
+// TestHeap_Peek tests Heap.Peek.
+func TestHeap_Peek(t *testing.T) {
+	h := New(testHeapObjectKeyFunc, compareInts)
+	if obj := h.Peek(); obj != nil {
+		t.Fatalf("didn't expect to get any object from an empty heap")
+	}
+	h.PushOrUpdate(mkHeapObj("foo", 10))
+	h.PushOrUpdate(mkHeapObj("bar", 1))
+	h.PushOrUpdate(mkHeapObj("baz", 11))
+
+	obj := h.Peek()
+	if obj == nil || obj.(testHeapObject).val != 1 {
+		t.Fatalf("expected bar to be at the head")
+	}
+	if h.Len() != 3 {
+		t.Fatalf("expected the head to remain in the heap")
+	}
+}
+
+// TestHeap_Reorder tests Heap.Reorder.
+func TestHeap_Reorder(t *testing.T) {
+	descending := false
+	h := New(testHeapObjectKeyFunc, func(a, b interface{}) bool {
+		if descending {
+			return compareInts(b, a)
+		}
+		return compareInts(a, b)
+	})
+	h.PushOrUpdate(mkHeapObj("foo", 10))
+	h.PushOrUpdate(mkHeapObj("bar", 1))
+	h.PushOrUpdate(mkHeapObj("baz", 11))
+
+	descending = true
+	h.Reorder()
+	for _, want := range []int{11, 10, 1} {
+		if obj := h.Pop(); obj.(testHeapObject).val != want {
+			t.Fatalf("expected %d to be at the head, got %v", want, obj.(testHeapObject).val)
+		}
+	}
+}
+
 // TestHeap_Get tests Heap.Get.
 func TestHeap_Get(t *testing.T) {
 	h := New(testHeapObjectKeyFunc, compareInts)
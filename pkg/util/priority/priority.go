@@ -38,6 +38,31 @@ func Priority(w *kueue.Workload) int32 {
 	return constants.DefaultPriority
 }
 
+// Band returns the name of the priority band of the given workload: the band
+// with the highest minPriority that is not above the priority of the workload,
+// or the band with the lowest minPriority if there is none. It returns an
+// empty string if there are no bands.
+func Band(bands []kueue.PriorityBand, w *kueue.Workload) string {
+	p := Priority(w)
+	var band, lowest *kueue.PriorityBand
+	for i := range bands {
+		b := &bands[i]
+		if lowest == nil || b.MinPriority < lowest.MinPriority {
+			lowest = b
+		}
+		if b.MinPriority <= p && (band == nil || b.MinPriority > band.MinPriority) {
+			band = b
+		}
+	}
+	if band == nil {
+		band = lowest
+	}
+	if band == nil {
+		return ""
+	}
+	return band.Name
+}
+
 // GetPriorityFromPriorityClass returns the priority populated from
 // priority class. If not specified, priority will be default or
 // zero if there is no default.
@@ -55,6 +80,26 @@ func GetPriorityFromPriorityClass(ctx context.Context, client client.Client,
 	return pc.Name, pc.Value, nil
 }
 
+// GetPriorityFromWorkloadPriorityClass returns the value of the
+// WorkloadPriorityClass.
+func GetPriorityFromWorkloadPriorityClass(ctx context.Context, client client.Client,
+	workloadPriorityClass string) (int32, error) {
+	wpc := &kueue.WorkloadPriorityClass{}
+	if err := client.Get(ctx, types.NamespacedName{Name: workloadPriorityClass}, wpc); err != nil {
+		return 0, err
+	}
+	return wpc.Value, nil
+}
+
+// PriorityClassSource returns the priorityClassSource of a workload whose
+// priorityClassName was taken from the PriorityClasses.
+func PriorityClassSource(priorityClassName string) string {
+	if len(priorityClassName) == 0 {
+		return ""
+	}
+	return kueue.PodPriorityClassSource
+}
+
 func getDefaultPriority(ctx context.Context, client client.Client) (string, int32, error) {
 	dpc, err := getDefaultPriorityClass(ctx, client)
 	if err != nil {
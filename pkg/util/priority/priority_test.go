@@ -59,6 +59,53 @@ func TestPriority(t *testing.T) {
 	}
 }
 
+func TestBand(t *testing.T) {
+	bands := []kueue.PriorityBand{
+		{Name: "low", MinPriority: 0},
+		{Name: "high", MinPriority: 1000},
+		{Name: "medium", MinPriority: 100},
+	}
+	tests := map[string]struct {
+		bands    []kueue.PriorityBand
+		priority int32
+		want     string
+	}{
+		"no bands": {
+			priority: 100,
+		},
+		"priority of the lowest band": {
+			bands:    bands,
+			priority: 0,
+			want:     "low",
+		},
+		"priority between bands": {
+			bands:    bands,
+			priority: 999,
+			want:     "medium",
+		},
+		"priority above the highest band": {
+			bands:    bands,
+			priority: 2000,
+			want:     "high",
+		},
+		"priority below the lowest band": {
+			bands:    bands,
+			priority: -10,
+			want:     "low",
+		},
+	}
+
+	for desc, tt := range tests {
+		t.Run(desc, func(t *testing.T) {
+			wl := utiltesting.MakeWorkload("name", "ns").Priority(pointer.Int32(tt.priority)).Obj()
+			got := Band(tt.bands, wl)
+			if got != tt.want {
+				t.Errorf("Band does not match: got: %q, expected: %q", got, tt.want)
+			}
+		})
+	}
+}
+
 func TestGetPriorityFromPriorityClass(t *testing.T) {
 	scheme := runtime.NewScheme()
 	if err := schedulingv1.AddToScheme(scheme); err != nil {
@@ -164,3 +211,26 @@ func TestGetPriorityFromPriorityClass(t *testing.T) {
 		})
 	}
 }
+
+func TestGetPriorityFromWorkloadPriorityClass(t *testing.T) {
+	scheme := runtime.NewScheme()
+	if err := kueue.AddToScheme(scheme); err != nil {
+		t.Fatalf("Failed adding kueue scheme: %v", err)
+	}
+	client := fake.NewClientBuilder().WithScheme(scheme).
+		WithObjects(utiltesting.MakeWorkloadPriorityClass("high").PriorityValue(1000).Obj()).
+		Build()
+
+	value, err := GetPriorityFromWorkloadPriorityClass(context.Background(), client, "high")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if value != 1000 {
+		t.Errorf("unexpected value: got: %d, expected: %d", value, 1000)
+	}
+
+	wantErr := `workloadpriorityclasses.kueue.x-k8s.io "low" not found`
+	if _, err := GetPriorityFromWorkloadPriorityClass(context.Background(), client, "low"); err == nil || err.Error() != wantErr {
+		t.Errorf("unexpected error: got: %v, expected: %s", err, wantErr)
+	}
+}
@@ -17,6 +17,7 @@ limitations under the License.
 package testing
 
 import (
+	"strconv"
 	"time"
 
 	batchv1 "k8s.io/api/batch/v1"
@@ -87,12 +88,27 @@ func (j *JobWrapper) PriorityClass(pc string) *JobWrapper {
 	return j
 }
 
+// Label sets a label of the job.
+func (j *JobWrapper) Label(k, v string) *JobWrapper {
+	if j.Labels == nil {
+		j.Labels = make(map[string]string)
+	}
+	j.Labels[k] = v
+	return j
+}
+
 // Queue updates the queue name of the job
 func (j *JobWrapper) Queue(queue string) *JobWrapper {
 	j.Annotations[constants.QueueAnnotation] = queue
 	return j
 }
 
+// Annotation sets an annotation of the job.
+func (j *JobWrapper) Annotation(k, v string) *JobWrapper {
+	j.Annotations[k] = v
+	return j
+}
+
 // Toleration adds a toleration to the job.
 func (j *JobWrapper) Toleration(t corev1.Toleration) *JobWrapper {
 	j.Spec.Template.Spec.Tolerations = append(j.Spec.Template.Spec.Tolerations, t)
@@ -121,6 +137,92 @@ func (j *JobWrapper) Image(name string, image string, args []string) *JobWrapper
 	return j
 }
 
+// PodWrapper wraps a Pod.
+type PodWrapper struct{ corev1.Pod }
+
+// MakePod creates a wrapper for a pending pod with a single container.
+func MakePod(name, ns string) *PodWrapper {
+	return &PodWrapper{corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        name,
+			Namespace:   ns,
+			Labels:      make(map[string]string, 1),
+			Annotations: make(map[string]string, 1),
+		},
+		Spec: corev1.PodSpec{
+			RestartPolicy: corev1.RestartPolicyNever,
+			Containers: []corev1.Container{
+				{
+					Name:      "c",
+					Image:     "pause",
+					Resources: corev1.ResourceRequirements{Requests: corev1.ResourceList{}},
+				},
+			},
+			NodeSelector: map[string]string{},
+		},
+		Status: corev1.PodStatus{
+			Phase: corev1.PodPending,
+		},
+	}}
+}
+
+// Obj returns the inner Pod.
+func (p *PodWrapper) Obj() *corev1.Pod {
+	return &p.Pod
+}
+
+// Queue sets the queue name label of the pod.
+func (p *PodWrapper) Queue(queue string) *PodWrapper {
+	return p.Label(constants.QueueAnnotation, queue)
+}
+
+// Group sets the pod group of the pod, with the total count of pods of the
+// group.
+func (p *PodWrapper) Group(name string, totalCount int) *PodWrapper {
+	p.Label(constants.PodGroupNameLabel, name)
+	return p.Annotation(constants.PodGroupTotalCountAnnotation, strconv.Itoa(totalCount))
+}
+
+// Managed sets the label of the pods managed by Kueue.
+func (p *PodWrapper) Managed() *PodWrapper {
+	return p.Label(constants.ManagedLabel, "true")
+}
+
+// Gated adds the admission gate node selector to the pod.
+func (p *PodWrapper) Gated() *PodWrapper {
+	return p.NodeSelector(constants.AdmissionGateNodeSelector, "true")
+}
+
+// Label sets a label of the pod.
+func (p *PodWrapper) Label(k, v string) *PodWrapper {
+	p.Labels[k] = v
+	return p
+}
+
+// Annotation sets an annotation of the pod.
+func (p *PodWrapper) Annotation(k, v string) *PodWrapper {
+	p.Annotations[k] = v
+	return p
+}
+
+// NodeSelector adds a node selector to the pod.
+func (p *PodWrapper) NodeSelector(k, v string) *PodWrapper {
+	p.Spec.NodeSelector[k] = v
+	return p
+}
+
+// Request adds a resource request to the default container.
+func (p *PodWrapper) Request(r corev1.ResourceName, v string) *PodWrapper {
+	p.Spec.Containers[0].Resources.Requests[r] = resource.MustParse(v)
+	return p
+}
+
+// Phase sets the phase of the pod.
+func (p *PodWrapper) Phase(phase corev1.PodPhase) *PodWrapper {
+	p.Status.Phase = phase
+	return p
+}
+
 // PriorityClassWrapper wraps a PriorityClass.
 type PriorityClassWrapper struct {
 	schedulingv1.PriorityClass
@@ -146,6 +248,27 @@ func (p *PriorityClassWrapper) Obj() *schedulingv1.PriorityClass {
 	return &p.PriorityClass
 }
 
+// WorkloadPriorityClassWrapper wraps a WorkloadPriorityClass.
+type WorkloadPriorityClassWrapper struct{ kueue.WorkloadPriorityClass }
+
+// MakeWorkloadPriorityClass creates a wrapper for a WorkloadPriorityClass.
+func MakeWorkloadPriorityClass(name string) *WorkloadPriorityClassWrapper {
+	return &WorkloadPriorityClassWrapper{kueue.WorkloadPriorityClass{
+		ObjectMeta: metav1.ObjectMeta{Name: name},
+	}}
+}
+
+// PriorityValue sets the value of the WorkloadPriorityClass.
+func (p *WorkloadPriorityClassWrapper) PriorityValue(v int32) *WorkloadPriorityClassWrapper {
+	p.Value = v
+	return p
+}
+
+// Obj returns the inner WorkloadPriorityClass.
+func (p *WorkloadPriorityClassWrapper) Obj() *kueue.WorkloadPriorityClass {
+	return &p.WorkloadPriorityClass
+}
+
 type WorkloadWrapper struct{ kueue.Workload }
 
 // MakeWorkload creates a wrapper for a Workload with a single
@@ -183,12 +306,32 @@ func (w *WorkloadWrapper) Request(r corev1.ResourceName, q string) *WorkloadWrap
 	return w
 }
 
+// Count sets the number of pods of the first podSet.
+func (w *WorkloadWrapper) Count(c int32) *WorkloadWrapper {
+	w.Spec.PodSets[0].Count = c
+	return w
+}
+
+// MinCount sets the minimum number of pods of the first podSet, for partial
+// admission.
+func (w *WorkloadWrapper) MinCount(c int32) *WorkloadWrapper {
+	w.Spec.PodSets[0].MinCount = &c
+	return w
+}
+
 func (w *WorkloadWrapper) Queue(q string) *WorkloadWrapper {
 	w.Spec.QueueName = q
 	return w
 }
 
 func (w *WorkloadWrapper) Admit(a *kueue.Admission) *WorkloadWrapper {
+	w.Status.Admission = a
+	return w
+}
+
+// DeprecatedAdmission sets the admission in the deprecated spec.admission
+// field, as in the workloads admitted by older versions of Kueue.
+func (w *WorkloadWrapper) DeprecatedAdmission(a *kueue.Admission) *WorkloadWrapper {
 	w.Spec.Admission = a
 	return w
 }
@@ -198,11 +341,43 @@ func (w *WorkloadWrapper) Creation(t time.Time) *WorkloadWrapper {
 	return w
 }
 
+func (w *WorkloadWrapper) Deadline(t time.Time) *WorkloadWrapper {
+	w.Spec.Deadline = &metav1.Time{Time: t}
+	return w
+}
+
+func (w *WorkloadWrapper) ExpectedDuration(d time.Duration) *WorkloadWrapper {
+	w.Spec.ExpectedDuration = &metav1.Duration{Duration: d}
+	return w
+}
+
+func (w *WorkloadWrapper) Active(a bool) *WorkloadWrapper {
+	w.Spec.Active = pointer.Bool(a)
+	return w
+}
+
+func (w *WorkloadWrapper) ManagedBy(m string) *WorkloadWrapper {
+	w.Spec.ManagedBy = m
+	return w
+}
+
+func (w *WorkloadWrapper) Evictions(n int32) *WorkloadWrapper {
+	w.Status.Evictions = n
+	return w
+}
+
 func (w *WorkloadWrapper) PriorityClass(priorityClassName string) *WorkloadWrapper {
 	w.Spec.PriorityClassName = priorityClassName
 	return w
 }
 
+// PriorityClassSource sets the kind of the priorityClassName of the
+// Workload.
+func (w *WorkloadWrapper) PriorityClassSource(source string) *WorkloadWrapper {
+	w.Spec.PriorityClassSource = source
+	return w
+}
+
 func (w *WorkloadWrapper) RuntimeClass(name string) *WorkloadWrapper {
 	for i := range w.Spec.PodSets {
 		w.Spec.PodSets[i].Spec.RuntimeClassName = &name
@@ -235,6 +410,83 @@ func (w *WorkloadWrapper) Condition(condition metav1.Condition) *WorkloadWrapper
 	return w
 }
 
+func (w *WorkloadWrapper) Finalizers(f ...string) *WorkloadWrapper {
+	w.ObjectMeta.Finalizers = f
+	return w
+}
+
+func (w *WorkloadWrapper) Deleted(t time.Time) *WorkloadWrapper {
+	w.DeletionTimestamp = &metav1.Time{Time: t}
+	return w
+}
+
+// MoveToQueue sets the LocalQueue to move the admitted workload to.
+func (w *WorkloadWrapper) MoveToQueue(q string) *WorkloadWrapper {
+	if w.Annotations == nil {
+		w.Annotations = make(map[string]string)
+	}
+	w.Annotations[constants.MoveToQueueAnnotation] = q
+	return w
+}
+
+// PodTemplateHash sets the hash of the pod template of the owner job.
+func (w *WorkloadWrapper) PodTemplateHash(h string) *WorkloadWrapper {
+	if w.Annotations == nil {
+		w.Annotations = make(map[string]string)
+	}
+	w.Annotations[constants.PodTemplateHashAnnotation] = h
+	return w
+}
+
+// AdmissionGroup makes the workload a member of the admission group with the
+// given name and size.
+func (w *WorkloadWrapper) AdmissionGroup(name string, size int) *WorkloadWrapper {
+	if w.Labels == nil {
+		w.Labels = make(map[string]string)
+	}
+	w.Labels[constants.AdmissionGroupLabel] = name
+	if w.Annotations == nil {
+		w.Annotations = make(map[string]string)
+	}
+	w.Annotations[constants.AdmissionGroupSizeAnnotation] = strconv.Itoa(size)
+	return w
+}
+
+// DependsOn sets the name of the workload that the workload depends on.
+func (w *WorkloadWrapper) DependsOn(name string) *WorkloadWrapper {
+	if w.Annotations == nil {
+		w.Annotations = make(map[string]string)
+	}
+	w.Annotations[constants.DependsOnAnnotation] = name
+	return w
+}
+
+// FlavorEvictions records evictions of the workload from the flavor at the
+// given times.
+func (w *WorkloadWrapper) FlavorEvictions(flavor string, times ...time.Time) *WorkloadWrapper {
+	fe := kueue.FlavorEvictions{Flavor: flavor}
+	for _, t := range times {
+		fe.Times = append(fe.Times, metav1.NewTime(t))
+	}
+	w.Status.FlavorEvictions = append(w.Status.FlavorEvictions, fe)
+	return w
+}
+
+// ReclaimablePods sets the reclaimable pods of the workload.
+func (w *WorkloadWrapper) ReclaimablePods(rps ...kueue.ReclaimablePod) *WorkloadWrapper {
+	w.Status.ReclaimablePods = rps
+	return w
+}
+
+// AdmissionCheck appends the state of an admission check.
+func (w *WorkloadWrapper) AdmissionCheck(name string, state kueue.CheckState) *WorkloadWrapper {
+	w.Status.AdmissionChecks = append(w.Status.AdmissionChecks, kueue.AdmissionCheckState{
+		Name:  name,
+		State: state,
+	})
+	return w
+}
+
 // AdmissionWrapper wraps an Admission
 type AdmissionWrapper struct{ kueue.Admission }
 
@@ -273,6 +525,34 @@ func (w *AdmissionWrapper) Flavor(r corev1.ResourceName, f string) *AdmissionWra
 	return w
 }
 
+// Count sets the number of pods admitted for the first podSet.
+func (w *AdmissionWrapper) Count(c int32) *AdmissionWrapper {
+	w.PodSetFlavors[0].Count = &c
+	return w
+}
+
+// Split adds a part of the first podSet, with count pods admitted with the
+// flavor f for the resource r.
+func (w *AdmissionWrapper) Split(r corev1.ResourceName, f string, count int32) *AdmissionWrapper {
+	w.PodSetFlavors[0].Splits = append(w.PodSetFlavors[0].Splits, kueue.PodSetSplit{
+		Flavors: map[corev1.ResourceName]string{r: f},
+		Count:   count,
+	})
+	return w
+}
+
+// Revocable marks the admission as revocable.
+func (w *AdmissionWrapper) Revocable() *AdmissionWrapper {
+	w.Admission.Revocable = true
+	return w
+}
+
+// AdmissionChecks sets the admission checks that the workload must pass.
+func (w *AdmissionWrapper) AdmissionChecks(checks ...string) *AdmissionWrapper {
+	w.Admission.AdmissionChecks = checks
+	return w
+}
+
 // LocalQueueWrapper wraps a Queue.
 type LocalQueueWrapper struct{ kueue.LocalQueue }
 
@@ -297,6 +577,47 @@ func (q *LocalQueueWrapper) ClusterQueue(c string) *LocalQueueWrapper {
 	return q
 }
 
+// Limit sets a limit for the resource in the queue.
+func (q *LocalQueueWrapper) Limit(r corev1.ResourceName, v string) *LocalQueueWrapper {
+	if q.Spec.Limits == nil {
+		q.Spec.Limits = make(corev1.ResourceList)
+	}
+	q.Spec.Limits[r] = resource.MustParse(v)
+	return q
+}
+
+// LimitPercent sets a limit for the resource in the queue, as a percentage of
+// the quota of the ClusterQueue.
+func (q *LocalQueueWrapper) LimitPercent(r corev1.ResourceName, pct int32) *LocalQueueWrapper {
+	if q.Spec.LimitsPercent == nil {
+		q.Spec.LimitsPercent = make(map[corev1.ResourceName]int32)
+	}
+	q.Spec.LimitsPercent[r] = pct
+	return q
+}
+
+// Toleration adds a toleration to the queue.
+func (q *LocalQueueWrapper) Toleration(t corev1.Toleration) *LocalQueueWrapper {
+	q.Spec.Tolerations = append(q.Spec.Tolerations, t)
+	return q
+}
+
+// PriorityClass sets the default priorityClassName of the queue.
+func (q *LocalQueueWrapper) PriorityClass(name string) *LocalQueueWrapper {
+	q.Spec.PriorityClassName = name
+	return q
+}
+
+// RoutePriorityClass routes the workloads with the priorityClassName to the
+// clusterQueue.
+func (q *LocalQueueWrapper) RoutePriorityClass(priorityClass, cq string) *LocalQueueWrapper {
+	q.Spec.PriorityClassRouting = append(q.Spec.PriorityClassRouting, kueue.PriorityClassRoute{
+		PriorityClassName: priorityClass,
+		ClusterQueue:      kueue.ClusterQueueReference(cq),
+	})
+	return q
+}
+
 // PendingWorkloads updates the pendingWorkloads in status.
 func (q *LocalQueueWrapper) PendingWorkloads(n int32) *LocalQueueWrapper {
 	q.Status.PendingWorkloads = n
@@ -331,24 +652,201 @@ func (c *ClusterQueueWrapper) Cohort(cohort string) *ClusterQueueWrapper {
 	return c
 }
 
+// ClassName sets the ClusterQueueClass.
+func (c *ClusterQueueWrapper) ClassName(name string) *ClusterQueueWrapper {
+	c.Spec.ClassName = name
+	return c
+}
+
 // Resource adds a resource with flavors.
 func (c *ClusterQueueWrapper) Resource(r *kueue.Resource) *ClusterQueueWrapper {
 	c.Spec.Resources = append(c.Spec.Resources, *r)
 	return c
 }
 
+// ResourceGroup appends a resource group.
+func (c *ClusterQueueWrapper) ResourceGroup(g *kueue.ResourceGroup) *ClusterQueueWrapper {
+	c.Spec.ResourceGroups = append(c.Spec.ResourceGroups, *g)
+	return c
+}
+
 // QueueingStrategy sets the queueing strategy in this ClusterQueue.
 func (c *ClusterQueueWrapper) QueueingStrategy(strategy kueue.QueueingStrategy) *ClusterQueueWrapper {
 	c.Spec.QueueingStrategy = strategy
 	return c
 }
 
+// Backfill sets whether workloads can be backfilled behind a blocked head.
+func (c *ClusterQueueWrapper) Backfill(b bool) *ClusterQueueWrapper {
+	c.Spec.Backfill = b
+	return c
+}
+
+func (c *ClusterQueueWrapper) HeadBlockingTimeout(d time.Duration) *ClusterQueueWrapper {
+	c.Spec.HeadBlockingTimeout = &metav1.Duration{Duration: d}
+	return c
+}
+
+func (c *ClusterQueueWrapper) OrderingPolicy(policy kueue.OrderingPolicy) *ClusterQueueWrapper {
+	c.Spec.OrderingPolicy = policy
+	return c
+}
+
+// LocalQueueFairness sets how the workloads of the LocalQueues are
+// interleaved.
+func (c *ClusterQueueWrapper) LocalQueueFairness(f kueue.LocalQueueFairness) *ClusterQueueWrapper {
+	c.Spec.LocalQueueFairness = f
+	return c
+}
+
+// FlavorAssignmentPolicy sets the policy to choose the flavors.
+func (c *ClusterQueueWrapper) FlavorAssignmentPolicy(p kueue.FlavorAssignmentPolicy) *ClusterQueueWrapper {
+	c.Spec.FlavorAssignmentPolicy = p
+	return c
+}
+
 // NamespaceSelector sets the namespace selector.
 func (c *ClusterQueueWrapper) NamespaceSelector(s *metav1.LabelSelector) *ClusterQueueWrapper {
 	c.Spec.NamespaceSelector = s
 	return c
 }
 
+// Preemption sets the preemption policies.
+func (c *ClusterQueueWrapper) Preemption(p kueue.ClusterQueuePreemption) *ClusterQueueWrapper {
+	c.Spec.Preemption = &p
+	return c
+}
+
+// BorrowingMinPriority sets the lowest priority of the workloads that can
+// borrow quota from the cohort.
+func (c *ClusterQueueWrapper) BorrowingMinPriority(p int32) *ClusterQueueWrapper {
+	c.Spec.BorrowWithinCohort = &kueue.BorrowWithinCohort{MinPriority: &p}
+	return c
+}
+
+// DeletionPolicy sets the deletion policy.
+func (c *ClusterQueueWrapper) DeletionPolicy(p kueue.DeletionPolicy) *ClusterQueueWrapper {
+	c.Spec.DeletionPolicy = p
+	return c
+}
+
+// FairWeight sets the fair sharing weight.
+func (c *ClusterQueueWrapper) FairWeight(w resource.Quantity) *ClusterQueueWrapper {
+	c.Spec.FairSharing = &kueue.FairSharing{Weight: &w}
+	return c
+}
+
+// PriorityBand adds a priority band.
+func (c *ClusterQueueWrapper) PriorityBand(name string, minPriority, weight int32) *ClusterQueueWrapper {
+	c.Spec.PriorityBands = append(c.Spec.PriorityBands, kueue.PriorityBand{
+		Name:        name,
+		MinPriority: minPriority,
+		Weight:      weight,
+	})
+	return c
+}
+
+// ReservedTier adds a reserved tier.
+func (c *ClusterQueueWrapper) ReservedTier(name string, minPriority, percent int32) *ClusterQueueWrapper {
+	c.Spec.ReservedTiers = append(c.Spec.ReservedTiers, kueue.ReservedTier{
+		Name:        name,
+		MinPriority: minPriority,
+		Percent:     percent,
+	})
+	return c
+}
+
+// RevocableBorrowing sets whether the workloads admitted borrowing quota are
+// revocable.
+func (c *ClusterQueueWrapper) RevocableBorrowing(b bool) *ClusterQueueWrapper {
+	c.Spec.RevocableBorrowing = b
+	return c
+}
+
+// QuotaWindow appends a quota window.
+func (c *ClusterQueueWrapper) QuotaWindow(w *kueue.QuotaWindow) *ClusterQueueWrapper {
+	c.Spec.QuotaWindows = append(c.Spec.QuotaWindows, *w)
+	return c
+}
+
+// Budget appends a budget of resource-hours of the resource in the flavor,
+// which is empty for all the flavors, per period.
+func (c *ClusterQueueWrapper) Budget(rName corev1.ResourceName, flavor string, period kueue.BudgetPeriod, limit string) *ClusterQueueWrapper {
+	c.Spec.Budgets = append(c.Spec.Budgets, kueue.Budget{
+		Resource: rName,
+		Flavor:   kueue.ResourceFlavorReference(flavor),
+		Period:   period,
+		Limit:    resource.MustParse(limit),
+	})
+	return c
+}
+
+// FlavorFallback appends a fallback to the fallbackFlavor for the workloads
+// evicted from the flavor more than maxEvictions times within the window.
+func (c *ClusterQueueWrapper) FlavorFallback(flavor, fallbackFlavor string, maxEvictions int32, window time.Duration) *ClusterQueueWrapper {
+	c.Spec.FlavorFallbacks = append(c.Spec.FlavorFallbacks, kueue.FlavorFallback{
+		Flavor:         kueue.ResourceFlavorReference(flavor),
+		FallbackFlavor: kueue.ResourceFlavorReference(fallbackFlavor),
+		MaxEvictions:   maxEvictions,
+		Window:         metav1.Duration{Duration: window},
+	})
+	return c
+}
+
+// AdmissionChecks sets the admission checks of the workloads.
+func (c *ClusterQueueWrapper) AdmissionChecks(checks ...string) *ClusterQueueWrapper {
+	c.Spec.AdmissionChecks = checks
+	return c
+}
+
+// MaxWorkloadsPendingChecks sets the limit of workloads waiting for their
+// admission checks.
+func (c *ClusterQueueWrapper) MaxWorkloadsPendingChecks(n int32) *ClusterQueueWrapper {
+	c.Spec.MaxWorkloadsPendingChecks = &n
+	return c
+}
+
+// QuotaWindowWrapper wraps a quota window.
+type QuotaWindowWrapper struct{ kueue.QuotaWindow }
+
+// MakeQuotaWindow creates a wrapper for a quota window between start and end,
+// every day, in UTC.
+func MakeQuotaWindow(name, start, end string) *QuotaWindowWrapper {
+	return &QuotaWindowWrapper{kueue.QuotaWindow{
+		Name:  name,
+		Start: start,
+		End:   end,
+	}}
+}
+
+// Obj returns the inner quota window.
+func (w *QuotaWindowWrapper) Obj() *kueue.QuotaWindow {
+	return &w.QuotaWindow
+}
+
+// Days sets the days of the week in which the window starts.
+func (w *QuotaWindowWrapper) Days(days ...kueue.Weekday) *QuotaWindowWrapper {
+	w.QuotaWindow.Days = days
+	return w
+}
+
+// TimeZone sets the time zone of the window.
+func (w *QuotaWindowWrapper) TimeZone(tz string) *QuotaWindowWrapper {
+	w.QuotaWindow.TimeZone = tz
+	return w
+}
+
+// Quota appends the min quota of the resource in the flavor during the
+// window.
+func (w *QuotaWindowWrapper) Quota(rName corev1.ResourceName, flavor, min string) *QuotaWindowWrapper {
+	w.Quotas = append(w.Quotas, kueue.WindowQuota{
+		Resource: rName,
+		Flavor:   kueue.ResourceFlavorReference(flavor),
+		Quota:    kueue.Quota{Min: resource.MustParse(min)},
+	})
+	return w
+}
+
 // ResourceWrapper wraps a resource.
 type ResourceWrapper struct{ kueue.Resource }
 
@@ -394,6 +892,129 @@ func (f *FlavorWrapper) Max(c string) *FlavorWrapper {
 	return f
 }
 
+// Hold stops the assignment of the flavor to new workloads.
+func (f *FlavorWrapper) Hold() *FlavorWrapper {
+	f.Flavor.Hold = true
+	return f
+}
+
+// AdmissionChecks sets the admission checks of the workloads assigned the
+// flavor.
+func (f *FlavorWrapper) AdmissionChecks(checks ...string) *FlavorWrapper {
+	f.Flavor.AdmissionChecks = checks
+	return f
+}
+
+// ResourceGroupWrapper wraps a resource group.
+type ResourceGroupWrapper struct{ kueue.ResourceGroup }
+
+// MakeResourceGroup creates a wrapper for a resource group covering the
+// resources.
+func MakeResourceGroup(covered ...corev1.ResourceName) *ResourceGroupWrapper {
+	return &ResourceGroupWrapper{kueue.ResourceGroup{
+		CoveredResources: covered,
+	}}
+}
+
+// Obj returns the inner resource group.
+func (g *ResourceGroupWrapper) Obj() *kueue.ResourceGroup {
+	return &g.ResourceGroup
+}
+
+// Flavor appends a flavor with the min quotas of the resources, given as
+// pairs of resource name and quantity.
+func (g *ResourceGroupWrapper) Flavor(name string, quotas ...string) *ResourceGroupWrapper {
+	f := kueue.FlavorQuotas{Name: kueue.ResourceFlavorReference(name)}
+	for i := 0; i+1 < len(quotas); i += 2 {
+		f.Resources = append(f.Resources, kueue.ResourceQuota{
+			Name:  corev1.ResourceName(quotas[i]),
+			Quota: kueue.Quota{Min: resource.MustParse(quotas[i+1])},
+		})
+	}
+	g.Flavors = append(g.Flavors, f)
+	return g
+}
+
+// CohortWrapper wraps a Cohort.
+type CohortWrapper struct{ kueue.Cohort }
+
+// MakeCohort creates a wrapper for a Cohort.
+func MakeCohort(name string) *CohortWrapper {
+	return &CohortWrapper{kueue.Cohort{
+		ObjectMeta: metav1.ObjectMeta{Name: name},
+	}}
+}
+
+// Obj returns the inner Cohort.
+func (c *CohortWrapper) Obj() *kueue.Cohort {
+	return &c.Cohort
+}
+
+// Parent sets the parent cohort.
+func (c *CohortWrapper) Parent(name string) *CohortWrapper {
+	c.Spec.Parent = name
+	return c
+}
+
+// Quota adds the quota of a flavor of the resource. An empty max leaves the
+// usage unlimited.
+func (c *CohortWrapper) Quota(r corev1.ResourceName, flavor, min, max string) *CohortWrapper {
+	f := kueue.CohortFlavor{
+		Name:  kueue.ResourceFlavorReference(flavor),
+		Quota: kueue.Quota{Min: resource.MustParse(min)},
+	}
+	if max != "" {
+		f.Quota.Max = pointer.Quantity(resource.MustParse(max))
+	}
+	for i := range c.Spec.Resources {
+		if c.Spec.Resources[i].Name == r {
+			c.Spec.Resources[i].Flavors = append(c.Spec.Resources[i].Flavors, f)
+			return c
+		}
+	}
+	c.Spec.Resources = append(c.Spec.Resources, kueue.CohortResource{Name: r, Flavors: []kueue.CohortFlavor{f}})
+	return c
+}
+
+// ClusterQueueClassWrapper wraps a ClusterQueueClass.
+type ClusterQueueClassWrapper struct{ kueue.ClusterQueueClass }
+
+// MakeClusterQueueClass creates a wrapper for a ClusterQueueClass.
+func MakeClusterQueueClass(name string) *ClusterQueueClassWrapper {
+	return &ClusterQueueClassWrapper{kueue.ClusterQueueClass{
+		ObjectMeta: metav1.ObjectMeta{Name: name},
+	}}
+}
+
+// Obj returns the inner ClusterQueueClass.
+func (c *ClusterQueueClassWrapper) Obj() *kueue.ClusterQueueClass {
+	return &c.ClusterQueueClass
+}
+
+// Cohort sets the cohort of the ClusterQueues of the class.
+func (c *ClusterQueueClassWrapper) Cohort(cohort string) *ClusterQueueClassWrapper {
+	c.Spec.Cohort = cohort
+	return c
+}
+
+// Resource adds a resource with flavors.
+func (c *ClusterQueueClassWrapper) Resource(r *kueue.Resource) *ClusterQueueClassWrapper {
+	c.Spec.Resources = append(c.Spec.Resources, *r)
+	return c
+}
+
+// NamespaceSelector sets the namespace selector.
+func (c *ClusterQueueClassWrapper) NamespaceSelector(s *metav1.LabelSelector) *ClusterQueueClassWrapper {
+	c.Spec.NamespaceSelector = s
+	return c
+}
+
+// Preemption sets the preemption policies.
+func (c *ClusterQueueClassWrapper) Preemption(p kueue.ClusterQueuePreemption) *ClusterQueueClassWrapper {
+	c.Spec.Preemption = &p
+	return c
+}
+
 // ResourceFlavorWrapper wraps a ResourceFlavor.
 type ResourceFlavorWrapper struct{ kueue.ResourceFlavor }
 
@@ -432,6 +1053,53 @@ func (rf *ResourceFlavorWrapper) Taint(t corev1.Taint) *ResourceFlavorWrapper {
 	return rf
 }
 
+// Cost sets the cost annotation of the ResourceFlavor.
+func (rf *ResourceFlavorWrapper) Cost(c string) *ResourceFlavorWrapper {
+	if rf.Annotations == nil {
+		rf.Annotations = make(map[string]string, 1)
+	}
+	rf.Annotations[constants.FlavorCostAnnotation] = c
+	return rf
+}
+
+// SharedResource declares that the nodes of the ResourceFlavor share each
+// unit of the resource between replicas pods.
+func (rf *ResourceFlavorWrapper) SharedResource(name corev1.ResourceName, replicas int32) *ResourceFlavorWrapper {
+	rf.SharedResources = append(rf.SharedResources, kueue.SharedResource{Name: name, Replicas: replicas})
+	return rf
+}
+
+// AdmissionCheckWrapper wraps an AdmissionCheck.
+type AdmissionCheckWrapper struct{ kueue.AdmissionCheck }
+
+// MakeAdmissionCheck creates a wrapper for an AdmissionCheck of the
+// controller.
+func MakeAdmissionCheck(name, controllerName string) *AdmissionCheckWrapper {
+	return &AdmissionCheckWrapper{kueue.AdmissionCheck{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: name,
+		},
+		Spec: kueue.AdmissionCheckSpec{
+			ControllerName: controllerName,
+		},
+	}}
+}
+
+// Obj returns the inner AdmissionCheck.
+func (ac *AdmissionCheckWrapper) Obj() *kueue.AdmissionCheck {
+	return &ac.AdmissionCheck
+}
+
+// Active sets the Active condition of the AdmissionCheck.
+func (ac *AdmissionCheckWrapper) Active(status metav1.ConditionStatus) *AdmissionCheckWrapper {
+	apimeta.SetStatusCondition(&ac.Status.Conditions, metav1.Condition{
+		Type:   kueue.AdmissionCheckActive,
+		Status: status,
+		Reason: "ByTest",
+	})
+	return ac
+}
+
 // RuntimeClassWrapper wraps a RuntimeClass.
 type RuntimeClassWrapper struct{ nodev1.RuntimeClass }
 
@@ -0,0 +1,42 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package tolerations
+
+import (
+	corev1 "k8s.io/api/core/v1"
+)
+
+// Has returns whether the tolerations include one that matches t.
+func Has(tolerations []corev1.Toleration, t *corev1.Toleration) bool {
+	for i := range tolerations {
+		if tolerations[i].MatchToleration(t) {
+			return true
+		}
+	}
+	return false
+}
+
+// AppendMissing appends to tolerations the ones from toAdd that they don't
+// have yet.
+func AppendMissing(tolerations []corev1.Toleration, toAdd []corev1.Toleration) []corev1.Toleration {
+	for i := range toAdd {
+		if !Has(tolerations, &toAdd[i]) {
+			tolerations = append(tolerations, toAdd[i])
+		}
+	}
+	return tolerations
+}
@@ -0,0 +1,56 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package tolerations
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	corev1 "k8s.io/api/core/v1"
+)
+
+func TestAppendMissing(t *testing.T) {
+	spot := corev1.Toleration{Key: "spot", Operator: corev1.TolerationOpExists, Effect: corev1.TaintEffectNoSchedule}
+	gpu := corev1.Toleration{Key: "gpu", Operator: corev1.TolerationOpEqual, Value: "a100", Effect: corev1.TaintEffectNoSchedule}
+	cases := map[string]struct {
+		tolerations []corev1.Toleration
+		toAdd       []corev1.Toleration
+		want        []corev1.Toleration
+	}{
+		"nothing to add": {
+			tolerations: []corev1.Toleration{spot},
+			want:        []corev1.Toleration{spot},
+		},
+		"adds the missing ones": {
+			tolerations: []corev1.Toleration{spot},
+			toAdd:       []corev1.Toleration{gpu, spot},
+			want:        []corev1.Toleration{spot, gpu},
+		},
+		"adds to no tolerations": {
+			toAdd: []corev1.Toleration{gpu},
+			want:  []corev1.Toleration{gpu},
+		},
+	}
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			got := AppendMissing(tc.tolerations, tc.toAdd)
+			if diff := cmp.Diff(tc.want, got); diff != "" {
+				t.Errorf("Unexpected tolerations (-want,+got):\n%s", diff)
+			}
+		})
+	}
+}
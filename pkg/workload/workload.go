@@ -19,14 +19,20 @@ package workload
 import (
 	"context"
 	"fmt"
+	"strconv"
 	"strings"
+	"time"
 
 	corev1 "k8s.io/api/core/v1"
+	apimeta "k8s.io/apimachinery/pkg/api/meta"
 	"k8s.io/apimachinery/pkg/api/resource"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/sets"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 
+	config "sigs.k8s.io/kueue/apis/config/v1alpha2"
 	kueue "sigs.k8s.io/kueue/apis/kueue/v1alpha2"
+	"sigs.k8s.io/kueue/pkg/constants"
 	"sigs.k8s.io/kueue/pkg/util/api"
 )
 
@@ -44,15 +50,17 @@ type PodSetResources struct {
 	Name     string
 	Requests Requests
 	Flavors  map[corev1.ResourceName]string
+	// Count is the number of pods of the pod set.
+	Count int32
 }
 
 func NewInfo(w *kueue.Workload) *Info {
 	info := &Info{
 		Obj:           w,
-		TotalRequests: totalRequests(&w.Spec),
+		TotalRequests: totalRequests(w, w.Status.ReclaimablePods),
 	}
-	if w.Spec.Admission != nil {
-		info.ClusterQueue = string(w.Spec.Admission.ClusterQueue)
+	if w.Status.Admission != nil {
+		info.ClusterQueue = string(w.Status.Admission.ClusterQueue)
 	}
 	return info
 }
@@ -61,6 +69,60 @@ func (i *Info) Update(wl *kueue.Workload) {
 	i.Obj = wl
 }
 
+// CanBePartiallyAdmitted returns the index of the pod set that can be
+// admitted with fewer pods than its count, or false if the workload doesn't
+// support partial admission.
+func CanBePartiallyAdmitted(w *kueue.Workload) (int, bool) {
+	for i := range w.Spec.PodSets {
+		ps := &w.Spec.PodSets[i]
+		if ps.MinCount != nil && *ps.MinCount < ps.Count {
+			return i, true
+		}
+	}
+	return 0, false
+}
+
+// WithPodSetCount returns a copy of the Info in which the pod set at index
+// idx requests the resources of count pods, for partial admission.
+func (i *Info) WithPodSetCount(idx int, count int32) *Info {
+	info := *i
+	info.TotalRequests = make([]PodSetResources, len(i.TotalRequests))
+	copy(info.TotalRequests, i.TotalRequests)
+	ps := &info.TotalRequests[idx]
+	full := int64(i.Obj.Spec.PodSets[idx].Count)
+	requests := make(Requests, len(ps.Requests))
+	for name, val := range ps.Requests {
+		if name == kueue.ResourceWorkloads {
+			requests[name] = val
+			continue
+		}
+		requests[name] = val / full * int64(count)
+	}
+	ps.Requests = requests
+	ps.Count = count
+	return &info
+}
+
+// WithSplitPodSet returns a copy of the Info in which the pod set at index idx
+// is split in two consecutive pod sets with the same name, requesting the
+// resources of count pods and of the rest of the pods respectively, so that
+// they can be assigned different flavors.
+func (i *Info) WithSplitPodSet(idx int, count int32) *Info {
+	first := i.WithPodSetCount(idx, count)
+	rest := i.WithPodSetCount(idx, i.Obj.Spec.PodSets[idx].Count-count)
+	info := *first
+	info.TotalRequests = make([]PodSetResources, 0, len(i.TotalRequests)+1)
+	info.TotalRequests = append(info.TotalRequests, first.TotalRequests[:idx+1]...)
+	info.TotalRequests = append(info.TotalRequests, rest.TotalRequests[idx])
+	info.TotalRequests = append(info.TotalRequests, first.TotalRequests[idx+1:]...)
+	obj := *i.Obj
+	obj.Spec.PodSets = make([]kueue.PodSet, 0, len(i.Obj.Spec.PodSets)+1)
+	obj.Spec.PodSets = append(obj.Spec.PodSets, i.Obj.Spec.PodSets[:idx+1]...)
+	obj.Spec.PodSets = append(obj.Spec.PodSets, i.Obj.Spec.PodSets[idx:]...)
+	info.Obj = &obj
+	return &info
+}
+
 func Key(w *kueue.Workload) string {
 	return fmt.Sprintf("%s/%s", w.Namespace, w.Name)
 }
@@ -69,37 +131,153 @@ func QueueKey(w *kueue.Workload) string {
 	return fmt.Sprintf("%s/%s", w.Namespace, w.Spec.QueueName)
 }
 
-func totalRequests(spec *kueue.WorkloadSpec) []PodSetResources {
-	if len(spec.PodSets) == 0 {
+// totalRequests returns the requests of the pod sets of the workload. The
+// pods that are reclaimable don't count towards the requests. A pod set split
+// between flavors by the admission results in one entry per part, all with
+// the name of the pod set.
+func totalRequests(wl *kueue.Workload, reclaimable []kueue.ReclaimablePod) []PodSetResources {
+	if len(wl.Spec.PodSets) == 0 {
 		return nil
 	}
-	res := make([]PodSetResources, 0, len(spec.PodSets))
-	var podSetFlavors map[string]map[corev1.ResourceName]string
-	if spec.Admission != nil {
-		podSetFlavors = make(map[string]map[corev1.ResourceName]string, len(spec.Admission.PodSetFlavors))
-		for _, ps := range spec.Admission.PodSetFlavors {
-			podSetFlavors[ps.Name] = ps.Flavors
+	res := make([]PodSetResources, 0, len(wl.Spec.PodSets))
+	var podSetFlavors map[string]*kueue.PodSetFlavors
+	if wl.Status.Admission != nil {
+		podSetFlavors = make(map[string]*kueue.PodSetFlavors, len(wl.Status.Admission.PodSetFlavors))
+		for i := range wl.Status.Admission.PodSetFlavors {
+			ps := &wl.Status.Admission.PodSetFlavors[i]
+			podSetFlavors[ps.Name] = ps
 		}
 	}
 
-	for _, ps := range spec.PodSets {
-		setRes := PodSetResources{
-			Name: ps.Name,
+	for _, ps := range wl.Spec.PodSets {
+		parts := podSetParts(&ps, podSetFlavors[ps.Name])
+		for _, rp := range reclaimable {
+			if rp.Name == ps.Name {
+				reclaim(parts, rp.Count)
+			}
 		}
-		setRes.Requests = podRequests(&ps.Spec)
-		setRes.Requests.scale(int64(ps.Count))
-		flavors := podSetFlavors[ps.Name]
-		if len(flavors) > 0 {
-			setRes.Flavors = make(map[corev1.ResourceName]string, len(flavors))
-			for r, t := range flavors {
-				setRes.Flavors[r] = t
+		for _, part := range parts {
+			setRes := PodSetResources{
+				Name:  ps.Name,
+				Count: part.count,
+			}
+			setRes.Requests = podRequests(&ps.Spec)
+			setRes.Requests.add(podOverhead)
+			setRes.Requests.scale(int64(part.count))
+			setRes.Requests.transform()
+			if len(part.flavors) > 0 {
+				setRes.Flavors = make(map[corev1.ResourceName]string, len(part.flavors))
+				for r, t := range part.flavors {
+					setRes.Flavors[r] = t
+				}
+				// The counting resources are only requested when the admission
+				// assigned them a flavor.
+				if _, ok := part.flavors[corev1.ResourcePods]; ok {
+					setRes.Requests[corev1.ResourcePods] = int64(part.count)
+				}
+				if _, ok := part.flavors[kueue.ResourceWorkloads]; ok {
+					setRes.Requests[kueue.ResourceWorkloads] = 1
+				}
 			}
+			res = append(res, setRes)
 		}
-		res = append(res, setRes)
 	}
 	return res
 }
 
+// podSetPart is a group of pods of a pod set admitted with the same flavors.
+type podSetPart struct {
+	flavors map[corev1.ResourceName]string
+	count   int32
+}
+
+// podSetParts returns the parts in which the admission split the pod set, or
+// a single part if it wasn't split or the workload isn't admitted.
+func podSetParts(ps *kueue.PodSet, admission *kueue.PodSetFlavors) []podSetPart {
+	if admission == nil {
+		return []podSetPart{{count: ps.Count}}
+	}
+	first := podSetPart{flavors: admission.Flavors, count: ps.Count}
+	if admission.Count != nil {
+		first.count = *admission.Count
+	}
+	parts := make([]podSetPart, 0, len(admission.Splits)+1)
+	parts = append(parts, first)
+	for _, split := range admission.Splits {
+		parts = append(parts, podSetPart{flavors: split.Flavors, count: split.Count})
+	}
+	return parts
+}
+
+// reclaim removes count reclaimable pods from the parts, starting with the
+// last one.
+func reclaim(parts []podSetPart, count int32) {
+	for i := len(parts) - 1; i >= 0 && count > 0; i-- {
+		removed := count
+		if removed > parts[i].count {
+			removed = parts[i].count
+		}
+		parts[i].count -= removed
+		count -= removed
+	}
+}
+
+var (
+	podOverhead     Requests
+	transformations []config.ResourceTransformation
+)
+
+// SetResourceTransformations sets the overhead added to the requests of each
+// pod and the transformations applied to the requests of each pod set, before
+// they are accounted against the quotas. It must be called before any Info is
+// created.
+func SetResourceTransformations(cfg *config.Resources) {
+	if cfg == nil {
+		podOverhead, transformations = nil, nil
+		return
+	}
+	podOverhead = newRequests(cfg.PodOverhead)
+	transformations = cfg.Transformations
+}
+
+// transform adds the outputs of the transformations of the requested
+// resources, and removes the inputs that are replaced.
+func (r Requests) transform() {
+	if len(transformations) == 0 {
+		return
+	}
+	outputs := Requests{}
+	for _, t := range transformations {
+		v, ok := r[t.Input]
+		if !ok {
+			continue
+		}
+		for name, q := range t.Outputs {
+			outputs[name] += transformedValue(t.Input, v, name, q)
+		}
+		if t.Strategy == config.ResourceTransformationReplace {
+			delete(r, t.Input)
+		}
+	}
+	r.add(outputs)
+}
+
+// transformedValue returns the value of the output resource for v of the
+// input resource, given the output quantity per unit of the input, rounded up.
+func transformedValue(input corev1.ResourceName, v int64, output corev1.ResourceName, perUnit resource.Quantity) int64 {
+	// Work in millionths of the output units to support fractional
+	// quantities. The value of CPU is already in thousandths.
+	micro := perUnit.MilliValue() * v
+	if input != corev1.ResourceCPU {
+		micro *= 1000
+	}
+	div := int64(1_000_000)
+	if output == corev1.ResourceCPU {
+		div = 1000
+	}
+	return (micro + div - 1) / div
+}
+
 // The following resources calculations are inspired on
 // https://github.com/kubernetes/kubernetes/blob/master/pkg/scheduler/framework/types.go
 
@@ -218,6 +396,310 @@ func UpdateStatus(ctx context.Context,
 	return c.Status().Update(ctx, &newWl)
 }
 
+// MaxFlavorEvictions is the number of most recent evictions recorded for
+// each flavor in the status of a workload.
+const MaxFlavorEvictions = 16
+
+// SetEvictedCondition marks the workload as evicted and counts the eviction,
+// also in each of the flavors of the admission it was evicted from, if any.
+// The condition is removed when the workload is admitted again. The states
+// of the admission checks are cleared, as the checks are performed again for
+// the next admission.
+func SetEvictedCondition(wl *kueue.Workload, admission *kueue.Admission, reason, message string) {
+	wl.Status.Evictions++
+	if admission != nil {
+		recordFlavorEvictions(wl, admission, time.Now())
+	}
+	wl.Status.AdmissionChecks = nil
+	if apimeta.IsStatusConditionTrue(wl.Status.Conditions, kueue.WorkloadQuotaReserved) {
+		SetQuotaReservedCondition(wl, metav1.ConditionFalse, reason, message)
+	}
+	apimeta.SetStatusCondition(&wl.Status.Conditions, metav1.Condition{
+		Type:    kueue.WorkloadEvicted,
+		Status:  metav1.ConditionTrue,
+		Reason:  reason,
+		Message: api.TruncateConditionMessage(message),
+	})
+}
+
+// Evict removes the admission of the workload and sets its Evicted,
+// QuotaReserved and Admitted conditions with the reason of the eviction. The
+// jobs of evicted workloads are suspended and the workloads are queued again.
+// The object in the client cache isn't modified.
+func Evict(ctx context.Context, c client.Client, wl *kueue.Workload, reason, message string) error {
+	newWl := wl.DeepCopy()
+	newWl.Status.Admission = nil
+	SetEvictedCondition(newWl, wl.Status.Admission, reason, message)
+	return UpdateStatus(ctx, c, newWl, kueue.WorkloadAdmitted, metav1.ConditionFalse, reason, message)
+}
+
+// EvictionReason returns the reason of the last eviction of the workload, or
+// an empty string if it wasn't evicted since it was last admitted.
+func EvictionReason(wl *kueue.Workload) string {
+	cond := apimeta.FindStatusCondition(wl.Status.Conditions, kueue.WorkloadEvicted)
+	if cond == nil || cond.Status != metav1.ConditionTrue {
+		return ""
+	}
+	return cond.Reason
+}
+
+func recordFlavorEvictions(wl *kueue.Workload, admission *kueue.Admission, now time.Time) {
+	flavors := sets.NewString()
+	for _, ps := range admission.PodSetFlavors {
+		for _, flv := range ps.Flavors {
+			flavors.Insert(flv)
+		}
+	}
+	for _, flv := range flavors.List() {
+		idx := -1
+		for i := range wl.Status.FlavorEvictions {
+			if wl.Status.FlavorEvictions[i].Flavor == flv {
+				idx = i
+				break
+			}
+		}
+		if idx == -1 {
+			wl.Status.FlavorEvictions = append(wl.Status.FlavorEvictions, kueue.FlavorEvictions{Flavor: flv})
+			idx = len(wl.Status.FlavorEvictions) - 1
+		}
+		fe := &wl.Status.FlavorEvictions[idx]
+		fe.Times = append(fe.Times, metav1.NewTime(now))
+		if len(fe.Times) > MaxFlavorEvictions {
+			fe.Times = fe.Times[len(fe.Times)-MaxFlavorEvictions:]
+		}
+	}
+}
+
+// ExceedsFlavorEvictions returns whether the workload was evicted from the
+// flavor more than maxEvictions times within some period of the given
+// duration.
+func ExceedsFlavorEvictions(wl *kueue.Workload, flavor string, maxEvictions int32, window time.Duration) bool {
+	n := int(maxEvictions)
+	for _, fe := range wl.Status.FlavorEvictions {
+		if fe.Flavor != flavor {
+			continue
+		}
+		for i := n; i < len(fe.Times); i++ {
+			if fe.Times[i].Sub(fe.Times[i-n].Time) <= window {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// AddSchedulingAttempt counts an evaluation of the workload by the scheduler
+// that didn't admit it, which took the given time.
+func AddSchedulingAttempt(wl *kueue.Workload, evaluationTime time.Duration) {
+	stats := wl.Status.SchedulingStats
+	if stats == nil {
+		stats = &kueue.SchedulingStats{}
+		wl.Status.SchedulingStats = stats
+	}
+	stats.Attempts++
+	stats.EvaluationTime.Duration += evaluationTime
+}
+
+// SchedulingAttempts returns the number of times the scheduler evaluated the
+// workload without admitting it.
+func SchedulingAttempts(wl *kueue.Workload) int32 {
+	if wl.Status.SchedulingStats == nil {
+		return 0
+	}
+	return wl.Status.SchedulingStats.Attempts
+}
+
+// IsEvicted returns whether the workload was evicted and wasn't admitted
+// again since then.
+func IsEvicted(wl *kueue.Workload) bool {
+	return apimeta.IsStatusConditionTrue(wl.Status.Conditions, kueue.WorkloadEvicted)
+}
+
+// IsQuarantined returns whether the workload is quarantined after failing to
+// be admitted too many consecutive times.
+func IsQuarantined(wl *kueue.Workload) bool {
+	return apimeta.IsStatusConditionTrue(wl.Status.Conditions, kueue.WorkloadQuarantined)
+}
+
+// IsActive returns whether the workload is considered for admission.
+func IsActive(wl *kueue.Workload) bool {
+	return wl.Spec.Active == nil || *wl.Spec.Active
+}
+
+// IsManagedByKueue returns whether the workload is queued and admitted by
+// Kueue, rather than by the external controller set in its managedBy.
+func IsManagedByKueue(wl *kueue.Workload) bool {
+	return wl.Spec.ManagedBy == "" || wl.Spec.ManagedBy == kueue.WorkloadManagedByKueue
+}
+
+// HasDeprecatedAdmission returns whether the admission of the workload is
+// still in the deprecated spec.admission field, waiting for the workload
+// controller to move it to status.admission. Such a workload isn't queued
+// until then.
+func HasDeprecatedAdmission(wl *kueue.Workload) bool {
+	return wl.Spec.Admission != nil
+}
+
+// WithDeprecatedAdmission returns the workload with its deprecated
+// spec.admission copied to status.admission, if only the spec has it, so that
+// its quota is counted while the workload controller converts it. Otherwise,
+// it returns the workload itself.
+func WithDeprecatedAdmission(wl *kueue.Workload) *kueue.Workload {
+	if wl.Spec.Admission == nil || wl.Status.Admission != nil {
+		return wl
+	}
+	wl = wl.DeepCopy()
+	wl.Status.Admission = wl.Spec.Admission.DeepCopy()
+	return wl
+}
+
+// IsAdmitted returns whether the workload is admitted: a ClusterQueue
+// reserved quota for it and all the admission checks of the admission are
+// Ready.
+func IsAdmitted(wl *kueue.Workload) bool {
+	return wl.Status.Admission != nil && len(PendingAdmissionChecks(wl)) == 0
+}
+
+// PendingAdmissionChecks returns the names of the admission checks of the
+// admission that are not Ready.
+func PendingAdmissionChecks(wl *kueue.Workload) []string {
+	if wl.Status.Admission == nil {
+		return nil
+	}
+	var pending []string
+	for _, name := range wl.Status.Admission.AdmissionChecks {
+		if check := FindAdmissionCheck(wl.Status.AdmissionChecks, name); check == nil || check.State != kueue.CheckStateReady {
+			pending = append(pending, name)
+		}
+	}
+	return pending
+}
+
+// FindAdmissionCheck returns the state of the admission check with the name,
+// or nil if it's not in the list.
+func FindAdmissionCheck(checks []kueue.AdmissionCheckState, name string) *kueue.AdmissionCheckState {
+	for i := range checks {
+		if checks[i].Name == name {
+			return &checks[i]
+		}
+	}
+	return nil
+}
+
+// FindAdmissionCheckInState returns the first admission check of the
+// admission in the state, or nil if there is none.
+func FindAdmissionCheckInState(wl *kueue.Workload, state kueue.CheckState) *kueue.AdmissionCheckState {
+	if wl.Status.Admission == nil {
+		return nil
+	}
+	for _, name := range wl.Status.Admission.AdmissionChecks {
+		if check := FindAdmissionCheck(wl.Status.AdmissionChecks, name); check != nil && check.State == state {
+			return check
+		}
+	}
+	return nil
+}
+
+// SetAdmissionCheckState sets the state of the admission check in the list.
+// The lastTransitionTime is only updated when the state changes.
+func SetAdmissionCheckState(checks *[]kueue.AdmissionCheckState, newCheck kueue.AdmissionCheckState) {
+	newCheck.Message = api.TruncateConditionMessage(newCheck.Message)
+	check := FindAdmissionCheck(*checks, newCheck.Name)
+	if check == nil {
+		if newCheck.LastTransitionTime.IsZero() {
+			newCheck.LastTransitionTime = metav1.Now()
+		}
+		*checks = append(*checks, newCheck)
+		return
+	}
+	if check.State != newCheck.State {
+		check.State = newCheck.State
+		check.LastTransitionTime = newCheck.LastTransitionTime
+		if check.LastTransitionTime.IsZero() {
+			check.LastTransitionTime = metav1.Now()
+		}
+	}
+	check.Message = newCheck.Message
+}
+
+// SyncAdmissionChecks makes the states of the admission checks in the status
+// match the checks of the admission: the missing checks are added as
+// Pending, and the checks that are not in the admission are removed. It
+// returns whether the status changed.
+func SyncAdmissionChecks(wl *kueue.Workload) bool {
+	var names []string
+	if wl.Status.Admission != nil {
+		names = wl.Status.Admission.AdmissionChecks
+	}
+	changed := false
+	checks := make([]kueue.AdmissionCheckState, 0, len(names))
+	for _, name := range names {
+		if check := FindAdmissionCheck(wl.Status.AdmissionChecks, name); check != nil {
+			checks = append(checks, *check)
+			continue
+		}
+		checks = append(checks, kueue.AdmissionCheckState{
+			Name:               name,
+			State:              kueue.CheckStatePending,
+			LastTransitionTime: metav1.Now(),
+		})
+		changed = true
+	}
+	if len(checks) != len(wl.Status.AdmissionChecks) {
+		changed = true
+	}
+	if changed {
+		wl.Status.AdmissionChecks = nil
+		if len(checks) > 0 {
+			wl.Status.AdmissionChecks = checks
+		}
+	}
+	return changed
+}
+
+// SetQuotaReservedCondition sets the QuotaReserved condition of the workload.
+// It returns whether the condition changed.
+func SetQuotaReservedCondition(wl *kueue.Workload, status metav1.ConditionStatus, reason, message string) bool {
+	return setCondition(wl, kueue.WorkloadQuotaReserved, status, reason, message)
+}
+
+// SetPendingConditions sets the QuotaReserved and Admitted conditions of a
+// workload without admission to false, with the reason why it isn't admitted.
+// It returns whether the conditions changed.
+func SetPendingConditions(wl *kueue.Workload, reason, message string) bool {
+	changed := setCondition(wl, kueue.WorkloadQuotaReserved, metav1.ConditionFalse, reason, message)
+	if setCondition(wl, kueue.WorkloadAdmitted, metav1.ConditionFalse, reason, message) {
+		changed = true
+	}
+	return changed
+}
+
+// UpdatePendingStatus sets the pending conditions in a copy of the workload,
+// to not modify the object in the client cache, and updates its status.
+func UpdatePendingStatus(ctx context.Context, c client.Client, wl *kueue.Workload, reason, message string) error {
+	newWl := wl.DeepCopy()
+	SetPendingConditions(newWl, reason, message)
+	return c.Status().Update(ctx, newWl)
+}
+
+// setCondition sets the condition of the workload, keeping its transition
+// time if the status doesn't change. It returns whether the condition
+// changed.
+func setCondition(wl *kueue.Workload, conditionType string, status metav1.ConditionStatus, reason, message string) bool {
+	message = api.TruncateConditionMessage(message)
+	cond := apimeta.FindStatusCondition(wl.Status.Conditions, conditionType)
+	if cond != nil && cond.Status == status && cond.Reason == reason && cond.Message == message {
+		return false
+	}
+	apimeta.SetStatusCondition(&wl.Status.Conditions, metav1.Condition{
+		Type:    conditionType,
+		Status:  status,
+		Reason:  reason,
+		Message: message,
+	})
+	return true
+}
+
 func UpdateStatusIfChanged(ctx context.Context,
 	c client.Client,
 	wl *kueue.Workload,
@@ -237,3 +719,86 @@ func UpdateStatusIfChanged(ctx context.Context,
 	// Updating an existing condition
 	return UpdateStatus(ctx, c, wl, conditionType, conditionStatus, reason, message)
 }
+
+// ResourceUsage returns a snapshot of the time and resources used by a
+// finished workload. It returns nil if the workload is not admitted or hasn't
+// finished.
+func ResourceUsage(wl *kueue.Workload) *kueue.WorkloadResourceUsage {
+	finishedCond := apimeta.FindStatusCondition(wl.Status.Conditions, kueue.WorkloadFinished)
+	if wl.Status.Admission == nil || finishedCond == nil || finishedCond.Status != metav1.ConditionTrue {
+		return nil
+	}
+	createdAt := wl.CreationTimestamp.Time
+	finishedAt := finishedCond.LastTransitionTime.Time
+	// The Admitted condition might not have been set if the workload finished
+	// quickly; consider it admitted at creation in that case.
+	admittedAt, ok := AdmissionTime(wl)
+	if !ok {
+		admittedAt = createdAt
+	}
+	if finishedAt.Before(admittedAt) {
+		finishedAt = admittedAt
+	}
+
+	usage := &kueue.WorkloadResourceUsage{
+		QueuedTime:        metav1.Duration{Duration: admittedAt.Sub(createdAt)},
+		RunTime:           metav1.Duration{Duration: finishedAt.Sub(admittedAt)},
+		AdmittedResources: make(map[corev1.ResourceName]map[string]resource.Quantity),
+	}
+	admitted := make(map[corev1.ResourceName]map[string]int64)
+	for _, ps := range totalRequests(wl, nil) {
+		for res, v := range ps.Requests {
+			flv := ps.Flavors[res]
+			if admitted[res] == nil {
+				admitted[res] = make(map[string]int64)
+			}
+			admitted[res][flv] += v
+		}
+	}
+	for res, flavors := range admitted {
+		usage.AdmittedResources[res] = make(map[string]resource.Quantity, len(flavors))
+		for flv, v := range flavors {
+			usage.AdmittedResources[res][flv] = ResourceQuantity(res, v)
+		}
+	}
+	return usage
+}
+
+// AdmissionTime returns the time at which the workload was admitted, from its
+// Admitted condition. It returns false if the condition is not true.
+func AdmissionTime(wl *kueue.Workload) (time.Time, bool) {
+	cond := apimeta.FindStatusCondition(wl.Status.Conditions, kueue.WorkloadAdmitted)
+	if cond == nil || cond.Status != metav1.ConditionTrue {
+		return time.Time{}, false
+	}
+	return cond.LastTransitionTime.Time, true
+}
+
+// LatestStartTime returns the latest time at which the workload can start to
+// complete before its deadline, that is, the deadline minus the expected
+// duration. It returns false if the workload doesn't have a deadline.
+func LatestStartTime(wl *kueue.Workload) (time.Time, bool) {
+	if wl.Spec.Deadline == nil {
+		return time.Time{}, false
+	}
+	start := wl.Spec.Deadline.Time
+	if wl.Spec.ExpectedDuration != nil {
+		start = start.Add(-wl.Spec.ExpectedDuration.Duration)
+	}
+	return start, true
+}
+
+// AdmissionGroup returns the key of the admission group of the workload, which
+// is unique across namespaces, and the number of workloads in the group. It
+// returns false if the workload doesn't belong to an admission group.
+func AdmissionGroup(wl *kueue.Workload) (string, int, bool) {
+	name := wl.Labels[constants.AdmissionGroupLabel]
+	if name == "" {
+		return "", 0, false
+	}
+	size, err := strconv.Atoi(wl.Annotations[constants.AdmissionGroupSizeAnnotation])
+	if err != nil || size < 1 {
+		return "", 0, false
+	}
+	return wl.Namespace + "/" + name, size, true
+}
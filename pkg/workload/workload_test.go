@@ -19,6 +19,7 @@ package workload
 import (
 	"context"
 	"testing"
+	"time"
 
 	"github.com/google/go-cmp/cmp"
 	"github.com/google/go-cmp/cmp/cmpopts"
@@ -26,9 +27,11 @@ import (
 	"k8s.io/apimachinery/pkg/api/resource"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/utils/pointer"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/client/fake"
 
+	config "sigs.k8s.io/kueue/apis/config/v1alpha2"
 	kueue "sigs.k8s.io/kueue/apis/kueue/v1alpha2"
 	utiltesting "sigs.k8s.io/kueue/pkg/util/testing"
 )
@@ -164,6 +167,7 @@ func TestNewInfo(t *testing.T) {
 							corev1.ResourceCPU:    10,
 							corev1.ResourceMemory: 512 * 1024,
 						},
+						Count: 1,
 					},
 				},
 			},
@@ -221,6 +225,7 @@ func TestNewInfo(t *testing.T) {
 						Flavors: map[corev1.ResourceName]string{
 							corev1.ResourceCPU: "on-demand",
 						},
+						Count: 1,
 					},
 					{
 						Name: "workers",
@@ -229,6 +234,255 @@ func TestNewInfo(t *testing.T) {
 							corev1.ResourceMemory: 3 * 1024 * 1024,
 							"ex.com/gpu":          3,
 						},
+						Count: 3,
+					},
+				},
+			},
+		},
+		"partially admitted": {
+			workload: kueue.Workload{
+				Spec: kueue.WorkloadSpec{
+					PodSets: []kueue.PodSet{
+						{
+							Name: "workers",
+							Spec: corev1.PodSpec{
+								Containers: containersForRequests(
+									map[corev1.ResourceName]string{
+										corev1.ResourceCPU: "5m",
+									}),
+							},
+							Count:    10,
+							MinCount: pointer.Int32(4),
+						},
+					},
+					Admission: &kueue.Admission{
+						ClusterQueue: "foo",
+						PodSetFlavors: []kueue.PodSetFlavors{
+							{
+								Name: "workers",
+								Flavors: map[corev1.ResourceName]string{
+									corev1.ResourceCPU: "on-demand",
+								},
+								Count: pointer.Int32(6),
+							},
+						},
+					},
+				},
+			},
+			wantInfo: Info{
+				ClusterQueue: "foo",
+				TotalRequests: []PodSetResources{
+					{
+						Name: "workers",
+						Requests: Requests{
+							corev1.ResourceCPU: 30,
+						},
+						Flavors: map[corev1.ResourceName]string{
+							corev1.ResourceCPU: "on-demand",
+						},
+						Count: 6,
+					},
+				},
+			},
+		},
+		"admitted with reclaimable pods": {
+			workload: kueue.Workload{
+				Spec: kueue.WorkloadSpec{
+					PodSets: []kueue.PodSet{
+						{
+							Name: "workers",
+							Spec: corev1.PodSpec{
+								Containers: containersForRequests(
+									map[corev1.ResourceName]string{
+										corev1.ResourceCPU: "5m",
+									}),
+							},
+							Count: 10,
+						},
+					},
+					Admission: &kueue.Admission{
+						ClusterQueue: "foo",
+						PodSetFlavors: []kueue.PodSetFlavors{
+							{
+								Name: "workers",
+								Flavors: map[corev1.ResourceName]string{
+									corev1.ResourceCPU:  "on-demand",
+									corev1.ResourcePods: "on-demand",
+								},
+							},
+						},
+					},
+				},
+				Status: kueue.WorkloadStatus{
+					ReclaimablePods: []kueue.ReclaimablePod{
+						{
+							Name:  "workers",
+							Count: 4,
+						},
+					},
+				},
+			},
+			wantInfo: Info{
+				ClusterQueue: "foo",
+				TotalRequests: []PodSetResources{
+					{
+						Name: "workers",
+						Requests: Requests{
+							corev1.ResourceCPU:  30,
+							corev1.ResourcePods: 6,
+						},
+						Flavors: map[corev1.ResourceName]string{
+							corev1.ResourceCPU:  "on-demand",
+							corev1.ResourcePods: "on-demand",
+						},
+						Count: 6,
+					},
+				},
+			},
+		},
+		"admitted with counting resources": {
+			workload: kueue.Workload{
+				Spec: kueue.WorkloadSpec{
+					PodSets: []kueue.PodSet{
+						{
+							Name: "driver",
+							Spec: corev1.PodSpec{
+								Containers: containersForRequests(
+									map[corev1.ResourceName]string{
+										corev1.ResourceCPU: "10m",
+									}),
+							},
+							Count: 1,
+						},
+						{
+							Name: "workers",
+							Spec: corev1.PodSpec{
+								Containers: containersForRequests(
+									map[corev1.ResourceName]string{
+										corev1.ResourceCPU: "5m",
+									}),
+							},
+							Count: 3,
+						},
+					},
+					Admission: &kueue.Admission{
+						ClusterQueue: "foo",
+						PodSetFlavors: []kueue.PodSetFlavors{
+							{
+								Name: "driver",
+								Flavors: map[corev1.ResourceName]string{
+									corev1.ResourceCPU:      "on-demand",
+									corev1.ResourcePods:     "on-demand",
+									kueue.ResourceWorkloads: "default",
+								},
+							},
+							{
+								Name: "workers",
+								Flavors: map[corev1.ResourceName]string{
+									corev1.ResourceCPU:  "spot",
+									corev1.ResourcePods: "spot",
+								},
+							},
+						},
+					},
+				},
+			},
+			wantInfo: Info{
+				ClusterQueue: "foo",
+				TotalRequests: []PodSetResources{
+					{
+						Name: "driver",
+						Requests: Requests{
+							corev1.ResourceCPU:      10,
+							corev1.ResourcePods:     1,
+							kueue.ResourceWorkloads: 1,
+						},
+						Flavors: map[corev1.ResourceName]string{
+							corev1.ResourceCPU:      "on-demand",
+							corev1.ResourcePods:     "on-demand",
+							kueue.ResourceWorkloads: "default",
+						},
+						Count: 1,
+					},
+					{
+						Name: "workers",
+						Requests: Requests{
+							corev1.ResourceCPU:  15,
+							corev1.ResourcePods: 3,
+						},
+						Flavors: map[corev1.ResourceName]string{
+							corev1.ResourceCPU:  "spot",
+							corev1.ResourcePods: "spot",
+						},
+						Count: 3,
+					},
+				},
+			},
+		},
+		"admitted split between flavors, with reclaimable pods": {
+			workload: kueue.Workload{
+				Spec: kueue.WorkloadSpec{
+					PodSets: []kueue.PodSet{
+						{
+							Name: "workers",
+							Spec: corev1.PodSpec{
+								Containers: containersForRequests(
+									map[corev1.ResourceName]string{
+										corev1.ResourceCPU: "1",
+									}),
+							},
+							Count: 10,
+						},
+					},
+					Admission: &kueue.Admission{
+						ClusterQueue: "foo",
+						PodSetFlavors: []kueue.PodSetFlavors{
+							{
+								Name: "workers",
+								Flavors: map[corev1.ResourceName]string{
+									corev1.ResourceCPU: "spot",
+								},
+								Count: pointer.Int32(6),
+								Splits: []kueue.PodSetSplit{
+									{
+										Flavors: map[corev1.ResourceName]string{
+											corev1.ResourceCPU: "on-demand",
+										},
+										Count: 4,
+									},
+								},
+							},
+						},
+					},
+				},
+				Status: kueue.WorkloadStatus{
+					ReclaimablePods: []kueue.ReclaimablePod{
+						{Name: "workers", Count: 5},
+					},
+				},
+			},
+			wantInfo: Info{
+				ClusterQueue: "foo",
+				TotalRequests: []PodSetResources{
+					{
+						Name: "workers",
+						Requests: Requests{
+							corev1.ResourceCPU: 5000,
+						},
+						Flavors: map[corev1.ResourceName]string{
+							corev1.ResourceCPU: "spot",
+						},
+						Count: 5,
+					},
+					{
+						Name: "workers",
+						Requests: Requests{
+							corev1.ResourceCPU: 0,
+						},
+						Flavors: map[corev1.ResourceName]string{
+							corev1.ResourceCPU: "on-demand",
+						},
+						Count: 0,
 					},
 				},
 			},
@@ -244,6 +498,173 @@ func TestNewInfo(t *testing.T) {
 	}
 }
 
+func TestResourceTransformations(t *testing.T) {
+	SetResourceTransformations(&config.Resources{
+		PodOverhead: corev1.ResourceList{
+			corev1.ResourceMemory: resource.MustParse("1Mi"),
+		},
+		Transformations: []config.ResourceTransformation{
+			{
+				Input:    "nvidia.com/mig-1g.5gb",
+				Strategy: config.ResourceTransformationReplace,
+				Outputs: corev1.ResourceList{
+					"nvidia.com/gpu": resource.MustParse("0.25"),
+				},
+			},
+			{
+				Input:    corev1.ResourceCPU,
+				Strategy: config.ResourceTransformationRetain,
+				Outputs: corev1.ResourceList{
+					"example.com/credits": resource.MustParse("2"),
+				},
+			},
+		},
+	})
+	defer SetResourceTransformations(nil)
+
+	wl := utiltesting.MakeWorkload("wl", "ns").
+		PodSets([]kueue.PodSet{{
+			Name:  "main",
+			Count: 3,
+			Spec: corev1.PodSpec{
+				Containers: containersForRequests(map[corev1.ResourceName]string{
+					corev1.ResourceCPU:      "500m",
+					"nvidia.com/mig-1g.5gb": "1",
+				}),
+			},
+		}}).Obj()
+	want := []PodSetResources{{
+		Name: "main",
+		Requests: Requests{
+			corev1.ResourceCPU:    1500,
+			corev1.ResourceMemory: 3 * 1024 * 1024,
+			// 3 pods with 0.25 each, rounded up.
+			"nvidia.com/gpu":      1,
+			"example.com/credits": 3,
+		},
+		Count: 3,
+	}}
+	if diff := cmp.Diff(want, NewInfo(wl).TotalRequests); diff != "" {
+		t.Errorf("Unexpected requests (-want,+got):\n%s", diff)
+	}
+}
+
+func TestWithPodSetCount(t *testing.T) {
+	wl := kueue.Workload{
+		Spec: kueue.WorkloadSpec{
+			PodSets: []kueue.PodSet{
+				{
+					Name: "driver",
+					Spec: corev1.PodSpec{
+						Containers: containersForRequests(
+							map[corev1.ResourceName]string{
+								corev1.ResourceCPU: "1",
+							}),
+					},
+					Count: 1,
+				},
+				{
+					Name: "workers",
+					Spec: corev1.PodSpec{
+						Containers: containersForRequests(
+							map[corev1.ResourceName]string{
+								corev1.ResourceCPU:    "500m",
+								corev1.ResourceMemory: "1Mi",
+							}),
+					},
+					Count:    10,
+					MinCount: pointer.Int32(4),
+				},
+			},
+		},
+	}
+	info := NewInfo(&wl)
+	idx, ok := CanBePartiallyAdmitted(&wl)
+	if !ok || idx != 1 {
+		t.Fatalf("CanBePartiallyAdmitted(_) = %d, %t, want 1, true", idx, ok)
+	}
+	got := info.WithPodSetCount(idx, 6)
+	want := []PodSetResources{
+		{
+			Name: "driver",
+			Requests: Requests{
+				corev1.ResourceCPU: 1000,
+			},
+			Count: 1,
+		},
+		{
+			Name: "workers",
+			Requests: Requests{
+				corev1.ResourceCPU:    3000,
+				corev1.ResourceMemory: 6 * 1024 * 1024,
+			},
+			Count: 6,
+		},
+	}
+	if diff := cmp.Diff(want, got.TotalRequests); diff != "" {
+		t.Errorf("WithPodSetCount(_) = (-want,+got):\n%s", diff)
+	}
+	if diff := cmp.Diff(int64(5000), info.TotalRequests[1].Requests[corev1.ResourceCPU]); diff != "" {
+		t.Errorf("WithPodSetCount(_) modified the original requests (-want,+got):\n%s", diff)
+	}
+}
+
+func TestWithSplitPodSet(t *testing.T) {
+	wl := utiltesting.MakeWorkload("wl", "ns").
+		PodSets([]kueue.PodSet{
+			{
+				Name:  "driver",
+				Count: 1,
+				Spec: corev1.PodSpec{
+					Containers: containersForRequests(map[corev1.ResourceName]string{
+						corev1.ResourceCPU: "1",
+					}),
+				},
+			},
+			{
+				Name:  "workers",
+				Count: 10,
+				Spec: corev1.PodSpec{
+					Containers: containersForRequests(map[corev1.ResourceName]string{
+						corev1.ResourceCPU: "500m",
+					}),
+				},
+			},
+		}).Obj()
+	info := NewInfo(wl)
+	got := info.WithSplitPodSet(1, 6)
+	want := []PodSetResources{
+		{
+			Name:     "driver",
+			Requests: Requests{corev1.ResourceCPU: 1000},
+			Count:    1,
+		},
+		{
+			Name:     "workers",
+			Requests: Requests{corev1.ResourceCPU: 3000},
+			Count:    6,
+		},
+		{
+			Name:     "workers",
+			Requests: Requests{corev1.ResourceCPU: 2000},
+			Count:    4,
+		},
+	}
+	if diff := cmp.Diff(want, got.TotalRequests); diff != "" {
+		t.Errorf("WithSplitPodSet(_) = (-want,+got):\n%s", diff)
+	}
+	gotNames := make([]string, len(got.Obj.Spec.PodSets))
+	for i, ps := range got.Obj.Spec.PodSets {
+		gotNames[i] = ps.Name
+	}
+	if diff := cmp.Diff([]string{"driver", "workers", "workers"}, gotNames); diff != "" {
+		t.Errorf("WithSplitPodSet(_) pod sets (-want,+got):\n%s", diff)
+	}
+	if len(wl.Spec.PodSets) != 2 {
+		t.Errorf("WithSplitPodSet(_) modified the original pod sets")
+	}
+}
+
 var ignoreConditionTimestamps = cmpopts.IgnoreFields(metav1.Condition{}, "LastTransitionTime")
 
 func TestUpdateWorkloadStatus(t *testing.T) {
@@ -345,6 +766,117 @@ func TestUpdateWorkloadStatus(t *testing.T) {
 	}
 }
 
+func TestSetPendingConditions(t *testing.T) {
+	cases := map[string]struct {
+		oldStatus   kueue.WorkloadStatus
+		reason      string
+		message     string
+		wantStatus  kueue.WorkloadStatus
+		wantChanged bool
+	}{
+		"initial empty": {
+			reason:  kueue.WorkloadReasonPending,
+			message: "didn't fit",
+			wantStatus: kueue.WorkloadStatus{
+				Conditions: []metav1.Condition{
+					{
+						Type:    kueue.WorkloadQuotaReserved,
+						Status:  metav1.ConditionFalse,
+						Reason:  kueue.WorkloadReasonPending,
+						Message: "didn't fit",
+					},
+					{
+						Type:    kueue.WorkloadAdmitted,
+						Status:  metav1.ConditionFalse,
+						Reason:  kueue.WorkloadReasonPending,
+						Message: "didn't fit",
+					},
+				},
+			},
+			wantChanged: true,
+		},
+		"unchanged": {
+			oldStatus: kueue.WorkloadStatus{
+				Conditions: []metav1.Condition{
+					{
+						Type:    kueue.WorkloadQuotaReserved,
+						Status:  metav1.ConditionFalse,
+						Reason:  kueue.WorkloadReasonInactive,
+						Message: "The workload is inactive",
+					},
+					{
+						Type:    kueue.WorkloadAdmitted,
+						Status:  metav1.ConditionFalse,
+						Reason:  kueue.WorkloadReasonInactive,
+						Message: "The workload is inactive",
+					},
+				},
+			},
+			reason:  kueue.WorkloadReasonInactive,
+			message: "The workload is inactive",
+			wantStatus: kueue.WorkloadStatus{
+				Conditions: []metav1.Condition{
+					{
+						Type:    kueue.WorkloadQuotaReserved,
+						Status:  metav1.ConditionFalse,
+						Reason:  kueue.WorkloadReasonInactive,
+						Message: "The workload is inactive",
+					},
+					{
+						Type:    kueue.WorkloadAdmitted,
+						Status:  metav1.ConditionFalse,
+						Reason:  kueue.WorkloadReasonInactive,
+						Message: "The workload is inactive",
+					},
+				},
+			},
+		},
+		"only admitted condition differs": {
+			oldStatus: kueue.WorkloadStatus{
+				Conditions: []metav1.Condition{
+					{
+						Type:    kueue.WorkloadQuotaReserved,
+						Status:  metav1.ConditionFalse,
+						Reason:  kueue.WorkloadReasonPending,
+						Message: "didn't fit",
+					},
+				},
+			},
+			reason:  kueue.WorkloadReasonPending,
+			message: "didn't fit",
+			wantStatus: kueue.WorkloadStatus{
+				Conditions: []metav1.Condition{
+					{
+						Type:    kueue.WorkloadQuotaReserved,
+						Status:  metav1.ConditionFalse,
+						Reason:  kueue.WorkloadReasonPending,
+						Message: "didn't fit",
+					},
+					{
+						Type:    kueue.WorkloadAdmitted,
+						Status:  metav1.ConditionFalse,
+						Reason:  kueue.WorkloadReasonPending,
+						Message: "didn't fit",
+					},
+				},
+			},
+			wantChanged: true,
+		},
+	}
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			wl := utiltesting.MakeWorkload("foo", "bar").Obj()
+			wl.Status = tc.oldStatus
+			if changed := SetPendingConditions(wl, tc.reason, tc.message); changed != tc.wantChanged {
+				t.Errorf("SetPendingConditions() = %t, want %t", changed, tc.wantChanged)
+			}
+			if diff := cmp.Diff(tc.wantStatus, wl.Status, ignoreConditionTimestamps); diff != "" {
+				t.Errorf("Unexpected status (-want,+got):\n%s", diff)
+			}
+		})
+	}
+}
+
 func containersForRequests(requests ...map[corev1.ResourceName]string) []corev1.Container {
 	containers := make([]corev1.Container, len(requests))
 	for i, r := range requests {
@@ -358,3 +890,422 @@ func containersForRequests(requests ...map[corev1.ResourceName]string) []corev1.
 	}
 	return containers
 }
+
+func TestResourceUsage(t *testing.T) {
+	created := time.Now().Truncate(time.Second)
+	admitted := created.Add(time.Minute)
+	finished := admitted.Add(time.Hour)
+	baseWorkload := func() *utiltesting.WorkloadWrapper {
+		return utiltesting.MakeWorkload("foo", "bar").
+			Creation(created).
+			PodSets([]kueue.PodSet{
+				{
+					Name:  "driver",
+					Count: 1,
+					Spec: corev1.PodSpec{
+						Containers: containersForRequests(map[corev1.ResourceName]string{
+							corev1.ResourceCPU: "1",
+						}),
+					},
+				},
+				{
+					Name:  "workers",
+					Count: 3,
+					Spec: corev1.PodSpec{
+						Containers: containersForRequests(map[corev1.ResourceName]string{
+							corev1.ResourceCPU:    "2",
+							corev1.ResourceMemory: "1Gi",
+						}),
+					},
+				},
+			})
+	}
+	admission := &kueue.Admission{
+		ClusterQueue: "cq",
+		PodSetFlavors: []kueue.PodSetFlavors{
+			{
+				Name:    "driver",
+				Flavors: map[corev1.ResourceName]string{corev1.ResourceCPU: "on-demand"},
+			},
+			{
+				Name: "workers",
+				Flavors: map[corev1.ResourceName]string{
+					corev1.ResourceCPU:    "spot",
+					corev1.ResourceMemory: "spot",
+				},
+			},
+		},
+	}
+	cases := map[string]struct {
+		workload  *kueue.Workload
+		wantUsage *kueue.WorkloadResourceUsage
+	}{
+		"not finished": {
+			workload: baseWorkload().Admit(admission).Obj(),
+		},
+		"finished without admission": {
+			workload: baseWorkload().
+				Condition(metav1.Condition{
+					Type:               kueue.WorkloadFinished,
+					Status:             metav1.ConditionTrue,
+					LastTransitionTime: metav1.NewTime(finished),
+				}).
+				Obj(),
+		},
+		"finished": {
+			workload: baseWorkload().
+				Admit(admission).
+				Condition(metav1.Condition{
+					Type:               kueue.WorkloadAdmitted,
+					Status:             metav1.ConditionTrue,
+					LastTransitionTime: metav1.NewTime(admitted),
+				}).
+				Condition(metav1.Condition{
+					Type:               kueue.WorkloadFinished,
+					Status:             metav1.ConditionTrue,
+					LastTransitionTime: metav1.NewTime(finished),
+				}).
+				Obj(),
+			wantUsage: &kueue.WorkloadResourceUsage{
+				QueuedTime: metav1.Duration{Duration: time.Minute},
+				RunTime:    metav1.Duration{Duration: time.Hour},
+				AdmittedResources: map[corev1.ResourceName]map[string]resource.Quantity{
+					corev1.ResourceCPU: {
+						"on-demand": resource.MustParse("1"),
+						"spot":      resource.MustParse("6"),
+					},
+					corev1.ResourceMemory: {
+						"spot": resource.MustParse("3Gi"),
+					},
+				},
+			},
+		},
+		"finished without admitted condition": {
+			workload: baseWorkload().
+				Admit(admission).
+				Condition(metav1.Condition{
+					Type:               kueue.WorkloadFinished,
+					Status:             metav1.ConditionTrue,
+					LastTransitionTime: metav1.NewTime(finished),
+				}).
+				Obj(),
+			wantUsage: &kueue.WorkloadResourceUsage{
+				RunTime: metav1.Duration{Duration: time.Hour + time.Minute},
+				AdmittedResources: map[corev1.ResourceName]map[string]resource.Quantity{
+					corev1.ResourceCPU: {
+						"on-demand": resource.MustParse("1"),
+						"spot":      resource.MustParse("6"),
+					},
+					corev1.ResourceMemory: {
+						"spot": resource.MustParse("3Gi"),
+					},
+				},
+			},
+		},
+	}
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			usage := ResourceUsage(tc.workload)
+			if diff := cmp.Diff(tc.wantUsage, usage); diff != "" {
+				t.Errorf("ResourceUsage(_) = (-want,+got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestLatestStartTime(t *testing.T) {
+	deadline := time.Now().Truncate(time.Second)
+	cases := map[string]struct {
+		workload  *kueue.Workload
+		wantStart time.Time
+		wantOk    bool
+	}{
+		"no deadline": {
+			workload: utiltesting.MakeWorkload("foo", "bar").ExpectedDuration(time.Hour).Obj(),
+		},
+		"deadline only": {
+			workload:  utiltesting.MakeWorkload("foo", "bar").Deadline(deadline).Obj(),
+			wantStart: deadline,
+			wantOk:    true,
+		},
+		"deadline and expected duration": {
+			workload: utiltesting.MakeWorkload("foo", "bar").
+				Deadline(deadline).
+				ExpectedDuration(time.Hour).
+				Obj(),
+			wantStart: deadline.Add(-time.Hour),
+			wantOk:    true,
+		},
+	}
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			start, ok := LatestStartTime(tc.workload)
+			if ok != tc.wantOk {
+				t.Errorf("LatestStartTime(_) returned %t, want %t", ok, tc.wantOk)
+			}
+			if !start.Equal(tc.wantStart) {
+				t.Errorf("LatestStartTime(_) = %v, want %v", start, tc.wantStart)
+			}
+		})
+	}
+}
+
+func TestAddSchedulingAttempt(t *testing.T) {
+	wl := utiltesting.MakeWorkload("foo", "bar").Obj()
+	AddSchedulingAttempt(wl, 2*time.Millisecond)
+	AddSchedulingAttempt(wl, 3*time.Millisecond)
+	want := &kueue.SchedulingStats{
+		Attempts:       2,
+		EvaluationTime: metav1.Duration{Duration: 5 * time.Millisecond},
+	}
+	if diff := cmp.Diff(want, wl.Status.SchedulingStats); diff != "" {
+		t.Errorf("Unexpected scheduling stats (-want,+got):\n%s", diff)
+	}
+	if got := SchedulingAttempts(wl); got != 2 {
+		t.Errorf("SchedulingAttempts(_) = %d, want 2", got)
+	}
+}
+
+func TestEvict(t *testing.T) {
+	scheme := runtime.NewScheme()
+	if err := kueue.AddToScheme(scheme); err != nil {
+		t.Fatalf("Failed to add kueue scheme: %v", err)
+	}
+	wl := utiltesting.MakeWorkload("foo", "bar").
+		Admit(utiltesting.MakeAdmission("cq").Flavor(corev1.ResourceCPU, "spot").Obj()).
+		Condition(metav1.Condition{
+			Type:   kueue.WorkloadQuotaReserved,
+			Status: metav1.ConditionTrue,
+			Reason: kueue.WorkloadReasonQuotaReserved,
+		}).
+		Condition(metav1.Condition{
+			Type:   kueue.WorkloadAdmitted,
+			Status: metav1.ConditionTrue,
+			Reason: kueue.WorkloadReasonAdmitted,
+		}).
+		Obj()
+	cl := fake.NewClientBuilder().WithScheme(scheme).WithObjects(wl).Build()
+	ctx := context.Background()
+	if err := Evict(ctx, cl, wl, kueue.WorkloadEvictedByPreemption, "preempted"); err != nil {
+		t.Fatalf("Failed evicting workload: %v", err)
+	}
+	if wl.Status.Admission == nil {
+		t.Errorf("Evict modified the admission of the original object")
+	}
+	var updatedWl kueue.Workload
+	if err := cl.Get(ctx, client.ObjectKeyFromObject(wl), &updatedWl); err != nil {
+		t.Fatalf("Failed obtaining updated object: %v", err)
+	}
+	if updatedWl.Status.Admission != nil {
+		t.Errorf("Evicted workload has admission %v", updatedWl.Status.Admission)
+	}
+	if updatedWl.Status.Evictions != 1 {
+		t.Errorf("Evicted workload has %d evictions, want 1", updatedWl.Status.Evictions)
+	}
+	if got := EvictionReason(&updatedWl); got != kueue.WorkloadEvictedByPreemption {
+		t.Errorf("EvictionReason() = %q, want %q", got, kueue.WorkloadEvictedByPreemption)
+	}
+	wantConditions := []metav1.Condition{
+		{
+			Type:    kueue.WorkloadQuotaReserved,
+			Status:  metav1.ConditionFalse,
+			Reason:  kueue.WorkloadEvictedByPreemption,
+			Message: "preempted",
+		},
+		{
+			Type:    kueue.WorkloadAdmitted,
+			Status:  metav1.ConditionFalse,
+			Reason:  kueue.WorkloadEvictedByPreemption,
+			Message: "preempted",
+		},
+		{
+			Type:    kueue.WorkloadEvicted,
+			Status:  metav1.ConditionTrue,
+			Reason:  kueue.WorkloadEvictedByPreemption,
+			Message: "preempted",
+		},
+	}
+	if diff := cmp.Diff(wantConditions, updatedWl.Status.Conditions, ignoreConditionTimestamps); diff != "" {
+		t.Errorf("Unexpected conditions (-want,+got):\n%s", diff)
+	}
+}
+
+func TestRecordFlavorEvictions(t *testing.T) {
+	start := time.Date(2022, 10, 14, 10, 0, 0, 0, time.UTC)
+	wl := utiltesting.MakeWorkload("foo", "bar").Obj()
+	admission := utiltesting.MakeAdmission("cq").
+		Flavor(corev1.ResourceCPU, "spot").
+		Flavor(corev1.ResourceMemory, "spot").
+		Flavor("example.com/gpu", "a100").
+		Obj()
+	var wantSpot []metav1.Time
+	for i := 0; i < MaxFlavorEvictions+2; i++ {
+		now := start.Add(time.Duration(i) * time.Minute)
+		recordFlavorEvictions(wl, admission, now)
+		wantSpot = append(wantSpot, metav1.NewTime(now))
+	}
+	want := []kueue.FlavorEvictions{
+		{Flavor: "a100", Times: wantSpot[2:]},
+		{Flavor: "spot", Times: wantSpot[2:]},
+	}
+	if diff := cmp.Diff(want, wl.Status.FlavorEvictions); diff != "" {
+		t.Errorf("Unexpected flavor evictions (-want,+got):\n%s", diff)
+	}
+}
+
+func TestExceedsFlavorEvictions(t *testing.T) {
+	start := time.Date(2022, 10, 14, 10, 0, 0, 0, time.UTC)
+	wl := utiltesting.MakeWorkload("foo", "bar").
+		FlavorEvictions("spot", start, start.Add(50*time.Minute), start.Add(70*time.Minute), start.Add(3*time.Hour)).
+		Obj()
+	cases := map[string]struct {
+		flavor       string
+		maxEvictions int32
+		window       time.Duration
+		want         bool
+	}{
+		"within the window": {
+			flavor:       "spot",
+			maxEvictions: 2,
+			window:       time.Hour + 10*time.Minute,
+			want:         true,
+		},
+		"spread beyond the window": {
+			flavor:       "spot",
+			maxEvictions: 2,
+			window:       time.Hour,
+		},
+		"fewer evictions": {
+			flavor:       "spot",
+			maxEvictions: 4,
+			window:       24 * time.Hour,
+		},
+		"other flavor": {
+			flavor:       "on-demand",
+			maxEvictions: 1,
+			window:       24 * time.Hour,
+		},
+	}
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			if got := ExceedsFlavorEvictions(wl, tc.flavor, tc.maxEvictions, tc.window); got != tc.want {
+				t.Errorf("ExceedsFlavorEvictions(_) = %t, want %t", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestAdmissionGroup(t *testing.T) {
+	cases := map[string]struct {
+		workload  *kueue.Workload
+		wantGroup string
+		wantSize  int
+		wantOk    bool
+	}{
+		"no group": {
+			workload: utiltesting.MakeWorkload("foo", "bar").Obj(),
+		},
+		"group": {
+			workload:  utiltesting.MakeWorkload("foo", "bar").AdmissionGroup("spark", 3).Obj(),
+			wantGroup: "bar/spark",
+			wantSize:  3,
+			wantOk:    true,
+		},
+		"invalid size": {
+			workload: utiltesting.MakeWorkload("foo", "bar").AdmissionGroup("spark", 0).Obj(),
+		},
+	}
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			group, size, ok := AdmissionGroup(tc.workload)
+			if ok != tc.wantOk {
+				t.Errorf("AdmissionGroup(_) returned %t, want %t", ok, tc.wantOk)
+			}
+			if group != tc.wantGroup || size != tc.wantSize {
+				t.Errorf("AdmissionGroup(_) = %q, %d, want %q, %d", group, size, tc.wantGroup, tc.wantSize)
+			}
+		})
+	}
+}
+
+func TestIsAdmitted(t *testing.T) {
+	admission := utiltesting.MakeAdmission("cq").AdmissionChecks("provisioning", "budget").Obj()
+	cases := map[string]struct {
+		workload    *kueue.Workload
+		want        bool
+		wantPending []string
+	}{
+		"no admission": {
+			workload: utiltesting.MakeWorkload("foo", "bar").Obj(),
+		},
+		"no admission checks": {
+			workload: utiltesting.MakeWorkload("foo", "bar").Admit(utiltesting.MakeAdmission("cq").Obj()).Obj(),
+			want:     true,
+		},
+		"checks pending": {
+			workload: utiltesting.MakeWorkload("foo", "bar").
+				Admit(admission).
+				AdmissionCheck("provisioning", kueue.CheckStateReady).
+				AdmissionCheck("budget", kueue.CheckStatePending).
+				Obj(),
+			wantPending: []string{"budget"},
+		},
+		"checks missing": {
+			workload:    utiltesting.MakeWorkload("foo", "bar").Admit(admission).Obj(),
+			wantPending: []string{"provisioning", "budget"},
+		},
+		"checks ready": {
+			workload: utiltesting.MakeWorkload("foo", "bar").
+				Admit(admission).
+				AdmissionCheck("budget", kueue.CheckStateReady).
+				AdmissionCheck("provisioning", kueue.CheckStateReady).
+				Obj(),
+			want: true,
+		},
+	}
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			if got := IsAdmitted(tc.workload); got != tc.want {
+				t.Errorf("IsAdmitted(_) = %t, want %t", got, tc.want)
+			}
+			if diff := cmp.Diff(tc.wantPending, PendingAdmissionChecks(tc.workload)); diff != "" {
+				t.Errorf("Unexpected pending admission checks (-want,+got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestSyncAdmissionChecks(t *testing.T) {
+	wl := utiltesting.MakeWorkload("foo", "bar").
+		Admit(utiltesting.MakeAdmission("cq").AdmissionChecks("provisioning", "budget").Obj()).
+		AdmissionCheck("budget", kueue.CheckStateReady).
+		AdmissionCheck("removed", kueue.CheckStateRetry).
+		Obj()
+	if !SyncAdmissionChecks(wl) {
+		t.Errorf("SyncAdmissionChecks(_) = false, want true")
+	}
+	want := []kueue.AdmissionCheckState{
+		{Name: "provisioning", State: kueue.CheckStatePending},
+		{Name: "budget", State: kueue.CheckStateReady},
+	}
+	if diff := cmp.Diff(want, wl.Status.AdmissionChecks, cmpopts.IgnoreFields(kueue.AdmissionCheckState{}, "LastTransitionTime")); diff != "" {
+		t.Errorf("Unexpected admission checks (-want,+got):\n%s", diff)
+	}
+	if SyncAdmissionChecks(wl) {
+		t.Errorf("SyncAdmissionChecks(_) = true for synced admission checks, want false")
+	}
+
+	SetAdmissionCheckState(&wl.Status.AdmissionChecks, kueue.AdmissionCheckState{
+		Name:    "provisioning",
+		State:   kueue.CheckStateReady,
+		Message: "Provisioned",
+	})
+	if !IsAdmitted(wl) {
+		t.Errorf("IsAdmitted(_) = false after all the admission checks are ready, want true")
+	}
+
+	SetEvictedCondition(wl, wl.Status.Admission, "Preempted", "Preempted")
+	if wl.Status.AdmissionChecks != nil {
+		t.Errorf("Got admission checks %v after eviction, want none", wl.Status.AdmissionChecks)
+	}
+}
@@ -17,6 +17,8 @@ limitations under the License.
 package e2e
 
 import (
+	"errors"
+
 	"github.com/onsi/ginkgo/v2"
 	"github.com/onsi/gomega"
 	batchv1 "k8s.io/api/batch/v1"
@@ -24,6 +26,7 @@ import (
 	apimeta "k8s.io/apimachinery/pkg/api/meta"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
 
 	kueue "sigs.k8s.io/kueue/apis/kueue/v1alpha2"
 	"sigs.k8s.io/kueue/pkg/util/testing"
@@ -115,5 +118,71 @@ var _ = ginkgo.Describe("Kueue", func() {
 
 			}, util.Timeout, util.Interval).Should(gomega.BeTrue())
 		})
+		ginkgo.It("Should resume a suspended job once the quota is released", func() {
+			lookupKey := types.NamespacedName{Name: "test-job", Namespace: ns.Name}
+			createdJob := &batchv1.Job{}
+			gomega.Eventually(func() bool {
+				if err := k8sClient.Get(ctx, lookupKey, createdJob); err != nil {
+					return false
+				}
+				return !*createdJob.Spec.Suspend
+			}, util.Timeout, util.Interval).Should(gomega.BeTrue())
+
+			ginkgo.By("creating a second job that doesn't fit while the first one runs")
+			secondJob := testing.MakeJob("test-job-2", ns.Name).Queue("main").Request("cpu", "1").Request("memory", "20Mi").
+				Image("sleep", "gcr.io/k8s-staging-perf-tests/sleep:v0.0.3", []string{"5s"}).Obj()
+			gomega.Expect(k8sClient.Create(ctx, secondJob)).Should(gomega.Succeed())
+			secondKey := client.ObjectKeyFromObject(secondJob)
+			secondWorkload := &kueue.Workload{}
+			gomega.Eventually(func() bool {
+				if err := k8sClient.Get(ctx, secondKey, secondWorkload); err != nil {
+					return false
+				}
+				cond := apimeta.FindStatusCondition(secondWorkload.Status.Conditions, kueue.WorkloadAdmitted)
+				return cond != nil && cond.Status == metav1.ConditionFalse && cond.Reason == "Pending"
+			}, util.Timeout, util.Interval).Should(gomega.BeTrue())
+			gomega.Expect(k8sClient.Get(ctx, secondKey, secondJob)).Should(gomega.Succeed())
+			gomega.Expect(*secondJob.Spec.Suspend).Should(gomega.BeTrue())
+
+			ginkgo.By("waiting for the second job to run after the first one finishes")
+			gomega.Eventually(func() bool {
+				if err := k8sClient.Get(ctx, secondKey, secondJob); err != nil {
+					return false
+				}
+				return !*secondJob.Spec.Suspend && secondJob.Status.Succeeded > 0
+			}, util.Timeout, util.Interval).Should(gomega.BeTrue())
+			firstWorkload := &kueue.Workload{}
+			gomega.Expect(k8sClient.Get(ctx, lookupKey, firstWorkload)).Should(gomega.Succeed())
+			gomega.Expect(apimeta.IsStatusConditionTrue(firstWorkload.Status.Conditions, kueue.WorkloadFinished)).Should(gomega.BeTrue())
+		})
+		ginkgo.It("Should expose the admission in the metrics endpoint", func() {
+			lookupKey := types.NamespacedName{Name: "test-job", Namespace: ns.Name}
+			createdWorkload := &kueue.Workload{}
+			gomega.Eventually(func() bool {
+				if err := k8sClient.Get(ctx, lookupKey, createdWorkload); err != nil {
+					return false
+				}
+				return apimeta.IsStatusConditionTrue(createdWorkload.Status.Conditions, kueue.WorkloadAdmitted)
+			}, util.Timeout, util.Interval).Should(gomega.BeTrue())
+
+			gomega.Eventually(scrapeMetrics, util.Timeout, util.Interval).Should(gomega.And(
+				gomega.ContainSubstring(`kueue_admitted_workloads_total{cluster_queue="cluster-queue"} 1`),
+				gomega.ContainSubstring(`kueue_pending_workloads{cluster_queue="cluster-queue",status="active"} 0`),
+			))
+		})
 	})
 })
+
+// scrapeMetrics returns the metrics exposed by the Kueue manager, reaching
+// its pod through the API server proxy.
+func scrapeMetrics() (string, error) {
+	pods, err := clientset.CoreV1().Pods(kueueNamespace).List(ctx, metav1.ListOptions{LabelSelector: "control-plane=controller-manager"})
+	if err != nil {
+		return "", err
+	}
+	if len(pods.Items) == 0 {
+		return "", errors.New("no pods of the Kueue manager")
+	}
+	body, err := clientset.CoreV1().Pods(kueueNamespace).ProxyGet("http", pods.Items[0].Name, "8080", "/metrics", nil).DoRaw(ctx)
+	return string(body), err
+}
@@ -23,7 +23,9 @@ import (
 
 	"github.com/onsi/ginkgo/v2"
 	"github.com/onsi/gomega"
+	"k8s.io/client-go/kubernetes"
 	"k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/rest"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/client/config"
 
@@ -35,12 +37,16 @@ import (
 
 var (
 	k8sClient client.Client
+	clientset *kubernetes.Clientset
 	ctx       context.Context
 )
 
 const (
 	Timeout  = time.Minute
 	Interval = time.Millisecond * 250
+
+	// kueueNamespace is the namespace where the Kueue manager is deployed.
+	kueueNamespace = "kueue-system"
 )
 
 func TestAPIs(t *testing.T) {
@@ -50,10 +56,7 @@ func TestAPIs(t *testing.T) {
 	)
 }
 
-func CreateClientUsingCluster() client.Client {
-	cfg := config.GetConfigOrDie()
-	gomega.ExpectWithOffset(1, cfg).NotTo(gomega.BeNil())
-
+func CreateClientUsingCluster(cfg *rest.Config) client.Client {
 	err := kueue.AddToScheme(scheme.Scheme)
 	gomega.ExpectWithOffset(1, err).NotTo(gomega.HaveOccurred())
 
@@ -73,7 +76,10 @@ func KueueReadyForTesting(client client.Client) {
 }
 
 var _ = ginkgo.BeforeSuite(func() {
-	k8sClient = CreateClientUsingCluster()
+	cfg := config.GetConfigOrDie()
+	gomega.Expect(cfg).NotTo(gomega.BeNil())
+	k8sClient = CreateClientUsingCluster(cfg)
+	clientset = kubernetes.NewForConfigOrDie(cfg)
 	ctx = context.Background()
 	KueueReadyForTesting(k8sClient)
 })
@@ -0,0 +1,68 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package e2e
+
+import (
+	"github.com/onsi/ginkgo/v2"
+	"github.com/onsi/gomega"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	kueue "sigs.k8s.io/kueue/apis/kueue/v1alpha2"
+	"sigs.k8s.io/kueue/pkg/util/testing"
+	"sigs.k8s.io/kueue/test/util"
+)
+
+var _ = ginkgo.Describe("Webhooks", func() {
+	var ns *corev1.Namespace
+	ginkgo.BeforeEach(func() {
+		ns = &corev1.Namespace{
+			ObjectMeta: metav1.ObjectMeta{
+				GenerateName: "e2e-webhook-",
+			},
+		}
+		gomega.Expect(k8sClient.Create(ctx, ns)).To(gomega.Succeed())
+	})
+	ginkgo.AfterEach(func() {
+		gomega.Expect(util.DeleteNamespace(ctx, k8sClient, ns)).To(gomega.Succeed())
+	})
+	ginkgo.It("Should suspend a Job created with a queue name", func() {
+		job := testing.MakeJob("job", ns.Name).Queue("main").Suspend(false).Obj()
+		gomega.Expect(k8sClient.Create(ctx, job)).Should(gomega.Succeed())
+		createdJob := &batchv1.Job{}
+		gomega.Expect(k8sClient.Get(ctx, client.ObjectKeyFromObject(job), createdJob)).Should(gomega.Succeed())
+		gomega.Expect(*createdJob.Spec.Suspend).Should(gomega.BeTrue())
+	})
+	ginkgo.It("Should reject an invalid ClusterQueue", func() {
+		cq := testing.MakeClusterQueue("invalid-cluster-queue").QueueingStrategy(kueue.BestEffortFIFO).Backfill(true).Obj()
+		err := k8sClient.Create(ctx, cq)
+		gomega.Expect(err).Should(gomega.HaveOccurred())
+		gomega.Expect(errors.IsForbidden(err)).Should(gomega.BeTrue(), "error: %v", err)
+	})
+	ginkgo.It("Should reject updating the queueing strategy of a ClusterQueue", func() {
+		cq := testing.MakeClusterQueue("webhook-cluster-queue").QueueingStrategy(kueue.StrictFIFO).Obj()
+		gomega.Expect(k8sClient.Create(ctx, cq)).Should(gomega.Succeed())
+		defer util.ExpectClusterQueueToBeDeleted(ctx, k8sClient, cq, true)
+		cq.Spec.QueueingStrategy = kueue.BestEffortFIFO
+		err := k8sClient.Update(ctx, cq)
+		gomega.Expect(err).Should(gomega.HaveOccurred())
+		gomega.Expect(errors.IsForbidden(err)).Should(gomega.BeTrue(), "error: %v", err)
+	})
+})
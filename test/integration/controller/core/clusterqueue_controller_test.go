@@ -17,10 +17,13 @@ limitations under the License.
 package core
 
 import (
+	"fmt"
+
 	"github.com/google/go-cmp/cmp/cmpopts"
 	"github.com/onsi/ginkgo/v2"
 	"github.com/onsi/gomega"
 	corev1 "k8s.io/api/core/v1"
+	apimeta "k8s.io/apimachinery/pkg/api/meta"
 	"k8s.io/apimachinery/pkg/api/resource"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"sigs.k8s.io/controller-runtime/pkg/client"
@@ -174,8 +177,8 @@ var _ = ginkgo.Describe("ClusterQueue controller", func() {
 				gomega.Eventually(func() error {
 					var newWL kueue.Workload
 					gomega.Expect(k8sClient.Get(ctx, client.ObjectKeyFromObject(w), &newWL)).To(gomega.Succeed())
-					newWL.Spec.Admission = admissions[i]
-					return k8sClient.Update(ctx, &newWL)
+					newWL.Status.Admission = admissions[i]
+					return k8sClient.Status().Update(ctx, &newWL)
 				}, util.Timeout, util.Interval).Should(gomega.Succeed())
 			}
 
@@ -340,8 +343,9 @@ var _ = ginkgo.Describe("ClusterQueue controller", func() {
 
 			ginkgo.By("Admit workload")
 			admission := testing.MakeAdmission(cq.Name).Obj()
-			wl := testing.MakeWorkload("workload", ns.Name).Queue(lq.Name).Admit(admission).Obj()
+			wl := testing.MakeWorkload("workload", ns.Name).Queue(lq.Name).Obj()
 			gomega.Expect(k8sClient.Create(ctx, wl)).To(gomega.Succeed())
+			util.SetWorkloadAdmission(ctx, k8sClient, wl, admission)
 
 			ginkgo.By("Delete clusterQueue")
 			gomega.Expect(util.DeleteClusterQueue(ctx, k8sClient, cq)).To(gomega.Succeed())
@@ -362,4 +366,92 @@ var _ = ginkgo.Describe("ClusterQueue controller", func() {
 			}, util.Timeout, util.Interval).Should(testing.BeNotFoundError())
 		})
 	})
+
+	ginkgo.When("Deleting workloads held by finalizers", func() {
+		var (
+			cq             *kueue.ClusterQueue
+			lq             *kueue.LocalQueue
+			onDemandFlavor *kueue.ResourceFlavor
+		)
+
+		ginkgo.BeforeEach(func() {
+			onDemandFlavor = testing.MakeResourceFlavor(flavorOnDemand).Obj()
+			gomega.Expect(k8sClient.Create(ctx, onDemandFlavor)).To(gomega.Succeed())
+			cq = testing.MakeClusterQueue("cluster-queue").
+				Resource(testing.MakeResource(corev1.ResourceCPU).
+					Flavor(testing.MakeFlavor(flavorOnDemand, "5").Obj()).Obj()).Obj()
+			gomega.Expect(k8sClient.Create(ctx, cq)).To(gomega.Succeed())
+			lq = testing.MakeLocalQueue("queue", ns.Name).ClusterQueue(cq.Name).Obj()
+			gomega.Expect(k8sClient.Create(ctx, lq)).To(gomega.Succeed())
+		})
+
+		ginkgo.AfterEach(func() {
+			util.ExpectClusterQueueToBeDeleted(ctx, k8sClient, cq, true)
+			util.ExpectResourceFlavorToBeDeleted(ctx, k8sClient, onDemandFlavor, true)
+		})
+
+		ginkgo.It("Should release the quota before the finalizers are removed", func() {
+			ginkgo.By("Admit a workload with a finalizer")
+			admission := testing.MakeAdmission(cq.Name).Flavor(corev1.ResourceCPU, flavorOnDemand).Obj()
+			wl := testing.MakeWorkload("workload", ns.Name).Queue(lq.Name).
+				Request(corev1.ResourceCPU, "2").
+				Finalizers("kueue.x-k8s.io/test").Obj()
+			gomega.Expect(k8sClient.Create(ctx, wl)).To(gomega.Succeed())
+			util.SetWorkloadAdmission(ctx, k8sClient, wl, admission)
+			gomega.Eventually(func() int32 {
+				var updatedCq kueue.ClusterQueue
+				gomega.Expect(k8sClient.Get(ctx, client.ObjectKeyFromObject(cq), &updatedCq)).To(gomega.Succeed())
+				return updatedCq.Status.AdmittedWorkloads
+			}, util.Timeout, util.Interval).Should(gomega.Equal(int32(1)))
+
+			ginkgo.By("Delete the workload, as when its namespace is deleted")
+			gomega.Expect(k8sClient.Delete(ctx, wl)).To(gomega.Succeed())
+			gomega.Eventually(func() kueue.UsedResources {
+				var updatedCq kueue.ClusterQueue
+				gomega.Expect(k8sClient.Get(ctx, client.ObjectKeyFromObject(cq), &updatedCq)).To(gomega.Succeed())
+				gomega.Expect(updatedCq.Status.AdmittedWorkloads).To(gomega.Equal(int32(0)))
+				return updatedCq.Status.UsedResources
+			}, util.Timeout, util.Interval).Should(gomega.BeComparableTo(kueue.UsedResources{
+				corev1.ResourceCPU: {
+					flavorOnDemand: {Total: pointer.Quantity(resource.MustParse("0"))},
+				},
+			}))
+			util.ExpectAdmittedActiveWorkloadsMetric(cq, 0)
+
+			ginkgo.By("The workload still exists until the finalizer is removed")
+			var updatedWl kueue.Workload
+			gomega.Expect(k8sClient.Get(ctx, client.ObjectKeyFromObject(wl), &updatedWl)).To(gomega.Succeed())
+			updatedWl.Finalizers = nil
+			gomega.Expect(k8sClient.Update(ctx, &updatedWl)).To(gomega.Succeed())
+		})
+
+		ginkgo.It("Should not admit the pending workloads of a localQueue being deleted", func() {
+			ginkgo.By("Add a finalizer to the localQueue and delete it")
+			var updatedLq kueue.LocalQueue
+			gomega.Expect(k8sClient.Get(ctx, client.ObjectKeyFromObject(lq), &updatedLq)).To(gomega.Succeed())
+			updatedLq.Finalizers = []string{"kueue.x-k8s.io/test"}
+			gomega.Expect(k8sClient.Update(ctx, &updatedLq)).To(gomega.Succeed())
+			gomega.Expect(k8sClient.Delete(ctx, &updatedLq)).To(gomega.Succeed())
+
+			ginkgo.By("Create a workload in the localQueue")
+			wl := testing.MakeWorkload("workload", ns.Name).Queue(lq.Name).
+				Request(corev1.ResourceCPU, "2").Obj()
+			gomega.Expect(k8sClient.Create(ctx, wl)).To(gomega.Succeed())
+			gomega.Eventually(func() *metav1.Condition {
+				var updatedWl kueue.Workload
+				gomega.Expect(k8sClient.Get(ctx, client.ObjectKeyFromObject(wl), &updatedWl)).To(gomega.Succeed())
+				return apimeta.FindStatusCondition(updatedWl.Status.Conditions, kueue.WorkloadAdmitted)
+			}, util.Timeout, util.Interval).Should(gomega.BeComparableTo(&metav1.Condition{
+				Type:    kueue.WorkloadAdmitted,
+				Status:  metav1.ConditionFalse,
+				Reason:  kueue.WorkloadReasonInadmissible,
+				Message: fmt.Sprintf("Queue %s doesn't exist", lq.Name),
+			}, cmpopts.IgnoreFields(metav1.Condition{}, "LastTransitionTime")))
+
+			ginkgo.By("Remove the finalizer of the localQueue")
+			gomega.Expect(k8sClient.Get(ctx, client.ObjectKeyFromObject(lq), &updatedLq)).To(gomega.Succeed())
+			updatedLq.Finalizers = nil
+			gomega.Expect(k8sClient.Update(ctx, &updatedLq)).To(gomega.Succeed())
+		})
+	})
 })
@@ -0,0 +1,127 @@
+/*
+Copyright 2022 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package core
+
+import (
+	"github.com/onsi/ginkgo/v2"
+	"github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	kueue "sigs.k8s.io/kueue/apis/kueue/v1alpha2"
+	"sigs.k8s.io/kueue/pkg/util/testing"
+	"sigs.k8s.io/kueue/test/util"
+)
+
+// +kubebuilder:docs-gen:collapse=Imports
+
+var _ = ginkgo.Describe("Cohort controller", func() {
+	var (
+		ns             *corev1.Namespace
+		cohort         *kueue.Cohort
+		cqA            *kueue.ClusterQueue
+		cqB            *kueue.ClusterQueue
+		onDemandFlavor *kueue.ResourceFlavor
+	)
+
+	ginkgo.BeforeEach(func() {
+		ns = &corev1.Namespace{
+			ObjectMeta: metav1.ObjectMeta{
+				GenerateName: "core-cohort-",
+			},
+		}
+		gomega.Expect(k8sClient.Create(ctx, ns)).To(gomega.Succeed())
+
+		onDemandFlavor = testing.MakeResourceFlavor(flavorOnDemand).Obj()
+		gomega.Expect(k8sClient.Create(ctx, onDemandFlavor)).To(gomega.Succeed())
+
+		cohort = &kueue.Cohort{ObjectMeta: metav1.ObjectMeta{Name: "research"}}
+		gomega.Expect(k8sClient.Create(ctx, cohort)).To(gomega.Succeed())
+
+		cqA = testing.MakeClusterQueue("cq-a").
+			Cohort(cohort.Name).
+			Resource(testing.MakeResource(corev1.ResourceCPU).
+				Flavor(testing.MakeFlavor(flavorOnDemand, "5").Obj()).Obj()).Obj()
+		gomega.Expect(k8sClient.Create(ctx, cqA)).To(gomega.Succeed())
+		cqB = testing.MakeClusterQueue("cq-b").
+			Cohort(cohort.Name).
+			Resource(testing.MakeResource(corev1.ResourceCPU).
+				Flavor(testing.MakeFlavor(flavorOnDemand, "3").Obj()).Obj()).Obj()
+		gomega.Expect(k8sClient.Create(ctx, cqB)).To(gomega.Succeed())
+	})
+
+	ginkgo.AfterEach(func() {
+		gomega.Expect(util.DeleteNamespace(ctx, k8sClient, ns)).To(gomega.Succeed())
+		util.ExpectClusterQueueToBeDeleted(ctx, k8sClient, cqA, true)
+		util.ExpectClusterQueueToBeDeleted(ctx, k8sClient, cqB, true)
+		util.ExpectResourceFlavorToBeDeleted(ctx, k8sClient, onDemandFlavor, true)
+		gomega.Expect(client.IgnoreNotFound(k8sClient.Delete(ctx, cohort))).To(gomega.Succeed())
+	})
+
+	ginkgo.It("Should report the members, quota and usage of the cohort", func() {
+		gomega.Eventually(func() kueue.CohortStatus {
+			var updatedCohort kueue.Cohort
+			gomega.Expect(k8sClient.Get(ctx, client.ObjectKeyFromObject(cohort), &updatedCohort)).To(gomega.Succeed())
+			return updatedCohort.Status
+		}, util.Timeout, util.Interval).Should(gomega.BeComparableTo(kueue.CohortStatus{
+			ClusterQueues: []string{cqA.Name, cqB.Name},
+			Quota: map[corev1.ResourceName]map[string]resource.Quantity{
+				corev1.ResourceCPU: {flavorOnDemand: resource.MustParse("8")},
+			},
+		}))
+
+		ginkgo.By("Admitting a workload in one of the ClusterQueues")
+		wl := testing.MakeWorkload("one", ns.Name).Request(corev1.ResourceCPU, "6").Obj()
+		gomega.Expect(k8sClient.Create(ctx, wl)).To(gomega.Succeed())
+		gomega.Eventually(func() error {
+			var newWL kueue.Workload
+			gomega.Expect(k8sClient.Get(ctx, client.ObjectKeyFromObject(wl), &newWL)).To(gomega.Succeed())
+			newWL.Status.Admission = testing.MakeAdmission(cqA.Name).Flavor(corev1.ResourceCPU, flavorOnDemand).Obj()
+			return k8sClient.Status().Update(ctx, &newWL)
+		}, util.Timeout, util.Interval).Should(gomega.Succeed())
+
+		gomega.Eventually(func() kueue.CohortStatus {
+			var updatedCohort kueue.Cohort
+			gomega.Expect(k8sClient.Get(ctx, client.ObjectKeyFromObject(cohort), &updatedCohort)).To(gomega.Succeed())
+			return updatedCohort.Status
+		}, util.Timeout, util.Interval).Should(gomega.BeComparableTo(kueue.CohortStatus{
+			ClusterQueues: []string{cqA.Name, cqB.Name},
+			Quota: map[corev1.ResourceName]map[string]resource.Quantity{
+				corev1.ResourceCPU: {flavorOnDemand: resource.MustParse("8")},
+			},
+			Usage: map[corev1.ResourceName]map[string]resource.Quantity{
+				corev1.ResourceCPU: {flavorOnDemand: resource.MustParse("6")},
+			},
+		}))
+
+		ginkgo.By("Removing a ClusterQueue from the cohort")
+		util.FinishWorkloads(ctx, k8sClient, wl)
+		util.ExpectClusterQueueToBeDeleted(ctx, k8sClient, cqB, true)
+		gomega.Eventually(func() kueue.CohortStatus {
+			var updatedCohort kueue.Cohort
+			gomega.Expect(k8sClient.Get(ctx, client.ObjectKeyFromObject(cohort), &updatedCohort)).To(gomega.Succeed())
+			return updatedCohort.Status
+		}, util.Timeout, util.Interval).Should(gomega.BeComparableTo(kueue.CohortStatus{
+			ClusterQueues: []string{cqA.Name},
+			Quota: map[corev1.ResourceName]map[string]resource.Quantity{
+				corev1.ResourceCPU: {flavorOnDemand: resource.MustParse("5")},
+			},
+		}))
+	})
+})
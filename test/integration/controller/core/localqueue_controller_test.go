@@ -89,9 +89,9 @@ var _ = ginkgo.Describe("Queue controller", func() {
 			gomega.Eventually(func() error {
 				var newWL kueue.Workload
 				gomega.Expect(k8sClient.Get(ctx, client.ObjectKeyFromObject(w), &newWL)).To(gomega.Succeed())
-				newWL.Spec.Admission = testing.MakeAdmission(clusterQueue.Name).
+				newWL.Status.Admission = testing.MakeAdmission(clusterQueue.Name).
 					Flavor(corev1.ResourceCPU, flavorOnDemand).Obj()
-				return k8sClient.Update(ctx, &newWL)
+				return k8sClient.Status().Update(ctx, &newWL)
 			}, util.Timeout, util.Interval).Should(gomega.Succeed())
 		}
 		gomega.Eventually(func() kueue.LocalQueueStatus {
@@ -81,8 +81,9 @@ var _ = ginkgo.Describe("Workload controller", func() {
 			gomega.Eventually(func() int {
 				gomega.Expect(k8sClient.Get(ctx, client.ObjectKeyFromObject(wl), &updatedQueueWorkload)).To(gomega.Succeed())
 				return len(updatedQueueWorkload.Status.Conditions)
-			}, util.Timeout, util.Interval).Should(gomega.BeComparableTo(1))
+			}, util.Timeout, util.Interval).Should(gomega.BeComparableTo(2))
 			gomega.Expect(updatedQueueWorkload.Status.Conditions[0].Message).To(gomega.BeComparableTo(message))
+			gomega.Expect(updatedQueueWorkload.Status.Conditions[1].Message).To(gomega.BeComparableTo(message))
 		})
 	})
 
@@ -97,8 +98,9 @@ var _ = ginkgo.Describe("Workload controller", func() {
 			gomega.Eventually(func() int {
 				gomega.Expect(k8sClient.Get(ctx, client.ObjectKeyFromObject(wl), &updatedQueueWorkload)).To(gomega.Succeed())
 				return len(updatedQueueWorkload.Status.Conditions)
-			}, util.Timeout, util.Interval).Should(gomega.BeComparableTo(1))
+			}, util.Timeout, util.Interval).Should(gomega.BeComparableTo(2))
 			gomega.Expect(updatedQueueWorkload.Status.Conditions[0].Message).To(gomega.BeComparableTo(message))
+			gomega.Expect(updatedQueueWorkload.Status.Conditions[1].Message).To(gomega.BeComparableTo(message))
 		})
 	})
 
@@ -149,9 +151,9 @@ var _ = ginkgo.Describe("Workload controller", func() {
 
 			ginkgo.By("Admit workload")
 			gomega.Expect(k8sClient.Get(ctx, client.ObjectKeyFromObject(wl), &updatedQueueWorkload)).To(gomega.Succeed())
-			updatedQueueWorkload.Spec.Admission = testing.MakeAdmission(clusterQueue.Name).
+			updatedQueueWorkload.Status.Admission = testing.MakeAdmission(clusterQueue.Name).
 				Flavor(corev1.ResourceCPU, flavorOnDemand).Obj()
-			gomega.Expect(k8sClient.Update(ctx, &updatedQueueWorkload)).To(gomega.Succeed())
+			gomega.Expect(k8sClient.Status().Update(ctx, &updatedQueueWorkload)).To(gomega.Succeed())
 			gomega.Eventually(func() bool {
 				gomega.Expect(k8sClient.Get(ctx, client.ObjectKeyFromObject(wl), &updatedQueueWorkload)).To(gomega.Succeed())
 				return apimeta.IsStatusConditionTrue(updatedQueueWorkload.Status.Conditions, kueue.WorkloadAdmitted)
@@ -182,11 +184,11 @@ var _ = ginkgo.Describe("Workload controller", func() {
 			wl = testing.MakeWorkload("one", ns.Name).
 				Queue(localQueue.Name).
 				Request(corev1.ResourceCPU, "1").
-				Admit(testing.MakeAdmission(clusterQueue.Name).
-					Flavor(corev1.ResourceCPU, flavorOnDemand).Obj()).
 				RuntimeClass("kata").
 				Obj()
 			gomega.Expect(k8sClient.Create(ctx, wl)).To(gomega.Succeed())
+			util.SetWorkloadAdmission(ctx, k8sClient, wl, testing.MakeAdmission(clusterQueue.Name).
+				Flavor(corev1.ResourceCPU, flavorOnDemand).Obj())
 
 			ginkgo.By("Got ClusterQueueStatus")
 			gomega.Eventually(func() kueue.ClusterQueueStatus {
@@ -227,11 +229,11 @@ var _ = ginkgo.Describe("Workload controller", func() {
 			wl = testing.MakeWorkload("one", ns.Name).
 				Queue(localQueue.Name).
 				Request(corev1.ResourceCPU, "1").
-				Admit(testing.MakeAdmission(clusterQueue.Name).
-					Flavor(corev1.ResourceCPU, flavorOnDemand).Obj()).
 				RuntimeClass("kata").
 				Obj()
 			gomega.Expect(k8sClient.Create(ctx, wl)).To(gomega.Succeed())
+			util.SetWorkloadAdmission(ctx, k8sClient, wl, testing.MakeAdmission(clusterQueue.Name).
+				Flavor(corev1.ResourceCPU, flavorOnDemand).Obj())
 
 			ginkgo.By("Got ClusterQueueStatus")
 			gomega.Eventually(func() kueue.ClusterQueueStatus {
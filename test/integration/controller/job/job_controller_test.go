@@ -107,7 +107,7 @@ var _ = ginkgo.Describe("Job controller", func() {
 		}, util.Timeout, util.Interval).Should(gomega.BeTrue())
 
 		ginkgo.By("checking a second non-matching workload is deleted")
-		secondWl, _ := workloadjob.ConstructWorkloadFor(ctx, k8sClient, createdJob, scheme.Scheme)
+		secondWl, _ := workloadjob.ConstructWorkloadFor(ctx, k8sClient, createdJob, scheme.Scheme, nil)
 		secondWl.Name = "second-workload"
 		secondWl.Spec.PodSets[0].Count = parallelism + 1
 		gomega.Expect(k8sClient.Create(ctx, secondWl)).Should(gomega.Succeed())
@@ -136,7 +136,7 @@ var _ = ginkgo.Describe("Job controller", func() {
 				Flavor(testing.MakeFlavor(onDemandFlavor.Name, "5").Obj()).
 				Flavor(testing.MakeFlavor(spotFlavor.Name, "5").Obj()).
 				Obj()).Obj()
-		createdWorkload.Spec.Admission = &kueue.Admission{
+		createdWorkload.Status.Admission = &kueue.Admission{
 			ClusterQueue: kueue.ClusterQueueReference(clusterQueue.Name),
 			PodSetFlavors: []kueue.PodSetFlavors{{
 				Flavors: map[corev1.ResourceName]string{
@@ -144,7 +144,7 @@ var _ = ginkgo.Describe("Job controller", func() {
 				},
 			}},
 		}
-		gomega.Expect(k8sClient.Update(ctx, createdWorkload)).Should(gomega.Succeed())
+		gomega.Expect(k8sClient.Status().Update(ctx, createdWorkload)).Should(gomega.Succeed())
 		gomega.Eventually(func() bool {
 			if err := k8sClient.Get(ctx, lookupKey, createdJob); err != nil {
 				return false
@@ -187,10 +187,10 @@ var _ = ginkgo.Describe("Job controller", func() {
 			}
 			return createdWorkload.Spec.PodSets[0].Count == newParallelism
 		}, util.Timeout, util.Interval).Should(gomega.BeTrue())
-		gomega.Expect(createdWorkload.Spec.Admission).Should(gomega.BeNil())
+		gomega.Expect(createdWorkload.Status.Admission).Should(gomega.BeNil())
 
 		ginkgo.By("checking the job is unsuspended and selectors added when workload is assigned again")
-		createdWorkload.Spec.Admission = &kueue.Admission{
+		createdWorkload.Status.Admission = &kueue.Admission{
 			ClusterQueue: kueue.ClusterQueueReference(clusterQueue.Name),
 			PodSetFlavors: []kueue.PodSetFlavors{{
 				Flavors: map[corev1.ResourceName]string{
@@ -198,7 +198,7 @@ var _ = ginkgo.Describe("Job controller", func() {
 				},
 			}},
 		}
-		gomega.Expect(k8sClient.Update(ctx, createdWorkload)).Should(gomega.Succeed())
+		gomega.Expect(k8sClient.Status().Update(ctx, createdWorkload)).Should(gomega.Succeed())
 		gomega.Eventually(func() bool {
 			if err := k8sClient.Get(ctx, lookupKey, createdJob); err != nil {
 				return false
@@ -311,7 +311,7 @@ var _ = ginkgo.Describe("Job controller when waitForPodsReady enabled", func() {
 			}, util.Timeout, util.Interval).Should(gomega.Succeed())
 
 			ginkgo.By("Admit the workload created for the job")
-			createdWorkload.Spec.Admission = &kueue.Admission{
+			createdWorkload.Status.Admission = &kueue.Admission{
 				ClusterQueue: kueue.ClusterQueueReference("foo"),
 				PodSetFlavors: []kueue.PodSetFlavors{{
 					Flavors: map[corev1.ResourceName]string{
@@ -319,7 +319,7 @@ var _ = ginkgo.Describe("Job controller when waitForPodsReady enabled", func() {
 					},
 				}},
 			}
-			gomega.Expect(k8sClient.Update(ctx, createdWorkload)).Should(gomega.Succeed())
+			gomega.Expect(k8sClient.Status().Update(ctx, createdWorkload)).Should(gomega.Succeed())
 			gomega.Expect(k8sClient.Get(ctx, lookupKey, createdWorkload)).Should(gomega.Succeed())
 
 			ginkgo.By("Await for the job to be unsuspended")
@@ -356,8 +356,8 @@ var _ = ginkgo.Describe("Job controller when waitForPodsReady enabled", func() {
 					if err := k8sClient.Get(ctx, lookupKey, createdWorkload); err != nil {
 						return err
 					}
-					createdWorkload.Spec.Admission = nil
-					return k8sClient.Update(ctx, createdWorkload)
+					createdWorkload.Status.Admission = nil
+					return k8sClient.Status().Update(ctx, createdWorkload)
 				}, util.Timeout, util.Interval).Should(gomega.Succeed())
 			}
 
@@ -620,6 +620,7 @@ var _ = ginkgo.Describe("Job controller interacting with scheduler", func() {
 			return createdProdJob2.Spec.Suspend
 		}, util.ConsistentDuration, util.Interval).Should(gomega.Equal(pointer.Bool(true)))
 		util.ExpectPendingWorkloadsMetric(prodClusterQ, 0, 1)
+		util.ExpectPendingWorkloadsByPriorityClassMetric(prodClusterQ, "", 0, 1)
 		util.ExpectAdmittedActiveWorkloadsMetric(prodClusterQ, 1)
 
 		ginkgo.By("checking a dev job starts")
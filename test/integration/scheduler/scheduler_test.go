@@ -17,6 +17,8 @@ limitations under the License.
 package scheduler
 
 import (
+	"time"
+
 	"github.com/onsi/ginkgo/v2"
 	"github.com/onsi/gomega"
 	corev1 "k8s.io/api/core/v1"
@@ -206,6 +208,22 @@ var _ = ginkgo.Describe("Scheduler", func() {
 			util.ExpectAdmittedActiveWorkloadsMetric(prodClusterQ, 2)
 			util.ExpectAdmittedWorkloadsTotalMetric(prodClusterQ, 3)
 		})
+
+		ginkgo.It("Should report the workloads admitted after their latest start time", func() {
+			ginkgo.By("Creating a workload that can still meet its deadline")
+			onTimeWl := testing.MakeWorkload("on-time-wl", ns.Name).Queue(prodQueue.Name).
+				Request(corev1.ResourceCPU, "2").Deadline(time.Now().Add(time.Hour)).Obj()
+			gomega.Expect(k8sClient.Create(ctx, onTimeWl)).Should(gomega.Succeed())
+			util.ExpectWorkloadsToBeAdmitted(ctx, k8sClient, prodClusterQ.Name, onTimeWl)
+			util.ExpectAdmissionDeadlineMissesMetric(prodClusterQ, 0)
+
+			ginkgo.By("Creating a workload whose deadline already passed")
+			lateWl := testing.MakeWorkload("late-wl", ns.Name).Queue(prodQueue.Name).
+				Request(corev1.ResourceCPU, "2").Deadline(time.Now().Add(-time.Minute)).Obj()
+			gomega.Expect(k8sClient.Create(ctx, lateWl)).Should(gomega.Succeed())
+			util.ExpectWorkloadsToBeAdmitted(ctx, k8sClient, prodClusterQ.Name, lateWl)
+			util.ExpectAdmissionDeadlineMissesMetric(prodClusterQ, 1)
+		})
 	})
 
 	ginkgo.When("Handling workloads events", func() {
@@ -891,7 +909,7 @@ var _ = ginkgo.Describe("Scheduler", func() {
 			gomega.Consistently(func() bool {
 				lookupKey := types.NamespacedName{Name: wl3.Name, Namespace: wl3.Namespace}
 				gomega.Expect(k8sClient.Get(ctx, lookupKey, wl3)).Should(gomega.Succeed())
-				return wl3.Spec.Admission == nil
+				return wl3.Status.Admission == nil
 			}, util.ConsistentDuration, util.Interval).Should(gomega.Equal(true))
 			util.ExpectPendingWorkloadsMetric(strictFIFOClusterQ, 2, 0)
 			util.ExpectAdmittedActiveWorkloadsMetric(strictFIFOClusterQ, 1)
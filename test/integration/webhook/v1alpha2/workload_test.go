@@ -151,9 +151,9 @@ var _ = ginkgo.Describe("Workload validating webhook", func() {
 			ginkgo.By("Creating a new Workload")
 			workload := testing.MakeWorkload(workloadName, ns.Name).
 				Queue("queue1").
-				Admit(testing.MakeAdmission("cq").Obj()).
 				Obj()
 			gomega.Expect(k8sClient.Create(ctx, workload)).Should(gomega.Succeed())
+			util.SetWorkloadAdmission(ctx, k8sClient, workload, testing.MakeAdmission("cq").Obj())
 
 			ginkgo.By("Updating queueName")
 			gomega.Eventually(func() error {
@@ -164,19 +164,18 @@ var _ = ginkgo.Describe("Workload validating webhook", func() {
 			}, util.Timeout, util.Interval).Should(testing.BeForbiddenError())
 		})
 
-		ginkgo.It("Should forbid the change of spec.admission", func() {
+		ginkgo.It("Should forbid the change of status.admission", func() {
 			ginkgo.By("Creating a new Workload")
-			workload := testing.MakeWorkload(workloadName, ns.Name).Admit(
-				testing.MakeAdmission("cluster-queue").Obj(),
-			).Obj()
+			workload := testing.MakeWorkload(workloadName, ns.Name).Obj()
 			gomega.Expect(k8sClient.Create(ctx, workload)).Should(gomega.Succeed())
+			util.SetWorkloadAdmission(ctx, k8sClient, workload, testing.MakeAdmission("cluster-queue").Obj())
 
 			ginkgo.By("Updating queueName")
 			gomega.Eventually(func() error {
 				var newWL kueue.Workload
 				gomega.Expect(k8sClient.Get(ctx, client.ObjectKeyFromObject(workload), &newWL)).To(gomega.Succeed())
-				newWL.Spec.Admission.ClusterQueue = "foo-clusterQueue"
-				return k8sClient.Update(ctx, &newWL)
+				newWL.Status.Admission.ClusterQueue = "foo-clusterQueue"
+				return k8sClient.Status().Update(ctx, &newWL)
 			}, util.Timeout, util.Interval).Should(testing.BeForbiddenError())
 
 		})
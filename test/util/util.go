@@ -122,13 +122,24 @@ func FinishWorkloads(ctx context.Context, k8sClient client.Client, workloads ...
 	}
 }
 
+// SetWorkloadAdmission sets the admission in the status of the workload, as
+// the scheduler does. The status is ignored when the workload is created.
+func SetWorkloadAdmission(ctx context.Context, k8sClient client.Client, wl *kueue.Workload, admission *kueue.Admission) {
+	gomega.EventuallyWithOffset(1, func() error {
+		var newWL kueue.Workload
+		gomega.Expect(k8sClient.Get(ctx, client.ObjectKeyFromObject(wl), &newWL)).To(gomega.Succeed())
+		newWL.Status.Admission = admission
+		return k8sClient.Status().Update(ctx, &newWL)
+	}, Timeout, Interval).Should(gomega.Succeed())
+}
+
 func ExpectWorkloadsToBeAdmitted(ctx context.Context, k8sClient client.Client, cqName string, wls ...*kueue.Workload) {
 	gomega.EventuallyWithOffset(1, func() int {
 		admitted := 0
 		var updatedWorkload kueue.Workload
 		for _, wl := range wls {
 			gomega.ExpectWithOffset(1, k8sClient.Get(ctx, client.ObjectKeyFromObject(wl), &updatedWorkload)).To(gomega.Succeed())
-			if updatedWorkload.Spec.Admission != nil && string(updatedWorkload.Spec.Admission.ClusterQueue) == cqName {
+			if updatedWorkload.Status.Admission != nil && string(updatedWorkload.Status.Admission.ClusterQueue) == cqName {
 				admitted++
 			}
 		}
@@ -147,7 +158,7 @@ func ExpectWorkloadsToBePending(ctx context.Context, k8sClient client.Client, wl
 				continue
 			}
 			cond := updatedWorkload.Status.Conditions[idx]
-			if cond.Status == metav1.ConditionFalse && cond.Reason == "Pending" && wl.Spec.Admission == nil {
+			if cond.Status == metav1.ConditionFalse && cond.Reason == kueue.WorkloadReasonPending && wl.Status.Admission == nil {
 				pending++
 			}
 		}
@@ -166,7 +177,7 @@ func ExpectWorkloadsToBeWaiting(ctx context.Context, k8sClient client.Client, wl
 				continue
 			}
 			cond := updatedWorkload.Status.Conditions[idx]
-			if cond.Status == metav1.ConditionFalse && cond.Reason == "Waiting" && wl.Spec.Admission == nil {
+			if cond.Status == metav1.ConditionFalse && cond.Reason == kueue.WorkloadReasonWaitingForPodsReady && wl.Status.Admission == nil {
 				pending++
 			}
 		}
@@ -186,7 +197,7 @@ func ExpectWorkloadsToBeFrozen(ctx context.Context, k8sClient client.Client, cq
 			}
 			msg := fmt.Sprintf("ClusterQueue %s is inactive", cq)
 			cond := updatedWorkload.Status.Conditions[idx]
-			if cond.Status == metav1.ConditionFalse && cond.Reason == "Inadmissible" && wl.Spec.Admission == nil && cond.Message == msg {
+			if cond.Status == metav1.ConditionFalse && cond.Reason == kueue.WorkloadReasonInadmissible && wl.Status.Admission == nil && cond.Message == msg {
 				frozen++
 			}
 		}
@@ -198,7 +209,7 @@ func ExpectWorkloadToBeAdmittedAs(ctx context.Context, k8sClient client.Client,
 	var updatedWorkload kueue.Workload
 	gomega.Eventually(func() *kueue.Admission {
 		gomega.Expect(k8sClient.Get(ctx, client.ObjectKeyFromObject(wl), &updatedWorkload)).To(gomega.Succeed())
-		return updatedWorkload.Spec.Admission
+		return updatedWorkload.Status.Admission
 	}, Timeout, Interval).Should(gomega.BeComparableTo(admission))
 }
 
@@ -216,6 +227,18 @@ func ExpectPendingWorkloadsMetric(cq *kueue.ClusterQueue, active, inadmissible i
 	}
 }
 
+func ExpectPendingWorkloadsByPriorityClassMetric(cq *kueue.ClusterQueue, priorityClass string, active, inadmissible int) {
+	vals := []int{active, inadmissible}
+	for i, status := range pendingStatuses {
+		metric := metrics.PendingWorkloadsByPriorityClass.WithLabelValues(cq.Name, priorityClass, status)
+		gomega.EventuallyWithOffset(1, func() int {
+			v, err := testutil.GetGaugeMetricValue(metric)
+			gomega.Expect(err).ToNot(gomega.HaveOccurred())
+			return int(v)
+		}, Timeout, Interval).Should(gomega.Equal(vals[i]), "pending_workloads_by_priority_class with priority_class=%q and status=%s", priorityClass, status)
+	}
+}
+
 func ExpectAdmittedActiveWorkloadsMetric(cq *kueue.ClusterQueue, v int) {
 	metric := metrics.AdmittedActiveWorkloads.WithLabelValues(cq.Name)
 	gomega.EventuallyWithOffset(1, func() int {
@@ -225,6 +248,15 @@ func ExpectAdmittedActiveWorkloadsMetric(cq *kueue.ClusterQueue, v int) {
 	}, Timeout, Interval).Should(gomega.Equal(v))
 }
 
+func ExpectAdmissionDeadlineMissesMetric(cq *kueue.ClusterQueue, v int) {
+	metric := metrics.AdmissionDeadlineMissesTotal.WithLabelValues(cq.Name)
+	gomega.EventuallyWithOffset(1, func() int {
+		v, err := testutil.GetCounterMetricValue(metric)
+		gomega.Expect(err).ToNot(gomega.HaveOccurred())
+		return int(v)
+	}, Timeout, Interval).Should(gomega.Equal(v))
+}
+
 func ExpectAdmittedWorkloadsTotalMetric(cq *kueue.ClusterQueue, v int) {
 	metric := metrics.AdmittedWorkloadsTotal.WithLabelValues(cq.Name)
 	gomega.EventuallyWithOffset(1, func() int {